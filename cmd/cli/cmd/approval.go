@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+const (
+	approvalStatusApproved = "approved"
+	approvalStatusDenied   = "denied"
+	approvalStatusPending  = "pending"
+)
+
+// approvalPollInterval is how often the approval endpoint is polled for a decision
+var approvalPollInterval = 15 * time.Second
+
+// approvalRequest is posted to the approval endpoint to request approval for deploying a ring
+type approvalRequest struct {
+	CorrelationID  string `json:"correlation_id"`
+	Reason         string `json:"reason"`
+	Project        string `json:"project"`
+	DeploymentRing string `json:"deployment_ring"`
+	Environment    string `json:"environment"`
+	Namespace      string `json:"namespace"`
+	Account        string `json:"account"`
+	Version        string `json:"version"`
+	SelfDestroy    bool   `json:"self_destroy"`
+	Destroy        bool   `json:"destroy"`
+}
+
+// approvalResponse is returned by the approval endpoint for both the request and every poll
+type approvalResponse struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// getRequireApprovalRings returns the rings configured under require_approval_rings in the runiac config file and the
+// rings with rings.{ring}.require_approval set
+func getRequireApprovalRings() []string {
+	rings := viper.GetStringSlice("require_approval_rings")
+
+	for _, ring := range getSortedKeys(viper.GetStringMap("rings")) {
+		if viper.GetBool(getRingConfigKey(ring, "require_approval")) {
+			rings = append(rings, ring)
+		}
+	}
+
+	return rings
+}
+
+// newCorrelationID returns a random identifier correlating an approval request with this run
+func newCorrelationID() (string, error) {
+	b := make([]byte, 16)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// waitForApproval posts the approval request to url, then polls {url}/{correlation_id} until the request is
+// approved, denied or the timeout elapses. Only an approval returns without error.
+func waitForApproval(client *http.Client, url string, req approvalRequest, timeout time.Duration) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	resp, err := doApprovalRequest(client, http.MethodPost, url, body)
+	if err != nil {
+		return fmt.Errorf("requesting approval failed: %w", err)
+	}
+
+	pollURL := fmt.Sprintf("%s/%s", strings.TrimSuffix(url, "/"), req.CorrelationID)
+	deadline := time.Now().Add(timeout)
+
+	for {
+		switch strings.ToLower(resp.Status) {
+		case approvalStatusApproved:
+			logrus.Infof("Deployment ring '%s' was approved (correlation id %s) %s", req.DeploymentRing, req.CorrelationID, resp.Message)
+			return nil
+		case approvalStatusDenied:
+			return fmt.Errorf("deployment ring '%s' was denied (correlation id %s): %s", req.DeploymentRing, req.CorrelationID, resp.Message)
+		case approvalStatusPending, "":
+		default:
+			return fmt.Errorf("approval endpoint returned unknown status '%s'", resp.Status)
+		}
+
+		if time.Now().Add(approvalPollInterval).After(deadline) {
+			return fmt.Errorf("timed out after %s waiting for approval of deployment ring '%s' (correlation id %s)", timeout, req.DeploymentRing, req.CorrelationID)
+		}
+
+		logrus.Infof("Waiting for approval of deployment ring '%s' (correlation id %s)...", req.DeploymentRing, req.CorrelationID)
+		time.Sleep(approvalPollInterval)
+
+		resp, err = doApprovalRequest(client, http.MethodGet, pollURL, nil)
+		if err != nil {
+			// transient failures should not lose an approval that may still be granted
+			logrus.WithError(err).Warn("Polling approval endpoint failed")
+			resp = approvalResponse{Status: approvalStatusPending}
+		}
+	}
+}
+
+func doApprovalRequest(client *http.Client, method string, url string, body []byte) (approvalResponse, error) {
+	approval := approvalResponse{}
+
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return approval, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return approval, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return approval, fmt.Errorf("approval endpoint %s returned %s", url, resp.Status)
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&approval)
+
+	return approval, err
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubApprovalServer returns pending for the request and the first polls, then the final status
+func stubApprovalServer(t *testing.T, pendingPolls int32, final string) (*httptest.Server, *int32) {
+	var polls int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := approvalStatusPending
+
+		if r.Method == http.MethodPost {
+			req := approvalRequest{}
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Equal(t, "prod", req.DeploymentRing)
+			assert.Equal(t, "CHG001", req.Reason)
+		} else {
+			assert.Equal(t, "/correlation", r.URL.Path)
+
+			if atomic.AddInt32(&polls, 1) > pendingPolls {
+				status = final
+			}
+		}
+
+		_, _ = fmt.Fprintf(w, `{"status": "%s"}`, status)
+	}))
+
+	return server, &polls
+}
+
+func TestGetRequireApprovalRings_ShouldIncludeRingsRequiringApproval(t *testing.T) {
+	viper.Set("require_approval_rings", []string{"prod"})
+	viper.Set("rings.dr.require_approval", true)
+	viper.Set("rings.dev.require_approval", false)
+	defer viper.Set("require_approval_rings", nil)
+	defer viper.Set("rings", nil)
+
+	require.Equal(t, []string{"prod", "dr"}, getRequireApprovalRings())
+}
+
+func TestWaitForApproval_ShouldPollUntilDecision(t *testing.T) {
+	approvalPollInterval = time.Millisecond
+	defer func() { approvalPollInterval = 15 * time.Second }()
+
+	req := approvalRequest{CorrelationID: "correlation", DeploymentRing: "prod", Reason: "CHG001"}
+
+	server, polls := stubApprovalServer(t, 2, approvalStatusApproved)
+	defer server.Close()
+
+	require.NoError(t, waitForApproval(server.Client(), server.URL, req, time.Minute))
+	require.Equal(t, int32(3), *polls)
+
+	deniedServer, _ := stubApprovalServer(t, 0, approvalStatusDenied)
+	defer deniedServer.Close()
+
+	require.Error(t, waitForApproval(deniedServer.Client(), deniedServer.URL, req, time.Minute), "a denial should abort")
+}
+
+func TestWaitForApproval_ShouldTimeoutWhenPending(t *testing.T) {
+	approvalPollInterval = 5 * time.Millisecond
+	defer func() { approvalPollInterval = 15 * time.Second }()
+
+	server, _ := stubApprovalServer(t, 1000, approvalStatusApproved)
+	defer server.Close()
+
+	err := waitForApproval(server.Client(), server.URL, approvalRequest{CorrelationID: "correlation", DeploymentRing: "prod", Reason: "CHG001"}, 20*time.Millisecond)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "timed out")
+}
@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+const (
+	containerOutputDir    = "/runiac/output"    // Where --output-dir is mounted inside the container
+	containerArtifactsDir = "/runiac/artifacts" // Where --artifacts-from is mounted inside the container
+	containerManifestDir  = "/runiac/manifest"  // Where the directory of --export-manifest is mounted inside the container
+	containerSarifDir     = "/runiac/sarif"     // Where the directory of --sarif is mounted inside the container
+	containerReportDir    = "/runiac/report"    // Where the directory of --report-path is mounted inside the container
+)
+
+// savedPlansDefaultEnvironment names the saved plans directory of the runs without an --environment
+const savedPlansDefaultEnvironment = "default"
+
+// getSavedPlansDir returns the directory the plans of a --dry-run of the environment are saved to and applied from
+// with --use-saved-plans, .runiac/plans/{environment}. The plans of each step are saved beneath it as
+// {track}/{step}/{regionDeployType}-{region}, in a directory per ring when deploying multiple rings.
+func getSavedPlansDir(environment string) string {
+	if environment == "" {
+		environment = savedPlansDefaultEnvironment
+	}
+
+	return filepath.Join(planDir, strings.ToLower(environment))
+}
+
+// configureSavedPlans saves the plans of a --dry-run deploy or destroy to the environment's saved plans directory
+// when no --output-dir is set, replacing the plans of its previous dry run, and applies them with --use-saved-plans
+func configureSavedPlans(action runAction) error {
+	if action != actionDeploy && action != actionDestroy {
+		if UseSavedPlans {
+			return fmt.Errorf("--use-saved-plans can only be used with deploy and destroy")
+		}
+
+		return nil
+	}
+
+	dir := getSavedPlansDir(Environment)
+
+	if UseSavedPlans {
+		if DryRun {
+			return fmt.Errorf("--use-saved-plans applies the plans of a previous --dry-run, it can not be used with --dry-run")
+		}
+
+		if ArtifactsFrom != "" {
+			return fmt.Errorf("--use-saved-plans can not be used with --artifacts-from, both set the plans to apply")
+		}
+
+		if exists, _ := afero.DirExists(appFS, dir); !exists {
+			return fmt.Errorf("no saved plans for environment '%s' in %s, save them with a --dry-run first", Environment, dir)
+		}
+
+		ArtifactsFrom = dir
+
+		return nil
+	}
+
+	// a kubernetes job can not write to this host
+	if !DryRun || OutputDir != "" || isKubernetesTarget() {
+		return nil
+	}
+
+	OutputDir = dir
+
+	if ShowResolvedVars || PrintContext {
+		return nil
+	}
+
+	// only the plans of this dry run are applied by --use-saved-plans
+	return appFS.RemoveAll(dir)
+}
+
+// previousRunConfig is the subset of the resolved configuration persisted by the
+// container to config.json that must match for artifacts to be reused
+type previousRunConfig struct {
+	DeploymentRing string
+	Environment    string
+	Namespace      string
+}
+
+// checkArtifactsCompatibility ensures the artifacts directory was produced by a previous run
+// targeting the same deployment ring, environment and namespace as this run
+func checkArtifactsCompatibility(dir string, ring string, environment string, namespace string) error {
+	info, err := os.Stat(dir)
+	if err != nil || !info.IsDir() {
+		return fmt.Errorf("artifacts directory '%s' does not exist", dir)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "config.json"))
+	if err != nil {
+		return fmt.Errorf("'%s' does not contain the config.json of a previous run's --output-dir", dir)
+	}
+
+	previous := previousRunConfig{}
+	err = json.Unmarshal(b, &previous)
+	if err != nil {
+		return err
+	}
+
+	if previous.DeploymentRing != ring {
+		return fmt.Errorf("artifacts were produced for deployment ring '%s', not '%s'", previous.DeploymentRing, ring)
+	}
+
+	if previous.Environment != environment {
+		return fmt.Errorf("artifacts were produced for environment '%s', not '%s'", previous.Environment, environment)
+	}
+
+	if previous.Namespace != namespace {
+		return fmt.Errorf("artifacts were produced for namespace '%s', not '%s'", previous.Namespace, namespace)
+	}
+
+	return nil
+}
+
+// getArtifactsArguments returns the container run arguments mounting the output and previous artifacts directories
+func getArtifactsArguments(outputDir string, artifactsFrom string) (args []string, err error) {
+	if outputDir != "" {
+		dir, err := filepath.Abs(outputDir)
+		if err != nil {
+			return nil, err
+		}
+
+		err = os.MkdirAll(dir, 0755)
+		if err != nil {
+			return nil, err
+		}
+
+		args = append(args, getVolumeArguments(dir, containerOutputDir, false)...)
+		args = appendE(args, "OUTPUT_DIR", containerOutputDir)
+	}
+
+	if artifactsFrom != "" {
+		dir, err := filepath.Abs(artifactsFrom)
+		if err != nil {
+			return nil, err
+		}
+
+		args = append(args, getVolumeArguments(dir, containerArtifactsDir, true)...)
+		args = appendE(args, "ARTIFACTS_FROM", containerArtifactsDir)
+	}
+
+	return
+}
+
+// getHostFileArguments returns the container run arguments mounting the directory of hostPath at containerDir so
+// the container can write the file to the host, passing the container path of the file as the env variable name
+func getHostFileArguments(hostPath string, containerDir string, name string) (args []string, err error) {
+	if hostPath == "" {
+		return
+	}
+
+	path, err := filepath.Abs(hostPath)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	args = append(args, getVolumeArguments(filepath.Dir(path), containerDir, false)...)
+	args = appendE(args, name, fmt.Sprintf("%s/%s", containerDir, filepath.Base(path)))
+
+	return
+}
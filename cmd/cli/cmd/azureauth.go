@@ -0,0 +1,226 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// azureClient requests the preflight token of the azure credentials
+var azureClient = &http.Client{Timeout: 30 * time.Second}
+
+// azureAuthorityHost is the microsoft entra login endpoint, AZURE_AUTHORITY_HOST overrides it for sovereign clouds
+const azureAuthorityHost = "https://login.microsoftonline.com"
+
+// azureManagementScope is the scope of the preflight token, the azure resource manager the steps deploy with
+const azureManagementScope = "https://management.azure.com/.default"
+
+var (
+	azureGUIDPattern   = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	azureTenantPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9.-]*$`)
+)
+
+// azureAuth is the service principal the steps authenticate to azure with, either with a federated token (OIDC)
+// or a client secret
+type azureAuth struct {
+	TenantID     string
+	ClientID     string
+	TokenFile    string
+	ClientSecret string
+}
+
+// resolveAzureAuth returns the service principal of --azure-client-id, the tenant, federated token file and client
+// secret fall back to the host's ARM_* and AZURE_* env variables. Empty when no client id is configured.
+func resolveAzureAuth() (azureAuth, error) {
+	auth := azureAuth{
+		TenantID:     getFirstEnv(AzureTenant, "ARM_TENANT_ID", "AZURE_TENANT_ID"),
+		ClientID:     AzureClientID,
+		TokenFile:    getFirstEnv(AzureTokenFile, "ARM_OIDC_TOKEN_FILE_PATH", "AZURE_FEDERATED_TOKEN_FILE"),
+		ClientSecret: getFirstEnv("", "ARM_CLIENT_SECRET", "AZURE_CLIENT_SECRET"),
+	}
+
+	if auth.ClientID == "" {
+		if AzureTenant != "" || AzureTokenFile != "" {
+			return azureAuth{}, errors.New("--azure-tenant-id and --azure-federated-token-file require the --azure-client-id of the service principal")
+		}
+
+		return azureAuth{}, nil
+	}
+
+	if !azureGUIDPattern.MatchString(auth.ClientID) {
+		return auth, fmt.Errorf("invalid --azure-client-id '%s', must be the application (client) id of the service principal", auth.ClientID)
+	}
+
+	if auth.TenantID == "" {
+		return auth, errors.New("no azure tenant for the service principal, set --azure-tenant-id or ARM_TENANT_ID")
+	}
+
+	if !azureTenantPattern.MatchString(auth.TenantID) {
+		return auth, fmt.Errorf("invalid --azure-tenant-id '%s', must be the tenant id or a domain of the tenant", auth.TenantID)
+	}
+
+	// a federated token is preferred over a long-lived client secret
+	if auth.TokenFile != "" {
+		auth.ClientSecret = ""
+
+		if _, err := readAzureFederatedToken(auth.TokenFile); err != nil {
+			return auth, err
+		}
+	} else if auth.ClientSecret == "" {
+		return auth, errors.New("no credential for the azure service principal, set --azure-federated-token-file or ARM_CLIENT_SECRET")
+	}
+
+	return auth, nil
+}
+
+// getFirstEnv returns the value when set, otherwise the first of the host's env variables that is set
+func getFirstEnv(value string, names ...string) string {
+	for _, name := range names {
+		if value != "" {
+			break
+		}
+
+		value = os.Getenv(name)
+	}
+
+	return value
+}
+
+// readAzureFederatedToken returns the federated token of the file, an error when it is empty or expired
+func readAzureFederatedToken(file string) (string, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("invalid --azure-federated-token-file '%s': %w", file, err)
+	}
+
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return "", fmt.Errorf("invalid --azure-federated-token-file '%s', the file is empty", file)
+	}
+
+	if expiry, ok := getTokenExpiry(token); ok && time.Now().After(expiry) {
+		return "", fmt.Errorf("the federated token in %s expired at %s, request a new token from the identity provider", file, expiry.Format(time.RFC3339))
+	}
+
+	return token, nil
+}
+
+// getTokenExpiry returns the exp claim of a jwt, false when the token is not a jwt with an expiry
+func getTokenExpiry(token string) (time.Time, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	claims := struct {
+		Exp int64 `json:"exp"`
+	}{}
+
+	if err = json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}, false
+	}
+
+	return time.Unix(claims.Exp, 0), true
+}
+
+// checkAzureCredentials requests a token for the azure resource manager with the service principal's credential,
+// failing before deploying when the credential is expired, revoked or not trusted by the tenant
+func checkAzureCredentials(client *http.Client, auth azureAuth) error {
+	form := url.Values{
+		"grant_type": {"client_credentials"},
+		"client_id":  {auth.ClientID},
+		"scope":      {azureManagementScope},
+	}
+
+	if auth.TokenFile != "" {
+		token, err := readAzureFederatedToken(auth.TokenFile)
+		if err != nil {
+			return err
+		}
+
+		form.Set("client_assertion_type", "urn:ietf:params:oauth:client-assertion-type:jwt-bearer")
+		form.Set("client_assertion", token)
+	} else {
+		form.Set("client_secret", auth.ClientSecret)
+	}
+
+	authority := strings.TrimSuffix(getFirstEnv("", "AZURE_AUTHORITY_HOST"), "/")
+	if authority == "" {
+		authority = azureAuthorityHost
+	}
+
+	resp, err := client.PostForm(fmt.Sprintf("%s/%s/oauth2/v2.0/token", authority, url.PathEscape(auth.TenantID)), form)
+	if err != nil {
+		return fmt.Errorf("unable to authenticate the azure service principal %s: %w", auth.ClientID, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		failure := struct {
+			Description string `json:"error_description"`
+		}{}
+
+		_ = json.NewDecoder(resp.Body).Decode(&failure)
+
+		// the description's first line has the error code, e.g. AADSTS7000222 for an expired client secret
+		description := strings.SplitN(failure.Description, "\n", 2)[0]
+		if description == "" {
+			description = resp.Status
+		}
+
+		return fmt.Errorf("the azure service principal %s failed to authenticate to tenant %s: %s", auth.ClientID, auth.TenantID, strings.TrimSpace(description))
+	}
+
+	return nil
+}
+
+// getAzureAuthArguments returns the container run arguments authenticating the steps as the service principal with
+// the ARM_* variables of the azure providers and the AZURE_* variables of the azure cli and sdks. The federated token
+// file is mounted since identity providers refresh it, a kubernetes Job which can not mount it is passed the token.
+func getAzureAuthArguments(auth azureAuth, mountToken bool) ([]string, error) {
+	args := []string{
+		"-e", fmt.Sprintf("ARM_TENANT_ID=%s", auth.TenantID),
+		"-e", fmt.Sprintf("ARM_CLIENT_ID=%s", auth.ClientID),
+		"-e", fmt.Sprintf("AZURE_TENANT_ID=%s", auth.TenantID),
+		"-e", fmt.Sprintf("AZURE_CLIENT_ID=%s", auth.ClientID),
+	}
+
+	if auth.TokenFile == "" {
+		secretValues[auth.ClientSecret] = true
+
+		return append(args, "-e", fmt.Sprintf("ARM_CLIENT_SECRET=%s", auth.ClientSecret), "-e", fmt.Sprintf("AZURE_CLIENT_SECRET=%s", auth.ClientSecret)), nil
+	}
+
+	args = append(args, "-e", "ARM_USE_OIDC=true")
+
+	if !mountToken {
+		token, err := readAzureFederatedToken(auth.TokenFile)
+		if err != nil {
+			return nil, err
+		}
+
+		return append(args, "-e", fmt.Sprintf("ARM_OIDC_TOKEN=%s", token)), nil
+	}
+
+	mountArgs, containerPath, err := getCredentialFileMount(auth.TokenFile, credentialsAzure)
+	if err != nil {
+		return nil, err
+	}
+
+	args = append(args, mountArgs...)
+
+	return append(args, "-e", fmt.Sprintf("ARM_OIDC_TOKEN_FILE_PATH=%s", containerPath), "-e", fmt.Sprintf("AZURE_FEDERATED_TOKEN_FILE=%s", containerPath)), nil
+}
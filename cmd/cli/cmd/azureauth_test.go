@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const stubAzureClientID = "00000000-0000-0000-0000-000000000001"
+
+func stubFederatedToken(t *testing.T, expiry time.Time) string {
+	t.Helper()
+
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"sub":"repo:stub","exp":%d}`, expiry.Unix())))
+	file := filepath.Join(t.TempDir(), "federated-token")
+
+	require.NoError(t, ioutil.WriteFile(file, []byte("eyJhbGciOiJSUzI1NiJ9."+payload+".c2ln\n"), 0600))
+
+	return file
+}
+
+func TestResolveAzureAuth_ShouldValidateTheServicePrincipal(t *testing.T) {
+	defer func() { AzureTenant, AzureClientID, AzureTokenFile = "", "", "" }()
+
+	auth, err := resolveAzureAuth()
+	require.NoError(t, err)
+	require.Equal(t, azureAuth{}, auth, "no service principal should be configured without a client id")
+
+	AzureTenant = "contoso.onmicrosoft.com"
+	_, err = resolveAzureAuth()
+	require.EqualError(t, err, "--azure-tenant-id and --azure-federated-token-file require the --azure-client-id of the service principal")
+
+	AzureClientID = "deployer"
+	_, err = resolveAzureAuth()
+	require.EqualError(t, err, "invalid --azure-client-id 'deployer', must be the application (client) id of the service principal")
+
+	AzureClientID = stubAzureClientID
+	_, err = resolveAzureAuth()
+	require.EqualError(t, err, "no credential for the azure service principal, set --azure-federated-token-file or ARM_CLIENT_SECRET")
+
+	_ = os.Setenv("ARM_CLIENT_SECRET", "s3cr3t")
+	defer os.Unsetenv("ARM_CLIENT_SECRET")
+
+	auth, err = resolveAzureAuth()
+	require.NoError(t, err)
+	require.Equal(t, azureAuth{TenantID: "contoso.onmicrosoft.com", ClientID: stubAzureClientID, ClientSecret: "s3cr3t"}, auth)
+
+	AzureTokenFile = stubFederatedToken(t, time.Now().Add(-time.Minute))
+	_, err = resolveAzureAuth()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "expired at")
+
+	// the federated token is preferred over the client secret
+	AzureTokenFile = stubFederatedToken(t, time.Now().Add(time.Hour))
+	auth, err = resolveAzureAuth()
+	require.NoError(t, err)
+	require.Equal(t, azureAuth{TenantID: "contoso.onmicrosoft.com", ClientID: stubAzureClientID, TokenFile: AzureTokenFile}, auth)
+}
+
+func TestCheckAzureCredentials_ShouldFailOnRejectedCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/contoso.onmicrosoft.com/oauth2/v2.0/token", r.URL.Path)
+		require.NoError(t, r.ParseForm())
+		require.Equal(t, stubAzureClientID, r.PostForm.Get("client_id"))
+		require.Equal(t, azureManagementScope, r.PostForm.Get("scope"))
+
+		if r.PostForm.Get("client_secret") == "s3cr3t" || r.PostForm.Get("client_assertion") != "" {
+			_, _ = w.Write([]byte(`{"token_type":"Bearer","access_token":"stub"}`))
+			return
+		}
+
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error":"invalid_client","error_description":"AADSTS7000222: The provided client secret keys are expired.\r\nTrace ID: stub"}`))
+	}))
+	defer server.Close()
+
+	_ = os.Setenv("AZURE_AUTHORITY_HOST", server.URL+"/")
+	defer os.Unsetenv("AZURE_AUTHORITY_HOST")
+
+	auth := azureAuth{TenantID: "contoso.onmicrosoft.com", ClientID: stubAzureClientID, ClientSecret: "s3cr3t"}
+	require.NoError(t, checkAzureCredentials(server.Client(), auth))
+
+	auth.ClientSecret = "expired"
+	require.EqualError(t, checkAzureCredentials(server.Client(), auth), "the azure service principal "+stubAzureClientID+" failed to authenticate to tenant contoso.onmicrosoft.com: AADSTS7000222: The provided client secret keys are expired.")
+
+	auth.ClientSecret, auth.TokenFile = "", stubFederatedToken(t, time.Now().Add(time.Hour))
+	require.NoError(t, checkAzureCredentials(server.Client(), auth))
+}
+
+func TestGetAzureAuthArguments_ShouldMountTheFederatedToken(t *testing.T) {
+	file := stubFederatedToken(t, time.Now().Add(time.Hour))
+	auth := azureAuth{TenantID: "contoso.onmicrosoft.com", ClientID: stubAzureClientID, TokenFile: file}
+
+	args, err := getAzureAuthArguments(auth, true)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"-e", "ARM_TENANT_ID=contoso.onmicrosoft.com",
+		"-e", "ARM_CLIENT_ID=" + stubAzureClientID,
+		"-e", "AZURE_TENANT_ID=contoso.onmicrosoft.com",
+		"-e", "AZURE_CLIENT_ID=" + stubAzureClientID,
+		"-e", "ARM_USE_OIDC=true",
+		"-v", file + ":/runiac/credentials/azure/federated-token:ro",
+		"-e", "ARM_OIDC_TOKEN_FILE_PATH=/runiac/credentials/azure/federated-token",
+		"-e", "AZURE_FEDERATED_TOKEN_FILE=/runiac/credentials/azure/federated-token",
+	}, args)
+
+	// a kubernetes job is passed the token
+	args, err = getAzureAuthArguments(auth, false)
+	require.NoError(t, err)
+	require.Contains(t, getEnvFromArgs(args), "ARM_USE_OIDC=true")
+	require.NotContains(t, args, "-v")
+
+	auth = azureAuth{TenantID: "contoso.onmicrosoft.com", ClientID: stubAzureClientID, ClientSecret: "s3cr3t"}
+
+	args, err = getAzureAuthArguments(auth, true)
+	require.NoError(t, err)
+	require.Contains(t, args, "ARM_CLIENT_SECRET=s3cr3t")
+	require.True(t, secretValues["s3cr3t"], "the client secret should be masked")
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var azurePipelinesOptions pipelineOptions
+
+func init() {
+	addPipelineFlags(genAzurePipelinesCmd, &azurePipelinesOptions, "azure-pipelines.yml")
+
+	genCmd.AddCommand(genAzurePipelinesCmd)
+}
+
+var genAzurePipelinesCmd = &cobra.Command{
+	Use:   "azure-pipelines",
+	Short: "Generate an Azure DevOps pipeline",
+	Long: `Writes an Azure DevOps pipeline from the runiac config, planning every deployment ring on pull requests and
+deploying the rings in order, one stage per ring, to the Azure DevOps environment named after the ring. Each ring
+authenticates with the azure resource manager service connection runiac-{ring}, whose credentials are exported as
+the ARM_ variables runiac forwards into the container.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runPipelineGenerator(azurePipelinesOptions, generateAzurePipeline)
+	},
+}
+
+// generateAzurePipeline returns an azure devops pipeline planning and deploying the targets of the project
+func generateAzurePipeline(graph projectGraph, targets []pipelineTarget, branch string) (string, error) {
+	return renderPipeline("azure-pipelines", generatedAzurePipelineTemplate, struct {
+		Header  string
+		Project string
+		Version string
+		Branch  string
+		Targets []pipelineTarget
+	}{getGeneratedPipelineHeader("azure-pipelines"), viper.GetString("project"), strings.TrimPrefix(Version, "v"), branch, targets})
+}
+
+const generatedAzurePipelineTemplate = `[[ .Header ]]
+[[- if .Project ]]
+# Plans and deploys [[ .Project ]] with runiac
+[[- end ]]
+trigger:
+  branches:
+    include:
+      - [[ .Branch ]]
+
+pr:
+  branches:
+    include:
+      - [[ .Branch ]]
+
+pool:
+  vmImage: ubuntu-latest
+
+variables:
+  RUNIAC_VERSION: "[[ .Version ]]" # the latest release when empty
+
+stages:
+  - stage: plan
+    condition: eq(variables['Build.Reason'], 'PullRequest')
+    jobs:
+[[- range .Targets ]]
+      - job: plan_[[ .ID ]]
+        displayName: Plan [[ .Ring ]]
+        steps:
+[[ include "steps" (dict "Action" "plan" "Target" .) | indent 10 ]]
+[[- end ]]
+[[- $previous := "" ]]
+[[- range .Targets ]]
+
+  - stage: deploy_[[ .ID ]]
+    displayName: Deploy [[ .Ring ]]
+    dependsOn: [[ if $previous ]]deploy_[[ $previous ]][[ else ]][][[ end ]]
+    condition: and(succeeded(), ne(variables['Build.Reason'], 'PullRequest'))
+    jobs:
+      - deployment: deploy
+        displayName: Deploy [[ .Ring ]]
+        environment: [[ .Environment ]] # the approvals and checks of the ring
+        strategy:
+          runOnce:
+            deploy:
+              steps:
+                - checkout: self
+[[ include "steps" (dict "Action" "deploy" "Target" .) | indent 16 ]]
+                - publish: .runiac/reports
+                  artifact: runiac-report-[[ .Ring ]]
+                  condition: always()
+[[- $previous = .ID ]]
+[[- end ]]
+[[- define "steps" -]]
+- script: |
+[[ install true | indent 4 ]]
+  displayName: Install runiac
+- task: AzureCLI@2
+  displayName: runiac [[ .Action ]] [[ .Target.Ring ]]
+  env:
+    PRIMARY_REGION: "[[ .Target.PrimaryRegion ]]"
+    REGIONAL_REGIONS: "[[ .Target.RegionalRegions ]]"
+  inputs:
+    azureSubscription: runiac-[[ .Target.Ring ]] # the azure resource manager service connection of the ring
+    scriptType: bash
+    scriptLocation: inlineScript
+    addSpnToEnvironment: true
+    inlineScript: |
+      # runiac forwards the ARM_ variables into the container
+      export ARM_CLIENT_ID="$servicePrincipalId" ARM_TENANT_ID="$tenantId"
+      export ARM_SUBSCRIPTION_ID="$(az account show --query id --output tsv)"
+
+      # workload identity federation service connections provide an id token instead of a secret
+      if [ -n "$idToken" ]; then
+        export ARM_USE_OIDC=true ARM_OIDC_TOKEN="$idToken"
+      else
+        export ARM_CLIENT_SECRET="$servicePrincipalKey"
+      fi
+
+[[ regionArgs | indent 6 ]]
+      runiac [[ .Action ]] -d "[[ .Target.Ring ]]" -e "[[ .Target.Environment ]]" "$@"
+[[- end ]]
+`
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+type azurePipelineStep struct {
+	Task   string            `yaml:"task"`
+	Script string            `yaml:"script"`
+	Env    map[string]string `yaml:"env"`
+	Inputs map[string]string `yaml:"inputs"`
+}
+
+func TestGenerateAzurePipeline_ShouldDeployRingsInOrder(t *testing.T) {
+	defer viper.Reset()
+
+	viper.Set("project", "stub")
+	viper.Set("primary_region", "centralus")
+	viper.Set("regional_regions", "centralus,eastus")
+
+	targets, err := getPipelineTargets([]string{"dev", "prod-us"})
+	require.NoError(t, err)
+
+	pipeline, err := generateAzurePipeline(projectGraph{}, targets, "main")
+	require.NoError(t, err)
+	require.Contains(t, pipeline, getGeneratedPipelineHeader("azure-pipelines"))
+
+	parsed := struct {
+		Stages []struct {
+			Stage     string      `yaml:"stage"`
+			DependsOn interface{} `yaml:"dependsOn"`
+			Jobs      []struct {
+				Job         string              `yaml:"job"`
+				Deployment  string              `yaml:"deployment"`
+				Environment string              `yaml:"environment"`
+				Steps       []azurePipelineStep `yaml:"steps"`
+				Strategy    struct {
+					RunOnce struct {
+						Deploy struct {
+							Steps []azurePipelineStep `yaml:"steps"`
+						} `yaml:"deploy"`
+					} `yaml:"runOnce"`
+				} `yaml:"strategy"`
+			} `yaml:"jobs"`
+		} `yaml:"stages"`
+	}{}
+
+	require.NoError(t, yaml.Unmarshal([]byte(pipeline), &parsed), pipeline)
+	require.Len(t, parsed.Stages, 3)
+
+	plan := parsed.Stages[0]
+	require.Equal(t, "plan", plan.Stage)
+	require.Len(t, plan.Jobs, 2)
+	require.Equal(t, "plan_prod_us", plan.Jobs[1].Job)
+
+	task := plan.Jobs[1].Steps[1]
+	require.Equal(t, "AzureCLI@2", task.Task)
+	require.Equal(t, "runiac-prod-us", task.Inputs["azureSubscription"])
+	require.Contains(t, task.Inputs["inlineScript"], `export ARM_CLIENT_ID="$servicePrincipalId"`)
+	require.Contains(t, task.Inputs["inlineScript"], `runiac plan -d "prod-us" -e "prod-us" "$@"`)
+	require.Equal(t, map[string]string{"PRIMARY_REGION": "centralus", "REGIONAL_REGIONS": "centralus,eastus"}, task.Env)
+
+	require.Equal(t, "deploy_dev", parsed.Stages[1].Stage)
+	require.Equal(t, []interface{}{}, parsed.Stages[1].DependsOn)
+	require.Equal(t, "deploy_prod_us", parsed.Stages[2].Stage)
+	require.Equal(t, "deploy_dev", parsed.Stages[2].DependsOn)
+
+	deploy := parsed.Stages[2].Jobs[0]
+	require.Equal(t, "prod-us", deploy.Environment)
+
+	steps := deploy.Strategy.RunOnce.Deploy.Steps
+	require.Len(t, steps, 4)
+	require.Contains(t, steps[1].Script, "sudo tar")
+	require.Equal(t, "runiac-prod-us", steps[2].Inputs["azureSubscription"])
+	require.Contains(t, steps[2].Inputs["inlineScript"], `runiac deploy -d "prod-us" -e "prod-us" "$@"`)
+}
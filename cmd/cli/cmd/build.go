@@ -0,0 +1,303 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/briandowns/spinner"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const buildKit = "DOCKER_BUILDKIT=1"
+
+// sourceHashLabel labels the project container with the hash of its build inputs, see getSourceHash
+const sourceHashLabel = "runiac.source-hash"
+
+var (
+	buildPush bool
+	buildTags []string
+)
+
+func init() {
+	buildCmd.Flags().StringVarP(&Container, "container", "c", Container, "The runiac deploy container the project container derives from")
+	buildCmd.Flags().StringVar(&ContainerDigest, "container-digest", "", "Pin the --container to this sha256:{hex} image digest so every build derives from the same image")
+	buildCmd.Flags().StringVarP(&Dockerfile, "dockerfile", "f", Dockerfile, "The dockerfile to build, defaults to '.runiac/Dockerfile.{ring}' when present for the deployment ring")
+	buildCmd.Flags().StringVarP(&DeploymentRing, "deployment-ring", "d", "", "The deployment ring whose dockerfile is built")
+	buildCmd.Flags().StringVar(&BuildContext, "context", "", "Build the project container from this tarball (optionally gzip compressed) instead of the working directory")
+	buildCmd.Flags().StringVar(&ContainerEngine, "container-engine", ContainerEngine, fmt.Sprintf("Container engine (ie. podman or docker). When %s or not set, the first running engine of %s, or of a comma separated list of engines", containerEngineAuto, strings.Join(supportedContainerEngines, ", ")))
+	buildCmd.Flags().BoolVar(&BuildNoCache, "no-cache", false, "Build the project container without the container engine's build cache")
+	buildCmd.Flags().StringArrayVar(&BuildArgs, "build-arg", []string{}, "Pass a build argument, KEY=VALUE or KEY for the host's value, to the dockerfile")
+	buildCmd.Flags().StringVar(&BuildPlatform, "platform", "", "Build the project container for this platform, e.g. linux/amd64")
+	buildCmd.Flags().StringVar(&BuildTarget, "target", "", "Build this stage of a multi-stage dockerfile")
+	buildCmd.Flags().StringSliceVarP(&buildTags, "tag", "t", []string{}, "Tag the project container as this image, e.g. registry.example.com/team/project:1.0.0")
+	buildCmd.Flags().BoolVar(&buildPush, "push", false, "Push every --tag to its registry")
+
+	rootCmd.AddCommand(buildCmd)
+}
+
+var buildCmd = &cobra.Command{
+	Use:   "build",
+	Short: "Build the project container",
+	Long: `Builds the project container runiac deploy runs, without deploying. With --push, every --tag is pushed so
+CI builds the image once and later deploys run it with runiac deploy --image instead of rebuilding it.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := applySettings(cmd.Flags())
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		if buildPush && len(buildTags) == 0 {
+			logrus.Fatal(errors.New("--push requires the --tag to push the project container as"))
+		}
+
+		Container, err = getPinnedContainer(Container, ContainerDigest)
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		ContainerEngine, err = resolveContainerEngine(ContainerEngine)
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		if BuildContext == "" && !checkInitialized() {
+			fmt.Printf("You need to run 'runiac init' before you can use the CLI in this directory\n")
+			return
+		}
+
+		projectFS, err := getBuildContextFs(BuildContext)
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		dockerfile, err := resolveRingDockerfile(projectFS, DeploymentRing, Dockerfile, isSettingExplicit(cmd.Flags(), "dockerfile"))
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		containerTag := getContainerTag(dockerfile, DeploymentRing)
+
+		// labeled so a later deploy reuses the container instead of rebuilding it
+		sourceHash, err := getSourceHash(appFS, BuildContext, dockerfile)
+		if err != nil {
+			logrus.WithError(err).Warn("Unable to hash the source of the project container, runiac deploy will rebuild it")
+		}
+
+		buildContainer(containerTag, dockerfile, sourceHash)
+
+		for _, image := range buildTags {
+			err = tagImage(containerTag, image)
+			if err != nil {
+				logrus.WithError(err).Fatal(err)
+			}
+
+			if buildPush {
+				err = pushImage(image)
+				if err != nil {
+					logrus.WithError(err).Fatal(err)
+				}
+			}
+		}
+
+		logrus.Infof("Built the project container %s", strings.Join(append([]string{containerTag}, buildTags...), ", "))
+	},
+}
+
+// buildChangedContainer builds the project container unless the container engine has a project container built
+// from the same source, i.e. labeled with the same source hash. --no-cache always rebuilds it.
+func buildChangedContainer(containerTag string, dockerfile string) {
+	sourceHash, err := getSourceHash(appFS, BuildContext, dockerfile)
+	if err != nil {
+		logrus.WithError(err).Warn("Unable to hash the source of the project container, rebuilding it")
+	}
+
+	if isContainerCurrent(containerTag, sourceHash) {
+		logrus.Infof("Reusing the project container %s, its source did not change since it was built", containerTag)
+		return
+	}
+
+	buildContainer(containerTag, dockerfile, sourceHash)
+}
+
+// isContainerCurrent returns true when the project container was built from the source of the hash
+func isContainerCurrent(containerTag string, sourceHash string) bool {
+	return !BuildNoCache && sourceHash != "" && getImageSourceHash(containerTag) == sourceHash
+}
+
+// getImageSourceHash returns the source hash the image is labeled with, empty when the image does not exist. A
+// variable so tests do not need a container engine.
+var getImageSourceHash = func(image string) string {
+	out, err := exec.Command(ContainerEngine, "image", "inspect", "-f", fmt.Sprintf("{{ index .Config.Labels %q }}", sourceHashLabel), image).Output()
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(out))
+}
+
+// getSourceHash returns a hash of the inputs of the project container's build: the project, the dockerfile and the
+// build options
+func getSourceHash(fs afero.Fs, contextTar string, dockerfile string) (string, error) {
+	projectHash, err := getProjectHash(fs, contextTar)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00", projectHash, filepath.ToSlash(dockerfile))
+
+	// the dockerfiles in .runiac are not part of the project hash, a tarball's hash includes its dockerfile
+	if contextTar == "" {
+		b, err := afero.ReadFile(fs, dockerfile)
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(h, "%x\x00", sha256.Sum256(b))
+	}
+
+	for _, arg := range getBuildOptionArguments() {
+		fmt.Fprintf(h, "%s\x00", arg)
+	}
+
+	// a build argument without value is passed the host's value
+	for _, arg := range BuildArgs {
+		if !strings.Contains(arg, "=") {
+			fmt.Fprintf(h, "%s=%s\x00", arg, os.Getenv(arg))
+		}
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// buildContainer builds the project container from the dockerfile, labeled with its source hash when set
+func buildContainer(containerTag string, dockerfile string, sourceHash string) {
+	cmdd := exec.Command(ContainerEngine, "build", "-t", containerTag, "-f", dockerfile)
+
+	if sourceHash != "" {
+		cmdd.Args = append(cmdd.Args, "--label", fmt.Sprintf("%s=%s", sourceHashLabel, sourceHash))
+	}
+
+	cmdd.Args = append(cmdd.Args, getBuildArguments()...)
+
+	// a --build-arg may pass a token to the build
+	logrus.Info(strings.Join(maskArgs(cmdd.Args), " "))
+
+	var stdoutBuf, stderrBuf bytes.Buffer
+
+	cmdd.Env = append(os.Environ(), buildKit)
+
+	if BuildContext != "" {
+		contextTar, err := os.Open(BuildContext)
+		if err != nil {
+			logrus.WithError(err).Fatalf("Unable to open build context %s", BuildContext)
+		}
+		defer contextTar.Close()
+
+		cmdd.Stdin = contextTar
+	}
+
+	s := spinner.New(spinner.CharSets[11], 100*time.Millisecond)
+	s.Suffix = " Building project container..."
+
+	if dockerfile != "" {
+		cmdd.Stdout = io.MultiWriter(runOutput, &stdoutBuf)
+		cmdd.Stderr = io.MultiWriter(os.Stderr, &stderrBuf)
+
+		err := cmdd.Run()
+		if err != nil {
+			log.Fatalf("Runiac failed to build %s", dockerfile)
+		}
+	} else {
+		s.Start()
+		b, err := cmdd.CombinedOutput()
+		if err != nil {
+			s.Stop()
+			logrus.Error(string(b))
+			logrus.WithError(err).Fatalf("Building project container failed with %s\n", err)
+		}
+
+		s.Stop()
+	}
+}
+
+func getBuildArguments() (args []string) {
+	args = getBuildOptionArguments()
+
+	// must be last argument added for docker build current directory context
+	args = append(args, getBuildContext(BuildContext))
+
+	return
+}
+
+// getBuildOptionArguments returns the build arguments of the base container and the build options passed through
+// to the container engine
+func getBuildOptionArguments() (args []string) {
+	// check viper configuration if not set
+	if Container == "" && viper.GetString("container") != "" {
+		Container = viper.GetString("container")
+	}
+
+	if Container != "" {
+		args = append(args, "--build-arg", fmt.Sprintf("RUNIAC_CONTAINER=%s", Container))
+	}
+
+	for _, arg := range BuildArgs {
+		args = append(args, "--build-arg", arg)
+	}
+
+	if BuildPlatform != "" {
+		args = append(args, "--platform", BuildPlatform)
+	}
+
+	if BuildTarget != "" {
+		args = append(args, "--target", BuildTarget)
+	}
+
+	if BuildNoCache {
+		args = append(args, "--no-cache")
+	}
+
+	return
+}
+
+// tagImage tags the built project container as the image
+func tagImage(containerTag string, image string) error {
+	return runContainerEngine(fmt.Sprintf("tagging %s as %s failed", containerTag, image), "tag", containerTag, image)
+}
+
+// pushImage pushes the image to its registry
+func pushImage(image string) error {
+	err := runContainerEngine(fmt.Sprintf("pushing %s failed", image), "push", image)
+	if err == nil {
+		logrus.Infof("Pushed %s", image)
+	}
+
+	return err
+}
+
+// runContainerEngine runs the container engine with the arguments, logging its output when it fails
+func runContainerEngine(failure string, args ...string) error {
+	cmd := exec.Command(ContainerEngine, args...)
+
+	logrus.Info(strings.Join(maskArgs(cmd.Args), " "))
+
+	if b, err := cmd.CombinedOutput(); err != nil {
+		logrus.Error(string(b))
+		return fmt.Errorf("%s: %w", failure, err)
+	}
+
+	return nil
+}
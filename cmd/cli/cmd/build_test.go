@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"os"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPushImage_ShouldFailWhenTheContainerEngineFails(t *testing.T) {
+	defer func(engine string) { ContainerEngine = engine }(ContainerEngine)
+
+	ContainerEngine = "true"
+	require.NoError(t, tagImage("project", "registry.stub/team/project:1.0.0"))
+	require.NoError(t, pushImage("registry.stub/team/project:1.0.0"))
+
+	ContainerEngine = "false"
+	require.EqualError(t, tagImage("project", "registry.stub/team/project:1.0.0"), "tagging project as registry.stub/team/project:1.0.0 failed: exit status 1")
+	require.EqualError(t, pushImage("registry.stub/team/project:1.0.0"), "pushing registry.stub/team/project:1.0.0 failed: exit status 1")
+}
+
+func TestGetBuildArguments_ShouldPassBuildOptionsBeforeContext(t *testing.T) {
+	defer func() {
+		Container, BuildArgs, BuildPlatform, BuildTarget, BuildNoCache = "", []string{}, "", "", false
+	}()
+
+	Container, BuildArgs, BuildPlatform, BuildTarget, BuildNoCache = "base", []string{"VERSION=1.0", "TOKEN"}, "linux/arm64", "deploy", true
+
+	require.Equal(t, []string{
+		"--build-arg", "RUNIAC_CONTAINER=base",
+		"--build-arg", "VERSION=1.0",
+		"--build-arg", "TOKEN",
+		"--platform", "linux/arm64",
+		"--target", "deploy",
+		"--no-cache",
+		".",
+	}, getBuildArguments())
+}
+
+func TestGetSourceHash_ShouldChangeWithTheBuildInputs(t *testing.T) {
+	defer func() { Container, BuildArgs = "", []string{} }()
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, ".runiac/Dockerfile", []byte("FROM runiac/deploy\n"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "tracks/network/main.tf", []byte("# network\n"), 0644))
+	require.NoError(t, afero.WriteFile(fs, ".runiac/logs/run.log", []byte("ignored"), 0644))
+
+	hash := func() string {
+		h, err := getSourceHash(fs, "", ".runiac/Dockerfile")
+		require.NoError(t, err)
+		return h
+	}
+
+	initial := hash()
+	require.Equal(t, initial, hash(), "the hash should be stable")
+
+	require.NoError(t, afero.WriteFile(fs, ".runiac/logs/run.log", []byte("still ignored"), 0644))
+	require.Equal(t, initial, hash(), "runiac's local data is not a build input")
+
+	require.NoError(t, afero.WriteFile(fs, ".runiac/Dockerfile", []byte("FROM runiac/deploy:pinned\n"), 0644))
+	dockerfileChanged := hash()
+	require.NotEqual(t, initial, dockerfileChanged)
+
+	BuildArgs = []string{"STUB_BUILD_ARG"}
+	_ = os.Setenv("STUB_BUILD_ARG", "a")
+	defer os.Unsetenv("STUB_BUILD_ARG")
+
+	buildArgA := hash()
+	require.NotEqual(t, dockerfileChanged, buildArgA)
+
+	_ = os.Setenv("STUB_BUILD_ARG", "b")
+	require.NotEqual(t, buildArgA, hash(), "the host's value of a build argument is a build input")
+
+	require.NoError(t, afero.WriteFile(fs, "tracks/network/main.tf", []byte("# network v2\n"), 0644))
+	require.NotEqual(t, buildArgA, hash())
+}
+
+func TestIsContainerCurrent_ShouldReuseContainerOfTheSameSource(t *testing.T) {
+	defer func(get func(string) string) {
+		getImageSourceHash, BuildNoCache = get, false
+	}(getImageSourceHash)
+
+	getImageSourceHash = func(image string) string {
+		if image == "project" {
+			return "abc"
+		}
+		return ""
+	}
+
+	require.True(t, isContainerCurrent("project", "abc"))
+	require.False(t, isContainerCurrent("project", "def"), "the source changed")
+	require.False(t, isContainerCurrent("project", ""), "the source could not be hashed")
+	require.False(t, isContainerCurrent("other", "abc"), "the container was not built")
+
+	BuildNoCache = true
+	require.False(t, isContainerCurrent("project", "abc"), "--no-cache always rebuilds")
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+// stdinBuildContext is the docker build context argument reading the context from stdin
+const stdinBuildContext = "-"
+
+// getBuildContext returns the build context argument, the working directory unless a tarball context is used
+func getBuildContext(contextTar string) string {
+	if contextTar != "" {
+		return stdinBuildContext
+	}
+
+	return "."
+}
+
+// getBuildContextFs returns a read only view of the project for inspecting which tracks and dockerfiles exist.
+// For a tarball context only the file names are read, file contents are empty.
+func getBuildContextFs(contextTar string) (afero.Fs, error) {
+	if contextTar == "" {
+		return afero.NewReadOnlyFs(appFS), nil
+	}
+
+	f, err := os.Open(contextTar)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open build context: %w", err)
+	}
+	defer f.Close()
+
+	r, err := getTarReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read build context %s: %w", contextTar, err)
+	}
+
+	fs := afero.NewMemMapFs()
+
+	for {
+		header, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("unable to read build context %s: %w", contextTar, err)
+		}
+
+		name := path.Clean(strings.TrimPrefix(header.Name, "./"))
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = fs.MkdirAll(name, 0755)
+		default:
+			err = afero.WriteFile(fs, name, []byte{}, 0644)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return afero.NewReadOnlyFs(fs), nil
+}
+
+// getTarReader returns a reader for a plain or gzip compressed tarball
+func getTarReader(r io.Reader) (*tar.Reader, error) {
+	br := bufio.NewReader(r)
+
+	magic, err := br.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+
+	if magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+
+		return tar.NewReader(gz), nil
+	}
+
+	return tar.NewReader(br), nil
+}
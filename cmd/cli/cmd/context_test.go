@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestTar(t *testing.T, w io.Writer, files []string) {
+	tw := tar.NewWriter(w)
+
+	for _, name := range files {
+		body := []byte("stub")
+		err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(body)), Typeflag: tar.TypeReg})
+		require.NoError(t, err)
+
+		_, err = tw.Write(body)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, tw.Close())
+}
+
+func TestGetBuildContextFs_ShouldListTarballEntries(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{"./.runiac/Dockerfile", "./tracks/network/step1_vnet/main.tf", "tracks/network/step2_peering/main.tf"}
+
+	plain, err := os.Create(filepath.Join(dir, "context.tar"))
+	require.NoError(t, err)
+	writeTestTar(t, plain, files)
+	require.NoError(t, plain.Close())
+
+	compressed, err := os.Create(filepath.Join(dir, "context.tar.gz"))
+	require.NoError(t, err)
+	gz := gzip.NewWriter(compressed)
+	writeTestTar(t, gz, files)
+	require.NoError(t, gz.Close())
+	require.NoError(t, compressed.Close())
+
+	for _, contextTar := range []string{plain.Name(), compressed.Name()} {
+		fs, err := getBuildContextFs(contextTar)
+		require.NoError(t, err)
+
+		exists, _ := afero.Exists(fs, ".runiac/Dockerfile")
+		require.True(t, exists, "dockerfile should be found in %s", contextTar)
+
+		steps, err := getTrackStepIDs(fs, []string{"network"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"network/peering", "network/vnet"}, steps)
+	}
+
+	_, err = getBuildContextFs(filepath.Join(dir, "missing.tar"))
+	require.Error(t, err)
+}
+
+func TestGetBuildContext_ShouldReadTarballFromStdin(t *testing.T) {
+	require.Equal(t, ".", getBuildContext(""))
+	require.Equal(t, "-", getBuildContext("context.tar"))
+}
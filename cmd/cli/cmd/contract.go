@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+const contractVersion = 1 // The RUNIAC_* contract version implemented by this CLI, see config.ContractVersion
+
+// containerContract is the environment variable contract reported by the deploy container's runiac contract
+type containerContract struct {
+	Version       int      `json:"version"`
+	RuniacVersion string   `json:"runiac_version"` // Empty for a container of a development build or predating its report
+	Variables     []string `json:"variables"`
+	Required      []string `json:"required"`
+}
+
+// getContainerContract queries the container for its contract. The project's runiac config is deliberately not
+// available to the query, so a container predating runiac contract fails its config validation instead of deploying.
+func getContainerContract(containerTag string) (containerContract, error) {
+	contract := containerContract{}
+
+	out, err := exec.Command(ContainerEngine, "run", "--rm", "--entrypoint", "runiac", "-w", "/", "-e", "RUNIAC_DRY_RUN=true", containerTag, "contract").Output()
+	if err != nil {
+		return contract, fmt.Errorf("container %s does not report its RUNIAC_* contract, it is likely older than this CLI: %w", containerTag, err)
+	}
+
+	err = json.Unmarshal(out, &contract)
+	if err != nil {
+		return contract, fmt.Errorf("container %s reported an invalid RUNIAC_* contract: %w", containerTag, err)
+	}
+
+	return contract, nil
+}
+
+// getContractEnv returns the names of the RUNIAC_* variables runiac sends to the container, excluding the
+// variables passed through from the host environment which are not set by runiac
+func getContractEnv(runArgs []string, passthrough []string) []string {
+	host := map[string]bool{}
+	for _, e := range passthrough {
+		host[e] = true
+	}
+
+	names := []string{}
+
+	for _, e := range getEnvFromArgs(runArgs) {
+		if host[e] || !strings.HasPrefix(e, "RUNIAC_") {
+			continue
+		}
+
+		names = append(names, strings.SplitN(e, "=", 2)[0])
+	}
+
+	return names
+}
+
+// getContractMismatches returns the problems found comparing the variables runiac sends with the container's
+// contract. A required variable is satisfied by the runiac config file as well.
+func getContractMismatches(contract containerContract, sent []string, isConfigured func(key string) bool) []string {
+	mismatches := []string{}
+
+	if contract.Version != contractVersion {
+		mismatches = append(mismatches, fmt.Sprintf("the container implements contract version %d, this CLI implements version %d", contract.Version, contractVersion))
+	}
+
+	understood := map[string]bool{}
+	for _, v := range contract.Variables {
+		understood[v] = true
+	}
+
+	isSent := map[string]bool{}
+
+	for _, name := range sent {
+		isSent[name] = true
+
+		if !understood[name] {
+			mismatches = append(mismatches, fmt.Sprintf("the container does not understand %s", name))
+		}
+	}
+
+	for _, name := range contract.Required {
+		if !isSent[name] && !isConfigured(strings.ToLower(strings.TrimPrefix(name, "RUNIAC_"))) {
+			mismatches = append(mismatches, fmt.Sprintf("the container requires %s which is not set", name))
+		}
+	}
+
+	sort.Strings(mismatches)
+
+	return mismatches
+}
+
+// checkContainerContract warns about every contract mismatch, failing instead when strict
+func checkContainerContract(contract containerContract, runArgs []string, strict bool) error {
+	mismatches := getContractMismatches(contract, getContractEnv(runArgs, getPassthroughEnv()), viper.IsSet)
+
+	if len(mismatches) == 0 {
+		return nil
+	}
+
+	if strict {
+		return fmt.Errorf("the CLI and the deploy container are incompatible: %s", strings.Join(mismatches, ", "))
+	}
+
+	for _, m := range mismatches {
+		logrus.Warnf("CLI and deploy container mismatch: %s", m)
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetContractEnv_ShouldIgnoreHostPassthrough(t *testing.T) {
+	runArgs := []string{"-e", "RUNIAC_ENVIRONMENT=prod", "-e", "TF_VAR_name=stub", "-e", "RUNIAC_PROFILE=ci", "-v", "/host:/container"}
+
+	require.Equal(t, []string{"RUNIAC_ENVIRONMENT"}, getContractEnv(runArgs, []string{"RUNIAC_PROFILE=ci"}))
+}
+
+func TestGetContractMismatches_ShouldReportUnknownAndMissingVariables(t *testing.T) {
+	contract := containerContract{
+		Version:   contractVersion,
+		Variables: []string{"RUNIAC_ENVIRONMENT", "RUNIAC_PRIMARY_REGION"},
+		Required:  []string{"RUNIAC_ENVIRONMENT", "RUNIAC_PRIMARY_REGION"},
+	}
+
+	notConfigured := func(string) bool { return false }
+
+	require.Empty(t, getContractMismatches(contract, []string{"RUNIAC_ENVIRONMENT", "RUNIAC_PRIMARY_REGION"}, notConfigured))
+
+	require.Equal(t, []string{
+		"the container does not understand RUNIAC_SIMULATE_IAM",
+		"the container requires RUNIAC_PRIMARY_REGION which is not set",
+	}, getContractMismatches(contract, []string{"RUNIAC_ENVIRONMENT", "RUNIAC_SIMULATE_IAM"}, notConfigured))
+
+	// a required variable may be set in the runiac config file
+	require.Empty(t, getContractMismatches(contract, []string{"RUNIAC_ENVIRONMENT"}, func(key string) bool { return key == "primary_region" }))
+
+	contract.Version = contractVersion + 1
+	require.Len(t, getContractMismatches(contract, []string{"RUNIAC_ENVIRONMENT", "RUNIAC_PRIMARY_REGION"}, notConfigured), 1)
+}
+
+func TestGetRunConfigArguments_ShouldMatchContainerContract(t *testing.T) {
+	c := config.GetContract()
+	contract := containerContract{Version: c.Version, Variables: c.Variables, Required: c.Required}
+
+	Environment = "prod"
+	PrimaryRegions = []string{"us-east-1"}
+	RegionalRegions = []string{"us-east-1"}
+	PlanThreshold = 10
+	Confirm = true
+	PrePullProviders = true
+	SimulateIAM = true
+	ParallelPrimary = true
+	PauseTracks = true
+	Interactive = true
+	ApprovalURL = "https://approvals.example.com"
+	RunnerArgs = []string{"--refresh"}
+	defer func() {
+		Environment, PrimaryRegions, RegionalRegions, RunnerArgs = "", []string{}, []string{}, []string{}
+		PlanThreshold, Confirm, PrePullProviders, SimulateIAM, ParallelPrimary = 0, false, false, false, false
+		PauseTracks, Interactive, ApprovalURL = false, false, ""
+	}()
+
+	sent := getContractEnv(getRunConfigArguments("1234"), nil)
+
+	require.Empty(t, getContractMismatches(contract, sent, func(string) bool { return false }))
+}
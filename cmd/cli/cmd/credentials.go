@@ -0,0 +1,374 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+// The clouds whose credentials --mount-credentials passes to the container
+const (
+	credentialsAWS   = "aws"
+	credentialsAzure = "azure"
+	credentialsGCP   = "gcp"
+	credentialsNone  = "none" // No credentials are passed, e.g. when the steps authenticate with env variables only
+)
+
+var supportedCredentials = []string{credentialsAWS, credentialsAzure, credentialsGCP}
+
+const (
+	containerCredentialsDir = "/runiac/credentials" // Credential files outside of the cloud clis' config directories
+	credentialTokensDir     = ".runiac/credentials" // The short-lived tokens of each run, one directory per run id
+)
+
+// credentialProviders are the terraform providers authenticating with the credentials of each cloud
+var credentialProviders = map[string]string{
+	"aws":         credentialsAWS,
+	"azurerm":     credentialsAzure,
+	"azuread":     credentialsAzure,
+	"azapi":       credentialsAzure,
+	"google":      credentialsGCP,
+	"google-beta": credentialsGCP,
+}
+
+// terraformProviderPattern matches provider blocks and the hashicorp sources of required_providers
+var terraformProviderPattern = regexp.MustCompile(`provider\s+"([a-z-]+)"|source\s*=\s*"(?:registry\.terraform\.io/)?hashicorp/([a-z-]+)"`)
+
+// awsTokenEnv are the env variables of the short-lived aws credentials exported by the aws cli
+var awsTokenEnv = map[string]bool{
+	"AWS_ACCESS_KEY_ID":         true,
+	"AWS_SECRET_ACCESS_KEY":     true,
+	"AWS_SESSION_TOKEN":         true,
+	"AWS_CREDENTIAL_EXPIRATION": true,
+}
+
+// credentialFileEnv are the env variables locating credential files on the host, not forwarded into the container
+// since their host paths are mounted at other container paths
+var credentialFileEnv = []string{"AWS_SHARED_CREDENTIALS_FILE", "AWS_CONFIG_FILE", "AWS_WEB_IDENTITY_TOKEN_FILE", "ARM_OIDC_TOKEN_FILE_PATH", "GOOGLE_APPLICATION_CREDENTIALS", "CLOUDSDK_CONFIG"}
+
+// credentialClouds are the clouds whose credentials are passed to the container, resolved before deploying
+var credentialClouds []string
+
+// azureTokenFiles are the files of the azure cli's config directory holding its logged in accounts and tokens
+var azureTokenFiles = []string{"azureProfile.json", "msal_token_cache.json"}
+
+// runCredentialHelper runs a cloud cli on the host and returns its output, a variable so tests do not need the clis
+var runCredentialHelper = func(name string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+
+	cmd := exec.Command(name, args...)
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s %s failed: %w %s", name, strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+
+	return out, nil
+}
+
+// resolveCredentialClouds returns the clouds whose credentials are passed to the container, the clouds of
+// --mount-credentials or, when not set, the clouds the steps target. Every cloud when no cloud is detected.
+func resolveCredentialClouds(fs afero.Fs, mount []string, runner string) ([]string, error) {
+	if len(mount) == 0 {
+		clouds, err := detectCredentialClouds(fs, runner)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(clouds) == 0 {
+			return supportedCredentials, nil
+		}
+
+		logrus.Debugf("Passing the credentials of the clouds the steps target: %s", strings.Join(clouds, ", "))
+
+		return clouds, nil
+	}
+
+	selected := map[string]bool{}
+
+	for _, cloud := range mount {
+		cloud = strings.ToLower(strings.TrimSpace(cloud))
+
+		if cloud == credentialsNone {
+			if len(mount) > 1 {
+				return nil, fmt.Errorf("--mount-credentials %s can not be combined with other clouds", credentialsNone)
+			}
+
+			return []string{}, nil
+		}
+
+		if !isStringInSlice(cloud, supportedCredentials) {
+			return nil, fmt.Errorf("invalid --mount-credentials '%s', must be one of %s or %s", cloud, strings.Join(supportedCredentials, ", "), credentialsNone)
+		}
+
+		selected[cloud] = true
+	}
+
+	clouds := []string{}
+
+	for _, cloud := range supportedCredentials {
+		if selected[cloud] {
+			clouds = append(clouds, cloud)
+		}
+	}
+
+	return clouds, nil
+}
+
+// detectCredentialClouds returns the clouds the steps target by the terraform providers of the tracks, the arm
+// runner always targets azure
+func detectCredentialClouds(fs afero.Fs, runner string) ([]string, error) {
+	detected := map[string]bool{}
+
+	if runner == "arm" {
+		detected[credentialsAzure] = true
+	}
+
+	if exists, _ := afero.DirExists(fs, "tracks"); exists {
+		err := afero.Walk(fs, "tracks", func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() || filepath.Ext(path) != ".tf" {
+				return err
+			}
+
+			b, err := afero.ReadFile(fs, path)
+			if err != nil {
+				return err
+			}
+
+			for _, m := range terraformProviderPattern.FindAllStringSubmatch(string(b), -1) {
+				if cloud, ok := credentialProviders[m[1]+m[2]]; ok {
+					detected[cloud] = true
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	clouds := []string{}
+
+	for _, cloud := range supportedCredentials {
+		if detected[cloud] {
+			clouds = append(clouds, cloud)
+		}
+	}
+
+	return clouds, nil
+}
+
+// getCredentialArguments returns the container run arguments passing the credentials of the clouds. The cloud clis'
+// config directories persist in the .runiac directory of dir unless the host's config location is set, e.g.
+// AZURE_CONFIG_DIR or AWS_SHARED_CREDENTIALS_FILE. With tokens, short-lived tokens of the host's cloud clis are
+// passed instead, the azure cli's token cache is copied to the run's token directory.
+func getCredentialArguments(dir string, clouds []string, tokens bool, tokenDir string) (args []string, err error) {
+	// the run context is printed without a token directory
+	gcpDir := ""
+	if tokenDir != "" {
+		gcpDir = filepath.Join(tokenDir, credentialsGCP)
+	}
+
+	for _, cloud := range clouds {
+		var cloudArgs []string
+
+		switch {
+		case cloud == credentialsAWS && tokens:
+			cloudArgs, err = getAWSTokenArguments()
+		case cloud == credentialsAWS:
+			cloudArgs, err = getAWSCredentialArguments(dir)
+		case cloud == credentialsAzure && tokens:
+			cloudArgs, err = getAzureTokenArguments(filepath.Join(tokenDir, credentialsAzure))
+		case cloud == credentialsAzure:
+			cloudArgs, err = getConfigDirArguments("AZURE_CONFIG_DIR", filepath.Join(dir, ".runiac", ".azure"), "/root/.azure")
+		case cloud == credentialsGCP && tokens:
+			cloudArgs, err = getGCPTokenArguments()
+		case cloud == credentialsGCP:
+			cloudArgs, err = getGCPCredentialArguments(dir, gcpDir)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		args = append(args, cloudArgs...)
+	}
+
+	return
+}
+
+// getConfigDirArguments returns the volume map of a cloud cli's config directory, the directory of the host's env
+// variable when set and otherwise the project's local directory
+func getConfigDirArguments(envName string, localDir string, containerDir string) ([]string, error) {
+	dir := os.Getenv(envName)
+	if dir == "" {
+		dir = localDir
+	}
+
+	path, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return getVolumeArguments(path, containerDir, false), nil
+}
+
+// getCredentialFileArguments returns the container run arguments mounting the credential file of the host's env
+// variable read-only, passing its container path as the env variable. None when the env variable is not set.
+func getCredentialFileArguments(envName string, cloud string) ([]string, error) {
+	file := os.Getenv(envName)
+	if file == "" {
+		return nil, nil
+	}
+
+	args, containerPath, err := getCredentialFileMount(file, cloud)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s '%s': %w", envName, file, err)
+	}
+
+	return append(args, "-e", fmt.Sprintf("%s=%s", envName, containerPath)), nil
+}
+
+// getCredentialFileMount returns the volume map mounting the credential file read-only in the cloud's credentials
+// directory of the container and the file's container path
+func getCredentialFileMount(file string, cloud string) (args []string, containerPath string, err error) {
+	path, err := filepath.Abs(file)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		return nil, "", err
+	}
+
+	containerPath = fmt.Sprintf("%s/%s/%s", containerCredentialsDir, cloud, filepath.Base(path))
+
+	return getVolumeArguments(path, containerPath, true), containerPath, nil
+}
+
+func getAWSCredentialArguments(dir string) ([]string, error) {
+	// the aws cli has no config directory variable, only the locations of its files
+	args := getVolumeArguments(filepath.Join(dir, ".runiac", ".aws"), "/root/.aws", false)
+
+	for _, envName := range []string{"AWS_SHARED_CREDENTIALS_FILE", "AWS_CONFIG_FILE"} {
+		fileArgs, err := getCredentialFileArguments(envName, credentialsAWS)
+		if err != nil {
+			return nil, err
+		}
+
+		args = append(args, fileArgs...)
+	}
+
+	return args, nil
+}
+
+func getGCPCredentialArguments(dir string, tokenDir string) ([]string, error) {
+	args, err := getConfigDirArguments("CLOUDSDK_CONFIG", filepath.Join(dir, ".runiac", ".config", "gcloud"), "/root/.config/gcloud")
+	if err != nil {
+		return nil, err
+	}
+
+	fileArgs, err := getGCPApplicationCredentialArguments(tokenDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(args, fileArgs...), nil
+}
+
+// getAWSTokenArguments returns the short-lived credentials of the aws cli's profile as env variables, including the
+// credentials of an aws sso login
+func getAWSTokenArguments() (args []string, err error) {
+	out, err := runCredentialHelper("aws", "configure", "export-credentials", "--format", "env-no-export")
+	if err != nil {
+		return nil, fmt.Errorf("unable to export the aws credentials, log in with 'aws sso login' or configure the profile: %w", err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+
+	for scanner.Scan() {
+		kv := strings.SplitN(strings.TrimSpace(scanner.Text()), "=", 2)
+
+		if len(kv) == 2 && awsTokenEnv[kv[0]] {
+			args = append(args, "-e", fmt.Sprintf("%s=%s", kv[0], kv[1]))
+		}
+	}
+
+	if len(args) == 0 {
+		return nil, errors.New("the aws cli exported no credentials")
+	}
+
+	return args, nil
+}
+
+// getGCPTokenArguments returns a short-lived access token of the application default credentials, or of the
+// --impersonate-service-account, as the env variable the google provider authenticates with
+func getGCPTokenArguments() ([]string, error) {
+	args := []string{"auth", "application-default", "print-access-token"}
+
+	// only the access token of gcloud's credentials can be requested for an impersonated service account
+	if GCPImpersonate != "" {
+		args = []string{"auth", "print-access-token", "--impersonate-service-account", GCPImpersonate}
+	}
+
+	out, err := runCredentialHelper("gcloud", args...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get an application default credentials token, log in with 'gcloud auth application-default login': %w", err)
+	}
+
+	return []string{"-e", fmt.Sprintf("GOOGLE_OAUTH_ACCESS_TOKEN=%s", strings.TrimSpace(string(out)))}, nil
+}
+
+// getAzureTokenArguments copies the logged in accounts and the token cache of the host's azure cli to dir and
+// returns its volume map, so the container uses the host's login without the rest of its config directory
+func getAzureTokenArguments(dir string) ([]string, error) {
+	hostDir := os.Getenv("AZURE_CONFIG_DIR")
+
+	if hostDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+
+		hostDir = filepath.Join(home, ".azure")
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	for _, name := range azureTokenFiles {
+		b, err := ioutil.ReadFile(filepath.Join(hostDir, name))
+		if err != nil {
+			return nil, fmt.Errorf("no azure cli token cache in %s, log in with 'az login': %w", hostDir, err)
+		}
+
+		if err = ioutil.WriteFile(filepath.Join(dir, name), b, 0600); err != nil {
+			return nil, err
+		}
+	}
+
+	path, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return getVolumeArguments(path, "/root/.azure", false), nil
+}
+
+// getCredentialTokensDir returns the directory of the short-lived tokens of the run
+func getCredentialTokensDir(runID string) string {
+	return filepath.Join(credentialTokensDir, runID)
+}
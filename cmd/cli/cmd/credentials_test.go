@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveCredentialClouds_ShouldDetectTheCloudsOfTheProviders(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	clouds, err := resolveCredentialClouds(fs, nil, "terraform")
+	require.NoError(t, err)
+	require.Equal(t, supportedCredentials, clouds, "every cloud should be passed when none is detected")
+
+	require.NoError(t, afero.WriteFile(fs, "tracks/network/step1_vpc/main.tf", []byte(`provider "aws" {
+  region = var.runiac_region
+}`), 0644))
+	require.NoError(t, afero.WriteFile(fs, "tracks/network/step2_dns/versions.tf", []byte(`terraform {
+  required_providers {
+    google = {
+      source = "hashicorp/google-beta"
+    }
+  }
+}`), 0644))
+	require.NoError(t, afero.WriteFile(fs, "tracks/network/step2_dns/README.md", []byte(`provider "azurerm"`), 0644))
+
+	clouds, err = resolveCredentialClouds(fs, nil, "terraform")
+	require.NoError(t, err)
+	require.Equal(t, []string{credentialsAWS, credentialsGCP}, clouds)
+
+	clouds, err = resolveCredentialClouds(fs, nil, "arm")
+	require.NoError(t, err)
+	require.Equal(t, []string{credentialsAWS, credentialsAzure, credentialsGCP}, clouds)
+}
+
+func TestResolveCredentialClouds_ShouldValidateExplicitClouds(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "tracks/network/step1_vpc/main.tf", []byte(`provider "aws" {}`), 0644))
+
+	clouds, err := resolveCredentialClouds(fs, []string{"GCP", " azure"}, "terraform")
+	require.NoError(t, err)
+	require.Equal(t, []string{credentialsAzure, credentialsGCP}, clouds)
+
+	clouds, err = resolveCredentialClouds(fs, []string{credentialsNone}, "terraform")
+	require.NoError(t, err)
+	require.Empty(t, clouds)
+
+	_, err = resolveCredentialClouds(fs, []string{credentialsNone, credentialsAWS}, "terraform")
+	require.EqualError(t, err, "--mount-credentials none can not be combined with other clouds")
+
+	_, err = resolveCredentialClouds(fs, []string{"oci"}, "terraform")
+	require.EqualError(t, err, "invalid --mount-credentials 'oci', must be one of aws, azure, gcp or none")
+}
+
+func TestGetCredentialArguments_ShouldHonorCustomCredentialLocations(t *testing.T) {
+	hostDir := t.TempDir()
+	credentials := filepath.Join(hostDir, "aws-credentials")
+	require.NoError(t, ioutil.WriteFile(credentials, []byte("[default]\n"), 0600))
+
+	args, err := getCredentialArguments("/home/stub/project", supportedCredentials, false, "")
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"-v", "/home/stub/project/.runiac/.aws:/root/.aws",
+		"-v", "/home/stub/project/.runiac/.azure:/root/.azure",
+		"-v", "/home/stub/project/.runiac/.config/gcloud:/root/.config/gcloud",
+	}, args)
+
+	_ = os.Setenv("AWS_SHARED_CREDENTIALS_FILE", credentials)
+	defer os.Unsetenv("AWS_SHARED_CREDENTIALS_FILE")
+	_ = os.Setenv("AZURE_CONFIG_DIR", filepath.Join(hostDir, "azure"))
+	defer os.Unsetenv("AZURE_CONFIG_DIR")
+
+	args, err = getCredentialArguments("/home/stub/project", []string{credentialsAWS, credentialsAzure}, false, "")
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"-v", "/home/stub/project/.runiac/.aws:/root/.aws",
+		"-v", credentials + ":/runiac/credentials/aws/aws-credentials:ro",
+		"-e", "AWS_SHARED_CREDENTIALS_FILE=/runiac/credentials/aws/aws-credentials",
+		"-v", filepath.Join(hostDir, "azure") + ":/root/.azure",
+	}, args)
+
+	_ = os.Setenv("GOOGLE_APPLICATION_CREDENTIALS", filepath.Join(hostDir, "missing.json"))
+	defer os.Unsetenv("GOOGLE_APPLICATION_CREDENTIALS")
+
+	_, err = getCredentialArguments("/home/stub/project", []string{credentialsGCP}, false, "")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid GOOGLE_APPLICATION_CREDENTIALS")
+}
+
+func TestGetCredentialArguments_ShouldPassShortLivedTokens(t *testing.T) {
+	defer func(run func(string, ...string) ([]byte, error)) { runCredentialHelper = run }(runCredentialHelper)
+
+	var helpers []string
+
+	runCredentialHelper = func(name string, args ...string) ([]byte, error) {
+		helpers = append(helpers, name+" "+strings.Join(args, " "))
+
+		if name == "aws" {
+			return []byte("AWS_ACCESS_KEY_ID=AKIASTUB\nAWS_SECRET_ACCESS_KEY=secret\nAWS_SESSION_TOKEN=session\nAWS_REGION=us-east-1\n"), nil
+		}
+
+		return []byte("ya29.stub\n"), nil
+	}
+
+	hostDir, tokenDir := t.TempDir(), filepath.Join(t.TempDir(), "0123abcdef")
+
+	_ = os.Setenv("AZURE_CONFIG_DIR", hostDir)
+	defer os.Unsetenv("AZURE_CONFIG_DIR")
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(hostDir, "azureProfile.json"), []byte("{}"), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(hostDir, "config"), []byte("[core]\n"), 0600))
+
+	_, err := getCredentialArguments("/home/stub/project", []string{credentialsAzure}, true, tokenDir)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "az login")
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(hostDir, "msal_token_cache.json"), []byte("{}"), 0600))
+
+	args, err := getCredentialArguments("/home/stub/project", supportedCredentials, true, tokenDir)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"-e", "AWS_ACCESS_KEY_ID=AKIASTUB",
+		"-e", "AWS_SECRET_ACCESS_KEY=secret",
+		"-e", "AWS_SESSION_TOKEN=session",
+		"-v", filepath.Join(tokenDir, "azure") + ":/root/.azure",
+		"-e", "GOOGLE_OAUTH_ACCESS_TOKEN=ya29.stub",
+	}, args)
+
+	require.Equal(t, []string{
+		"aws configure export-credentials --format env-no-export",
+		"gcloud auth application-default print-access-token",
+	}, helpers)
+
+	copied, err := ioutil.ReadDir(filepath.Join(tokenDir, "azure"))
+	require.NoError(t, err)
+	require.Len(t, copied, 2, "only the accounts and the token cache should be copied")
+
+	runCredentialHelper = func(name string, args ...string) ([]byte, error) { return nil, errors.New("not logged in") }
+
+	_, err = getCredentialArguments("/home/stub/project", []string{credentialsAWS}, true, tokenDir)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "aws sso login")
+}
+
+func TestGetPassthroughEnv_ShouldNotForwardHostCredentialFiles(t *testing.T) {
+	_ = os.Setenv("AWS_SHARED_CREDENTIALS_FILE", "/home/stub/.aws/credentials")
+	defer os.Unsetenv("AWS_SHARED_CREDENTIALS_FILE")
+	_ = os.Setenv("AWS_PROFILE", "deployer")
+	defer os.Unsetenv("AWS_PROFILE")
+
+	env := getPassthroughEnv()
+
+	require.Contains(t, env, "AWS_PROFILE=deployer")
+	require.NotContains(t, env, "AWS_SHARED_CREDENTIALS_FILE=/home/stub/.aws/credentials")
+}
@@ -2,234 +2,1798 @@ package cmd
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
+	"net/http"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
 
-	"github.com/briandowns/spinner"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 var (
-	AppVersion      string
-	Environment     string
-	PrimaryRegions  []string
-	RegionalRegions []string
-	DryRun          bool
-	SelfDestroy     bool
-	Account         string
-	LogLevel        string
-	Interactive     bool
-	Container       string = "docker.io/runiac/deploy:latest-alpine-full"
-	Namespace       string
-	DeploymentRing  string
-	Local           bool
-	Runner          string
-	PullRequest     string
-	StepWhitelist   []string
-	Dockerfile      string = ".runiac/Dockerfile"
-	ContainerEngine string = "docker"
-	Test            bool   = false
+	AppVersion       string
+	Environment      string
+	PrimaryRegions   []string
+	RegionalRegions  []string
+	DryRun           bool
+	SelfDestroy      bool
+	Destroy          bool
+	Account          string
+	LogLevel         string
+	LogFormat        string
+	RunnerLogLevel   string
+	Interactive      bool
+	Record           string
+	UI               string
+	ApprovalURL      string
+	ApprovalReason   string
+	ApprovalTimeout  time.Duration
+	PauseTracks      bool
+	Detach           bool
+	MaxLogSize       string
+	RestartPolicy    string
+	PidMode          string
+	IpcMode          string
+	DockerSocket     string
+	MigrateStateTo   string
+	MountDocker      bool
+	ShowResolvedVars bool
+	PrintContext     bool
+	Container        string = "docker.io/runiac/deploy:latest-alpine-full"
+	ContainerDigest  string
+	Namespace        string
+	NsRegistry       string
+	DeploymentRing   string
+	DeploymentRings  []string
+	Local            bool
+	Runner           string
+	RunnerArgs       []string
+	Features         []string
+	EnvPrefixes      []string
+	ExtraEnv         []string
+	PullRequest      string
+	StepWhitelist    []string
+	TeardownOrder    []string
+	TrackWhitelist   []string
+	ConfirmDestroy   []string
+	Confirm          bool
+	PrePullProviders bool
+	PlanThreshold    int
+	OutputDir        string
+	ExportManifest   string
+	Sarif            string
+	ReportPath       string
+	Plugins          []string
+	BuildContext     string
+	OnlyChanged      bool
+	ParallelPrimary  bool
+	SimulateIAM      bool
+	CostEstimate     bool
+	Policy           string
+	BreakGlass       string
+	ValidateContract bool
+	StrictContract   bool
+	VerifySignature  bool
+	SigKey           string
+	SigIdentity      string
+	SigIssuer        string
+	AbortThreshold   int
+	MaxParallel      int
+	StepTimeout      time.Duration
+	StepRetries      int
+	StepRetryBackoff time.Duration
+	TestRollback     bool
+	RollbackOnFail   bool
+	Resume           bool
+	FromStep         string
+	ArtifactsFrom    string
+	UseSavedPlans    bool
+	PlanSigningKey   string
+	GitHubComment    bool
+	SinceRef         string
+	SkipSteps        []string
+	StepTags         []string
+	SkipTags         []string
+	ContainerEngine  string
+	Native           bool
+	NativeExecutor   string
+	ExecutionTarget  string
+	Image            string
+	BuildNoCache     bool
+	BuildArgs        []string
+	BuildPlatform    string
+	BuildTarget      string
+	KubeRegistry     string
+	KubeContext      string
+	KubeNamespace    string
+	KubeSvcAccount   string
+	MountCredentials []string
+	CredentialTokens bool
+	AzureTenant      string
+	AzureClientID    string
+	AzureTokenFile   string
+	GCPImpersonate   string
+	Why              string
+	Profile          string
+	Dockerfile       string = ".runiac/Dockerfile"
+	Test             bool   = false
 )
 
+// dockerSocket is the conventional docker socket, mounted by --mount-docker-socket when the engine has no other
+// socket and the socket's path in the container
+const dockerSocket = "/var/run/docker.sock"
+
+// defaultStepRetryBackoff is the deploy container's wait before the first retry of a failed step
+const defaultStepRetryBackoff = 30 * time.Second
+
+// supportedRunners are the deployment tools the runiac deploy container can execute steps with
+var supportedRunners = []string{"terraform", "arm", "pulumi", "terragrunt"}
+
+// validExternalRunner matches the names of external runner plugins, executing runiac-runner-{name} in the container
+var validExternalRunner = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// maxRunnerTypoDistance is the edit distance up to which an external runner name is a typo of a supported runner
+const maxRunnerTypoDistance = 2
+
+var lookPath = exec.LookPath
+
+// runOutput receives the output of building and running the container, stderr when stdout is reserved for
+// structured output such as runiac plan --output json
+var runOutput io.Writer = os.Stdout
+
 func init() {
 	deployCmd.Flags().StringVarP(&AppVersion, "version", "v", "", "Version of the iac code")
-	deployCmd.Flags().StringVarP(&Environment, "environment", "e", "", "Targeted environment")
+	deployCmd.Flags().StringVarP(&Environment, "environment", "e", "", "Targeted environment, the environments.{environment} overlay of the runiac config is merged over the base config")
 	deployCmd.Flags().StringVarP(&Account, "account", "a", "", "Targeted Cloud Account (ie. azure subscription, gcp project or aws account)")
-	deployCmd.Flags().StringArrayVarP(&PrimaryRegions, "primary-regions", "p", []string{}, "Primary regions")
-	deployCmd.Flags().StringArrayVarP(&RegionalRegions, "regional-regions", "r", []string{}, "Runiac will concurrently execute the ./regional directory across these regions setting the runiac_region input variable")
+	deployCmd.Flags().StringArrayVarP(&PrimaryRegions, "primary-regions", "p", []string{}, "Primary regions, defaults to rings.{ring}.primary_regions in the runiac config")
+	deployCmd.Flags().StringArrayVarP(&RegionalRegions, "regional-regions", "r", []string{}, "Runiac will concurrently execute the ./regional directory across these regions setting the runiac_region input variable, defaults to rings.{ring}.regional_regions in the runiac config")
 	deployCmd.Flags().BoolVar(&DryRun, "dry-run", false, "Dry Run")
 	deployCmd.Flags().BoolVar(&SelfDestroy, "self-destroy", false, "Teardown after running deploy")
+	deployCmd.Flags().StringSliceVar(&ConfirmDestroy, "confirm-destroy", []string{}, "Confirm destroying the deployment rings listed in the protected_rings configuration by providing the ring names. Every protected ring of a multi-ring destroy must be confirmed, can be repeated or comma separated")
 	deployCmd.Flags().StringVar(&LogLevel, "log-level", "", "Log level")
+	deployCmd.Flags().StringVar(&LogFormat, "log-format", "", "Log format of runiac and the deploy container, json for structured logs with track, step and region fields or text")
+	deployCmd.Flags().StringVar(&RunnerLogLevel, "runner-log-level", "", "Log level of the deployment tool inside the container, independent of runiac's log level (ie. TF_LOG for terraform)")
 	deployCmd.Flags().BoolVar(&Interactive, "interactive", false, "Run Docker container in interactive mode")
+	deployCmd.Flags().BoolVar(&Detach, "detach", false, "Run the container in the background and print the container ID")
+	deployCmd.Flags().StringVar(&MaxLogSize, "max-log-size", "", fmt.Sprintf("Rotate the host log capturing a detached container's output in %s once it exceeds this size, e.g. 10m", containerLogDir))
+	deployCmd.Flags().StringVar(&RestartPolicy, "restart", "", "Restart policy of a detached container (no, always, unless-stopped or on-failure[:max-retries])")
+	deployCmd.Flags().StringVar(&PidMode, "pid", "", "PID namespace of the container for debugging (host or container:<name|id>)")
+	deployCmd.Flags().StringVar(&IpcMode, "ipc", "", "IPC namespace of the container for debugging (none, private, shareable, host or container:<name|id>)")
+	deployCmd.Flags().StringVar(&DockerSocket, "docker-socket", "", "Mount the docker socket at this path of the host into the container for steps that build images. Grants the steps root equivalent access to the host")
+	deployCmd.Flags().BoolVar(&MountDocker, "mount-docker-socket", false, fmt.Sprintf("Mount the container engine's socket into the container for steps that build images, e.g. the socket of rootless podman or %s. Grants the steps root equivalent access to the host", dockerSocket))
+	deployCmd.Flags().BoolVar(&ShowResolvedVars, "show-resolved-vars", false, "Print the TF_VAR_* and RUNIAC_* values that would be passed to the container, grouped by source with secrets masked, and exit")
+	deployCmd.Flags().BoolVar(&PrintContext, "print-context", false, "Print the resolved run context (container, engine, dockerfile, runner, rings, regions, accounts, env, mounts and flags) of each deployment ring as json and exit")
 	deployCmd.Flags().StringVarP(&Container, "container", "c", Container, "The runiac deploy container to execute in.")
-	deployCmd.Flags().StringVarP(&DeploymentRing, "deployment-ring", "d", "", "The deployment ring to configure")
+	deployCmd.Flags().StringVar(&ContainerDigest, "container-digest", "", "Pin the --container to this sha256:{hex} image digest so every run derives from the same image, e.g. container_digest in the runiac config. The runiac version of the pinned container is verified to be compatible with the CLI before deploying")
+	deployCmd.Flags().StringVar(&NsRegistry, "namespace-registry", "", "Shared file claiming the --namespace of each run, failing when the unexpired claim of another run holds it. Claims are released on completion and expire after namespace_claim_ttl in the runiac config (default 6h)")
+	deployCmd.Flags().StringSliceVarP(&DeploymentRings, "deployment-ring", "d", []string{}, "The deployment ring(s) to configure. Multiple rings are deployed in order, using the account configured for each under rings.{ring}.account")
 	deployCmd.Flags().BoolVar(&Local, "local", false, "Pre-configure settings to create an isolated configuration specific to the executing machine")
-	deployCmd.Flags().StringVarP(&Runner, "runner", "", "terraform", "The deployment tool to use for deploying infrastructure")
-	deployCmd.Flags().StringSliceVarP(&StepWhitelist, "steps", "s", []string{}, "Only run the specified steps. To specify steps inside a track: -s {trackName}/{stepName}.  To run multiple steps, separate with a comma.  If empty, it will run all steps. To run no steps, specify a non-existent step.")
+	deployCmd.Flags().StringVarP(&Runner, "runner", "", "terraform", "The deployment tool to use for deploying infrastructure (terraform, arm, pulumi, terragrunt or the name of an external runner plugin executing runiac-runner-{name} from the container's path)")
+	deployCmd.Flags().StringArrayVar(&RunnerArgs, "runner-arg", []string{}, "Additional argument appended to the runner's commands (ie. pulumi preview, up and destroy, or the request of external runner plugins), can be repeated")
+	deployCmd.Flags().StringArrayVar(&Features, "feature", []string{}, "Toggle an optional feature with name[=true|false], forwarded as the TF_VAR_feature_{name} input variable and overriding the ring's rings.{ring}.features in the runiac config. Can be repeated")
+	deployCmd.Flags().StringSliceVar(&EnvPrefixes, "env-prefix", []string{}, fmt.Sprintf("Forward the host environment variables with this prefix into the container in addition to %s and the env_passthrough prefixes in the runiac config. Can be repeated or comma separated", strings.Join(defaultEnvPrefixes, ", ")))
+	deployCmd.Flags().StringArrayVar(&ExtraEnv, "env", []string{}, "Set the KEY=VALUE environment variable in the container, taking precedence over a forwarded host variable of the same name. Can be repeated")
+	deployCmd.Flags().StringSliceVarP(&StepWhitelist, "steps", "s", []string{}, "Only run the specified steps. To specify steps inside a track: -s {trackName}/{stepName}.  To run multiple steps, separate with a comma.  Globs (ie. core/* or */dns) and regular expressions prefixed with re: (ie. re:^infra/(net|dns)$) select every matching step.  If empty, it will run all steps. To run no steps, specify a non-existent step.")
+	deployCmd.Flags().StringSliceVar(&TeardownOrder, "teardown-order", []string{}, "The {trackName}/{stepName} steps in the order --self-destroy destroys them, instead of the reverse progression order of each track. Must include every targeted step, or set teardown_order in the runiac config")
+	deployCmd.Flags().StringSliceVar(&TrackWhitelist, "track", []string{}, "Only run the steps of the specified track. Can be repeated or comma separated to run multiple tracks and combined with --steps")
+	deployCmd.Flags().StringSliceVar(&SkipSteps, "skip-steps", []string{}, "Do not run the specified steps, specified as {trackName}/{stepName}. Can be repeated or comma separated and combined with --steps, --track and --tags")
+	deployCmd.Flags().StringSliceVar(&StepTags, "tags", []string{}, "Only run the steps tagged with one of the tags in the tags of their runiac.yml. Can be repeated or comma separated")
+	deployCmd.Flags().StringSliceVar(&SkipTags, "skip-tags", []string{}, "Do not run the steps tagged with one of the tags in the tags of their runiac.yml. Can be repeated or comma separated")
+	deployCmd.Flags().StringVar(&SinceRef, "since", "", "Only run the steps with files changed since the git ref (ie. origin/main) and the steps depending on them. A change outside of the tracks and step directories runs every step. Combined with --steps and --track")
 	deployCmd.Flags().StringVar(&PullRequest, "pull-request", "", "Pre-configure settings to create an isolated configuration specific to a pull request, provide pull request identifier")
-	deployCmd.Flags().StringVarP(&Dockerfile, "dockerfile", "f", Dockerfile, "The dockerfile runiac builds to execute the deploy in, defaults to the autogenerated '%s' and must derive from runiac/deploy:{version}-alpine. Runiac official dockerfiles are here: https://github.com/runiac/docker")
-	deployCmd.Flags().StringVar(&ContainerEngine, "container-engine", ContainerEngine, "Container engine (ie. podman or docker)")
+	deployCmd.Flags().StringVarP(&Dockerfile, "dockerfile", "f", Dockerfile, "The dockerfile runiac builds to execute the deploy in, defaults to '.runiac/Dockerfile.{ring}' when present for the deployment ring, otherwise the autogenerated '%s'. Must derive from runiac/deploy:{version}-alpine. Runiac official dockerfiles are here: https://github.com/runiac/docker")
+	deployCmd.Flags().StringVar(&Image, "image", "", "Run this pre-built image, e.g. pushed by 'runiac build --push', instead of building the project container")
+	deployCmd.Flags().BoolVar(&BuildNoCache, "no-cache", false, "Rebuild the project container without the container engine's build cache, even when its source did not change since it was last built")
+	deployCmd.Flags().StringArrayVar(&BuildArgs, "build-arg", []string{}, "Pass a build argument, KEY=VALUE or KEY for the host's value, to the dockerfile")
+	deployCmd.Flags().StringVar(&BuildPlatform, "platform", "", "Build the project container for this platform, e.g. linux/amd64")
+	deployCmd.Flags().StringVar(&BuildTarget, "target", "", "Build this stage of a multi-stage dockerfile")
+	deployCmd.Flags().StringVar(&BuildContext, "context", "", "Build the project container from this tarball (optionally gzip compressed) instead of the working directory. The --dockerfile path is relative to the root of the tarball")
+	deployCmd.Flags().BoolVar(&ValidateContract, "validate-config-against-container", false, "Query the built container for the RUNIAC_* variables it supports and warn when runiac sends variables it does not understand or omits variables it requires")
+	deployCmd.Flags().BoolVar(&StrictContract, "strict-contract", false, "Fail instead of warning when the CLI and the container's RUNIAC_* contract do not match, implies --validate-config-against-container")
+	deployCmd.Flags().BoolVar(&VerifySignature, "verify-signature", false, "Verify the --container base image is signed with cosign before building, aborting when verification fails")
+	deployCmd.Flags().StringVar(&SigKey, "signature-key", "", "The cosign public key (path, url or KMS uri) the --container base image must be signed with")
+	deployCmd.Flags().StringVar(&SigIdentity, "signature-identity", "", "The certificate identity of a keyless cosign signature, used with --signature-issuer")
+	deployCmd.Flags().StringVar(&SigIssuer, "signature-issuer", "", "The OIDC issuer of a keyless cosign signature, used with --signature-identity")
+	deployCmd.Flags().BoolVar(&ParallelPrimary, "parallel-primary-regions", false, "Deploy the primary steps of each track to all --primary-regions concurrently instead of one region at a time. The regional steps deploy once every primary region completed, with the outputs of the first primary region")
+	deployCmd.Flags().BoolVar(&OnlyChanged, "only-changed-regions", false, "Only deploy the --regional-regions whose inputs changed since the last successful deploy of the ring recorded in the history, all regions when there is none. A region's inputs are the regional directories of the steps, the var files of their tracks, its own regional/regions/{region}.tfvars and the deployment settings")
+	deployCmd.Flags().BoolVar(&SimulateIAM, "simulate-iam", false, "Simulate the aws IAM permissions each step's plan likely requires against the deploying credentials and report missing permissions before applying, best combined with --dry-run")
+	deployCmd.Flags().StringVar(&Policy, "policy", policyHardFail, fmt.Sprintf("How plans violating the rego policies in %s are handled, each step's plan is evaluated with conftest before applying. %s fails the step without applying, %s applies the plan and only warns", policyDir, policyHardFail, policySoftFail))
+	deployCmd.Flags().BoolVar(&CostEstimate, "cost-estimate", false, "Estimate the monthly cost change of each step's plan with infracost in the container and summarize it per step and in total once the run completes. Requires infracost and its INFRACOST_API_KEY, e.g. forwarded with --env-prefix INFRACOST_")
+	deployCmd.Flags().StringVar(&BreakGlass, "break-glass", "", "Deploy rings frozen by an active freeze_windows window in the runiac config, the reason is logged, recorded in the history and posted to freeze_notify_url")
+	deployCmd.Flags().StringVar(&ContainerEngine, "container-engine", ContainerEngine, fmt.Sprintf("Container engine (ie. podman or docker). When %s or not set, the first running engine of %s, or of a comma separated list of engines", containerEngineAuto, strings.Join(supportedContainerEngines, ", ")))
+	deployCmd.Flags().BoolVar(&Native, "native", false, "Execute the steps directly on the host with the runner's executable from the PATH instead of building and running the project container. The steps run in the working directory and must keep their state remotely, not in /runiac/tfstate")
+	deployCmd.Flags().BoolVar(&Native, "no-container", false, "Alias of --native")
+	deployCmd.Flags().StringVar(&NativeExecutor, "native-executor", "runiac-deploy", "The runiac deploy executable --native executes, shipped in the release archive or built with 'go build -o runiac-deploy ./cmd/runiac'")
+	deployCmd.Flags().StringVar(&ExecutionTarget, "execution-target", executionTargetLocal, fmt.Sprintf("Where the deploy container runs, %s runs it with the container engine on this host, %s runs it as a Job in the cluster of the current kubectl context and streams its logs. Local state is not available in the cluster, steps should use remote state", executionTargetLocal, executionTargetKubernetes))
+	deployCmd.Flags().StringVar(&KubeRegistry, "kube-registry", "", "Push the built project container to this registry, tagged with --version, for the kubernetes Job to run")
+	deployCmd.Flags().StringVar(&KubeContext, "kube-context", "", "The kubectl context of the cluster the kubernetes Job runs in, defaults to the current context")
+	deployCmd.Flags().StringVar(&KubeNamespace, "kube-namespace", "", "The kubernetes namespace the Job runs in, defaults to the namespace of the kubectl context")
+	deployCmd.Flags().StringVar(&KubeSvcAccount, "kube-service-account", "", "The service account of the kubernetes Job's pod, e.g. the one bound to the workload identity the steps deploy with")
+	deployCmd.Flags().StringSliceVar(&MountCredentials, "mount-credentials", []string{}, fmt.Sprintf("Pass the credentials of these clouds (%s) to the container, or %s. Defaults to the clouds of the terraform providers in the tracks, every cloud when none is detected. The host's AWS_SHARED_CREDENTIALS_FILE, AWS_CONFIG_FILE, AZURE_CONFIG_DIR, CLOUDSDK_CONFIG and GOOGLE_APPLICATION_CREDENTIALS are mounted, otherwise the cloud clis' config directories in .runiac", strings.Join(supportedCredentials, ", "), credentialsNone))
+	deployCmd.Flags().BoolVar(&CredentialTokens, "credential-tokens", false, "Pass short-lived tokens of the host's logged in cloud clis instead of their config directories: the aws cli's exported credentials (e.g. of an aws sso login), the azure cli's token cache and a gcloud application default credentials access token")
+	deployCmd.Flags().StringVar(&AzureClientID, "azure-client-id", "", "Authenticate the steps to azure as the service principal of this application (client) id, with the --azure-federated-token-file or the host's ARM_CLIENT_SECRET. The credential is checked with the tenant before deploying and passed as the ARM_* variables")
+	deployCmd.Flags().StringVar(&AzureTenant, "azure-tenant-id", "", "The tenant of the --azure-client-id service principal, defaults to the host's ARM_TENANT_ID or AZURE_TENANT_ID")
+	deployCmd.Flags().StringVar(&AzureTokenFile, "azure-federated-token-file", "", "The federated (OIDC) token file of the --azure-client-id service principal, e.g. of a CI's identity provider or workload identity, defaults to the host's ARM_OIDC_TOKEN_FILE_PATH or AZURE_FEDERATED_TOKEN_FILE")
+	deployCmd.Flags().StringVar(&GCPImpersonate, "impersonate-service-account", "", "Deploy to gcp as this service account, impersonated with the passed gcp credentials by gcloud and the google providers, e.g. deployer@project.iam.gserviceaccount.com")
+	deployCmd.Flags().IntVar(&PlanThreshold, "plan-summary-threshold", 0, "Warn when a step's plan changes more than N resources. Set plan_summary_require_confirm in the runiac config to also require --confirm")
+	deployCmd.Flags().BoolVar(&Confirm, "confirm", false, "Confirm applying changes that require confirmation, such as plans exceeding --plan-summary-threshold")
+	deployCmd.Flags().BoolVar(&PrePullProviders, "pre-pull-providers", false, "Download the providers of every step before deploying any step, separating network bound work from the deployment")
+	deployCmd.Flags().StringVar(&ExportManifest, "export-manifest", "", "Write a json manifest of the resources managed by each step, with their ids, regions, deployment ring and account, to this path")
+	deployCmd.Flags().StringVar(&Sarif, "sarif", "", "Validate each step and write the validation findings as a SARIF report to this path")
+	deployCmd.Flags().StringVar(&ReportPath, "report-path", "", "Write a summary report of the deployment with the status, duration, resource changes and region of each step and the exit code to this path, as yaml when the extension is .yml or .yaml. Defaults to .runiac/reports/{timestamp}.json")
+	deployCmd.Flags().StringArrayVar(&Plugins, "plugin", []string{}, "Executable called with the resolved run context as json on stdin, returning additional env variables and mounts as json on stdout, e.g. {\"env\": {\"KEY\": \"value\"}, \"mounts\": [{\"source\": \"/host\", \"target\": \"/container\", \"read_only\": true}]}. Can be repeated")
+	deployCmd.Flags().IntVar(&MaxParallel, "max-parallel", 0, "The maximum number of steps of a track executing concurrently in each region. Steps start once the steps they depend on (depends_on in the step's runiac.yml, otherwise the previous progression level) completed. 0 is unlimited")
+	deployCmd.Flags().DurationVar(&StepTimeout, "step-timeout", 0, "How long a step may execute in a region before its runner is killed and the step fails, e.g. 30m. Overrides timeout in the step's runiac.yml. 0 is unlimited")
+	deployCmd.Flags().IntVar(&StepRetries, "step-retries", 0, "How many times a failed step is retried, e.g. on a transient cloud API error. Overrides retries in the step's runiac.yml, a retried step's runner does not retry its commands itself")
+	deployCmd.Flags().DurationVar(&StepRetryBackoff, "step-retry-backoff", defaultStepRetryBackoff, "How long to wait before the first retry of a failed step, doubled for every further retry. Overrides retry_backoff in the step's runiac.yml")
+	deployCmd.Flags().BoolVar(&TestRollback, "test-rollback", false, "Destroy a step's execution when its tests fail, the tests/tests.test binary or tests/assertions.yml of the step. test_rollback in the step's runiac.yml overrides it for the step")
+	deployCmd.Flags().BoolVar(&RollbackOnFail, "rollback-on-failure", false, "When a step fails, destroy the step executions the deploy applied in reverse order. Only for the namespaces of --local and --pull-request, whose resources the namespace's deploys created")
+	deployCmd.Flags().StringVar(&MigrateStateTo, "migrate-state-to", "", fmt.Sprintf("The deployment ring the shared local state in %s, created before local state was isolated by ring, belongs to. It is copied into the ring's state the first time the ring is deployed, the other rings start with empty state", localStateDir))
+	deployCmd.Flags().BoolVar(&Resume, "resume", false, "Resume the last deploy of the ring, the step executions it completed for the same version, environment and namespace are not executed again and their recorded output variables are passed to the later steps")
+	deployCmd.Flags().StringVar(&FromStep, "from-step", "", "Start the track of the {trackName}/{stepName} step at the step, the steps of the track at a lower progression level are not executed. Combine with --resume to pass their recorded output variables to the later steps")
+	deployCmd.Flags().IntVar(&AbortThreshold, "abort-after-failures", 1, "Skip the remaining deployment rings once this many have failed, and the remaining regions of a track once this many of its regions have failed. 0 deploys every ring and region regardless of failures")
+	deployCmd.Flags().StringVar(&Record, "record", "", "Record the --interactive session output to this file for later review")
+	deployCmd.Flags().StringVar(&UI, "ui", uiModePlain, "The progress view of the run, plain streams the container output, tui renders a live dashboard of each step's status, region, elapsed time and last log line with the step's full log a keypress away")
+	deployCmd.Flags().StringVar(&ApprovalURL, "approval-url", "", "Change approval endpoint that must approve deploying rings listed in require_approval_rings in the runiac config, polled until approved, denied or --approval-timeout")
+	deployCmd.Flags().StringVar(&ApprovalReason, "approval-reason", "", "The reason for the change, recorded in the deploy history and sent to the --approval-url")
+	deployCmd.Flags().DurationVar(&ApprovalTimeout, "approval-timeout", time.Hour, "How long to wait for an approval decision from the --approval-url")
+	deployCmd.Flags().BoolVar(&PauseTracks, "pause-between-tracks", false, "Deploy the tracks one at a time and wait for an approval after each completed track, confirmed with --interactive or requested from the --approval-url. Tracks with approval: manual in their runiac.yml also wait for their plan to be approved")
+	deployCmd.Flags().StringVar(&OutputDir, "output-dir", "", "Directory to write run artifacts (plans and resolved configuration) to for use by later pipeline stages")
+	deployCmd.Flags().StringVar(&ArtifactsFrom, "artifacts-from", "", "Directory containing the --output-dir of a previous run (ie. a dry run) whose plans should be applied")
+	deployCmd.Flags().BoolVar(&UseSavedPlans, "use-saved-plans", false, fmt.Sprintf("Apply the plans the last --dry-run of the --environment saved to %s/{environment} instead of planning again", planDir))
+	deployCmd.Flags().StringVar(&PlanSigningKey, "plan-signing-key", "", "File of the key the plans of a dry run's --output-dir are signed with (HMAC-SHA256), applying the plans with --use-saved-plans or --artifacts-from verifies their signature and is required when the plans were signed")
+	deployCmd.Flags().BoolVar(&GitHubComment, "github-comment", false, "Comment the plans of a dry run on the pull request of the GitHub Actions run, updating the comment of the previous run. Requires GITHUB_TOKEN")
+	deployCmd.Flags().StringVar(&Profile, "profile", "", "The profile in the runiac config (profiles.{profile}.{setting}) to resolve settings from, or RUNIAC_PROFILE")
+	deployCmd.Flags().StringVar(&Why, "why", "", "Print how a setting (ie. container-engine) is resolved across flag > env > config-set > profile > environment > config file > default and exit")
 	deployCmd.Flags().BoolVar(&Test, "test", Test, "Hidden flag only set during unit testing")
 	deployCmd.Flags().MarkHidden("test")
 
 	rootCmd.AddCommand(deployCmd)
 }
 
-var deployCmd = &cobra.Command{
-	Use:   "deploy",
-	Short: "Deploy configurations",
-	Long:  `This will execute the deploy action for each step.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		// These options can be set via config file.
-		// The command line option, if set, always takes precendence.
-		setStringFlag(cmd, &ContainerEngine, "container-engine", "container_engine")
-		setStringFlag(cmd, &Container, "container", "container")
-		setStringFlag(cmd, &Dockerfile, "dockerfile", "dockerfile")
+var deployCmd = &cobra.Command{
+	Use:   "deploy",
+	Short: "Deploy configurations",
+	Long:  `This will execute the deploy action for each step.`,
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		failed := runDeploy(cmd, actionDeploy)
+
+		printRunCostEstimates(runOutput, "text")
+
+		return getRunError(cmd, failed)
+	},
+}
+
+// errRunFailed is returned by the commands running the project container when a deployment ring failed, the CLI
+// exits with status 1 once the command returned
+var errRunFailed = errors.New("one or more deployment rings failed")
+
+// getRunError returns errRunFailed when a deployment ring failed. The failure was already logged by the ring, so the
+// usage and error are not printed by cobra.
+func getRunError(cmd *cobra.Command, failed bool) error {
+	if !failed {
+		return nil
+	}
+
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+
+	return errRunFailed
+}
+
+// runAction is the action runDeploy runs the project container for
+type runAction string
+
+const (
+	actionDeploy  runAction = "deploy"
+	actionPlan    runAction = "plan"    // Every ring is a dry run saving its plans to the output directory
+	actionDestroy runAction = "destroy" // The previously deployed resources are destroyed without deploying
+	actionScan    runAction = "scan"    // The source of every step is scanned without executing the steps
+	actionDrift   runAction = "drift"   // Every step is planned refresh-only to detect drift without applying
+)
+
+// runDeploy builds and runs the project container for every deployment ring, returning true when a ring failed
+func runDeploy(cmd *cobra.Command, action runAction) bool {
+	// Every option can also be set via environment, profile or config file.
+	// The command line option, if set, always takes precendence.
+	err := applySettings(cmd.Flags())
+	if err != nil {
+		logrus.WithError(err).Fatal(err)
+	}
+
+	runStart := time.Now().UTC()
+
+	// the rings, accounts and variables of the environment's overlay are merged over the base config
+	err = applyEnvironmentConfig(viper.GetViper(), cmd.Flags(), Environment)
+	if err != nil {
+		logrus.WithError(err).Fatal(err)
+	}
+
+	err = configureLogFormat(LogFormat)
+	if err != nil {
+		logrus.WithError(err).Fatal(err)
+	}
+
+	if Why != "" {
+		err = printSettingResolution(os.Stdout, cmd.Flags(), Why)
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		return false
+	}
+
+	// This condition is only met during unit testing.
+	// It should come after any setup / option parsing and precendence steps.
+	if Test {
+		return false
+	}
+
+	switch action {
+	case actionPlan:
+		err = configurePlan()
+	case actionDestroy:
+		err = configureDestroy()
+	case actionScan:
+		err = configureScan()
+	case actionDrift:
+		err = configureDrift()
+	}
+
+	if err != nil {
+		logrus.WithError(err).Fatal(err)
+	}
+
+	err = configureSavedPlans(action)
+	if err != nil {
+		logrus.WithError(err).Fatal(err)
+	}
+
+	err = validatePlanSigning(PlanSigningKey, DryRun, Detach)
+	if err != nil {
+		logrus.WithError(err).Fatal(err)
+	}
+
+	var pullRequest githubPullRequest
+
+	if GitHubComment {
+		err = validateGitHubComment(DryRun, Detach)
+		if err == nil {
+			pullRequest, err = getGitHubPullRequest(appFS)
+		}
+
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+	}
+
+	var planSigningKey []byte
+
+	if PlanSigningKey != "" {
+		planSigningKey, err = readPlanSigningKey(appFS, PlanSigningKey)
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+	}
+
+	if Native {
+		err = validateNativeMode(NativeExecutor, Runner)
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+	}
+
+	err = validateExecutionTarget(ExecutionTarget)
+	if err != nil {
+		logrus.WithError(err).Fatal(err)
+	}
+
+	// a pre-built image runs in the cluster, there is no container to build or run on this host
+	usesContainerEngine := !Native && !(isKubernetesTarget() && Image != "")
+
+	if usesContainerEngine {
+		engine, err := resolveContainerEngine(ContainerEngine)
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		if engine != ContainerEngine {
+			logrus.Infof("Using auto-detected container engine '%s'", engine)
+		}
+
+		ContainerEngine = engine
+
+		// a pinned or validated container's version is checked against its contract
+		if !ValidateContract && !StrictContract && ContainerDigest == "" {
+			warnOutdatedCLI(Container)
+		}
+	}
+
+	// a tarball context is expected to be initialized when it was packaged
+	if BuildContext == "" {
+		ok := checkInitialized()
+		if !ok {
+			fmt.Printf("You need to run 'runiac init' before you can use the CLI in this directory\n")
+			return false
+		}
+	}
+
+	projectFS, err := getBuildContextFs(BuildContext)
+	if err != nil {
+		logrus.WithError(err).Fatal(err)
+	}
+
+	credentialClouds, err = resolveCredentialClouds(projectFS, MountCredentials, Runner)
+	if err != nil {
+		logrus.WithError(err).Fatal(err)
+	}
+
+	// pre-configure for local development experience
+	err = configureRingAndNamespace()
+	if err != nil {
+		logrus.WithError(err).Fatal(err)
+	}
+
+	whitelist, unselected, err := resolveStepWhitelist(projectFS)
+	if err != nil {
+		logrus.WithError(err).Fatal(err)
+	}
+
+	if unselected != "" {
+		logrus.Info(unselected)
+		return false
+	}
+
+	StepWhitelist = whitelist
+
+	err = validateRunner(Runner, RunnerArgs)
+	if err != nil {
+		logrus.WithError(err).Fatal(err)
+	}
+
+	// --native verifies the plugin is on the host's path, a container's plugins are only found once it runs
+	if !Native && !isStringInSlice(Runner, supportedRunners) {
+		logrus.Warnf("'%s' is not a runner of runiac, the container must provide the external runner plugin %s%s", Runner, externalRunnerPrefix, Runner)
+	}
+
+	err = validateEnvPassthrough(append(viper.GetStringSlice("env_passthrough"), EnvPrefixes...), ExtraEnv)
+	if err != nil {
+		logrus.WithError(err).Fatal(err)
+	}
+
+	secrets, err := getSecretsConfig()
+	if err != nil {
+		logrus.WithError(err).Fatal(err)
+	}
+
+	resolvedSecretEnv, err = resolveSecrets(vaultClient, secrets)
+	if err != nil {
+		logrus.WithError(err).Fatal(err)
+	}
+
+	err = validateImpersonateServiceAccount(GCPImpersonate)
+	if err != nil {
+		logrus.WithError(err).Fatal(err)
+	}
+
+	azureAuth, err := resolveAzureAuth()
+	if err != nil {
+		logrus.WithError(err).Fatal(err)
+	}
+
+	// fail fast on an expired or revoked credential instead of in the first step
+	if azureAuth.ClientID != "" && !ShowResolvedVars && !PrintContext {
+		err = checkAzureCredentials(azureClient, azureAuth)
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		logrus.Infof("Authenticated the azure service principal %s with tenant %s", azureAuth.ClientID, azureAuth.TenantID)
+	}
+
+	maskPatterns, err = getMaskPatterns()
+	if err != nil {
+		logrus.WithError(err).Fatal(err)
+	}
+
+	// a kubernetes job can not write the report to this host
+	if ReportPath == "" && !isKubernetesTarget() {
+		ReportPath = getDefaultReportPath(time.Now())
+	}
+
+	if Runner == "pulumi" && os.Getenv("PULUMI_ACCESS_TOKEN") == "" && os.Getenv("PULUMI_BACKEND_URL") == "" {
+		logrus.Warn("The pulumi runner expects PULUMI_ACCESS_TOKEN or PULUMI_BACKEND_URL to be set to log in to a state backend")
+	}
+
+	runnerLogEnv, err := getRunnerLogLevelEnv(Runner, RunnerLogLevel)
+	if err != nil {
+		logrus.WithError(err).Fatal(err)
+	}
+
+	err = validateRestartPolicy(RestartPolicy, Detach)
+	if err != nil {
+		logrus.WithError(err).Fatal(err)
+	}
+
+	err = validateNamespaceMode("pid", PidMode, []string{"host"})
+	if err != nil {
+		logrus.WithError(err).Fatal(err)
+	}
+
+	err = validateNamespaceMode("ipc", IpcMode, []string{"none", "private", "shareable", "host"})
+	if err != nil {
+		logrus.WithError(err).Fatal(err)
+	}
+
+	if MountDocker && DockerSocket == "" {
+		DockerSocket = getEngineSocket(ContainerEngine)
+	}
+
+	err = validateDockerSocket(DockerSocket)
+	if err != nil {
+		if ContainerEngine == "podman" {
+			err = fmt.Errorf("%w, start the podman socket with 'systemctl --user start podman.socket' or set --docker-socket to its path", err)
+		}
+
+		logrus.WithError(err).Fatal(err)
+	}
+
+	if DockerSocket != "" {
+		logrus.Warnf("Mounting the docker socket %s into the container, every step can control the host's docker daemon which is equivalent to root access on the host", DockerSocket)
+	}
+
+	if len(TeardownOrder) > 0 {
+		if !SelfDestroy && !Destroy {
+			logrus.Warn("--teardown-order is only used with --self-destroy or destroy")
+		}
+
+		err = validateTeardownOrder(projectFS, TeardownOrder, StepWhitelist)
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+	}
+
+	err = validateResume(projectFS, Resume, FromStep, StepWhitelist)
+	if err != nil {
+		logrus.WithError(err).Fatal(err)
+	}
+
+	err = validateCostEstimate()
+	if err != nil {
+		logrus.WithError(err).Fatal(err)
+	}
+
+	err = validatePolicy(appFS, Policy)
+	if err != nil {
+		logrus.WithError(err).Fatal(err)
+	}
+
+	err = validateScan()
+	if err != nil {
+		logrus.WithError(err).Fatal(err)
+	}
+
+	notifications, err := getNotifications(viper.GetViper())
+	if err != nil {
+		logrus.WithError(err).Fatal(err)
+	}
+
+	metrics, err := getMetricsConfig(viper.GetViper())
+	if err != nil {
+		logrus.WithError(err).Fatal(err)
+	}
+
+	if RollbackOnFail && (SelfDestroy || Destroy || Resume || FromStep != "") {
+		logrus.Fatal("--rollback-on-failure can not be used with --self-destroy, destroy, --resume or --from-step")
+	}
+
+	// the rollback destroys every step the deploy applied, including the resources that existed before the deploy
+	if RollbackOnFail && !isNamespacedRun() {
+		logrus.Fatal("--rollback-on-failure can only be used with --local or --pull-request, it destroys every step the failed deploy applied")
+	}
+
+	if OnlyChanged && (SelfDestroy || Destroy) {
+		logrus.Fatal("--only-changed-regions can not be used with --self-destroy or destroy")
+	}
+
+	if Detach && Interactive {
+		logrus.Fatal("--detach can not be used with --interactive")
+	}
+
+	if MaxParallel < 0 {
+		logrus.Fatal("--max-parallel must be 0 (unlimited) or more")
+	}
+
+	if StepTimeout < 0 || StepRetries < 0 || StepRetryBackoff < 0 {
+		logrus.Fatal("--step-timeout, --step-retries and --step-retry-backoff can not be negative")
+	}
+
+	if MaxLogSize != "" && !Detach {
+		logrus.Fatal("--max-log-size can only be used with --detach")
+	}
+
+	if _, err := parseLogSize(MaxLogSize); err != nil {
+		logrus.WithError(err).Fatal(err)
+	}
+
+	if Record != "" && !Interactive {
+		logrus.Fatal("--record can only be used with --interactive")
+	}
+
+	if PauseTracks && !Interactive && ApprovalURL == "" {
+		logrus.Fatal("--pause-between-tracks requires --interactive or --approval-url to approve continuing after each track")
+	}
+
+	if err := validateUIMode(UI, isTerminal(os.Stdout)); err != nil {
+		logrus.WithError(err).Fatal(err)
+	}
+
+	if ContainerDigest != "" && Image != "" {
+		logrus.Fatal("--container-digest can not be used with --image, pin the pre-built image with its digest instead, e.g. --image registry.example.com/project@sha256:...")
+	}
+
+	Container, err = getPinnedContainer(Container, ContainerDigest)
+	if err != nil {
+		logrus.WithError(err).Fatal(err)
+	}
+
+	rings := getDeploymentRings()
+	multipleRings := len(rings) > 1
+
+	accounts, err := resolveRingAccounts(rings, Account, cmd.Flags().Changed("account"))
+	if err != nil {
+		logrus.WithError(err).Fatal(err)
+	}
+
+	// assumed once the ring deploys, an invalid role fails before any ring deploys
+	roles, err := resolveRingRoles(rings, accounts, Environment)
+	if err != nil {
+		logrus.WithError(err).Fatal(err)
+	}
+
+	regions, err := resolveRingRegions(rings, PrimaryRegions, RegionalRegions, cmd.Flags().Changed("primary-regions"), cmd.Flags().Changed("regional-regions"))
+	if err != nil {
+		logrus.WithError(err).Fatal(err)
+	}
+
+	if ShowResolvedVars {
+		for _, ring := range rings {
+			DeploymentRing = ring
+			PrimaryRegions = regions[ring].Primary
+			RegionalRegions = regions[ring].Regional
+
+			runiacEnv := getEnvFromArgs(getRunConfigArguments(accounts[ring]))
+
+			if runnerLogEnv != "" {
+				runiacEnv = append(runiacEnv, runnerLogEnv)
+			}
+
+			printResolvedVars(os.Stdout, ring, runiacEnv, getPassthroughEnv())
+		}
+
+		return false
+	}
+
+	dockerfileExplicit := isSettingExplicit(cmd.Flags(), "dockerfile")
+
+	if PrintContext {
+		runContext, err := getRunContext(projectFS, cmd.Flags(), rings, accounts, regions, dockerfileExplicit, runnerLogEnv)
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		err = printRunContext(os.Stdout, runContext)
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		return false
+	}
+
+	freezeWindows, err := getFreezeWindows()
+	if err != nil {
+		logrus.WithError(err).Fatal(err)
+	}
+
+	ringDockerfiles := map[string]string{}
+	ringFreezes := map[string]*freezeWindow{}
+
+	for _, ring := range rings {
+		// a pre-built image is not built from the ring's dockerfile
+		if Image == "" {
+			ringDockerfiles[ring], err = resolveRingDockerfile(projectFS, ring, Dockerfile, dockerfileExplicit)
+			if err != nil {
+				logrus.WithError(err).Fatal(err)
+			}
+		}
+
+		if _, err := resolveFeatures(ring, Features); err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		if _, err := getRingVariables(ring); err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		if err := checkRingEnvironment(ring, Environment); err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		if _, err := getRingStateDir(ring); err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		ringFreezes[ring], err = getActiveFreeze(ring, freezeWindows, getProtectedRings(), time.Now())
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		err = checkFreeze(ring, ringFreezes[ring], BreakGlass, DryRun)
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		if ringFreezes[ring] != nil && DryRun {
+			logrus.Warnf("Deployment ring '%s' is frozen by the '%s' freeze window, continuing as this is a dry run", ring, ringFreezes[ring].Name)
+		}
+
+		if ApprovalURL == "" && isRingInSet(ring, getRequireApprovalRings()) {
+			logrus.Fatalf("deployment ring '%s' requires approval, set --approval-url", ring)
+		}
+
+		if SelfDestroy || Destroy || RollbackOnFail {
+			err = checkDestroyConfirmation(ring, ConfirmDestroy, getProtectedRings())
+			if err != nil {
+				logrus.WithError(err).Fatal(err)
+			}
+		}
+
+		if ArtifactsFrom != "" {
+			err = checkArtifactsCompatibility(getRingScopedDir(ArtifactsFrom, ring, multipleRings), ring, Environment, Namespace)
+			if err != nil {
+				logrus.WithError(err).Fatal(err)
+			}
+		}
+
+		if ArtifactsFrom != "" {
+			err = checkPlansKey(appFS, getRingScopedDir(ArtifactsFrom, ring, multipleRings), planSigningKey)
+			if err != nil {
+				logrus.WithError(err).Fatal(err)
+			}
+		}
+
+		if ArtifactsFrom != "" && planSigningKey != nil {
+			err = verifyPlans(appFS, getRingScopedDir(ArtifactsFrom, ring, multipleRings), planSigningKey)
+			if err != nil {
+				logrus.WithError(err).Fatal(err)
+			}
+
+			logrus.Infof("Verified the signature of the plans in %s", getRingScopedDir(ArtifactsFrom, ring, multipleRings))
+		}
+	}
+
+	if VerifySignature {
+		// a pre-built image runs as is, so it is verified instead of the base image it was built from
+		verifiedImage := Container
+		if Image != "" {
+			verifiedImage = Image
+		}
+
+		err = verifyImageSignature(verifiedImage, SigKey, SigIdentity, SigIssuer)
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+	}
+
+	// build each distinct dockerfile once, ring specific dockerfiles are tagged with the ring name
+	containerTags := map[string]string{}
+
+	for _, ring := range rings {
+		// the steps execute on the host, there is no container to build
+		if Native {
+			break
+		}
+
+		dockerfile := ringDockerfiles[ring]
+
+		if _, ok := containerTags[dockerfile]; ok {
+			continue
+		}
+
+		// a pre-built image is run as is
+		if Image != "" {
+			containerTags[dockerfile] = Image
+			continue
+		}
+
+		containerTag := getContainerTag(dockerfile, ring)
+		buildChangedContainer(containerTag, dockerfile)
+		containerTags[dockerfile] = containerTag
+
+		if isKubernetesTarget() {
+			image := getKubernetesImage(containerTag)
+
+			err = tagImage(containerTag, image)
+			if err == nil {
+				err = pushImage(image)
+			}
+
+			if err != nil {
+				logrus.WithError(err).Fatal(err)
+			}
+		}
+	}
+
+	contracts := map[string]containerContract{}
+
+	// a pinned container is verified to be compatible, its version is reported by its contract
+	if ValidateContract || StrictContract || ContainerDigest != "" {
+		for _, containerTag := range containerTags {
+			contract, err := getContainerContract(containerTag)
+			if err != nil {
+				if StrictContract || ContainerDigest != "" {
+					logrus.WithError(err).Fatal(err)
+				}
+
+				logrus.WithError(err).Warn("Unable to validate the configuration against the container")
+				continue
+			}
+
+			err = checkContainerVersion(containerTag, contract)
+			if err != nil {
+				logrus.WithError(err).Fatal(err)
+			}
+
+			if contract.RuniacVersion == "" && ContainerDigest != "" {
+				logrus.Fatalf("Unable to verify the runiac version of the pinned container %s, it does not report its version. Pin --container-digest to an image built with the RUNIAC_VERSION build argument", Container)
+			}
+
+			contracts[containerTag] = contract
+		}
+	}
+
+	logrus.Info("Completed build, lets run!")
+
+	var regional regionalInputs
+	var history []historyEntry
+
+	if OnlyChanged {
+		regional, err = getRegionalInputs(projectFS)
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		history, err = readHistory(appFS)
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+	}
+
+	claimTTL := defaultNamespaceClaimTTL
+
+	if NsRegistry != "" {
+		claimTTL, err = getNamespaceClaimTTL()
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+	}
+
+	fanOut := fanOutTracker{abortAfterFailures: AbortThreshold}
+
+	for i, ring := range rings {
+		PrimaryRegions = regions[ring].Primary
+		RegionalRegions = regions[ring].Regional
+		allRegions := RegionalRegions
+
+		if fanOut.shouldAbort() {
+			for _, skipped := range rings[i:] {
+				PrimaryRegions = regions[skipped].Primary
+				RegionalRegions = regions[skipped].Regional
+				fanOut.skip(getFanOutTargetName(skipped, accounts[skipped]))
+				recordHistory(newHistoryEntry(skipped, accounts[skipped], historyResultSkipped, ""))
+			}
+
+			logrus.Errorf("Aborting the remaining deployment rings after %d failure(s)", len(fanOut.failed))
+			break
+		}
+
+		DeploymentRing = ring
+
+		if multipleRings {
+			logrus.Infof("Deploying ring '%s' to account '%s'", ring, accounts[ring])
+		}
+
+		var regionInputs map[string]string
+
+		if OnlyChanged {
+			regionInputs = getRegionFingerprints(regional, ring, accounts[ring], allRegions)
+
+			if previous, ok := getLastRegionFingerprints(history, ring, accounts[ring]); ok {
+				RegionalRegions = getChangedRegions(allRegions, regionInputs, previous)
+				logrus.Infof("Deploying the regional regions with changed inputs: %v", RegionalRegions)
+			} else {
+				logrus.Info("No previous deploy recorded, deploying all regional regions")
+			}
+		}
+
+		// correlates the approval request and the history of this ring's run
+		correlationID, err := newCorrelationID()
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		runArgs, err := getRingRunArguments(ring, accounts[ring], multipleRings, runnerLogEnv)
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		// the output of each step is persisted by the correlation id of the ring's run, a job's output is in its
+		// pod's logs
+		if !isKubernetesTarget() {
+			stepLogArgs, err := getStepLogArguments(correlationID, runArgs)
+			if err != nil {
+				logrus.WithError(err).Fatal(err)
+			}
+
+			runArgs = append(runArgs, stepLogArgs...)
+		}
+
+		if contract, ok := contracts[containerTags[ringDockerfiles[ring]]]; ok {
+			err = checkContainerContract(contract, runArgs, StrictContract)
+			if err != nil {
+				logrus.WithError(err).Fatal(err)
+			}
+		}
+
+		// removed once the ring ran, a detached container still reads the tokens
+		tokenDir := getCredentialTokensDir(correlationID)
+
+		if !Native && !isKubernetesTarget() {
+			dir, err := os.Getwd()
+			if err != nil {
+				logrus.WithError(err).Fatal(err)
+			}
+
+			credentialArgs, err := getCredentialArguments(dir, credentialClouds, CredentialTokens, tokenDir)
+			if err != nil {
+				logrus.WithError(err).Fatal(err)
+			}
+
+			runArgs = replaceEnvArguments(runArgs, credentialArgs)
+		}
+
+		if role, ok := roles[ring]; ok {
+			logrus.Infof("Assuming the role %s to deploy account %s", role, accounts[ring])
+
+			roleArgs, err := getAssumeRoleArguments(role, getRoleSessionName(correlationID), !isKubernetesTarget())
+			if err != nil {
+				logrus.WithError(err).Fatal(err)
+			}
+
+			runArgs = replaceEnvArguments(runArgs, roleArgs)
+		}
+
+		// a short-lived token of the host is requested for the impersonated service account
+		impersonationArgs := getImpersonationArguments(GCPImpersonate, CredentialTokens && isStringInSlice(credentialsGCP, credentialClouds) && !Native && !isKubernetesTarget())
+		runArgs = replaceEnvArguments(runArgs, impersonationArgs)
+
+		if azureAuth.ClientID != "" {
+			azureArgs, err := getAzureAuthArguments(azureAuth, !isKubernetesTarget())
+			if err != nil {
+				logrus.WithError(err).Fatal(err)
+			}
+
+			runArgs = replaceEnvArguments(runArgs, azureArgs)
+		}
+
+		for _, plugin := range Plugins {
+			result, err := runPlugin(plugin, pluginContext{
+				Project:         viper.GetString("project"),
+				DeploymentRing:  ring,
+				Environment:     Environment,
+				Namespace:       Namespace,
+				Account:         accounts[ring],
+				Version:         AppVersion,
+				Runner:          Runner,
+				PrimaryRegions:  PrimaryRegions,
+				RegionalRegions: RegionalRegions,
+				Steps:           StepWhitelist,
+				DryRun:          DryRun,
+				SelfDestroy:     SelfDestroy,
+				Destroy:         Destroy,
+			})
+			if err != nil {
+				logrus.WithError(err).Fatal(err)
+			}
+
+			runArgs = append(runArgs, getPluginArguments(result)...)
+		}
+
+		if isRingInSet(ring, getRequireApprovalRings()) {
+			err = waitForApproval(&http.Client{Timeout: 30 * time.Second}, ApprovalURL, approvalRequest{
+				CorrelationID:  correlationID,
+				Reason:         ApprovalReason,
+				Project:        viper.GetString("project"),
+				DeploymentRing: ring,
+				Environment:    Environment,
+				Namespace:      Namespace,
+				Account:        accounts[ring],
+				Version:        AppVersion,
+				SelfDestroy:    SelfDestroy,
+				Destroy:        Destroy,
+			}, ApprovalTimeout)
+			if err != nil {
+				logrus.WithError(err).Fatal(err)
+			}
+		}
+
+		if freeze := ringFreezes[ring]; freeze != nil && !DryRun {
+			breakFreeze(ring, freeze)
+		}
+
+		releaseNamespace := func() {}
+
+		if NsRegistry != "" && Namespace != "" {
+			releaseNamespace, err = claimRunNamespace(appFS, NsRegistry, getNamespaceClaimKey(viper.GetString("project"), Environment, ring, Namespace), correlationID, claimTTL, DryRun)
+			if err != nil {
+				logrus.WithError(err).Fatal(err)
+			}
+		}
+
+		ringEvent := notificationEvent{
+			Event:          notifyDeployStarted,
+			Project:        viper.GetString("project"),
+			DeploymentRing: ring,
+			Environment:    Environment,
+			Namespace:      Namespace,
+			Account:        accounts[ring],
+			Version:        AppVersion,
+			Action:         string(action),
+			DryRun:         DryRun,
+		}
+
+		notifyClient := &http.Client{Timeout: 30 * time.Second}
+		notify(notifyClient, notifications, ringEvent)
+		ringStart := time.Now()
+
+		startProgressView(UI, strings.TrimSpace(fmt.Sprintf("%s %s", action, ring)))
+
+		if Native {
+			var stateArgs []string
+
+			stateArgs, err = getNativeStateArguments(ring)
+			if err != nil {
+				logrus.WithError(err).Fatal(err)
+			}
+
+			err = runNative(NativeExecutor, append(runArgs, stateArgs...), getRingScopedFile(Record, ring, multipleRings))
+		} else if isKubernetesTarget() {
+			err = runKubernetesJob(getKubernetesJobName(ring, correlationID), getKubernetesImage(containerTags[ringDockerfiles[ring]]), runArgs,
+				getKubernetesLabels(ring, Namespace, Environment, correlationID), getRingScopedFile(Record, ring, multipleRings))
+		} else {
+			err = runContainer(containerTags[ringDockerfiles[ring]], runArgs, getRingScopedFile(Record, ring, multipleRings))
+		}
+
+		stopProgressView()
+
+		if !Detach {
+			_ = os.RemoveAll(tokenDir)
+		}
+
+		if err != nil {
+			logrus.Errorf("Running iac failed with %s", err)
+		}
+
+		// only the plans of a completed dry run are signed for review
+		if err == nil && DryRun && OutputDir != "" && planSigningKey != nil {
+			dir := getRingScopedDir(OutputDir, ring, multipleRings)
+
+			if signErr := signPlans(appFS, dir, planSigningKey); signErr != nil {
+				logrus.WithError(signErr).Error("Unable to sign the plans")
+				err = signErr
+			} else {
+				logrus.Infof("Signed the plans in %s", dir)
+			}
+		}
+
+		if isKubernetesTarget() {
+			logrus.Infof("The output of the steps is in the logs of job %s, view it with 'kubectl logs job/%s'", getKubernetesJobName(ring, correlationID), getKubernetesJobName(ring, correlationID))
+		} else {
+			logrus.Infof("The output of each step is persisted to %s, view it with 'runiac logs %s'", getRunLogDir(correlationID), correlationID)
+		}
+
+		if report := getRingScopedFile(ReportPath, ring, multipleRings); !Detach && isReportWritten(report) {
+			logrus.Infof("Wrote the deployment report to %s", report)
+			writtenReports = append(writtenReports, report)
+
+			stepEvents, err := getStepFailedEvents(appFS, report, ringEvent)
+			if err != nil {
+				logrus.WithError(err).Warn("Unable to read the failed steps to notify of")
+			}
+
+			for _, e := range stepEvents {
+				notify(notifyClient, notifications, e)
+			}
+		}
+
+		// the outcome of a detached container is unknown
+		if !Detach {
+			completed := ringEvent
+			completed.Event = notifyDeployCompleted
+			completed.Result = getHistoryResult(err, false)
+			completed.DurationSeconds = time.Since(ringStart).Seconds()
+
+			notify(notifyClient, notifications, completed)
+
+			if metrics.PushgatewayURL != "" {
+				pushRingMetrics(metrics, completed, getRingScopedFile(ReportPath, ring, multipleRings))
+			}
+		}
+
+		// a detached container is still deploying, its claim is left to expire
+		if !Detach {
+			releaseNamespace()
+		}
+
+		fanOut.record(getFanOutTargetName(ring, accounts[ring]), err)
+		entry := newHistoryEntry(ring, accounts[ring], getHistoryResult(err, Detach), correlationID)
+		entry.RegionInputs = regionInputs
+
+		if !Detach {
+			entry.DurationSeconds = time.Since(ringStart).Seconds()
+		}
+
+		if ringFreezes[ring] != nil && !DryRun {
+			entry.BreakGlass = BreakGlass
+		}
+
+		recordHistory(entry)
+
+		// the environments of the namespaces of --local and --pull-request are recorded for runiac gc, a destroy of
+		// selected steps leaves the environment deployed
+		if isNamespacedRun() && !DryRun && (action == actionDeploy || action == actionDestroy) {
+			destroyed := err == nil && !Detach && (Destroy || SelfDestroy) && len(StepWhitelist) == 0
+
+			if recordErr := recordNamespaceRun(appFS, getNamespaceRecordsPath(), entry, destroyed, time.Now().UTC()); recordErr != nil {
+				logrus.WithError(recordErr).Warnf("Unable to record the namespaced environment in %s for runiac gc", getNamespaceRecordsPath())
+			}
+		}
+	}
+
+	if multipleRings {
+		logrus.Info(fanOut.summary())
+	}
+
+	if GitHubComment {
+		summary, err := readPlanSummary(appFS, OutputDir, runStart)
+		if err != nil {
+			logrus.WithError(err).Warn("Unable to read the plans to comment")
+		}
+
+		title := fmt.Sprintf("runiac plan of %s to ring(s) %s", viper.GetString("project"), strings.Join(rings, ", "))
+		if Environment != "" {
+			title = fmt.Sprintf("%s (%s)", title, Environment)
+		}
+
+		commentPlans(&http.Client{Timeout: 30 * time.Second}, pullRequest, getGitHubCommentMarker(viper.GetString("project"), Environment, rings), title, summary, len(fanOut.failed) > 0)
+	}
+
+	return len(fanOut.failed) > 0
+}
+
+// newHistoryEntry describes the run of a deployment ring for the history log
+func newHistoryEntry(ring string, account string, result string, correlationID string) historyEntry {
+	return historyEntry{
+		Project:         viper.GetString("project"),
+		Environment:     Environment,
+		DeploymentRing:  ring,
+		Namespace:       Namespace,
+		Account:         account,
+		PrimaryRegions:  PrimaryRegions,
+		RegionalRegions: RegionalRegions,
+		Version:         AppVersion,
+		GitSHA:          getGitSHA(),
+		DryRun:          DryRun,
+		SelfDestroy:     SelfDestroy,
+		Destroy:         Destroy,
+		Result:          result,
+		Reason:          ApprovalReason,
+		CorrelationID:   correlationID,
+	}
+}
+
+// breakFreeze logs overriding the ring's freeze and notifies the freeze_notify_url when configured
+func breakFreeze(ring string, freeze *freezeWindow) {
+	logrus.Warnf("Breaking glass to deploy ring '%s' during the '%s' freeze window: %s", ring, freeze.Name, BreakGlass)
+
+	url := viper.GetString("freeze_notify_url")
+	if url == "" {
+		return
+	}
+
+	user, _ := getMachineName()
+
+	err := notifyBreakGlass(&http.Client{Timeout: 30 * time.Second}, url, breakGlassNotification{
+		Reason:         BreakGlass,
+		FreezeWindow:   freeze.Name,
+		Project:        viper.GetString("project"),
+		DeploymentRing: ring,
+		Environment:    Environment,
+		Namespace:      Namespace,
+		Version:        AppVersion,
+		User:           user,
+	})
+	if err != nil {
+		logrus.WithError(err).Warnf("Unable to notify %s of breaking glass", url)
+	}
+}
+
+// pushRingMetrics pushes the metrics of the completed ring's run and the steps of its deployment report to the
+// pushgateway, failing to push is only logged
+func pushRingMetrics(config metricsConfig, completed notificationEvent, report string) {
+	m := ringMetrics{
+		Project:         completed.Project,
+		DeploymentRing:  completed.DeploymentRing,
+		Environment:     completed.Environment,
+		Namespace:       completed.Namespace,
+		Version:         completed.Version,
+		Action:          completed.Action,
+		DryRun:          completed.DryRun,
+		Succeeded:       completed.Result == historyResultSucceeded,
+		DurationSeconds: completed.DurationSeconds,
+		CompletedAt:     time.Now(),
+	}
 
-		// This condition is only met during unit testing.
-		// It should come after any setup / option parsing and precendence steps.
-		if Test {
-			return
+	if isReportWritten(report) {
+		steps, err := readStepMetrics(appFS, report)
+		if err != nil {
+			logrus.WithError(err).Warn("Unable to read the step metrics of the deployment report")
 		}
 
-		checkDockerExists()
+		m.Steps = steps
+	}
 
-		ok := checkInitialized()
-		if !ok {
-			fmt.Printf("You need to run 'runiac init' before you can use the CLI in this directory\n")
-			return
-		}
+	if err := pushMetrics(&http.Client{Timeout: 30 * time.Second}, config, m); err != nil {
+		logrus.WithError(err).Warn("Unable to push the metrics to the pushgateway")
+		return
+	}
 
-		buildKit := "DOCKER_BUILDKIT=1"
-		containerTag := viper.GetString("project")
+	logrus.Infof("Pushed the metrics of ring '%s' to the pushgateway", m.DeploymentRing)
+}
 
-		cmdd := exec.Command(ContainerEngine, "build", "-t", containerTag, "-f", Dockerfile)
+// getFanOutTargetName describes a deployment ring and its account for reporting
+func getFanOutTargetName(ring string, account string) string {
+	if account == "" {
+		return ring
+	}
 
-		cmdd.Args = append(cmdd.Args, getBuildArguments()...)
+	return fmt.Sprintf("%s (%s)", ring, account)
+}
 
-		logrus.Info(strings.Join(cmdd.Args, " "))
+// getRunArguments returns the container run arguments passing the deployment configuration to the container
+func getRunArguments(account string) (args []string) {
+	args = getRunConfigArguments(account)
 
-		var stdoutBuf, stderrBuf bytes.Buffer
+	for _, env := range getPassthroughEnv() {
+		args = append(args, "-e", env)
+	}
 
-		cmdd.Env = append(os.Environ(), buildKit)
-		s := spinner.New(spinner.CharSets[11], 100*time.Millisecond)
-		s.Suffix = " Building project container..."
+	return
+}
 
-		if Dockerfile != "" {
-			cmdd.Stdout = io.MultiWriter(os.Stdout, &stdoutBuf)
-			cmdd.Stderr = io.MultiWriter(os.Stderr, &stderrBuf)
+// getRingRunArguments returns the container run arguments of the deployment ring, creating the host directories
+// of the artifacts, manifest and sarif mounts
+func getRingRunArguments(ring string, account string, multipleRings bool, runnerLogEnv string) ([]string, error) {
+	runArgs := getRunArguments(account)
 
-			err := cmdd.Run()
-			if err != nil {
-				log.Fatalf("Runiac failed to build %s", Dockerfile)
-			}
-		} else {
-			s.Start()
-			b, err := cmdd.CombinedOutput()
-			if err != nil {
-				s.Stop()
-				logrus.Error(string(b))
-				logrus.WithError(err).Fatalf("Building project container failed with %s\n", err)
-			}
+	// recorded in the ring's local state directory for runiac output and resuming the ring's deploy
+	runArgs = appendE(runArgs, "OUTPUTS_PATH", containerOutputs)
+	runArgs = appendE(runArgs, "CHECKPOINT_PATH", containerCheckpoint)
 
-			s.Stop()
-		}
+	if runnerLogEnv != "" {
+		runArgs = append(runArgs, "-e", runnerLogEnv)
+	}
 
-		logrus.Info("Completed build, lets run!")
+	artifactsArgs, err := getArtifactsArguments(getRingScopedDir(OutputDir, ring, multipleRings), getRingScopedDir(ArtifactsFrom, ring, multipleRings))
+	if err != nil {
+		return nil, err
+	}
 
-		cmd2 := exec.Command(ContainerEngine, "run", "--rm")
+	runArgs = append(runArgs, artifactsArgs...)
 
-		cmd2.Env = append(os.Environ(), buildKit)
+	policyArgs, err := getPolicyArguments(appFS, Policy)
+	if err != nil {
+		return nil, err
+	}
 
-		// pre-configure for local development experience
-		if Local {
-			namespace, err := getMachineName()
+	runArgs = append(runArgs, policyArgs...)
 
-			if err != nil {
-				logrus.WithError(err).Fatal(err)
+	manifestArgs, err := getHostFileArguments(getRingScopedFile(ExportManifest, ring, multipleRings), containerManifestDir, "MANIFEST_PATH")
+	if err != nil {
+		return nil, err
+	}
+
+	runArgs = append(runArgs, manifestArgs...)
+
+	sarifArgs, err := getHostFileArguments(getRingScopedFile(Sarif, ring, multipleRings), containerSarifDir, "SARIF_PATH")
+	if err != nil {
+		return nil, err
+	}
+
+	runArgs = append(runArgs, sarifArgs...)
+
+	reportArgs, err := getHostFileArguments(getRingScopedFile(ReportPath, ring, multipleRings), containerReportDir, "REPORT_PATH")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(runArgs, reportArgs...), nil
+}
+
+// getContainerTag returns the tag of the project container built from the dockerfile, ring specific dockerfiles
+// are tagged with the ring name
+func getContainerTag(dockerfile string, ring string) string {
+	containerTag := viper.GetString("project")
+
+	if dockerfile != Dockerfile {
+		containerTag = fmt.Sprintf("%s-%s", containerTag, strings.ToLower(ring))
+	}
+
+	return containerTag
+}
+
+// getRunConfigArguments returns the container run arguments for the settings resolved by runiac
+func getRunConfigArguments(account string) (args []string) {
+	args = appendEIfSet(args, "DEPLOYMENT_RING", DeploymentRing)
+	args = appendEIfSet(args, "RUNNER", Runner)
+
+	if len(RunnerArgs) > 0 {
+		// json so individual arguments may contain commas
+		runnerArgs, _ := json.Marshal(RunnerArgs)
+		args = appendE(args, "RUNNER_ARGS", string(runnerArgs))
+	}
+
+	args = appendEIfSet(args, "NAMESPACE", Namespace)
+	args = appendEIfSet(args, "VERSION", AppVersion)
+	args = appendEIfSet(args, "ENVIRONMENT", Environment)
+	args = appendEIfSet(args, "DRY_RUN", fmt.Sprintf("%v", DryRun))
+	args = appendEIfSet(args, "SELF_DESTROY", fmt.Sprintf("%v", SelfDestroy))
+
+	if Destroy {
+		args = appendE(args, "DESTROY", "true")
+	}
+
+	args = appendEIfSet(args, "STEP_WHITELIST", strings.Join(StepWhitelist, ","))
+	args = appendEIfSet(args, "TEARDOWN_ORDER", strings.Join(TeardownOrder, ","))
+
+	if len(PrimaryRegions) > 0 {
+		args = appendEIfSet(args, "PRIMARY_REGION", PrimaryRegions[0])
+		args = appendEIfSet(args, "PRIMARY_REGIONS", strings.Join(PrimaryRegions, ","))
+	}
+
+	if ParallelPrimary {
+		args = appendE(args, "PARALLEL_PRIMARY_REGIONS", "true")
+	}
+
+	if len(RegionalRegions) > 0 {
+		args = appendEIfSet(args, "REGIONAL_REGIONS", strings.Join(RegionalRegions, ","))
+	} else if OnlyChanged {
+		// an empty list would fall back to the regional regions in the runiac config
+		args = appendE(args, "SKIP_REGIONAL", "true")
+	}
+	args = appendEIfSet(args, "ACCOUNT_ID", account)
+	args = appendEIfSet(args, "LOG_LEVEL", LogLevel)
+	args = appendEIfSet(args, "LOG_FORMAT", getContainerLogFormat(UI, LogFormat))
+
+	if PlanThreshold > 0 {
+		args = appendE(args, "PLAN_SUMMARY_THRESHOLD", strconv.Itoa(PlanThreshold))
+	}
+
+	if MaxParallel > 0 {
+		args = appendE(args, "MAX_PARALLEL", strconv.Itoa(MaxParallel))
+	}
+
+	if AbortThreshold > 0 {
+		args = appendE(args, "ABORT_AFTER_FAILURES", strconv.Itoa(AbortThreshold))
+	}
+
+	if StepTimeout > 0 {
+		args = appendE(args, "STEP_TIMEOUT", StepTimeout.String())
+	}
+
+	if StepRetries > 0 {
+		args = appendE(args, "STEP_RETRIES", strconv.Itoa(StepRetries))
+	}
+
+	if StepRetryBackoff != defaultStepRetryBackoff {
+		args = appendE(args, "STEP_RETRY_BACKOFF", StepRetryBackoff.String())
+	}
+
+	if TestRollback {
+		args = appendE(args, "TEST_ROLLBACK", "true")
+	}
+
+	if RollbackOnFail {
+		args = appendE(args, "ROLLBACK_ON_FAILURE", "true")
+	}
+
+	if Resume {
+		args = appendE(args, "RESUME", "true")
+	}
+
+	args = appendEIfSet(args, "FROM_STEP", FromStep)
+
+	if Confirm {
+		args = appendE(args, "CONFIRMED", "true")
+	}
+
+	if PrePullProviders {
+		args = appendE(args, "PRE_PULL_PROVIDERS", "true")
+	}
+
+	if SimulateIAM {
+		args = appendE(args, "SIMULATE_IAM", "true")
+	}
+
+	if CostEstimate {
+		args = appendE(args, "COST_ESTIMATE", "true")
+	}
+
+	if ScanOnly {
+		args = appendE(args, "SCAN_ONLY", "true")
+	}
+
+	if Drift {
+		args = appendE(args, "DRIFT", "true")
+	}
+
+	if PauseTracks {
+		args = appendE(args, "PAUSE_BETWEEN_TRACKS", "true")
+	}
+
+	// the tracks requiring approval are confirmed interactively or requested from the approval endpoint
+	if Interactive {
+		args = appendE(args, "INTERACTIVE", "true")
+	}
+
+	if ApprovalURL != "" {
+		args = appendE(args, "APPROVAL_URL", ApprovalURL)
+		args = appendE(args, "APPROVAL_TIMEOUT", ApprovalTimeout.String())
+	}
+
+	// the ring variables and features are validated before building
+	variables, _ := getRingVariables(DeploymentRing)
+	args = append(args, getRingVariableArguments(variables)...)
+
+	features, _ := resolveFeatures(DeploymentRing, Features)
+	args = append(args, getFeatureArguments(features)...)
+
+	for _, e := range resolvedSecretEnv {
+		args = append(args, "-e", e)
+	}
+
+	for _, e := range ExtraEnv {
+		args = append(args, "-e", e)
+	}
+
+	return
+}
+
+// getPassthroughEnv returns the host environment variables forwarded into the container, those set explicitly
+// with --env or resolved from the secrets section and the host locations of credential files are not forwarded
+func getPassthroughEnv() (env []string) {
+	prefixes := getEnvPrefixes()
+	explicit := map[string]bool{}
+
+	for _, name := range credentialFileEnv {
+		explicit[name] = true
+	}
+
+	for _, e := range append(append([]string{}, resolvedSecretEnv...), ExtraEnv...) {
+		explicit[strings.SplitN(e, "=", 2)[0]] = true
+	}
+
+	for _, e := range os.Environ() {
+		if explicit[strings.SplitN(e, "=", 2)[0]] {
+			continue
+		}
+
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(e, prefix) {
+				env = append(env, e)
+				break
 			}
+		}
+	}
+
+	return
+}
 
-			Namespace = namespace
-			DeploymentRing = "local"
-		} else if PullRequest != "" {
-			Namespace = PullRequest
-			DeploymentRing = "pr"
+// getEnvFromArgs returns the KEY=VALUE pairs set with -e in container run arguments
+func getEnvFromArgs(args []string) (env []string) {
+	for i := 0; i < len(args)-1; i++ {
+		if args[i] == "-e" {
+			env = append(env, args[i+1])
+			i++
 		}
+	}
+
+	return
+}
+
+// replaceEnvArguments returns the container run arguments with the overrides appended, the env variables set in
+// args that the overrides set again are removed since container engines do not agree on which value wins
+func replaceEnvArguments(args []string, overrides []string) []string {
+	replaced := map[string]bool{}
+
+	for _, e := range getEnvFromArgs(overrides) {
+		replaced[strings.SplitN(e, "=", 2)[0]] = true
+	}
 
-		cmd2.Args = appendEIfSet(cmd2.Args, "DEPLOYMENT_RING", DeploymentRing)
-		cmd2.Args = appendEIfSet(cmd2.Args, "RUNNER", Runner)
-		cmd2.Args = appendEIfSet(cmd2.Args, "NAMESPACE", Namespace)
-		cmd2.Args = appendEIfSet(cmd2.Args, "VERSION", AppVersion)
-		cmd2.Args = appendEIfSet(cmd2.Args, "ENVIRONMENT", Environment)
-		cmd2.Args = appendEIfSet(cmd2.Args, "DRY_RUN", fmt.Sprintf("%v", DryRun))
-		cmd2.Args = appendEIfSet(cmd2.Args, "SELF_DESTROY", fmt.Sprintf("%v", SelfDestroy))
-		cmd2.Args = appendEIfSet(cmd2.Args, "STEP_WHITELIST", strings.Join(StepWhitelist, ","))
+	result := []string{}
 
-		if len(PrimaryRegions) > 0 {
-			cmd2.Args = appendEIfSet(cmd2.Args, "PRIMARY_REGION", PrimaryRegions[0])
+	for i := 0; i < len(args); i++ {
+		if args[i] == "-e" && i+1 < len(args) && replaced[strings.SplitN(args[i+1], "=", 2)[0]] {
+			i++
+			continue
 		}
 
-		if len(RegionalRegions) > 0 {
-			cmd2.Args = appendEIfSet(cmd2.Args, "REGIONAL_REGIONS", strings.Join(RegionalRegions, ","))
+		result = append(result, args[i])
+	}
+
+	return append(result, overrides...)
+}
+
+// printResolvedVars prints the environment variables passed to the container grouped by their source with
+// sensitive values masked
+func printResolvedVars(w io.Writer, ring string, runiacEnv []string, hostEnv []string) {
+	if ring != "" {
+		fmt.Fprintf(w, "Deployment ring: %s\n", ring)
+	}
+
+	groups := []struct {
+		source string
+		env    []string
+	}{
+		{"runiac (flags and config)", runiacEnv},
+		{"host environment", hostEnv},
+	}
+
+	for _, g := range groups {
+		fmt.Fprintf(w, "  %s:\n", g.source)
+
+		if len(g.env) == 0 {
+			fmt.Fprintln(w, "    (none)")
 		}
-		cmd2.Args = appendEIfSet(cmd2.Args, "ACCOUNT_ID", Account)
-		cmd2.Args = appendEIfSet(cmd2.Args, "LOG_LEVEL", LogLevel)
 
-		if Interactive {
-			cmd2.Args = append(cmd2.Args, "-it")
+		for _, e := range g.env {
+			fmt.Fprintf(w, "    %s\n", maskEnv(e))
 		}
+	}
+}
 
-		// TODO: how best to allow consumer whitelist environment variables or simply pass all in?
-		for _, env := range cmd2.Env {
-			if strings.HasPrefix(env, "TF_VAR_") {
-				cmd2.Args = append(cmd2.Args, "-e", env)
-			}
+// getContainerRunModeArguments returns the run command along with the container lifecycle arguments
+func getContainerRunModeArguments() []string {
+	args := []string{"run"}
 
-			if strings.HasPrefix(env, "ARM_") {
-				cmd2.Args = append(cmd2.Args, "-e", env)
-			}
+	// the container can not be removed on exit when the engine is restarting it
+	if RestartPolicy == "" {
+		args = append(args, "--rm")
+	} else {
+		args = append(args, "--restart", RestartPolicy)
+	}
 
-			if strings.HasPrefix(env, "RUNIAC_") {
-				cmd2.Args = append(cmd2.Args, "-e", env)
-			}
+	if Detach {
+		args = append(args, "-d")
+	}
 
-			if strings.HasPrefix(env, "AWS_") {
-				cmd2.Args = append(cmd2.Args, "-e", env)
-			}
+	return args
+}
+
+// validateRestartPolicy ensures the restart policy is supported and only used with detached containers
+func validateRestartPolicy(policy string, detach bool) error {
+	if policy == "" {
+		return nil
+	}
+
+	if !detach {
+		return fmt.Errorf("--restart can only be used with --detach")
+	}
+
+	if policy == "no" || policy == "always" || policy == "unless-stopped" || policy == "on-failure" {
+		return nil
+	}
+
+	if strings.HasPrefix(policy, "on-failure:") {
+		retries, err := strconv.Atoi(strings.TrimPrefix(policy, "on-failure:"))
+		if err == nil && retries > 0 {
+			return nil
 		}
+	}
 
-		// handle local volume maps
-		dir, err := os.Getwd()
-		if err != nil {
-			log.Fatal(err)
+	return fmt.Errorf("invalid restart policy '%s', must be one of no, always, unless-stopped or on-failure[:max-retries]", policy)
+}
+
+// validateNamespaceMode ensures a --pid or --ipc namespace is one of the allowed modes or another container's
+func validateNamespaceMode(flag string, mode string, allowed []string) error {
+	if mode == "" {
+		return nil
+	}
+
+	for _, a := range allowed {
+		if mode == a {
+			return nil
 		}
+	}
+
+	if strings.HasPrefix(mode, "container:") && len(mode) > len("container:") {
+		return nil
+	}
+
+	return fmt.Errorf("invalid --%s '%s', must be one of %s or container:<name|id>", flag, mode, strings.Join(allowed, ", "))
+}
 
-		// persist azure cli between container executions
-		cmd2.Args = append(cmd2.Args, "-v", fmt.Sprintf("%s/.runiac/.azure:/root/.azure", dir))
+// getNamespaceArguments returns the container run arguments sharing the pid and ipc namespaces when set
+func getNamespaceArguments(pid string, ipc string) (args []string) {
+	if pid != "" {
+		args = append(args, "--pid", pid)
+	}
 
-		// persist gcloud cli
-		cmd2.Args = append(cmd2.Args, "-v", fmt.Sprintf("%s/.runiac/.config/gcloud:/root/.config/gcloud", dir))
+	if ipc != "" {
+		args = append(args, "--ipc", ipc)
+	}
 
-		// persist aws cli
-		cmd2.Args = append(cmd2.Args, "-v", fmt.Sprintf("%s/.runiac/.aws:/root/.aws", dir))
+	return
+}
 
-		// persist local terraform state between container executions
-		cmd2.Args = append(cmd2.Args, "-v", fmt.Sprintf("%s/.runiac/tfstate:/runiac/tfstate", dir))
+// validateDockerSocket ensures the --docker-socket path is a unix socket
+func validateDockerSocket(path string) error {
+	if path == "" {
+		return nil
+	}
 
-		cmd2.Args = append(cmd2.Args, containerTag)
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("invalid --docker-socket '%s': %w", path, err)
+	}
 
-		logrus.Info(strings.Join(cmd2.Args, " "))
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("invalid --docker-socket '%s', not a unix socket", path)
+	}
 
-		cmd2.Stdout = io.MultiWriter(os.Stdout, &stdoutBuf)
-		cmd2.Stderr = io.MultiWriter(os.Stderr, &stderrBuf)
-		cmd2.Stdin = os.Stdin
+	return nil
+}
 
-		err2 := cmd2.Run()
-		if err2 != nil {
-			log.Fatalf("Running iac failed with %s\n", err2)
-		}
-	},
+// getDockerSocketArguments returns the container run arguments mounting the docker socket when set
+func getDockerSocketArguments(path string) (args []string) {
+	if path == "" {
+		return
+	}
+
+	return getVolumeArguments(path, dockerSocket, false)
 }
 
-// setStringFlag - If flag is changed via command line, do nothing, else check config file for value.
-func setStringFlag(cmd *cobra.Command, flag *string, cmdLineOption string, configOption string) {
-	if cmd.Flags().Changed(cmdLineOption) == false {
-		configValue := viper.GetString(configOption)
+// runContainer runs the project container with the provided arguments and the local volume maps
+func runContainer(containerTag string, runArgs []string, recordPath string) error {
+	cmd2 := exec.Command(ContainerEngine, getContainerRunModeArguments()...)
+
+	cmd2.Env = append(os.Environ(), buildKit)
+
+	cmd2.Args = append(cmd2.Args, runArgs...)
+
+	if Interactive {
+		cmd2.Args = append(cmd2.Args, getInteractiveArguments(isTerminal(os.Stdin))...)
+	}
+
+	// label every run so runiac containers are discoverable, e.g. docker ps --filter label=runiac.ring=prod
+	cmd2.Args = append(cmd2.Args, getContainerLabelArguments(DeploymentRing, Namespace, Environment, AppVersion)...)
+
+	cmd2.Args = append(cmd2.Args, getNamespaceArguments(PidMode, IpcMode)...)
+
+	cmd2.Args = append(cmd2.Args, getDockerSocketArguments(DockerSocket)...)
+
+	// handle local volume maps, the project is copied into the image so these stay relative to the
+	// working directory even when the image was built from a --context tarball
+	dir, err := os.Getwd()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// persist local terraform state between container executions, isolated by deployment ring
+	stateDir, err := prepareRingStateDir(appFS, DeploymentRing, MigrateStateTo)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	cmd2.Args = append(cmd2.Args, getLocalVolumeArguments(dir, stateDir)...)
 
-		if configValue != "" {
-			*flag = configValue
+	cmd2.Args = append(cmd2.Args, containerTag)
+
+	logrus.Info(strings.Join(maskArgs(cmd2.Args), " "))
+
+	var stdoutBuf, stderrBuf bytes.Buffer
+
+	terminalOut, terminalErr := getTerminalWriters()
+
+	stdout, stderr := io.MultiWriter(terminalOut, &stdoutBuf), io.MultiWriter(terminalErr, &stderrBuf)
+	cmd2.Stdin = os.Stdin
+
+	var recorder *sessionRecorder
+
+	if recordPath != "" {
+		recorder, err = newSessionRecorder(recordPath, maskArgs(cmd2.Args))
+		if err != nil {
+			log.Fatalf("Unable to record session to %s: %s\n", recordPath, err)
+		}
+
+		logrus.Infof("Recording session to %s", recordPath)
+
+		stdout = io.MultiWriter(terminalOut, &stdoutBuf, recorder)
+		stderr = io.MultiWriter(terminalErr, &stderrBuf, recorder)
+	}
+
+	// mask the sensitive values the steps may echo before the output reaches the terminal, buffers and recording
+	maskedStdout, maskedStderr := getMaskingWriters(getEnvFromArgs(cmd2.Args), stdout, stderr)
+	cmd2.Stdout, cmd2.Stderr = maskedStdout, maskedStderr
+
+	err2 := runCommand(cmd2)
+
+	_ = maskedStdout.Flush()
+	_ = maskedStderr.Flush()
+
+	if recorder != nil {
+		if err := recorder.Close(err2); err != nil {
+			logrus.WithError(err).Warnf("Unable to finish recording session to %s", recordPath)
+		}
+	}
+
+	// the container engine prints the id of the detached container
+	if Detach && err2 == nil {
+		if err := startLogCapture(strings.TrimSpace(stdoutBuf.String()), MaxLogSize); err != nil {
+			logrus.WithError(err).Warn("Unable to capture the output of the detached container, runiac logs --from-start will not be available")
 		}
 	}
+
+	return err2
+}
+
+// getLocalVolumeArguments returns the volume map persisting the ring's local terraform state in the working
+// directory between container executions, the cloud credentials are mounted by getCredentialArguments
+func getLocalVolumeArguments(dir string, stateDir string) (args []string) {
+	return getVolumeArguments(filepath.Join(dir, stateDir), containerTFState, false)
+}
+
+// getContainerLabelArguments returns the container run arguments labeling the container with the resolved run context
+func getContainerLabelArguments(ring string, namespace string, environment string, version string) (args []string) {
+	labels := []struct {
+		name  string
+		value string
+	}{
+		{"runiac.ring", ring},
+		{"runiac.namespace", namespace},
+		{"runiac.environment", environment},
+		{"runiac.version", version},
+	}
+
+	for _, l := range labels {
+		args = append(args, "--label", fmt.Sprintf("%s=%s", l.name, l.value))
+	}
+
+	return
 }
 
 func appendEIfSet(slice []string, arg string, val string) []string {
@@ -243,31 +1807,95 @@ func appendE(slice []string, arg string, val string) []string {
 	return append(slice, "-e", fmt.Sprintf("RUNIAC_%s=%s", arg, val))
 }
 
-func checkDockerExists() {
-	_, err := exec.LookPath(ContainerEngine)
-	if err != nil {
-		fmt.Printf("please add '%s' to the path\n", ContainerEngine)
+// validateRunner returns an error when the runner is neither supported nor a valid external runner plugin name or
+// does not accept --runner-arg. External runners execute runiac-runner-{name} of the container's path, a name within
+// a typo of a supported runner, e.g. terrafrom, is rejected instead of failing in the container after the build.
+func validateRunner(runner string, runnerArgs []string) error {
+	supported := false
+
+	for _, r := range supportedRunners {
+		if runner == r {
+			supported = true
+		}
 	}
-}
 
-func checkInitialized() bool {
-	return InitAction()
+	if !supported && !validExternalRunner.MatchString(runner) {
+		return fmt.Errorf("invalid runner '%s', must be one of %s or the name of an external runner plugin, runiac-runner-{name}", runner, strings.Join(supportedRunners, ", "))
+	}
+
+	if !supported {
+		for _, r := range supportedRunners {
+			// a short name is only a typo of a runner of a similar length by a single character, e.g. arn of arm
+			if d := getEditDistance(runner, r); d <= maxRunnerTypoDistance && d <= len(r)/3 {
+				return fmt.Errorf("invalid runner '%s', did you mean '%s'? External runner plugins may not be named like a supported runner", runner, r)
+			}
+		}
+	}
+
+	if len(runnerArgs) > 0 && supported && runner != "pulumi" {
+		return fmt.Errorf("runner '%s' does not support --runner-arg", runner)
+	}
+
+	return nil
 }
 
-func getBuildArguments() (args []string) {
-	// check viper configuration if not set
-	if Container == "" && viper.GetString("container") != "" {
-		Container = viper.GetString("container")
+// getEditDistance returns the levenshtein distance of a and b, the number of single character insertions, deletions
+// and substitutions changing a into b
+func getEditDistance(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+
+		for j := 1; j <= len(b); j++ {
+			current[j] = previous[j-1]
+			if a[i-1] != b[j-1] {
+				current[j]++
+			}
+
+			if previous[j]+1 < current[j] {
+				current[j] = previous[j] + 1
+			}
+
+			if current[j-1]+1 < current[j] {
+				current[j] = current[j-1] + 1
+			}
+		}
+
+		previous = current
 	}
 
-	if Container != "" {
-		args = append(args, "--build-arg", fmt.Sprintf("RUNIAC_CONTAINER=%s", Container))
+	return previous[len(b)]
+}
+
+// getRunnerLogLevelEnv maps the runner log level to the runner's native logging environment variable
+func getRunnerLogLevelEnv(runner string, level string) (string, error) {
+	if level == "" {
+		return "", nil
 	}
 
-	// must be last argument added for docker build current directory context
-	args = append(args, ".")
+	switch runner {
+	case "terraform", "terragrunt":
+		lvl := strings.ToUpper(level)
 
-	return
+		for _, valid := range []string{"TRACE", "DEBUG", "INFO", "WARN", "ERROR"} {
+			if lvl == valid {
+				return fmt.Sprintf("TF_LOG=%s", lvl), nil
+			}
+		}
+
+		return "", fmt.Errorf("invalid runner log level '%s' for %s, must be one of trace, debug, info, warn or error", level, runner)
+	default:
+		return "", fmt.Errorf("runner '%s' does not support --runner-log-level", runner)
+	}
+}
+
+func checkInitialized() bool {
+	return InitAction()
 }
 
 func getMachineName() (string, error) {
@@ -277,10 +1905,10 @@ func getMachineName() (string, error) {
 		return sanitizeMachineName(username), nil
 	}
 
-	// This handles Windows platforms
+	// This handles Windows platforms, the account may be qualified with its domain
 	username = os.Getenv("USERNAME")
 	if username != "" {
-		return sanitizeMachineName(username), nil
+		return sanitizeMachineName(getAccountName(username)), nil
 	}
 
 	// This is for other platforms without ENV vars set above
@@ -302,7 +1930,8 @@ func getMachineName() (string, error) {
 		return "", err
 	}
 
-	return sanitizeMachineName(string(out)), err
+	// whoami prints DOMAIN\user on windows
+	return sanitizeMachineName(getAccountName(string(out))), err
 }
 
 func sanitizeMachineName(s string) string {
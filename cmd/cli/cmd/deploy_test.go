@@ -1,8 +1,14 @@
 package cmd
 
 import (
+	"bytes"
+	"errors"
+	"net"
+	"os/exec"
+	"path/filepath"
 	"testing"
 
+	"github.com/spf13/afero"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/require"
 )
@@ -67,3 +73,187 @@ func Test_DeployCommand(t *testing.T) {
 	require.Equal(t, "mockofseagulls", ContainerEngine)
 	require.Equal(t, "mockofseagulls", Container)
 }
+
+func TestGetRunnerLogLevelEnv_ShouldMapToNativeRunnerLogging(t *testing.T) {
+	env, err := getRunnerLogLevelEnv("terraform", "debug")
+	require.NoError(t, err)
+	require.Equal(t, "TF_LOG=DEBUG", env)
+
+	env, err = getRunnerLogLevelEnv("terraform", "")
+	require.NoError(t, err)
+	require.Equal(t, "", env)
+
+	_, err = getRunnerLogLevelEnv("terraform", "verbose")
+	require.Error(t, err)
+
+	env, err = getRunnerLogLevelEnv("terragrunt", "trace")
+	require.NoError(t, err)
+	require.Equal(t, "TF_LOG=TRACE", env)
+
+	_, err = getRunnerLogLevelEnv("arm", "debug")
+	require.Error(t, err)
+}
+
+func TestValidateRunner_ShouldOnlyAllowSupportedRunners(t *testing.T) {
+	require.NoError(t, validateRunner("terraform", nil))
+	require.NoError(t, validateRunner("pulumi", []string{"--parallel", "4"}))
+	require.NoError(t, validateRunner("terragrunt", nil))
+	require.NoError(t, validateRunner("ansible", []string{"--check"}), "external runner plugins accept runner args")
+	require.Error(t, validateRunner("../ansible", nil))
+	require.Error(t, validateRunner("Ansible Runner", nil))
+	require.Error(t, validateRunner("terraform", []string{"--parallelism=4"}), "only pulumi accepts runner args")
+	require.Error(t, validateRunner("terrafrom", nil), "a typo of a supported runner is not an external runner plugin")
+	require.Error(t, validateRunner("pulumni", nil))
+	require.NoError(t, validateRunner("aws", nil), "a short plugin name is not a typo of arm")
+	require.Error(t, validateRunner("arn", nil))
+}
+
+func TestResolveContainerEngine_ShouldPreferFirstAvailableEngine(t *testing.T) {
+	defer func(probe func(string) error) {
+		lookPath, probeContainerEngine = exec.LookPath, probe
+	}(probeContainerEngine)
+
+	available := map[string]bool{"podman": true, "nerdctl": true}
+	lookPath = func(file string) (string, error) {
+		if available[file] {
+			return "/usr/bin/" + file, nil
+		}
+		return "", errors.New("not found")
+	}
+	probeContainerEngine = func(engine string) error { return nil }
+
+	engine, err := resolveContainerEngine("")
+	require.NoError(t, err)
+	require.Equal(t, "podman", engine)
+
+	available = map[string]bool{}
+	_, err = resolveContainerEngine(containerEngineAuto)
+	require.Error(t, err)
+}
+
+func TestPrintResolvedVars_ShouldGroupBySourceAndMaskSecrets(t *testing.T) {
+	var b bytes.Buffer
+
+	printResolvedVars(&b, "prod", []string{"RUNIAC_ACCOUNT_ID=1234"}, []string{"TF_VAR_name=stub", "ARM_CLIENT_SECRET=hunter2"})
+
+	out := b.String()
+	require.Contains(t, out, "Deployment ring: prod")
+	require.Contains(t, out, "RUNIAC_ACCOUNT_ID=1234")
+	require.Contains(t, out, "TF_VAR_name=stub")
+	require.Contains(t, out, "ARM_CLIENT_SECRET=***")
+	require.NotContains(t, out, "hunter2")
+}
+
+func TestValidateRestartPolicy_ShouldOnlyAllowValidPoliciesWhenDetached(t *testing.T) {
+	valid := []string{"", "no", "always", "unless-stopped", "on-failure", "on-failure:3"}
+	for _, policy := range valid {
+		require.NoError(t, validateRestartPolicy(policy, true), "policy '%s' should be valid", policy)
+	}
+
+	invalid := []string{"sometimes", "on-failure:", "on-failure:-1", "on-failure:x"}
+	for _, policy := range invalid {
+		require.Error(t, validateRestartPolicy(policy, true), "policy '%s' should be invalid", policy)
+	}
+
+	require.Error(t, validateRestartPolicy("always", false), "restart policy requires --detach")
+}
+
+func TestValidateNamespaceMode_ShouldOnlyAllowSupportedModes(t *testing.T) {
+	for _, mode := range []string{"", "host", "container:debugger"} {
+		require.NoError(t, validateNamespaceMode("pid", mode, []string{"host"}), "pid '%s' should be valid", mode)
+	}
+
+	for _, mode := range []string{"private", "container:", "hosts"} {
+		require.Error(t, validateNamespaceMode("pid", mode, []string{"host"}), "pid '%s' should be invalid", mode)
+	}
+
+	require.NoError(t, validateNamespaceMode("ipc", "shareable", []string{"none", "private", "shareable", "host"}))
+	require.Equal(t, []string{"--pid", "host", "--ipc", "container:debugger"}, getNamespaceArguments("host", "container:debugger"))
+	require.Empty(t, getNamespaceArguments("", ""))
+}
+
+func TestValidateDockerSocket_ShouldRequireUnixSocket(t *testing.T) {
+	dir := t.TempDir()
+	socket := filepath.Join(dir, "docker.sock")
+
+	l, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	defer l.Close()
+
+	require.NoError(t, validateDockerSocket(""))
+	require.NoError(t, validateDockerSocket(socket))
+	require.Error(t, validateDockerSocket(filepath.Join(dir, "missing.sock")), "a missing socket should be invalid")
+	require.Error(t, validateDockerSocket(dir), "a directory should be invalid")
+
+	require.Equal(t, []string{"-v", socket + ":/var/run/docker.sock"}, getDockerSocketArguments(socket))
+	require.Empty(t, getDockerSocketArguments(""))
+}
+
+func TestDeployCmd_ShouldParseTheDockerSocketPath(t *testing.T) {
+	defer func() {
+		DockerSocket = ""
+		_ = deployCmd.Flags().Set("docker-socket", "")
+	}()
+
+	require.NoError(t, deployCmd.ParseFlags([]string{"--docker-socket", "/custom.sock"}))
+	require.Equal(t, "/custom.sock", DockerSocket)
+	require.Empty(t, deployCmd.Flags().Args(), "the path should not be parsed as an argument")
+
+	require.Error(t, deployCmd.Args(deployCmd, []string{"/custom.sock"}), "deploy should not accept arguments")
+}
+
+func TestGetContainerLabelArguments_ShouldAlwaysLabelRunContext(t *testing.T) {
+	require.Equal(t, []string{
+		"--label", "runiac.ring=prod",
+		"--label", "runiac.namespace=",
+		"--label", "runiac.environment=production",
+		"--label", "runiac.version=v1.2.3",
+	}, getContainerLabelArguments("prod", "", "production", "v1.2.3"))
+}
+
+func TestConfigureSavedPlans_ShouldSaveDryRunPlansPerEnvironment(t *testing.T) {
+	defer func(fs afero.Fs) { appFS = fs }(appFS)
+	defer func() { DryRun, UseSavedPlans, OutputDir, ArtifactsFrom, Environment = false, false, "", "", "" }()
+
+	appFS = afero.NewMemMapFs()
+	dir := filepath.Join(planDir, "prod")
+	_ = afero.WriteFile(appFS, filepath.Join(dir, "track", "stale", "tfplan"), []byte("stale plan"), 0644)
+
+	DryRun, Environment = true, "Prod"
+	require.NoError(t, configureSavedPlans(actionDeploy))
+	require.Equal(t, dir, OutputDir)
+
+	exists, _ := afero.Exists(appFS, filepath.Join(dir, "track", "stale", "tfplan"))
+	require.False(t, exists, "the plans of the previous dry run should be replaced")
+
+	// an explicit --output-dir is kept
+	OutputDir = "output"
+	require.NoError(t, configureSavedPlans(actionDeploy))
+	require.Equal(t, "output", OutputDir)
+
+	DryRun, OutputDir = false, ""
+	require.NoError(t, configureSavedPlans(actionDeploy))
+	require.Equal(t, "", OutputDir, "only dry runs save their plans")
+}
+
+func TestConfigureSavedPlans_ShouldApplyTheSavedPlansOfTheEnvironment(t *testing.T) {
+	defer func(fs afero.Fs) { appFS = fs }(appFS)
+	defer func() { DryRun, UseSavedPlans, OutputDir, ArtifactsFrom, Environment = false, false, "", "", "" }()
+
+	appFS = afero.NewMemMapFs()
+	UseSavedPlans, Environment = true, "dev"
+
+	require.Error(t, configureSavedPlans(actionDeploy), "the environment has no saved plans")
+
+	require.NoError(t, appFS.MkdirAll(filepath.Join(planDir, "dev"), 0755))
+	require.NoError(t, configureSavedPlans(actionDeploy))
+	require.Equal(t, filepath.Join(planDir, "dev"), ArtifactsFrom)
+
+	require.Error(t, configureSavedPlans(actionDeploy), "--artifacts-from is already set")
+
+	ArtifactsFrom, DryRun = "", true
+	require.Error(t, configureSavedPlans(actionDeploy), "saved plans can not be applied by a dry run")
+
+	DryRun = false
+	require.Error(t, configureSavedPlans(actionPlan))
+}
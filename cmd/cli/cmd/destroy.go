@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	destroyCmd.Flags().AddFlagSet(deployCmd.Flags())
+
+	rootCmd.AddCommand(destroyCmd)
+}
+
+var destroyCmd = &cobra.Command{
+	Use:   "destroy",
+	Short: "Destroy deployed configurations",
+	Long: `Destroys the resources previously deployed by each step without deploying first, accepting the flags of
+deploy. The steps are planned to read the output variables the destroy depends on, then destroyed in reverse
+progression order, or in the --teardown-order. With --dry-run the destroy is only planned.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		failed := runDeploy(cmd, actionDestroy)
+
+		printRunCostEstimates(runOutput, "text")
+
+		return getRunError(cmd, failed)
+	},
+}
+
+// configureDestroy makes the run destroy the deployed resources without deploying
+func configureDestroy() error {
+	if SelfDestroy {
+		return fmt.Errorf("--self-destroy can not be used with destroy, destroy does not deploy")
+	}
+
+	Destroy = true
+
+	return nil
+}
@@ -0,0 +1,18 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigureDestroy_ShouldDestroyWithoutDeploying(t *testing.T) {
+	defer func() { Destroy, SelfDestroy = false, false }()
+
+	require.NoError(t, configureDestroy())
+	require.True(t, Destroy)
+	require.Contains(t, getEnvFromArgs(getRunConfigArguments("")), "RUNIAC_DESTROY=true")
+
+	SelfDestroy = true
+	require.Error(t, configureDestroy(), "destroy does not deploy, so it can not self destroy")
+}
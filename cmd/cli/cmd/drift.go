@@ -0,0 +1,271 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	driftExitFailed   = 1 // A step's drift could not be detected
+	driftExitDetected = 2 // A step's resources drifted from its state
+)
+
+// Drift plans every step refresh-only to detect the resources that drifted from the state
+var Drift bool
+
+var driftFormat string
+
+func init() {
+	driftCmd.Flags().AddFlagSet(deployCmd.Flags())
+	driftCmd.Flags().StringVar(&driftFormat, "output", "text", "The drift report format, text or json. With json the container output is written to stderr")
+
+	rootCmd.AddCommand(driftCmd)
+}
+
+var driftCmd = &cobra.Command{
+	Use:   "drift",
+	Short: "Detect the drift of deployed configurations",
+	Long: fmt.Sprintf(`Plans every step refresh-only in all of its regions inside the container, accepting the flags of deploy,
+and reports the resources changed or deleted outside of runiac since the step was deployed. Nothing is
+applied, neither the state nor the resources are modified. Only terraform steps detect drift.
+
+The exit code suits scheduled jobs: %d when drift was detected, %d when a step's drift could not be detected
+and 0 when no step drifted. With --output json the report lists the drifted resources of every step.`, driftExitDetected, driftExitFailed),
+	Run: func(cmd *cobra.Command, args []string) {
+		if driftFormat != "text" && driftFormat != "json" {
+			logrus.Fatalf("invalid --output '%s', must be text or json", driftFormat)
+		}
+
+		if driftFormat == "json" {
+			runOutput = os.Stderr
+		}
+
+		failed := runDeploy(cmd, actionDrift)
+
+		// nothing was planned when only printing settings
+		if Why != "" || Test || ShowResolvedVars || PrintContext {
+			return
+		}
+
+		summary, err := readDriftSummary(appFS, writtenReports)
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		if driftFormat == "json" {
+			err = printDriftSummaryJSON(os.Stdout, summary)
+		} else {
+			err = printDriftSummary(os.Stdout, summary)
+		}
+
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		os.Exit(getDriftExitCode(summary, failed))
+	},
+}
+
+// configureDrift makes the run a dry run planning every step refresh-only
+func configureDrift() error {
+	if Detach {
+		return fmt.Errorf("--detach can not be used with drift, the drift is reported once the container exits")
+	}
+
+	if SelfDestroy {
+		return fmt.Errorf("--self-destroy can not be used with drift, drift does not deploy")
+	}
+
+	if Runner != "terraform" {
+		logrus.Warnf("The %s runner does not detect drift, only terraform steps are reported", Runner)
+	}
+
+	DryRun = true
+	Drift = true
+
+	return nil
+}
+
+// getDriftExitCode returns the exit code of the drift command, a failure to detect drift takes precedence over the
+// detected drift as the report is incomplete
+func getDriftExitCode(summary driftSummary, failed bool) int {
+	if failed || summary.Failed > 0 {
+		return driftExitFailed
+	}
+
+	if summary.Drifted > 0 {
+		return driftExitDetected
+	}
+
+	return 0
+}
+
+// reportDriftedResources is the subset of a ring's deployment report used to summarize its drift
+type reportDriftedResources struct {
+	DeploymentRing string `yaml:"deployment_ring"`
+	Steps          []struct {
+		Track            string            `yaml:"track"`
+		Step             string            `yaml:"step"`
+		RegionDeployType string            `yaml:"region_deploy_type"`
+		Region           string            `yaml:"region"`
+		Status           string            `yaml:"status"`
+		Error            string            `yaml:"error"`
+		DriftedResources []driftedResource `yaml:"drifted_resources"`
+	} `yaml:"steps"`
+}
+
+// driftedResource is a resource changed outside of runiac, the action is update or delete
+type driftedResource struct {
+	Address string `json:"address" yaml:"address"`
+	Type    string `json:"type" yaml:"type"`
+	Action  string `json:"action" yaml:"action"`
+}
+
+// stepDrift is the drift of a step in a region
+type stepDrift struct {
+	StepID           string            `json:"step_id"`
+	RegionDeployType string            `json:"region_deploy_type"`
+	Region           string            `json:"region"`
+	DeploymentRing   string            `json:"deployment_ring"`
+	Drifted          bool              `json:"drifted"`
+	Error            string            `json:"error,omitempty"` // The drift of the step could not be detected
+	Resources        []driftedResource `json:"resources"`
+}
+
+// driftSummary is the consolidated drift of a run
+type driftSummary struct {
+	Drifted   int         `json:"drifted"`   // The steps whose resources drifted
+	Failed    int         `json:"failed"`    // The steps whose drift could not be detected
+	Resources int         `json:"resources"` // The drifted resources across the steps
+	Steps     []stepDrift `json:"steps"`
+}
+
+// readDriftSummary summarizes the drifted resources of the deployment reports at paths, ordered by ring, step and
+// region
+func readDriftSummary(fs afero.Fs, paths []string) (driftSummary, error) {
+	summary := driftSummary{Steps: []stepDrift{}}
+
+	for _, path := range paths {
+		b, err := afero.ReadFile(fs, path)
+		if err != nil {
+			return summary, err
+		}
+
+		report := reportDriftedResources{}
+
+		if err = yaml.Unmarshal(b, &report); err != nil {
+			return summary, fmt.Errorf("invalid deployment report %s: %w", path, err)
+		}
+
+		for _, s := range report.Steps {
+			step := stepDrift{
+				StepID:           fmt.Sprintf("%s/%s", s.Track, s.Step),
+				RegionDeployType: s.RegionDeployType,
+				Region:           s.Region,
+				DeploymentRing:   report.DeploymentRing,
+				Drifted:          len(s.DriftedResources) > 0,
+				Error:            s.Error,
+				Resources:        []driftedResource{},
+			}
+
+			step.Resources = append(step.Resources, s.DriftedResources...)
+
+			if step.Error == "" && strings.EqualFold(s.Status, "fail") {
+				step.Error = "the step failed"
+			}
+
+			if step.Error != "" {
+				summary.Failed++
+			}
+
+			if step.Drifted {
+				summary.Drifted++
+			}
+
+			summary.Resources += len(step.Resources)
+			summary.Steps = append(summary.Steps, step)
+		}
+	}
+
+	sort.SliceStable(summary.Steps, func(i, j int) bool {
+		a, b := summary.Steps[i], summary.Steps[j]
+
+		if a.DeploymentRing != b.DeploymentRing {
+			return a.DeploymentRing < b.DeploymentRing
+		}
+
+		if a.StepID != b.StepID {
+			return a.StepID < b.StepID
+		}
+
+		if a.RegionDeployType != b.RegionDeployType {
+			return a.RegionDeployType < b.RegionDeployType
+		}
+
+		return a.Region < b.Region
+	})
+
+	return summary, nil
+}
+
+// printDriftSummary writes the drift of every step followed by the drifted resources of each drifted step
+func printDriftSummary(w io.Writer, summary driftSummary) error {
+	if len(summary.Steps) == 0 {
+		_, err := fmt.Fprintln(w, "No steps were checked for drift")
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "RING\tSTEP\tREGION\tRESOURCES\tSTATUS")
+
+	for _, s := range summary.Steps {
+		status := "in sync"
+		if s.Error != "" {
+			status = "failed"
+		} else if s.Drifted {
+			status = "drifted"
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n", s.DeploymentRing, s.StepID, s.Region, len(s.Resources), status)
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, s := range summary.Steps {
+		if !s.Drifted {
+			continue
+		}
+
+		fmt.Fprintf(w, "\n%s (%s):\n", s.StepID, s.Region)
+
+		for _, r := range s.Resources {
+			fmt.Fprintf(w, "  %s %s\n", r.Action, r.Address)
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "\nDrift: %d resource(s) drifted in %d of %d step(s), %d failed.\n",
+		summary.Resources, summary.Drifted, len(summary.Steps), summary.Failed)
+
+	return err
+}
+
+// printDriftSummaryJSON writes the summary as indented json
+func printDriftSummaryJSON(w io.Writer, summary driftSummary) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(summary)
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+const stubDriftReport = `{
+  "deployment_ring": "prod",
+  "steps": [
+    {"track": "storage", "step": "bucket", "action": "deploy", "region_deploy_type": "primary", "region": "us-east-1", "status": "SUCCESS",
+     "drifted_resources": [
+       {"address": "aws_s3_bucket.logs", "type": "aws_s3_bucket", "action": "update"},
+       {"address": "aws_s3_bucket_policy.logs", "type": "aws_s3_bucket_policy", "action": "delete"}
+     ]},
+    {"track": "network", "step": "vpc", "action": "deploy", "region_deploy_type": "regional", "region": "us-west-2", "status": "SUCCESS"},
+    {"track": "network", "step": "vpc", "action": "deploy", "region_deploy_type": "regional", "region": "us-east-1", "status": "FAIL",
+     "error": "Error running terraform plan"}
+  ]
+}`
+
+func TestReadDriftSummary_ShouldListDriftedResourcesPerStep(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	_ = afero.WriteFile(fs, ".runiac/reports/prod.json", []byte(stubDriftReport), 0644)
+
+	summary, err := readDriftSummary(fs, []string{".runiac/reports/prod.json"})
+	require.NoError(t, err)
+
+	require.Len(t, summary.Steps, 3, "every region of a step has its own state")
+	require.Equal(t, "network/vpc", summary.Steps[0].StepID)
+	require.Equal(t, "us-east-1", summary.Steps[0].Region)
+	require.Equal(t, "Error running terraform plan", summary.Steps[0].Error)
+	require.False(t, summary.Steps[1].Drifted)
+
+	bucket := summary.Steps[2]
+	require.True(t, bucket.Drifted)
+	require.Equal(t, []driftedResource{
+		{Address: "aws_s3_bucket.logs", Type: "aws_s3_bucket", Action: "update"},
+		{Address: "aws_s3_bucket_policy.logs", Type: "aws_s3_bucket_policy", Action: "delete"},
+	}, bucket.Resources)
+
+	require.Equal(t, 1, summary.Drifted)
+	require.Equal(t, 1, summary.Failed)
+	require.Equal(t, 2, summary.Resources)
+
+	var b bytes.Buffer
+	require.NoError(t, printDriftSummary(&b, summary))
+	require.Contains(t, b.String(), "prod  storage/bucket  us-east-1  2          drifted")
+	require.Contains(t, b.String(), "prod  network/vpc     us-west-2  0          in sync")
+	require.Contains(t, b.String(), "storage/bucket (us-east-1):\n  update aws_s3_bucket.logs\n  delete aws_s3_bucket_policy.logs\n")
+	require.Contains(t, b.String(), "Drift: 2 resource(s) drifted in 1 of 3 step(s), 1 failed.")
+
+	b.Reset()
+	require.NoError(t, printDriftSummaryJSON(&b, summary))
+	require.Contains(t, b.String(), `"address": "aws_s3_bucket_policy.logs"`)
+	require.Contains(t, b.String(), `"resources": []`, "steps without drift list no resources")
+}
+
+func TestGetDriftExitCode_ShouldSignalDriftToScheduledJobs(t *testing.T) {
+	require.Equal(t, 0, getDriftExitCode(driftSummary{}, false))
+	require.Equal(t, driftExitDetected, getDriftExitCode(driftSummary{Drifted: 1}, false))
+	require.Equal(t, driftExitFailed, getDriftExitCode(driftSummary{Drifted: 1}, true), "an incomplete report is a failure")
+	require.Equal(t, driftExitFailed, getDriftExitCode(driftSummary{Failed: 1}, false))
+}
+
+func TestConfigureDrift_ShouldNotApply(t *testing.T) {
+	defer func() { DryRun, Drift, Detach, SelfDestroy, Runner = false, false, false, false, "" }()
+
+	Runner = "terraform"
+
+	require.NoError(t, configureDrift())
+	require.True(t, DryRun)
+	require.True(t, Drift)
+
+	Detach = true
+	require.Error(t, configureDrift(), "the drift can not be reported for a detached container")
+
+	Detach, SelfDestroy = false, true
+	require.Error(t, configureDrift())
+}
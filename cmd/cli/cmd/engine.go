@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// containerEngineAuto detects the container engine, the first healthy engine of supportedContainerEngines
+const containerEngineAuto = "auto"
+
+// supportedContainerEngines in order of preference when auto-detecting
+var supportedContainerEngines = []string{"docker", "podman", "nerdctl"}
+
+// containerEngineProbeTimeout bounds the health probe, an engine whose daemon does not respond is not healthy
+const containerEngineProbeTimeout = 15 * time.Second
+
+// probeContainerEngine returns an error when the engine can not reach its daemon or socket, the equivalent of
+// docker info. A variable so tests do not need a container engine.
+var probeContainerEngine = func(engine string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), containerEngineProbeTimeout)
+	defer cancel()
+
+	b, err := exec.CommandContext(ctx, engine, "info").CombinedOutput()
+	if ctx.Err() != nil {
+		return fmt.Errorf("'%s info' did not respond within %s", engine, containerEngineProbeTimeout)
+	}
+
+	if err != nil {
+		return fmt.Errorf("'%s info' failed: %s", engine, strings.TrimSpace(string(b)))
+	}
+
+	return nil
+}
+
+// getContainerEngineCandidates returns the engines to try in order. auto or no engine tries every supported engine,
+// a comma separated list tries the listed engines and a single engine is used without falling back.
+func getContainerEngineCandidates(engine string) []string {
+	if engine == "" || engine == containerEngineAuto {
+		return supportedContainerEngines
+	}
+
+	candidates := []string{}
+
+	for _, e := range strings.Split(engine, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			candidates = append(candidates, e)
+		}
+	}
+
+	return candidates
+}
+
+// resolveContainerEngine returns the first candidate of the configured engine that is on the path and healthy. The
+// error describes why each candidate is unusable and how to fix it.
+func resolveContainerEngine(engine string) (string, error) {
+	candidates := getContainerEngineCandidates(engine)
+	problems := []string{}
+
+	for _, candidate := range candidates {
+		if _, err := lookPath(candidate); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: not on the path", candidate))
+			continue
+		}
+
+		err := probeContainerEngine(candidate)
+		if err == nil {
+			return candidate, nil
+		}
+
+		problems = append(problems, fmt.Sprintf("%s: %s, %s", candidate, err, getContainerEngineRemediation(candidate, err.Error())))
+	}
+
+	if len(candidates) == 1 {
+		return "", fmt.Errorf("container engine %s is not usable. %s", candidates[0], strings.TrimPrefix(problems[0], candidates[0]+": "))
+	}
+
+	return "", fmt.Errorf("no usable container engine found, install or start one of '%s':\n  %s", strings.Join(candidates, "', '"), strings.Join(problems, "\n  "))
+}
+
+// getContainerEngineRemediation suggests how to fix the failed health probe of the engine
+func getContainerEngineRemediation(engine string, probeErr string) string {
+	permissionDenied := strings.Contains(strings.ToLower(probeErr), "permission denied")
+
+	switch engine {
+	case "docker":
+		if permissionDenied {
+			return "add your user to the docker group with 'sudo usermod -aG docker $USER' and log in again, or use rootless docker or podman"
+		}
+
+		if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+			return "start Docker Desktop"
+		}
+
+		return "start the docker daemon with 'sudo systemctl start docker', or for rootless docker 'systemctl --user start docker' with DOCKER_HOST=unix://$XDG_RUNTIME_DIR/docker.sock"
+	case "podman":
+		if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+			return "start the podman machine with 'podman machine start'"
+		}
+
+		return "check the podman installation with 'podman system info', or with 'podman system migrate' after upgrading podman"
+	case "nerdctl":
+		if permissionDenied {
+			return "run containerd rootless with 'containerd-rootless-setuptool.sh install'"
+		}
+
+		return "start containerd with 'sudo systemctl start containerd', or for rootless containerd 'systemctl --user start containerd'"
+	default:
+		return fmt.Sprintf("check that '%s info' succeeds", engine)
+	}
+}
+
+// getEngineSocketCandidates returns the locations of the engine's socket in order of preference, the rootless
+// sockets of the user before the system's socket
+func getEngineSocketCandidates(engine string) []string {
+	candidates := []string{}
+
+	// DOCKER_HOST is honored by docker and the docker compatible sockets of podman and nerdctl
+	if host := os.Getenv("DOCKER_HOST"); strings.HasPrefix(host, "unix://") {
+		candidates = append(candidates, strings.TrimPrefix(host, "unix://"))
+	}
+
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+
+	switch engine {
+	case "podman":
+		if runtimeDir != "" {
+			candidates = append(candidates, filepath.Join(runtimeDir, "podman", "podman.sock"))
+		}
+
+		candidates = append(candidates, "/run/podman/podman.sock")
+	case "nerdctl":
+		if runtimeDir != "" {
+			candidates = append(candidates, filepath.Join(runtimeDir, "containerd-rootless", "api.sock"))
+		}
+	default:
+		if runtimeDir != "" {
+			candidates = append(candidates, filepath.Join(runtimeDir, "docker.sock"))
+		}
+	}
+
+	return append(candidates, dockerSocket)
+}
+
+// getEngineSocket returns the first existing socket of the engine, the conventional docker socket when none exists
+func getEngineSocket(engine string) string {
+	for _, socket := range getEngineSocketCandidates(engine) {
+		if info, err := os.Stat(socket); err == nil && info.Mode()&os.ModeSocket != 0 {
+			return socket
+		}
+	}
+
+	return dockerSocket
+}
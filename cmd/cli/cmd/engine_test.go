@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"errors"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveContainerEngine_ShouldFallBackToHealthyEngine(t *testing.T) {
+	defer func(probe func(string) error) {
+		lookPath, probeContainerEngine = exec.LookPath, probe
+	}(probeContainerEngine)
+
+	lookPath = func(file string) (string, error) {
+		if file == "nerdctl" {
+			return "", errors.New("not found")
+		}
+		return "/usr/bin/" + file, nil
+	}
+
+	running := map[string]bool{"podman": true}
+	probeContainerEngine = func(engine string) error {
+		if running[engine] {
+			return nil
+		}
+		return errors.New("'" + engine + " info' failed: Cannot connect to the Docker daemon at unix:///var/run/docker.sock")
+	}
+
+	engine, err := resolveContainerEngine(containerEngineAuto)
+	require.NoError(t, err)
+	require.Equal(t, "podman", engine, "a stopped docker daemon should fall back to podman")
+
+	engine, err = resolveContainerEngine("nerdctl, podman")
+	require.NoError(t, err)
+	require.Equal(t, "podman", engine, "a list should fall back in its order")
+
+	_, err = resolveContainerEngine("docker")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "container engine docker is not usable")
+	require.Contains(t, err.Error(), "Cannot connect to the Docker daemon", "an explicit engine does not fall back")
+
+	running = map[string]bool{}
+	_, err = resolveContainerEngine("")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "nerdctl: not on the path")
+	require.Contains(t, err.Error(), "podman: 'podman info' failed")
+}
+
+func TestGetContainerEngineRemediation_ShouldSuggestFixForPermissionDenied(t *testing.T) {
+	require.Contains(t, getContainerEngineRemediation("docker", "permission denied while trying to connect to the Docker daemon socket"), "usermod -aG docker")
+	require.Contains(t, getContainerEngineRemediation("lima", "failed"), "'lima info'")
+}
+
+func TestGetEngineSocket_ShouldPreferRootlessSocket(t *testing.T) {
+	dir := t.TempDir()
+
+	defer func(runtimeDir string, host string) {
+		_ = os.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+		_ = os.Setenv("DOCKER_HOST", host)
+	}(os.Getenv("XDG_RUNTIME_DIR"), os.Getenv("DOCKER_HOST"))
+
+	_ = os.Setenv("XDG_RUNTIME_DIR", dir)
+	_ = os.Setenv("DOCKER_HOST", "")
+
+	require.Equal(t, []string{filepath.Join(dir, "podman", "podman.sock"), "/run/podman/podman.sock", dockerSocket}, getEngineSocketCandidates("podman"))
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "podman"), 0755))
+
+	listener, err := net.Listen("unix", filepath.Join(dir, "podman", "podman.sock"))
+	require.NoError(t, err)
+	defer listener.Close()
+
+	require.Equal(t, filepath.Join(dir, "podman", "podman.sock"), getEngineSocket("podman"))
+	require.NotEqual(t, filepath.Join(dir, "podman", "podman.sock"), getEngineSocket("docker"))
+}
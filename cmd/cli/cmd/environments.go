@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// environmentsConfigKey is the section of the runiac config overlaying the settings and configuration of each
+// environment over the base config, e.g. environments.prod.container or environments.prod.rings.prod.account
+const environmentsConfigKey = "environments"
+
+// unoverlaidConfigKeys are the keys of the runiac config an environment overlay can not set, since they select the
+// environment or its overlay
+var unoverlaidConfigKeys = map[string]bool{
+	"environment":         true,
+	environmentsConfigKey: true,
+	"profile":             true,
+	"profiles":            true,
+}
+
+// getEnvironmentConfigKey returns the runiac config key of the environment's overlay of the key, e.g.
+// environments.prod.container. The runiac config's keys are case insensitive, so the environment is lower case.
+func getEnvironmentConfigKey(environment string, key string) string {
+	return fmt.Sprintf("%s.%s.%s", environmentsConfigKey, strings.ToLower(environment), key)
+}
+
+// resolveEnvironment returns the environment whose overlay applies, --environment or the environment setting
+// resolved from RUNIAC_ENVIRONMENT, the profile or the config file. Empty when no environment is targeted.
+func resolveEnvironment(flags *pflag.FlagSet, profile string) string {
+	if flags.Lookup("environment") == nil {
+		return ""
+	}
+
+	layers, i, err := resolveSetting(flags, "environment", profile, "")
+	if err != nil {
+		return ""
+	}
+
+	return layers[i].Value
+}
+
+// applyEnvironmentConfig merges the environment's overlay of the runiac config (environments.{environment}) over the
+// configuration that is not a setting of the flags, e.g. rings, accounts and variables. Maps are merged key by key,
+// so an overlay only sets what differs from the base config, lists and values are replaced. Settings of the flags
+// are resolved from the overlay by applySettings.
+func applyEnvironmentConfig(config *viper.Viper, flags *pflag.FlagSet, environment string) error {
+	key := fmt.Sprintf("%s.%s", environmentsConfigKey, strings.ToLower(environment))
+
+	if environment == "" || !fileConfig.IsSet(key) {
+		return nil
+	}
+
+	settings := getSettingConfigKeys(flags)
+	overlay := map[string]interface{}{}
+
+	for k, v := range fileConfig.GetStringMap(key) {
+		if unoverlaidConfigKeys[k] {
+			return fmt.Errorf("%s.%s can not be set by an environment overlay", key, k)
+		}
+
+		if !settings[k] {
+			overlay[k] = v
+		}
+	}
+
+	return config.MergeConfigMap(overlay)
+}
+
+// getSettingConfigKeys returns the config keys of the settings of the flags, every flag that is resolved from the
+// environment and configuration
+func getSettingConfigKeys(flags *pflag.FlagSet) map[string]bool {
+	settings := map[string]bool{}
+
+	flags.VisitAll(func(f *pflag.Flag) {
+		if !unresolvedSettings[f.Name] {
+			settings[getSettingConfigKey(f.Name)] = true
+		}
+	})
+
+	return settings
+}
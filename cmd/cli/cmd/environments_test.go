@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplySettings_ShouldResolveTheEnvironmentOverlay(t *testing.T) {
+	defer func() { fileConfig = viper.New() }()
+
+	flags, engine, regions := newStubSettingsFlags()
+	flags.String("environment", "", "")
+
+	fileConfig.Set("stub_engine", "docker")
+	fileConfig.Set("primary_region", "centralus")
+	fileConfig.Set("environments.prod.stub_engine", "podman")
+
+	require.NoError(t, applySettings(flags))
+	require.Equal(t, "docker", *engine, "the overlay should only apply to its environment")
+
+	flags, engine, regions = newStubSettingsFlags()
+	flags.String("environment", "", "")
+
+	require.NoError(t, flags.Set("environment", "Prod"))
+	require.NoError(t, applySettings(flags))
+	require.Equal(t, "podman", *engine)
+	require.Equal(t, []string{"centralus"}, *regions, "settings the overlay does not set should resolve from the base config")
+}
+
+func TestApplyEnvironmentConfig_ShouldMergeTheOverlay(t *testing.T) {
+	defer func() { fileConfig = viper.New() }()
+
+	flags, _, _ := newStubSettingsFlags()
+
+	fileConfig.Set("environments.prod", map[string]interface{}{
+		"stub_engine": "podman",
+		"rings":       map[string]interface{}{"prod": map[string]interface{}{"account": "222"}},
+		"variables":   map[string]interface{}{"tier": "premium"},
+	})
+
+	// as read from the runiac config
+	config := viper.New()
+	require.NoError(t, config.MergeConfigMap(map[string]interface{}{
+		"rings": map[string]interface{}{"prod": map[string]interface{}{"account": "111", "primary_regions": []string{"eastus"}}},
+	}))
+
+	require.NoError(t, applyEnvironmentConfig(config, flags, ""))
+	require.Equal(t, "111", config.GetString("rings.prod.account"))
+
+	require.NoError(t, applyEnvironmentConfig(config, flags, "prod"))
+	require.Equal(t, "222", config.GetString("rings.prod.account"))
+	require.Equal(t, []string{"eastus"}, config.GetStringSlice("rings.prod.primary_regions"), "the overlay should be merged key by key")
+	require.Equal(t, "premium", config.GetString("variables.tier"))
+	require.False(t, config.IsSet("stub_engine"), "settings are resolved by applySettings")
+
+	fileConfig.Set("environments.dev.profile", "ci")
+	require.EqualError(t, applyEnvironmentConfig(config, flags, "dev"), "environments.dev.profile can not be set by an environment overlay")
+}
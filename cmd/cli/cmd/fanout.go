@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// fanOutTracker records the outcome of each fan-out target (ie. a deployment ring and its account) and
+// decides when enough targets have failed to abort the remaining ones
+type fanOutTracker struct {
+	abortAfterFailures int // Abort once this many targets failed, never abort when 0
+	completed          []string
+	failed             []string
+	skipped            []string
+}
+
+// record the result of running a target
+func (f *fanOutTracker) record(target string, err error) {
+	if err != nil {
+		f.failed = append(f.failed, target)
+	} else {
+		f.completed = append(f.completed, target)
+	}
+}
+
+// skip records targets that were not run
+func (f *fanOutTracker) skip(targets ...string) {
+	f.skipped = append(f.skipped, targets...)
+}
+
+// shouldAbort returns true when the remaining targets should not be run
+func (f *fanOutTracker) shouldAbort() bool {
+	return f.abortAfterFailures > 0 && len(f.failed) >= f.abortAfterFailures
+}
+
+// summary describes which targets completed, failed and were skipped
+func (f *fanOutTracker) summary() string {
+	total := len(f.completed) + len(f.failed) + len(f.skipped)
+	msg := fmt.Sprintf("Completed %d/%d targets.", len(f.completed), total)
+
+	if len(f.completed) > 0 {
+		msg += fmt.Sprintf("  Completed: %s.", strings.Join(f.completed, ", "))
+	}
+
+	if len(f.failed) > 0 {
+		msg += fmt.Sprintf("  Failed: %s.", strings.Join(f.failed, ", "))
+	}
+
+	if len(f.skipped) > 0 {
+		msg += fmt.Sprintf("  Skipped after %d failure(s): %s.", len(f.failed), strings.Join(f.skipped, ", "))
+	}
+
+	return msg
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFanOutTracker_ShouldAbortAfterFailures(t *testing.T) {
+	tracker := fanOutTracker{abortAfterFailures: 2}
+
+	tracker.record("dev (1)", nil)
+	tracker.record("stage (2)", errors.New("failed"))
+	require.False(t, tracker.shouldAbort())
+
+	tracker.record("prod-east (3)", errors.New("failed"))
+	require.True(t, tracker.shouldAbort())
+
+	tracker.skip("prod-west (4)")
+
+	summary := tracker.summary()
+	require.Contains(t, summary, "Completed 1/4 targets.")
+	require.Contains(t, summary, "Failed: stage (2), prod-east (3).")
+	require.Contains(t, summary, "Skipped after 2 failure(s): prod-west (4).")
+}
+
+func TestFanOutTracker_ShouldNotAbortWhenDisabled(t *testing.T) {
+	tracker := fanOutTracker{}
+
+	for i := 0; i < 10; i++ {
+		tracker.record("ring", errors.New("failed"))
+	}
+
+	require.False(t, tracker.shouldAbort())
+}
+
+func TestGetRunError_ShouldFailTheCommandWhenARingFailed(t *testing.T) {
+	cmd := &cobra.Command{}
+
+	require.NoError(t, getRunError(cmd, false))
+	require.False(t, cmd.SilenceUsage, "the usage of a succeeding command is unchanged")
+
+	require.Equal(t, errRunFailed, getRunError(cmd, true))
+	require.True(t, cmd.SilenceUsage, "a failed ring is not a usage error")
+	require.True(t, cmd.SilenceErrors)
+}
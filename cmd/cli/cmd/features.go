@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+const featureVarPrefix = "TF_VAR_feature_" // A feature is forwarded as the feature_{name} terraform input variable
+
+var featureNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// parseFeatures parses --feature name[=bool] values, a feature without a value is enabled
+func parseFeatures(features []string) (map[string]bool, error) {
+	parsed := map[string]bool{}
+
+	for _, f := range features {
+		parts := strings.SplitN(f, "=", 2)
+		name := parts[0]
+		enabled := true
+
+		if len(parts) == 2 {
+			var err error
+
+			enabled, err = strconv.ParseBool(parts[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid --feature '%s', the value must be true or false", f)
+			}
+		}
+
+		if !featureNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid --feature '%s', the name may only contain letters, digits, '_' and '-'", f)
+		}
+
+		parsed[name] = enabled
+	}
+
+	return parsed, nil
+}
+
+// getRingFeatures returns the features configured for the ring under rings.{ring}.features in the runiac config,
+// either a map of feature names to booleans or a list of enabled feature names
+func getRingFeatures(ring string) (map[string]bool, error) {
+	features := map[string]bool{}
+	key := getRingConfigKey(ring, "features")
+
+	if ring == "" || !viper.IsSet(key) {
+		return features, nil
+	}
+
+	switch v := viper.Get(key).(type) {
+	case map[string]interface{}:
+		for name, value := range v {
+			enabled, ok := value.(bool)
+			if !ok {
+				return nil, fmt.Errorf("invalid %s.%s, must be true or false", key, name)
+			}
+
+			features[name] = enabled
+		}
+	default:
+		for _, name := range viper.GetStringSlice(key) {
+			features[name] = true
+		}
+	}
+
+	for name := range features {
+		if !featureNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid feature '%s' in %s, the name may only contain letters, digits, '_' and '-'", name, key)
+		}
+	}
+
+	return features, nil
+}
+
+// resolveFeatures returns the ring's configured features overridden by the --feature values
+func resolveFeatures(ring string, flagFeatures []string) (map[string]bool, error) {
+	features, err := getRingFeatures(ring)
+	if err != nil {
+		return nil, err
+	}
+
+	overrides, err := parseFeatures(flagFeatures)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, enabled := range overrides {
+		features[name] = enabled
+	}
+
+	return features, nil
+}
+
+// getFeatureArguments returns the container run arguments forwarding each feature as a terraform input variable
+func getFeatureArguments(features map[string]bool) (args []string) {
+	names := make([]string, 0, len(features))
+
+	for name := range features {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		args = append(args, "-e", fmt.Sprintf("%s%s=%v", featureVarPrefix, name, features[name]))
+	}
+
+	return
+}
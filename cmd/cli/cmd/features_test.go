@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFeatures_ShouldDefaultToEnabled(t *testing.T) {
+	features, err := parseFeatures([]string{"waf", "cdn=false", "dns_failover=TRUE"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{"waf": true, "cdn": false, "dns_failover": true}, features)
+
+	for _, f := range []string{"", "cdn=maybe", "my feature", "=true"} {
+		_, err := parseFeatures([]string{f})
+		require.Error(t, err, "--feature '%s' should be invalid", f)
+	}
+}
+
+func TestResolveFeatures_ShouldMergeFlagsOverRingConfig(t *testing.T) {
+	viper.Set("rings.prod.features", map[string]interface{}{"waf": true, "cdn": true})
+	viper.Set("rings.dev.features", []string{"debug"})
+	defer viper.Set("rings", nil)
+
+	features, err := resolveFeatures("prod", []string{"cdn=false", "dns_failover"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{"waf": true, "cdn": false, "dns_failover": true}, features)
+
+	features, err = resolveFeatures("dev", nil)
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{"debug": true}, features)
+
+	features, err = resolveFeatures("stage", []string{"waf"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{"waf": true}, features)
+
+	viper.Set("rings.prod.features", map[string]interface{}{"waf": "yes"})
+
+	_, err = resolveFeatures("prod", nil)
+	require.Error(t, err, "a non boolean ring feature should be invalid")
+}
+
+func TestGetFeatureArguments_ShouldForwardSortedFeatureVariables(t *testing.T) {
+	require.Equal(t, []string{
+		"-e", "TF_VAR_feature_cdn=false",
+		"-e", "TF_VAR_feature_waf=true",
+	}, getFeatureArguments(map[string]bool{"waf": true, "cdn": false}))
+	require.Empty(t, getFeatureArguments(map[string]bool{}))
+}
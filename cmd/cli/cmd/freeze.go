@@ -0,0 +1,269 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const freezeDateLayout = "2006-01-02"
+
+// freezeWindow is a change-freeze window configured under freeze_windows in the runiac config, either a date range
+// (start and end) or recurring (a cron expression for when the freeze starts and its duration)
+type freezeWindow struct {
+	Name     string   `mapstructure:"name"`
+	Start    string   `mapstructure:"start"`    // RFC3339 timestamp or date, inclusive
+	End      string   `mapstructure:"end"`      // RFC3339 timestamp or date, exclusive
+	Cron     string   `mapstructure:"cron"`     // minute hour day-of-month month day-of-week
+	Duration string   `mapstructure:"duration"` // How long a recurring freeze lasts, e.g. 60h
+	Timezone string   `mapstructure:"timezone"` // The timezone of dates and the cron expression, defaults to UTC
+	Rings    []string `mapstructure:"rings"`    // The rings frozen, defaults to protected_rings
+}
+
+// breakGlassNotification is posted to the freeze_notify_url when a freeze is overridden with --break-glass
+type breakGlassNotification struct {
+	Reason         string `json:"reason"`
+	FreezeWindow   string `json:"freeze_window"`
+	Project        string `json:"project"`
+	DeploymentRing string `json:"deployment_ring"`
+	Environment    string `json:"environment"`
+	Namespace      string `json:"namespace"`
+	Version        string `json:"version"`
+	User           string `json:"user"`
+}
+
+// getFreezeWindows returns the freeze windows configured under freeze_windows in the runiac config file
+func getFreezeWindows() ([]freezeWindow, error) {
+	windows := []freezeWindow{}
+
+	err := viper.UnmarshalKey("freeze_windows", &windows)
+	if err != nil {
+		return nil, fmt.Errorf("invalid freeze_windows configuration: %w", err)
+	}
+
+	return windows, nil
+}
+
+// getActiveFreeze returns the first freeze window freezing the ring at the given time. Local and pull request
+// rings are never frozen.
+func getActiveFreeze(ring string, windows []freezeWindow, protectedRings []string, now time.Time) (*freezeWindow, error) {
+	if isEphemeralRing(ring) {
+		return nil, nil
+	}
+
+	for i, w := range windows {
+		if !w.appliesTo(ring, protectedRings) {
+			continue
+		}
+
+		active, err := w.isActive(now)
+		if err != nil {
+			return nil, fmt.Errorf("invalid freeze window '%s': %w", w.Name, err)
+		}
+
+		if active {
+			return &windows[i], nil
+		}
+	}
+
+	return nil, nil
+}
+
+// appliesTo returns true when the window freezes the ring. Without rings configured for the window the protected
+// rings are frozen, or every ring when there are no protected rings.
+func (w freezeWindow) appliesTo(ring string, protectedRings []string) bool {
+	rings := w.Rings
+
+	if len(rings) == 0 {
+		rings = protectedRings
+	}
+
+	return len(rings) == 0 || isRingInSet(ring, rings)
+}
+
+// isActive returns true when the time is within the freeze window
+func (w freezeWindow) isActive(now time.Time) (bool, error) {
+	loc := time.UTC
+
+	if w.Timezone != "" {
+		var err error
+
+		loc, err = time.LoadLocation(w.Timezone)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	now = now.In(loc)
+
+	if w.Cron != "" {
+		return isRecurringFreezeActive(w.Cron, w.Duration, now)
+	}
+
+	if w.Start == "" || w.End == "" {
+		return false, fmt.Errorf("a freeze window requires either start and end, or cron and duration")
+	}
+
+	start, err := parseFreezeTime(w.Start, loc)
+	if err != nil {
+		return false, err
+	}
+
+	end, err := parseFreezeTime(w.End, loc)
+	if err != nil {
+		return false, err
+	}
+
+	return !now.Before(start) && now.Before(end), nil
+}
+
+// parseFreezeTime parses an RFC3339 timestamp or a date in the location
+func parseFreezeTime(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.ParseInLocation(freezeDateLayout, value, loc); err == nil {
+		return t, nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
+
+// isRecurringFreezeActive returns true when the cron expression matched a minute within the duration before now
+func isRecurringFreezeActive(expr string, duration string, now time.Time) (bool, error) {
+	schedule, err := parseCronSchedule(expr)
+	if err != nil {
+		return false, err
+	}
+
+	d, err := time.ParseDuration(duration)
+	if err != nil || d <= 0 {
+		return false, fmt.Errorf("a recurring freeze window requires a positive duration, e.g. 60h")
+	}
+
+	now = now.Truncate(time.Minute)
+
+	for t := now; now.Sub(t) < d; t = t.Add(-time.Minute) {
+		if schedule.matches(t) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// cronSchedule is the set of values matched by each field of a cron expression
+type cronSchedule struct {
+	minute, hour, dayOfMonth, month, dayOfWeek map[int]bool
+}
+
+// parseCronSchedule parses a standard 5 field cron expression supporting *, lists, ranges and steps
+func parseCronSchedule(expr string) (cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return cronSchedule{}, fmt.Errorf("cron expression '%s' must have 5 fields: minute hour day-of-month month day-of-week", expr)
+	}
+
+	bounds := [][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 6}}
+	sets := make([]map[int]bool, 5)
+
+	for i, field := range fields {
+		set, err := parseCronField(field, bounds[i][0], bounds[i][1])
+		if err != nil {
+			return cronSchedule{}, fmt.Errorf("invalid cron expression '%s': %w", expr, err)
+		}
+
+		sets[i] = set
+	}
+
+	return cronSchedule{minute: sets[0], hour: sets[1], dayOfMonth: sets[2], month: sets[3], dayOfWeek: sets[4]}, nil
+}
+
+// parseCronField returns the values between min and max matched by a cron field, e.g. 1-5, */15 or 0,30
+func parseCronField(field string, min int, max int) (map[int]bool, error) {
+	set := map[int]bool{}
+
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+
+		if i := strings.Index(part, "/"); i >= 0 {
+			s, err := strconv.Atoi(part[i+1:])
+			if err != nil || s <= 0 {
+				return nil, fmt.Errorf("invalid step in '%s'", part)
+			}
+
+			step = s
+			part = part[:i]
+		}
+
+		lo, hi := min, max
+
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+
+			var err error
+
+			lo, err = strconv.Atoi(bounds[0])
+			if err != nil {
+				return nil, fmt.Errorf("invalid value '%s'", part)
+			}
+
+			hi = lo
+
+			if len(bounds) == 2 {
+				hi, err = strconv.Atoi(bounds[1])
+				if err != nil {
+					return nil, fmt.Errorf("invalid value '%s'", part)
+				}
+			}
+		}
+
+		if lo < min || hi > max || lo > hi {
+			return nil, fmt.Errorf("'%s' is outside of %d-%d", part, min, max)
+		}
+
+		for v := lo; v <= hi; v += step {
+			set[v] = true
+		}
+	}
+
+	return set, nil
+}
+
+// matches returns true when the minute of t is matched by the schedule
+func (s cronSchedule) matches(t time.Time) bool {
+	return s.minute[t.Minute()] && s.hour[t.Hour()] && s.dayOfMonth[t.Day()] && s.month[int(t.Month())] && s.dayOfWeek[int(t.Weekday())]
+}
+
+// checkFreeze returns an error when the ring is frozen, unless the freeze is overridden with a break glass reason.
+// Dry runs do not change infrastructure and are allowed during a freeze.
+func checkFreeze(ring string, freeze *freezeWindow, breakGlass string, dryRun bool) error {
+	if freeze == nil || dryRun || strings.TrimSpace(breakGlass) != "" {
+		return nil
+	}
+
+	return fmt.Errorf("deployment ring '%s' is frozen by the '%s' freeze window, re-run with --break-glass <reason> to override", ring, freeze.Name)
+}
+
+// notifyBreakGlass posts the break glass notification to the url
+func notifyBreakGlass(client *http.Client, url string, n breakGlassNotification) error {
+	b, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Post(url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("break glass notification returned %s", resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetActiveFreeze_ShouldFreezeProtectedRingsDuringWindows(t *testing.T) {
+	windows := []freezeWindow{
+		{Name: "year-end", Start: "2026-12-20", End: "2027-01-04"},
+		// fridays 18:00 until monday 06:00
+		{Name: "weekend", Cron: "0 18 * * 5", Duration: "60h", Rings: []string{"stage"}},
+	}
+	protected := []string{"prod"}
+
+	tests := []struct {
+		ring     string
+		now      string
+		expected string
+	}{
+		{"prod", "2026-12-24T12:00:00Z", "year-end"},
+		{"PROD", "2027-01-03T23:59:00Z", "year-end"},
+		{"prod", "2027-01-04T00:00:00Z", ""},
+		{"dev", "2026-12-24T12:00:00Z", ""},
+		{"local", "2026-12-24T12:00:00Z", ""},
+		{"stage", "2026-10-16T18:00:00Z", "weekend"},
+		{"stage", "2026-10-19T05:59:00Z", "weekend"},
+		{"stage", "2026-10-19T06:00:00Z", ""},
+		{"stage", "2026-10-16T17:59:00Z", ""},
+	}
+
+	for _, tt := range tests {
+		now, _ := time.Parse(time.RFC3339, tt.now)
+
+		freeze, err := getActiveFreeze(tt.ring, windows, protected, now)
+		require.NoError(t, err)
+
+		name := ""
+		if freeze != nil {
+			name = freeze.Name
+		}
+
+		require.Equal(t, tt.expected, name, "getActiveFreeze(\"%s\", %s)", tt.ring, tt.now)
+	}
+
+	_, err := getActiveFreeze("prod", []freezeWindow{{Name: "broken", Cron: "0 18 * *", Duration: "1h"}}, protected, time.Now())
+	require.Error(t, err)
+}
+
+func TestParseCronField_ShouldSupportListsRangesAndSteps(t *testing.T) {
+	set, err := parseCronField("0,30", 0, 59)
+	require.NoError(t, err)
+	require.Equal(t, map[int]bool{0: true, 30: true}, set)
+
+	set, err = parseCronField("1-5", 0, 6)
+	require.NoError(t, err)
+	require.Len(t, set, 5)
+
+	set, err = parseCronField("*/15", 0, 59)
+	require.NoError(t, err)
+	require.Equal(t, map[int]bool{0: true, 15: true, 30: true, 45: true}, set)
+
+	_, err = parseCronField("7", 0, 6)
+	require.Error(t, err)
+}
+
+func TestCheckFreeze_ShouldRequireBreakGlassUnlessDryRun(t *testing.T) {
+	freeze := &freezeWindow{Name: "year-end"}
+
+	require.Error(t, checkFreeze("prod", freeze, "", false))
+	require.Error(t, checkFreeze("prod", freeze, "  ", false))
+	require.NoError(t, checkFreeze("prod", freeze, "INC-42 outage fix", false))
+	require.NoError(t, checkFreeze("prod", freeze, "", true))
+	require.NoError(t, checkFreeze("prod", nil, "", false))
+}
+
+func TestNotifyBreakGlass_ShouldPostReason(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := breakGlassNotification{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&n))
+		assert.Equal(t, "INC-42 outage fix", n.Reason)
+		assert.Equal(t, "year-end", n.FreezeWindow)
+	}))
+	defer server.Close()
+
+	err := notifyBreakGlass(server.Client(), server.URL, breakGlassNotification{Reason: "INC-42 outage fix", FreezeWindow: "year-end"})
+	require.NoError(t, err)
+}
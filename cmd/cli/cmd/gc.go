@@ -0,0 +1,296 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultNamespaceRecords = ".runiac/namespaces.json" // Where the namespaced environments are recorded unless namespace_records is configured
+	defaultGCOlderThan      = 72 * time.Hour
+)
+
+// namespaceRecord records the environment deployed to the namespace of --local or --pull-request, destroyed by
+// runiac gc once it was not deployed for --older-than
+type namespaceRecord struct {
+	Project         string    `json:"project"`
+	Environment     string    `json:"environment"`
+	DeploymentRing  string    `json:"deployment_ring"`
+	Namespace       string    `json:"namespace"`
+	Account         string    `json:"account"`
+	PrimaryRegions  []string  `json:"primary_regions"`
+	RegionalRegions []string  `json:"regional_regions"`
+	Version         string    `json:"version"`
+	CreatedBy       string    `json:"created_by"`
+	CreatedAt       time.Time `json:"created_at"`
+	DeployedAt      time.Time `json:"deployed_at"`    // The last deploy of the namespace
+	CorrelationID   string    `json:"correlation_id"` // The correlation id of the last deploy
+}
+
+var (
+	gcOlderThan   time.Duration
+	gcEnvironment string
+	gcDryRun      bool
+)
+
+// runGCDestroy runs runiac destroy with the arguments, a variable so tests do not destroy
+var runGCDestroy = func(args []string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	destroy := exec.Command(exe, args...)
+	destroy.Stdin = os.Stdin
+	destroy.Stdout = os.Stdout
+	destroy.Stderr = os.Stderr
+
+	return destroy.Run()
+}
+
+func init() {
+	gcCmd.Flags().DurationVar(&gcOlderThan, "older-than", defaultGCOlderThan, "Destroy the namespaced environments that were not deployed within this duration, e.g. 72h")
+	gcCmd.Flags().StringVarP(&gcEnvironment, "environment", "e", "", "Only destroy the stale namespaced environments of this environment")
+	gcCmd.Flags().BoolVar(&gcDryRun, "dry-run", false, "Only list the stale namespaced environments without destroying them")
+
+	rootCmd.AddCommand(gcCmd)
+}
+
+var gcCmd = &cobra.Command{
+	Use:   "gc [-- destroy flags]",
+	Short: "Destroy the stale namespaced environments",
+	Long: fmt.Sprintf(`Destroys the environments deployed with --local or --pull-request that were not deployed within
+--older-than, so abandoned pull request environments do not linger. Deploying a namespaced environment records it
+in %s, or the namespace_records file of the runiac config which can be shared by the runs of a
+ci pipeline, and destroying it removes its record. Each stale environment is destroyed with runiac destroy, the
+flags after -- are passed to it, e.g. --container-engine podman. A --local environment is only destroyed by the
+user who deployed it.`, defaultNamespaceRecords),
+	Run: func(cmd *cobra.Command, args []string) {
+		if gcOlderThan <= 0 {
+			logrus.Fatal("--older-than must be a positive duration such as 72h")
+		}
+
+		stale, err := getStaleNamespaceRecords(appFS, getNamespaceRecordsPath(), viper.GetString("project"), gcEnvironment, gcOlderThan, time.Now().UTC())
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		if len(stale) == 0 {
+			logrus.Infof("No namespaced environment was left undeployed for more than %s", gcOlderThan)
+			return
+		}
+
+		failed := destroyNamespaceRecords(stale, args, gcDryRun)
+
+		if len(failed) > 0 {
+			logrus.Errorf("Failed to destroy %d stale environment(s): %s", len(failed), strings.Join(failed, ", "))
+			os.Exit(1)
+		}
+	},
+}
+
+// getNamespaceRecordsPath returns the file the namespaced environments are recorded in, namespace_records in the
+// runiac config
+func getNamespaceRecordsPath() string {
+	if path := viper.GetString("namespace_records"); path != "" {
+		return path
+	}
+
+	return defaultNamespaceRecords
+}
+
+// isNamespacedRun returns true when the run deploys the namespace of --local or --pull-request
+func isNamespacedRun() bool {
+	return Local || PullRequest != ""
+}
+
+// recordNamespaceRun records the run's namespaced environment once deployed, keeping when it was created, and removes
+// its record once destroyed
+func recordNamespaceRun(fs afero.Fs, path string, entry historyEntry, destroyed bool, now time.Time) error {
+	key := getNamespaceClaimKey(entry.Project, entry.Environment, entry.DeploymentRing, entry.Namespace)
+
+	return updateNamespaceRecords(fs, path, func(records map[string]namespaceRecord) {
+		if destroyed {
+			delete(records, key)
+			return
+		}
+
+		// a failed destroy does not extend the environment's life
+		if entry.Destroy {
+			return
+		}
+
+		record, ok := records[key]
+		if !ok {
+			record = namespaceRecord{CreatedBy: getNamespaceOwner(), CreatedAt: now}
+		}
+
+		record.Project = entry.Project
+		record.Environment = entry.Environment
+		record.DeploymentRing = entry.DeploymentRing
+		record.Namespace = entry.Namespace
+		record.Account = entry.Account
+		record.PrimaryRegions = entry.PrimaryRegions
+		record.RegionalRegions = entry.RegionalRegions
+		record.Version = entry.Version
+		record.DeployedAt = now
+		record.CorrelationID = entry.CorrelationID
+
+		records[key] = record
+	})
+}
+
+// getStaleNamespaceRecords returns the records of the project's namespaced environments that were not deployed within
+// olderThan, of the environment when set, ordered by their key
+func getStaleNamespaceRecords(fs afero.Fs, path string, project string, environment string, olderThan time.Duration, now time.Time) ([]namespaceRecord, error) {
+	records, err := readNamespaceRecords(fs, path)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []string{}
+	for key, r := range records {
+		if r.Project == project && (environment == "" || strings.EqualFold(r.Environment, environment)) && now.Sub(r.DeployedAt) > olderThan {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	stale := []namespaceRecord{}
+	for _, key := range keys {
+		stale = append(stale, records[key])
+	}
+
+	return stale, nil
+}
+
+// destroyNamespaceRecords destroys the environment of each record with runiac destroy, which removes its record,
+// returning the keys of the records that failed to destroy. A dry run only lists the environments.
+func destroyNamespaceRecords(records []namespaceRecord, extraArgs []string, dryRun bool) (failed []string) {
+	user, _ := getMachineName()
+
+	for _, r := range records {
+		key := getNamespaceClaimKey(r.Project, r.Environment, r.DeploymentRing, r.Namespace)
+		logger := logrus.WithField("namespace", key)
+
+		if r.DeploymentRing == LocalDeploymentRing && r.Namespace != user {
+			logger.Warnf("Skipping the --local environment of %s, it can only be destroyed by %s", r.CreatedBy, r.Namespace)
+			continue
+		}
+
+		if dryRun {
+			logger.Infof("Would destroy the environment created %s by %s, last deployed %s", r.CreatedAt.Format(time.RFC3339), r.CreatedBy, r.DeployedAt.Format(time.RFC3339))
+			continue
+		}
+
+		logger.Infof("Destroying the environment created %s by %s, last deployed %s", r.CreatedAt.Format(time.RFC3339), r.CreatedBy, r.DeployedAt.Format(time.RFC3339))
+
+		if err := runGCDestroy(getGCDestroyArguments(r, extraArgs)); err != nil {
+			logger.WithError(err).Error("Failed to destroy the environment")
+			failed = append(failed, key)
+		}
+	}
+
+	return failed
+}
+
+// getGCDestroyArguments returns the runiac destroy arguments targeting the record's environment
+func getGCDestroyArguments(r namespaceRecord, extraArgs []string) []string {
+	args := []string{"destroy"}
+
+	if r.DeploymentRing == LocalDeploymentRing {
+		args = append(args, "--local")
+	} else {
+		args = append(args, "--pull-request", r.Namespace)
+	}
+
+	if r.Environment != "" {
+		args = append(args, "--environment", r.Environment)
+	}
+
+	if r.Account != "" {
+		args = append(args, "--account", r.Account)
+	}
+
+	for _, region := range r.PrimaryRegions {
+		args = append(args, "--primary-regions", region)
+	}
+
+	for _, region := range r.RegionalRegions {
+		args = append(args, "--regional-regions", region)
+	}
+
+	return append(args, extraArgs...)
+}
+
+// updateNamespaceRecords applies update to the records while holding the records' lock
+func updateNamespaceRecords(fs afero.Fs, path string, update func(records map[string]namespaceRecord)) error {
+	if err := fs.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	unlock, err := lockNamespaceRegistry(fs, path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	records, err := readNamespaceRecords(fs, path)
+	if err != nil {
+		return err
+	}
+
+	update(records)
+
+	b, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// write then rename so readers never observe partially written records
+	tmp := path + ".tmp"
+
+	err = afero.WriteFile(fs, tmp, b, 0644)
+	if err != nil {
+		return err
+	}
+
+	return fs.Rename(tmp, path)
+}
+
+// readNamespaceRecords returns the recorded namespaced environments, none when nothing was recorded yet
+func readNamespaceRecords(fs afero.Fs, path string) (map[string]namespaceRecord, error) {
+	records := map[string]namespaceRecord{}
+
+	b, err := afero.ReadFile(fs, path)
+	if os.IsNotExist(err) {
+		return records, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(strings.TrimSpace(string(b))) == 0 {
+		return records, nil
+	}
+
+	err = json.Unmarshal(b, &records)
+	if err != nil {
+		return nil, fmt.Errorf("namespace records %s are invalid: %w", path, err)
+	}
+
+	return records, nil
+}
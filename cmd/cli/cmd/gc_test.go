@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordNamespaceRun_ShouldKeepCreationUntilDestroyed(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	path := ".runiac/namespaces.json"
+	key := getNamespaceClaimKey("runiac", "dev", PullRequestDeploymentRing, "1234")
+	created := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	entry := historyEntry{Project: "runiac", Environment: "dev", DeploymentRing: PullRequestDeploymentRing, Namespace: "1234", Account: "123456789012", PrimaryRegions: []string{"us-east-1"}, Version: "v1", CorrelationID: "a"}
+
+	require.NoError(t, recordNamespaceRun(fs, path, entry, false, created))
+
+	entry.Version = "v2"
+	entry.CorrelationID = "b"
+	require.NoError(t, recordNamespaceRun(fs, path, entry, false, created.Add(time.Hour)))
+
+	records, err := readNamespaceRecords(fs, path)
+	require.NoError(t, err)
+	require.Equal(t, created, records[key].CreatedAt, "a redeploy keeps when the environment was created")
+	require.Equal(t, created.Add(time.Hour), records[key].DeployedAt)
+	require.Equal(t, "v2", records[key].Version)
+	require.Equal(t, "b", records[key].CorrelationID)
+
+	// a failed destroy leaves the environment deployed without extending its life
+	destroy := entry
+	destroy.Destroy = true
+	require.NoError(t, recordNamespaceRun(fs, path, destroy, false, created.Add(2*time.Hour)))
+
+	records, _ = readNamespaceRecords(fs, path)
+	require.Equal(t, created.Add(time.Hour), records[key].DeployedAt)
+
+	require.NoError(t, recordNamespaceRun(fs, path, destroy, true, created.Add(3*time.Hour)))
+
+	records, _ = readNamespaceRecords(fs, path)
+	require.NotContains(t, records, key, "a destroyed environment is no longer recorded")
+}
+
+func TestGetStaleNamespaceRecords_ShouldSelectEnvironmentsNotDeployedWithinOlderThan(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	path := "namespaces.json"
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+
+	record := func(project string, environment string, namespace string, deployedAt time.Time) {
+		entry := historyEntry{Project: project, Environment: environment, DeploymentRing: PullRequestDeploymentRing, Namespace: namespace}
+		require.NoError(t, recordNamespaceRun(fs, path, entry, false, deployedAt))
+	}
+
+	record("runiac", "dev", "1", now.Add(-100*time.Hour))
+	record("runiac", "dev", "2", now.Add(-time.Hour))
+	record("runiac", "prod", "3", now.Add(-100*time.Hour))
+	record("other", "dev", "4", now.Add(-100*time.Hour))
+
+	stale, err := getStaleNamespaceRecords(fs, path, "runiac", "", 72*time.Hour, now)
+	require.NoError(t, err)
+
+	namespaces := []string{}
+	for _, r := range stale {
+		namespaces = append(namespaces, r.Namespace)
+	}
+	require.Equal(t, []string{"1", "3"}, namespaces, "only the project's environments not deployed within 72h are stale")
+
+	stale, err = getStaleNamespaceRecords(fs, path, "runiac", "DEV", 72*time.Hour, now)
+	require.NoError(t, err)
+	require.Len(t, stale, 1)
+	require.Equal(t, "1", stale[0].Namespace)
+}
+
+func TestDestroyNamespaceRecords_ShouldDestroyEachStaleEnvironment(t *testing.T) {
+	defer func(f func([]string) error) { runGCDestroy = f }(runGCDestroy)
+	defer func(user string) { _ = os.Setenv("USER", user) }(os.Getenv("USER"))
+	_ = os.Setenv("USER", "alice")
+
+	destroyed := [][]string{}
+	runGCDestroy = func(args []string) error {
+		destroyed = append(destroyed, args)
+
+		if args[2] == "5678" {
+			return errors.New("exit status 1")
+		}
+
+		return nil
+	}
+
+	records := []namespaceRecord{
+		{Project: "runiac", Environment: "dev", DeploymentRing: PullRequestDeploymentRing, Namespace: "1234", Account: "123456789012", PrimaryRegions: []string{"us-east-1"}, RegionalRegions: []string{"us-east-2"}},
+		{Project: "runiac", Environment: "dev", DeploymentRing: PullRequestDeploymentRing, Namespace: "5678"},
+		{Project: "runiac", Environment: "dev", DeploymentRing: LocalDeploymentRing, Namespace: "alice"},
+		{Project: "runiac", Environment: "dev", DeploymentRing: LocalDeploymentRing, Namespace: "bob"},
+	}
+
+	failed := destroyNamespaceRecords(records, []string{"--container-engine", "podman"}, true)
+
+	require.Empty(t, failed)
+	require.Empty(t, destroyed, "a dry run does not destroy")
+
+	failed = destroyNamespaceRecords(records, []string{"--container-engine", "podman"}, false)
+
+	require.Equal(t, []string{"runiac/dev/pr/5678"}, failed)
+	require.Equal(t, [][]string{
+		{"destroy", "--pull-request", "1234", "--environment", "dev", "--account", "123456789012", "--primary-regions", "us-east-1", "--regional-regions", "us-east-2", "--container-engine", "podman"},
+		{"destroy", "--pull-request", "5678", "--environment", "dev", "--container-engine", "podman"},
+		{"destroy", "--local", "--environment", "dev", "--container-engine", "podman"},
+	}, destroyed, "the --local environment of another user is not destroyed")
+}
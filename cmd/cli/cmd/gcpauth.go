@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// serviceAccountPattern matches the email of a google service account
+var serviceAccountPattern = regexp.MustCompile(`^[a-z0-9-]+@[a-z0-9.-]+\.gserviceaccount\.com$`)
+
+// gcpCredentialSourceDir is the directory of the credential sources below the gcp credentials of the container, so a
+// source does not collide with the credential configuration of the same name
+const gcpCredentialSourceDir = credentialsGCP + "/source"
+
+// validateImpersonateServiceAccount ensures --impersonate-service-account is the email of a service account
+func validateImpersonateServiceAccount(serviceAccount string) error {
+	if serviceAccount != "" && !serviceAccountPattern.MatchString(serviceAccount) {
+		return fmt.Errorf("invalid --impersonate-service-account '%s', must be the email of a service account, e.g. deployer@project.iam.gserviceaccount.com", serviceAccount)
+	}
+
+	return nil
+}
+
+// getImpersonationArguments returns the container run arguments impersonating the service account with the
+// credentials of the container, for gcloud and the google providers. None when the passed access token already
+// belongs to the service account, see getGCPTokenArguments.
+func getImpersonationArguments(serviceAccount string, tokenImpersonated bool) []string {
+	if serviceAccount == "" || tokenImpersonated {
+		return nil
+	}
+
+	return []string{
+		"-e", fmt.Sprintf("CLOUDSDK_AUTH_IMPERSONATE_SERVICE_ACCOUNT=%s", serviceAccount),
+		"-e", fmt.Sprintf("GOOGLE_IMPERSONATE_SERVICE_ACCOUNT=%s", serviceAccount),
+	}
+}
+
+// getGCPApplicationCredentialArguments returns the container run arguments mounting the host's
+// GOOGLE_APPLICATION_CREDENTIALS. A workload identity federation configuration (external_account) reading its
+// subject token from a file references the file by its host path, so the file is mounted as well and a copy of the
+// configuration referencing its container path is written to dir and mounted instead.
+func getGCPApplicationCredentialArguments(dir string) ([]string, error) {
+	file := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	if file == "" {
+		return nil, nil
+	}
+
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GOOGLE_APPLICATION_CREDENTIALS '%s': %w", file, err)
+	}
+
+	config := map[string]interface{}{}
+	source := ""
+
+	// a key file without a credential source is mounted as is
+	if json.Unmarshal(b, &config) == nil && config["type"] == "external_account" {
+		if credentialSource, ok := config["credential_source"].(map[string]interface{}); ok {
+			source, _ = credentialSource["file"].(string)
+		}
+	}
+
+	// the run context is printed without writing the configuration's copy
+	if source == "" || dir == "" {
+		return getCredentialFileArguments("GOOGLE_APPLICATION_CREDENTIALS", credentialsGCP)
+	}
+
+	args, sourcePath, err := getCredentialFileMount(source, gcpCredentialSourceDir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid credential_source.file '%s' of GOOGLE_APPLICATION_CREDENTIALS: %w", source, err)
+	}
+
+	config["credential_source"].(map[string]interface{})["file"] = sourcePath
+
+	b, err = json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	if err = os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	copied := filepath.Join(dir, filepath.Base(file))
+
+	if err = ioutil.WriteFile(copied, b, 0600); err != nil {
+		return nil, err
+	}
+
+	configArgs, configPath, err := getCredentialFileMount(copied, credentialsGCP)
+	if err != nil {
+		return nil, err
+	}
+
+	args = append(args, configArgs...)
+
+	return append(args, "-e", fmt.Sprintf("GOOGLE_APPLICATION_CREDENTIALS=%s", configPath)), nil
+}
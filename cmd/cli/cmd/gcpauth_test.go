@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateImpersonateServiceAccount_ShouldRequireServiceAccountEmail(t *testing.T) {
+	require.NoError(t, validateImpersonateServiceAccount(""))
+	require.NoError(t, validateImpersonateServiceAccount("deployer@project-1.iam.gserviceaccount.com"))
+	require.Error(t, validateImpersonateServiceAccount("deployer@example.com"))
+	require.Error(t, validateImpersonateServiceAccount("deployer"))
+}
+
+func TestGetImpersonationArguments_ShouldSkipImpersonatedTokens(t *testing.T) {
+	require.Nil(t, getImpersonationArguments("", false))
+	require.Nil(t, getImpersonationArguments("deployer@project.iam.gserviceaccount.com", true))
+	require.Equal(t, []string{
+		"-e", "CLOUDSDK_AUTH_IMPERSONATE_SERVICE_ACCOUNT=deployer@project.iam.gserviceaccount.com",
+		"-e", "GOOGLE_IMPERSONATE_SERVICE_ACCOUNT=deployer@project.iam.gserviceaccount.com",
+	}, getImpersonationArguments("deployer@project.iam.gserviceaccount.com", false))
+}
+
+func TestGetGCPApplicationCredentialArguments_ShouldMountTheCredentialSource(t *testing.T) {
+	hostDir, tokenDir := t.TempDir(), filepath.Join(t.TempDir(), "0123abcdef", "gcp")
+
+	source := filepath.Join(hostDir, "oidc-token")
+	require.NoError(t, ioutil.WriteFile(source, []byte("eyJ"), 0600))
+
+	config := filepath.Join(hostDir, "wif.json")
+	require.NoError(t, ioutil.WriteFile(config, []byte(`{
+  "type": "external_account",
+  "audience": "//iam.googleapis.com/projects/1/locations/global/workloadIdentityPools/ci/providers/github",
+  "credential_source": {"file": "`+filepath.ToSlash(source)+`"}
+}`), 0600))
+
+	_ = os.Setenv("GOOGLE_APPLICATION_CREDENTIALS", config)
+	defer os.Unsetenv("GOOGLE_APPLICATION_CREDENTIALS")
+
+	args, err := getGCPApplicationCredentialArguments(tokenDir)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"-v", source + ":/runiac/credentials/gcp/source/oidc-token:ro",
+		"-v", filepath.Join(tokenDir, "wif.json") + ":/runiac/credentials/gcp/wif.json:ro",
+		"-e", "GOOGLE_APPLICATION_CREDENTIALS=/runiac/credentials/gcp/wif.json",
+	}, args)
+
+	b, err := ioutil.ReadFile(filepath.Join(tokenDir, "wif.json"))
+	require.NoError(t, err)
+
+	copied := struct {
+		Audience         string `json:"audience"`
+		CredentialSource struct {
+			File string `json:"file"`
+		} `json:"credential_source"`
+	}{}
+
+	require.NoError(t, json.Unmarshal(b, &copied))
+	require.Equal(t, "/runiac/credentials/gcp/source/oidc-token", copied.CredentialSource.File)
+	require.True(t, strings.HasSuffix(copied.Audience, "/providers/github"), "the rest of the configuration should be kept")
+
+	// a key file is mounted as is
+	key := filepath.Join(hostDir, "key.json")
+	require.NoError(t, ioutil.WriteFile(key, []byte(`{"type": "service_account"}`), 0600))
+	_ = os.Setenv("GOOGLE_APPLICATION_CREDENTIALS", key)
+
+	args, err = getGCPApplicationCredentialArguments(tokenDir)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"-v", key + ":/runiac/credentials/gcp/key.json:ro",
+		"-e", "GOOGLE_APPLICATION_CREDENTIALS=/runiac/credentials/gcp/key.json",
+	}, args)
+}
+
+func TestGetGCPTokenArguments_ShouldRequestTheImpersonatedToken(t *testing.T) {
+	defer func(run func(string, ...string) ([]byte, error)) {
+		runCredentialHelper, GCPImpersonate = run, ""
+	}(runCredentialHelper)
+
+	var helper string
+
+	runCredentialHelper = func(name string, args ...string) ([]byte, error) {
+		helper = name + " " + strings.Join(args, " ")
+		return []byte("ya29.impersonated\n"), nil
+	}
+
+	GCPImpersonate = "deployer@project.iam.gserviceaccount.com"
+
+	args, err := getGCPTokenArguments()
+	require.NoError(t, err)
+	require.Equal(t, "gcloud auth print-access-token --impersonate-service-account deployer@project.iam.gserviceaccount.com", helper)
+	require.Equal(t, []string{"-e", "GOOGLE_OAUTH_ACCESS_TOKEN=ya29.impersonated"}, args)
+}
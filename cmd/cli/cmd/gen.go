@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+// autogeneratedHeader marks the files runiac owns and rewrites on every run
+const autogeneratedHeader = "# do not edit --- autogenerated by runiac --- do not edit"
+
+// generatedDockerfileHeader marks a dockerfile produced by runiac gen dockerfile, which runiac never overwrites
+const generatedDockerfileHeader = "# generated by runiac gen dockerfile --- safe to edit, runiac will not overwrite this file"
+
+// baseContainerPattern is the runiac deploy base image contract, runiac/deploy:{version}-alpine
+var baseContainerPattern = regexp.MustCompile(`(^|/)runiac/deploy:[^:/]+-alpine[^:/]*$`)
+
+var apkPackagePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._+-]*$`)
+
+var (
+	genRunner     string
+	genContainer  string
+	genTools      []string
+	genDockerfile string
+	genForce      bool
+)
+
+func init() {
+	genDockerfileCmd.Flags().StringVar(&genRunner, "runner", "terraform", fmt.Sprintf("The deployment tool the image runs (%s)", strings.Join(supportedRunners, ", ")))
+	genDockerfileCmd.Flags().StringVarP(&genContainer, "container", "c", "docker.io/runiac/deploy:latest-alpine-full", "The runiac deploy base image, must be runiac/deploy:{version}-alpine")
+	genDockerfileCmd.Flags().StringSliceVar(&genTools, "with-tools", []string{}, "Additional alpine packages to install in the image, e.g. jq,python3")
+	genDockerfileCmd.Flags().StringVarP(&genDockerfile, "output", "o", ".runiac/Dockerfile", "The dockerfile to write")
+	genDockerfileCmd.Flags().BoolVar(&genForce, "force", false, "Overwrite an existing dockerfile that was not autogenerated by runiac")
+
+	genCmd.AddCommand(genDockerfileCmd)
+	rootCmd.AddCommand(genCmd)
+}
+
+var genCmd = &cobra.Command{
+	Use:     "gen",
+	Aliases: []string{"generate"},
+	Short:   "Generate runiac project files",
+	Long:    `Generates runiac project files from options, to be further customized by hand.`,
+}
+
+var genDockerfileCmd = &cobra.Command{
+	Use:   "dockerfile",
+	Short: "Generate the project dockerfile",
+	Long: `Writes a dockerfile deriving from the runiac deploy base image with the tools for the runner.
+Unlike the dockerfile autogenerated by runiac, the generated dockerfile is never overwritten by runiac deploy.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		dockerfile, err := generateDockerfile(genRunner, genContainer, genTools)
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		err = writeGeneratedDockerfile(appFS, genDockerfile, dockerfile, genForce)
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		fmt.Printf("Wrote %s\n", genDockerfile)
+	},
+}
+
+const generatedDockerfileTemplate = `{{ .Header }}
+# syntax = docker/dockerfile:experimental
+
+ARG RUNIAC_CONTAINER="{{ .Container }}"
+
+FROM $RUNIAC_CONTAINER
+{{- if .Tools }}
+
+RUN apk add --no-cache {{ join .Tools " " }}
+{{- end }}
+{{- if eq .Runner "pulumi" }}
+
+# the pulumi runner expects the pulumi cli on the path
+RUN apk add --no-cache curl && curl -fsSL https://get.pulumi.com | sh
+ENV PATH="/root/.pulumi/bin:${PATH}"
+{{- end }}
+{{- if eq .Runner "terragrunt" }}
+
+# the terragrunt runner expects the terragrunt cli on the path, wrapping the container's terraform
+ARG TERRAGRUNT_VERSION="0.38.7"
+RUN apk add --no-cache curl && curl -fsSL -o /usr/local/bin/terragrunt "https://github.com/gruntwork-io/terragrunt/releases/download/v${TERRAGRUNT_VERSION}/terragrunt_linux_amd64" && chmod +x /usr/local/bin/terragrunt
+{{- end }}
+
+WORKDIR /app
+
+COPY . .
+{{- if or (eq .Runner "terraform") (eq .Runner "terragrunt") }}
+
+RUN mkdir -p $HOME/.terraform.d/plugin-cache
+{{- end }}
+RUN mkdir -p /runiac/tfstate
+
+COPY entrypoint.sh entrypoint.sh
+RUN chmod +x entrypoint.sh
+
+
+ENTRYPOINT ["bash", "-c", "./entrypoint.sh"]
+`
+
+// generateDockerfile returns a dockerfile for the runner deriving from the base container with the additional tools
+func generateDockerfile(runner string, container string, tools []string) (string, error) {
+	err := validateRunner(runner, nil)
+	if err != nil {
+		return "", err
+	}
+
+	if !baseContainerPattern.MatchString(container) {
+		return "", fmt.Errorf("base container '%s' must be runiac/deploy:{version}-alpine", container)
+	}
+
+	for _, tool := range tools {
+		if !apkPackagePattern.MatchString(tool) {
+			return "", fmt.Errorf("invalid tool '%s', must be an alpine package name", tool)
+		}
+	}
+
+	tmpl := template.Must(template.New("dockerfile").Funcs(template.FuncMap{"join": strings.Join}).Parse(generatedDockerfileTemplate))
+
+	var b bytes.Buffer
+
+	err = tmpl.Execute(&b, struct {
+		Header    string
+		Runner    string
+		Container string
+		Tools     []string
+	}{generatedDockerfileHeader, runner, container, tools})
+
+	return b.String(), err
+}
+
+// writeGeneratedDockerfile writes the dockerfile, refusing to overwrite one that was not autogenerated unless forced
+func writeGeneratedDockerfile(fs afero.Fs, path string, dockerfile string, force bool) error {
+	if existing, err := afero.ReadFile(fs, path); err == nil && !force && !isAutogenerated(existing) {
+		return fmt.Errorf("%s already exists, use --force to overwrite it", path)
+	}
+
+	err := fs.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	return afero.WriteFile(fs, path, []byte(dockerfile), 0644)
+}
+
+// isAutogenerated returns true for file contents runiac owns and rewrites on every run
+func isAutogenerated(contents []byte) bool {
+	return strings.HasPrefix(string(contents), autogeneratedHeader)
+}
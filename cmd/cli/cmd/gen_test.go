@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateDockerfile_ShouldRespectTheBaseContainerContract(t *testing.T) {
+	dockerfile, err := generateDockerfile("pulumi", "docker.io/runiac/deploy:v0.0.5-alpine-full", []string{"jq", "python3"})
+	require.NoError(t, err)
+	require.Contains(t, dockerfile, generatedDockerfileHeader)
+	require.Contains(t, dockerfile, `ARG RUNIAC_CONTAINER="docker.io/runiac/deploy:v0.0.5-alpine-full"`)
+	require.Contains(t, dockerfile, "RUN apk add --no-cache jq python3")
+	require.Contains(t, dockerfile, "get.pulumi.com")
+	require.NotContains(t, dockerfile, "terraform.d")
+
+	dockerfile, err = generateDockerfile("terraform", "runiac/deploy:latest-alpine", nil)
+	require.NoError(t, err)
+	require.NotContains(t, dockerfile, "apk add")
+	require.Contains(t, dockerfile, "terraform.d/plugin-cache")
+
+	dockerfile, err = generateDockerfile("terragrunt", "runiac/deploy:latest-alpine", nil)
+	require.NoError(t, err)
+	require.Contains(t, dockerfile, "gruntwork-io/terragrunt/releases")
+	require.Contains(t, dockerfile, "terraform.d/plugin-cache")
+
+	_, err = generateDockerfile("terraform", "ubuntu:20.04", nil)
+	require.Error(t, err, "the base image must be a runiac deploy image")
+
+	_, err = generateDockerfile("terraform", "runiac/deploy:latest", nil)
+	require.Error(t, err, "the base image must be alpine")
+
+	_, err = generateDockerfile("../ansible", "runiac/deploy:latest-alpine", nil)
+	require.Error(t, err)
+
+	_, err = generateDockerfile("terraform", "runiac/deploy:latest-alpine", []string{"jq && rm -rf /"})
+	require.Error(t, err)
+}
+
+func TestWriteGeneratedDockerfile_ShouldNotOverwriteCustomDockerfiles(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	_ = afero.WriteFile(fs, ".runiac/Dockerfile", []byte(autogeneratedHeader+"\nFROM base"), 0644)
+
+	require.NoError(t, writeGeneratedDockerfile(fs, ".runiac/Dockerfile", "FROM generated", false), "autogenerated dockerfiles can be replaced")
+	require.Error(t, writeGeneratedDockerfile(fs, ".runiac/Dockerfile", "FROM other", false))
+	require.NoError(t, writeGeneratedDockerfile(fs, ".runiac/Dockerfile", "FROM other", true))
+
+	b, _ := afero.ReadFile(fs, ".runiac/Dockerfile")
+	require.Equal(t, "FROM other", string(b))
+}
+
+func TestInitAction_ShouldKeepGeneratedDockerfiles(t *testing.T) {
+	defer func(fs afero.Fs) { appFS = fs }(appFS)
+	appFS = afero.NewMemMapFs()
+
+	require.True(t, InitAction())
+	b, _ := afero.ReadFile(appFS, ".runiac/Dockerfile")
+	require.True(t, isAutogenerated(b))
+
+	_ = afero.WriteFile(appFS, ".runiac/Dockerfile", []byte(generatedDockerfileHeader+"\nFROM custom"), 0644)
+
+	require.True(t, InitAction())
+	b, _ = afero.ReadFile(appFS, ".runiac/Dockerfile")
+	require.Equal(t, generatedDockerfileHeader+"\nFROM custom", string(b))
+}
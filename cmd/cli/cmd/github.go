@@ -0,0 +1,290 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+const (
+	githubDefaultAPIURL     = "https://api.github.com"
+	githubCommentMaxLength  = 65536 // The maximum length of the body of a GitHub comment
+	githubCommentsPerPage   = 100
+	githubCommentMarkerText = "<!-- runiac-plan project=%s environment=%s rings=%s -->"
+)
+
+// githubPullRefPattern matches the GITHUB_REF of a pull_request run, e.g. refs/pull/42/merge
+var githubPullRefPattern = regexp.MustCompile(`^refs/pull/(\d+)/`)
+
+// githubPullRequest is the pull request a GitHub Actions run comments the plans on
+type githubPullRequest struct {
+	APIURL     string
+	Repository string // The owner/repo of the pull request
+	Number     int
+	Token      string
+}
+
+// githubComment is the subset of an issue comment of the GitHub API used to find the comment of a previous run
+type githubComment struct {
+	ID   int64  `json:"id"`
+	Body string `json:"body"`
+}
+
+// validateGitHubComment ensures the plans of the run can be commented on the pull request once it completes
+func validateGitHubComment(dryRun bool, detach bool) error {
+	if !dryRun {
+		return errors.New("--github-comment comments the plans of a dry run, use it with --dry-run or runiac plan")
+	}
+
+	if detach {
+		return errors.New("--github-comment can not be used with --detach, the plans are commented once the container exits")
+	}
+
+	return nil
+}
+
+// getGitHubPullRequest returns the pull request of the GitHub Actions run from its environment. The pull request
+// number is read from the GITHUB_EVENT_PATH event or the GITHUB_REF of the run, the comment is posted with
+// GITHUB_TOKEN, or GH_TOKEN, which needs the pull-requests: write permission.
+func getGitHubPullRequest(fs afero.Fs) (githubPullRequest, error) {
+	pr := githubPullRequest{
+		APIURL:     strings.TrimSuffix(os.Getenv("GITHUB_API_URL"), "/"),
+		Repository: os.Getenv("GITHUB_REPOSITORY"),
+		Token:      os.Getenv("GITHUB_TOKEN"),
+	}
+
+	if pr.APIURL == "" {
+		pr.APIURL = githubDefaultAPIURL
+	}
+
+	if pr.Token == "" {
+		pr.Token = os.Getenv("GH_TOKEN")
+	}
+
+	if pr.Repository == "" {
+		return pr, errors.New("--github-comment requires GITHUB_REPOSITORY, it is set by GitHub Actions")
+	}
+
+	if pr.Token == "" {
+		return pr, errors.New("--github-comment requires GITHUB_TOKEN, e.g. set to ${{ github.token }} with the pull-requests: write permission")
+	}
+
+	if path := os.Getenv("GITHUB_EVENT_PATH"); path != "" {
+		event := struct {
+			Number      int `json:"number"`
+			PullRequest struct {
+				Number int `json:"number"`
+			} `json:"pull_request"`
+		}{}
+
+		if b, err := afero.ReadFile(fs, path); err == nil && json.Unmarshal(b, &event) == nil {
+			pr.Number = event.PullRequest.Number
+			if pr.Number == 0 {
+				pr.Number = event.Number
+			}
+		}
+	}
+
+	if match := githubPullRefPattern.FindStringSubmatch(os.Getenv("GITHUB_REF")); pr.Number == 0 && match != nil {
+		pr.Number, _ = strconv.Atoi(match[1])
+	}
+
+	if pr.Number == 0 {
+		return pr, errors.New("--github-comment requires a pull_request run of GitHub Actions, no pull request found in GITHUB_EVENT_PATH or GITHUB_REF")
+	}
+
+	return pr, nil
+}
+
+// getGitHubCommentMarker returns the hidden marker identifying the comment of the project's plans of the environment
+// and rings, so each run updates the comment of its previous run instead of adding one
+func getGitHubCommentMarker(project string, environment string, rings []string) string {
+	if environment == "" {
+		environment = savedPlansDefaultEnvironment
+	}
+
+	return fmt.Sprintf(githubCommentMarkerText, project, environment, strings.Join(rings, ","))
+}
+
+// getGitHubRunURL returns the url of the GitHub Actions run, empty outside of GitHub Actions
+func getGitHubRunURL() string {
+	server, repository, runID := os.Getenv("GITHUB_SERVER_URL"), os.Getenv("GITHUB_REPOSITORY"), os.Getenv("GITHUB_RUN_ID")
+	if server == "" || repository == "" || runID == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("%s/%s/actions/runs/%s", strings.TrimSuffix(server, "/"), repository, runID)
+}
+
+// renderPlanComment returns the markdown of the pull request comment of the plan summary, a collapsible section per
+// step execution listing its resource changes. The resource changes are left out when the comment would exceed the
+// maximum length of a comment.
+func renderPlanComment(marker string, title string, summary planSummary, failed bool, runURL string) string {
+	body := renderPlanCommentBody(marker, title, summary, failed, runURL, true)
+
+	if len(body) > githubCommentMaxLength {
+		body = renderPlanCommentBody(marker, title, summary, failed, runURL, false)
+	}
+
+	return body
+}
+
+// renderPlanCommentBody returns the markdown of the comment, with or without the resource changes of each step
+func renderPlanCommentBody(marker string, title string, summary planSummary, failed bool, runURL string, resources bool) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%s\n### %s\n\n", marker, title)
+
+	if failed {
+		b.WriteString("> **Warning**\n> The dry run failed, the plans of the failed steps are missing.")
+
+		if runURL != "" {
+			fmt.Fprintf(&b, " See the [workflow run](%s) for details.", runURL)
+		}
+
+		b.WriteString("\n\n")
+	}
+
+	if len(summary.Steps) == 0 {
+		b.WriteString("No plans were saved, structured plans are only available for terraform steps.\n")
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "**Plan: %d to add, %d to change, %d to destroy** across %d step execution(s).\n\n", summary.Add, summary.Change, summary.Destroy, len(summary.Steps))
+
+	for _, s := range summary.Steps {
+		fmt.Fprintf(&b, "<details><summary><code>%s</code> %s in %s (%s): +%d ~%d -%d</summary>\n\n", s.DeploymentRing, s.StepID, s.Region, s.RegionDeployType, s.Add, s.Change, s.Destroy)
+
+		switch {
+		case len(s.Resources) == 0:
+			b.WriteString("No changes.\n")
+		case !resources:
+			b.WriteString("The resource changes are left out, the plan is too large for a comment.\n")
+		default:
+			b.WriteString("```diff\n")
+
+			for _, r := range s.Resources {
+				fmt.Fprintf(&b, "%s %s (%s)\n", getResourceChangePrefix(r.Action), r.Address, r.Action)
+			}
+
+			b.WriteString("```\n")
+		}
+
+		b.WriteString("\n</details>\n")
+	}
+
+	if runURL != "" {
+		fmt.Fprintf(&b, "\nPlanned by [this workflow run](%s).\n", runURL)
+	}
+
+	return b.String()
+}
+
+// getResourceChangePrefix returns the diff prefix of the action of a resource change
+func getResourceChangePrefix(action string) string {
+	switch action {
+	case "create":
+		return "+"
+	case "delete":
+		return "-"
+	case "replace":
+		return "-/+"
+	default:
+		return "~"
+	}
+}
+
+// upsertGitHubComment updates the comment of the pull request containing the marker, or posts the body as a new
+// comment when no previous run commented
+func upsertGitHubComment(client *http.Client, pr githubPullRequest, marker string, body string) error {
+	var existing *githubComment
+
+	for page := 1; existing == nil; page++ {
+		comments := []githubComment{}
+
+		url := fmt.Sprintf("%s/repos/%s/issues/%d/comments?per_page=%d&page=%d", pr.APIURL, pr.Repository, pr.Number, githubCommentsPerPage, page)
+		if err := sendGitHubRequest(client, pr, http.MethodGet, url, nil, &comments); err != nil {
+			return err
+		}
+
+		for i := range comments {
+			if strings.Contains(comments[i].Body, marker) {
+				existing = &comments[i]
+				break
+			}
+		}
+
+		if len(comments) < githubCommentsPerPage {
+			break
+		}
+	}
+
+	payload := map[string]string{"body": body}
+
+	if existing != nil {
+		return sendGitHubRequest(client, pr, http.MethodPatch, fmt.Sprintf("%s/repos/%s/issues/comments/%d", pr.APIURL, pr.Repository, existing.ID), payload, nil)
+	}
+
+	return sendGitHubRequest(client, pr, http.MethodPost, fmt.Sprintf("%s/repos/%s/issues/%d/comments", pr.APIURL, pr.Repository, pr.Number), payload, nil)
+}
+
+// sendGitHubRequest sends the payload as json to the GitHub API, decoding the response into out when set
+func sendGitHubRequest(client *http.Client, pr githubPullRequest, method string, url string, payload interface{}, out interface{}) error {
+	var body io.Reader
+
+	if payload != nil {
+		b, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+pr.Token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s %s returned %s", method, url, resp.Status)
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// commentPlans comments the summary of the plans of the run on the pull request. Commenting is best
+// effort, a failed comment is logged without failing the run.
+func commentPlans(client *http.Client, pr githubPullRequest, marker string, title string, summary planSummary, failed bool) {
+	err := upsertGitHubComment(client, pr, marker, renderPlanComment(marker, title, summary, failed, getGitHubRunURL()))
+	if err != nil {
+		logrus.WithError(err).Warnf("Unable to comment the plans on pull request #%d of %s", pr.Number, pr.Repository)
+		return
+	}
+
+	logrus.Infof("Commented the plans on pull request #%d of %s", pr.Number, pr.Repository)
+}
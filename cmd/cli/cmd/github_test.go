@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetGitHubPullRequest_ShouldDetectThePullRequestOfTheRun(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	_ = afero.WriteFile(fs, "event.json", []byte(`{"action": "synchronize", "number": 42, "pull_request": {"number": 42}}`), 0644)
+
+	// the run of the tests may itself be a GitHub Actions run
+	for _, name := range []string{"GITHUB_API_URL", "GITHUB_REPOSITORY", "GITHUB_TOKEN", "GH_TOKEN", "GITHUB_EVENT_PATH", "GITHUB_REF"} {
+		defer func(name string, value string, set bool) {
+			if set {
+				_ = os.Setenv(name, value)
+			} else {
+				_ = os.Unsetenv(name)
+			}
+		}(name, os.Getenv(name), os.Getenv(name) != "")
+		_ = os.Unsetenv(name)
+	}
+
+	_, err := getGitHubPullRequest(fs)
+	require.Error(t, err, "GITHUB_REPOSITORY is required")
+
+	_ = os.Setenv("GITHUB_REPOSITORY", "optum/runiac")
+	_, err = getGitHubPullRequest(fs)
+	require.Error(t, err, "GITHUB_TOKEN is required")
+
+	_ = os.Setenv("GITHUB_TOKEN", "ghs_token")
+	_, err = getGitHubPullRequest(fs)
+	require.Error(t, err, "a push run has no pull request")
+
+	_ = os.Setenv("GITHUB_EVENT_PATH", "event.json")
+	pr, err := getGitHubPullRequest(fs)
+	require.NoError(t, err)
+	require.Equal(t, githubPullRequest{APIURL: githubDefaultAPIURL, Repository: "optum/runiac", Number: 42, Token: "ghs_token"}, pr)
+
+	_ = os.Unsetenv("GITHUB_EVENT_PATH")
+	_ = os.Setenv("GITHUB_REF", "refs/pull/7/merge")
+	pr, err = getGitHubPullRequest(fs)
+	require.NoError(t, err)
+	require.Equal(t, 7, pr.Number)
+}
+
+func TestRenderPlanComment_ShouldCollapseTheChangesOfEachStep(t *testing.T) {
+	summary := planSummary{Add: 2, Destroy: 1, Steps: []stepPlanSummary{
+		{StepID: "network/vpc", Region: "centralus", RegionDeployType: "primary", DeploymentRing: "dev", Add: 2, Destroy: 1, Resources: []resourcePlanChange{
+			{Address: "azurerm_virtual_network.vnet", Action: "create"},
+			{Address: "azurerm_subnet.subnet", Action: "replace"},
+		}},
+		{StepID: "network/dns", Region: "centralus", RegionDeployType: "primary", DeploymentRing: "dev", Resources: []resourcePlanChange{}},
+	}}
+
+	comment := renderPlanComment("<!-- marker -->", "runiac plan", summary, false, "")
+
+	require.True(t, strings.HasPrefix(comment, "<!-- marker -->\n### runiac plan\n"))
+	require.Contains(t, comment, "**Plan: 2 to add, 0 to change, 1 to destroy** across 2 step execution(s).")
+	require.Contains(t, comment, "<details><summary><code>dev</code> network/vpc in centralus (primary): +2 ~0 -1</summary>")
+	require.Contains(t, comment, "```diff\n+ azurerm_virtual_network.vnet (create)\n-/+ azurerm_subnet.subnet (replace)\n```")
+	require.Contains(t, comment, "No changes.")
+	require.NotContains(t, comment, "Warning")
+
+	require.Contains(t, renderPlanComment("<!-- marker -->", "runiac plan", summary, true, "https://github.com/optum/runiac/actions/runs/1"), "See the [workflow run](https://github.com/optum/runiac/actions/runs/1) for details.")
+
+	// a plan too large for a comment leaves out the resource changes
+	large := summary
+	large.Steps = []stepPlanSummary{summary.Steps[0]}
+	large.Steps[0].Resources = []resourcePlanChange{}
+
+	for len(large.Steps[0].Resources)*40 <= githubCommentMaxLength {
+		large.Steps[0].Resources = append(large.Steps[0].Resources, resourcePlanChange{Address: "azurerm_resource_group.resource_group", Action: "create"})
+	}
+
+	comment = renderPlanComment("<!-- marker -->", "runiac plan", large, false, "")
+	require.LessOrEqual(t, len(comment), githubCommentMaxLength)
+	require.Contains(t, comment, "The resource changes are left out")
+}
+
+func TestUpsertGitHubComment_ShouldUpdateTheCommentOfThePreviousRun(t *testing.T) {
+	comments := []githubComment{{ID: 1, Body: "LGTM"}}
+	requests := []string{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		require.Equal(t, "Bearer ghs_token", r.Header.Get("Authorization"))
+
+		payload := map[string]string{}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+
+		switch r.Method {
+		case http.MethodGet:
+			_ = json.NewEncoder(w).Encode(comments)
+		case http.MethodPost:
+			comments = append(comments, githubComment{ID: 2, Body: payload["body"]})
+			w.WriteHeader(http.StatusCreated)
+		case http.MethodPatch:
+			comments[1].Body = payload["body"]
+		}
+	}))
+	defer server.Close()
+
+	pr := githubPullRequest{APIURL: server.URL, Repository: "optum/runiac", Number: 42, Token: "ghs_token"}
+	marker := getGitHubCommentMarker("project", "", []string{"dev"})
+
+	require.Equal(t, "<!-- runiac-plan project=project environment=default rings=dev -->", marker)
+
+	require.NoError(t, upsertGitHubComment(server.Client(), pr, marker, marker+"\nfirst plan"))
+	require.NoError(t, upsertGitHubComment(server.Client(), pr, marker, marker+"\nsecond plan"))
+
+	require.Equal(t, []string{
+		"GET /repos/optum/runiac/issues/42/comments",
+		"POST /repos/optum/runiac/issues/42/comments",
+		"GET /repos/optum/runiac/issues/42/comments",
+		"PATCH /repos/optum/runiac/issues/comments/2",
+	}, requests)
+	require.Len(t, comments, 2)
+	require.Equal(t, marker+"\nsecond plan", comments[1].Body)
+}
+
+func TestValidateGitHubComment_ShouldRequireADryRun(t *testing.T) {
+	require.NoError(t, validateGitHubComment(true, false))
+	require.Error(t, validateGitHubComment(false, false))
+	require.Error(t, validateGitHubComment(true, true))
+}
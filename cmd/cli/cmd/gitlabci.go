@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var gitlabCIOptions pipelineOptions
+
+func init() {
+	addPipelineFlags(genGitlabCICmd, &gitlabCIOptions, ".gitlab-ci.yml")
+
+	genCmd.AddCommand(genGitlabCICmd)
+}
+
+var genGitlabCICmd = &cobra.Command{
+	Use:   "gitlab-ci",
+	Short: "Generate a GitLab CI pipeline",
+	Long: `Writes a GitLab CI pipeline from the runiac config, planning every deployment ring on merge requests and
+deploying the rings in order to the GitLab environment named after the ring. The jobs run runiac with docker in
+docker; set the cloud credentials, e.g. ARM_CLIENT_ID or AWS_ACCESS_KEY_ID, as masked CI/CD variables scoped to the
+ring's environment and runiac forwards them into the container.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runPipelineGenerator(gitlabCIOptions, generateGitlabCI)
+	},
+}
+
+// generateGitlabCI returns a gitlab ci pipeline planning and deploying the targets of the project
+func generateGitlabCI(graph projectGraph, targets []pipelineTarget, branch string) (string, error) {
+	return renderPipeline("gitlab-ci", generatedGitlabCITemplate, struct {
+		Header  string
+		Project string
+		Version string
+		Branch  string
+		Targets []pipelineTarget
+	}{getGeneratedPipelineHeader("gitlab-ci"), viper.GetString("project"), strings.TrimPrefix(Version, "v"), branch, targets})
+}
+
+const generatedGitlabCITemplate = `[[ .Header ]]
+[[- if .Project ]]
+# Plans and deploys [[ .Project ]] with runiac
+[[- end ]]
+#
+# Set the cloud credentials, e.g. ARM_CLIENT_ID, ARM_CLIENT_SECRET, ARM_SUBSCRIPTION_ID and ARM_TENANT_ID or
+# AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY, as masked CI/CD variables scoped to the environment of each ring.
+# runiac forwards them into the container.
+#
+# runiac mounts the project into the container, so the runner must share the builds directory with the docker
+# service, e.g. the docker executor with the default /builds volume.
+stages:
+  - plan
+  - deploy
+
+variables:
+  RUNIAC_VERSION: "[[ .Version ]]" # the latest release when empty
+  DOCKER_HOST: tcp://docker:2375
+  DOCKER_TLS_CERTDIR: ""
+
+default:
+  image: docker:24
+  services:
+    - docker:24-dind
+  before_script:
+    - apk add --no-cache curl
+    - |
+[[ install false | indent 6 ]]
+
+.runiac:
+  script:
+    - |
+[[ regionArgs | indent 6 ]]
+      runiac "$RUNIAC_ACTION" -d "$RING" -e "$ENVIRONMENT" "$@"
+  artifacts:
+    when: always
+    paths:
+      - .runiac/reports/
+[[- range .Targets ]]
+
+plan:[[ .Ring ]]:
+  extends: .runiac
+  stage: plan
+  rules:
+    - if: $CI_PIPELINE_SOURCE == "merge_request_event"
+  environment:
+    name: [[ .Environment ]]
+    action: prepare
+  variables:
+[[ include "variables" (dict "Action" "plan" "Target" .) | indent 4 ]]
+[[- end ]]
+[[- $previous := "" ]]
+[[- range .Targets ]]
+
+deploy:[[ .Ring ]]:
+  extends: .runiac
+  stage: deploy
+  rules:
+    - if: $CI_COMMIT_BRANCH == "[[ $.Branch ]]"
+  needs: [[ if $previous ]]["deploy:[[ $previous ]]"][[ else ]][][[ end ]]
+  resource_group: [[ .Ring ]]
+  environment:
+    name: [[ .Environment ]]
+  variables:
+[[ include "variables" (dict "Action" "deploy" "Target" .) | indent 4 ]]
+[[- $previous = .Ring ]]
+[[- end ]]
+[[- define "variables" -]]
+RUNIAC_ACTION: [[ .Action ]]
+RING: "[[ .Target.Ring ]]"
+ENVIRONMENT: "[[ .Target.Environment ]]"
+PRIMARY_REGION: "[[ .Target.PrimaryRegion ]]"
+REGIONAL_REGIONS: "[[ .Target.RegionalRegions ]]"
+[[- end ]]
+`
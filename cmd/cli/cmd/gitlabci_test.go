@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestGenerateGitlabCI_ShouldDeployRingsInOrder(t *testing.T) {
+	defer viper.Reset()
+
+	viper.Set("project", "stub")
+	viper.Set("primary_region", "us-east-1")
+	viper.Set("rings.prod.regional_regions", []string{"us-east-1", "eu-west-1"})
+
+	targets, err := getPipelineTargets([]string{"dev", "prod"})
+	require.NoError(t, err)
+
+	pipeline, err := generateGitlabCI(projectGraph{}, targets, "main")
+	require.NoError(t, err)
+	require.Contains(t, pipeline, getGeneratedPipelineHeader("gitlab-ci"))
+
+	type gitlabJob struct {
+		Extends       string            `yaml:"extends"`
+		Stage         string            `yaml:"stage"`
+		Needs         []string          `yaml:"needs"`
+		ResourceGroup string            `yaml:"resource_group"`
+		Variables     map[string]string `yaml:"variables"`
+		Script        []string          `yaml:"script"`
+		Environment   struct {
+			Name string `yaml:"name"`
+		} `yaml:"environment"`
+		Rules []struct {
+			If string `yaml:"if"`
+		} `yaml:"rules"`
+	}
+
+	nodes := map[string]yaml.Node{}
+	require.NoError(t, yaml.Unmarshal([]byte(pipeline), &nodes), pipeline)
+
+	parsed := map[string]gitlabJob{}
+
+	for _, name := range []string{".runiac", "plan:dev", "plan:prod", "deploy:dev", "deploy:prod"} {
+		node, ok := nodes[name]
+		require.True(t, ok, name)
+
+		job := gitlabJob{}
+		require.NoError(t, node.Decode(&job))
+
+		parsed[name] = job
+	}
+
+	for _, name := range []string{"plan:dev", "plan:prod", "deploy:dev", "deploy:prod"} {
+		require.Equal(t, ".runiac", parsed[name].Extends)
+	}
+
+	require.Equal(t, `$CI_PIPELINE_SOURCE == "merge_request_event"`, parsed["plan:prod"].Rules[0].If)
+	require.Equal(t, `$CI_COMMIT_BRANCH == "main"`, parsed["deploy:prod"].Rules[0].If)
+
+	require.Equal(t, []string{}, parsed["deploy:dev"].Needs)
+	require.Equal(t, []string{"deploy:dev"}, parsed["deploy:prod"].Needs)
+	require.Equal(t, "prod", parsed["deploy:prod"].ResourceGroup)
+	require.Equal(t, "prod", parsed["deploy:prod"].Environment.Name)
+	require.Equal(t, map[string]string{
+		"RUNIAC_ACTION":    "deploy",
+		"RING":             "prod",
+		"ENVIRONMENT":      "prod",
+		"PRIMARY_REGION":   "us-east-1",
+		"REGIONAL_REGIONS": "us-east-1,eu-west-1",
+	}, parsed["deploy:prod"].Variables)
+
+	require.Contains(t, parsed[".runiac"].Script[0], `runiac "$RUNIAC_ACTION" -d "$RING" -e "$ENVIRONMENT" "$@"`)
+}
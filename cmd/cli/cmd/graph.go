@@ -0,0 +1,703 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/pkg/tracks"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var mermaidIDPattern = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+var (
+	graphFormat   string
+	graphOutput   string
+	graphRings    []string
+	graphPrimary  []string
+	graphRegional []string
+	graphParallel bool
+)
+
+func init() {
+	graphCmd.Flags().StringVar(&graphFormat, "format", "dot", "The graph format, dot (graphviz) or mermaid")
+	graphCmd.Flags().StringVarP(&graphOutput, "output", "o", "", "Write the graph to this file instead of stdout")
+	graphCmd.Flags().StringSliceVarP(&graphRings, "deployment-ring", "d", []string{}, "Annotate the fan-out of these deployment rings, in deployment order")
+	graphCmd.Flags().StringArrayVarP(&graphPrimary, "primary-regions", "p", []string{}, "Primary region, defaults to rings.{ring}.primary_regions or primary_region in the runiac config")
+	graphCmd.Flags().StringArrayVarP(&graphRegional, "regional-regions", "r", []string{}, "Regional regions, defaults to rings.{ring}.regional_regions or regional_regions in the runiac config")
+	graphCmd.Flags().BoolVar(&graphParallel, "parallel-primary-regions", false, "Annotate the primary regions as deployed concurrently, defaults to parallel_primary_regions in the runiac config")
+
+	rootCmd.AddCommand(graphCmd)
+}
+
+var graphCmd = &cobra.Command{
+	Use:   "graph",
+	Short: "Print the track and step dependency graph",
+	Long: `Prints the dependency graph of the project's tracks and steps, derived from the directory layout, in dot
+(graphviz) or mermaid format. Steps depend on the steps of the previous progression level of their track and every
+track depends on the _pretrack. Edges are labeled with the inputs a step reads from the outputs of another step, dashed
+when the step does not directly depend on it. Steps without a dependency on one another that start together are grouped
+as concurrent, and the tracks following the _pretrack run concurrently. Steps are annotated with the regions they fan
+out to for each deployment ring, regions separated by -> deploy one after another, e.g. the primary regions unless
+--parallel-primary-regions or the waves of the regional_rollout, and regions separated by a comma concurrently.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if graphFormat != "dot" && graphFormat != "mermaid" {
+			logrus.Fatalf("invalid --format '%s', must be dot or mermaid", graphFormat)
+		}
+
+		graph, err := getProjectGraph(appFS)
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		primary := graphPrimary
+		if !cmd.Flags().Changed("primary-regions") && viper.IsSet("primary_region") {
+			primary = getConfigRegions("primary_region")
+		}
+
+		regional := graphRegional
+		if !cmd.Flags().Changed("regional-regions") && viper.IsSet("regional_regions") {
+			regional = getConfigRegions("regional_regions")
+		}
+
+		rings := graphRings
+		if len(rings) == 0 {
+			rings = []string{""}
+		}
+
+		regions, err := resolveRingRegions(rings, primary, regional, cmd.Flags().Changed("primary-regions"), cmd.Flags().Changed("regional-regions"))
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		for _, ring := range rings {
+			graph.Rings = append(graph.Rings, graphRing{Name: ring, Regions: regions[ring]})
+		}
+
+		graph.ParallelPrimary = graphParallel || (!cmd.Flags().Changed("parallel-primary-regions") && viper.GetBool("parallel_primary_regions"))
+
+		graph.RegionalRollout, err = getRegionalRollout(viper.GetViper())
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		var w io.Writer = os.Stdout
+
+		if graphOutput != "" {
+			f, err := appFS.Create(graphOutput)
+			if err != nil {
+				logrus.WithError(err).Fatal(err)
+			}
+			defer f.Close()
+
+			w = f
+		}
+
+		if graphFormat == "mermaid" {
+			err = writeMermaidGraph(w, graph)
+		} else {
+			err = writeDotGraph(w, graph)
+		}
+
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+	},
+}
+
+// graphStep is a step of the dependency graph
+type graphStep struct {
+	ID        string // The {trackName}/{stepName} id used by --steps
+	Name      string
+	Level     int               // The progression level, steps of a level depend on every step of the previous level
+	Regional  bool              // The step has a regional directory deployed to every regional region
+	DependsOn []string          // The names of the steps of the track from depends_on in the step's runiac.yml, nil depends on the previous level
+	Inputs    map[string]string // The input variables of the step by the name of the step whose output they read, from inputs in the step's runiac.yml
+	Tags      []string          // The tags of the step from tags in the step's runiac.yml, selected by --tags and --skip-tags
+}
+
+// graphTrack is a track of the dependency graph with its steps ordered by progression level
+type graphTrack struct {
+	Name  string
+	Steps []graphStep
+}
+
+// graphRing is a deployment ring the graph is annotated with
+type graphRing struct {
+	Name    string
+	Regions ringRegions
+}
+
+// projectGraph is the dependency graph of a project's tracks and steps
+type projectGraph struct {
+	Tracks          []graphTrack
+	Rings           []graphRing
+	ParallelPrimary bool          // The primary regions are deployed concurrently instead of one region at a time
+	RegionalRollout []rolloutWave // The waves the regional regions are deployed in, every regional region at once when empty
+}
+
+// edge is a dependency of a step on another step
+type edge struct {
+	From   string
+	To     string
+	Label  string // The input variables To reads from the outputs of From
+	Inputs bool   // From is not a direct dependency of To, To only reads its outputs
+}
+
+// getProjectGraph reads the tracks and steps of the project from the directory layout
+func getProjectGraph(fs afero.Fs) (projectGraph, error) {
+	graph := projectGraph{}
+
+	tracks := []string{defaultTrackName}
+
+	items, _ := afero.ReadDir(fs, tracksDir)
+	for _, item := range items {
+		if item.IsDir() {
+			tracks = append(tracks, item.Name())
+		}
+	}
+
+	for _, name := range tracks {
+		track, err := getGraphTrack(fs, name)
+		if err != nil {
+			return graph, err
+		}
+
+		if len(track.Steps) > 0 {
+			graph.Tracks = append(graph.Tracks, track)
+		}
+	}
+
+	if len(graph.Tracks) == 0 {
+		return graph, fmt.Errorf("no steps found, expected step{progressionLevel}_{stepName} directories at the top-level or in %s/{trackName}", tracksDir)
+	}
+
+	return graph, nil
+}
+
+// getGraphTrack reads the steps of the named track, ordered by progression level then name
+func getGraphTrack(fs afero.Fs, name string) (graphTrack, error) {
+	track := graphTrack{Name: name}
+	dir := getTrackDir(name)
+
+	items, err := afero.ReadDir(fs, dir)
+	if err != nil {
+		return track, err
+	}
+
+	for _, item := range items {
+		// step folder convention is step{progressionLevel}_{stepName}
+		if !item.IsDir() || !strings.HasPrefix(item.Name(), stepDirPrefix) || len(item.Name()) <= len(stepDirPrefix)+2 {
+			continue
+		}
+
+		level, err := strconv.Atoi(string(item.Name()[len(stepDirPrefix)]))
+		if err != nil {
+			logrus.Warnf("Skipping %s, step directories must be named step{progressionLevel}_{stepName}", filepath.Join(dir, item.Name()))
+			continue
+		}
+
+		stepName := item.Name()[len(stepDirPrefix)+2:]
+		regional, _ := afero.DirExists(fs, filepath.Join(dir, item.Name(), "regional"))
+
+		step := graphStep{
+			ID:       fmt.Sprintf("%s/%s", name, stepName),
+			Name:     stepName,
+			Level:    level,
+			Regional: regional,
+		}
+
+		if err = readGraphStepConfig(fs, name, filepath.Join(dir, item.Name()), &step); err != nil {
+			return track, err
+		}
+
+		track.Steps = append(track.Steps, step)
+	}
+
+	sort.Slice(track.Steps, func(i, j int) bool {
+		if track.Steps[i].Level != track.Steps[j].Level {
+			return track.Steps[i].Level < track.Steps[j].Level
+		}
+
+		return track.Steps[i].Name < track.Steps[j].Name
+	})
+
+	return track, nil
+}
+
+// getEdges returns the dependencies of the graph. Within a track, a step depends on the steps of its depends_on or
+// otherwise every step of the previous progression level. The first steps of every track depend on the last steps of
+// the _pretrack. The edges are labeled with the input variables a step reads from the outputs of another step.
+func (g projectGraph) getEdges() []edge {
+	edges := g.getDependencyEdges()
+
+	for _, t := range g.Tracks {
+		for _, s := range t.Steps {
+			for _, from := range getSortedStringKeys(s.Inputs) {
+				fromID := fmt.Sprintf("%s/%s", t.Name, from)
+				labeled := false
+
+				for i, e := range edges {
+					if e.From == fromID && e.To == s.ID {
+						edges[i].Label, labeled = s.Inputs[from], true
+					}
+				}
+
+				if !labeled {
+					edges = append(edges, edge{From: fromID, To: s.ID, Label: s.Inputs[from], Inputs: true})
+				}
+			}
+		}
+	}
+
+	return edges
+}
+
+// getDependencyEdges returns the execution order dependencies of the graph
+func (g projectGraph) getDependencyEdges() []edge {
+	edges := []edge{}
+	var preTrackLast []graphStep
+
+	for _, t := range g.Tracks {
+		if t.Name == tracks.PRE_TRACK_NAME {
+			preTrackLast = t.getLevel(t.Steps[len(t.Steps)-1].Level)
+		}
+	}
+
+	for _, t := range g.Tracks {
+		first := []graphStep{}
+
+		if t.Name != tracks.PRE_TRACK_NAME {
+			first = preTrackLast
+		}
+
+		previous := first
+
+		for i := 0; i < len(t.Steps); {
+			level := t.getLevel(t.Steps[i].Level)
+
+			for _, to := range level {
+				from := previous
+
+				if to.DependsOn != nil {
+					from = []graphStep{}
+
+					for _, d := range to.DependsOn {
+						from = append(from, graphStep{ID: fmt.Sprintf("%s/%s", t.Name, d)})
+					}
+
+					if len(from) == 0 {
+						from = first
+					}
+				}
+
+				for _, f := range from {
+					edges = append(edges, edge{From: f.ID, To: to.ID})
+				}
+			}
+
+			previous = level
+			i += len(level)
+		}
+	}
+
+	return edges
+}
+
+// readGraphStepConfig reads the depends_on list, nil when not set, the names of the inputs by the step whose output
+// they read and the tags of the optional runiac.yml or runiac.hcl in the step directory into the step
+func readGraphStepConfig(fs afero.Fs, track string, dir string, step *graphStep) error {
+	path := config.FindConfigFile(fs, dir)
+	if path == "" {
+		return nil
+	}
+
+	stepConfig := viper.New()
+
+	if err := config.ReadConfigFile(fs, stepConfig, path); err != nil {
+		return fmt.Errorf("unable to read %s: %w", path, err)
+	}
+
+	step.Inputs = getStepInputNames(stepConfig, track)
+
+	for _, tag := range stepConfig.GetStringSlice("tags") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			step.Tags = append(step.Tags, tag)
+		}
+	}
+
+	if !stepConfig.IsSet("depends_on") {
+		return nil
+	}
+
+	step.DependsOn = []string{}
+	for _, d := range stepConfig.GetStringSlice("depends_on") {
+		step.DependsOn = append(step.DependsOn, strings.TrimPrefix(strings.TrimSpace(d), track+"/"))
+	}
+
+	return nil
+}
+
+// getStepInputNames returns the comma separated names of the step's input variables by the step whose output they
+// read. inputs maps variable names to {stepName}.{output}, or lists {stepName}.{output} read as {stepName}_{output}.
+func getStepInputNames(stepConfig *viper.Viper, track string) map[string]string {
+	if !stepConfig.IsSet("inputs") {
+		return nil
+	}
+
+	references := map[string]string{}
+
+	switch inputs := stepConfig.Get("inputs").(type) {
+	case map[string]interface{}:
+		for name, reference := range inputs {
+			references[name] = fmt.Sprintf("%v", reference)
+		}
+	case []interface{}:
+		for _, reference := range inputs {
+			r := strings.TrimPrefix(strings.TrimSpace(fmt.Sprintf("%v", reference)), track+"/")
+			references[strings.Replace(r, ".", "_", 1)] = r
+		}
+	}
+
+	names := map[string][]string{}
+
+	for name, reference := range references {
+		step := strings.SplitN(strings.TrimPrefix(strings.TrimSpace(reference), track+"/"), ".", 2)[0]
+		names[step] = append(names[step], name)
+	}
+
+	inputs := map[string]string{}
+
+	for step, n := range names {
+		sort.Strings(n)
+		inputs[step] = strings.Join(n, ", ")
+	}
+
+	return inputs
+}
+
+// getLevel returns the steps of the track at the progression level
+func (t graphTrack) getLevel(level int) []graphStep {
+	steps := []graphStep{}
+
+	for _, s := range t.Steps {
+		if s.Level == level {
+			steps = append(steps, s)
+		}
+	}
+
+	return steps
+}
+
+// getConcurrentSteps returns the groups of steps of the track that run concurrently, in execution order. The steps of
+// a group do not depend on one another and start once the steps of the earlier groups they depend on completed.
+func (g projectGraph) getConcurrentSteps(t graphTrack) [][]graphStep {
+	depth := map[string]int{}
+	inTrack := map[string]bool{}
+
+	for _, s := range t.Steps {
+		inTrack[s.ID] = true
+	}
+
+	edges := []edge{}
+	for _, e := range g.getDependencyEdges() {
+		if inTrack[e.From] && inTrack[e.To] {
+			edges = append(edges, e)
+		}
+	}
+
+	// relax once per step so the longest dependency chain of every step is found, bounded should depends_on cycle
+	for range t.Steps {
+		for _, e := range edges {
+			if depth[e.From]+1 > depth[e.To] {
+				depth[e.To] = depth[e.From] + 1
+			}
+		}
+	}
+
+	groups := [][]graphStep{}
+
+	for _, s := range t.Steps {
+		for len(groups) <= depth[s.ID] {
+			groups = append(groups, []graphStep{})
+		}
+
+		groups[depth[s.ID]] = append(groups[depth[s.ID]], s)
+	}
+
+	concurrent := [][]graphStep{}
+	for _, group := range groups {
+		if len(group) > 0 {
+			concurrent = append(concurrent, group)
+		}
+	}
+
+	return concurrent
+}
+
+// getStepAnnotations returns the regions the step fans out to for each deployment ring
+func (g projectGraph) getStepAnnotations(s graphStep) []string {
+	annotations := []string{}
+
+	for _, r := range g.Rings {
+		separator := " -> "
+		if g.ParallelPrimary {
+			separator = ", "
+		}
+
+		regions := strings.Join(r.Regions.Primary, separator)
+
+		if s.Regional && len(r.Regions.Regional) > 0 {
+			regions = strings.TrimPrefix(fmt.Sprintf("%s + regional %s", regions, g.getRegionalFanOut(r.Regions.Regional)), " + ")
+		}
+
+		if regions == "" {
+			continue
+		}
+
+		if r.Name != "" {
+			regions = fmt.Sprintf("%s: %s", r.Name, regions)
+		}
+
+		annotations = append(annotations, regions)
+	}
+
+	return annotations
+}
+
+// getRegionalFanOut describes the waves of the regional_rollout the regional regions are deployed in, the regions of
+// no wave are deployed at once in a final wave
+func (g projectGraph) getRegionalFanOut(regional []string) string {
+	targeted := map[string]bool{}
+	for _, region := range regional {
+		targeted[region] = true
+	}
+
+	waves := []string{}
+	rolledOut := map[string]bool{}
+
+	for _, wave := range g.RegionalRollout {
+		regions := []string{}
+
+		for _, region := range wave.Regions {
+			if targeted[region] && !rolledOut[region] {
+				regions = append(regions, region)
+				rolledOut[region] = true
+			}
+		}
+
+		if len(regions) == 0 {
+			continue
+		}
+
+		description := strings.Join(regions, ", ")
+		if wave.Parallel > 0 && wave.Parallel < len(regions) {
+			description = fmt.Sprintf("%s (%d at a time)", description, wave.Parallel)
+		}
+
+		waves = append(waves, description)
+	}
+
+	remaining := []string{}
+	for _, region := range regional {
+		if !rolledOut[region] {
+			remaining = append(remaining, region)
+		}
+	}
+
+	if len(remaining) > 0 {
+		waves = append(waves, strings.Join(remaining, ", "))
+	}
+
+	return strings.Join(waves, " -> ")
+}
+
+// getTrackOrder describes the tracks that run concurrently, empty for a single track
+func (g projectGraph) getTrackOrder() string {
+	names := []string{}
+	preTrack := false
+
+	for _, t := range g.Tracks {
+		if t.Name == tracks.PRE_TRACK_NAME {
+			preTrack = true
+		} else {
+			names = append(names, t.Name)
+		}
+	}
+
+	if len(names) < 2 {
+		return ""
+	}
+
+	order := fmt.Sprintf("tracks %s run concurrently", strings.Join(names, ", "))
+	if preTrack {
+		order += " after the " + tracks.PRE_TRACK_NAME
+	}
+
+	return order
+}
+
+// getNotes returns the descriptions of the ring order and the concurrent tracks the graph is labeled with
+func (g projectGraph) getNotes() []string {
+	notes := []string{}
+
+	for _, note := range []string{g.getRingOrder(), g.getTrackOrder()} {
+		if note != "" {
+			notes = append(notes, note)
+		}
+	}
+
+	return notes
+}
+
+// getRingOrder describes the order deployment rings are deployed in, empty for a single ring
+func (g projectGraph) getRingOrder() string {
+	if len(g.Rings) < 2 {
+		return ""
+	}
+
+	names := []string{}
+	for _, r := range g.Rings {
+		names = append(names, r.Name)
+	}
+
+	return fmt.Sprintf("deployment rings: %s", strings.Join(names, " -> "))
+}
+
+// writeDotGraph writes the graph in graphviz dot format, a cluster per track
+func writeDotGraph(w io.Writer, g projectGraph) error {
+	var b strings.Builder
+
+	b.WriteString("digraph runiac {\n  rankdir=LR;\n  node [shape=box];\n")
+
+	if notes := g.getNotes(); len(notes) > 0 {
+		fmt.Fprintf(&b, "  label=%s;\n  labelloc=t;\n", strconv.Quote(strings.Join(notes, "\n")))
+	}
+
+	for _, t := range g.Tracks {
+		fmt.Fprintf(&b, "\n  subgraph %s {\n    label=%s;\n", strconv.Quote("cluster_"+t.Name), strconv.Quote(t.Name))
+
+		for _, s := range t.Steps {
+			label := strings.Join(append([]string{s.Name}, g.getStepAnnotations(s)...), "\n")
+			fmt.Fprintf(&b, "    %s [label=%s];\n", strconv.Quote(s.ID), strconv.Quote(label))
+		}
+
+		// concurrent steps are ranked side by side
+		for _, group := range g.getConcurrentSteps(t) {
+			if len(group) < 2 {
+				continue
+			}
+
+			ids := []string{}
+			for _, s := range group {
+				ids = append(ids, strconv.Quote(s.ID))
+			}
+
+			fmt.Fprintf(&b, "    {rank=same; %s;}\n", strings.Join(ids, "; "))
+		}
+
+		b.WriteString("  }\n")
+	}
+
+	edges := g.getEdges()
+	if len(edges) > 0 {
+		b.WriteString("\n")
+	}
+
+	for _, e := range edges {
+		var attributes []string
+
+		if e.Label != "" {
+			attributes = append(attributes, fmt.Sprintf("label=%s", strconv.Quote(e.Label)))
+		}
+
+		if e.Inputs {
+			attributes = append(attributes, "style=dashed")
+		}
+
+		if len(attributes) > 0 {
+			fmt.Fprintf(&b, "  %s -> %s [%s];\n", strconv.Quote(e.From), strconv.Quote(e.To), strings.Join(attributes, ", "))
+		} else {
+			fmt.Fprintf(&b, "  %s -> %s;\n", strconv.Quote(e.From), strconv.Quote(e.To))
+		}
+	}
+
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
+
+// writeMermaidGraph writes the graph as a mermaid flowchart, a subgraph per track
+func writeMermaidGraph(w io.Writer, g projectGraph) error {
+	var b strings.Builder
+
+	for _, note := range g.getNotes() {
+		fmt.Fprintf(&b, "%%%% %s\n", note)
+	}
+
+	b.WriteString("flowchart LR\n")
+
+	for _, t := range g.Tracks {
+		fmt.Fprintf(&b, "  subgraph %s [%s]\n", getMermaidID("track_"+t.Name), getMermaidText(t.Name))
+
+		// concurrent steps are grouped in a subgraph of the track
+		for i, group := range g.getConcurrentSteps(t) {
+			indent := "    "
+
+			if len(group) > 1 {
+				fmt.Fprintf(&b, "    subgraph %s [%s]\n", getMermaidID(fmt.Sprintf("concurrent_%s_%d", t.Name, i+1)), getMermaidText("concurrent"))
+				indent = "      "
+			}
+
+			for _, s := range group {
+				label := strings.Join(append([]string{s.Name}, g.getStepAnnotations(s)...), "<br/>")
+				fmt.Fprintf(&b, "%s%s[%s]\n", indent, getMermaidID(s.ID), getMermaidText(label))
+			}
+
+			if len(group) > 1 {
+				b.WriteString("    end\n")
+			}
+		}
+
+		b.WriteString("  end\n")
+	}
+
+	for _, e := range g.getEdges() {
+		arrow := "-->"
+		if e.Inputs {
+			arrow = "-.->"
+		}
+
+		if e.Label != "" {
+			arrow = fmt.Sprintf("%s|%s|", arrow, getMermaidText(e.Label))
+		}
+
+		fmt.Fprintf(&b, "  %s %s %s\n", getMermaidID(e.From), arrow, getMermaidID(e.To))
+	}
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
+
+// getMermaidID returns a mermaid node id for the value
+func getMermaidID(value string) string {
+	return mermaidIDPattern.ReplaceAllString(value, "_")
+}
+
+// getMermaidText returns the value quoted as mermaid text
+func getMermaidText(value string) string {
+	return fmt.Sprintf("\"%s\"", strings.ReplaceAll(value, "\"", "#quot;"))
+}
@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func getTestProjectGraph(t *testing.T) projectGraph {
+	fs := afero.NewMemMapFs()
+	_ = fs.MkdirAll("tracks/_pretrack/step1_dns", 0755)
+	_ = fs.MkdirAll("tracks/network/step1_vnet/regional", 0755)
+	_ = fs.MkdirAll("tracks/network/step1_firewall", 0755)
+	_ = fs.MkdirAll("tracks/network/step3_peering", 0755)
+	_ = fs.MkdirAll("tracks/network/modules", 0755)
+
+	graph, err := getProjectGraph(fs)
+	require.NoError(t, err)
+
+	return graph
+}
+
+func TestGetProjectGraph_ShouldOrderStepsByProgressionLevel(t *testing.T) {
+	graph := getTestProjectGraph(t)
+
+	require.Len(t, graph.Tracks, 2)
+	require.Equal(t, "network", graph.Tracks[1].Name)
+	require.Equal(t, []graphStep{
+		{ID: "network/firewall", Name: "firewall", Level: 1},
+		{ID: "network/vnet", Name: "vnet", Level: 1, Regional: true},
+		{ID: "network/peering", Name: "peering", Level: 3},
+	}, graph.Tracks[1].Steps)
+
+	require.Equal(t, []edge{
+		{From: "_pretrack/dns", To: "network/firewall"},
+		{From: "_pretrack/dns", To: "network/vnet"},
+		{From: "network/firewall", To: "network/peering"},
+		{From: "network/vnet", To: "network/peering"},
+	}, graph.getEdges())
+
+	_, err := getProjectGraph(afero.NewMemMapFs())
+	require.Error(t, err, "a project without steps should fail")
+}
+
+func TestGetProjectGraph_ShouldUseStepDependsOn(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	_ = fs.MkdirAll("tracks/app/step1_network", 0755)
+	_ = fs.MkdirAll("tracks/app/step1_dns", 0755)
+	_ = fs.MkdirAll("tracks/app/step2_cluster", 0755)
+	_ = fs.MkdirAll("tracks/app/step2_monitoring", 0755)
+	_ = afero.WriteFile(fs, "tracks/app/step2_cluster/runiac.yml", []byte("depends_on:\n  - app/network\n"), 0644)
+
+	graph, err := getProjectGraph(fs)
+	require.NoError(t, err)
+
+	require.Equal(t, []edge{
+		{From: "app/network", To: "app/cluster"},
+		{From: "app/dns", To: "app/monitoring"},
+		{From: "app/network", To: "app/monitoring"},
+	}, graph.getEdges())
+}
+
+func TestGetProjectGraph_ShouldLabelStepInputs(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	_ = fs.MkdirAll("tracks/app/step1_network", 0755)
+	_ = fs.MkdirAll("tracks/app/step2_cluster", 0755)
+	_ = fs.MkdirAll("tracks/app/step3_dns", 0755)
+	_ = afero.WriteFile(fs, "tracks/app/step2_cluster/runiac.yml", []byte("inputs: [network.subnet_id, network.vnet_id]\n"), 0644)
+	_ = afero.WriteFile(fs, "tracks/app/step3_dns/runiac.yml", []byte("inputs:\n  vnet: app/network.vnet_id\n"), 0644)
+
+	graph, err := getProjectGraph(fs)
+	require.NoError(t, err)
+
+	require.Equal(t, []edge{
+		{From: "app/network", To: "app/cluster", Label: "network_subnet_id, network_vnet_id"},
+		{From: "app/cluster", To: "app/dns"},
+		{From: "app/network", To: "app/dns", Label: "vnet", Inputs: true},
+	}, graph.getEdges())
+
+	var b bytes.Buffer
+	require.NoError(t, writeDotGraph(&b, graph))
+	require.Contains(t, b.String(), `"app/network" -> "app/dns" [label="vnet", style=dashed];`)
+
+	b.Reset()
+	require.NoError(t, writeMermaidGraph(&b, graph))
+	require.Contains(t, b.String(), "  app_network -.->|\"vnet\"| app_dns\n")
+}
+
+func TestWriteDotGraph_ShouldAnnotateRegionFanOut(t *testing.T) {
+	graph := getTestProjectGraph(t)
+	graph.Rings = []graphRing{
+		{Name: "dev", Regions: ringRegions{Primary: []string{"us-east-1"}}},
+		{Name: "prod", Regions: ringRegions{Primary: []string{"us-east-1"}, Regional: []string{"us-east-1", "us-west-2"}}},
+	}
+
+	var b bytes.Buffer
+	require.NoError(t, writeDotGraph(&b, graph))
+
+	out := b.String()
+	require.Contains(t, out, `label="deployment rings: dev -> prod";`)
+	require.Contains(t, out, `subgraph "cluster_network" {`)
+	require.Contains(t, out, `"network/vnet" [label="vnet\ndev: us-east-1\nprod: us-east-1 + regional us-east-1, us-west-2"];`)
+	require.Contains(t, out, `"network/firewall" [label="firewall\ndev: us-east-1\nprod: us-east-1"];`)
+	require.Contains(t, out, `"network/vnet" -> "network/peering";`)
+}
+
+func TestWriteMermaidGraph_ShouldUseSafeNodeIds(t *testing.T) {
+	graph := getTestProjectGraph(t)
+	graph.Rings = []graphRing{{Regions: ringRegions{Primary: []string{"centralus"}, Regional: []string{"eastus"}}}}
+
+	var b bytes.Buffer
+	require.NoError(t, writeMermaidGraph(&b, graph))
+
+	out := b.String()
+	require.Contains(t, out, "flowchart LR\n")
+	require.Contains(t, out, `  subgraph track_network ["network"]`)
+	require.Contains(t, out, `    network_vnet["vnet<br/>centralus + regional eastus"]`)
+	require.Contains(t, out, "  _pretrack_dns --> network_firewall\n")
+	require.NotContains(t, out, "%%", "a single ring should not describe the ring order")
+}
+
+func TestGetConcurrentSteps_ShouldGroupStepsWithoutDependencies(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	_ = fs.MkdirAll("tracks/app/step1_network", 0755)
+	_ = fs.MkdirAll("tracks/app/step1_dns", 0755)
+	_ = fs.MkdirAll("tracks/app/step2_cluster", 0755)
+	_ = fs.MkdirAll("tracks/app/step3_monitoring", 0755)
+	_ = fs.MkdirAll("tracks/data/step1_db", 0755)
+	_ = afero.WriteFile(fs, "tracks/app/step3_monitoring/runiac.yml", []byte("depends_on:\n  - app/dns\n"), 0644)
+
+	graph, err := getProjectGraph(fs)
+	require.NoError(t, err)
+
+	names := [][]string{}
+	for _, group := range graph.getConcurrentSteps(graph.Tracks[0]) {
+		n := []string{}
+		for _, s := range group {
+			n = append(n, s.Name)
+		}
+		names = append(names, n)
+	}
+
+	require.Equal(t, [][]string{{"dns", "network"}, {"cluster", "monitoring"}}, names, "monitoring only waits for dns")
+
+	var b bytes.Buffer
+	require.NoError(t, writeDotGraph(&b, graph))
+	require.Contains(t, b.String(), `label="tracks app, data run concurrently";`)
+	require.Contains(t, b.String(), `    {rank=same; "app/cluster"; "app/monitoring";}`)
+	require.NotContains(t, b.String(), `{rank=same; "data/db";}`, "a single step is not concurrent")
+
+	b.Reset()
+	require.NoError(t, writeMermaidGraph(&b, graph))
+	require.Contains(t, b.String(), "%% tracks app, data run concurrently\n")
+	require.Contains(t, b.String(), "    subgraph concurrent_app_1 [\"concurrent\"]\n      app_dns[\"dns\"]\n      app_network[\"network\"]\n    end\n")
+}
+
+func TestGetStepAnnotations_ShouldDescribeRegionOrder(t *testing.T) {
+	graph := getTestProjectGraph(t)
+	graph.Rings = []graphRing{{Regions: ringRegions{Primary: []string{"us-east-1", "us-west-2"}, Regional: []string{"eu-west-1", "us-east-2", "us-west-1", "us-west-2"}}}}
+	vnet := graph.Tracks[1].Steps[1]
+
+	require.Equal(t, []string{"us-east-1 -> us-west-2 + regional eu-west-1, us-east-2, us-west-1, us-west-2"}, graph.getStepAnnotations(vnet))
+
+	graph.ParallelPrimary = true
+	graph.RegionalRollout = []rolloutWave{{Regions: []string{"us-east-2"}}, {Regions: []string{"us-west-1", "us-west-2", "ap-south-1"}, Parallel: 1}}
+
+	require.Equal(t, []string{"us-east-1, us-west-2 + regional us-east-2 -> us-west-1, us-west-2 (1 at a time) -> eu-west-1"}, graph.getStepAnnotations(vnet))
+}
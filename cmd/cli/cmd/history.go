@@ -0,0 +1,444 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+const historyFile = ".runiac/history.log" // Append-only JSON lines audit trail of every deploy run from this directory
+
+const (
+	historyResultSucceeded = "succeeded" // The container exited successfully
+	historyResultFailed    = "failed"    // The container failed
+	historyResultStarted   = "started"   // The container was started with --detach, its outcome is unknown
+	historyResultSkipped   = "skipped"   // The ring was not run after --abort-after-failures was reached
+)
+
+// historyEntry is a single line of the history log describing the run of a deployment ring
+type historyEntry struct {
+	Timestamp       time.Time         `json:"timestamp"`
+	Project         string            `json:"project"`
+	Environment     string            `json:"environment"`
+	DeploymentRing  string            `json:"deployment_ring"`
+	Namespace       string            `json:"namespace"`
+	Account         string            `json:"account"`
+	PrimaryRegions  []string          `json:"primary_regions"`
+	RegionalRegions []string          `json:"regional_regions"`
+	Version         string            `json:"version"`
+	GitSHA          string            `json:"git_sha,omitempty"` // The commit of the working directory the run deployed
+	DryRun          bool              `json:"dry_run"`
+	SelfDestroy     bool              `json:"self_destroy"`
+	Destroy         bool              `json:"destroy,omitempty"` // The run destroyed the ring's resources without deploying
+	Result          string            `json:"result"`
+	DurationSeconds float64           `json:"duration_seconds,omitempty"` // How long the ring's container ran, unknown for detached runs
+	Reason          string            `json:"reason"`
+	CorrelationID   string            `json:"correlation_id"`
+	BreakGlass      string            `json:"break_glass,omitempty"`   // The reason given for deploying during a freeze window
+	RegionInputs    map[string]string `json:"region_inputs,omitempty"` // Fingerprint of each requested regional region's inputs, recorded with --only-changed-regions
+}
+
+// historyFilter selects history entries, empty fields match every entry
+type historyFilter struct {
+	Environment    string
+	DeploymentRing string
+	Namespace      string
+	Version        string
+	Result         string
+	CorrelationID  string
+	Since          time.Time
+	Until          time.Time
+}
+
+var (
+	historyFilters historyFilter
+	historySince   time.Duration
+	historyOn      string
+	historyLimit   int
+	historyJSON    bool
+)
+
+// getGitSHA returns the commit of the working directory, empty outside of a git repository. A variable so tests do
+// not need git.
+var getGitSHA = func() string {
+	out, err := exec.Command("git", "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(out))
+}
+
+func init() {
+	historyCmd.Flags().StringVarP(&historyFilters.Environment, "environment", "e", "", "Only show runs targeting this environment")
+	historyCmd.Flags().StringVarP(&historyFilters.DeploymentRing, "deployment-ring", "d", "", "Only show runs of this deployment ring")
+	historyCmd.Flags().StringVarP(&historyFilters.Namespace, "namespace", "n", "", "Only show runs of this namespace")
+	historyCmd.Flags().StringVarP(&historyFilters.Version, "version", "v", "", "Only show runs of this version")
+	historyCmd.Flags().StringVar(&historyFilters.Result, "result", "", "Only show runs with this result (succeeded, failed, started or skipped)")
+	historyCmd.Flags().StringVar(&historyFilters.CorrelationID, "correlation-id", "", "Only show the run with this correlation id")
+	historyCmd.Flags().DurationVar(&historySince, "since", 0, "Only show runs within this duration, e.g. 24h")
+	historyCmd.Flags().StringVar(&historyOn, "on", "", "Only show runs on this local date, e.g. 2026-10-06")
+	historyCmd.Flags().IntVar(&historyLimit, "limit", 0, "Only show the most recent N matching runs")
+	historyCmd.Flags().BoolVar(&historyJSON, "json", false, "Print the matching runs as json lines")
+
+	historyListCmd.Flags().AddFlagSet(historyCmd.Flags())
+	historyShowCmd.Flags().BoolVar(&historyJSON, "json", false, "Print the run as json")
+
+	historyCmd.AddCommand(historyListCmd)
+	historyCmd.AddCommand(historyShowCmd)
+	rootCmd.AddCommand(historyCmd)
+}
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show the deploy history",
+	Long: fmt.Sprintf(`Shows the runs recorded in %s by runiac deploy, oldest first. With list, the runs
+are filtered, e.g. what was deployed to prod on a date with --environment prod --on 2026-10-06. With show,
+the details of a single run are shown.`, historyFile),
+	Run: func(cmd *cobra.Command, args []string) {
+		listHistory()
+	},
+}
+
+var historyListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the runs of the deploy history",
+	Run: func(cmd *cobra.Command, args []string) {
+		listHistory()
+	},
+}
+
+var historyShowCmd = &cobra.Command{
+	Use:   "show <correlation id>",
+	Short: "Show a run of the deploy history",
+	Long:  `Shows the details of the run with the correlation id, matching the full or a shortened correlation id.`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		entries, err := readHistory(appFS)
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		entry, err := findHistoryEntry(entries, args[0])
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		if historyJSON {
+			err = printHistoryJSON(os.Stdout, []historyEntry{entry})
+		} else {
+			err = printHistoryEntry(appFS, os.Stdout, entry)
+		}
+
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+	},
+}
+
+// getHistoryDay returns the start and end of the local date, e.g. 2026-10-06
+func getHistoryDay(date string) (time.Time, time.Time, error) {
+	start, err := time.ParseInLocation("2006-01-02", date, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid --on '%s', expected a date such as 2026-10-06", date)
+	}
+
+	return start, start.AddDate(0, 0, 1), nil
+}
+
+// listHistory prints the runs of the history log matching the flags
+func listHistory() {
+	entries, err := readHistory(appFS)
+	if err != nil {
+		logrus.WithError(err).Fatal(err)
+	}
+
+	filter := historyFilters
+	if historySince > 0 {
+		filter.Since = time.Now().Add(-historySince)
+	}
+
+	if historyOn != "" {
+		from, until, err := getHistoryDay(historyOn)
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		if from.After(filter.Since) {
+			filter.Since = from
+		}
+
+		filter.Until = until
+	}
+
+	entries = filterHistory(entries, filter, historyLimit)
+
+	if historyJSON {
+		err = printHistoryJSON(os.Stdout, entries)
+	} else {
+		err = printHistory(os.Stdout, entries)
+	}
+
+	if err != nil {
+		logrus.WithError(err).Fatal(err)
+	}
+}
+
+// getHistoryResult returns the history result of running a ring's container
+func getHistoryResult(err error, detached bool) string {
+	if err != nil {
+		return historyResultFailed
+	}
+
+	if detached {
+		return historyResultStarted
+	}
+
+	return historyResultSucceeded
+}
+
+// appendHistory appends the entry to the history log, creating it when missing
+func appendHistory(fs afero.Fs, entry historyEntry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	err = fs.MkdirAll(filepath.Dir(historyFile), 0755)
+	if err != nil {
+		return err
+	}
+
+	f, err := fs.OpenFile(historyFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(b, '\n'))
+
+	return err
+}
+
+// recordHistory appends the entry to the history log, a run is never failed because its history could not be recorded
+func recordHistory(entry historyEntry) {
+	entry.Timestamp = time.Now().UTC()
+
+	if err := appendHistory(appFS, entry); err != nil {
+		logrus.WithError(err).Warnf("Unable to record the run of deployment ring '%s' in %s", entry.DeploymentRing, historyFile)
+	}
+}
+
+// readHistory returns every entry of the history log, none when the log does not exist yet
+func readHistory(fs afero.Fs) ([]historyEntry, error) {
+	entries := []historyEntry{}
+
+	f, err := fs.Open(historyFile)
+	if os.IsNotExist(err) {
+		return entries, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	line := 0
+
+	for scanner.Scan() {
+		line++
+
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		entry := historyEntry{}
+
+		err = json.Unmarshal(scanner.Bytes(), &entry)
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d is not a valid history entry: %w", historyFile, line, err)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, scanner.Err()
+}
+
+// matches returns true when the entry satisfies every set field of the filter
+func (f historyFilter) matches(entry historyEntry) bool {
+	fields := []struct {
+		filter string
+		value  string
+	}{
+		{f.Environment, entry.Environment},
+		{f.DeploymentRing, entry.DeploymentRing},
+		{f.Namespace, entry.Namespace},
+		{f.Version, entry.Version},
+		{f.Result, entry.Result},
+		{f.CorrelationID, entry.CorrelationID},
+	}
+
+	for _, field := range fields {
+		if field.filter != "" && !strings.EqualFold(field.filter, field.value) {
+			return false
+		}
+	}
+
+	if !f.Until.IsZero() && !entry.Timestamp.Before(f.Until) {
+		return false
+	}
+
+	return f.Since.IsZero() || !entry.Timestamp.Before(f.Since)
+}
+
+// findHistoryEntry returns the most recent run with the correlation id, matching the full or a shortened
+// correlation id
+func findHistoryEntry(entries []historyEntry, correlationID string) (historyEntry, error) {
+	matched := map[string]bool{}
+	entry := historyEntry{}
+
+	for _, e := range entries {
+		if e.CorrelationID != "" && strings.HasPrefix(e.CorrelationID, correlationID) {
+			matched[e.CorrelationID] = true
+			entry = e
+		}
+	}
+
+	switch len(matched) {
+	case 0:
+		return entry, fmt.Errorf("no run with correlation id '%s' in %s", correlationID, historyFile)
+	case 1:
+		return entry, nil
+	default:
+		return historyEntry{}, fmt.Errorf("correlation id '%s' is ambiguous, it matches %d runs in %s", correlationID, len(matched), historyFile)
+	}
+}
+
+// getHistoryAction describes what the run did
+func (e historyEntry) getHistoryAction() string {
+	switch {
+	case e.Destroy:
+		return "destroy"
+	case e.SelfDestroy:
+		return "deploy and destroy"
+	case e.DryRun:
+		return "dry run"
+	default:
+		return "deploy"
+	}
+}
+
+// getHistoryDuration formats how long the run took, empty when unknown
+func (e historyEntry) getHistoryDuration() string {
+	if e.DurationSeconds <= 0 {
+		return ""
+	}
+
+	return (time.Duration(e.DurationSeconds * float64(time.Second))).Round(time.Second).String()
+}
+
+// filterHistory returns the entries matching the filter, limited to the most recent limit entries when limit is set
+func filterHistory(entries []historyEntry, filter historyFilter, limit int) []historyEntry {
+	matched := []historyEntry{}
+
+	for _, entry := range entries {
+		if filter.matches(entry) {
+			matched = append(matched, entry)
+		}
+	}
+
+	if limit > 0 && len(matched) > limit {
+		matched = matched[len(matched)-limit:]
+	}
+
+	return matched
+}
+
+// printHistory writes the entries as a table
+func printHistory(w io.Writer, entries []historyEntry) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "TIMESTAMP\tENVIRONMENT\tRING\tNAMESPACE\tREGIONS\tVERSION\tCOMMIT\tRESULT\tDURATION\tREASON\tCORRELATION ID")
+
+	for _, e := range entries {
+		regions := strings.Join(append(append([]string{}, e.PrimaryRegions...), e.RegionalRegions...), ",")
+		result := e.Result
+
+		if e.SelfDestroy || e.Destroy {
+			result += " (destroy)"
+		} else if e.DryRun {
+			result += " (dry run)"
+		}
+
+		commit := e.GitSHA
+		if len(commit) > 7 {
+			commit = commit[:7]
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", e.Timestamp.Format(time.RFC3339), e.Environment, e.DeploymentRing, e.Namespace, regions, e.Version, commit, result, e.getHistoryDuration(), e.Reason, e.CorrelationID)
+	}
+
+	return tw.Flush()
+}
+
+// printHistoryEntry writes the details of the run, including where the output of its steps was persisted
+func printHistoryEntry(fs afero.Fs, w io.Writer, e historyEntry) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fields := [][]string{
+		{"Timestamp", e.Timestamp.Format(time.RFC3339)},
+		{"Project", e.Project},
+		{"Environment", e.Environment},
+		{"Deployment ring", e.DeploymentRing},
+		{"Namespace", e.Namespace},
+		{"Account", e.Account},
+		{"Primary regions", strings.Join(e.PrimaryRegions, ", ")},
+		{"Regional regions", strings.Join(e.RegionalRegions, ", ")},
+		{"Version", e.Version},
+		{"Git SHA", e.GitSHA},
+		{"Action", e.getHistoryAction()},
+		{"Result", e.Result},
+		{"Duration", e.getHistoryDuration()},
+		{"Reason", e.Reason},
+		{"Break glass", e.BreakGlass},
+		{"Correlation ID", e.CorrelationID},
+	}
+
+	if exists, _ := afero.DirExists(fs, getRunLogDir(e.CorrelationID)); exists && e.CorrelationID != "" {
+		fields = append(fields, []string{"Step logs", fmt.Sprintf("%s, view them with 'runiac logs %s'", getRunLogDir(e.CorrelationID), e.CorrelationID)})
+	}
+
+	for _, f := range fields {
+		if f[1] != "" {
+			fmt.Fprintf(tw, "%s:\t%s\n", f[0], f[1])
+		}
+	}
+
+	return tw.Flush()
+}
+
+// printHistoryJSON writes the entries as json lines
+func printHistoryJSON(w io.Writer, entries []historyEntry) error {
+	enc := json.NewEncoder(w)
+
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
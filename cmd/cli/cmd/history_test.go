@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistory_ShouldAppendAndFilterRuns(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	entries, err := readHistory(fs)
+	require.NoError(t, err)
+	require.Empty(t, entries, "a missing history log should have no entries")
+
+	now := time.Now().UTC()
+	runs := []historyEntry{
+		{Timestamp: now.Add(-48 * time.Hour), Environment: "nonprod", DeploymentRing: "dev", Version: "v1", Result: historyResultSucceeded, CorrelationID: "a"},
+		{Timestamp: now.Add(-time.Hour), Environment: "prod", DeploymentRing: "prod", Version: "v1", Result: historyResultFailed, Reason: "CHG123", CorrelationID: "b"},
+		{Timestamp: now, Environment: "prod", DeploymentRing: "prod", Version: "v2", Result: historyResultSucceeded, Reason: "CHG124", CorrelationID: "c"},
+	}
+
+	for _, run := range runs {
+		require.NoError(t, appendHistory(fs, run))
+	}
+
+	entries, err = readHistory(fs)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	require.Equal(t, "CHG123", entries[1].Reason)
+
+	tests := []struct {
+		filter   historyFilter
+		limit    int
+		expected []string
+	}{
+		{historyFilter{}, 0, []string{"a", "b", "c"}},
+		{historyFilter{DeploymentRing: "PROD"}, 0, []string{"b", "c"}},
+		{historyFilter{Environment: "prod", Result: historyResultSucceeded}, 0, []string{"c"}},
+		{historyFilter{CorrelationID: "a"}, 0, []string{"a"}},
+		{historyFilter{Since: now.Add(-24 * time.Hour)}, 0, []string{"b", "c"}},
+		{historyFilter{}, 2, []string{"b", "c"}},
+	}
+
+	for _, tt := range tests {
+		ids := []string{}
+
+		for _, e := range filterHistory(entries, tt.filter, tt.limit) {
+			ids = append(ids, e.CorrelationID)
+		}
+
+		require.Equal(t, tt.expected, ids, "filterHistory(%+v, %d)", tt.filter, tt.limit)
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, printHistory(&buf, entries))
+	require.Contains(t, buf.String(), "CHG124")
+}
+
+func TestReadHistory_ShouldFailOnInvalidEntries(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	_ = afero.WriteFile(fs, historyFile, []byte("{\"result\":\"succeeded\"}\n\nnot json\n"), 0644)
+
+	_, err := readHistory(fs)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "line 3")
+}
+
+func TestHistory_ShouldFilterRunsOnDate(t *testing.T) {
+	from, until, err := getHistoryDay("2026-10-06")
+	require.NoError(t, err)
+	require.Equal(t, 24*time.Hour, until.Sub(from))
+
+	entries := []historyEntry{
+		{Timestamp: from.Add(-time.Minute), Environment: "prod", CorrelationID: "a"},
+		{Timestamp: from.Add(10 * time.Hour), Environment: "prod", CorrelationID: "b"},
+		{Timestamp: until, Environment: "prod", CorrelationID: "c"},
+	}
+
+	matched := filterHistory(entries, historyFilter{Environment: "prod", Since: from, Until: until}, 0)
+	require.Len(t, matched, 1)
+	require.Equal(t, "b", matched[0].CorrelationID)
+
+	_, _, err = getHistoryDay("last tuesday")
+	require.Error(t, err)
+}
+
+func TestHistory_ShouldShowRunByCorrelationID(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	entries := []historyEntry{
+		{Environment: "prod", DeploymentRing: "prod", Version: "v1", GitSHA: "4f2c9e1d0b", Result: historyResultSucceeded, DurationSeconds: 95.4, CorrelationID: "abc123"},
+		{Environment: "prod", DeploymentRing: "prod", Version: "v2", DryRun: true, Result: historyResultFailed, CorrelationID: "abd456"},
+	}
+
+	entry, err := findHistoryEntry(entries, "abc")
+	require.NoError(t, err)
+	require.Equal(t, "v1", entry.Version)
+
+	_, err = findHistoryEntry(entries, "ab")
+	require.Error(t, err, "a shortened correlation id matching several runs should be ambiguous")
+
+	_, err = findHistoryEntry(entries, "xyz")
+	require.Error(t, err)
+
+	require.NoError(t, fs.MkdirAll(getRunLogDir("abc123"), 0755))
+
+	var buf bytes.Buffer
+	require.NoError(t, printHistoryEntry(fs, &buf, entry))
+	require.Contains(t, buf.String(), "Git SHA:")
+	require.Contains(t, buf.String(), "4f2c9e1d0b")
+	require.Contains(t, buf.String(), "1m35s")
+	require.Contains(t, buf.String(), "runiac logs abc123")
+	require.NotContains(t, buf.String(), "Break glass", "fields that are not set should not be shown")
+
+	buf.Reset()
+	require.NoError(t, printHistory(&buf, entries))
+	require.Contains(t, buf.String(), "4f2c9e1")
+	require.Contains(t, buf.String(), "failed (dry run)")
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// windowsDrivePath matches the drive letter of an absolute windows path, e.g. C:\Users or C:/Users
+var windowsDrivePath = regexp.MustCompile(`^([A-Za-z]):[\\/]`)
+
+// getVolumeArguments returns the container run arguments mounting the host path at the container path, read only
+// when set
+func getVolumeArguments(hostPath string, containerPath string, readOnly bool) []string {
+	volume := fmt.Sprintf("%s:%s", getHostMountPath(hostPath), containerPath)
+
+	if readOnly {
+		volume += ":ro"
+	}
+
+	return []string{"-v", volume}
+}
+
+// getHostMountPath returns the host path as the container engine expects the source of a volume on this host
+func getHostMountPath(path string) string {
+	if runtime.GOOS == "windows" {
+		return toWindowsMountPath(path)
+	}
+
+	return path
+}
+
+// toWindowsMountPath returns the windows path with forward slashes and an upper case drive letter, e.g.
+// C:/Users/stub/project, the form docker desktop and podman accept as the source of a volume
+func toWindowsMountPath(path string) string {
+	path = strings.ReplaceAll(path, `\`, "/")
+
+	if m := windowsDrivePath.FindStringSubmatch(path); m != nil {
+		path = strings.ToUpper(m[1]) + path[1:]
+	}
+
+	return path
+}
+
+// getAccountName returns the user name of a windows account without its domain, DOMAIN\user and user@domain are
+// both user
+func getAccountName(account string) string {
+	account = strings.TrimSpace(account)
+
+	if i := strings.LastIndex(account, `\`); i >= 0 {
+		account = account[i+1:]
+	}
+
+	if i := strings.Index(account, "@"); i > 0 {
+		account = account[:i]
+	}
+
+	return account
+}
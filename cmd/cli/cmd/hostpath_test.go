@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestToWindowsMountPath_ShouldUseForwardSlashesAndUpperCaseDrive(t *testing.T) {
+	tests := map[string]string{
+		`C:\Users\stub\project\.runiac\.aws`: "C:/Users/stub/project/.runiac/.aws",
+		`c:\Users\stub\project`:              "C:/Users/stub/project",
+		"d:/stub/reports":                    "D:/stub/reports",
+		"/var/run/docker.sock":               "/var/run/docker.sock",
+	}
+
+	for in, expected := range tests {
+		require.Equal(t, expected, toWindowsMountPath(in), in)
+	}
+}
+
+func TestGetVolumeArguments_ShouldMountReadOnly(t *testing.T) {
+	require.Equal(t, []string{"-v", "/stub/plans:/runiac/artifacts:ro"}, getVolumeArguments("/stub/plans", "/runiac/artifacts", true))
+	require.Equal(t, []string{"-v", "/stub/out:/runiac/output"}, getVolumeArguments("/stub/out", "/runiac/output", false))
+}
+
+func TestGetAccountName_ShouldStripWindowsDomain(t *testing.T) {
+	tests := map[string]string{
+		"CORP\\jdoe\r\n":   "jdoe",
+		"jdoe@corp.local":  "jdoe",
+		"jdoe":             "jdoe",
+		"desktop-1\\j doe": "j doe",
+	}
+
+	for in, expected := range tests {
+		require.Equal(t, expected, getAccountName(in), in)
+	}
+
+	require.Equal(t, "j_doe", sanitizeMachineName(getAccountName("desktop-1\\j doe")))
+}
@@ -0,0 +1,230 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var costFormat string
+
+func init() {
+	costCmd.Flags().AddFlagSet(deployCmd.Flags())
+	costCmd.Flags().StringVar(&costFormat, "output", "text", "The cost estimate format, text or json. With json the container output is written to stderr")
+
+	rootCmd.AddCommand(costCmd)
+}
+
+var costCmd = &cobra.Command{
+	Use:   "cost",
+	Short: "Estimate the monthly cost of configurations",
+	Long: fmt.Sprintf(`Plans every step like plan and estimates the monthly cost of each step's plan with infracost inside the
+container, accepting the flags of deploy. The estimated monthly cost and its change are summarized per step
+and in total, as json for pull request comments with --output json. Requires infracost in the container and
+its INFRACOST_API_KEY, e.g. forwarded with --env-prefix INFRACOST_. The plans are saved to %s by default.`, planDir),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if costFormat != "text" && costFormat != "json" {
+			logrus.Fatalf("invalid --output '%s', must be text or json", costFormat)
+		}
+
+		if costFormat == "json" {
+			runOutput = os.Stderr
+		}
+
+		CostEstimate = true
+
+		failed := runDeploy(cmd, actionPlan)
+
+		printRunCostEstimates(os.Stdout, costFormat)
+
+		return getRunError(cmd, failed)
+	},
+}
+
+// validateCostEstimate returns an error when --cost-estimate is combined with options the cost estimates can not be
+// summarized with or infracost is not on the path in --native mode
+func validateCostEstimate() error {
+	if !CostEstimate {
+		return nil
+	}
+
+	if Detach {
+		return fmt.Errorf("--cost-estimate can not be used with --detach, the cost estimates are summarized once the container exits")
+	}
+
+	if Native {
+		if _, err := lookPath("infracost"); err != nil {
+			return fmt.Errorf("--cost-estimate requires 'infracost' on the path in --native mode")
+		}
+	}
+
+	if Runner != "terraform" {
+		logrus.Warnf("The %s runner does not estimate costs, the cost estimates only cover terraform steps", Runner)
+	}
+
+	return nil
+}
+
+// reportCostEstimate is the subset of a ring's deployment report used to summarize its cost estimates
+type reportCostEstimate struct {
+	DeploymentRing string `yaml:"deployment_ring"`
+	Steps          []struct {
+		Track            string `yaml:"track"`
+		Step             string `yaml:"step"`
+		Action           string `yaml:"action"`
+		RegionDeployType string `yaml:"region_deploy_type"`
+		Region           string `yaml:"region"`
+		CostEstimate     *struct {
+			Currency         string  `yaml:"currency"`
+			PastMonthlyCost  float64 `yaml:"past_monthly_cost"`
+			MonthlyCost      float64 `yaml:"monthly_cost"`
+			MonthlyCostDelta float64 `yaml:"monthly_cost_delta"`
+		} `yaml:"cost_estimate"`
+	} `yaml:"steps"`
+}
+
+// stepCostEstimate is the estimated monthly cost of a step in a region before and after applying its plan
+type stepCostEstimate struct {
+	StepID           string  `json:"step_id"`
+	Action           string  `json:"action"`
+	RegionDeployType string  `json:"region_deploy_type"`
+	Region           string  `json:"region"`
+	DeploymentRing   string  `json:"deployment_ring"`
+	PastMonthlyCost  float64 `json:"past_monthly_cost"`
+	MonthlyCost      float64 `json:"monthly_cost"`
+	MonthlyCostDelta float64 `json:"monthly_cost_delta"`
+}
+
+// costSummary is the consolidated cost estimate of a run
+type costSummary struct {
+	Currency         string             `json:"currency"`
+	PastMonthlyCost  float64            `json:"past_monthly_cost"`
+	MonthlyCost      float64            `json:"monthly_cost"`
+	MonthlyCostDelta float64            `json:"monthly_cost_delta"`
+	Steps            []stepCostEstimate `json:"steps"`
+}
+
+// readCostSummary summarizes the cost estimates of the deployment reports at paths, ordered by ring, step and
+// region. The reports are json or yaml, which yaml decodes alike.
+func readCostSummary(fs afero.Fs, paths []string) (costSummary, error) {
+	summary := costSummary{Steps: []stepCostEstimate{}}
+
+	for _, path := range paths {
+		b, err := afero.ReadFile(fs, path)
+		if err != nil {
+			return summary, err
+		}
+
+		report := reportCostEstimate{}
+
+		if err = yaml.Unmarshal(b, &report); err != nil {
+			return summary, fmt.Errorf("invalid deployment report %s: %w", path, err)
+		}
+
+		for _, s := range report.Steps {
+			if s.CostEstimate == nil {
+				continue
+			}
+
+			if summary.Currency == "" {
+				summary.Currency = s.CostEstimate.Currency
+			} else if s.CostEstimate.Currency != summary.Currency {
+				return summary, fmt.Errorf("the cost of step %s/%s is estimated in %s instead of %s", s.Track, s.Step, s.CostEstimate.Currency, summary.Currency)
+			}
+
+			summary.Steps = append(summary.Steps, stepCostEstimate{
+				StepID:           fmt.Sprintf("%s/%s", s.Track, s.Step),
+				Action:           s.Action,
+				RegionDeployType: s.RegionDeployType,
+				Region:           s.Region,
+				DeploymentRing:   report.DeploymentRing,
+				PastMonthlyCost:  s.CostEstimate.PastMonthlyCost,
+				MonthlyCost:      s.CostEstimate.MonthlyCost,
+				MonthlyCostDelta: s.CostEstimate.MonthlyCostDelta,
+			})
+
+			summary.PastMonthlyCost += s.CostEstimate.PastMonthlyCost
+			summary.MonthlyCost += s.CostEstimate.MonthlyCost
+			summary.MonthlyCostDelta += s.CostEstimate.MonthlyCostDelta
+		}
+	}
+
+	sort.SliceStable(summary.Steps, func(i, j int) bool {
+		a, b := summary.Steps[i], summary.Steps[j]
+
+		if a.DeploymentRing != b.DeploymentRing {
+			return a.DeploymentRing < b.DeploymentRing
+		}
+
+		if a.StepID != b.StepID {
+			return a.StepID < b.StepID
+		}
+
+		return a.RegionDeployType+a.Region < b.RegionDeployType+b.Region
+	})
+
+	return summary, nil
+}
+
+// printCostSummary writes the estimated monthly cost and its change per step and region followed by the totals
+func printCostSummary(w io.Writer, summary costSummary) error {
+	if len(summary.Steps) == 0 {
+		_, err := fmt.Fprintln(w, "No costs were estimated, cost estimates are only available for terraform steps with infracost in the container")
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "RING\tSTEP\tREGION\tPREVIOUS\tMONTHLY\tDELTA")
+
+	for _, s := range summary.Steps {
+		fmt.Fprintf(tw, "%s\t%s\t%s (%s)\t%.2f\t%.2f\t%+.2f\n", s.DeploymentRing, s.StepID, s.Region, s.RegionDeployType, s.PastMonthlyCost, s.MonthlyCost, s.MonthlyCostDelta)
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "\nMonthly cost: %.2f %s (%+.2f %s) across %d step execution(s).\n", summary.MonthlyCost, summary.Currency, summary.MonthlyCostDelta, summary.Currency, len(summary.Steps))
+
+	return err
+}
+
+// printCostSummaryJSON writes the summary as indented json
+func printCostSummaryJSON(w io.Writer, summary costSummary) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(summary)
+}
+
+// printRunCostEstimates writes the cost estimates of the reports the run wrote when --cost-estimate is set, nothing
+// is estimated when only printing settings
+func printRunCostEstimates(w io.Writer, format string) {
+	if !CostEstimate || Why != "" || Test || ShowResolvedVars || PrintContext {
+		return
+	}
+
+	summary, err := readCostSummary(appFS, writtenReports)
+	if err != nil {
+		logrus.WithError(err).Fatal(err)
+	}
+
+	if format == "json" {
+		err = printCostSummaryJSON(w, summary)
+	} else {
+		err = printCostSummary(w, summary)
+	}
+
+	if err != nil {
+		logrus.WithError(err).Fatal(err)
+	}
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+const stubCostReport = `{
+  "deployment_ring": "dev",
+  "steps": [
+    {"track": "network", "step": "vnet", "action": "deploy", "region_deploy_type": "regional", "region": "eastus",
+     "cost_estimate": {"currency": "USD", "past_monthly_cost": 100, "monthly_cost": 150, "monthly_cost_delta": 50}},
+    {"track": "default", "step": "dns", "action": "deploy", "region_deploy_type": "primary", "region": "eastus",
+     "cost_estimate": {"currency": "USD", "past_monthly_cost": 20, "monthly_cost": 10.5, "monthly_cost_delta": -9.5}},
+    {"track": "default", "step": "hello", "action": "deploy", "region_deploy_type": "primary", "region": "eastus"}
+  ]
+}`
+
+const stubCostReportYAML = `deployment_ring: prod
+steps:
+  - track: default
+    step: dns
+    action: deploy
+    region_deploy_type: primary
+    region: westus
+    cost_estimate:
+      currency: USD
+      past_monthly_cost: 0
+      monthly_cost: 12
+      monthly_cost_delta: 12
+`
+
+func TestReadCostSummary_ShouldAggregateCostEstimatesPerStep(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	_ = afero.WriteFile(fs, ".runiac/reports/dev.json", []byte(stubCostReport), 0644)
+	_ = afero.WriteFile(fs, ".runiac/reports/prod.yml", []byte(stubCostReportYAML), 0644)
+
+	summary, err := readCostSummary(fs, []string{".runiac/reports/prod.yml", ".runiac/reports/dev.json"})
+	require.NoError(t, err)
+
+	require.Len(t, summary.Steps, 3, "steps without a cost estimate should be ignored")
+	require.Equal(t, stepCostEstimate{StepID: "default/dns", Action: "deploy", RegionDeployType: "primary", Region: "eastus", DeploymentRing: "dev", PastMonthlyCost: 20, MonthlyCost: 10.5, MonthlyCostDelta: -9.5}, summary.Steps[0])
+	require.Equal(t, "network/vnet", summary.Steps[1].StepID)
+	require.Equal(t, "prod", summary.Steps[2].DeploymentRing)
+
+	require.Equal(t, "USD", summary.Currency)
+	require.Equal(t, 120.0, summary.PastMonthlyCost)
+	require.Equal(t, 172.5, summary.MonthlyCost)
+	require.Equal(t, 52.5, summary.MonthlyCostDelta)
+
+	var b bytes.Buffer
+	require.NoError(t, printCostSummary(&b, summary))
+	require.Contains(t, b.String(), "dev   default/dns   eastus (primary)   20.00     10.50    -9.50")
+	require.Contains(t, b.String(), "Monthly cost: 172.50 USD (+52.50 USD) across 3 step execution(s).")
+
+	b.Reset()
+	require.NoError(t, printCostSummaryJSON(&b, summary))
+
+	exported := costSummary{}
+	require.NoError(t, json.Unmarshal(b.Bytes(), &exported))
+	require.Equal(t, summary, exported)
+
+	empty, err := readCostSummary(fs, nil)
+	require.NoError(t, err)
+	require.Empty(t, empty.Steps)
+
+	b.Reset()
+	require.NoError(t, printCostSummary(&b, empty))
+	require.Contains(t, b.String(), "No costs were estimated")
+}
+
+func TestReadCostSummary_ShouldRejectMixedCurrencies(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	_ = afero.WriteFile(fs, "dev.json", []byte(stubCostReport), 0644)
+	_ = afero.WriteFile(fs, "prod.json", []byte(`{"steps": [{"track": "default", "step": "dns", "cost_estimate": {"currency": "EUR", "monthly_cost": 1}}]}`), 0644)
+
+	_, err := readCostSummary(fs, []string{"dev.json", "prod.json"})
+	require.EqualError(t, err, "the cost of step default/dns is estimated in EUR instead of USD")
+}
+
+func TestValidateCostEstimate_ShouldRequireSummarizableRun(t *testing.T) {
+	defer func(f func(string) (string, error)) { lookPath = f }(lookPath)
+	defer func() { CostEstimate, Detach, Native, Runner = false, false, false, "" }()
+
+	CostEstimate, Detach, Runner = false, true, "terraform"
+	require.NoError(t, validateCostEstimate(), "nothing is validated without --cost-estimate")
+
+	CostEstimate = true
+	require.Error(t, validateCostEstimate(), "the cost estimates of a detached container can not be summarized")
+
+	Detach, Native = false, true
+	lookPath = func(file string) (string, error) { return "", errors.New("not found") }
+	require.EqualError(t, validateCostEstimate(), "--cost-estimate requires 'infracost' on the path in --native mode")
+
+	lookPath = func(file string) (string, error) { return "/usr/local/bin/" + file, nil }
+	require.NoError(t, validateCostEstimate())
+}
@@ -15,18 +15,23 @@ func InitAction() bool {
 	logrus.Debug("Creating .runiac directory")
 	_ = appFS.Mkdir(".runiac", 0755)
 
-	dockerfile := strings.ReplaceAll(DockerfileTemplate, "${BASE_CONTAINER}", BaseContainer)
-
-	logrus.Debug("Writing .runiac/Dockerfile")
-	err := afero.WriteFile(appFS, ".runiac/Dockerfile", []byte(dockerfile), 0644)
-
-	if err != nil {
-		logrus.WithError(err).Error(err)
-		return false
+	// a dockerfile from runiac gen dockerfile is owned by the project
+	if existing, err := afero.ReadFile(appFS, ".runiac/Dockerfile"); err == nil && !isAutogenerated(existing) {
+		logrus.Debug("Keeping the existing .runiac/Dockerfile")
+	} else {
+		dockerfile := strings.ReplaceAll(DockerfileTemplate, "${BASE_CONTAINER}", BaseContainer)
+
+		logrus.Debug("Writing .runiac/Dockerfile")
+		err := afero.WriteFile(appFS, ".runiac/Dockerfile", []byte(dockerfile), 0644)
+
+		if err != nil {
+			logrus.WithError(err).Error(err)
+			return false
+		}
 	}
 
 	logrus.Debug("Writing .runiac/.dockerignore")
-	err = afero.WriteFile(appFS, ".runiac/.dockerignore", []byte(DockerIgnore), 0644)
+	err := afero.WriteFile(appFS, ".runiac/.dockerignore", []byte(DockerIgnore), 0644)
 
 	if err != nil {
 		logrus.WithError(err).Error(err)
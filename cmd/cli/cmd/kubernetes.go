@@ -0,0 +1,419 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// The targets --execution-target runs the deploy container on
+const (
+	executionTargetLocal      = "local"      // The container engine on this host
+	executionTargetKubernetes = "kubernetes" // A Kubernetes Job in the cluster of the current kubectl context
+)
+
+const kubectlBinary = "kubectl"
+
+// kubernetesContainerName is the name of the deploy container in the pod of the job
+const kubernetesContainerName = "runiac"
+
+// kubernetesJobTTL is how long a finished job and its pod are kept for inspection before the cluster removes them
+const kubernetesJobTTL = 24 * time.Hour
+
+// kubernetesPodRunningTimeout is how long the logs wait for the pod of the job to be scheduled and start
+const kubernetesPodRunningTimeout = 15 * time.Minute
+
+// kubernetesExitTimeout is how long the exit code of the job is waited for once its logs ended
+var kubernetesExitTimeout = 5 * time.Minute
+
+// kubernetesPollInterval is how often the pod of the job is polled for its exit code, stubbed by the tests
+var kubernetesPollInterval = 2 * time.Second
+
+// invalidKubernetesNameChars matches the characters a kubernetes object name or label value may not contain
+var invalidKubernetesNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// runKubectl runs kubectl with the arguments, a variable so tests do not need a cluster
+var runKubectl = func(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	cmd := exec.Command(kubectlBinary, args...)
+
+	cmd.Env = os.Environ()
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = stdin, stdout, stderr
+
+	return runCommand(cmd)
+}
+
+// validateExecutionTarget returns an error when the execution target is unknown or, for kubernetes, combined with
+// options that require the container to run on this host. The job has none of the mounts of a local container, so
+// the steps must keep their state remotely and --resume, --from-step and the deployment report, which read or
+// write the local state and report directories, are rejected.
+func validateExecutionTarget(target string) error {
+	switch target {
+	case executionTargetLocal:
+		return nil
+	case executionTargetKubernetes:
+	default:
+		return fmt.Errorf("invalid --execution-target '%s', must be %s or %s", target, executionTargetLocal, executionTargetKubernetes)
+	}
+
+	conflicts := []struct {
+		flag string
+		set  bool
+	}{
+		{"--native", Native},
+		{"--detach", Detach},
+		{"--interactive", Interactive},
+		{"--restart", RestartPolicy != ""},
+		{"--pid", PidMode != ""},
+		{"--ipc", IpcMode != ""},
+		{"--docker-socket", DockerSocket != ""},
+		{"--mount-docker-socket", MountDocker},
+		{"--mount-credentials", len(MountCredentials) > 0},
+		{"--credential-tokens", CredentialTokens},
+		{"--output-dir", OutputDir != ""},
+		{"--resume", Resume},
+		{"--from-step", FromStep != ""},
+		{"--report-path", ReportPath != ""},
+		{"--use-saved-plans", UseSavedPlans},
+		{"--github-comment", GitHubComment},
+		{"--artifacts-from", ArtifactsFrom != ""},
+		{"--export-manifest", ExportManifest != ""},
+		{"--sarif", Sarif != ""},
+	}
+
+	for _, c := range conflicts {
+		if c.set {
+			return fmt.Errorf("%s can not be used with --execution-target %s, the container runs in the cluster instead of on this host", c.flag, executionTargetKubernetes)
+		}
+	}
+
+	if Image == "" && KubeRegistry == "" {
+		return fmt.Errorf("--execution-target %s requires --image, a pre-built image, or --kube-registry to push the built image to", executionTargetKubernetes)
+	}
+
+	if Image != "" && (ValidateContract || StrictContract) {
+		return fmt.Errorf("--validate-config-against-container can not be used with --image and --execution-target %s, the pre-built image is not run on this host", executionTargetKubernetes)
+	}
+
+	if _, err := lookPath(kubectlBinary); err != nil {
+		return fmt.Errorf("--execution-target %s requires '%s' on the path", executionTargetKubernetes, kubectlBinary)
+	}
+
+	steps, err := getLocalStateSteps(appFS)
+	if err != nil {
+		return err
+	}
+
+	if len(steps) > 0 {
+		return fmt.Errorf("--execution-target %s requires remote state, the steps %s keep their state in %s which is lost with the pod of the job, configure a remote backend for them", executionTargetKubernetes, strings.Join(steps, ", "), containerTFState)
+	}
+
+	return nil
+}
+
+// isKubernetesTarget returns true when the deploy container runs as a kubernetes job
+func isKubernetesTarget() bool {
+	return ExecutionTarget == executionTargetKubernetes
+}
+
+// getKubectlArguments returns the kubectl arguments targeting --kube-context and --kube-namespace, the current
+// context and its namespace when not set
+func getKubectlArguments(args ...string) []string {
+	base := []string{}
+
+	if KubeContext != "" {
+		base = append(base, "--context", KubeContext)
+	}
+
+	if KubeNamespace != "" {
+		base = append(base, "--namespace", KubeNamespace)
+	}
+
+	return append(base, args...)
+}
+
+// getKubernetesName returns the value as a valid kubernetes object name or label value, lower case alphanumeric
+// characters and dashes of at most 63 characters
+func getKubernetesName(value string) string {
+	name := invalidKubernetesNameChars.ReplaceAllString(strings.ToLower(value), "-")
+
+	if len(name) > 63 {
+		name = name[:63]
+	}
+
+	return strings.Trim(name, "-")
+}
+
+// getKubernetesJobName returns the name of the job running the ring's deploy, unique by the correlation id of the
+// ring's run
+func getKubernetesJobName(ring string, correlationID string) string {
+	if len(correlationID) > 8 {
+		correlationID = correlationID[:8]
+	}
+
+	return getKubernetesName(fmt.Sprintf("runiac-%s-%s", ring, correlationID))
+}
+
+// getKubernetesImage returns the image of the ring's job, the pre-built --image or the built container pushed to
+// --kube-registry
+func getKubernetesImage(containerTag string) string {
+	if Image != "" {
+		return Image
+	}
+
+	tag := AppVersion
+	if tag == "" {
+		tag = "latest"
+	}
+
+	return fmt.Sprintf("%s/%s:%s", strings.TrimSuffix(KubeRegistry, "/"), containerTag, getKubernetesName(tag))
+}
+
+// getKubernetesJobManifest returns a list of the job running the deploy container and the secret passing it the env
+// variables of the container run arguments, so their sensitive values are not part of the job's spec. Mounts of
+// the run arguments are not available in the cluster and are left out.
+func getKubernetesJobManifest(name string, image string, runArgs []string, labels map[string]string) ([]byte, error) {
+	env := map[string]string{}
+
+	for _, e := range getEnvFromArgs(runArgs) {
+		kv := strings.SplitN(e, "=", 2)
+
+		if len(kv) == 2 {
+			env[kv[0]] = kv[1]
+		} else if v, ok := os.LookupEnv(kv[0]); ok {
+			// -e NAME passes the host's value, as it would to a local container
+			env[kv[0]] = v
+		}
+	}
+
+	pullPolicy := "IfNotPresent"
+
+	// the pushed tag is reused between builds, so the cluster must not run a stale image
+	if Image == "" {
+		pullPolicy = "Always"
+	}
+
+	metadata := map[string]interface{}{"name": name, "labels": labels}
+
+	podSpec := map[string]interface{}{
+		"restartPolicy": "Never",
+		"containers": []map[string]interface{}{
+			{
+				"name":            kubernetesContainerName,
+				"image":           image,
+				"imagePullPolicy": pullPolicy,
+				"envFrom":         []map[string]interface{}{{"secretRef": map[string]string{"name": name}}},
+			},
+		},
+	}
+
+	// workload identity is bound to the service account of the pod
+	if KubeSvcAccount != "" {
+		podSpec["serviceAccountName"] = KubeSvcAccount
+	}
+
+	manifest := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "List",
+		"items": []map[string]interface{}{
+			{
+				"apiVersion": "v1",
+				"kind":       "Secret",
+				"metadata":   metadata,
+				"type":       "Opaque",
+				"stringData": env,
+			},
+			{
+				"apiVersion": "batch/v1",
+				"kind":       "Job",
+				"metadata":   metadata,
+				"spec": map[string]interface{}{
+					// a failed deploy is not retried, runiac deploy --resume continues it
+					"backoffLimit":            0,
+					"ttlSecondsAfterFinished": int(kubernetesJobTTL.Seconds()),
+					"template": map[string]interface{}{
+						"metadata": map[string]interface{}{"labels": labels},
+						"spec":     podSpec,
+					},
+				},
+			},
+		},
+	}
+
+	return json.MarshalIndent(manifest, "", "  ")
+}
+
+// getKubernetesLabels returns the labels of the job and its pod with the resolved run context, so runiac jobs are
+// discoverable, e.g. kubectl get jobs -l runiac.ring=prod
+func getKubernetesLabels(ring string, namespace string, environment string, correlationID string) map[string]string {
+	labels := map[string]string{}
+
+	for name, value := range map[string]string{
+		"runiac.ring":           ring,
+		"runiac.namespace":      namespace,
+		"runiac.environment":    environment,
+		"runiac.correlation-id": correlationID,
+	} {
+		if value = getKubernetesName(value); value != "" {
+			labels[name] = value
+		}
+	}
+
+	return labels
+}
+
+// runKubernetesJob runs the deploy container as a kubernetes job, streaming the logs of its pod and returning an
+// error when the container exits with a non-zero exit code
+func runKubernetesJob(name string, image string, runArgs []string, labels map[string]string, recordPath string) error {
+	manifest, err := getKubernetesJobManifest(name, image, runArgs, labels)
+	if err != nil {
+		return err
+	}
+
+	logrus.Infof("Creating job %s running %s", name, image)
+
+	var applyOut bytes.Buffer
+
+	if err = runKubectl(getKubectlArguments("apply", "-f", "-"), bytes.NewReader(manifest), &applyOut, &applyOut); err != nil {
+		logrus.Error(applyOut.String())
+		return fmt.Errorf("creating job %s failed: %w", name, err)
+	}
+
+	defer deleteKubernetesJobSecret(name)
+
+	interrupted, stopWatching := deleteSecretOnInterrupt(name)
+	defer stopWatching()
+
+	terminalOut, terminalErr := getTerminalWriters()
+
+	var stdout, stderr io.Writer = terminalOut, terminalErr
+
+	var recorder *sessionRecorder
+
+	if recordPath != "" {
+		recorder, err = newSessionRecorder(recordPath, maskArgs(runArgs))
+		if err != nil {
+			return fmt.Errorf("unable to record session to %s: %w", recordPath, err)
+		}
+
+		logrus.Infof("Recording session to %s", recordPath)
+
+		stdout = io.MultiWriter(terminalOut, recorder)
+		stderr = io.MultiWriter(terminalErr, recorder)
+	}
+
+	maskedStdout, maskedStderr := getMaskingWriters(getEnvFromArgs(runArgs), stdout, stderr)
+
+	logsErr := runKubectl(getKubectlArguments("logs", "-f", "job/"+name, "-c", kubernetesContainerName,
+		fmt.Sprintf("--pod-running-timeout=%s", kubernetesPodRunningTimeout)), nil, maskedStdout, maskedStderr)
+
+	_ = maskedStdout.Flush()
+	_ = maskedStderr.Flush()
+
+	err = getKubernetesJobErr(name, logsErr, interrupted)
+
+	if recorder != nil {
+		if err := recorder.Close(err); err != nil {
+			logrus.WithError(err).Warnf("Unable to finish recording session to %s", recordPath)
+		}
+	}
+
+	return err
+}
+
+// getKubernetesJobErr waits for the exit code of the job's container, returning an error unless it succeeded
+func getKubernetesJobErr(name string, logsErr error, interrupted <-chan struct{}) error {
+	code, err := waitForKubernetesJobExit(name, interrupted)
+	if err != nil {
+		if logsErr != nil {
+			return fmt.Errorf("streaming the logs of job %s failed: %v, %w", name, logsErr, err)
+		}
+
+		return err
+	}
+
+	if code != 0 {
+		return fmt.Errorf("job %s failed with exit code %d", name, code)
+	}
+
+	return nil
+}
+
+// waitForKubernetesJobExit polls the pod of the job until its container terminated and returns its exit code, or
+// an error once runiac was interrupted
+func waitForKubernetesJobExit(name string, interrupted <-chan struct{}) (int, error) {
+	deadline := time.Now().Add(kubernetesExitTimeout)
+
+	for {
+		select {
+		case <-interrupted:
+			return 0, fmt.Errorf("interrupted while job %s is running, it keeps running in the cluster, stop it with 'kubectl delete job %s'", name, name)
+		default:
+		}
+
+		var out, errOut bytes.Buffer
+
+		err := runKubectl(getKubectlArguments("get", "pods", "-l", "job-name="+name, "-o",
+			fmt.Sprintf("jsonpath={.items[0].status.containerStatuses[?(@.name==%q)].state.terminated.exitCode}", kubernetesContainerName)), nil, &out, &errOut)
+
+		if err == nil && strings.TrimSpace(out.String()) != "" {
+			return strconv.Atoi(strings.TrimSpace(out.String()))
+		}
+
+		if err != nil {
+			logrus.WithError(err).Warnf("Getting the pod of job %s failed: %s", name, strings.TrimSpace(errOut.String()))
+		}
+
+		if time.Now().Add(kubernetesPollInterval).After(deadline) {
+			return 0, fmt.Errorf("job %s did not complete within %s of its logs ending, check on it with 'kubectl get job %s'", name, kubernetesExitTimeout, name)
+		}
+
+		time.Sleep(kubernetesPollInterval)
+	}
+}
+
+// deleteSecretOnInterrupt deletes the secret of the job as soon as runiac is interrupted or terminated, a signal
+// would otherwise exit before the deferred deletion. The returned channel is closed once the secret was deleted,
+// the returned func stops watching for the signals.
+func deleteSecretOnInterrupt(name string) (<-chan struct{}, func()) {
+	signals := make(chan os.Signal, 1)
+	interrupted := make(chan struct{})
+	done := make(chan struct{})
+
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case <-signals:
+			logrus.Warnf("Interrupted, deleting the secret of job %s", name)
+			deleteKubernetesJobSecret(name)
+			close(interrupted)
+		case <-done:
+		}
+	}()
+
+	return interrupted, func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
+
+// deleteKubernetesJobSecret deletes the secret of the job's env variables once the job completed, the cluster
+// removes the job itself after its ttl
+func deleteKubernetesJobSecret(name string) {
+	var out bytes.Buffer
+
+	if err := runKubectl(getKubectlArguments("delete", "secret", name, "--ignore-not-found"), nil, &out, &out); err != nil {
+		logrus.WithError(err).Warnf("Unable to delete the secret %s of job %s: %s", name, name, out.String())
+	}
+}
@@ -0,0 +1,226 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateExecutionTarget_ShouldRejectHostOptions(t *testing.T) {
+	defer func(fs afero.Fs) {
+		appFS = fs
+		lookPath = exec.LookPath
+		Image, KubeRegistry, Detach, Sarif, Resume, ReportPath = "", "", false, "", false, ""
+	}(appFS)
+
+	appFS = afero.NewMemMapFs()
+	_ = afero.WriteFile(appFS, "tracks/network/step1_vpc/backend.tf", []byte(`terraform { backend "s3" {} }`), 0644)
+
+	lookPath = func(file string) (string, error) { return "/usr/bin/" + file, nil }
+
+	require.NoError(t, validateExecutionTarget(executionTargetLocal))
+	require.EqualError(t, validateExecutionTarget("lambda"), "invalid --execution-target 'lambda', must be local or kubernetes")
+
+	err := validateExecutionTarget(executionTargetKubernetes)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "--image")
+
+	Image = "registry.stub/project:1.0.0"
+	require.NoError(t, validateExecutionTarget(executionTargetKubernetes))
+
+	Detach = true
+	err = validateExecutionTarget(executionTargetKubernetes)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "--detach")
+
+	Detach, Sarif = false, "report.sarif"
+	err = validateExecutionTarget(executionTargetKubernetes)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "--sarif")
+
+	Sarif, Resume = "", true
+	err = validateExecutionTarget(executionTargetKubernetes)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "--resume")
+
+	Resume, ReportPath = false, "report.json"
+	err = validateExecutionTarget(executionTargetKubernetes)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "--report-path")
+
+	ReportPath = ""
+	_ = afero.WriteFile(appFS, "tracks/network/step2_dns/regional/backend.tf", []byte(`terraform { backend "local" { workspace_dir = "/runiac/tfstate" } }`), 0644)
+	require.EqualError(t, validateExecutionTarget(executionTargetKubernetes), "--execution-target kubernetes requires remote state, the steps network/dns keep their state in /runiac/tfstate which is lost with the pod of the job, configure a remote backend for them")
+
+	require.NoError(t, appFS.RemoveAll("tracks/network/step2_dns"))
+	lookPath = func(file string) (string, error) { return "", errors.New("not found") }
+	err = validateExecutionTarget(executionTargetKubernetes)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "'kubectl'")
+}
+
+func TestGetKubernetesJobName_ShouldBeValidObjectName(t *testing.T) {
+	require.Equal(t, "runiac-prod-0123abcd", getKubernetesJobName("prod", "0123abcdef"))
+	require.Equal(t, "runiac-canary-eu-0123abcd", getKubernetesJobName("Canary_EU", "0123abcdef"))
+
+	name := getKubernetesJobName(strings.Repeat("ring", 20), "0123abcdef")
+	require.Len(t, name, 63)
+	require.True(t, strings.HasPrefix(name, "runiac-ring"))
+}
+
+func TestGetKubernetesImage_ShouldPreferPrePushedImage(t *testing.T) {
+	defer func() { Image, KubeRegistry, AppVersion = "", "", "" }()
+
+	KubeRegistry = "registry.stub/team/"
+	require.Equal(t, "registry.stub/team/project:latest", getKubernetesImage("project"))
+
+	AppVersion = "1.2.3+build"
+	require.Equal(t, "registry.stub/team/project:1-2-3-build", getKubernetesImage("project"))
+
+	Image = "registry.stub/project:pinned"
+	require.Equal(t, "registry.stub/project:pinned", getKubernetesImage("project"))
+}
+
+func TestGetKubernetesJobManifest_ShouldPassEnvThroughSecret(t *testing.T) {
+	defer func() { Image, KubeSvcAccount = "", "" }()
+
+	Image, KubeSvcAccount = "registry.stub/project:1.0.0", "deployer"
+	_ = os.Setenv("STUB_HOST_VAR", "from-host")
+	defer os.Unsetenv("STUB_HOST_VAR")
+
+	runArgs := []string{
+		"-e", "RUNIAC_ENVIRONMENT=dev",
+		"-e", "ARM_CLIENT_SECRET=a=b",
+		"-e", "STUB_HOST_VAR",
+		"-v", "/home/stub/.runiac/tfstate:/runiac/tfstate",
+	}
+
+	b, err := getKubernetesJobManifest("runiac-prod-0123abcd", Image, runArgs, getKubernetesLabels("prod", "", "dev", "0123abcdef"))
+	require.NoError(t, err)
+
+	manifest := struct {
+		Items []struct {
+			Kind     string `json:"kind"`
+			Metadata struct {
+				Name   string            `json:"name"`
+				Labels map[string]string `json:"labels"`
+			} `json:"metadata"`
+			StringData map[string]string `json:"stringData"`
+			Spec       struct {
+				BackoffLimit int `json:"backoffLimit"`
+				Template     struct {
+					Spec struct {
+						ServiceAccountName string `json:"serviceAccountName"`
+						RestartPolicy      string `json:"restartPolicy"`
+						Containers         []struct {
+							Image   string            `json:"image"`
+							Env     []json.RawMessage `json:"env"`
+							EnvFrom []struct {
+								SecretRef struct {
+									Name string `json:"name"`
+								} `json:"secretRef"`
+							} `json:"envFrom"`
+						} `json:"containers"`
+					} `json:"spec"`
+				} `json:"template"`
+			} `json:"spec"`
+		} `json:"items"`
+	}{}
+
+	require.NoError(t, json.Unmarshal(b, &manifest))
+	require.Len(t, manifest.Items, 2)
+
+	secret, job := manifest.Items[0], manifest.Items[1]
+
+	require.Equal(t, "Secret", secret.Kind)
+	require.Equal(t, map[string]string{
+		"RUNIAC_ENVIRONMENT": "dev",
+		"ARM_CLIENT_SECRET":  "a=b",
+		"STUB_HOST_VAR":      "from-host",
+	}, secret.StringData)
+
+	require.Equal(t, "Job", job.Kind)
+	require.Equal(t, "runiac-prod-0123abcd", job.Metadata.Name)
+	require.Equal(t, map[string]string{"runiac.ring": "prod", "runiac.environment": "dev", "runiac.correlation-id": "0123abcdef"}, job.Metadata.Labels)
+	require.Equal(t, 0, job.Spec.BackoffLimit)
+
+	pod := job.Spec.Template.Spec
+	require.Equal(t, "deployer", pod.ServiceAccountName)
+	require.Equal(t, "Never", pod.RestartPolicy)
+	require.Len(t, pod.Containers, 1)
+	require.Equal(t, Image, pod.Containers[0].Image)
+	require.Empty(t, pod.Containers[0].Env, "the env should only be passed through the secret")
+	require.Equal(t, "runiac-prod-0123abcd", pod.Containers[0].EnvFrom[0].SecretRef.Name)
+}
+
+func TestRunKubernetesJob_ShouldPropagateExitCode(t *testing.T) {
+	defer func(run func([]string, io.Reader, io.Writer, io.Writer) error, interval time.Duration, out io.Writer) {
+		runKubectl, kubernetesPollInterval, runOutput = run, interval, out
+		KubeContext, KubeNamespace = "", ""
+	}(runKubectl, kubernetesPollInterval, runOutput)
+
+	kubernetesPollInterval, runOutput = time.Millisecond, ioutil.Discard
+	KubeContext, KubeNamespace = "stub-cluster", "deploys"
+
+	for exitCode, expectedErr := range map[string]string{"0": "", "3": "job runiac-prod-0123abcd failed with exit code 3"} {
+		var calls []string
+		var applied []byte
+		polls := 0
+
+		runKubectl = func(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+			calls = append(calls, strings.Join(args, " "))
+
+			switch args[4] {
+			case "apply":
+				applied, _ = ioutil.ReadAll(stdin)
+			case "logs":
+				_, _ = fmt.Fprintln(stdout, "deploying step network")
+			case "get":
+				// the exit code is only set once the container terminated
+				if polls++; polls > 1 {
+					_, _ = fmt.Fprint(stdout, exitCode)
+				}
+			}
+
+			return nil
+		}
+
+		err := runKubernetesJob("runiac-prod-0123abcd", "registry.stub/project:1.0.0", []string{"-e", "RUNIAC_ENVIRONMENT=dev"}, nil, "")
+
+		if expectedErr == "" {
+			require.NoError(t, err)
+		} else {
+			require.EqualError(t, err, expectedErr)
+		}
+
+		require.Contains(t, string(applied), `"kind": "Job"`)
+		require.Equal(t, 2, polls)
+		require.Equal(t, "--context stub-cluster --namespace deploys apply -f -", calls[0])
+		require.True(t, strings.HasPrefix(calls[1], "--context stub-cluster --namespace deploys logs -f job/runiac-prod-0123abcd -c runiac"), calls[1])
+		require.Equal(t, "--context stub-cluster --namespace deploys delete secret runiac-prod-0123abcd --ignore-not-found", calls[len(calls)-1])
+	}
+}
+
+func TestWaitForKubernetesJobExit_ShouldStopWhenInterrupted(t *testing.T) {
+	defer func(run func([]string, io.Reader, io.Writer, io.Writer) error) { runKubectl = run }(runKubectl)
+
+	runKubectl = func(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+		return nil
+	}
+
+	interrupted := make(chan struct{})
+	close(interrupted)
+
+	_, err := waitForKubernetesJobExit("runiac-prod-0123abcd", interrupted)
+	require.EqualError(t, err, "interrupted while job runiac-prod-0123abcd is running, it keeps running in the cluster, stop it with 'kubectl delete job runiac-prod-0123abcd'")
+}
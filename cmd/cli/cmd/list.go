@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/optum/runiac/pkg/tracks"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var listJSON bool
+
+func init() {
+	listCmd.Flags().AddFlagSet(deployCmd.Flags())
+	listCmd.Flags().BoolVar(&listJSON, "json", false, "Print the tracks and steps as json")
+
+	rootCmd.AddCommand(listCmd)
+}
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the tracks, steps and regions a deploy would execute",
+	Long: `Lists the project's tracks and their steps in progression order with the runner and the regions each step
+deploys to for each deployment ring, and whether the selectors of the flags, e.g. --steps, --tracks, --tags or
+--since, select the step. Accepts the flags of deploy, nothing is built or executed.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := applySettings(cmd.Flags())
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		err = applyEnvironmentConfig(viper.GetViper(), cmd.Flags(), Environment)
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		err = configureRingAndNamespace()
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		projectFS, err := getBuildContextFs(BuildContext)
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		graph, err := getProjectGraph(projectFS)
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		whitelist, unselected, err := resolveStepWhitelist(projectFS)
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		if unselected != "" {
+			logrus.Info(unselected)
+		}
+
+		primary := PrimaryRegions
+		if !cmd.Flags().Changed("primary-regions") && len(primary) == 0 && viper.IsSet("primary_region") {
+			primary = getConfigRegions("primary_region")
+		}
+
+		rings := getDeploymentRings()
+
+		regions, err := resolveRingRegions(rings, primary, RegionalRegions, cmd.Flags().Changed("primary-regions"), cmd.Flags().Changed("regional-regions"))
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		for _, ring := range rings {
+			graph.Rings = append(graph.Rings, graphRing{Name: ring, Regions: regions[ring]})
+		}
+
+		graph.ParallelPrimary = ParallelPrimary
+
+		graph.RegionalRollout, err = getRegionalRollout(viper.GetViper())
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		tracks := getListTracks(graph, Runner, whitelist, unselected == "")
+
+		if listJSON {
+			err = printListJSON(os.Stdout, tracks)
+		} else {
+			err = printList(os.Stdout, tracks)
+		}
+
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+	},
+}
+
+// listTrack is a track of runiac list, the _pretrack is executed before every other track
+type listTrack struct {
+	Name  string     `json:"name"`
+	Steps []listStep `json:"steps"`
+}
+
+// listStep is a step of runiac list
+type listStep struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Level    int      `json:"level"`
+	Runner   string   `json:"runner"`
+	Regional bool     `json:"regional"`
+	Regions  []string `json:"regions"` // The regions the step deploys to for each deployment ring
+	Tags     []string `json:"tags,omitempty"`
+	Selected bool     `json:"selected"` // The step is executed by a deploy with the same flags
+}
+
+// getListTracks returns the tracks of the graph with the _pretrack first, a step is selected when the whitelist is
+// empty or includes it. No step is selected when the selectors selected none.
+func getListTracks(graph projectGraph, runner string, whitelist []string, anySelected bool) []listTrack {
+	listTracks := []listTrack{}
+
+	for _, t := range graph.Tracks {
+		track := listTrack{Name: t.Name, Steps: []listStep{}}
+
+		for _, s := range t.Steps {
+			track.Steps = append(track.Steps, listStep{
+				ID:       s.ID,
+				Name:     s.Name,
+				Level:    s.Level,
+				Runner:   runner,
+				Regional: s.Regional,
+				Regions:  graph.getStepAnnotations(s),
+				Tags:     s.Tags,
+				Selected: anySelected && (len(whitelist) == 0 || isStringInSlice(s.ID, whitelist)),
+			})
+		}
+
+		if t.Name == tracks.PRE_TRACK_NAME {
+			listTracks = append([]listTrack{track}, listTracks...)
+		} else {
+			listTracks = append(listTracks, track)
+		}
+	}
+
+	return listTracks
+}
+
+// printList prints a row per step of each track in progression order
+func printList(w io.Writer, tracks []listTrack) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "TRACK\tLEVEL\tSTEP\tRUNNER\tREGIONS\tTAGS\tSELECTED")
+
+	for _, t := range tracks {
+		for _, s := range t.Steps {
+			regions := strings.Join(s.Regions, "; ")
+			if regions == "" {
+				regions = "-"
+			}
+
+			tags := strings.Join(s.Tags, ", ")
+			if tags == "" {
+				tags = "-"
+			}
+
+			selected := "no"
+			if s.Selected {
+				selected = "yes"
+			}
+
+			fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\t%s\n", t.Name, s.Level, s.Name, s.Runner, regions, tags, selected)
+		}
+	}
+
+	return tw.Flush()
+}
+
+// printListJSON prints the tracks as indented json
+func printListJSON(w io.Writer, tracks []listTrack) error {
+	b, err := json.MarshalIndent(tracks, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(w, string(b))
+
+	return err
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/optum/runiac/pkg/tracks"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetListTracks_ShouldListStepsWithTheirRegionsAndSelection(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	_ = fs.MkdirAll("tracks/_pretrack/step1_network", 0755)
+	_ = fs.MkdirAll("tracks/core/step1_db/regional", 0755)
+	_ = fs.MkdirAll("tracks/core/step2_api", 0755)
+	_ = fs.MkdirAll("step1_dns", 0755)
+	_ = afero.WriteFile(fs, "tracks/core/step2_api/runiac.yml", []byte("tags: [api]\n"), 0644)
+
+	graph, err := getProjectGraph(fs)
+	require.NoError(t, err)
+
+	graph.Rings = []graphRing{{Name: "dev", Regions: ringRegions{Primary: []string{"us-east-1"}, Regional: []string{"us-east-2"}}}}
+
+	listTracks := getListTracks(graph, "terraform", []string{"core/api"}, true)
+
+	require.Equal(t, []string{tracks.PRE_TRACK_NAME, defaultTrackName, "core"}, []string{listTracks[0].Name, listTracks[1].Name, listTracks[2].Name}, "the _pretrack is listed first")
+	require.Equal(t, listStep{ID: "core/db", Name: "db", Level: 1, Runner: "terraform", Regional: true, Regions: []string{"dev: us-east-1 + regional us-east-2"}, Selected: false}, listTracks[2].Steps[0])
+	require.Equal(t, listStep{ID: "core/api", Name: "api", Level: 2, Runner: "terraform", Regions: []string{"dev: us-east-1"}, Tags: []string{"api"}, Selected: true}, listTracks[2].Steps[1])
+
+	for _, s := range getListTracks(graph, "terraform", nil, true)[2].Steps {
+		require.True(t, s.Selected, "every step is selected without a whitelist")
+	}
+
+	for _, s := range getListTracks(graph, "terraform", nil, false)[2].Steps {
+		require.False(t, s.Selected, "no step is selected when the selectors selected none")
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, printList(&out, listTracks[2:]))
+	require.Equal(t, `TRACK  LEVEL  STEP  RUNNER     REGIONS                              TAGS  SELECTED
+core   1      db    terraform  dev: us-east-1 + regional us-east-2  -     no
+core   2      api   terraform  dev: us-east-1                       api   yes
+`, out.String())
+}
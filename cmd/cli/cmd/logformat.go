@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/optum/runiac/pkg/logging"
+	"github.com/sirupsen/logrus"
+)
+
+// configureLogFormat switches the formatter of runiac's logs to the formatter of the deploy container, json or text
+// (the default). The text is only colored on a terminal.
+func configureLogFormat(format string) error {
+	formatter, err := logging.NewFormatter(format, !isTerminal(os.Stderr))
+	if err != nil {
+		return err
+	}
+
+	logrus.SetFormatter(formatter)
+
+	return nil
+}
@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/optum/runiac/pkg/logging"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigureLogFormat(t *testing.T) {
+	defer logrus.SetFormatter(logrus.StandardLogger().Formatter)
+
+	require.NoError(t, configureLogFormat("json"))
+	require.IsType(t, &logrus.JSONFormatter{}, logrus.StandardLogger().Formatter)
+
+	require.NoError(t, configureLogFormat(""))
+	require.IsType(t, &logging.RuniacFormatter{}, logrus.StandardLogger().Formatter, "the text is formatted like the container's logs")
+
+	require.Error(t, configureLogFormat("yaml"))
+}
+
+func TestGetRunConfigArguments_ShouldForwardLogFormat(t *testing.T) {
+	LogFormat = "json"
+	defer func() { LogFormat = "" }()
+
+	require.Contains(t, getEnvFromArgs(getRunConfigArguments("")), "RUNIAC_LOG_FORMAT=json")
+}
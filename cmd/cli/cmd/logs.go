@@ -0,0 +1,402 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+const (
+	containerLogDir   = ".runiac/logs" // The output of detached containers, one {container id}.log per container
+	containerLogDone  = ".done"        // Marks a container log as complete once the container's output ended
+	maxLogBackups     = 5              // The rotated logs kept with --max-log-size, {container id}.log.1 being the most recent
+	logFollowInterval = 500 * time.Millisecond
+)
+
+var (
+	logsFollow    bool
+	logsFromStart bool
+	logsCapture   bool
+	logsMaxSize   string
+)
+
+// isContainerRunning returns true while the container is running, a variable so tests do not need a container engine
+var isContainerRunning = func(id string) bool {
+	out, err := exec.Command(ContainerEngine, "inspect", "-f", "{{.State.Running}}", id).Output()
+
+	return err == nil && strings.TrimSpace(string(out)) == "true"
+}
+
+func init() {
+	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "Follow the step logs of a run in progress until it completes")
+	logsCmd.Flags().BoolVar(&logsFromStart, "from-start", false, fmt.Sprintf("Replay the container's output from the start from the log in %s, then follow new output", containerLogDir))
+	logsCmd.Flags().StringVar(&ContainerEngine, "container-engine", ContainerEngine, fmt.Sprintf("Container engine (ie. podman or docker). When %s or not set, the first running engine of %s, or of a comma separated list of engines", containerEngineAuto, strings.Join(supportedContainerEngines, ", ")))
+	logsCmd.Flags().StringVar(&logsMaxSize, "max-log-size", "", "Rotate the captured log once it exceeds this size, e.g. 10m")
+	logsCmd.Flags().BoolVar(&logsCapture, "capture", false, fmt.Sprintf("Capture the container's output to %s, used by runiac deploy --detach", containerLogDir))
+	_ = logsCmd.Flags().MarkHidden("capture")
+	_ = logsCmd.Flags().MarkHidden("max-log-size")
+
+	rootCmd.AddCommand(logsCmd)
+}
+
+var logsCmd = &cobra.Command{
+	Use:   "logs [run id | container id] [track/step]",
+	Short: "Show the output of the steps of a run or follow a detached deploy",
+	Long: fmt.Sprintf(`Shows the output of the steps of a run, persisted per step to %s/{run id}/{track}/{step}.log.
+The run id is the correlation id of runiac history and defaults to the most recent run, a {track}/{step}
+only shows the output of that step. With --follow, the output of a run in progress is followed until
+it completes.
+
+Given the id of a container started by runiac deploy --detach, follows the container's output instead.
+With --from-start, the output captured on the host since the container started is replayed first.`, containerLogDir),
+	Args: cobra.MaximumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		if !logsCapture && (len(args) != 1 || isRunLogDir(appFS, args[0])) {
+			runID, step := "", ""
+
+			if len(args) > 0 {
+				runID = args[0]
+			}
+
+			if len(args) > 1 {
+				step = args[1]
+			}
+
+			runDir, err := findRunLogDir(appFS, runID)
+			if err != nil {
+				logrus.WithError(err).Fatal(err)
+			}
+
+			err = followStepLogs(appFS, runDir, step, os.Stdout, func() bool {
+				completed, _ := afero.Exists(appFS, filepath.Join(runDir, containerLogDone))
+
+				return !logsFollow || completed
+			})
+			if err != nil {
+				logrus.WithError(err).Fatal(err)
+			}
+
+			return
+		}
+
+		engine, err := resolveContainerEngine(ContainerEngine)
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		ContainerEngine = engine
+
+		id := args[0]
+
+		if logsCapture {
+			err := captureContainerLogs(appFS, id, logsMaxSize)
+			if err != nil {
+				logrus.WithError(err).Fatal(err)
+			}
+
+			return
+		}
+
+		if !logsFromStart {
+			err := streamContainerLogs(id, os.Stdout, os.Stderr, "--tail", "0")
+			if err != nil {
+				logrus.WithError(err).Fatal(err)
+			}
+
+			return
+		}
+
+		path, err := findContainerLog(appFS, id)
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		err = followContainerLog(appFS, path, os.Stdout, func() bool {
+			exists, _ := afero.Exists(appFS, path+containerLogDone)
+
+			return exists || !isContainerRunning(id)
+		})
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+	},
+}
+
+// getContainerLog returns the path of the host log capturing the container's output
+func getContainerLog(id string) string {
+	return filepath.Join(containerLogDir, id+".log")
+}
+
+// findContainerLog returns the log of the container, matching the full or a shortened container id
+func findContainerLog(fs afero.Fs, id string) (string, error) {
+	matches, err := afero.Glob(fs, filepath.Join(containerLogDir, id+"*.log"))
+	if err != nil {
+		return "", err
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no log captured for container '%s' in %s, the output of containers is only captured when deployed with --detach", id, containerLogDir)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("container id '%s' is ambiguous, it matches %d logs in %s", id, len(matches), containerLogDir)
+	}
+}
+
+// startLogCapture captures the output of the detached container to its host log in the background, surviving
+// this process exiting
+func startLogCapture(id string, maxSize string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	args := []string{"logs", id, "--capture", "--container-engine", ContainerEngine}
+
+	if maxSize != "" {
+		args = append(args, "--max-log-size", maxSize)
+	}
+
+	capture := exec.Command(exe, args...)
+
+	err = capture.Start()
+	if err != nil {
+		return err
+	}
+
+	logrus.Infof("Capturing the output of container %s to %s, replay it with 'runiac logs %s --from-start'", id, getContainerLog(id), id)
+
+	return capture.Process.Release()
+}
+
+// captureContainerLogs writes the container's output to its host log until the container's output ends
+func captureContainerLogs(fs afero.Fs, id string, maxSize string) error {
+	size, err := parseLogSize(maxSize)
+	if err != nil {
+		return err
+	}
+
+	w, err := newRotatingWriter(fs, getContainerLog(id), size)
+	if err != nil {
+		return err
+	}
+
+	err = streamContainerLogs(id, w, w)
+
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+
+	if markErr := afero.WriteFile(fs, getContainerLog(id)+containerLogDone, []byte{}, 0644); err == nil {
+		err = markErr
+	}
+
+	return err
+}
+
+// streamContainerLogs follows the container's output from the container engine
+func streamContainerLogs(id string, stdout io.Writer, stderr io.Writer, args ...string) error {
+	cmd := exec.Command(ContainerEngine, append(append([]string{"logs", "-f"}, args...), id)...)
+
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	return cmd.Run()
+}
+
+// parseLogSize parses a size in bytes with an optional k, m or g suffix, e.g. 10m. An empty size is unlimited.
+func parseLogSize(size string) (int64, error) {
+	if size == "" {
+		return 0, nil
+	}
+
+	if strings.TrimSpace(size) == "" {
+		return 0, fmt.Errorf("invalid --max-log-size '%s', must be a positive size such as 512k, 10m or 1g", size)
+	}
+
+	multipliers := map[string]int64{"k": 1 << 10, "m": 1 << 20, "g": 1 << 30}
+
+	value := strings.ToLower(strings.TrimSpace(size))
+	multiplier := int64(1)
+
+	if m, ok := multipliers[value[len(value)-1:]]; ok && len(value) > 1 {
+		multiplier = m
+		value = value[:len(value)-1]
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid --max-log-size '%s', must be a positive size such as 512k, 10m or 1g", size)
+	}
+
+	return n * multiplier, nil
+}
+
+// rotatingWriter appends to a log, rotating it to {path}.1 when writing would exceed the max size
+type rotatingWriter struct {
+	mu      sync.Mutex
+	fs      afero.Fs
+	path    string
+	maxSize int64
+	size    int64
+	file    afero.File
+}
+
+// newRotatingWriter creates the log at path along with its directory, a max size of 0 never rotates
+func newRotatingWriter(fs afero.Fs, path string, maxSize int64) (*rotatingWriter, error) {
+	err := fs.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := fs.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	return &rotatingWriter{fs: fs, path: path, maxSize: maxSize, size: info.Size(), file: f}, nil
+}
+
+func (w *rotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.maxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.maxSize {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+
+	return n, err
+}
+
+// rotate shifts the rotated logs, dropping the oldest beyond maxLogBackups, and starts a new log
+func (w *rotatingWriter) rotate() error {
+	err := w.file.Close()
+	if err != nil {
+		return err
+	}
+
+	_ = w.fs.Remove(fmt.Sprintf("%s.%d", w.path, maxLogBackups))
+
+	for i := maxLogBackups - 1; i > 0; i-- {
+		_ = w.fs.Rename(fmt.Sprintf("%s.%d", w.path, i), fmt.Sprintf("%s.%d", w.path, i+1))
+	}
+
+	err = w.fs.Rename(w.path, w.path+".1")
+	if err != nil {
+		return err
+	}
+
+	w.file, err = w.fs.OpenFile(w.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	w.size = 0
+
+	return err
+}
+
+func (w *rotatingWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.file.Close()
+}
+
+// getRotatedLogs returns the rotated logs of path that exist, oldest first
+func getRotatedLogs(fs afero.Fs, path string) []string {
+	logs := []string{}
+
+	for i := maxLogBackups; i > 0; i-- {
+		rotated := fmt.Sprintf("%s.%d", path, i)
+
+		if exists, _ := afero.Exists(fs, rotated); exists {
+			logs = append(logs, rotated)
+		}
+	}
+
+	return logs
+}
+
+// followContainerLog replays the rotated logs and the log at path, then follows new output, including across
+// rotations, until done returns true and the output is drained
+func followContainerLog(fs afero.Fs, path string, w io.Writer, done func() bool) error {
+	for _, rotated := range getRotatedLogs(fs, path) {
+		b, err := afero.ReadFile(fs, rotated)
+		if err != nil {
+			return err
+		}
+
+		if _, err = w.Write(b); err != nil {
+			return err
+		}
+	}
+
+	f, err := fs.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { f.Close() }()
+
+	finished := false
+
+	for {
+		if _, err = io.Copy(w, f); err != nil {
+			return err
+		}
+
+		// the writer rotated the log, the rest of the open log is drained before moving on to the new log
+		if rotated, _ := isRotated(fs, path, f); rotated {
+			f.Close()
+
+			f, err = fs.Open(path)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if finished {
+			return nil
+		}
+
+		// drain once more after done so output written while the container exited is not lost
+		finished = done()
+
+		time.Sleep(logFollowInterval)
+	}
+}
+
+// isRotated returns true when the path no longer refers to the open log, always false for filesystems that can not
+// identify files
+func isRotated(fs afero.Fs, path string, f afero.File) (bool, error) {
+	open, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	current, err := fs.Stat(path)
+	if err != nil {
+		return false, err
+	}
+
+	return os.SameFile(current, current) && !os.SameFile(open, current), nil
+}
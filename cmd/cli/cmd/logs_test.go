@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLogSize_ShouldSupportSizeSuffixes(t *testing.T) {
+	tests := map[string]int64{
+		"":     0,
+		"512":  512,
+		"512k": 512 << 10,
+		"10M":  10 << 20,
+		"1g":   1 << 30,
+	}
+
+	for size, expected := range tests {
+		actual, err := parseLogSize(size)
+
+		require.NoError(t, err, "parseLogSize(\"%s\") should succeed", size)
+		require.Equal(t, expected, actual, "parseLogSize(\"%s\")", size)
+	}
+
+	for _, size := range []string{" ", "m", "-1m", "0", "ten"} {
+		_, err := parseLogSize(size)
+		require.Error(t, err, "parseLogSize(\"%s\") should fail", size)
+	}
+}
+
+func TestRotatingWriter_ShouldRotateAndKeepBackups(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	path := getContainerLog("abc123")
+
+	w, err := newRotatingWriter(fs, path, 10)
+	require.NoError(t, err)
+
+	for i := 0; i < maxLogBackups+3; i++ {
+		_, err = w.Write([]byte{byte('a' + i), byte('a' + i), byte('a' + i), byte('a' + i), byte('a' + i), byte('a' + i), '\n'})
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, w.Close())
+
+	current, _ := afero.ReadFile(fs, path)
+	require.Equal(t, "hhhhhh\n", string(current))
+
+	newest, _ := afero.ReadFile(fs, path+".1")
+	require.Equal(t, "gggggg\n", string(newest))
+
+	require.Len(t, getRotatedLogs(fs, path), maxLogBackups, "only maxLogBackups rotated logs should be kept")
+}
+
+func TestFollowContainerLog_ShouldReplayRotatedLogsInOrder(t *testing.T) {
+	fs := afero.NewOsFs()
+	path := filepath.Join(t.TempDir(), "abc123.log")
+
+	w, err := newRotatingWriter(fs, path, 8)
+	require.NoError(t, err)
+
+	for _, line := range []string{"first\n", "second\n", "third\n"} {
+		_, err = w.Write([]byte(line))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, w.Close())
+
+	var out bytes.Buffer
+
+	err = followContainerLog(fs, path, &out, func() bool { return true })
+	require.NoError(t, err)
+	require.Equal(t, "first\nsecond\nthird\n", out.String())
+}
+
+func TestFindContainerLog_ShouldMatchShortenedIds(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	_ = afero.WriteFile(fs, getContainerLog("abc123def"), []byte("output"), 0644)
+	_ = afero.WriteFile(fs, getContainerLog("abd456"), []byte("output"), 0644)
+
+	path, err := findContainerLog(fs, "abc")
+	require.NoError(t, err)
+	require.Equal(t, getContainerLog("abc123def"), path)
+
+	_, err = findContainerLog(fs, "ab")
+	require.Error(t, err, "an ambiguous id should fail")
+
+	_, err = findContainerLog(fs, "fff")
+	require.Error(t, err, "an id without a log should fail")
+}
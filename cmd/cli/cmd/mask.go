@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const maskedValue = "***"
+
+// minMaskedValueLength is the length below which sensitive values are not masked in the output, masking every
+// occurrence of e.g. "1" would garble the output without protecting anything
+const minMaskedValueLength = 4
+
+// sensitiveEnvKey matches environment variable names that conventionally hold secrets
+var sensitiveEnvKey = regexp.MustCompile(`(?i)(SECRET|PASSWORD|PASSWD|TOKEN|CREDENTIAL|PRIVATE|ACCESS_KEY|API_KEY|CLIENT_KEY)`)
+
+// maskPatterns are the compiled mask_patterns of the runiac config, matches are masked in the output of the container
+var maskPatterns []*regexp.Regexp
+
+// isSensitiveEnvKey returns true when the environment variable name likely holds a secret
+func isSensitiveEnvKey(key string) bool {
+	return sensitiveEnvKey.MatchString(key)
+}
+
+// maskEnv masks the value of a KEY=VALUE environment variable when the key is sensitive or the value is a resolved
+// secret
+func maskEnv(env string) string {
+	parts := strings.SplitN(env, "=", 2)
+
+	if len(parts) == 2 && parts[1] != "" && (isSensitiveEnvKey(parts[0]) || secretValues[parts[1]]) {
+		return parts[0] + "=" + maskedValue
+	}
+
+	return env
+}
+
+// getMaskPatterns compiles the mask_patterns of the runiac config, regular expressions matching values to mask in the
+// output of the container in addition to the values of sensitive env variables
+func getMaskPatterns() ([]*regexp.Regexp, error) {
+	patterns := []*regexp.Regexp{}
+
+	for _, p := range viper.GetStringSlice("mask_patterns") {
+		if strings.TrimSpace(p) == "" {
+			continue
+		}
+
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mask pattern '%s': %w", p, err)
+		}
+
+		patterns = append(patterns, re)
+	}
+
+	return patterns, nil
+}
+
+// outputMasker replaces sensitive values and matches of the mask patterns with ***
+type outputMasker struct {
+	values   []string
+	patterns []*regexp.Regexp
+}
+
+// newOutputMasker returns a masker of the values of the sensitive KEY=VALUE env variables, the resolved secrets and
+// the patterns
+func newOutputMasker(env []string, patterns []*regexp.Regexp) *outputMasker {
+	unique := map[string]bool{}
+
+	for v := range secretValues {
+		unique[v] = true
+	}
+
+	for _, e := range env {
+		parts := strings.SplitN(e, "=", 2)
+
+		if len(parts) == 2 && isSensitiveEnvKey(parts[0]) {
+			unique[parts[1]] = true
+		}
+	}
+
+	m := &outputMasker{patterns: patterns}
+
+	for v := range unique {
+		if len(v) >= minMaskedValueLength {
+			m.values = append(m.values, v)
+		}
+	}
+
+	// longest first so a value containing another value is masked entirely
+	sort.Slice(m.values, func(i, j int) bool {
+		if len(m.values[i]) != len(m.values[j]) {
+			return len(m.values[i]) > len(m.values[j])
+		}
+		return m.values[i] < m.values[j]
+	})
+
+	return m
+}
+
+// mask returns s with the sensitive values and matches of the patterns masked
+func (m *outputMasker) mask(s string) string {
+	for _, v := range m.values {
+		s = strings.ReplaceAll(s, v, maskedValue)
+	}
+
+	for _, re := range m.patterns {
+		s = re.ReplaceAllString(s, maskedValue)
+	}
+
+	return s
+}
+
+// maskingFlushDelay is how long output not terminated by a new line is held back for the rest of its line, so a
+// prompt waiting for input is still shown, e.g. an approval or an interactive run
+var maskingFlushDelay = 250 * time.Millisecond
+
+// maskingWriter masks the output written to w line by line, so a value split across writes is still masked. Output
+// not terminated by a new line is written once the writer is flushed or nothing was written for maskingFlushDelay.
+type maskingWriter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	masker  *outputMasker
+	pending []byte
+	timer   *time.Timer // Flushes the pending output once the writes paused
+}
+
+// newMaskingWriter returns a writer masking the output written to w
+func newMaskingWriter(w io.Writer, masker *outputMasker) *maskingWriter {
+	return &maskingWriter{w: w, masker: masker}
+}
+
+func (w *maskingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.pending = append(w.pending, p...)
+
+	var err error
+
+	if i := bytes.LastIndexByte(w.pending, '\n'); i >= 0 {
+		_, err = io.WriteString(w.w, w.masker.mask(string(w.pending[:i+1])))
+		w.pending = append([]byte{}, w.pending[i+1:]...)
+	}
+
+	if len(w.pending) > 0 {
+		if w.timer == nil {
+			w.timer = time.AfterFunc(maskingFlushDelay, func() { _ = w.Flush() })
+		} else {
+			w.timer.Reset(maskingFlushDelay)
+		}
+	}
+
+	return len(p), err
+}
+
+// Flush writes the remaining output not terminated by a new line
+func (w *maskingWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+
+	if len(w.pending) == 0 {
+		return nil
+	}
+
+	_, err := io.WriteString(w.w, w.masker.mask(string(w.pending)))
+	w.pending = nil
+
+	return err
+}
+
+// getMaskingWriters returns the masking writers of the stdout and stderr of a run with the env variables
+func getMaskingWriters(env []string, stdout io.Writer, stderr io.Writer) (*maskingWriter, *maskingWriter) {
+	masker := newOutputMasker(env, maskPatterns)
+
+	return newMaskingWriter(stdout, masker), newMaskingWriter(stderr, masker)
+}
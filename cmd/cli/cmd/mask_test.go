@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"bytes"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaskingWriter_ShouldMaskSensitiveValues(t *testing.T) {
+	secretValues = map[string]bool{"vault-secret": true}
+	defer func() { secretValues = map[string]bool{} }()
+
+	masker := newOutputMasker([]string{
+		"ARM_CLIENT_SECRET=client-secret",
+		"TF_VAR_db_password=hunter2",
+		"TF_VAR_region=centralus",
+		"PULUMI_ACCESS_TOKEN=abc",
+		"RUNIAC_PASSTHROUGH",
+	}, []*regexp.Regexp{regexp.MustCompile(`ghp_[A-Za-z0-9]+`)})
+
+	var buf bytes.Buffer
+	w := newMaskingWriter(&buf, masker)
+
+	// values split across writes are masked once the line completes
+	for _, s := range []string{"client_secret = \"client-se", "cret\"\npassword: hunter2 in centralus\n", "vault-secret ghp_abc123 abc"} {
+		_, err := w.Write([]byte(s))
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, "client_secret = \"***\"\npassword: *** in centralus\n", buf.String())
+
+	require.NoError(t, w.Flush())
+	require.Equal(t, "client_secret = \"***\"\npassword: *** in centralus\n*** *** abc", buf.String(), "values shorter than the minimum length should not be masked")
+}
+
+func TestGetMaskPatterns(t *testing.T) {
+	defer viper.Set("mask_patterns", nil)
+
+	viper.Set("mask_patterns", []string{`ghp_\w+`, " "})
+	patterns, err := getMaskPatterns()
+	require.NoError(t, err)
+	require.Len(t, patterns, 1)
+
+	viper.Set("mask_patterns", []string{"(unclosed"})
+	_, err = getMaskPatterns()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "(unclosed")
+}
+
+func TestMaskingWriter_ShouldFlushPromptsOnceTheWritesPause(t *testing.T) {
+	defer func(delay time.Duration) { maskingFlushDelay = delay }(maskingFlushDelay)
+
+	maskingFlushDelay = 10 * time.Millisecond
+
+	out := &lockedBuffer{}
+	w := newMaskingWriter(out, newOutputMasker([]string{"TF_VAR_db_password=hunter2"}, nil))
+
+	_, err := w.Write([]byte("password: hunt"))
+	require.NoError(t, err)
+	_, err = w.Write([]byte("er2\nTrack network was planned. Deploy it? [y/N]: "))
+	require.NoError(t, err)
+
+	require.Equal(t, "password: ***\n", out.String(), "the prompt is held back for the rest of its line")
+	require.Eventually(t, func() bool {
+		return out.String() == "password: ***\nTrack network was planned. Deploy it? [y/N]: "
+	}, time.Second, time.Millisecond, "the prompt should be written once the writes paused")
+}
+
+// lockedBuffer is a buffer safe to write from the flush of a masking writer while the test reads it
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
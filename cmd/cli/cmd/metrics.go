@@ -0,0 +1,223 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// metricsConfigKeys are the keys of the metrics section of the runiac config
+var metricsConfigKeys = []string{"pushgateway_url", "job"}
+
+// defaultMetricsJob is the pushgateway job the metrics are grouped under unless metrics.job is set
+const defaultMetricsJob = "runiac"
+
+// metricsConfig is the metrics section of the runiac config. The metrics of each ring's run are pushed to the
+// pushgateway, the url is expanded from the environment, e.g. ${PUSHGATEWAY_URL}.
+type metricsConfig struct {
+	PushgatewayURL string `mapstructure:"pushgateway_url"`
+	Job            string `mapstructure:"job"`
+}
+
+// ringMetrics are the metrics of a ring's run
+type ringMetrics struct {
+	Project         string
+	DeploymentRing  string
+	Environment     string
+	Namespace       string
+	Version         string
+	Action          string
+	DryRun          bool
+	Succeeded       bool
+	DurationSeconds float64
+	CompletedAt     time.Time
+	Steps           []stepMetrics
+}
+
+// stepMetrics are the metrics of a step execution in a region, read from the ring's deployment report
+type stepMetrics struct {
+	Track            string  `yaml:"track"`
+	Step             string  `yaml:"step"`
+	RegionDeployType string  `yaml:"region_deploy_type"`
+	Region           string  `yaml:"region"`
+	Status           string  `yaml:"status"`
+	DurationSeconds  float64 `yaml:"duration_seconds"`
+	ResourceChanges  struct {
+		Add     int `yaml:"add"`
+		Change  int `yaml:"change"`
+		Destroy int `yaml:"destroy"`
+	} `yaml:"resource_changes"`
+}
+
+// getMetricsConfig returns the metrics section of the runiac config, an error when it is invalid
+func getMetricsConfig(config *viper.Viper) (metricsConfig, error) {
+	metrics := metricsConfig{}
+
+	if err := config.UnmarshalKey("metrics", &metrics); err != nil {
+		return metrics, fmt.Errorf("invalid metrics configuration: %w", err)
+	}
+
+	if metrics.PushgatewayURL != "" {
+		if _, err := url.Parse(os.ExpandEnv(metrics.PushgatewayURL)); err != nil {
+			return metrics, fmt.Errorf("invalid metrics.pushgateway_url: %w", err)
+		}
+	}
+
+	if metrics.Job == "" {
+		metrics.Job = defaultMetricsJob
+	}
+
+	return metrics, nil
+}
+
+// readStepMetrics returns the step executions of the deployment report at path
+func readStepMetrics(fs afero.Fs, path string) ([]stepMetrics, error) {
+	b, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return nil, err
+	}
+
+	report := struct {
+		Steps []stepMetrics `yaml:"steps"`
+	}{}
+
+	if err = yaml.Unmarshal(b, &report); err != nil {
+		return nil, fmt.Errorf("invalid deployment report %s: %w", path, err)
+	}
+
+	return report.Steps, nil
+}
+
+// writeMetrics writes the metrics of the ring's run in the prometheus text exposition format
+func writeMetrics(w io.Writer, m ringMetrics) error {
+	var b bytes.Buffer
+
+	success := 0
+	if m.Succeeded {
+		success = 1
+	}
+
+	fmt.Fprintln(&b, "# HELP runiac_deploy_info The run of the deployment ring.")
+	fmt.Fprintln(&b, "# TYPE runiac_deploy_info gauge")
+	fmt.Fprintf(&b, "runiac_deploy_info%s 1\n", formatLabels("environment", m.Environment, "version", m.Version, "action", m.Action, "dry_run", fmt.Sprint(m.DryRun)))
+
+	fmt.Fprintln(&b, "# HELP runiac_deploy_success Whether the run of the deployment ring succeeded.")
+	fmt.Fprintln(&b, "# TYPE runiac_deploy_success gauge")
+	fmt.Fprintf(&b, "runiac_deploy_success %d\n", success)
+
+	fmt.Fprintln(&b, "# HELP runiac_deploy_duration_seconds How long the run of the deployment ring took.")
+	fmt.Fprintln(&b, "# TYPE runiac_deploy_duration_seconds gauge")
+	fmt.Fprintf(&b, "runiac_deploy_duration_seconds %g\n", m.DurationSeconds)
+
+	fmt.Fprintln(&b, "# HELP runiac_deploy_last_completion_timestamp_seconds When the run of the deployment ring completed.")
+	fmt.Fprintln(&b, "# TYPE runiac_deploy_last_completion_timestamp_seconds gauge")
+	fmt.Fprintf(&b, "runiac_deploy_last_completion_timestamp_seconds %d\n", m.CompletedAt.Unix())
+
+	statuses := map[string]int{}
+	for _, s := range m.Steps {
+		statuses[strings.ToLower(s.Status)]++
+	}
+
+	fmt.Fprintln(&b, "# HELP runiac_steps_total The step executions of the run by status.")
+	fmt.Fprintln(&b, "# TYPE runiac_steps_total counter")
+
+	keys := []string{}
+	for status := range statuses {
+		keys = append(keys, status)
+	}
+
+	sort.Strings(keys)
+
+	for _, status := range keys {
+		fmt.Fprintf(&b, "runiac_steps_total%s %d\n", formatLabels("status", status), statuses[status])
+	}
+
+	fmt.Fprintln(&b, "# HELP runiac_step_duration_seconds How long the step execution took.")
+	fmt.Fprintln(&b, "# TYPE runiac_step_duration_seconds gauge")
+
+	for _, s := range m.Steps {
+		fmt.Fprintf(&b, "runiac_step_duration_seconds%s %g\n", s.formatLabels("status", strings.ToLower(s.Status)), s.DurationSeconds)
+	}
+
+	fmt.Fprintln(&b, "# HELP runiac_step_resource_changes The resources the step execution planned to change by action.")
+	fmt.Fprintln(&b, "# TYPE runiac_step_resource_changes gauge")
+
+	for _, s := range m.Steps {
+		fmt.Fprintf(&b, "runiac_step_resource_changes%s %d\n", s.formatLabels("action", "add"), s.ResourceChanges.Add)
+		fmt.Fprintf(&b, "runiac_step_resource_changes%s %d\n", s.formatLabels("action", "change"), s.ResourceChanges.Change)
+		fmt.Fprintf(&b, "runiac_step_resource_changes%s %d\n", s.formatLabels("action", "destroy"), s.ResourceChanges.Destroy)
+	}
+
+	_, err := w.Write(b.Bytes())
+
+	return err
+}
+
+// formatLabels returns the labels of a step execution sample followed by the additional name and value pairs
+func (s stepMetrics) formatLabels(pairs ...string) string {
+	return formatLabels(append([]string{"track", s.Track, "step", s.Step, "region_deploy_type", s.RegionDeployType, "region", s.Region}, pairs...)...)
+}
+
+// formatLabels returns the label set of the name and value pairs, escaping the values
+func formatLabels(pairs ...string) string {
+	labels := []string{}
+	escaper := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+	for i := 0; i+1 < len(pairs); i += 2 {
+		labels = append(labels, fmt.Sprintf(`%s="%s"`, pairs[i], escaper.Replace(pairs[i+1])))
+	}
+
+	return "{" + strings.Join(labels, ",") + "}"
+}
+
+// getPushgatewayURL returns the url of the pushgateway group of the ring's metrics, grouped by job, project, ring
+// and namespace so each ring's latest run replaces its previous metrics
+func getPushgatewayURL(config metricsConfig, m ringMetrics) string {
+	u := strings.TrimSuffix(os.ExpandEnv(config.PushgatewayURL), "/") + "/metrics/job/" + url.PathEscape(config.Job)
+
+	for _, label := range [][]string{{"project", m.Project}, {"deployment_ring", m.DeploymentRing}, {"namespace", m.Namespace}} {
+		if label[1] != "" {
+			u += fmt.Sprintf("/%s/%s", label[0], url.PathEscape(label[1]))
+		}
+	}
+
+	return u
+}
+
+// pushMetrics replaces the ring's metrics group of the pushgateway with the metrics of its run
+func pushMetrics(client *http.Client, config metricsConfig, m ringMetrics) error {
+	var b bytes.Buffer
+
+	if err := writeMetrics(&b, m); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPut, getPushgatewayURL(config, m), &b)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("pushgateway returned %s", resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+const stubMetricsReport = `{"deployment_ring": "prod", "steps": [
+	{"track": "network", "step": "vpc", "region_deploy_type": "primary", "region": "us-east-1", "status": "SUCCESS", "duration_seconds": 42.5,
+	 "resource_changes": {"add": 2, "change": 1, "destroy": 0}},
+	{"track": "storage", "step": "bucket", "region_deploy_type": "primary", "region": "us-east-1", "status": "FAIL", "duration_seconds": 3}
+]}`
+
+func TestWriteMetrics_ShouldWriteTheTextExpositionFormat(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	_ = afero.WriteFile(fs, ".runiac/reports/prod.json", []byte(stubMetricsReport), 0644)
+
+	steps, err := readStepMetrics(fs, ".runiac/reports/prod.json")
+	require.NoError(t, err)
+	require.Len(t, steps, 2)
+
+	var b bytes.Buffer
+	require.NoError(t, writeMetrics(&b, ringMetrics{
+		Environment:     "production",
+		Version:         `1.2.3"rc`,
+		Action:          "deploy",
+		DurationSeconds: 61.2,
+		CompletedAt:     time.Unix(1767225600, 0),
+		Steps:           steps,
+	}))
+
+	for _, expected := range []string{
+		`runiac_deploy_info{environment="production",version="1.2.3\"rc",action="deploy",dry_run="false"} 1`,
+		"runiac_deploy_success 0",
+		"runiac_deploy_duration_seconds 61.2",
+		"runiac_deploy_last_completion_timestamp_seconds 1767225600",
+		"# TYPE runiac_steps_total counter\nruniac_steps_total{status=\"fail\"} 1\nruniac_steps_total{status=\"success\"} 1\n",
+		`runiac_step_duration_seconds{track="network",step="vpc",region_deploy_type="primary",region="us-east-1",status="success"} 42.5`,
+		`runiac_step_resource_changes{track="network",step="vpc",region_deploy_type="primary",region="us-east-1",action="add"} 2`,
+		`runiac_step_resource_changes{track="storage",step="bucket",region_deploy_type="primary",region="us-east-1",action="destroy"} 0`,
+	} {
+		require.Contains(t, b.String(), expected)
+	}
+}
+
+func TestPushMetrics_ShouldReplaceTheRingGroup(t *testing.T) {
+	var method, path, body string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		method, path, body = r.Method, r.URL.EscapedPath(), string(b)
+	}))
+	defer server.Close()
+
+	config := viper.New()
+	config.SetConfigType("yaml")
+	require.NoError(t, config.ReadConfig(strings.NewReader("metrics:\n  pushgateway_url: "+server.URL+"/\n")))
+
+	metrics, err := getMetricsConfig(config)
+	require.NoError(t, err)
+	require.Equal(t, defaultMetricsJob, metrics.Job)
+
+	err = pushMetrics(server.Client(), metrics, ringMetrics{Project: "stub", DeploymentRing: "prod", Succeeded: true})
+	require.NoError(t, err)
+
+	require.Equal(t, http.MethodPut, method)
+	require.Equal(t, "/metrics/job/runiac/project/stub/deployment_ring/prod", path, "metrics without a namespace are not grouped by namespace")
+	require.Contains(t, body, "runiac_deploy_success 1")
+
+	require.Equal(t, server.URL+"/metrics/job/runiac/project/stub/deployment_ring/pr/namespace/feature%2F42",
+		getPushgatewayURL(metrics, ringMetrics{Project: "stub", DeploymentRing: "pr", Namespace: "feature/42"}))
+}
@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultNamespaceClaimTTL = 6 * time.Hour    // How long a claim is honored when none is configured, so crashed runs do not hold a namespace forever
+	registryLockTimeout      = 30 * time.Second // How long to wait for another run to finish updating the registry
+	registryLockStale        = time.Minute      // A lock older than this was left behind by a run that died while updating the registry
+	registryLockInterval     = 250 * time.Millisecond
+)
+
+// namespaceClaim records the run actively deploying a namespace in the namespace registry
+type namespaceClaim struct {
+	Owner         string    `json:"owner"`
+	CorrelationID string    `json:"correlation_id"`
+	ClaimedAt     time.Time `json:"claimed_at"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+// getNamespaceClaimKey returns the registry key of the deployment's namespace, e.g. project/dev/pr/1234
+func getNamespaceClaimKey(project string, environment string, ring string, namespace string) string {
+	return strings.ToLower(strings.Join([]string{project, environment, ring, namespace}, "/"))
+}
+
+// getNamespaceOwner identifies the user and machine claiming a namespace
+func getNamespaceOwner() string {
+	user, _ := getMachineName()
+	host, _ := os.Hostname()
+
+	return fmt.Sprintf("%s@%s", user, host)
+}
+
+// getNamespaceClaimTTL returns how long claims are honored, namespace_claim_ttl in the runiac config
+func getNamespaceClaimTTL() (time.Duration, error) {
+	ttl := viper.GetString("namespace_claim_ttl")
+	if ttl == "" {
+		return defaultNamespaceClaimTTL, nil
+	}
+
+	d, err := time.ParseDuration(ttl)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid namespace_claim_ttl '%s', must be a positive duration such as 6h", ttl)
+	}
+
+	return d, nil
+}
+
+// claimRunNamespace claims the key in the registry for the run, returning the func releasing the claim. Dry runs do
+// not change infrastructure, they only warn about a conflicting claim.
+func claimRunNamespace(fs afero.Fs, registry string, key string, correlationID string, ttl time.Duration, dryRun bool) (func(), error) {
+	now := time.Now().UTC()
+	claim := namespaceClaim{Owner: getNamespaceOwner(), CorrelationID: correlationID, ClaimedAt: now, ExpiresAt: now.Add(ttl)}
+
+	if dryRun {
+		if err := getNamespaceConflict(fs, registry, key, claim); err != nil {
+			logrus.WithError(err).Warn("Continuing as this is a dry run")
+		}
+
+		return func() {}, nil
+	}
+
+	err := claimNamespace(fs, registry, key, claim)
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.Infof("Claimed namespace '%s' in %s until %s", key, registry, claim.ExpiresAt.Format(time.RFC3339))
+
+	return func() {
+		if err := releaseNamespace(fs, registry, key, correlationID); err != nil {
+			logrus.WithError(err).Warnf("Unable to release namespace '%s' in %s, it is released when the claim expires", key, registry)
+		}
+	}, nil
+}
+
+// isConflictingClaim returns true when the existing claim is held by another run than the claim's until after the
+// claim is made. Another run of the same owner conflicts as well, e.g. a concurrent deploy on the same machine, only
+// the run holding the claim renews it.
+func isConflictingClaim(existing namespaceClaim, claim namespaceClaim) bool {
+	return existing.ExpiresAt.After(claim.ClaimedAt) && existing.CorrelationID != claim.CorrelationID
+}
+
+// claimNamespace claims the key in the registry, failing when another run holds an unexpired claim
+func claimNamespace(fs afero.Fs, registry string, key string, claim namespaceClaim) error {
+	return updateNamespaceRegistry(fs, registry, func(claims map[string]namespaceClaim) error {
+		if existing, ok := claims[key]; ok && isConflictingClaim(existing, claim) {
+			return fmt.Errorf("namespace '%s' is in use by %s since %s (correlation id %s), choose another namespace or wait until the claim expires at %s",
+				key, existing.Owner, existing.ClaimedAt.Format(time.RFC3339), existing.CorrelationID, existing.ExpiresAt.Format(time.RFC3339))
+		}
+
+		claims[key] = claim
+
+		return nil
+	})
+}
+
+// releaseNamespace removes the claim of the key when it is still held by the run
+func releaseNamespace(fs afero.Fs, registry string, key string, correlationID string) error {
+	return updateNamespaceRegistry(fs, registry, func(claims map[string]namespaceClaim) error {
+		if existing, ok := claims[key]; ok && existing.CorrelationID == correlationID {
+			delete(claims, key)
+		}
+
+		return nil
+	})
+}
+
+// getNamespaceConflict returns the error claiming the key would fail with without claiming it
+func getNamespaceConflict(fs afero.Fs, registry string, key string, claim namespaceClaim) error {
+	claims, err := readNamespaceRegistry(fs, registry)
+	if err != nil {
+		return err
+	}
+
+	if existing, ok := claims[key]; ok && isConflictingClaim(existing, claim) {
+		return fmt.Errorf("namespace '%s' is in use by %s since %s (correlation id %s)", key, existing.Owner, existing.ClaimedAt.Format(time.RFC3339), existing.CorrelationID)
+	}
+
+	return nil
+}
+
+// updateNamespaceRegistry applies update to the registry's claims while holding the registry's lock, writing the
+// claims back only when update succeeds
+func updateNamespaceRegistry(fs afero.Fs, registry string, update func(claims map[string]namespaceClaim) error) error {
+	unlock, err := lockNamespaceRegistry(fs, registry)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	claims, err := readNamespaceRegistry(fs, registry)
+	if err != nil {
+		return err
+	}
+
+	err = update(claims)
+	if err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(claims, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// write then rename so readers never observe a partially written registry
+	tmp := registry + ".tmp"
+
+	err = afero.WriteFile(fs, tmp, b, 0644)
+	if err != nil {
+		return err
+	}
+
+	return fs.Rename(tmp, registry)
+}
+
+// readNamespaceRegistry returns the claims of the registry, none when it does not exist yet
+func readNamespaceRegistry(fs afero.Fs, registry string) (map[string]namespaceClaim, error) {
+	claims := map[string]namespaceClaim{}
+
+	b, err := afero.ReadFile(fs, registry)
+	if os.IsNotExist(err) {
+		return claims, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(strings.TrimSpace(string(b))) == 0 {
+		return claims, nil
+	}
+
+	err = json.Unmarshal(b, &claims)
+	if err != nil {
+		return nil, fmt.Errorf("namespace registry %s is invalid: %w", registry, err)
+	}
+
+	return claims, nil
+}
+
+// lockNamespaceRegistry exclusively creates the registry's lock file, removing locks left behind by dead runs
+func lockNamespaceRegistry(fs afero.Fs, registry string) (func(), error) {
+	lock := registry + ".lock"
+	deadline := time.Now().Add(registryLockTimeout)
+
+	for {
+		f, err := fs.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			f.Close()
+
+			return func() {
+				if err := fs.Remove(lock); err != nil {
+					logrus.WithError(err).Warnf("Unable to unlock the namespace registry %s", registry)
+				}
+			}, nil
+		}
+
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		if info, statErr := fs.Stat(lock); statErr == nil && time.Since(info.ModTime()) > registryLockStale {
+			logrus.Warnf("Removing the stale namespace registry lock %s", lock)
+			_ = fs.Remove(lock)
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for the namespace registry lock %s: %w", lock, err)
+		}
+
+		time.Sleep(registryLockInterval)
+	}
+}
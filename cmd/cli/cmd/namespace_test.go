@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClaimNamespace_ShouldFailWhileAnotherOwnerHoldsTheNamespace(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	registry := "/shared/namespaces.json"
+	key := getNamespaceClaimKey("runiac", "dev", "pr", "1234")
+	now := time.Now().UTC()
+
+	alice := namespaceClaim{Owner: "alice@laptop", CorrelationID: "a", ClaimedAt: now, ExpiresAt: now.Add(time.Hour)}
+	bob := namespaceClaim{Owner: "bob@ci", CorrelationID: "b", ClaimedAt: now, ExpiresAt: now.Add(time.Hour)}
+
+	require.NoError(t, claimNamespace(fs, registry, key, alice))
+	require.Error(t, claimNamespace(fs, registry, key, bob), "a namespace claimed by another owner should fail")
+	require.Error(t, getNamespaceConflict(fs, registry, key, bob))
+
+	// another run of the same owner conflicts, only the run holding the claim renews it
+	require.Error(t, claimNamespace(fs, registry, key, namespaceClaim{Owner: "alice@laptop", CorrelationID: "a2", ClaimedAt: now, ExpiresAt: now.Add(time.Hour)}))
+	require.NoError(t, claimNamespace(fs, registry, key, namespaceClaim{Owner: "alice@laptop", CorrelationID: "a", ClaimedAt: now, ExpiresAt: now.Add(2 * time.Hour)}))
+
+	// other namespaces are not affected
+	require.NoError(t, claimNamespace(fs, registry, getNamespaceClaimKey("runiac", "dev", "pr", "5678"), bob))
+
+	// only the run holding the claim releases it
+	require.NoError(t, releaseNamespace(fs, registry, key, "a2"))
+	require.Error(t, claimNamespace(fs, registry, key, bob))
+
+	require.NoError(t, releaseNamespace(fs, registry, key, "a"))
+	require.NoError(t, claimNamespace(fs, registry, key, bob))
+
+	exists, _ := afero.Exists(fs, registry+".lock")
+	require.False(t, exists, "the registry should be unlocked")
+}
+
+func TestClaimNamespace_ShouldIgnoreExpiredClaims(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	registry := "namespaces.json"
+	key := getNamespaceClaimKey("runiac", "dev", "local", "alice")
+	now := time.Now().UTC()
+
+	require.NoError(t, claimNamespace(fs, registry, key, namespaceClaim{Owner: "alice@laptop", ClaimedAt: now.Add(-2 * time.Hour), ExpiresAt: now.Add(-time.Hour)}))
+	require.NoError(t, claimNamespace(fs, registry, key, namespaceClaim{Owner: "bob@ci", ClaimedAt: now, ExpiresAt: now.Add(time.Hour)}))
+}
+
+func TestLockNamespaceRegistry_ShouldRemoveStaleLocks(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	registry := "namespaces.json"
+
+	_ = afero.WriteFile(fs, registry+".lock", []byte{}, 0644)
+	_ = fs.Chtimes(registry+".lock", time.Now().Add(-2*registryLockStale), time.Now().Add(-2*registryLockStale))
+
+	unlock, err := lockNamespaceRegistry(fs, registry)
+	require.NoError(t, err)
+	unlock()
+}
@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// nativeRunnerBinaries are the executables each runner requires on the host's PATH in --native mode
+var nativeRunnerBinaries = map[string]string{
+	"terraform":  "terraform",
+	"arm":        "az",
+	"pulumi":     "pulumi",
+	"terragrunt": "terragrunt",
+}
+
+// externalRunnerPrefix prefixes the executables of external runner plugins
+const externalRunnerPrefix = "runiac-runner-"
+
+// validateNativeMode returns an error when --native is combined with options that only apply to a container, when
+// the deploy executor or the runner's executable are not on the PATH or when a step keeps its state in the
+// container's local state directory, which is not mounted on the host
+func validateNativeMode(executor string, runner string) error {
+	conflicts := []struct {
+		flag string
+		set  bool
+	}{
+		{"--context", BuildContext != ""},
+		{"--image", Image != ""},
+		{"--detach", Detach},
+		{"--restart", RestartPolicy != ""},
+		{"--pid", PidMode != ""},
+		{"--ipc", IpcMode != ""},
+		{"--docker-socket", DockerSocket != ""},
+		{"--mount-docker-socket", MountDocker},
+		{"--mount-credentials", len(MountCredentials) > 0},
+		{"--credential-tokens", CredentialTokens},
+		{"--validate-config-against-container", ValidateContract},
+		{"--strict-contract", StrictContract},
+		{"--verify-signature", VerifySignature},
+		{"--container-digest", ContainerDigest != ""},
+	}
+
+	for _, c := range conflicts {
+		if c.set {
+			return fmt.Errorf("%s can not be used with --native, the steps execute on the host instead of in a container", c.flag)
+		}
+	}
+
+	if _, err := lookPath(executor); err != nil {
+		return fmt.Errorf("the deploy executor '%s' is not on the path, install it from the runiac release archive, build it with 'go build -o %s ./cmd/runiac' or set --native-executor", executor, executor)
+	}
+
+	binary, ok := nativeRunnerBinaries[runner]
+	if !ok {
+		binary = externalRunnerPrefix + runner
+	}
+
+	if _, err := lookPath(binary); err != nil {
+		return fmt.Errorf("the %s runner requires '%s' on the path in --native mode", runner, binary)
+	}
+
+	steps, err := getLocalStateSteps(appFS)
+	if err != nil {
+		return err
+	}
+
+	// the path is part of the step's configuration, natively it would be the root of the host's filesystem
+	if len(steps) > 0 {
+		return fmt.Errorf("--native can not deploy the steps %s, they keep their state in the container's %s, configure a remote backend for them or deploy them in the container", strings.Join(steps, ", "), containerTFState)
+	}
+
+	return nil
+}
+
+// getNativeEnv returns the env variables of the container run arguments, rewriting values referencing a mounted
+// container path to the path on the host
+func getNativeEnv(runArgs []string) []string {
+	mounts := map[string]string{}
+
+	for _, m := range getMountsFromArgs(runArgs) {
+		source, target := parseVolumeMap(m)
+		mounts[target] = source
+	}
+
+	// longest targets first so nested mounts are rewritten to their own source
+	targets := make([]string, 0, len(mounts))
+	for target := range mounts {
+		targets = append(targets, target)
+	}
+
+	sort.Slice(targets, func(i, j int) bool { return len(targets[i]) > len(targets[j]) })
+
+	env := []string{}
+
+	for _, e := range getEnvFromArgs(runArgs) {
+		kv := strings.SplitN(e, "=", 2)
+
+		if len(kv) == 2 {
+			for _, target := range targets {
+				if kv[1] == target || strings.HasPrefix(kv[1], target+"/") {
+					e = fmt.Sprintf("%s=%s%s", kv[0], mounts[target], strings.TrimPrefix(kv[1], target))
+					break
+				}
+			}
+		}
+
+		env = append(env, e)
+	}
+
+	return env
+}
+
+// parseVolumeMap splits a {host}:{container}[:ro] volume map, the host path may contain a windows drive letter
+func parseVolumeMap(volume string) (source string, target string) {
+	volume = strings.TrimSuffix(volume, ":ro")
+
+	i := strings.LastIndex(volume, ":")
+	if i < 0 {
+		return volume, volume
+	}
+
+	return volume[:i], volume[i+1:]
+}
+
+// getNativeStateArguments returns the volume map of the ring's local state directory, so env variables referencing
+// the container's state directory, e.g. the recorded step outputs, are rewritten to the local state directory
+func getNativeStateArguments(ring string) ([]string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	stateDir, err := prepareRingStateDir(appFS, ring, MigrateStateTo)
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{"-v", fmt.Sprintf("%s:%s", filepath.Join(dir, stateDir), containerTFState)}, nil
+}
+
+// runNative executes the deploy executor in the working directory with the env variables the container would
+// receive, the steps run the runner's executable installed on the host
+func runNative(executor string, runArgs []string, recordPath string) error {
+	cmd := exec.Command(executor)
+
+	cmd.Env = append(os.Environ(), getNativeEnv(runArgs)...)
+
+	logrus.Infof("Running %s natively in the working directory", executor)
+
+	terminalOut, terminalErr := getTerminalWriters()
+
+	var stdout, stderr io.Writer = terminalOut, terminalErr
+	cmd.Stdin = os.Stdin
+
+	var recorder *sessionRecorder
+
+	if recordPath != "" {
+		var err error
+
+		recorder, err = newSessionRecorder(recordPath, cmd.Args)
+		if err != nil {
+			return fmt.Errorf("unable to record session to %s: %w", recordPath, err)
+		}
+
+		logrus.Infof("Recording session to %s", recordPath)
+
+		stdout = io.MultiWriter(terminalOut, recorder)
+		stderr = io.MultiWriter(terminalErr, recorder)
+	}
+
+	// the steps inherit the host's environment, so its sensitive values are masked as well
+	maskedStdout, maskedStderr := getMaskingWriters(cmd.Env, stdout, stderr)
+	cmd.Stdout, cmd.Stderr = maskedStdout, maskedStderr
+
+	err := runCommand(cmd)
+
+	_ = maskedStdout.Flush()
+	_ = maskedStderr.Flush()
+
+	if recorder != nil {
+		if err := recorder.Close(err); err != nil {
+			logrus.WithError(err).Warnf("Unable to finish recording session to %s", recordPath)
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetNativeEnv_ShouldRewriteMountedContainerPaths(t *testing.T) {
+	runArgs := []string{
+		"-e", "RUNIAC_ENVIRONMENT=dev",
+		"-v", "/home/stub/out:/runiac/output",
+		"-e", "RUNIAC_OUTPUT_DIR=/runiac/output",
+		"-v", "/home/stub/plans:/runiac/artifacts:ro",
+		"-e", "RUNIAC_ARTIFACTS_FROM=/runiac/artifacts",
+		"-v", "C:/stub/reports:/runiac/sarif",
+		"-e", "RUNIAC_SARIF_PATH=/runiac/sarif/report.sarif",
+		"-e", "RUNIAC_NAMESPACE=/runiac/outputs",
+	}
+
+	require.Equal(t, []string{
+		"RUNIAC_ENVIRONMENT=dev",
+		"RUNIAC_OUTPUT_DIR=/home/stub/out",
+		"RUNIAC_ARTIFACTS_FROM=/home/stub/plans",
+		"RUNIAC_SARIF_PATH=C:/stub/reports/report.sarif",
+		"RUNIAC_NAMESPACE=/runiac/outputs",
+	}, getNativeEnv(runArgs))
+}
+
+func TestValidateNativeMode_ShouldRejectContainerOptions(t *testing.T) {
+	defer func(fs afero.Fs) {
+		appFS = fs
+		lookPath = exec.LookPath
+		Detach, DockerSocket, Image = false, "", ""
+	}(appFS)
+
+	appFS = afero.NewMemMapFs()
+	_ = afero.WriteFile(appFS, "step1_vpc/backend.tf", []byte(`terraform { backend "s3" {} }`), 0644)
+
+	available := map[string]bool{"runiac-deploy": true, "terraform": true}
+	lookPath = func(file string) (string, error) {
+		if available[file] {
+			return "/usr/bin/" + file, nil
+		}
+		return "", errors.New("not found")
+	}
+
+	require.NoError(t, validateNativeMode("runiac-deploy", "terraform"))
+
+	err := validateNativeMode("runiac-deploy", "pulumi")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "'pulumi'")
+
+	err = validateNativeMode("deploy", "terraform")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "--native-executor")
+
+	Detach = true
+	err = validateNativeMode("runiac-deploy", "terraform")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "--detach")
+
+	Detach, DockerSocket = false, dockerSocket
+	err = validateNativeMode("runiac-deploy", "terraform")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "--docker-socket")
+
+	DockerSocket, Image = "", "registry.stub/project:1.0.0"
+	err = validateNativeMode("runiac-deploy", "terraform")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "--image")
+
+	Image = ""
+	_ = afero.WriteFile(appFS, "step2_dns/backend.tf", []byte("terraform {\n  backend \"local\" {\n    workspace_dir = \"/runiac/tfstate\"\n  }\n}\n"), 0644)
+	require.EqualError(t, validateNativeMode("runiac-deploy", "terraform"), "--native can not deploy the steps default/dns, they keep their state in the container's /runiac/tfstate, configure a remote backend for them or deploy them in the container")
+}
@@ -19,11 +19,13 @@ import (
 
 var templateUrl string
 var scm string
+var configFormat string
 var runiacGitHubOrg = "github.com/runiac"
 
 func init() {
 	newCmd.Flags().StringVarP(&templateUrl, "url", "", "", "URL to download project template")
 	newCmd.Flags().StringVarP(&scm, "scm", "", "git", "Default initializes git, set to None to prevent this")
+	newCmd.Flags().StringVarP(&configFormat, "config-format", "", "yml", "The format of the runiac config of a custom project, yml for runiac.yml or hcl for runiac.hcl")
 
 	rootCmd.AddCommand(newCmd)
 }
@@ -48,6 +50,10 @@ var newCmd = &cobra.Command{
 			return errors.New("too many arguments provided")
 		}
 
+		if configFormat != "yml" && configFormat != "hcl" {
+			return fmt.Errorf("invalid --config-format '%s', must be yml or hcl", configFormat)
+		}
+
 		return nil
 	},
 	Run: func(cmd *cobra.Command, args []string) {
@@ -154,6 +160,13 @@ regional_regions: ${PRIMARY_REGION}
 runner: ${RUNNER}
 `
 
+const runiacConfigHCL = `# Generated by runiac CLI.
+project          = "${PROJECT_NAME}"
+primary_region   = "${PRIMARY_REGION}"
+regional_regions = ["${PRIMARY_REGION}"]
+runner           = "${RUNNER}"
+`
+
 func createSimpleDirectories(name string, fs afero.Fs) error {
 	err := fs.MkdirAll(fmt.Sprintf("%s/step1_initial", name), 0755)
 	if err != nil {
@@ -212,11 +225,16 @@ func initializeGit(name string, fs afero.Fs) error {
 }
 
 func initializeRuniacConfig(projectName string, region string, runner string, fs afero.Fs) (err error) {
-	runiacConfigYml := strings.ReplaceAll(runiacConfig, "${PROJECT_NAME}", projectName)
+	template := runiacConfig
+	if configFormat == "hcl" {
+		template = runiacConfigHCL
+	}
+
+	runiacConfigYml := strings.ReplaceAll(template, "${PROJECT_NAME}", projectName)
 	runiacConfigYml = strings.ReplaceAll(runiacConfigYml, "${PRIMARY_REGION}", region)
 	runiacConfigYml = strings.ReplaceAll(runiacConfigYml, "${RUNNER}", runner)
 
-	err = afero.WriteFile(fs, fmt.Sprintf("%s/runiac.yml", projectName), []byte(runiacConfigYml), 0644)
+	err = afero.WriteFile(fs, fmt.Sprintf("%s/runiac.%s", projectName, getConfigFormatExt(configFormat)), []byte(runiacConfigYml), 0644)
 	if err != nil {
 		return err
 	}
@@ -224,6 +242,15 @@ func initializeRuniacConfig(projectName string, region string, runner string, fs
 	return
 }
 
+// getConfigFormatExt returns the extension of the runiac config file of the --config-format, runiac.yml by default
+func getConfigFormatExt(format string) string {
+	if format == "hcl" {
+		return "hcl"
+	}
+
+	return "yml"
+}
+
 func initializeFiles(projectName string, fs afero.Fs) (err error) {
 	err = afero.WriteFile(fs, fmt.Sprintf("%s/entrypoint.sh", projectName), []byte(entrypointScript), 0744)
 	if err != nil {
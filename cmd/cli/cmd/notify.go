@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	notifyDeployStarted   = "deploy_started"   // A deployment ring's run started
+	notifyStepFailed      = "step_failed"      // A step failed in a region of a deployment ring
+	notifyDeployCompleted = "deploy_completed" // A deployment ring's run completed, successfully or not
+)
+
+// notificationEvents are the events a notification can be sent for
+var notificationEvents = []string{notifyDeployStarted, notifyStepFailed, notifyDeployCompleted}
+
+// notificationTypes are the payload formats of the notification urls
+var notificationTypes = []string{"slack", "teams", "webhook"}
+
+// notificationKeys are the keys of a notification of the notifications section of the runiac config
+var notificationKeys = []string{"type", "url", "events"}
+
+// notification is a url notified of the events of a run, configured under notifications in the runiac config. The
+// url is expanded from the environment so webhook secrets do not need to be committed, e.g. ${SLACK_WEBHOOK_URL}.
+type notification struct {
+	Type   string   `mapstructure:"type"`   // slack, teams or webhook, webhook posts the event as json
+	URL    string   `mapstructure:"url"`    // The incoming webhook url
+	Events []string `mapstructure:"events"` // The events notified, defaults to every event
+}
+
+// notificationEvent is an event of a run
+type notificationEvent struct {
+	Event           string  `json:"event"`
+	Project         string  `json:"project"`
+	DeploymentRing  string  `json:"deployment_ring"`
+	Environment     string  `json:"environment"`
+	Namespace       string  `json:"namespace,omitempty"`
+	Account         string  `json:"account,omitempty"`
+	Version         string  `json:"version"`
+	Action          string  `json:"action"`
+	DryRun          bool    `json:"dry_run"`
+	Result          string  `json:"result,omitempty"`           // The history result of a completed run
+	DurationSeconds float64 `json:"duration_seconds,omitempty"` // How long a completed run took
+	Step            string  `json:"step,omitempty"`             // The track/step of a failed step
+	Region          string  `json:"region,omitempty"`           // The region a step failed in
+	Error           string  `json:"error,omitempty"`
+	Message         string  `json:"message"`
+}
+
+// getNotifications returns the notifications configured under notifications in the runiac config, an error when a
+// notification is invalid
+func getNotifications(config *viper.Viper) ([]notification, error) {
+	notifications := []notification{}
+
+	if err := config.UnmarshalKey("notifications", &notifications); err != nil {
+		return nil, fmt.Errorf("invalid notifications configuration: %w", err)
+	}
+
+	for i, n := range notifications {
+		if !isStringInSlice(n.Type, notificationTypes) {
+			return nil, fmt.Errorf("invalid notifications[%d].type '%s', must be one of %s", i, n.Type, strings.Join(notificationTypes, ", "))
+		}
+
+		if n.URL == "" {
+			return nil, fmt.Errorf("notifications[%d].url is required", i)
+		}
+
+		for _, e := range n.Events {
+			if !isStringInSlice(e, notificationEvents) {
+				return nil, fmt.Errorf("invalid notifications[%d].events '%s', must be one of %s", i, e, strings.Join(notificationEvents, ", "))
+			}
+		}
+	}
+
+	return notifications, nil
+}
+
+// isStringInSlice returns true when s is one of values
+func isStringInSlice(s string, values []string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isNotified returns true when the notification is sent for the event
+func (n notification) isNotified(event string) bool {
+	return len(n.Events) == 0 || isStringInSlice(event, n.Events)
+}
+
+// getMessage describes the event for chat notifications
+func (e notificationEvent) getMessage() string {
+	run := fmt.Sprintf("runiac %s of %s %s to ring %s", e.Action, e.Project, e.Version, e.DeploymentRing)
+
+	if e.Environment != "" {
+		run = fmt.Sprintf("%s (%s)", run, e.Environment)
+	}
+
+	if e.DryRun {
+		run += " [dry run]"
+	}
+
+	switch e.Event {
+	case notifyDeployStarted:
+		return run + " started"
+	case notifyStepFailed:
+		return fmt.Sprintf("%s: step %s failed in %s: %s", run, e.Step, e.Region, e.Error)
+	default:
+		return fmt.Sprintf("%s %s after %s", run, e.Result, time.Duration(e.DurationSeconds*float64(time.Second)).Round(time.Second))
+	}
+}
+
+// getNotificationPayload returns the body posted to the url of the notification type for the event
+func getNotificationPayload(notificationType string, e notificationEvent) ([]byte, error) {
+	switch notificationType {
+	case "slack":
+		return json.Marshal(map[string]string{"text": e.Message})
+	case "teams":
+		color := "2EB886"
+		if e.Event == notifyStepFailed || e.Result == historyResultFailed {
+			color = "D00000"
+		}
+
+		return json.Marshal(map[string]string{
+			"@type":      "MessageCard",
+			"@context":   "https://schema.org/extensions",
+			"summary":    e.Message,
+			"themeColor": color,
+			"text":       e.Message,
+		})
+	default:
+		return json.Marshal(e)
+	}
+}
+
+// sendNotification posts the event to the url of the notification
+func sendNotification(client *http.Client, n notification, e notificationEvent) error {
+	b, err := getNotificationPayload(n.Type, e)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Post(os.ExpandEnv(n.URL), "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("notification returned %s", resp.Status)
+	}
+
+	return nil
+}
+
+// notify sends the event to every notification configured for it. Notifying is best effort, a failed notification
+// is logged without failing the run.
+func notify(client *http.Client, notifications []notification, e notificationEvent) {
+	e.Message = e.getMessage()
+
+	for _, n := range notifications {
+		if !n.isNotified(e.Event) {
+			continue
+		}
+
+		if err := sendNotification(client, n, e); err != nil {
+			logrus.WithError(err).Warnf("Unable to send the %s %s notification", n.Type, e.Event)
+		}
+	}
+}
+
+// reportFailedSteps is the subset of a ring's deployment report used to notify of its failed steps
+type reportFailedSteps struct {
+	Steps []struct {
+		Track  string `yaml:"track"`
+		Step   string `yaml:"step"`
+		Region string `yaml:"region"`
+		Status string `yaml:"status"`
+		Error  string `yaml:"error"`
+	} `yaml:"steps"`
+}
+
+// getStepFailedEvents returns a step_failed event for every failed step of the deployment report at path, based on
+// the ring's event
+func getStepFailedEvents(fs afero.Fs, path string, ring notificationEvent) ([]notificationEvent, error) {
+	b, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return nil, err
+	}
+
+	report := reportFailedSteps{}
+
+	if err = yaml.Unmarshal(b, &report); err != nil {
+		return nil, fmt.Errorf("invalid deployment report %s: %w", path, err)
+	}
+
+	events := []notificationEvent{}
+
+	for _, s := range report.Steps {
+		if !strings.EqualFold(s.Status, "fail") {
+			continue
+		}
+
+		e := ring
+		e.Event = notifyStepFailed
+		e.Step = fmt.Sprintf("%s/%s", s.Track, s.Step)
+		e.Region = s.Region
+		e.Error = s.Error
+
+		events = append(events, e)
+	}
+
+	return events, nil
+}
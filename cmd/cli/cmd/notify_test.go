@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetNotifications_ShouldValidateNotifications(t *testing.T) {
+	config := viper.New()
+	config.SetConfigType("yaml")
+	require.NoError(t, config.ReadConfig(strings.NewReader(`
+notifications:
+  - type: slack
+    url: https://hooks.slack.com/services/stub
+  - type: webhook
+    url: https://deploys.example.com/events
+    events: [deploy_completed]
+`)))
+
+	notifications, err := getNotifications(config)
+	require.NoError(t, err)
+	require.Equal(t, []notification{
+		{Type: "slack", URL: "https://hooks.slack.com/services/stub"},
+		{Type: "webhook", URL: "https://deploys.example.com/events", Events: []string{notifyDeployCompleted}},
+	}, notifications)
+
+	require.True(t, notifications[0].isNotified(notifyStepFailed), "every event is notified by default")
+	require.False(t, notifications[1].isNotified(notifyDeployStarted))
+
+	config.Set("notifications", []map[string]interface{}{{"type": "teams"}})
+	_, err = getNotifications(config)
+	require.EqualError(t, err, "notifications[0].url is required")
+
+	config.Set("notifications", []map[string]interface{}{{"type": "teams", "url": "https://outlook.office.com/webhook/stub", "events": []string{"step_started"}}})
+	_, err = getNotifications(config)
+	require.EqualError(t, err, "invalid notifications[0].events 'step_started', must be one of deploy_started, step_failed, deploy_completed")
+}
+
+func TestNotify_ShouldPostEventsInTheFormatOfEachNotification(t *testing.T) {
+	bodies := map[string]map[string]interface{}{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := map[string]interface{}{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		bodies[r.URL.Path] = body
+
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	defer os.Unsetenv("STUB_NOTIFY_URL")
+	os.Setenv("STUB_NOTIFY_URL", server.URL)
+
+	notify(server.Client(), []notification{
+		{Type: "broken", URL: server.URL + "/broken"},
+		{Type: "slack", URL: "${STUB_NOTIFY_URL}/slack"},
+		{Type: "teams", URL: server.URL + "/teams"},
+		{Type: "webhook", URL: server.URL + "/webhook"},
+		{Type: "slack", URL: server.URL + "/started", Events: []string{notifyDeployStarted}},
+	}, notificationEvent{
+		Event:           notifyDeployCompleted,
+		Project:         "stub",
+		DeploymentRing:  "prod",
+		Environment:     "production",
+		Version:         "1.2.3",
+		Action:          "deploy",
+		Result:          historyResultFailed,
+		DurationSeconds: 192.4,
+	})
+
+	message := "runiac deploy of stub 1.2.3 to ring prod (production) failed after 3m12s"
+
+	require.Contains(t, bodies, "/broken", "a failed notification does not stop the others")
+	require.Equal(t, map[string]interface{}{"text": message}, bodies["/slack"])
+	require.Equal(t, "MessageCard", bodies["/teams"]["@type"])
+	require.Equal(t, "D00000", bodies["/teams"]["themeColor"])
+	require.Equal(t, "deploy_completed", bodies["/webhook"]["event"])
+	require.Equal(t, 192.4, bodies["/webhook"]["duration_seconds"])
+	require.Equal(t, message, bodies["/webhook"]["message"])
+	require.NotContains(t, bodies, "/started")
+}
+
+func TestGetStepFailedEvents_ShouldNotifyEveryFailedStep(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	_ = afero.WriteFile(fs, ".runiac/reports/prod.json", []byte(`{"deployment_ring": "prod", "steps": [
+		{"track": "network", "step": "vpc", "region": "us-east-1", "status": "SUCCESS"},
+		{"track": "storage", "step": "bucket", "region": "us-west-2", "status": "FAIL", "error": "Error running terraform apply"}
+	]}`), 0644)
+
+	events, err := getStepFailedEvents(fs, ".runiac/reports/prod.json", notificationEvent{Project: "stub", DeploymentRing: "prod", Version: "1.2.3", Action: "deploy", DryRun: true})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+
+	require.Equal(t, notifyStepFailed, events[0].Event)
+	require.Equal(t, "storage/bucket", events[0].Step)
+	require.Equal(t, "runiac deploy of stub 1.2.3 to ring prod [dry run]: step storage/bucket failed in us-west-2: Error running terraform apply", events[0].getMessage())
+}
@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+const (
+	outputsFile      = "outputs.json"                       // Records the outputs of the deployed steps in the ring's local state directory
+	containerOutputs = containerTFState + "/" + outputsFile // The container records the step outputs here
+	sensitiveOutput  = "<sensitive>"                        // Replaces the value of a sensitive output, as terraform output shows it
+)
+
+// stepOutputs are the outputs of a step execution recorded by the container after each deploy
+type stepOutputs struct {
+	Track            string                 `json:"track"`
+	Step             string                 `json:"step"`
+	RegionDeployType string                 `json:"region_deploy_type"`
+	Region           string                 `json:"region"`
+	AccountID        string                 `json:"account_id"`
+	Environment      string                 `json:"environment"`
+	Namespace        string                 `json:"namespace"`
+	Version          string                 `json:"version"`
+	UpdatedAt        time.Time              `json:"updated_at"`
+	Outputs          map[string]interface{} `json:"outputs"`
+	Sensitive        []string               `json:"sensitive,omitempty"` // The outputs the runner marked sensitive
+}
+
+// outputFilter selects recorded step outputs, empty fields match every step execution
+type outputFilter struct {
+	Track       string
+	Step        string
+	Environment string
+	Namespace   string
+	Region      string
+}
+
+var (
+	outputRing          string
+	outputFilters       outputFilter
+	outputJSON          bool
+	outputShowSensitive bool
+)
+
+func init() {
+	outputCmd.Flags().StringVarP(&outputRing, "deployment-ring", "d", "", "Show the outputs recorded by deploys of this deployment ring")
+	outputCmd.Flags().StringVarP(&outputFilters.Environment, "environment", "e", "", "Only show outputs of deploys targeting this environment")
+	outputCmd.Flags().StringVarP(&outputFilters.Namespace, "namespace", "n", "", "Only show outputs of deploys of this namespace")
+	outputCmd.Flags().StringVar(&outputFilters.Region, "region", "", "Only show outputs of step executions in this region")
+	outputCmd.Flags().BoolVar(&outputJSON, "json", false, "Print the matching step executions and their outputs as json")
+	outputCmd.Flags().BoolVar(&outputShowSensitive, "show-sensitive", false, "Print the values of the outputs marked sensitive instead of masking them")
+
+	rootCmd.AddCommand(outputCmd)
+}
+
+var outputCmd = &cobra.Command{
+	Use:   "output [track[/step]]",
+	Short: "Show the outputs of deployed steps",
+	Long: fmt.Sprintf(`Shows the outputs of the steps deployed from this directory, optionally of a single track or step.
+
+Every deploy records the outputs of its successfully deployed steps in %s of the deployment ring's local state
+directory (%s), whether the steps store their state locally or in a remote backend. Destroys remove the outputs
+of the destroyed steps. The values of sensitive outputs are masked unless --show-sensitive is set.`, outputsFile, localStateDir),
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		filter := outputFilters
+
+		if len(args) > 0 {
+			filter.Track, filter.Step = parseOutputTarget(args[0])
+		}
+
+		stateDir, err := getRingStateDir(outputRing)
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		outputs, err := readStepOutputs(appFS, filepath.Join(stateDir, outputsFile))
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		outputs = filterStepOutputs(outputs, filter)
+
+		if !outputShowSensitive {
+			outputs = maskSensitiveOutputs(outputs)
+		}
+
+		if len(outputs) == 0 {
+			logrus.Warn("No outputs recorded for the matching steps, deploy them first")
+		}
+
+		if outputJSON {
+			err = printStepOutputsJSON(os.Stdout, outputs)
+		} else {
+			err = printStepOutputs(os.Stdout, outputs)
+		}
+
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+	},
+}
+
+// parseOutputTarget splits a track or track/step target
+func parseOutputTarget(target string) (track string, step string) {
+	parts := strings.SplitN(strings.Trim(target, "/"), "/", 2)
+
+	if len(parts) == 2 {
+		return parts[0], parts[1]
+	}
+
+	return parts[0], ""
+}
+
+// readStepOutputs returns the step outputs recorded in path, none when no deploy recorded outputs yet
+func readStepOutputs(fs afero.Fs, path string) ([]stepOutputs, error) {
+	outputs := []stepOutputs{}
+
+	b, err := afero.ReadFile(fs, path)
+	if os.IsNotExist(err) {
+		return outputs, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(b, &outputs); err != nil {
+		return nil, fmt.Errorf("unable to read the step outputs recorded in %s: %w", path, err)
+	}
+
+	return outputs, nil
+}
+
+// filterStepOutputs returns the step outputs matching the filter, ordered by track, step and region
+func filterStepOutputs(outputs []stepOutputs, filter outputFilter) []stepOutputs {
+	matched := []stepOutputs{}
+
+	for _, o := range outputs {
+		if (filter.Track != "" && o.Track != filter.Track) ||
+			(filter.Step != "" && o.Step != filter.Step) ||
+			(filter.Environment != "" && o.Environment != filter.Environment) ||
+			(filter.Namespace != "" && o.Namespace != filter.Namespace) ||
+			(filter.Region != "" && o.Region != filter.Region) {
+			continue
+		}
+
+		matched = append(matched, o)
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		a, b := matched[i], matched[j]
+
+		if a.Track != b.Track {
+			return a.Track < b.Track
+		}
+
+		if a.Step != b.Step {
+			return a.Step < b.Step
+		}
+
+		return a.RegionDeployType+a.Region < b.RegionDeployType+b.Region
+	})
+
+	return matched
+}
+
+// maskSensitiveOutputs returns the step outputs with the values of their sensitive outputs masked, the recorded
+// outputs are not changed
+func maskSensitiveOutputs(outputs []stepOutputs) []stepOutputs {
+	masked := make([]stepOutputs, 0, len(outputs))
+
+	for _, o := range outputs {
+		values := make(map[string]interface{}, len(o.Outputs))
+		for name, value := range o.Outputs {
+			values[name] = value
+		}
+
+		for _, name := range o.Sensitive {
+			if _, ok := values[name]; ok {
+				values[name] = sensitiveOutput
+			}
+		}
+
+		o.Outputs = values
+		masked = append(masked, o)
+	}
+
+	return masked
+}
+
+// printStepOutputs writes a table of every output of the step executions
+func printStepOutputs(w io.Writer, outputs []stepOutputs) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "TRACK\tSTEP\tREGION\tNAMESPACE\tOUTPUT\tVALUE")
+
+	for _, o := range outputs {
+		names := make([]string, 0, len(o.Outputs))
+		for name := range o.Outputs {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		for _, name := range names {
+			fmt.Fprintf(tw, "%s\t%s\t%s/%s\t%s\t%s\t%s\n", o.Track, o.Step, o.RegionDeployType, o.Region, o.Namespace, name, formatOutputValue(o.Outputs[name]))
+		}
+	}
+
+	return tw.Flush()
+}
+
+// formatOutputValue returns strings as is and every other value as json
+func formatOutputValue(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+
+	return string(b)
+}
+
+// printStepOutputsJSON writes the step executions and their outputs as a json array
+func printStepOutputsJSON(w io.Writer, outputs []stepOutputs) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(outputs)
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterStepOutputs_ShouldSelectTargetedSteps(t *testing.T) {
+	memFs := afero.NewMemMapFs()
+	_ = afero.WriteFile(memFs, ".runiac/tfstate/prod/outputs.json", []byte(`[
+		{"track": "network", "step": "vnet", "region_deploy_type": "regional", "region": "eastus", "environment": "prod", "outputs": {"vnet_id": "regional-vnet"}},
+		{"track": "network", "step": "vnet", "region_deploy_type": "primary", "region": "centralus", "environment": "prod", "outputs": {"vnet_id": "primary-vnet", "cidrs": ["10.0.0.0/16"]}},
+		{"track": "network", "step": "dns", "region_deploy_type": "primary", "region": "centralus", "environment": "prod", "outputs": {"zone": "stub.example.com"}},
+		{"track": "app", "step": "web", "region_deploy_type": "primary", "region": "centralus", "environment": "prod", "namespace": "pr-12", "outputs": {"url": "https://stub"}}
+	]`), 0644)
+
+	outputs, err := readStepOutputs(memFs, ".runiac/tfstate/prod/outputs.json")
+	require.NoError(t, err)
+	require.Len(t, outputs, 4)
+
+	track, step := parseOutputTarget("network/vnet")
+	vnet := filterStepOutputs(outputs, outputFilter{Track: track, Step: step})
+	require.Len(t, vnet, 2)
+	require.Equal(t, "centralus", vnet[0].Region, "primary executions sort first")
+
+	track, step = parseOutputTarget("network")
+	require.Len(t, filterStepOutputs(outputs, outputFilter{Track: track, Step: step}), 3)
+	require.Len(t, filterStepOutputs(outputs, outputFilter{Namespace: "pr-12"}), 1)
+	require.Len(t, filterStepOutputs(outputs, outputFilter{Region: "eastus"}), 1)
+
+	var table bytes.Buffer
+	require.NoError(t, printStepOutputs(&table, vnet))
+	require.Contains(t, table.String(), "primary/centralus")
+	require.Contains(t, table.String(), `["10.0.0.0/16"]`)
+	require.Contains(t, table.String(), "regional-vnet")
+
+	missing, err := readStepOutputs(memFs, ".runiac/tfstate/outputs.json")
+	require.NoError(t, err)
+	require.Empty(t, missing)
+}
+
+func TestMaskSensitiveOutputs_ShouldMaskTheValuesOfSensitiveOutputs(t *testing.T) {
+	outputs := []stepOutputs{{
+		Track:     "app",
+		Step:      "db",
+		Outputs:   map[string]interface{}{"host": "stub.example.com", "password": "stub-password"},
+		Sensitive: []string{"password"},
+	}}
+
+	masked := maskSensitiveOutputs(outputs)
+	require.Equal(t, map[string]interface{}{"host": "stub.example.com", "password": sensitiveOutput}, masked[0].Outputs)
+	require.Equal(t, "stub-password", outputs[0].Outputs["password"], "the recorded outputs are not changed")
+
+	var table bytes.Buffer
+	require.NoError(t, printStepOutputs(&table, masked))
+	require.NotContains(t, table.String(), "stub-password")
+
+	var out bytes.Buffer
+	require.NoError(t, printStepOutputsJSON(&out, masked))
+	require.NotContains(t, out.String(), "stub-password")
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// defaultEnvPrefixes are the prefixes of the host environment variables forwarded into the container unless
+// env_passthrough_only is set in the runiac config
+var defaultEnvPrefixes = []string{"TF_VAR_", "ARM_", "RUNIAC_", "AWS_", "PULUMI_", "CLOUDSDK_", "OTEL_", "TRACEPARENT"}
+
+// getEnvPrefixes returns the prefixes of the forwarded host environment variables, the defaults extended by
+// env_passthrough in the runiac config and --env-prefix. A trailing * is optional, e.g. GOOGLE_* or GOOGLE_.
+func getEnvPrefixes() []string {
+	prefixes := []string{}
+
+	if !viper.GetBool("env_passthrough_only") {
+		prefixes = append(prefixes, defaultEnvPrefixes...)
+	}
+
+	for _, prefix := range append(viper.GetStringSlice("env_passthrough"), EnvPrefixes...) {
+		prefix = strings.TrimSuffix(strings.TrimSpace(prefix), "*")
+
+		if prefix != "" {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+
+	return prefixes
+}
+
+// validateEnvPassthrough returns an error when a passthrough prefix would forward every host environment variable or
+// an --env value is not KEY=VALUE
+func validateEnvPassthrough(prefixes []string, env []string) error {
+	for _, prefix := range prefixes {
+		if strings.TrimSuffix(strings.TrimSpace(prefix), "*") == "" {
+			return fmt.Errorf("invalid env passthrough prefix '%s', forwarding every host environment variable is not supported, use --env to set variables explicitly", prefix)
+		}
+	}
+
+	for _, e := range env {
+		kv := strings.SplitN(e, "=", 2)
+
+		if len(kv) != 2 || !validEnvName.MatchString(kv[0]) {
+			return fmt.Errorf("invalid --env '%s', must be KEY=VALUE", e)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"os"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPassthroughEnv_ShouldForwardConfiguredPrefixes(t *testing.T) {
+	for k, v := range map[string]string{
+		"ARM_CLIENT_ID":         "arm",
+		"GOOGLE_PROJECT":        "gcp",
+		"VAULT_ADDR":            "vault",
+		"ACME_TOKEN":            "acme",
+		"TF_VAR_region":         "host",
+		"UNRELATED_PASSTHROUGH": "no",
+	} {
+		_ = os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	defer func() {
+		EnvPrefixes, ExtraEnv = []string{}, []string{}
+		viper.Set("env_passthrough", nil)
+		viper.Set("env_passthrough_only", nil)
+	}()
+
+	tests := []struct {
+		name     string
+		config   []string
+		only     bool
+		prefixes []string
+		extraEnv []string
+		expected []string
+		excluded []string
+	}{
+		{
+			name:     "ShouldForwardDefaultPrefixes",
+			expected: []string{"ARM_CLIENT_ID=arm", "TF_VAR_region=host"},
+			excluded: []string{"GOOGLE_PROJECT=gcp", "VAULT_ADDR=vault", "UNRELATED_PASSTHROUGH=no"},
+		},
+		{
+			name:     "ShouldExtendDefaultsWithConfigAndFlag",
+			config:   []string{"GOOGLE_*", "VAULT_"},
+			prefixes: []string{"ACME_"},
+			expected: []string{"ARM_CLIENT_ID=arm", "GOOGLE_PROJECT=gcp", "VAULT_ADDR=vault", "ACME_TOKEN=acme"},
+			excluded: []string{"UNRELATED_PASSTHROUGH=no"},
+		},
+		{
+			name:     "ShouldOverrideDefaultsWhenOnly",
+			config:   []string{"GOOGLE_*"},
+			only:     true,
+			expected: []string{"GOOGLE_PROJECT=gcp"},
+			excluded: []string{"ARM_CLIENT_ID=arm", "TF_VAR_region=host"},
+		},
+		{
+			name:     "ShouldNotForwardExplicitEnv",
+			extraEnv: []string{"TF_VAR_region=explicit"},
+			expected: []string{"ARM_CLIENT_ID=arm"},
+			excluded: []string{"TF_VAR_region=host"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			viper.Set("env_passthrough", tc.config)
+			viper.Set("env_passthrough_only", tc.only)
+			EnvPrefixes, ExtraEnv = tc.prefixes, tc.extraEnv
+
+			env := getPassthroughEnv()
+
+			for _, e := range tc.expected {
+				require.Contains(t, env, e)
+			}
+
+			for _, e := range tc.excluded {
+				require.NotContains(t, env, e)
+			}
+		})
+	}
+}
+
+func TestGetRunConfigArguments_ShouldSetExplicitEnv(t *testing.T) {
+	ExtraEnv = []string{"VAULT_ADDR=https://vault.example.com", "TF_VAR_region=explicit"}
+	defer func() { ExtraEnv = []string{} }()
+
+	env := getEnvFromArgs(getRunConfigArguments(""))
+
+	require.Contains(t, env, "VAULT_ADDR=https://vault.example.com")
+	require.Contains(t, env, "TF_VAR_region=explicit")
+}
+
+func TestValidateEnvPassthrough(t *testing.T) {
+	require.NoError(t, validateEnvPassthrough([]string{"GOOGLE_*", "VAULT_"}, []string{"KEY=VALUE", "EMPTY="}))
+	require.Error(t, validateEnvPassthrough([]string{"*"}, []string{}))
+	require.Error(t, validateEnvPassthrough([]string{" "}, []string{}))
+	require.Error(t, validateEnvPassthrough([]string{}, []string{"KEY"}))
+	require.Error(t, validateEnvPassthrough([]string{}, []string{"1KEY=VALUE"}))
+}
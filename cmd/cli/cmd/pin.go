@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// containerDigestPattern matches the sha256 digest of an image
+var containerDigestPattern = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
+// getPinnedContainer returns the container pinned to the digest, e.g.
+// docker.io/runiac/deploy:latest-alpine-full@sha256:..., so every build derives from the same image. The tag is kept
+// for readability, the container engine pulls the image of the digest. The container is unchanged without a digest.
+func getPinnedContainer(container string, digest string) (string, error) {
+	if digest == "" {
+		return container, nil
+	}
+
+	if !containerDigestPattern.MatchString(digest) {
+		return "", fmt.Errorf("invalid --container-digest '%s', must be the sha256:{hex} digest of the image, e.g. from '%s image inspect --format \"{{ index .RepoDigests 0 }}\" %s'", digest, ContainerEngine, container)
+	}
+
+	if container == "" {
+		return "", fmt.Errorf("--container-digest requires the --container it pins")
+	}
+
+	if i := strings.LastIndex(container, "@"); i >= 0 {
+		if container[i+1:] != digest {
+			return "", fmt.Errorf("--container %s is already pinned to a different digest than --container-digest %s", container, digest)
+		}
+
+		return container, nil
+	}
+
+	return fmt.Sprintf("%s@%s", container, digest), nil
+}
+
+// getContainerVersionMismatch returns why the deploy container's runiac is incompatible with the CLI, empty when
+// compatible. The major versions must match and the container's runiac may not be a newer minor version than the
+// CLI, whose flags and settings it would not match. A development build or a container that does not report its
+// version can not be verified and is compatible.
+func getContainerVersionMismatch(cliVersion string, containerVersion string) string {
+	cli, ok := parseVersion(cliVersion)
+	if !ok {
+		return ""
+	}
+
+	container, ok := parseVersion(containerVersion)
+	if !ok {
+		return ""
+	}
+
+	switch {
+	case container.Major != cli.Major:
+		return fmt.Sprintf("the container's runiac %s is a different major version than this CLI %s, update the CLI to a v%d release with 'runiac self-update --version' or pin --container-digest to an image of v%d", containerVersion, cliVersion, container.Major, cli.Major)
+	case container.Minor > cli.Minor:
+		return fmt.Sprintf("the container's runiac %s is newer than this CLI %s, update the CLI with 'runiac self-update' or pin --container-digest to an image of v%d.%d", containerVersion, cliVersion, cli.Major, cli.Minor)
+	}
+
+	return ""
+}
+
+// checkContainerVersion returns an error when the container's runiac, reported by its contract, is incompatible
+// with the CLI
+func checkContainerVersion(containerTag string, contract containerContract) error {
+	if mismatch := getContainerVersionMismatch(Version, contract.RuniacVersion); mismatch != "" {
+		return fmt.Errorf("the CLI and the deploy container %s are incompatible: %s", containerTag, mismatch)
+	}
+
+	return nil
+}
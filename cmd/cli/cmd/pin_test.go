@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var stubDigest = "sha256:" + strings.Repeat("ab", 32)
+
+func TestGetPinnedContainer_ShouldPinContainerToDigest(t *testing.T) {
+	tests := map[string]struct {
+		container     string
+		digest        string
+		expected      string
+		expectedError string
+	}{
+		"ShouldKeepContainerWithoutDigest": {container: "docker.io/runiac/deploy:latest-alpine-full", expected: "docker.io/runiac/deploy:latest-alpine-full"},
+		"ShouldAppendDigestToTag":          {container: "docker.io/runiac/deploy:latest-alpine-full", digest: stubDigest, expected: "docker.io/runiac/deploy:latest-alpine-full@" + stubDigest},
+		"ShouldKeepTheSamePinnedDigest":    {container: "localhost:5000/deploy@" + stubDigest, digest: stubDigest, expected: "localhost:5000/deploy@" + stubDigest},
+		"ShouldFailForDifferentDigest":     {container: "runiac/deploy@sha256:" + strings.Repeat("cd", 32), digest: stubDigest, expectedError: "is already pinned to a different digest"},
+		"ShouldFailForInvalidDigest":       {container: "runiac/deploy:latest", digest: "latest", expectedError: "invalid --container-digest 'latest'"},
+		"ShouldFailWithoutContainer":       {digest: stubDigest, expectedError: "--container-digest requires the --container it pins"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			container, err := getPinnedContainer(test.container, test.digest)
+
+			if test.expectedError != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), test.expectedError)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, test.expected, container)
+		})
+	}
+}
+
+func TestGetContainerVersionMismatch_ShouldRequireCompatibleVersion(t *testing.T) {
+	tests := map[string]struct {
+		cli      string
+		runiac   string
+		expected string
+	}{
+		"ShouldBeCompatibleForSameMinor":    {cli: "v1.2.0", runiac: "v1.2.5"},
+		"ShouldBeCompatibleForOlderMinor":   {cli: "v1.3.0", runiac: "v1.1.0"},
+		"ShouldNotVerifyDevelopmentBuild":   {cli: "", runiac: "v2.0.0"},
+		"ShouldNotVerifyUnreportedVersion":  {cli: "v1.2.0", runiac: ""},
+		"ShouldBeIncompatibleForNewerMinor": {cli: "v1.2.0", runiac: "v1.4.0", expected: "the container's runiac v1.4.0 is newer than this CLI v1.2.0, update the CLI with 'runiac self-update' or pin --container-digest to an image of v1.2"},
+		"ShouldBeIncompatibleForOtherMajor": {cli: "v2.1.0", runiac: "v1.9.0", expected: "the container's runiac v1.9.0 is a different major version than this CLI v2.1.0, update the CLI to a v1 release with 'runiac self-update --version' or pin --container-digest to an image of v2"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, test.expected, getContainerVersionMismatch(test.cli, test.runiac))
+		})
+	}
+}
+
+func TestCheckContainerVersion_ShouldFailForIncompatibleContainer(t *testing.T) {
+	defer func(v string) { Version = v }(Version)
+	Version = "v1.2.0"
+
+	require.NoError(t, checkContainerVersion("runiac-project", containerContract{Version: contractVersion, RuniacVersion: "v1.2.3"}))
+
+	err := checkContainerVersion("runiac-project", containerContract{Version: contractVersion, RuniacVersion: "v2.0.0"})
+	require.Error(t, err)
+	require.True(t, strings.HasPrefix(err.Error(), "the CLI and the deploy container runiac-project are incompatible: "), err.Error())
+}
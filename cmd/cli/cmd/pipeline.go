@@ -0,0 +1,278 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/hcl/hcl/ast"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// pipelineRingName matches the deployment rings the pipeline generators write into a pipeline
+var pipelineRingName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// pipelineInstallScript installs the runiac cli of RUNIAC_VERSION, the latest release when empty, in a ci job
+const pipelineInstallScript = `version="${RUNIAC_VERSION:-$(curl -fsSL https://api.github.com/repos/optum/runiac/releases/latest | sed -n 's/.*"tag_name": *"v\{0,1\}\([^"]*\)".*/\1/p')}"
+curl -fsSL "https://github.com/optum/runiac/releases/download/v${version}/runiac_${version}_linux_x86_64.tar.gz" | %star -xz -C /usr/local/bin runiac`
+
+// pipelineRegionArgs sets the positional arguments of a posix shell to the region flags of PRIMARY_REGION and the
+// comma separated REGIONAL_REGIONS, each regional region is passed separately
+const pipelineRegionArgs = `set --
+if [ -n "$PRIMARY_REGION" ]; then set -- "$@" --primary-regions "$PRIMARY_REGION"; fi
+for region in $(echo "$REGIONAL_REGIONS" | tr ',' ' '); do set -- "$@" --regional-regions "$region"; done`
+
+// pipelineOptions are the options of the ci pipeline generators of runiac gen
+type pipelineOptions struct {
+	Rings  []string
+	Branch string
+	Output string
+	Force  bool
+}
+
+// pipelineTarget is a deployment ring the pipeline plans and deploys
+type pipelineTarget struct {
+	Ring            string
+	ID              string // The ring as a job identifier
+	Environment     string
+	PrimaryRegion   string
+	RegionalRegions string // Comma separated
+}
+
+// pipelineGenerator returns the pipeline planning and deploying the targets in order
+type pipelineGenerator func(graph projectGraph, targets []pipelineTarget, branch string) (string, error)
+
+// addPipelineFlags adds the options shared by the pipeline generators to the command
+func addPipelineFlags(cmd *cobra.Command, opts *pipelineOptions, output string) {
+	cmd.Flags().StringSliceVarP(&opts.Rings, "deployment-ring", "d", []string{}, "The deployment rings to deploy, in order. Defaults to the rings of the runiac config in the order they are configured")
+	cmd.Flags().StringVar(&opts.Branch, "branch", "main", "The branch deployed on push, pull requests to it are planned")
+	cmd.Flags().StringVarP(&opts.Output, "output", "o", output, "The pipeline to write")
+	cmd.Flags().BoolVar(&opts.Force, "force", false, "Overwrite an existing pipeline")
+}
+
+// runPipelineGenerator generates the pipeline of the project's rings and writes it to the output
+func runPipelineGenerator(opts pipelineOptions, generate pipelineGenerator) {
+	graph, err := getProjectGraph(appFS)
+	if err != nil {
+		logrus.WithError(err).Fatal(err)
+	}
+
+	rings := opts.Rings
+	if len(rings) == 0 {
+		rings, err = getConfiguredRings(appFS, viper.ConfigFileUsed())
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+	}
+
+	targets, err := getPipelineTargets(rings)
+	if err != nil {
+		logrus.WithError(err).Fatal(err)
+	}
+
+	pipeline, err := generate(graph, targets, opts.Branch)
+	if err != nil {
+		logrus.WithError(err).Fatal(err)
+	}
+
+	err = writeGeneratedPipeline(appFS, opts.Output, pipeline, opts.Force)
+	if err != nil {
+		logrus.WithError(err).Fatal(err)
+	}
+
+	fmt.Printf("Wrote %s\n", opts.Output)
+}
+
+// getGeneratedPipelineHeader marks a pipeline produced by the runiac gen command, which runiac never overwrites
+func getGeneratedPipelineHeader(command string) string {
+	return fmt.Sprintf("# generated by runiac gen %s --- safe to edit, runiac will not overwrite this file", command)
+}
+
+// getConfiguredRings returns the names of the rings of the runiac config file, in the order they are configured
+func getConfiguredRings(fs afero.Fs, configFile string) ([]string, error) {
+	if configFile == "" {
+		return nil, fmt.Errorf("no runiac config found, set --deployment-ring")
+	}
+
+	b, err := afero.ReadFile(fs, configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var rings []string
+
+	if strings.EqualFold(filepath.Ext(configFile), ".hcl") {
+		rings, err = getHCLRings(b)
+	} else {
+		rings, err = getYAMLRings(b)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", configFile, err)
+	}
+
+	if len(rings) == 0 {
+		return nil, fmt.Errorf("no deployment rings in %s, configure rings or set --deployment-ring", configFile)
+	}
+
+	return rings, nil
+}
+
+// getYAMLRings returns the keys of the rings mapping of a yaml runiac config, in the order they are configured
+func getYAMLRings(b []byte) ([]string, error) {
+	doc := yaml.Node{}
+
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+
+	rings := []string{}
+
+	if len(doc.Content) > 0 && doc.Content[0].Kind == yaml.MappingNode {
+		root := doc.Content[0]
+
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			if root.Content[i].Value == "rings" && root.Content[i+1].Kind == yaml.MappingNode {
+				for j := 0; j < len(root.Content[i+1].Content); j += 2 {
+					rings = append(rings, root.Content[i+1].Content[j].Value)
+				}
+			}
+		}
+	}
+
+	return rings, nil
+}
+
+// getHCLRings returns the rings of an hcl runiac config, in the order they are configured. Rings are configured as
+// labeled blocks, rings "prod" { ... }, or the blocks of a rings block, rings { prod { ... } }.
+func getHCLRings(b []byte) ([]string, error) {
+	file, err := hcl.ParseBytes(b)
+	if err != nil {
+		return nil, err
+	}
+
+	list, ok := file.Node.(*ast.ObjectList)
+	if !ok {
+		return nil, nil
+	}
+
+	rings := []string{}
+
+	add := func(key *ast.ObjectKey) {
+		if ring := fmt.Sprint(key.Token.Value()); !isStringInSlice(ring, rings) {
+			rings = append(rings, ring)
+		}
+	}
+
+	for _, item := range list.Filter("rings").Items {
+		if len(item.Keys) > 0 {
+			add(item.Keys[0])
+			continue
+		}
+
+		if obj, ok := item.Val.(*ast.ObjectType); ok {
+			for _, ring := range obj.List.Items {
+				add(ring.Keys[0])
+			}
+		}
+	}
+
+	return rings, nil
+}
+
+// getPipelineTargets returns the rings with the regions they deploy to, the environment is named after the ring
+func getPipelineTargets(rings []string) ([]pipelineTarget, error) {
+	regions, err := resolveRingRegions(rings, getConfigRegions("primary_region"), getConfigRegions("regional_regions"), false, false)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := []pipelineTarget{}
+
+	for _, ring := range rings {
+		if !pipelineRingName.MatchString(ring) {
+			return nil, fmt.Errorf("invalid deployment ring '%s'", ring)
+		}
+
+		t := pipelineTarget{
+			Ring:            ring,
+			ID:              strings.ReplaceAll(ring, "-", "_"),
+			Environment:     ring,
+			RegionalRegions: strings.Join(regions[ring].Regional, ","),
+		}
+
+		if len(regions[ring].Primary) > 0 {
+			t.PrimaryRegion = regions[ring].Primary[0]
+		}
+
+		targets = append(targets, t)
+	}
+
+	return targets, nil
+}
+
+// renderPipeline executes the pipeline template. The expressions of ci systems use the template package's default
+// delimiters, so templates use [[ and ]]. Like helm charts, templates can include a defined template indented with
+// include and indent, passing a dict of values.
+func renderPipeline(name string, text string, data interface{}) (string, error) {
+	tmpl := template.New(name).Delims("[[", "]]")
+
+	tmpl = template.Must(tmpl.Funcs(template.FuncMap{
+		"include": func(name string, data interface{}) (string, error) {
+			var b bytes.Buffer
+			err := tmpl.ExecuteTemplate(&b, name, data)
+			return strings.TrimSuffix(b.String(), "\n"), err
+		},
+		"indent": func(spaces int, s string) string {
+			lines := strings.Split(s, "\n")
+			for i, line := range lines {
+				if line != "" {
+					lines[i] = strings.Repeat(" ", spaces) + line
+				}
+			}
+			return strings.Join(lines, "\n")
+		},
+		"dict": func(kv ...interface{}) map[string]interface{} {
+			d := map[string]interface{}{}
+			for i := 0; i+1 < len(kv); i += 2 {
+				d[fmt.Sprint(kv[i])] = kv[i+1]
+			}
+			return d
+		},
+		"install": func(sudo bool) string {
+			if sudo {
+				return fmt.Sprintf(pipelineInstallScript, "sudo ")
+			}
+			return fmt.Sprintf(pipelineInstallScript, "")
+		},
+		"regionArgs": func() string { return pipelineRegionArgs },
+	}).Parse(text))
+
+	var b bytes.Buffer
+
+	err := tmpl.Execute(&b, data)
+
+	return b.String(), err
+}
+
+// writeGeneratedPipeline writes the pipeline, refusing to overwrite an existing pipeline unless forced
+func writeGeneratedPipeline(fs afero.Fs, path string, pipeline string, force bool) error {
+	if exists, _ := afero.Exists(fs, path); exists && !force {
+		return fmt.Errorf("%s already exists, use --force to overwrite it", path)
+	}
+
+	err := fs.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	return afero.WriteFile(fs, path, []byte(pipeline), 0644)
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetConfiguredRings_ShouldKeepTheConfiguredOrder(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	_ = afero.WriteFile(fs, "runiac.yml", []byte(`project: stub
+rings:
+  dev:
+    account: "1"
+  stage:
+    account: "2"
+  prod:
+    account: "3"
+`), 0644)
+	_ = afero.WriteFile(fs, "empty.yml", []byte("project: stub\n"), 0644)
+
+	rings, err := getConfiguredRings(fs, "runiac.yml")
+	require.NoError(t, err)
+	require.Equal(t, []string{"dev", "stage", "prod"}, rings)
+
+	_, err = getConfiguredRings(fs, "empty.yml")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "--deployment-ring")
+
+	_ = afero.WriteFile(fs, "runiac.hcl", []byte(`
+rings "dev" {
+  account = "111"
+}
+
+rings {
+  stage {}
+  dev {}
+}
+
+rings "prod" {}
+`), 0644)
+
+	rings, err = getConfiguredRings(fs, "runiac.hcl")
+	require.NoError(t, err)
+	require.Equal(t, []string{"dev", "stage", "prod"}, rings, "the rings of an hcl config should keep their order")
+}
+
+func TestGetPipelineTargets_ShouldResolveTheRegionsOfEveryRing(t *testing.T) {
+	defer viper.Reset()
+
+	viper.Set("primary_region", "us-east-1")
+	viper.Set("regional_regions", "us-east-1,us-west-2")
+	viper.Set("rings.prod-eu.primary_regions", []string{"eu-west-1"})
+
+	targets, err := getPipelineTargets([]string{"dev", "prod-eu"})
+	require.NoError(t, err)
+	require.Equal(t, []pipelineTarget{
+		{Ring: "dev", ID: "dev", Environment: "dev", PrimaryRegion: "us-east-1", RegionalRegions: "us-east-1,us-west-2"},
+		{Ring: "prod-eu", ID: "prod_eu", Environment: "prod-eu", PrimaryRegion: "eu-west-1", RegionalRegions: "us-east-1,us-west-2"},
+	}, targets)
+
+	_, err = getPipelineTargets([]string{"dev: x"})
+	require.Error(t, err)
+}
+
+func TestWriteGeneratedPipeline_ShouldNotOverwritePipelines(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	require.NoError(t, writeGeneratedPipeline(fs, ".github/workflows/runiac.yml", "first", false))
+	require.Error(t, writeGeneratedPipeline(fs, ".github/workflows/runiac.yml", "second", false))
+	require.NoError(t, writeGeneratedPipeline(fs, ".github/workflows/runiac.yml", "second", true))
+
+	b, _ := afero.ReadFile(fs, ".github/workflows/runiac.yml")
+	require.Equal(t, "second", string(b))
+}
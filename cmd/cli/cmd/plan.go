@@ -0,0 +1,289 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+const (
+	planDir              = ".runiac/plans" // The output directory of runiac plan when --output-dir is not set, one directory per run
+	planArtifactFile     = "artifact.json" // Describes the step execution a saved plan was produced for
+	planArtifactJSONFile = "tfplan.json"   // The terraform show -json representation of a saved plan
+)
+
+var planFormat string
+
+func init() {
+	planCmd.Flags().AddFlagSet(deployCmd.Flags())
+	planCmd.Flags().StringVar(&planFormat, "output", "text", "The plan summary format, text or json. With json the container output is written to stderr")
+
+	rootCmd.AddCommand(planCmd)
+}
+
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Plan configurations",
+	Long: fmt.Sprintf(`Plans every step without applying, accepting the flags of deploy. The plan of each step is saved
+to the --output-dir, a new directory in %s by default, and summarized as the resources to add, change and
+destroy per step. Structured plans are available for the terraform runner.`, planDir),
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if planFormat != "text" && planFormat != "json" {
+			logrus.Fatalf("invalid --output '%s', must be text or json", planFormat)
+		}
+
+		if planFormat == "json" {
+			runOutput = os.Stderr
+		}
+
+		start := time.Now().UTC()
+		failed := runDeploy(cmd, actionPlan)
+
+		// nothing was planned when only printing settings
+		if Why != "" || Test || ShowResolvedVars || PrintContext {
+			return nil
+		}
+
+		summary, err := readPlanSummary(appFS, OutputDir, start)
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		if planFormat == "json" {
+			err = printPlanSummaryJSON(os.Stdout, summary)
+		} else {
+			err = printPlanSummary(os.Stdout, summary)
+		}
+
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		printRunCostEstimates(runOutput, "text")
+
+		return getRunError(cmd, failed)
+	},
+}
+
+// configurePlan makes the deploy a dry run saving its plans to the output directory
+func configurePlan() error {
+	if Detach {
+		return fmt.Errorf("--detach can not be used with plan, the plans are summarized once the container exits")
+	}
+
+	if SelfDestroy {
+		return fmt.Errorf("--self-destroy can not be used with plan, use deploy --self-destroy --dry-run to plan a destroy")
+	}
+
+	if Runner != "terraform" {
+		logrus.Warnf("The %s runner does not save structured plans, the plan summary only covers terraform steps", Runner)
+	}
+
+	DryRun = true
+
+	if OutputDir == "" {
+		OutputDir = filepath.Join(planDir, time.Now().UTC().Format("20060102T150405Z"))
+	}
+
+	return nil
+}
+
+// planArtifact is the metadata the deploy container saves along with a step's plan
+type planArtifact struct {
+	StepID           string    `json:"step_id"`
+	Track            string    `json:"track"`
+	Step             string    `json:"step"`
+	RegionDeployType string    `json:"region_deploy_type"`
+	Region           string    `json:"region"`
+	DeploymentRing   string    `json:"deployment_ring"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// terraformPlan is the subset of terraform show -json used to summarize a plan
+type terraformPlan struct {
+	ResourceChanges []struct {
+		Address string `json:"address"`
+		Change  struct {
+			Actions []string `json:"actions"`
+		} `json:"change"`
+	} `json:"resource_changes"`
+}
+
+// resourcePlanChange is a planned change of a resource, the action is create, update, delete or replace
+type resourcePlanChange struct {
+	Address string `json:"address"`
+	Action  string `json:"action"`
+}
+
+// stepPlanSummary counts the planned changes of a step in a region
+type stepPlanSummary struct {
+	StepID           string               `json:"step_id"`
+	RegionDeployType string               `json:"region_deploy_type"`
+	Region           string               `json:"region"`
+	DeploymentRing   string               `json:"deployment_ring"`
+	Add              int                  `json:"add"`
+	Change           int                  `json:"change"`
+	Destroy          int                  `json:"destroy"`
+	Resources        []resourcePlanChange `json:"resources"`
+}
+
+// planSummary is the consolidated plan of a run
+type planSummary struct {
+	Add     int               `json:"add"`
+	Change  int               `json:"change"`
+	Destroy int               `json:"destroy"`
+	Steps   []stepPlanSummary `json:"steps"`
+}
+
+// readPlanSummary summarizes the plans saved in dir since the run started, ordered by ring, step and region
+func readPlanSummary(fs afero.Fs, dir string, since time.Time) (planSummary, error) {
+	summary := planSummary{Steps: []stepPlanSummary{}}
+
+	if exists, _ := afero.DirExists(fs, dir); !exists {
+		return summary, nil
+	}
+
+	err := afero.Walk(fs, dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || info.Name() != planArtifactFile {
+			return err
+		}
+
+		artifact := planArtifact{}
+
+		b, err := afero.ReadFile(fs, path)
+		if err != nil {
+			return err
+		}
+
+		if err = json.Unmarshal(b, &artifact); err != nil {
+			return fmt.Errorf("invalid plan artifact %s: %w", path, err)
+		}
+
+		// plans of previous runs saved to the same --output-dir
+		if artifact.CreatedAt.Before(since) {
+			return nil
+		}
+
+		b, err = afero.ReadFile(fs, filepath.Join(filepath.Dir(path), planArtifactJSONFile))
+		if err != nil {
+			return err
+		}
+
+		step, err := getStepPlanSummary(artifact, b)
+		if err != nil {
+			return fmt.Errorf("invalid plan %s: %w", filepath.Join(filepath.Dir(path), planArtifactJSONFile), err)
+		}
+
+		summary.Steps = append(summary.Steps, step)
+		summary.Add += step.Add
+		summary.Change += step.Change
+		summary.Destroy += step.Destroy
+
+		return nil
+	})
+
+	sort.SliceStable(summary.Steps, func(i, j int) bool {
+		a, b := summary.Steps[i], summary.Steps[j]
+
+		if a.DeploymentRing != b.DeploymentRing {
+			return a.DeploymentRing < b.DeploymentRing
+		}
+
+		if a.StepID != b.StepID {
+			return a.StepID < b.StepID
+		}
+
+		return a.RegionDeployType+a.Region < b.RegionDeployType+b.Region
+	})
+
+	return summary, err
+}
+
+// getStepPlanSummary counts the changes of the terraform show -json plan, a replaced resource is both added and
+// destroyed
+func getStepPlanSummary(artifact planArtifact, planJSON []byte) (stepPlanSummary, error) {
+	step := stepPlanSummary{
+		StepID:           artifact.StepID,
+		RegionDeployType: artifact.RegionDeployType,
+		Region:           artifact.Region,
+		DeploymentRing:   artifact.DeploymentRing,
+		Resources:        []resourcePlanChange{},
+	}
+
+	plan := terraformPlan{}
+
+	err := json.Unmarshal(planJSON, &plan)
+	if err != nil {
+		return step, err
+	}
+
+	for _, c := range plan.ResourceChanges {
+		action := ""
+
+		switch strings.Join(c.Change.Actions, ",") {
+		case "create":
+			action = "create"
+			step.Add++
+		case "update":
+			action = "update"
+			step.Change++
+		case "delete":
+			action = "delete"
+			step.Destroy++
+		case "delete,create", "create,delete":
+			action = "replace"
+			step.Add++
+			step.Destroy++
+		default:
+			// no-op and read do not change infrastructure
+			continue
+		}
+
+		step.Resources = append(step.Resources, resourcePlanChange{Address: c.Address, Action: action})
+	}
+
+	return step, nil
+}
+
+// printPlanSummary writes the planned changes per step and region followed by the totals
+func printPlanSummary(w io.Writer, summary planSummary) error {
+	if len(summary.Steps) == 0 {
+		_, err := fmt.Fprintln(w, "No plans were saved, structured plans are only available for terraform steps")
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "RING\tSTEP\tREGION\tADD\tCHANGE\tDESTROY")
+
+	for _, s := range summary.Steps {
+		fmt.Fprintf(tw, "%s\t%s\t%s (%s)\t%d\t%d\t%d\n", s.DeploymentRing, s.StepID, s.Region, s.RegionDeployType, s.Add, s.Change, s.Destroy)
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "\nPlan: %d to add, %d to change, %d to destroy across %d step execution(s).\n", summary.Add, summary.Change, summary.Destroy, len(summary.Steps))
+
+	return err
+}
+
+// printPlanSummaryJSON writes the summary as indented json
+func printPlanSummaryJSON(w io.Writer, summary planSummary) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(summary)
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+const stubTerraformPlan = `{"resource_changes": [
+	{"address": "azurerm_resource_group.rg", "change": {"actions": ["create"]}},
+	{"address": "azurerm_storage_account.sa", "change": {"actions": ["update"]}},
+	{"address": "azurerm_key_vault.kv", "change": {"actions": ["delete", "create"]}},
+	{"address": "azurerm_network.vnet", "change": {"actions": ["no-op"]}},
+	{"address": "data.azurerm_client_config.current", "change": {"actions": ["read"]}}
+]}`
+
+func writeStubPlan(fs afero.Fs, dir string, stepID string, region string, createdAt time.Time, plan string) {
+	_ = fs.MkdirAll(dir, 0755)
+	_ = afero.WriteFile(fs, filepath.Join(dir, planArtifactFile), []byte(fmt.Sprintf(`{"step_id": "%s", "region_deploy_type": "primary", "region": "%s", "deployment_ring": "dev", "created_at": "%s"}`, stepID, region, createdAt.Format(time.RFC3339))), 0644)
+	_ = afero.WriteFile(fs, filepath.Join(dir, planArtifactJSONFile), []byte(plan), 0644)
+}
+
+func TestReadPlanSummary_ShouldCountChangesPerStep(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	start := time.Now().UTC().Truncate(time.Second)
+
+	writeStubPlan(fs, "out/network/vnet/primary-eastus", "network/vnet", "eastus", start.Add(time.Minute), stubTerraformPlan)
+	writeStubPlan(fs, "out/default/hello/primary-eastus", "default/hello", "eastus", start.Add(time.Minute), `{"resource_changes": [{"address": "null_resource.hello", "change": {"actions": ["delete"]}}]}`)
+	writeStubPlan(fs, "out/default/old/primary-eastus", "default/old", "eastus", start.Add(-time.Hour), stubTerraformPlan)
+
+	summary, err := readPlanSummary(fs, "out", start)
+	require.NoError(t, err)
+
+	require.Len(t, summary.Steps, 2, "plans saved before the run started should be ignored")
+	require.Equal(t, "default/hello", summary.Steps[0].StepID)
+	require.Equal(t, 1, summary.Steps[0].Destroy)
+
+	vnet := summary.Steps[1]
+	require.Equal(t, 2, vnet.Add)
+	require.Equal(t, 1, vnet.Change)
+	require.Equal(t, 1, vnet.Destroy)
+	require.Equal(t, []resourcePlanChange{
+		{Address: "azurerm_resource_group.rg", Action: "create"},
+		{Address: "azurerm_storage_account.sa", Action: "update"},
+		{Address: "azurerm_key_vault.kv", Action: "replace"},
+	}, vnet.Resources)
+
+	require.Equal(t, 2, summary.Add)
+	require.Equal(t, 1, summary.Change)
+	require.Equal(t, 2, summary.Destroy)
+
+	var b bytes.Buffer
+	require.NoError(t, printPlanSummary(&b, summary))
+	require.Contains(t, b.String(), "Plan: 2 to add, 1 to change, 2 to destroy across 2 step execution(s).")
+
+	empty, err := readPlanSummary(fs, "missing", start)
+	require.NoError(t, err)
+	require.Empty(t, empty.Steps)
+}
+
+func TestConfigurePlan_ShouldForceDryRunToOutputDir(t *testing.T) {
+	defer func() { DryRun, OutputDir, Detach, SelfDestroy, Runner = false, "", false, false, "" }()
+
+	DryRun, OutputDir, Runner = false, "", "terraform"
+	require.NoError(t, configurePlan())
+	require.True(t, DryRun)
+	require.Contains(t, OutputDir, planDir)
+
+	Detach = true
+	require.Error(t, configurePlan(), "plans can not be summarized for a detached container")
+
+	Detach, SelfDestroy = false, true
+	require.Error(t, configurePlan())
+}
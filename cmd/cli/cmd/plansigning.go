@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+const (
+	planManifestFile  = "plans.sha256" // The sha256 of every file of a dry run's --output-dir, in the format of sha256sum
+	planSignatureFile = "plans.sig"    // The hex encoded HMAC-SHA256 of the manifest with the --plan-signing-key
+)
+
+// validatePlanSigning ensures the plans of the run can be signed or verified with the --plan-signing-key
+func validatePlanSigning(key string, dryRun bool, detach bool) error {
+	if key != "" && dryRun && detach {
+		return errors.New("--plan-signing-key can not be used with --detach, the plans of a dry run are signed once the container exits")
+	}
+
+	return nil
+}
+
+// readPlanSigningKey reads the key of the --plan-signing-key file, leading and trailing whitespace is not part of
+// the key
+func readPlanSigningKey(fs afero.Fs, path string) ([]byte, error) {
+	b, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read --plan-signing-key '%s': %w", path, err)
+	}
+
+	key := []byte(strings.TrimSpace(string(b)))
+	if len(key) == 0 {
+		return nil, fmt.Errorf("--plan-signing-key '%s' is empty", path)
+	}
+
+	return key, nil
+}
+
+// getPlanManifest returns the sha256 of every file in dir by its slash separated path relative to dir, one per line
+// sorted by path. The manifest and signature are not part of the manifest.
+func getPlanManifest(fs afero.Fs, dir string) (string, error) {
+	var manifest strings.Builder
+
+	err := afero.Walk(fs, dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		rel = filepath.ToSlash(rel)
+		if rel == planManifestFile || rel == planSignatureFile {
+			return nil
+		}
+
+		b, err := afero.ReadFile(fs, path)
+		if err != nil {
+			return err
+		}
+
+		manifest.WriteString(fmt.Sprintf("%x  %s\n", sha256.Sum256(b), rel))
+
+		return nil
+	})
+
+	return manifest.String(), err
+}
+
+// getPlanSignature returns the hex encoded HMAC-SHA256 of the manifest
+func getPlanSignature(manifest string, key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(manifest))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// signPlans writes the manifest of the plans in dir and its signature with the key to dir, so an apply stage can
+// verify it applies the plans that were reviewed
+func signPlans(fs afero.Fs, dir string, key []byte) error {
+	manifest, err := getPlanManifest(fs, dir)
+	if err != nil {
+		return err
+	}
+
+	err = afero.WriteFile(fs, filepath.Join(dir, planManifestFile), []byte(manifest), 0644)
+	if err != nil {
+		return err
+	}
+
+	return afero.WriteFile(fs, filepath.Join(dir, planSignatureFile), []byte(getPlanSignature(manifest, key)+"\n"), 0644)
+}
+
+// checkPlansKey ensures the signed plans in dir are verified, a --plan-signing-key is required to apply plans that
+// were signed so plans modified since their review are not applied unverified
+func checkPlansKey(fs afero.Fs, dir string, key []byte) error {
+	if key != nil {
+		return nil
+	}
+
+	if signed, _ := afero.Exists(fs, filepath.Join(dir, planSignatureFile)); signed {
+		return fmt.Errorf("the plans in %s are signed, set the --plan-signing-key they were signed with to verify them", dir)
+	}
+
+	return nil
+}
+
+// verifyPlans ensures the plans in dir are the plans that were signed with the key. The manifest is computed from
+// the files, any file added, removed or modified since the plans were signed fails the verification.
+func verifyPlans(fs afero.Fs, dir string, key []byte) error {
+	b, err := afero.ReadFile(fs, filepath.Join(dir, planSignatureFile))
+	if err != nil {
+		return fmt.Errorf("the plans in %s are not signed, sign them with --plan-signing-key when saving them", dir)
+	}
+
+	manifest, err := getPlanManifest(fs, dir)
+	if err != nil {
+		return err
+	}
+
+	signature, err := hex.DecodeString(strings.TrimSpace(string(b)))
+	if err != nil {
+		return fmt.Errorf("invalid signature of the plans in %s: %w", dir, err)
+	}
+
+	expected, _ := hex.DecodeString(getPlanSignature(manifest, key))
+
+	if !hmac.Equal(signature, expected) {
+		return fmt.Errorf("the plans in %s do not match their signature, they were modified since they were signed or signed with a different key", dir)
+	}
+
+	return nil
+}
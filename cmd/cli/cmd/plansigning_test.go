@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignPlans_ShouldVerifyTheSignedPlans(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	dir := filepath.Join(planDir, "prod")
+	plan := filepath.Join(dir, "track", "step", "primary-centralus", "tfplan")
+
+	_ = afero.WriteFile(fs, plan, []byte("reviewed plan"), 0644)
+	_ = afero.WriteFile(fs, filepath.Join(dir, "config.json"), []byte(`{"deployment_ring":"prod"}`), 0644)
+
+	require.Error(t, verifyPlans(fs, dir, []byte("key")), "unsigned plans should fail the verification")
+
+	require.NoError(t, signPlans(fs, dir, []byte("key")))
+	require.NoError(t, verifyPlans(fs, dir, []byte("key")))
+
+	manifest, _ := afero.ReadFile(fs, filepath.Join(dir, planManifestFile))
+	require.Contains(t, string(manifest), "  track/step/primary-centralus/tfplan\n")
+
+	require.Error(t, verifyPlans(fs, dir, []byte("other key")), "a different key should fail the verification")
+
+	_ = afero.WriteFile(fs, plan, []byte("modified plan"), 0644)
+	require.Error(t, verifyPlans(fs, dir, []byte("key")), "a modified plan should fail the verification")
+
+	_ = afero.WriteFile(fs, plan, []byte("reviewed plan"), 0644)
+	_ = afero.WriteFile(fs, filepath.Join(dir, "track", "other", "primary-centralus", "tfplan"), []byte("added plan"), 0644)
+	require.Error(t, verifyPlans(fs, dir, []byte("key")), "an added plan should fail the verification")
+}
+
+func TestReadPlanSigningKey_ShouldTrimTheKey(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	_ = afero.WriteFile(fs, "plan.key", []byte("secret\n"), 0600)
+	_ = afero.WriteFile(fs, "empty.key", []byte("\n"), 0600)
+
+	key, err := readPlanSigningKey(fs, "plan.key")
+	require.NoError(t, err)
+	require.Equal(t, "secret", string(key))
+
+	_, err = readPlanSigningKey(fs, "empty.key")
+	require.Error(t, err)
+
+	_, err = readPlanSigningKey(fs, "missing.key")
+	require.Error(t, err)
+}
+
+func TestValidatePlanSigning_ShouldRejectDetachedDryRuns(t *testing.T) {
+	require.NoError(t, validatePlanSigning("", true, true))
+	require.NoError(t, validatePlanSigning("plan.key", true, false))
+	require.NoError(t, validatePlanSigning("plan.key", false, true))
+	require.Error(t, validatePlanSigning("plan.key", true, true))
+}
+
+func TestCheckPlansKey_ShouldRequireTheKeyOfSignedPlans(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	_ = afero.WriteFile(fs, "plans/step/plan.tfplan", []byte("plan"), 0644)
+
+	require.NoError(t, checkPlansKey(fs, "plans", nil), "unsigned plans do not require a key")
+
+	require.NoError(t, signPlans(fs, "plans", []byte("key")))
+
+	require.Error(t, checkPlansKey(fs, "plans", nil), "signed plans should require the key")
+	require.NoError(t, checkPlansKey(fs, "plans", []byte("key")))
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// pluginTimeout is how long a plugin may run before it is considered failed
+var pluginTimeout = 30 * time.Second
+
+var validEnvName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// pluginContext is the resolved run context passed to plugins as json on stdin
+type pluginContext struct {
+	Project         string   `json:"project"`
+	DeploymentRing  string   `json:"deployment_ring"`
+	Environment     string   `json:"environment"`
+	Namespace       string   `json:"namespace"`
+	Account         string   `json:"account"`
+	Version         string   `json:"version"`
+	Runner          string   `json:"runner"`
+	PrimaryRegions  []string `json:"primary_regions"`
+	RegionalRegions []string `json:"regional_regions"`
+	Steps           []string `json:"steps"`
+	DryRun          bool     `json:"dry_run"`
+	SelfDestroy     bool     `json:"self_destroy"`
+	Destroy         bool     `json:"destroy"`
+}
+
+// pluginResult is the json a plugin writes to stdout to customize the container run
+type pluginResult struct {
+	Env    map[string]string `json:"env"`
+	Mounts []pluginMount     `json:"mounts"`
+}
+
+// pluginMount is a host path to mount into the container
+type pluginMount struct {
+	Source   string `json:"source"`
+	Target   string `json:"target"`
+	ReadOnly bool   `json:"read_only"`
+}
+
+// runPlugin executes the plugin with the run context on stdin and returns its validated result
+func runPlugin(plugin string, runContext pluginContext) (pluginResult, error) {
+	result := pluginResult{}
+
+	input, err := json.Marshal(runContext)
+	if err != nil {
+		return result, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pluginTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, plugin)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return result, fmt.Errorf("plugin %s did not complete within %s", plugin, pluginTimeout)
+	}
+
+	if err != nil {
+		return result, fmt.Errorf("plugin %s failed: %w: %s", plugin, err, strings.TrimSpace(stderr.String()))
+	}
+
+	decoder := json.NewDecoder(&stdout)
+	decoder.DisallowUnknownFields()
+
+	err = decoder.Decode(&result)
+	if err != nil {
+		return result, fmt.Errorf("plugin %s returned invalid output: %w", plugin, err)
+	}
+
+	err = validatePluginResult(result)
+	if err != nil {
+		return result, fmt.Errorf("plugin %s returned invalid output: %w", plugin, err)
+	}
+
+	return result, nil
+}
+
+// validatePluginResult ensures every env variable and mount returned by a plugin is usable
+func validatePluginResult(result pluginResult) error {
+	for k := range result.Env {
+		if !validEnvName.MatchString(k) {
+			return fmt.Errorf("'%s' is not a valid environment variable name", k)
+		}
+	}
+
+	for _, m := range result.Mounts {
+		if m.Source == "" || m.Target == "" {
+			return fmt.Errorf("mounts require both a source and a target")
+		}
+
+		if !filepath.IsAbs(m.Source) || !strings.HasPrefix(m.Target, "/") {
+			return fmt.Errorf("mount %s:%s must use absolute paths", m.Source, m.Target)
+		}
+
+		if strings.Contains(m.Target, ":") {
+			return fmt.Errorf("mount target %s must not contain ':'", m.Target)
+		}
+	}
+
+	return nil
+}
+
+// getPluginArguments returns the container run arguments for a plugin's result, env variables sorted by name
+func getPluginArguments(result pluginResult) (args []string) {
+	keys := make([]string, 0, len(result.Env))
+	for k := range result.Env {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		args = append(args, "-e", fmt.Sprintf("%s=%s", k, result.Env[k]))
+	}
+
+	for _, m := range result.Mounts {
+		args = append(args, getVolumeArguments(m.Source, m.Target, m.ReadOnly)...)
+	}
+
+	return
+}
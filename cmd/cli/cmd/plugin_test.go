@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeStubPlugin writes an executable shell script plugin with the given body
+func writeStubPlugin(t *testing.T, body string) string {
+	path := filepath.Join(t.TempDir(), "plugin.sh")
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0755))
+
+	return path
+}
+
+func TestRunPlugin_ShouldReturnEnvAndMounts(t *testing.T) {
+	// echo the ring from the run context on stdin back as an env variable
+	plugin := writeStubPlugin(t, `ring=$(sed -n 's/.*"deployment_ring":"\([^"]*\)".*/\1/p')
+echo "{\"env\": {\"TEAM_RING\": \"$ring\"}, \"mounts\": [{\"source\": \"/etc/team\", \"target\": \"/team\", \"read_only\": true}]}"`)
+
+	result, err := runPlugin(plugin, pluginContext{DeploymentRing: "prod"})
+	require.NoError(t, err)
+	require.Equal(t, "prod", result.Env["TEAM_RING"])
+	require.Equal(t, []string{"-e", "TEAM_RING=prod", "-v", "/etc/team:/team:ro"}, getPluginArguments(result))
+}
+
+func TestRunPlugin_ShouldFailSafely(t *testing.T) {
+	tests := map[string]string{
+		"non-zero exit":     `echo "broken" >&2; exit 1`,
+		"invalid json":      `echo "not json"`,
+		"unknown field":     `echo '{"environment": {"A": "b"}}'`,
+		"invalid env name":  `echo '{"env": {"NOT-VALID": "b"}}'`,
+		"relative mount":    `echo '{"mounts": [{"source": "./team", "target": "/team"}]}'`,
+		"missing target":    `echo '{"mounts": [{"source": "/team"}]}'`,
+		"target with colon": `echo '{"mounts": [{"source": "/team", "target": "/team:rw"}]}'`,
+	}
+
+	for name, body := range tests {
+		_, err := runPlugin(writeStubPlugin(t, body), pluginContext{})
+
+		require.Error(t, err, name)
+	}
+}
+
+func TestRunPlugin_ShouldTimeout(t *testing.T) {
+	pluginTimeout = 50 * time.Millisecond
+	defer func() { pluginTimeout = 30 * time.Second }()
+
+	_, err := runPlugin(writeStubPlugin(t, "exec sleep 5"), pluginContext{})
+
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "did not complete")
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+const (
+	policyDir          = ".runiac/policies" // The rego policies each step's plan is evaluated against before applying
+	containerPolicyDir = "/runiac/policies" // Where the policies are mounted inside the container
+	policyHardFail     = "hard-fail"        // A plan violating a policy fails its step without applying
+	policySoftFail     = "soft-fail"        // A plan violating a policy is applied, the violations are only warned about
+)
+
+// hasPolicies returns true when dir contains a rego policy
+func hasPolicies(fs afero.Fs, dir string) bool {
+	found := false
+
+	_ = afero.Walk(fs, dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() && strings.HasSuffix(info.Name(), ".rego") {
+			found = true
+		}
+
+		return err
+	})
+
+	return found
+}
+
+// validatePolicy returns an error when the --policy mode is invalid or conftest is not on the path in --native mode
+// while the project has policies
+func validatePolicy(fs afero.Fs, mode string) error {
+	if mode != policyHardFail && mode != policySoftFail {
+		return fmt.Errorf("invalid --policy '%s', must be %s or %s", mode, policyHardFail, policySoftFail)
+	}
+
+	if !hasPolicies(fs, policyDir) {
+		return nil
+	}
+
+	if Native {
+		if _, err := lookPath("conftest"); err != nil {
+			return fmt.Errorf("the policies in %s require 'conftest' on the path in --native mode", policyDir)
+		}
+	}
+
+	if Runner != "terraform" {
+		logrus.Warnf("The %s runner does not evaluate policies, the policies in %s only apply to terraform steps", Runner, policyDir)
+	}
+
+	return nil
+}
+
+// getPolicyArguments returns the container run arguments mounting the project's policies read-only, none when the
+// project has no policies
+func getPolicyArguments(fs afero.Fs, mode string) (args []string, err error) {
+	if !hasPolicies(fs, policyDir) {
+		return
+	}
+
+	dir, err := filepath.Abs(policyDir)
+	if err != nil {
+		return nil, err
+	}
+
+	args = append(args, getVolumeArguments(dir, containerPolicyDir, true)...)
+	args = appendE(args, "POLICY_DIR", containerPolicyDir)
+
+	if mode == policySoftFail {
+		args = appendE(args, "POLICY_SOFT_FAIL", "true")
+	}
+
+	return
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPolicyArguments_ShouldMountPoliciesReadOnly(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	args, err := getPolicyArguments(fs, policyHardFail)
+	require.NoError(t, err)
+	require.Empty(t, args, "a project without policies is not evaluated")
+
+	_ = afero.WriteFile(fs, filepath.Join(policyDir, "README.md"), []byte("# policies"), 0644)
+	require.False(t, hasPolicies(fs, policyDir))
+
+	_ = afero.WriteFile(fs, filepath.Join(policyDir, "storage", "public_buckets.rego"), []byte("package storage"), 0644)
+	require.True(t, hasPolicies(fs, policyDir))
+
+	dir, _ := filepath.Abs(policyDir)
+
+	args, err = getPolicyArguments(fs, policyHardFail)
+	require.NoError(t, err)
+	require.Equal(t, []string{"-v", fmt.Sprintf("%s:%s:ro", dir, containerPolicyDir), "-e", "RUNIAC_POLICY_DIR=" + containerPolicyDir}, args)
+
+	args, err = getPolicyArguments(fs, policySoftFail)
+	require.NoError(t, err)
+	require.Contains(t, args, "RUNIAC_POLICY_SOFT_FAIL=true")
+}
+
+func TestValidatePolicy_ShouldRequireConftestInNativeMode(t *testing.T) {
+	defer func(f func(string) (string, error)) { lookPath = f }(lookPath)
+	defer func() { Native, Runner = false, "" }()
+
+	fs := afero.NewMemMapFs()
+	Runner = "terraform"
+
+	require.NoError(t, validatePolicy(fs, policyHardFail))
+	require.NoError(t, validatePolicy(fs, policySoftFail))
+	require.EqualError(t, validatePolicy(fs, "warn"), "invalid --policy 'warn', must be hard-fail or soft-fail")
+
+	Native = true
+	lookPath = func(file string) (string, error) { return "", errors.New("not found") }
+	require.NoError(t, validatePolicy(fs, policyHardFail), "conftest is only required when the project has policies")
+
+	_ = afero.WriteFile(fs, filepath.Join(policyDir, "deny.rego"), []byte("package main"), 0644)
+	require.EqualError(t, validatePolicy(fs, policyHardFail), "the policies in .runiac/policies require 'conftest' on the path in --native mode")
+
+	lookPath = func(file string) (string, error) { return "/usr/local/bin/" + file, nil }
+	require.NoError(t, validatePolicy(fs, policyHardFail))
+}
@@ -0,0 +1,649 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+const (
+	uiModePlain = "plain" // Streams the container output to the terminal
+	uiModeTUI   = "tui"   // Renders a live dashboard of the steps from the container's json logs
+)
+
+// The messages the deploy container logs when a step starts and completes executing in a region, see
+// tracks.StepStartedMessage
+const (
+	containerStepStartedMessage   = "Executing step"
+	containerStepCompletedMessage = "Completed step"
+)
+
+const (
+	progressRefreshInterval = 250 * time.Millisecond
+	progressMaxStepLogLines = 5000 // The lines of each step's log kept to drill into
+	progressGeneralLogLines = 4    // The lines of the logs without a step shown below the steps
+)
+
+var progressSpinner = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// textLogLevel and textLogMessage match the level and message of a logrus text formatted line
+var (
+	textLogLevel   = regexp.MustCompile(`level=(\w+)`)
+	textLogMessage = regexp.MustCompile(`msg="((?:[^"\\]|\\.)*)"`)
+)
+
+// progressView is the active dashboard of the running deploy, nil with --ui plain
+var progressView *progressDashboard
+
+var (
+	progressKeys     = make(chan string)
+	progressExitOnce sync.Once
+)
+
+// validateUIMode returns an error when the ui mode is invalid or the tui can not be rendered
+func validateUIMode(ui string, stdoutIsTerminal bool) error {
+	switch ui {
+	case uiModePlain:
+		return nil
+	case uiModeTUI:
+	default:
+		return fmt.Errorf("invalid --ui '%s', must be %s or %s", ui, uiModePlain, uiModeTUI)
+	}
+
+	if !stdoutIsTerminal {
+		return fmt.Errorf("--ui %s requires a terminal, use --ui %s when the output is redirected", uiModeTUI, uiModePlain)
+	}
+
+	if Interactive || Detach {
+		return fmt.Errorf("--ui %s can not be used with --interactive or --detach", uiModeTUI)
+	}
+
+	return nil
+}
+
+// getContainerLogFormat returns the log format of the deploy container, the tui reads the container's json logs
+func getContainerLogFormat(ui string, format string) string {
+	if ui == uiModeTUI {
+		return "json"
+	}
+
+	return format
+}
+
+// getTerminalWriters returns the writers of the run's stdout and stderr, the dashboard when it is active
+func getTerminalWriters() (io.Writer, io.Writer) {
+	if progressView != nil {
+		return progressView, progressView
+	}
+
+	return runOutput, os.Stderr
+}
+
+// runCommand runs the command, a ctrl+c in the dashboard interrupts it
+func runCommand(cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	if progressView != nil {
+		progressView.setProcess(cmd.Process)
+	}
+
+	return cmd.Wait()
+}
+
+// startProgressView starts the dashboard of the ring's run with --ui tui
+func startProgressView(ui string, title string) {
+	if ui != uiModeTUI {
+		return
+	}
+
+	progressView = newProgressDashboard(os.Stdout, title)
+	progressView.start()
+}
+
+// stopProgressView stops the active dashboard, printing the summary of the run
+func stopProgressView() {
+	if progressView != nil {
+		progressView.stop()
+		progressView = nil
+	}
+}
+
+// stepProgress is the progress of a step executing in a region
+type stepProgress struct {
+	Track    string
+	Step     string
+	Region   string
+	Status   string // running until the step completes, then its status, e.g. success or fail
+	Started  time.Time
+	Finished time.Time
+	LastLine string
+	Log      []string
+}
+
+// ID returns the {trackName}/{stepName} id of the step
+func (s *stepProgress) ID() string {
+	return fmt.Sprintf("%s/%s", s.Track, s.Step)
+}
+
+// Elapsed returns how long the step executes or executed
+func (s *stepProgress) Elapsed(now time.Time) time.Duration {
+	if !s.Finished.IsZero() {
+		now = s.Finished
+	}
+
+	return now.Sub(s.Started).Truncate(time.Second)
+}
+
+// progressDashboard renders the steps of the run from the container's json logs. It is the writer of the run's
+// output, lines without a step are shown below the steps.
+type progressDashboard struct {
+	mu       sync.Mutex
+	out      io.Writer
+	title    string
+	now      func() time.Time
+	size     func() (int, int)
+	started  time.Time
+	steps    []*stepProgress
+	byKey    map[string]*stepProgress
+	general  []string
+	problems []string
+	partial  []byte
+	selected int
+	detail   bool
+	scroll   int
+	frame    int
+
+	process     *os.Process
+	interrupted bool
+
+	done      chan struct{}
+	stopped   sync.Once
+	rendering sync.WaitGroup
+	restore   func()
+	logOutput io.Writer
+}
+
+// newProgressDashboard returns the dashboard of the run rendered to out
+func newProgressDashboard(out io.Writer, title string) *progressDashboard {
+	return &progressDashboard{
+		out:   out,
+		title: title,
+		now:   time.Now,
+		size:  getTerminalSize,
+		byKey: map[string]*stepProgress{},
+		done:  make(chan struct{}),
+	}
+}
+
+// getTerminalSize returns the width and height of the terminal
+func getTerminalSize() (int, int) {
+	width, height, err := terminal.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 || height <= 0 {
+		return 120, 30
+	}
+
+	return width, height
+}
+
+// Write receives the output of the run, line by line
+func (d *progressDashboard) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.partial = append(d.partial, p...)
+
+	for {
+		i := bytes.IndexByte(d.partial, '\n')
+		if i < 0 {
+			break
+		}
+
+		d.handleLine(strings.TrimRight(string(d.partial[:i]), "\r"))
+		d.partial = d.partial[i+1:]
+	}
+
+	return len(p), nil
+}
+
+// handleLine updates the steps with a line of the run's output
+func (d *progressDashboard) handleLine(line string) {
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+
+	if d.started.IsZero() {
+		d.started = d.now()
+	}
+
+	entry := map[string]interface{}{}
+
+	if err := json.Unmarshal([]byte(line), &entry); err != nil || entry["msg"] == nil {
+		level, msg := "", line
+
+		if m := textLogLevel.FindStringSubmatch(line); m != nil {
+			level = m[1]
+		}
+
+		if m := textLogMessage.FindStringSubmatch(line); m != nil {
+			msg = strings.ReplaceAll(m[1], `\"`, `"`)
+		}
+
+		d.addGeneral(level, msg)
+		return
+	}
+
+	field := func(name string) string {
+		if v, ok := entry[name]; ok && v != nil {
+			return fmt.Sprint(v)
+		}
+		return ""
+	}
+
+	level, msg := field("level"), field("msg")
+	if e := field("error"); e != "" {
+		msg = fmt.Sprintf("%s: %s", msg, e)
+	}
+
+	if field("step") == "" {
+		d.addGeneral(level, msg)
+		return
+	}
+
+	key := strings.Join([]string{field("track"), field("step"), field("region")}, "/")
+
+	s, ok := d.byKey[key]
+	if !ok {
+		s = &stepProgress{Track: field("track"), Step: field("step"), Region: field("region"), Status: "running", Started: d.now()}
+		d.byKey[key] = s
+		d.steps = append(d.steps, s)
+	}
+
+	switch {
+	case field("msg") == containerStepStartedMessage:
+		s.Status, s.Started, s.Finished = "running", d.now(), time.Time{}
+	case field("msg") == containerStepCompletedMessage:
+		s.Status, s.Finished = strings.ToLower(field("status")), d.now()
+	case strings.HasPrefix(field("msg"), "Skipping step"):
+		s.Status, s.Finished = "skipped", d.now()
+	}
+
+	s.LastLine = msg
+	s.Log = append(s.Log, formatProgressLine(level, msg))
+
+	if len(s.Log) > progressMaxStepLogLines {
+		s.Log = s.Log[len(s.Log)-progressMaxStepLogLines:]
+	}
+
+	if isProblemLevel(level) {
+		d.problems = append(d.problems, fmt.Sprintf("%s %s: %s", s.ID(), s.Region, formatProgressLine(level, msg)))
+	}
+}
+
+// addGeneral adds a line without a step
+func (d *progressDashboard) addGeneral(level string, msg string) {
+	line := formatProgressLine(level, msg)
+
+	d.general = append(d.general, line)
+	if len(d.general) > progressGeneralLogLines {
+		d.general = d.general[len(d.general)-progressGeneralLogLines:]
+	}
+
+	if isProblemLevel(level) {
+		d.problems = append(d.problems, line)
+	}
+}
+
+// formatProgressLine formats a log line with its level
+func formatProgressLine(level string, msg string) string {
+	if level == "" {
+		return msg
+	}
+
+	return fmt.Sprintf("%-7s %s", strings.ToUpper(level), msg)
+}
+
+// isProblemLevel returns true for the log levels summarized after the run
+func isProblemLevel(level string) bool {
+	switch strings.ToLower(level) {
+	case "warning", "warn", "error", "fatal", "panic":
+		return true
+	}
+
+	return false
+}
+
+// setProcess sets the process interrupted by ctrl+c
+func (d *progressDashboard) setProcess(p *os.Process) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.process = p
+}
+
+// handleKey handles a key pressed in the dashboard
+func (d *progressDashboard) handleKey(key string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	switch key {
+	case "up":
+		if d.detail {
+			d.scroll++
+		} else if d.selected > 0 {
+			d.selected--
+		}
+	case "down":
+		if d.detail {
+			if d.scroll > 0 {
+				d.scroll--
+			}
+		} else if d.selected < len(d.steps)-1 {
+			d.selected++
+		}
+	case "enter":
+		if len(d.steps) > 0 {
+			d.detail, d.scroll = true, 0
+		}
+	case "back":
+		d.detail = false
+	case "interrupt":
+		if d.process == nil {
+			return
+		}
+
+		// the container engine forwards the interrupt to the deploy, a second ctrl+c kills it
+		if d.interrupted || d.process.Signal(os.Interrupt) != nil {
+			_ = d.process.Kill()
+		}
+
+		d.interrupted = true
+	}
+}
+
+// render returns the frame of the dashboard
+func (d *progressDashboard) render() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	width, height := d.size()
+	now := d.now()
+	lines := []string{}
+
+	if d.detail && d.selected < len(d.steps) {
+		s := d.steps[d.selected]
+
+		lines = append(lines, fmt.Sprintf("%s %s  %s  %s", s.ID(), s.Region, s.Status, s.Elapsed(now)), "")
+
+		visible := height - 3
+		if visible < 1 {
+			visible = 1
+		}
+
+		maxScroll := len(s.Log) - visible
+		if maxScroll < 0 {
+			maxScroll = 0
+		}
+
+		if d.scroll > maxScroll {
+			d.scroll = maxScroll
+		}
+
+		end := len(s.Log) - d.scroll
+		start := end - visible
+		if start < 0 {
+			start = 0
+		}
+
+		lines = append(lines, s.Log[start:end]...)
+		for len(lines) < height-1 {
+			lines = append(lines, "")
+		}
+
+		lines = append(lines, "↑/↓ scroll · esc back · ctrl+c interrupt")
+
+		return d.frameOf(lines, width)
+	}
+
+	completed, failed := 0, 0
+	for _, s := range d.steps {
+		if s.Status != "running" {
+			completed++
+		}
+
+		if s.Status == "fail" {
+			failed++
+		}
+	}
+
+	elapsed := time.Duration(0)
+	if !d.started.IsZero() {
+		elapsed = now.Sub(d.started).Truncate(time.Second)
+	}
+
+	header := fmt.Sprintf("runiac %s  %s elapsed  %d/%d steps completed", d.title, elapsed, completed, len(d.steps))
+	if failed > 0 {
+		header += fmt.Sprintf("  %d failed", failed)
+	}
+
+	if d.interrupted {
+		header += "  interrupting..."
+	}
+
+	stepWidth, regionWidth := len("STEP"), len("REGION")
+	for _, s := range d.steps {
+		if len(s.ID()) > stepWidth {
+			stepWidth = len(s.ID())
+		}
+
+		if len(s.Region) > regionWidth {
+			regionWidth = len(s.Region)
+		}
+	}
+
+	row := func(marker string, status string, step string, region string, elapsed string, last string) string {
+		return fmt.Sprintf("%s %-10s %-*s  %-*s  %8s  %s", marker, status, stepWidth, step, regionWidth, region, elapsed, last)
+	}
+
+	lines = append(lines, header, "", row(" ", "STATUS", "STEP", "REGION", "ELAPSED", "LAST LOG"))
+
+	visible := height - len(lines) - progressGeneralLogLines - 2
+	if visible < 1 {
+		visible = 1
+	}
+
+	offset := 0
+	if d.selected >= visible {
+		offset = d.selected - visible + 1
+	}
+
+	for i := offset; i < len(d.steps) && i < offset+visible; i++ {
+		s := d.steps[i]
+
+		marker := " "
+		if i == d.selected {
+			marker = ">"
+		}
+
+		lines = append(lines, row(marker, d.getStatusLabel(s), s.ID(), s.Region, s.Elapsed(now).String(), s.LastLine))
+	}
+
+	for len(lines) < height-progressGeneralLogLines-1 {
+		lines = append(lines, "")
+	}
+
+	lines = append(lines, d.general...)
+	for len(lines) < height-1 {
+		lines = append(lines, "")
+	}
+
+	lines = append(lines, "↑/↓ select · enter view log · esc back · ctrl+c interrupt")
+
+	d.frame++
+
+	return d.frameOf(lines, width)
+}
+
+// getStatusLabel returns the status of the step with its symbol
+func (d *progressDashboard) getStatusLabel(s *stepProgress) string {
+	switch s.Status {
+	case "running":
+		return progressSpinner[d.frame%len(progressSpinner)] + " running"
+	case "success":
+		return "✔ success"
+	case "fail":
+		return "✖ fail"
+	case "skipped":
+		return "↷ skipped"
+	default:
+		return "- " + s.Status
+	}
+}
+
+// frameOf joins the lines of a frame truncated to the terminal width
+func (d *progressDashboard) frameOf(lines []string, width int) string {
+	for i, line := range lines {
+		if runes := []rune(line); len(runes) > width {
+			lines[i] = string(runes[:width])
+		}
+	}
+
+	return strings.Join(lines, "\r\n")
+}
+
+// summary returns the final status of every step and the warnings and errors of the run
+func (d *progressDashboard) summary() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "runiac %s\n", d.title)
+
+	for _, s := range d.steps {
+		fmt.Fprintf(&b, "  %-8s %s %s %s\n", s.Status, s.ID(), s.Region, s.Elapsed(d.now()))
+	}
+
+	if len(d.problems) > 0 {
+		b.WriteString("\nWarnings and errors:\n")
+
+		for _, p := range d.problems {
+			fmt.Fprintf(&b, "  %s\n", p)
+		}
+	}
+
+	return b.String()
+}
+
+// start switches the terminal to the dashboard, rendering it until stopped
+func (d *progressDashboard) start() {
+	restore := func() {}
+
+	if fd := int(os.Stdin.Fd()); terminal.IsTerminal(fd) {
+		if state, err := terminal.MakeRaw(fd); err == nil {
+			stopKeys := startProgressKeys(fd, progressKeys)
+
+			restore = func() {
+				stopKeys()
+				_ = terminal.Restore(fd, state)
+			}
+		}
+	}
+
+	d.restore = restore
+
+	// a fatal error restores the terminal before exiting
+	progressExitOnce.Do(func() {
+		logrus.RegisterExitHandler(stopProgressView)
+	})
+
+	// runiac's own logs are part of the dashboard while it renders
+	d.logOutput = logrus.StandardLogger().Out
+	logrus.SetOutput(d)
+
+	fmt.Fprint(d.out, "\x1b[?1049h\x1b[?25l")
+
+	d.rendering.Add(1)
+
+	go func() {
+		defer d.rendering.Done()
+
+		ticker := time.NewTicker(progressRefreshInterval)
+		defer ticker.Stop()
+
+		for {
+			fmt.Fprint(d.out, "\x1b[H\x1b[2J"+d.render())
+
+			select {
+			case <-d.done:
+				return
+			case key := <-progressKeys:
+				d.handleKey(key)
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// stop restores the terminal and prints the summary of the run
+func (d *progressDashboard) stop() {
+	d.stopped.Do(func() {
+		close(d.done)
+		d.rendering.Wait()
+
+		d.restore()
+		logrus.SetOutput(d.logOutput)
+
+		fmt.Fprint(d.out, "\x1b[?25h\x1b[?1049l")
+		fmt.Fprint(d.out, d.summary())
+	})
+}
+
+// readProgressKeys sends the dashboard keys read from in until reading fails, keys pressed while the dashboard is
+// busy rendering are dropped
+func readProgressKeys(in io.Reader, keys chan<- string) {
+	buf := make([]byte, 16)
+
+	for {
+		n, err := in.Read(buf)
+		if err != nil {
+			return
+		}
+
+		if key := parseProgressKey(buf[:n]); key != "" {
+			select {
+			case keys <- key:
+			default:
+			}
+		}
+	}
+}
+
+// parseProgressKey returns the dashboard key of the bytes read from the raw terminal
+func parseProgressKey(b []byte) string {
+	switch {
+	case bytes.Equal(b, []byte("\x1b[A")), bytes.Equal(b, []byte("k")):
+		return "up"
+	case bytes.Equal(b, []byte("\x1b[B")), bytes.Equal(b, []byte("j")):
+		return "down"
+	case bytes.Equal(b, []byte("\r")), bytes.Equal(b, []byte("\n")):
+		return "enter"
+	case bytes.Equal(b, []byte("\x1b")), bytes.Equal(b, []byte("q")), bytes.Equal(b, []byte{0x7f}):
+		return "back"
+	case bytes.Equal(b, []byte{0x03}):
+		return "interrupt"
+	}
+
+	return ""
+}
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestProgressDashboard(now *time.Time) *progressDashboard {
+	d := newProgressDashboard(nil, "deploy prod")
+	d.now = func() time.Time { return *now }
+	d.size = func() (int, int) { return 120, 20 }
+
+	return d
+}
+
+func TestProgressDashboard_ShouldTrackStepStatusFromJsonLogs(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	d := newTestProgressDashboard(&now)
+
+	_, err := d.Write([]byte(`{"level":"info","msg":"Executing step","track":"default","step":"network","region":"centralus"}` + "\n"))
+	require.NoError(t, err)
+
+	_, err = d.Write([]byte(`{"level":"info","msg":"Executing step","track":"default","step":"network","region":"eastus"}` + "\n" + `{"level":"info","msg":"Creating vnet","track":"default",`))
+	require.NoError(t, err)
+
+	// the rest of a partially written line
+	now = now.Add(42 * time.Second)
+	_, err = d.Write([]byte(`"step":"network","region":"centralus"}` + "\n"))
+	require.NoError(t, err)
+
+	_, err = d.Write([]byte(`{"level":"info","msg":"Completed step","status":"Success","track":"default","step":"network","region":"centralus"}` + "\n"))
+	require.NoError(t, err)
+
+	_, err = d.Write([]byte(`{"level":"error","msg":"Completed step","status":"Fail","error":"quota exceeded","track":"default","step":"network","region":"eastus"}` + "\n"))
+	require.NoError(t, err)
+
+	require.Len(t, d.steps, 2)
+
+	require.Equal(t, "default/network", d.steps[0].ID())
+	require.Equal(t, "centralus", d.steps[0].Region)
+	require.Equal(t, "success", d.steps[0].Status)
+	require.Equal(t, 42*time.Second, d.steps[0].Elapsed(now.Add(time.Hour)))
+	require.Equal(t, []string{"INFO    Executing step", "INFO    Creating vnet", "INFO    Completed step"}, d.steps[0].Log)
+
+	require.Equal(t, "eastus", d.steps[1].Region)
+	require.Equal(t, "fail", d.steps[1].Status)
+	require.Equal(t, "Completed step: quota exceeded", d.steps[1].LastLine)
+	require.Equal(t, []string{"default/network eastus: ERROR   Completed step: quota exceeded"}, d.problems)
+}
+
+func TestProgressDashboard_ShouldShowLinesWithoutStepBelowSteps(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	d := newTestProgressDashboard(&now)
+
+	_, err := d.Write([]byte(`{"level":"info","msg":"Executing step","track":"default","step":"network","region":"centralus"}` + "\n"))
+	require.NoError(t, err)
+
+	_, err = d.Write([]byte(`time="2021-01-01T00:00:00Z" level=warning msg="Using \"latest\" container tag"` + "\n" + "Step 1/4 : FROM runiac:alpine\n"))
+	require.NoError(t, err)
+
+	now = now.Add(3 * time.Second)
+	frame := d.render()
+	lines := strings.Split(frame, "\r\n")
+
+	require.Len(t, lines, 20)
+	require.Equal(t, "runiac deploy prod  3s elapsed  0/1 steps completed", lines[0])
+	require.Contains(t, lines[3], "> ⠋ running  default/network  centralus        3s  Executing step")
+	require.Equal(t, `WARNING Using "latest" container tag`, lines[15])
+	require.Equal(t, "Step 1/4 : FROM runiac:alpine", lines[16])
+	require.Equal(t, []string{`WARNING Using "latest" container tag`}, d.problems)
+}
+
+func TestProgressDashboard_ShouldDrillIntoSelectedStepLog(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	d := newTestProgressDashboard(&now)
+
+	for _, line := range []string{
+		`{"level":"info","msg":"Executing step","track":"default","step":"network","region":"centralus"}`,
+		`{"level":"info","msg":"Executing step","track":"default","step":"dns","region":"centralus"}`,
+		`{"level":"info","msg":"Creating zone","track":"default","step":"dns","region":"centralus"}`,
+	} {
+		_, err := d.Write([]byte(line + "\n"))
+		require.NoError(t, err)
+	}
+
+	d.handleKey("down")
+	d.handleKey("enter")
+
+	lines := strings.Split(d.render(), "\r\n")
+
+	require.Equal(t, "default/dns centralus  running  0s", lines[0])
+	require.Equal(t, "INFO    Executing step", lines[2])
+	require.Equal(t, "INFO    Creating zone", lines[3])
+
+	d.handleKey("back")
+	d.handleKey("up")
+
+	require.False(t, d.detail)
+	require.Equal(t, 0, d.selected)
+}
+
+func TestProgressDashboard_ShouldSummarizeSteps(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	d := newTestProgressDashboard(&now)
+
+	for _, line := range []string{
+		`{"level":"info","msg":"Executing step","track":"default","step":"network","region":"centralus"}`,
+		`{"level":"info","msg":"Skipping step due to failure","track":"default","step":"dns","region":"centralus"}`,
+	} {
+		_, err := d.Write([]byte(line + "\n"))
+		require.NoError(t, err)
+	}
+
+	now = now.Add(time.Minute)
+	_, err := d.Write([]byte(`{"level":"info","msg":"Completed step","status":"Success","track":"default","step":"network","region":"centralus"}` + "\n"))
+	require.NoError(t, err)
+
+	require.Equal(t, "runiac deploy prod\n  success  default/network centralus 1m0s\n  skipped  default/dns centralus 0s\n", d.summary())
+}
+
+func TestParseProgressKey_ShouldMapTerminalInput(t *testing.T) {
+	tests := map[string]string{
+		"\x1b[A": "up",
+		"k":      "up",
+		"\x1b[B": "down",
+		"\r":     "enter",
+		"\x1b":   "back",
+		"\x03":   "interrupt",
+		"x":      "",
+	}
+
+	for input, expected := range tests {
+		require.Equal(t, expected, parseProgressKey([]byte(input)), "input %q", input)
+	}
+}
+
+func TestValidateUIMode_ShouldRequireTerminalForTUI(t *testing.T) {
+	require.NoError(t, validateUIMode(uiModePlain, false))
+	require.NoError(t, validateUIMode(uiModeTUI, true))
+	require.Error(t, validateUIMode(uiModeTUI, false))
+	require.Error(t, validateUIMode("fancy", true))
+
+	Interactive = true
+	defer func() { Interactive = false }()
+
+	require.Error(t, validateUIMode(uiModeTUI, true))
+}
+
+func TestGetContainerLogFormat_ShouldUseJsonForTUI(t *testing.T) {
+	require.Equal(t, "json", getContainerLogFormat(uiModeTUI, "text"))
+	require.Equal(t, "text", getContainerLogFormat(uiModePlain, "text"))
+	require.Equal(t, "", getContainerLogFormat(uiModePlain, ""))
+}
@@ -0,0 +1,47 @@
+//go:build !windows
+// +build !windows
+
+package cmd
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// progressKeysIn reads the terminal's keys through the runtime poller so a read can be interrupted, it stays
+// referenced as closing it would close stdin. Only the fd of the first dashboard's terminal is read.
+var progressKeysIn *os.File
+
+// startProgressKeys sends the dashboard keys read from the terminal until the returned func stops reading. Reading
+// stops before the returned func returns, so the prompts and interactive runs after the dashboard read the input.
+func startProgressKeys(fd int, keys chan<- string) func() {
+	if err := syscall.SetNonblock(fd, true); err != nil {
+		return func() {}
+	}
+
+	if progressKeysIn == nil {
+		progressKeysIn = os.NewFile(uintptr(fd), "/dev/stdin")
+	}
+
+	// a reader that can not be interrupted would keep reading the input after the dashboard, the keys are left
+	// unread instead
+	if err := progressKeysIn.SetReadDeadline(time.Time{}); err != nil {
+		_ = syscall.SetNonblock(fd, false)
+		return func() {}
+	}
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		readProgressKeys(progressKeysIn, keys)
+	}()
+
+	return func() {
+		_ = progressKeysIn.SetReadDeadline(time.Now())
+		<-done
+
+		_ = syscall.SetNonblock(fd, false)
+	}
+}
@@ -0,0 +1,46 @@
+//go:build !windows
+// +build !windows
+
+package cmd
+
+import (
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartProgressKeys_ShouldStopReadingOnceStopped(t *testing.T) {
+	p := make([]int, 2)
+	require.NoError(t, syscall.Pipe(p))
+	defer syscall.Close(p[1])
+
+	defer func() {
+		_ = progressKeysIn.Close()
+		progressKeysIn = nil
+	}()
+
+	keys := make(chan string, 1)
+	stopKeys := startProgressKeys(p[0], keys)
+
+	_, err := syscall.Write(p[1], []byte("j"))
+	require.NoError(t, err)
+
+	select {
+	case key := <-keys:
+		require.Equal(t, "down", key)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "the key should be read while the dashboard renders")
+	}
+
+	stopKeys()
+
+	_, err = syscall.Write(p[1], []byte("y\n"))
+	require.NoError(t, err)
+
+	b := make([]byte, 2)
+	n, err := syscall.Read(p[0], b)
+	require.NoError(t, err)
+	require.Equal(t, "y\n", string(b[:n]), "the input after the dashboard should be left to the prompts")
+}
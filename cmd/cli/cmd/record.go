@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// sessionRecorder captures the output of an interactive session to a file, similar to script(1).
+// Failing to record never interrupts the session itself.
+type sessionRecorder struct {
+	mu     sync.Mutex
+	file   *os.File
+	failed bool
+}
+
+// newSessionRecorder creates the recording file at path, along with its directory
+func newSessionRecorder(path string, args []string) (*sessionRecorder, error) {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &sessionRecorder{file: f}
+
+	_, _ = fmt.Fprintf(f, "Session started on %s [COMMAND=\"%s\"]\n", time.Now().Format(time.RFC3339), strings.Join(args, " "))
+
+	return r, nil
+}
+
+// Write records p, swallowing any error so the session output is still presented when recording fails
+func (r *sessionRecorder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.failed {
+		return len(p), nil
+	}
+
+	_, err := r.file.Write(p)
+	if err != nil {
+		r.failed = true
+		logrus.WithError(err).Warnf("Recording to %s failed, the rest of the session will not be recorded", r.file.Name())
+	}
+
+	return len(p), nil
+}
+
+// Close finishes the recording with the result of the session
+func (r *sessionRecorder) Close(sessionErr error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := "0"
+	if sessionErr != nil {
+		result = sessionErr.Error()
+	}
+
+	_, _ = fmt.Fprintf(r.file, "\nSession ended on %s [COMMAND_EXIT=\"%s\"]\n", time.Now().Format(time.RFC3339), result)
+
+	return r.file.Close()
+}
+
+// isTerminal returns true when f is attached to a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// getInteractiveArguments returns the container run arguments for an interactive session, only allocating
+// a TTY when stdin is a terminal so piped or redirected input does not fail
+func getInteractiveArguments(stdinIsTerminal bool) []string {
+	if !stdinIsTerminal {
+		logrus.Warn("--interactive is set but stdin is not a terminal, running without a TTY")
+		return []string{"-i"}
+	}
+
+	return []string{"-it"}
+}
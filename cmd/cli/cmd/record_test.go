@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionRecorder_ShouldRecordSessionWithResult(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions", "debug.log")
+
+	recorder, err := newSessionRecorder(path, []string{"docker", "run", "-it"})
+	require.NoError(t, err)
+
+	n, err := recorder.Write([]byte("terraform plan\n"))
+	require.NoError(t, err)
+	require.Equal(t, 15, n)
+
+	require.NoError(t, recorder.Close(errors.New("exit status 1")))
+
+	b, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	require.Contains(t, lines[0], `[COMMAND="docker run -it"]`)
+	require.Equal(t, "terraform plan", lines[1])
+	require.Contains(t, lines[len(lines)-1], `[COMMAND_EXIT="exit status 1"]`)
+}
+
+func TestSessionRecorder_ShouldNotFailSessionWhenRecordingFails(t *testing.T) {
+	recorder, err := newSessionRecorder(filepath.Join(t.TempDir(), "debug.log"), []string{})
+	require.NoError(t, err)
+
+	// closing the underlying file makes every following write fail
+	_ = recorder.file.Close()
+
+	n, err := recorder.Write([]byte("output"))
+	require.NoError(t, err)
+	require.Equal(t, 6, n)
+}
+
+func TestGetInteractiveArguments_ShouldOnlyAllocateTTYForTerminals(t *testing.T) {
+	require.Equal(t, []string{"-it"}, getInteractiveArguments(true))
+	require.Equal(t, []string{"-i"}, getInteractiveArguments(false))
+}
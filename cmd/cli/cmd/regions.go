@@ -0,0 +1,235 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+// projectHashSkipDirs are not deployment inputs, ie. runiac's local data and downloaded terraform providers
+var projectHashSkipDirs = map[string]bool{
+	".git":       true,
+	".runiac":    true,
+	".terraform": true,
+}
+
+// getProjectHash returns a hash of the project files, or of the tarball when building from a --context tarball
+func getProjectHash(fs afero.Fs, contextTar string) (string, error) {
+	h := sha256.New()
+
+	if contextTar != "" {
+		f, err := fs.Open(contextTar)
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+
+		if _, err := io.Copy(h, f); err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("%x", h.Sum(nil)), nil
+	}
+
+	err := afero.Walk(fs, ".", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if projectHashSkipDirs[info.Name()] {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		b, err := afero.ReadFile(fs, path)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(h, "%s\x00%x\x00", filepath.ToSlash(path), sha256.Sum256(b))
+
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// regionVarFilesDir is the directory of a step's regional directory containing the var files of each region, e.g.
+// regional/regions/eastus.tfvars, which only the region's deployment reads
+const regionVarFilesDir = "regions"
+
+// trackVarFilePattern matches the track.tfvars and track.{environment}.tfvars var files of a track
+var trackVarFilePattern = regexp.MustCompile(`^track(\.[^.]+)?\.tfvars(\.json)?$`)
+
+// regionalInputs are the hashes of the project files the regional deployments read
+type regionalInputs struct {
+	Shared  string            // The regional directories of the steps and the var files of their tracks, read by every region
+	Regions map[string]string // The region var files of each region
+}
+
+// getRegionalInputs hashes the inputs of the regional deployments: each step's regional directory and the var files
+// of its track are read by every region, the var files of regional/regions/{region}.tfvars only by the region. The
+// other project files, e.g. the primary deployments, are not regional inputs.
+func getRegionalInputs(fs afero.Fs) (regionalInputs, error) {
+	shared := sha256.New()
+	regions := map[string]hash.Hash{}
+
+	err := afero.Walk(fs, ".", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// the regional-{region} directories are the copies of the regional directory the executions ran in
+		if info.IsDir() {
+			if projectHashSkipDirs[info.Name()] || strings.HasPrefix(info.Name(), "regional-") {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		parts := strings.Split(filepath.ToSlash(path), "/")
+		region, regional := getRegionalInput(parts)
+
+		isTrackVarFile := trackVarFilePattern.MatchString(info.Name()) && (len(parts) == 1 || (len(parts) == 3 && parts[0] == tracksDir))
+
+		if !regional && !isTrackVarFile {
+			return nil
+		}
+
+		b, err := afero.ReadFile(fs, path)
+		if err != nil {
+			return err
+		}
+
+		h := shared
+
+		if region != "" {
+			if regions[region] == nil {
+				regions[region] = sha256.New()
+			}
+
+			h = regions[region]
+		}
+
+		fmt.Fprintf(h, "%s\x00%x\x00", filepath.ToSlash(path), sha256.Sum256(b))
+
+		return nil
+	})
+	if err != nil {
+		return regionalInputs{}, err
+	}
+
+	inputs := regionalInputs{Shared: fmt.Sprintf("%x", shared.Sum(nil)), Regions: map[string]string{}}
+
+	for region, h := range regions {
+		inputs.Regions[region] = fmt.Sprintf("%x", h.Sum(nil))
+	}
+
+	return inputs, nil
+}
+
+// getRegionalInput returns whether the file is in the regional directory of a step, {step}/regional/..., and the
+// region of a region var file, {step}/regional/regions/{region}.tfvars
+func getRegionalInput(parts []string) (string, bool) {
+	for i := 1; i < len(parts)-1; i++ {
+		if parts[i] != "regional" || !strings.HasPrefix(parts[i-1], stepDirPrefix) {
+			continue
+		}
+
+		if i+3 == len(parts) && parts[i+1] == regionVarFilesDir {
+			name := parts[i+2]
+
+			for _, ext := range []string{".tfvars.json", ".tfvars"} {
+				if strings.HasSuffix(name, ext) {
+					return strings.ToLower(strings.TrimSuffix(name, ext)), true
+				}
+			}
+		}
+
+		return "", true
+	}
+
+	return "", false
+}
+
+// getRegionFingerprints returns a fingerprint of the inputs of each regional region's deployment: the regional
+// inputs every region reads, the region's own var files, the resolved deployment settings and the TF_VAR_* variables
+// passed to the container. A change of a region's var files only changes the region's fingerprint.
+func getRegionFingerprints(inputs regionalInputs, ring string, account string, regions []string) map[string]string {
+	shared := []string{
+		inputs.Shared,
+		ring,
+		account,
+		Environment,
+		Namespace,
+		AppVersion,
+		Runner,
+		strings.Join(PrimaryRegions, ","),
+		strings.Join(StepWhitelist, ","),
+	}
+
+	vars := []string{}
+	for _, e := range os.Environ() {
+		if strings.HasPrefix(e, "TF_VAR_") {
+			vars = append(vars, e)
+		}
+	}
+
+	sort.Strings(vars)
+	shared = append(shared, vars...)
+
+	fingerprints := map[string]string{}
+
+	for _, region := range regions {
+		fingerprint := append(append([]string{}, shared...), region, inputs.Regions[strings.ToLower(region)])
+		fingerprints[region] = fmt.Sprintf("%x", sha256.Sum256([]byte(strings.Join(fingerprint, "\x00"))))
+	}
+
+	return fingerprints
+}
+
+// getLastRegionFingerprints returns the region fingerprints of the most recent successful deploy of the ring, account,
+// environment and namespace
+func getLastRegionFingerprints(entries []historyEntry, ring string, account string) (map[string]string, bool) {
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+
+		if e.Result != historyResultSucceeded || e.DryRun || e.RegionInputs == nil {
+			continue
+		}
+
+		if strings.EqualFold(e.DeploymentRing, ring) && e.Account == account && strings.EqualFold(e.Environment, Environment) && strings.EqualFold(e.Namespace, Namespace) {
+			return e.RegionInputs, true
+		}
+	}
+
+	return nil, false
+}
+
+// getChangedRegions returns the regions whose fingerprint differs from, or is missing in, the previous run
+func getChangedRegions(regions []string, current map[string]string, previous map[string]string) []string {
+	changed := []string{}
+
+	for _, region := range regions {
+		if previous[region] != current[region] {
+			changed = append(changed, region)
+		}
+	}
+
+	return changed
+}
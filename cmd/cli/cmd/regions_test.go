@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetProjectHash_ShouldIgnoreRuniacAndProviderDirectories(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	_ = afero.WriteFile(fs, "step1_network/main.tf", []byte("resource {}"), 0644)
+
+	hash, err := getProjectHash(fs, "")
+	require.NoError(t, err)
+
+	_ = afero.WriteFile(fs, ".runiac/history.log", []byte("{}"), 0644)
+	_ = afero.WriteFile(fs, "step1_network/.terraform/providers/provider", []byte("binary"), 0644)
+
+	unchanged, err := getProjectHash(fs, "")
+	require.NoError(t, err)
+	require.Equal(t, hash, unchanged)
+
+	_ = afero.WriteFile(fs, "step1_network/regional/main.tf", []byte("resource {}"), 0644)
+
+	changed, err := getProjectHash(fs, "")
+	require.NoError(t, err)
+	require.NotEqual(t, hash, changed)
+}
+
+func TestGetRegionalInputs_ShouldHashTheInputsOfTheRegionalDeployments(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	_ = afero.WriteFile(fs, "tracks/net/track.tfvars", []byte(`owner = "platform"`), 0644)
+	_ = afero.WriteFile(fs, "tracks/net/step1_vpc/main.tf", []byte("resource {}"), 0644)
+	_ = afero.WriteFile(fs, "tracks/net/step1_vpc/regional/main.tf", []byte("resource {}"), 0644)
+	_ = afero.WriteFile(fs, "tracks/net/step1_vpc/regional/regions/eastus.tfvars", []byte(`zones = 3`), 0644)
+
+	inputs, err := getRegionalInputs(fs)
+	require.NoError(t, err)
+	require.Len(t, inputs.Regions, 1)
+
+	// the primary deployment, the executions' regional copies and other files are not regional inputs
+	_ = afero.WriteFile(fs, "tracks/net/step1_vpc/main.tf", []byte("resource { changed }"), 0644)
+	_ = afero.WriteFile(fs, "tracks/net/step1_vpc/regional-eastus/main.tf", []byte("resource {}"), 0644)
+	_ = afero.WriteFile(fs, "README.md", []byte("docs"), 0644)
+
+	unchanged, err := getRegionalInputs(fs)
+	require.NoError(t, err)
+	require.Equal(t, inputs, unchanged)
+
+	_ = afero.WriteFile(fs, "tracks/net/step1_vpc/regional/regions/eastus.tfvars", []byte(`zones = 2`), 0644)
+
+	changed, err := getRegionalInputs(fs)
+	require.NoError(t, err)
+	require.Equal(t, inputs.Shared, changed.Shared, "a region var file should only change the inputs of its region")
+	require.NotEqual(t, inputs.Regions["eastus"], changed.Regions["eastus"])
+
+	_ = afero.WriteFile(fs, "tracks/net/track.tfvars", []byte(`owner = "network"`), 0644)
+
+	changed, err = getRegionalInputs(fs)
+	require.NoError(t, err)
+	require.NotEqual(t, inputs.Shared, changed.Shared)
+}
+
+func TestGetChangedRegions_ShouldOnlyReturnRegionsWithChangedInputs(t *testing.T) {
+	regions := []string{"eastus", "westus", "centralus"}
+	v1 := regionalInputs{Shared: "regional-v1", Regions: map[string]string{"eastus": "eastus-v1"}}
+	previous := getRegionFingerprints(v1, "prod", "account", regions[:2])
+
+	history := []historyEntry{
+		{DeploymentRing: "prod", Account: "account", Result: historyResultSucceeded, RegionInputs: previous},
+		{DeploymentRing: "prod", Account: "account", Result: historyResultFailed, RegionInputs: map[string]string{}},
+		{DeploymentRing: "dev", Account: "account", Result: historyResultSucceeded, RegionInputs: map[string]string{}},
+	}
+
+	last, ok := getLastRegionFingerprints(history, "prod", "account")
+	require.True(t, ok)
+	require.Equal(t, previous, last, "failed runs and other rings should be ignored")
+
+	_, ok = getLastRegionFingerprints(history, "prod", "other-account")
+	require.False(t, ok)
+
+	// only the region that was not part of the previous deploy has changed inputs
+	current := getRegionFingerprints(v1, "prod", "account", regions)
+	require.Equal(t, []string{"centralus"}, getChangedRegions(regions, current, last))
+
+	// a change of a region's var files only changes the inputs of the region
+	current = getRegionFingerprints(regionalInputs{Shared: "regional-v1", Regions: map[string]string{"eastus": "eastus-v2"}}, "prod", "account", regions)
+	require.Equal(t, []string{"eastus", "centralus"}, getChangedRegions(regions, current, last))
+
+	// a change of the regional inputs every region reads changes the inputs of every region
+	current = getRegionFingerprints(regionalInputs{Shared: "regional-v2", Regions: v1.Regions}, "prod", "account", regions)
+	require.Equal(t, regions, getChangedRegions(regions, current, last))
+}
@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// reportsDir is where the deployment reports are written to unless --report-path is set
+const reportsDir = ".runiac/reports"
+
+// writtenReports are the reports the rings of the run wrote, in deployment order
+var writtenReports []string
+
+// getDefaultReportPath returns the path of the report of a deployment started at now
+func getDefaultReportPath(now time.Time) string {
+	return filepath.Join(reportsDir, fmt.Sprintf("%s.json", now.UTC().Format("20060102T150405Z")))
+}
+
+// isReportWritten returns true when the container wrote the report to path
+func isReportWritten(path string) bool {
+	exists, _ := afero.Exists(appFS, path)
+
+	return exists
+}
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetDefaultReportPath_ShouldBeTimestamped(t *testing.T) {
+	now := time.Date(2021, 6, 1, 9, 30, 15, 0, time.FixedZone("CDT", -5*60*60))
+
+	require.Equal(t, filepath.Join(".runiac", "reports", "20210601T143015Z.json"), getDefaultReportPath(now))
+}
+
+func TestGetRingRunArguments_ShouldMountTheRingScopedReport(t *testing.T) {
+	dir := t.TempDir()
+	ReportPath = filepath.Join(dir, "reports", "report.yaml")
+	defer func() { ReportPath = "" }()
+
+	args, err := getRingRunArguments("prod", "", true, "")
+	require.NoError(t, err)
+
+	require.Contains(t, getMountsFromArgs(args), filepath.Join(dir, "reports")+":"+containerReportDir)
+	require.Contains(t, getEnvFromArgs(args), "RUNIAC_REPORT_PATH="+containerReportDir+"/report.prod.yaml")
+
+	_, err = os.Stat(filepath.Join(dir, "reports"))
+	require.NoError(t, err, "the report directory should be created so the container can write to it")
+}
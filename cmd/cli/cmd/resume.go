@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+const (
+	checkpointFile      = "checkpoint.json"                       // Records the step executions the last deploy of the ring completed, in the ring's local state directory
+	containerCheckpoint = containerTFState + "/" + checkpointFile // The container records the completed step executions here
+)
+
+// validateResume returns an error when --resume or --from-step can not be used with the run or --from-step is not a
+// targeted step of the project
+func validateResume(fs afero.Fs, resume bool, fromStep string, whitelist []string) error {
+	if !resume && fromStep == "" {
+		return nil
+	}
+
+	if DryRun || Destroy {
+		return fmt.Errorf("--resume and --from-step can only be used to deploy, not with --dry-run, plan or destroy")
+	}
+
+	if fromStep == "" {
+		return nil
+	}
+
+	graph, err := getProjectGraph(fs)
+	if err != nil {
+		return err
+	}
+
+	fromStep = strings.TrimSpace(fromStep)
+
+	for _, t := range graph.Tracks {
+		for _, s := range t.Steps {
+			if s.ID != fromStep {
+				continue
+			}
+
+			if len(whitelist) > 0 && !isWhitelisted(whitelist, fromStep) {
+				return fmt.Errorf("--from-step step '%s' is not one of the targeted steps", fromStep)
+			}
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("--from-step step '%s' does not exist, expected a {trackName}/{stepName} step id", fromStep)
+}
+
+// isWhitelisted returns true when the step id is one of the whitelisted steps
+func isWhitelisted(whitelist []string, id string) bool {
+	for _, w := range whitelist {
+		if strings.TrimSpace(w) == id {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateResume_ShouldRequireTargetedFromStep(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	_ = fs.MkdirAll("tracks/app/step1_db", 0755)
+	_ = fs.MkdirAll("tracks/app/step2_api", 0755)
+
+	tests := []struct {
+		name      string
+		resume    bool
+		fromStep  string
+		whitelist []string
+		err       bool
+	}{
+		{"not resumed", false, "", nil, false},
+		{"resumed", true, "", nil, false},
+		{"existing step", false, "app/api", nil, false},
+		{"track directory instead of step id", true, "tracks/app/step2_api", nil, true},
+		{"step does not exist", false, "app/web", nil, true},
+		{"untargeted step", false, "app/api", []string{"app/db"}, true},
+		{"targeted step", false, "app/api", []string{"app/db", "app/api"}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateResume(fs, test.resume, test.fromStep, test.whitelist)
+
+			if test.err {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateResume_ShouldOnlyResumeDeploys(t *testing.T) {
+	DryRun = true
+	defer func() { DryRun = false }()
+
+	require.Error(t, validateResume(afero.NewMemMapFs(), true, "", nil))
+	require.NoError(t, validateResume(afero.NewMemMapFs(), false, "", nil))
+}
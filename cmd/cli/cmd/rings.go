@@ -0,0 +1,346 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+)
+
+const (
+	LocalDeploymentRing       = config.LocalDeploymentRing       // The deployment ring used when executing with --local
+	PullRequestDeploymentRing = config.PullRequestDeploymentRing // The deployment ring used when executing with --pull-request
+)
+
+// configureRingAndNamespace pre-configures the namespace and deployment ring for
+// local development and pull request executions
+func configureRingAndNamespace() error {
+	if Local {
+		namespace, err := getMachineName()
+
+		if err != nil {
+			return err
+		}
+
+		Namespace = namespace
+		DeploymentRings = []string{LocalDeploymentRing}
+	} else if PullRequest != "" {
+		Namespace = PullRequest
+		DeploymentRings = []string{PullRequestDeploymentRing}
+	}
+
+	return nil
+}
+
+// getDeploymentRings returns the rings targeted by this run, a single unnamed ring when none are specified
+func getDeploymentRings() []string {
+	if len(DeploymentRings) == 0 {
+		return []string{""}
+	}
+
+	return DeploymentRings
+}
+
+// getRingConfigKey returns the runiac config file key for a ring specific setting, e.g. rings.prod.account
+func getRingConfigKey(ring string, key string) string {
+	return fmt.Sprintf("rings.%s.%s", strings.ToLower(ring), key)
+}
+
+// resolveRingAccounts returns the account to deploy each ring to. When deploying a single ring, an
+// explicitly set --account takes precedence over rings.{ring}.account. When deploying multiple rings,
+// rings.{ring}.account takes precedence and every ring must resolve to an account.
+func resolveRingAccounts(rings []string, account string, accountFlagSet bool) (map[string]string, error) {
+	accounts := map[string]string{}
+	multipleRings := len(rings) > 1
+
+	for _, ring := range rings {
+		ringAccount := ""
+
+		if ring != "" {
+			ringAccount = viper.GetString(getRingConfigKey(ring, "account"))
+		}
+
+		if ringAccount == "" || (accountFlagSet && !multipleRings) {
+			ringAccount = account
+		}
+
+		if ringAccount == "" && multipleRings {
+			return nil, fmt.Errorf("no account configured for deployment ring '%s', set rings.%s.account in the runiac config", ring, ring)
+		}
+
+		accounts[ring] = ringAccount
+	}
+
+	return accounts, nil
+}
+
+// checkRingEnvironment returns an error when the environment is not one of the environments the ring allows under
+// rings.{ring}.environments in the runiac config, every environment is allowed when none are configured
+func checkRingEnvironment(ring string, environment string) error {
+	if ring == "" {
+		return nil
+	}
+
+	allowed := viper.GetStringSlice(getRingConfigKey(ring, "environments"))
+
+	if len(allowed) == 0 || isRingInSet(environment, allowed) {
+		return nil
+	}
+
+	return fmt.Errorf("deployment ring '%s' can not be deployed to environment '%s', rings.%s.environments only allows %s", ring, environment, strings.ToLower(ring), strings.Join(allowed, ", "))
+}
+
+// ringVariableNamePattern matches terraform input variable names
+var ringVariableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// getRingVariables returns the input variables configured for every ring under variables and for the ring under
+// rings.{ring}.variables in the runiac config, forwarded to every step as TF_VAR_{name}. The ring's variables take
+// precedence. Values that are not strings are json encoded, which terraform parses for list, map and object variables.
+// The runiac config's keys are case insensitive, so names are lower case.
+func getRingVariables(ring string) (map[string]string, error) {
+	variables := map[string]string{}
+
+	keys := []string{"variables"}
+	if ring != "" {
+		keys = append(keys, getRingConfigKey(ring, "variables"))
+	}
+
+	for _, key := range keys {
+		if err := addConfigVariables(variables, key); err != nil {
+			return nil, err
+		}
+	}
+
+	return variables, nil
+}
+
+// addConfigVariables adds the input variables of the runiac config key to variables
+func addConfigVariables(variables map[string]string, key string) error {
+	if !viper.IsSet(key) {
+		return nil
+	}
+
+	for name, value := range viper.GetStringMap(key) {
+		if !ringVariableNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid variable '%s' in %s, the name may only contain letters, digits, '_' and '-'", name, key)
+		}
+
+		if s, ok := value.(string); ok {
+			variables[name] = s
+			continue
+		}
+
+		b, err := json.Marshal(getJSONValue(value))
+		if err != nil {
+			return fmt.Errorf("invalid %s.%s: %w", key, name, err)
+		}
+
+		variables[name] = string(b)
+	}
+
+	return nil
+}
+
+// getJSONValue converts the yaml maps of a config value to maps json can encode
+func getJSONValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := map[string]interface{}{}
+		for k, e := range v {
+			m[fmt.Sprint(k)] = getJSONValue(e)
+		}
+
+		return m
+	case map[string]interface{}:
+		m := map[string]interface{}{}
+		for k, e := range v {
+			m[k] = getJSONValue(e)
+		}
+
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, e := range v {
+			l[i] = getJSONValue(e)
+		}
+
+		return l
+	default:
+		return v
+	}
+}
+
+// getRingVariableArguments returns the container run arguments forwarding each ring variable as a terraform input
+// variable
+func getRingVariableArguments(variables map[string]string) (args []string) {
+	names := make([]string, 0, len(variables))
+
+	for name := range variables {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		args = append(args, "-e", fmt.Sprintf("TF_VAR_%s=%s", name, variables[name]))
+	}
+
+	return
+}
+
+// getRingScopedDir returns a ring specific subdirectory of dir when deploying multiple rings so rings do not collide
+func getRingScopedDir(dir string, ring string, multipleRings bool) string {
+	if dir == "" || !multipleRings {
+		return dir
+	}
+
+	return filepath.Join(dir, ring)
+}
+
+// getRingScopedFile returns a ring specific variant of path when deploying multiple rings, e.g. manifest.prod.json
+func getRingScopedFile(path string, ring string, multipleRings bool) string {
+	if path == "" || !multipleRings {
+		return path
+	}
+
+	ext := filepath.Ext(path)
+
+	return fmt.Sprintf("%s.%s%s", strings.TrimSuffix(path, ext), strings.ToLower(ring), ext)
+}
+
+// isEphemeralRing returns true for rings that only ever contain isolated, short-lived deployments
+func isEphemeralRing(ring string) bool {
+	return strings.EqualFold(ring, LocalDeploymentRing) || strings.EqualFold(ring, PullRequestDeploymentRing)
+}
+
+// isRingInSet returns true when the ring is present in the list of rings, ignoring case
+func isRingInSet(ring string, rings []string) bool {
+	for _, r := range rings {
+		if strings.EqualFold(strings.TrimSpace(r), ring) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getProtectedRings returns the rings configured under protected_rings in the runiac config file
+func getProtectedRings() []string {
+	return viper.GetStringSlice("protected_rings")
+}
+
+// checkDestroyConfirmation ensures a destroy targeting a protected ring has been explicitly
+// confirmed by passing the ring name to --confirm-destroy, which lists every protected ring of a multi-ring destroy.
+// Local and pull request rings are exempt.
+func checkDestroyConfirmation(ring string, confirmations []string, protectedRings []string) error {
+	if ring == "" || isEphemeralRing(ring) || !isRingInSet(ring, protectedRings) {
+		return nil
+	}
+
+	if !isRingInSet(ring, confirmations) {
+		return fmt.Errorf("deployment ring '%s' is protected, re-run with '--confirm-destroy %s' to destroy it", ring, ring)
+	}
+
+	return nil
+}
+
+// resolveRingDockerfile returns the dockerfile to build for the ring. An explicitly configured dockerfile takes
+// precedence, followed by a ring specific {dockerfile}.{ring} (e.g. .runiac/Dockerfile.prod) when present.
+func resolveRingDockerfile(fs afero.Fs, ring string, dockerfile string, explicit bool) (string, error) {
+	if !explicit && ring != "" {
+		ringDockerfile := fmt.Sprintf("%s.%s", dockerfile, strings.ToLower(ring))
+
+		if exists, _ := afero.Exists(fs, ringDockerfile); exists {
+			return ringDockerfile, nil
+		}
+	}
+
+	if exists, _ := afero.Exists(fs, dockerfile); !exists {
+		return "", fmt.Errorf("dockerfile %s does not exist", dockerfile)
+	}
+
+	return dockerfile, nil
+}
+
+// regionPattern matches cloud region names, e.g. us-east-1, centralus or europe-west4
+var regionPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// ringRegions are the primary and regional regions a deployment ring deploys to
+type ringRegions struct {
+	Primary  []string
+	Regional []string
+}
+
+// resolveRingRegions returns the regions to deploy each ring to. rings.{ring}.primary_regions and
+// rings.{ring}.regional_regions apply when the corresponding region flag is not set on the command line,
+// otherwise the region flags are used.
+func resolveRingRegions(rings []string, primary []string, regional []string, primaryFlagSet bool, regionalFlagSet bool) (map[string]ringRegions, error) {
+	regions := map[string]ringRegions{}
+
+	for _, ring := range rings {
+		r := ringRegions{Primary: primary, Regional: regional}
+
+		if ring != "" && !primaryFlagSet && viper.IsSet(getRingConfigKey(ring, "primary_regions")) {
+			r.Primary = getConfigRegions(getRingConfigKey(ring, "primary_regions"))
+		}
+
+		if ring != "" && !regionalFlagSet && viper.IsSet(getRingConfigKey(ring, "regional_regions")) {
+			r.Regional = getConfigRegions(getRingConfigKey(ring, "regional_regions"))
+		}
+
+		err := validateRegions(r.Primary)
+		if err != nil {
+			return nil, fmt.Errorf("invalid primary regions for deployment ring '%s': %w", ring, err)
+		}
+
+		err = validateRegions(r.Regional)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regional regions for deployment ring '%s': %w", ring, err)
+		}
+
+		regions[ring] = r
+	}
+
+	return regions, nil
+}
+
+// getConfigRegions returns the regions of the runiac config key, a list or a comma separated string as read by the
+// deploy container
+func getConfigRegions(key string) []string {
+	regions := []string{}
+
+	for _, value := range viper.GetStringSlice(key) {
+		for _, region := range strings.Split(value, ",") {
+			if region = strings.TrimSpace(region); region != "" {
+				regions = append(regions, region)
+			}
+		}
+	}
+
+	return regions
+}
+
+// validateRegions returns an error for region names that are not valid or are listed more than once
+func validateRegions(regions []string) error {
+	seen := map[string]bool{}
+
+	for _, region := range regions {
+		if !regionPattern.MatchString(region) {
+			return fmt.Errorf("'%s' is not a valid region name", region)
+		}
+
+		if seen[region] {
+			return fmt.Errorf("region '%s' is listed more than once", region)
+		}
+
+		seen[region] = true
+	}
+
+	return nil
+}
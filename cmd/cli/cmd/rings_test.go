@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckDestroyConfirmation_ShouldRequireRingNameForProtectedRings(t *testing.T) {
+	protectedRings := []string{"prod", "Stage", "local"}
+
+	tests := []struct {
+		ring          string
+		confirmations []string
+		expectErr     bool
+	}{
+		{"prod", nil, true},
+		{"prod", []string{"stage"}, true},
+		{"prod", []string{"prod"}, false},
+		{"stage", []string{"stage"}, false},
+		{"prod", []string{"stage", " prod"}, false},
+		{"dev", nil, false},
+		{"", nil, false},
+		{"local", nil, false},
+		{"pr", nil, false},
+	}
+
+	for _, tt := range tests {
+		err := checkDestroyConfirmation(tt.ring, tt.confirmations, protectedRings)
+
+		if tt.expectErr {
+			require.Error(t, err, "checkDestroyConfirmation(\"%s\", %v) should fail", tt.ring, tt.confirmations)
+		} else {
+			require.NoError(t, err, "checkDestroyConfirmation(\"%s\", %v) should succeed", tt.ring, tt.confirmations)
+		}
+	}
+}
+
+func TestCheckDestroyConfirmation_ShouldRequireEveryProtectedRingOfAMultiRingDestroy(t *testing.T) {
+	protectedRings := []string{"stage", "prod"}
+	rings := []string{"dev", "stage", "prod"}
+
+	check := func(confirmations []string) error {
+		for _, ring := range rings {
+			if err := checkDestroyConfirmation(ring, confirmations, protectedRings); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	require.Error(t, check([]string{"prod"}), "the protected stage ring is not confirmed")
+	require.NoError(t, check([]string{"stage", "prod"}), "every protected ring is confirmed")
+}
+
+func TestResolveRingAccounts_ShouldUseRingAccountsWhenDeployingMultipleRings(t *testing.T) {
+	viper.Set("rings.dev.account", "dev-account")
+	viper.Set("rings.prod.account", "prod-account")
+	defer viper.Set("rings", nil)
+
+	accounts, err := resolveRingAccounts([]string{"dev", "prod"}, "flag-account", true)
+	require.NoError(t, err)
+	require.Equal(t, "dev-account", accounts["dev"])
+	require.Equal(t, "prod-account", accounts["prod"])
+
+	// explicit flag wins for a single ring
+	accounts, err = resolveRingAccounts([]string{"prod"}, "flag-account", true)
+	require.NoError(t, err)
+	require.Equal(t, "flag-account", accounts["prod"])
+
+	// --account is the fallback for rings without a configured account
+	accounts, err = resolveRingAccounts([]string{"dev", "stage"}, "flag-account", true)
+	require.NoError(t, err)
+	require.Equal(t, "flag-account", accounts["stage"])
+
+	// every ring must resolve to an account
+	_, err = resolveRingAccounts([]string{"dev", "stage"}, "", false)
+	require.Error(t, err)
+}
+
+func TestResolveRingDockerfile_ShouldPreferRingDockerfileUnlessExplicit(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	_ = afero.WriteFile(fs, ".runiac/Dockerfile", []byte("FROM base"), 0644)
+	_ = afero.WriteFile(fs, ".runiac/Dockerfile.prod", []byte("FROM hardened"), 0644)
+	_ = afero.WriteFile(fs, "custom.Dockerfile", []byte("FROM custom"), 0644)
+
+	tests := []struct {
+		ring       string
+		dockerfile string
+		explicit   bool
+		expected   string
+	}{
+		{"prod", ".runiac/Dockerfile", false, ".runiac/Dockerfile.prod"},
+		{"PROD", ".runiac/Dockerfile", false, ".runiac/Dockerfile.prod"},
+		{"dev", ".runiac/Dockerfile", false, ".runiac/Dockerfile"},
+		{"", ".runiac/Dockerfile", false, ".runiac/Dockerfile"},
+		{"prod", "custom.Dockerfile", true, "custom.Dockerfile"},
+	}
+
+	for _, tt := range tests {
+		dockerfile, err := resolveRingDockerfile(fs, tt.ring, tt.dockerfile, tt.explicit)
+
+		require.NoError(t, err)
+		require.Equal(t, tt.expected, dockerfile, "resolveRingDockerfile(\"%s\", \"%s\", %v)", tt.ring, tt.dockerfile, tt.explicit)
+	}
+
+	_, err := resolveRingDockerfile(fs, "prod", "missing.Dockerfile", true)
+	require.Error(t, err, "a dockerfile that does not exist should fail")
+}
+
+func TestResolveRingRegions_ShouldUseRingRegionsUnlessFlagsAreSet(t *testing.T) {
+	viper.Set("rings.prod.primary_regions", []string{"us-east-1"})
+	viper.Set("rings.prod.regional_regions", []string{"us-east-1", "us-west-2", "eu-west-1"})
+	defer viper.Set("rings", nil)
+
+	regions, err := resolveRingRegions([]string{"dev", "prod"}, []string{"us-east-2"}, []string{"us-east-2"}, false, false)
+	require.NoError(t, err)
+	require.Equal(t, []string{"us-east-2"}, regions["dev"].Primary, "rings without configured regions should use the region flags")
+	require.Equal(t, []string{"us-east-2"}, regions["dev"].Regional)
+	require.Equal(t, []string{"us-east-1"}, regions["prod"].Primary)
+	require.Equal(t, []string{"us-east-1", "us-west-2", "eu-west-1"}, regions["prod"].Regional)
+
+	// explicit flags win
+	regions, err = resolveRingRegions([]string{"prod"}, []string{"us-east-2"}, []string{"us-east-2"}, true, false)
+	require.NoError(t, err)
+	require.Equal(t, []string{"us-east-2"}, regions["prod"].Primary)
+	require.Equal(t, []string{"us-east-1", "us-west-2", "eu-west-1"}, regions["prod"].Regional)
+
+	regions, err = resolveRingRegions([]string{"prod"}, []string{}, []string{"us-east-2"}, false, true)
+	require.NoError(t, err)
+	require.Equal(t, []string{"us-east-1"}, regions["prod"].Primary)
+	require.Equal(t, []string{"us-east-2"}, regions["prod"].Regional)
+
+	viper.Set("rings.prod.primary_regions", []string{"us-east-1", "us-west-2"})
+
+	regions, err = resolveRingRegions([]string{"prod"}, []string{}, []string{}, false, false)
+	require.NoError(t, err)
+	require.Equal(t, []string{"us-east-1", "us-west-2"}, regions["prod"].Primary, "the primary steps deploy to every primary region")
+}
+
+func TestResolveRingRegions_ShouldValidateRegions(t *testing.T) {
+	defer viper.Set("rings", nil)
+
+	tests := []struct {
+		primary  []string
+		regional []string
+	}{
+		{[]string{"us-east-1", "us-east-1"}, []string{}},
+		{[]string{"US East"}, []string{}},
+		{[]string{"us-east-1"}, []string{"us-west-2", "us_west_1"}},
+		{[]string{"us-east-1"}, []string{"us-west-2", "us-west-2"}},
+	}
+
+	for _, tt := range tests {
+		viper.Set("rings.prod.primary_regions", tt.primary)
+		viper.Set("rings.prod.regional_regions", tt.regional)
+
+		_, err := resolveRingRegions([]string{"prod"}, []string{}, []string{}, false, false)
+		require.Error(t, err, "resolveRingRegions with primary %v and regional %v should fail", tt.primary, tt.regional)
+	}
+}
+
+func TestGetConfigRegions_ShouldSplitCommaSeparatedRegions(t *testing.T) {
+	viper.Set("rings.prod.regional_regions", "us-east-1, us-west-2")
+	defer viper.Set("rings", nil)
+
+	require.Equal(t, []string{"us-east-1", "us-west-2"}, getConfigRegions("rings.prod.regional_regions"))
+	require.Empty(t, getConfigRegions("rings.dev.regional_regions"))
+}
+
+func TestCheckRingEnvironment_ShouldOnlyAllowConfiguredEnvironments(t *testing.T) {
+	viper.Set("rings.prod.environments", []string{"prod", "dr"})
+	defer viper.Set("rings", nil)
+
+	require.NoError(t, checkRingEnvironment("prod", "dr"))
+	require.NoError(t, checkRingEnvironment("dev", "prod"), "rings without environments should allow every environment")
+	require.NoError(t, checkRingEnvironment("", "nonprod"))
+	require.EqualError(t, checkRingEnvironment("Prod", "nonprod"), "deployment ring 'Prod' can not be deployed to environment 'nonprod', rings.prod.environments only allows prod, dr")
+}
+
+func TestGetRingVariables_ShouldEncodeNonStringValues(t *testing.T) {
+	// as decoded from the runiac config
+	viper.Set("rings.prod.variables", map[string]interface{}{
+		"instance_type": "m5.large",
+		"replicas":      3,
+		"zones":         []interface{}{"a", "b"},
+		"tags":          map[interface{}]interface{}{"team": "platform"},
+	})
+	defer viper.Set("rings", nil)
+
+	variables, err := getRingVariables("prod")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"instance_type": "m5.large",
+		"replicas":      "3",
+		"zones":         `["a","b"]`,
+		"tags":          `{"team":"platform"}`,
+	}, variables)
+
+	require.Equal(t, []string{"-e", "TF_VAR_instance_type=m5.large", "-e", "TF_VAR_replicas=3"}, getRingVariableArguments(map[string]string{"replicas": "3", "instance_type": "m5.large"}))
+
+	variables, err = getRingVariables("dev")
+	require.NoError(t, err)
+	require.Empty(t, variables)
+
+	viper.Set("variables", map[string]interface{}{"owner": "platform", "replicas": 1})
+	defer viper.Set("variables", nil)
+
+	variables, err = getRingVariables("prod")
+	require.NoError(t, err)
+	require.Equal(t, "platform", variables["owner"], "every ring should receive the variables")
+	require.Equal(t, "3", variables["replicas"], "the ring's variables should take precedence")
+
+	variables, err = getRingVariables("")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"owner": "platform", "replicas": "1"}, variables)
+
+	viper.Set("rings.prod.variables", map[string]interface{}{"not valid": "x"})
+
+	_, err = getRingVariables("prod")
+	require.Error(t, err, "a variable name with a space should be invalid")
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// roleArnPattern matches the arn of an aws iam role
+var roleArnPattern = regexp.MustCompile(`^arn:aws[a-z-]*:iam::[0-9]{12}:role/.+$`)
+
+// assumedRoleCredentials are the temporary credentials of aws sts assume-role
+type assumedRoleCredentials struct {
+	Credentials struct {
+		AccessKeyID     string `json:"AccessKeyId"`
+		SecretAccessKey string `json:"SecretAccessKey"`
+		SessionToken    string `json:"SessionToken"`
+		Expiration      string `json:"Expiration"`
+	} `json:"Credentials"`
+}
+
+// getAccountRole returns the aws role to assume for deploying the account in the environment, configured under
+// accounts.{environment}.{account id} in the runiac config. Empty when none is configured.
+func getAccountRole(environment string, account string) (string, error) {
+	if environment == "" || account == "" {
+		return "", nil
+	}
+
+	key := fmt.Sprintf("accounts.%s.%s", strings.ToLower(environment), account)
+
+	role := viper.GetString(key)
+	if role == "" {
+		return "", nil
+	}
+
+	if !roleArnPattern.MatchString(role) {
+		return "", fmt.Errorf("invalid role '%s' in %s, must be the arn of an iam role, e.g. arn:aws:iam::%s:role/deployer", role, key, account)
+	}
+
+	return role, nil
+}
+
+// resolveRingRoles returns the aws role to assume for deploying each ring to its account, none for the rings
+// without a configured role
+func resolveRingRoles(rings []string, accounts map[string]string, environment string) (map[string]string, error) {
+	roles := map[string]string{}
+
+	for _, ring := range rings {
+		role, err := getAccountRole(environment, accounts[ring])
+		if err != nil {
+			return nil, err
+		}
+
+		if role != "" {
+			roles[ring] = role
+		}
+	}
+
+	return roles, nil
+}
+
+// getRoleSessionName returns the session name of the assumed role, correlating the cloudtrail events of the
+// session with the ring's run
+func getRoleSessionName(correlationID string) string {
+	return fmt.Sprintf("runiac-%s", correlationID)
+}
+
+// getAssumeRoleArguments returns the container run arguments deploying with the role. With a web identity token on
+// the host, e.g. of a CI's OIDC provider, the token is mounted for the steps to assume the role themselves since
+// they refresh its credentials. Otherwise, and in a kubernetes Job which can not mount the token, the role is
+// assumed with the host's aws credentials and its temporary credentials are passed.
+func getAssumeRoleArguments(role string, sessionName string, mountToken bool) ([]string, error) {
+	if os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE") != "" && mountToken {
+		args, err := getCredentialFileArguments("AWS_WEB_IDENTITY_TOKEN_FILE", credentialsAWS)
+		if err != nil {
+			return nil, err
+		}
+
+		return append(args, "-e", fmt.Sprintf("AWS_ROLE_ARN=%s", role), "-e", fmt.Sprintf("AWS_ROLE_SESSION_NAME=%s", sessionName)), nil
+	}
+
+	out, err := runCredentialHelper("aws", "sts", "assume-role", "--role-arn", role, "--role-session-name", sessionName, "--output", "json")
+	if err != nil {
+		return nil, fmt.Errorf("unable to assume the role %s: %w", role, err)
+	}
+
+	assumed := assumedRoleCredentials{}
+
+	if err = json.Unmarshal(out, &assumed); err != nil || assumed.Credentials.AccessKeyID == "" {
+		return nil, fmt.Errorf("unable to read the credentials of the assumed role %s: %v", role, err)
+	}
+
+	args := []string{
+		"-e", fmt.Sprintf("AWS_ACCESS_KEY_ID=%s", assumed.Credentials.AccessKeyID),
+		"-e", fmt.Sprintf("AWS_SECRET_ACCESS_KEY=%s", assumed.Credentials.SecretAccessKey),
+		"-e", fmt.Sprintf("AWS_SESSION_TOKEN=%s", assumed.Credentials.SessionToken),
+	}
+
+	if assumed.Credentials.Expiration != "" {
+		args = append(args, "-e", fmt.Sprintf("AWS_CREDENTIAL_EXPIRATION=%s", assumed.Credentials.Expiration))
+	}
+
+	return args, nil
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveRingRoles_ShouldUseTheRoleOfTheEnvironmentsAccount(t *testing.T) {
+	viper.Set("accounts", map[string]interface{}{
+		"prod": map[string]interface{}{
+			"111111111111": "arn:aws:iam::111111111111:role/Deployer",
+		},
+		"dev": map[string]interface{}{
+			"222222222222": "not-a-role",
+		},
+	})
+	defer viper.Set("accounts", nil)
+
+	roles, err := resolveRingRoles([]string{"us", "eu"}, map[string]string{"us": "111111111111", "eu": "333333333333"}, "Prod")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"us": "arn:aws:iam::111111111111:role/Deployer"}, roles)
+
+	roles, err = resolveRingRoles([]string{""}, map[string]string{"": ""}, "prod")
+	require.NoError(t, err)
+	require.Empty(t, roles)
+
+	_, err = resolveRingRoles([]string{"us"}, map[string]string{"us": "222222222222"}, "dev")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid role 'not-a-role' in accounts.dev.222222222222")
+}
+
+func TestGetAssumeRoleArguments_ShouldPassTheTemporaryCredentials(t *testing.T) {
+	defer func(run func(string, ...string) ([]byte, error)) { runCredentialHelper = run }(runCredentialHelper)
+
+	var helper string
+
+	runCredentialHelper = func(name string, args ...string) ([]byte, error) {
+		helper = name + " " + strings.Join(args, " ")
+
+		return []byte(`{"Credentials": {"AccessKeyId": "ASIASTUB", "SecretAccessKey": "secret", "SessionToken": "session", "Expiration": "2026-10-14T12:00:00Z"}}`), nil
+	}
+
+	args, err := getAssumeRoleArguments("arn:aws:iam::111111111111:role/Deployer", "runiac-0123abcdef", true)
+	require.NoError(t, err)
+	require.Equal(t, "aws sts assume-role --role-arn arn:aws:iam::111111111111:role/Deployer --role-session-name runiac-0123abcdef --output json", helper)
+	require.Equal(t, []string{
+		"-e", "AWS_ACCESS_KEY_ID=ASIASTUB",
+		"-e", "AWS_SECRET_ACCESS_KEY=secret",
+		"-e", "AWS_SESSION_TOKEN=session",
+		"-e", "AWS_CREDENTIAL_EXPIRATION=2026-10-14T12:00:00Z",
+	}, args)
+
+	runCredentialHelper = func(name string, args ...string) ([]byte, error) { return nil, errors.New("access denied") }
+
+	_, err = getAssumeRoleArguments("arn:aws:iam::111111111111:role/Deployer", "runiac-0123abcdef", true)
+	require.EqualError(t, err, "unable to assume the role arn:aws:iam::111111111111:role/Deployer: access denied")
+}
+
+func TestGetAssumeRoleArguments_ShouldMountTheWebIdentityToken(t *testing.T) {
+	defer func(run func(string, ...string) ([]byte, error)) { runCredentialHelper = run }(runCredentialHelper)
+
+	runCredentialHelper = func(name string, args ...string) ([]byte, error) {
+		return []byte(`{"Credentials": {"AccessKeyId": "ASIASTUB"}}`), nil
+	}
+
+	token := filepath.Join(t.TempDir(), "oidc-token")
+	require.NoError(t, ioutil.WriteFile(token, []byte("eyJ"), 0600))
+
+	_ = os.Setenv("AWS_WEB_IDENTITY_TOKEN_FILE", token)
+	defer os.Unsetenv("AWS_WEB_IDENTITY_TOKEN_FILE")
+
+	args, err := getAssumeRoleArguments("arn:aws:iam::111111111111:role/Deployer", "runiac-0123abcdef", true)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"-v", token + ":/runiac/credentials/aws/oidc-token:ro",
+		"-e", "AWS_WEB_IDENTITY_TOKEN_FILE=/runiac/credentials/aws/oidc-token",
+		"-e", "AWS_ROLE_ARN=arn:aws:iam::111111111111:role/Deployer",
+		"-e", "AWS_ROLE_SESSION_NAME=runiac-0123abcdef",
+	}, args)
+
+	// a kubernetes job can not mount the token
+	args, err = getAssumeRoleArguments("arn:aws:iam::111111111111:role/Deployer", "runiac-0123abcdef", false)
+	require.NoError(t, err)
+	require.Contains(t, args, "AWS_ACCESS_KEY_ID=ASIASTUB")
+}
+
+func TestReplaceEnvArguments_ShouldRemoveOverriddenEnv(t *testing.T) {
+	args := []string{"-e", "AWS_ROLE_ARN=arn:aws:iam::222222222222:role/Host", "-e", "AWS_REGION=us-east-1", "-v", "/a:/b", "-e", "AWS_ROLE_ARN"}
+
+	require.Equal(t, []string{
+		"-e", "AWS_REGION=us-east-1",
+		"-v", "/a:/b",
+		"-e", "AWS_ROLE_ARN=arn:aws:iam::111111111111:role/Deployer",
+	}, replaceEnvArguments(args, []string{"-e", "AWS_ROLE_ARN=arn:aws:iam::111111111111:role/Deployer"}))
+}
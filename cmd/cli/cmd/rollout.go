@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+// rolloutWaveKeys are the keys of a wave of the regional_rollout section of the runiac config
+var rolloutWaveKeys = []string{"regions", "parallel"}
+
+// rolloutWave is a wave of the regional_rollout section of the runiac config. The deploy container deploys the
+// regional regions of each wave once the earlier waves completed, a failed wave halts the later waves:
+//
+//	regional_rollout:
+//	  - regions: [us-east-1]                       # canary
+//	  - regions: [us-east-2, us-west-1, us-west-2]
+//	    parallel: 2                                # regions of the wave deployed concurrently, all when 0
+type rolloutWave struct {
+	Regions  []string `mapstructure:"regions"`
+	Parallel int      `mapstructure:"parallel"`
+}
+
+// getRegionalRollout returns the waves of the regional_rollout section of the runiac config, an error when a wave is
+// invalid
+func getRegionalRollout(config *viper.Viper) ([]rolloutWave, error) {
+	waves := []rolloutWave{}
+
+	if err := config.UnmarshalKey("regional_rollout", &waves); err != nil {
+		return nil, fmt.Errorf("invalid regional_rollout configuration: %w", err)
+	}
+
+	seen := map[string]bool{}
+
+	for i, wave := range waves {
+		if len(wave.Regions) == 0 {
+			return nil, fmt.Errorf("regional_rollout[%d].regions is required", i)
+		}
+
+		if wave.Parallel < 0 {
+			return nil, fmt.Errorf("invalid regional_rollout[%d].parallel %d, must be 0 or more", i, wave.Parallel)
+		}
+
+		for _, region := range wave.Regions {
+			if seen[region] {
+				return nil, fmt.Errorf("region %s is part of more than one wave of the regional_rollout", region)
+			}
+
+			seen[region] = true
+		}
+	}
+
+	return waves, nil
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetRegionalRollout_ShouldRejectInvalidWaves(t *testing.T) {
+	for config, expected := range map[string]string{
+		"regional_rollout:\n  - regions: [eastus]\n    parallel: -1\n":              "invalid regional_rollout[0].parallel -1, must be 0 or more",
+		"regional_rollout:\n  - regions: [eastus]\n  - regions: [eastus, westus]\n": "region eastus is part of more than one wave of the regional_rollout",
+		"regional_rollout:\n  - parallel: 2\n":                                      "regional_rollout[0].regions is required",
+	} {
+		v := viper.New()
+		v.SetConfigType("yaml")
+		require.NoError(t, v.ReadConfig(strings.NewReader(config)))
+
+		_, err := getRegionalRollout(v)
+		require.EqualError(t, err, expected)
+	}
+
+	v := viper.New()
+	v.SetConfigType("yaml")
+	require.NoError(t, v.ReadConfig(strings.NewReader("regional_rollout:\n  - regions: [eastus]\n  - regions: [westus, centralus]\n    parallel: 1\n")))
+
+	waves, err := getRegionalRollout(v)
+	require.NoError(t, err)
+	require.Equal(t, []rolloutWave{{Regions: []string{"eastus"}}, {Regions: []string{"westus", "centralus"}, Parallel: 1}}, waves)
+}
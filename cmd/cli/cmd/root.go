@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/optum/runiac/pkg/config"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -32,12 +33,17 @@ func init() {
 }
 
 func initConfig() {
-	// viper.AddConfigPath(".")
-	viper.SetConfigFile("runiac.yml")
+	// runiac.yml, runiac.yaml or runiac.hcl, the commands requiring a config report runiac.yml when there is none
+	configFile := config.FindConfigFile(appFS, ".")
+	if configFile == "" {
+		configFile = "runiac.yml"
+	}
 
 	viper.AutomaticEnv()
 
-	if err := viper.ReadInConfig(); err != nil {
+	if err := config.ReadConfigFile(appFS, viper.GetViper(), configFile); err != nil {
 		//logrus.WithError(err).Warn("Failed reading .runiac configuration")
 	}
+
+	_ = config.ReadConfigFile(appFS, fileConfig, configFile)
 }
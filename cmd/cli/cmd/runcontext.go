@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// runContext is everything runiac resolved for an invocation of deploy, printed by --print-context
+type runContext struct {
+	Project         string            `json:"project"`
+	Container       string            `json:"container"` // The base image the project container derives from
+	ContainerEngine string            `json:"container_engine"`
+	BuildContext    string            `json:"build_context"`
+	Runner          string            `json:"runner"`
+	RunnerArgs      []string          `json:"runner_args"`
+	Environment     string            `json:"environment"`
+	Namespace       string            `json:"namespace"`
+	Version         string            `json:"version"`
+	Steps           []string          `json:"steps"`
+	TeardownOrder   []string          `json:"teardown_order"`
+	DryRun          bool              `json:"dry_run"`
+	SelfDestroy     bool              `json:"self_destroy"`
+	Destroy         bool              `json:"destroy"`
+	Detach          bool              `json:"detach"`
+	Interactive     bool              `json:"interactive"`
+	Native          bool              `json:"native"` // The steps execute on the host instead of in a container
+	Plugins         []string          `json:"plugins"`
+	Flags           map[string]string `json:"flags"`
+	Rings           []ringRunContext  `json:"rings"`
+}
+
+// ringRunContext is the run context of a deployment ring, rings are deployed in order
+type ringRunContext struct {
+	DeploymentRing  string   `json:"deployment_ring"`
+	Account         string   `json:"account"`
+	Dockerfile      string   `json:"dockerfile"`
+	ContainerTag    string   `json:"container_tag"`
+	PrimaryRegions  []string `json:"primary_regions"`
+	RegionalRegions []string `json:"regional_regions"`
+	Env             []string `json:"env"`             // The KEY=VALUE variables set by runiac, sensitive values are masked
+	PassthroughEnv  []string `json:"passthrough_env"` // The names of the host environment variables forwarded into the container
+	Mounts          []string `json:"mounts"`          // The {host}:{container}[:ro] volume maps
+}
+
+// getRunContext resolves the run context of every deployment ring the way deploy runs them. Plugins are listed but
+// not executed, their env variables and mounts are not part of the context.
+func getRunContext(fs afero.Fs, flags *pflag.FlagSet, rings []string, accounts map[string]string, regions map[string]ringRegions, dockerfileExplicit bool, runnerLogEnv string) (runContext, error) {
+	runContext := runContext{
+		Project:         viper.GetString("project"),
+		Container:       Container,
+		ContainerEngine: ContainerEngine,
+		BuildContext:    BuildContext,
+		Runner:          Runner,
+		RunnerArgs:      RunnerArgs,
+		Environment:     Environment,
+		Namespace:       Namespace,
+		Version:         AppVersion,
+		Steps:           StepWhitelist,
+		TeardownOrder:   TeardownOrder,
+		DryRun:          DryRun,
+		SelfDestroy:     SelfDestroy,
+		Destroy:         Destroy,
+		Detach:          Detach,
+		Interactive:     Interactive,
+		Native:          Native,
+		Plugins:         Plugins,
+		Flags:           getContextFlags(flags),
+		Rings:           []ringRunContext{},
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return runContext, err
+	}
+
+	passthrough := getPassthroughEnv()
+
+	for _, ring := range rings {
+		DeploymentRing = ring
+		PrimaryRegions = regions[ring].Primary
+		RegionalRegions = regions[ring].Regional
+
+		dockerfile, err := resolveRingDockerfile(fs, ring, Dockerfile, dockerfileExplicit)
+		if err != nil {
+			return runContext, err
+		}
+
+		stateDir, err := getRingStateDir(ring)
+		if err != nil {
+			return runContext, err
+		}
+
+		runArgs, err := getRingRunArguments(ring, accounts[ring], len(rings) > 1, runnerLogEnv)
+		if err != nil {
+			return runContext, err
+		}
+
+		// the short-lived tokens are not requested to print the context
+		credentialArgs, err := getCredentialArguments(dir, credentialClouds, false, "")
+		if err != nil {
+			return runContext, err
+		}
+
+		mountArgs := append(append(append(runArgs, getDockerSocketArguments(DockerSocket)...), credentialArgs...), getLocalVolumeArguments(dir, stateDir)...)
+
+		runContext.Rings = append(runContext.Rings, ringRunContext{
+			DeploymentRing:  ring,
+			Account:         accounts[ring],
+			Dockerfile:      dockerfile,
+			ContainerTag:    getContainerTag(dockerfile, ring),
+			PrimaryRegions:  PrimaryRegions,
+			RegionalRegions: RegionalRegions,
+			Env:             getContextEnv(runArgs, passthrough),
+			PassthroughEnv:  getEnvNames(passthrough),
+			Mounts:          getMountsFromArgs(mountArgs),
+		})
+	}
+
+	return runContext, nil
+}
+
+// printRunContext writes the run context as indented json
+func printRunContext(w io.Writer, runContext runContext) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(runContext)
+}
+
+// getContextFlags returns the resolved value of every deploy flag by name, sensitive values are masked
+func getContextFlags(flags *pflag.FlagSet) map[string]string {
+	values := map[string]string{}
+
+	flags.VisitAll(func(f *pflag.Flag) {
+		if f.Hidden {
+			return
+		}
+
+		value := getFlagValueString(f)
+		if value != "" && isSensitiveEnvKey(f.Name) {
+			value = maskedValue
+		}
+
+		values[f.Name] = value
+	})
+
+	return values
+}
+
+// getContextEnv returns the masked variables runiac sets in the container, excluding the passed through host
+// environment variables
+func getContextEnv(runArgs []string, passthrough []string) []string {
+	host := map[string]bool{}
+	for _, e := range passthrough {
+		host[e] = true
+	}
+
+	env := []string{}
+
+	for _, e := range getEnvFromArgs(runArgs) {
+		if !host[e] {
+			env = append(env, maskEnv(e))
+		}
+	}
+
+	return env
+}
+
+// getEnvNames returns the sorted names of the KEY=VALUE environment variables
+func getEnvNames(env []string) []string {
+	names := []string{}
+
+	for _, e := range env {
+		names = append(names, strings.SplitN(e, "=", 2)[0])
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// getMountsFromArgs returns the volume maps of the container run arguments
+func getMountsFromArgs(args []string) []string {
+	mounts := []string{}
+
+	for i := 0; i < len(args)-1; i++ {
+		if args[i] == "-v" {
+			mounts = append(mounts, args[i+1])
+			i++
+		}
+	}
+
+	return mounts
+}
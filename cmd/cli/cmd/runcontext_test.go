@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetRunContext_ShouldResolveEachRing(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	_ = afero.WriteFile(fs, ".runiac/Dockerfile", []byte("FROM runiac/deploy"), 0644)
+	_ = afero.WriteFile(fs, ".runiac/Dockerfile.prod", []byte("FROM runiac/deploy"), 0644)
+
+	viper.Set("project", "stub")
+	defer viper.Set("project", nil)
+
+	_ = os.Setenv("ARM_CLIENT_SECRET", "hunter2")
+	defer os.Unsetenv("ARM_CLIENT_SECRET")
+
+	dockerfile := Dockerfile
+	ContainerEngine, Runner, DockerSocket, Dockerfile = "podman", "terraform", dockerSocket, ".runiac/Dockerfile"
+	defer func() {
+		ContainerEngine, Runner, DockerSocket, Dockerfile, DeploymentRing = "", "", "", dockerfile, ""
+		PrimaryRegions, RegionalRegions = []string{}, []string{}
+	}()
+
+	flags := pflag.NewFlagSet("deploy", pflag.ContinueOnError)
+	flags.String("container-engine", "podman", "")
+	flags.String("client-secret", "hunter2", "")
+
+	regions := map[string]ringRegions{
+		"dev":  {Primary: []string{"eastus"}},
+		"prod": {Primary: []string{"eastus"}, Regional: []string{"westus", "centralus"}},
+	}
+
+	runContext, err := getRunContext(fs, flags, []string{"dev", "prod"}, map[string]string{"dev": "1111", "prod": "2222"}, regions, false, "")
+	require.NoError(t, err)
+
+	require.Equal(t, "stub", runContext.Project)
+	require.Equal(t, "podman", runContext.ContainerEngine)
+	require.Equal(t, map[string]string{"container-engine": "podman", "client-secret": maskedValue}, runContext.Flags)
+	require.Len(t, runContext.Rings, 2)
+
+	dev, prod := runContext.Rings[0], runContext.Rings[1]
+	require.Equal(t, ".runiac/Dockerfile", dev.Dockerfile)
+	require.Equal(t, "stub", dev.ContainerTag)
+	require.Equal(t, ".runiac/Dockerfile.prod", prod.Dockerfile)
+	require.Equal(t, "stub-prod", prod.ContainerTag)
+	require.Equal(t, "2222", prod.Account)
+	require.Equal(t, []string{"westus", "centralus"}, prod.RegionalRegions)
+	require.Contains(t, prod.Env, "RUNIAC_ACCOUNT_ID=2222")
+	require.Contains(t, prod.Env, "RUNIAC_REGIONAL_REGIONS=westus,centralus")
+	require.Contains(t, prod.PassthroughEnv, "ARM_CLIENT_SECRET")
+	require.Contains(t, prod.Mounts, dockerSocket+":"+dockerSocket)
+
+	var b bytes.Buffer
+	require.NoError(t, printRunContext(&b, runContext))
+	require.NotContains(t, b.String(), "hunter2", "secrets should be masked")
+	require.True(t, json.Valid(b.Bytes()))
+}
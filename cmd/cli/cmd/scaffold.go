@@ -0,0 +1,391 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/pkg/tracks"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// scaffoldName matches valid track and step names, they form the {trackName}/{stepName} step ids and state paths
+var scaffoldName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+const maxProgressionLevel = 9 // Step directories encode the progression level in a single digit
+
+var (
+	newTrackStep   string
+	newStepTrack   string
+	newStepLevel   int
+	scaffoldRunner string
+)
+
+func init() {
+	newTrackCmd.Flags().StringVar(&newTrackStep, "step", "initial", "The name of the first step of the track")
+	newTrackCmd.Flags().StringVar(&scaffoldRunner, "runner", "", "The runner of the starter files, defaults to runner in the runiac config")
+
+	newStepCmd.Flags().StringVarP(&newStepTrack, "track", "t", defaultTrackName, "The track of the step, the default track is formed by the step directories at the top-level of the project")
+	newStepCmd.Flags().IntVarP(&newStepLevel, "level", "l", 0, "The progression level of the step, defaults to the level after the highest of the track")
+	newStepCmd.Flags().StringVar(&scaffoldRunner, "runner", "", "The runner of the starter files, defaults to runner in the runiac config")
+
+	newCmd.AddCommand(newTrackCmd)
+	newCmd.AddCommand(newStepCmd)
+}
+
+var newTrackCmd = &cobra.Command{
+	Use:   "track <name>",
+	Short: "Create a new track in the project",
+	Long: `Creates the tracks/{name} directory with a first step, named with --step, containing the starter files of
+the project's runner.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		stepDir, err := createTrack(appFS, args[0], newTrackStep, getScaffoldRunner())
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		logrus.Infof("Created track %s with step %s", args[0], stepDir)
+
+		updateScaffoldedTeardownOrder(fmt.Sprintf("%s/%s", args[0], newTrackStep))
+	},
+}
+
+var newStepCmd = &cobra.Command{
+	Use:   "step <name>",
+	Short: "Create a new step in a track",
+	Long: `Creates the step{progressionLevel}_{name} directory in the track, containing the starter files of the
+project's runner. The step is added to the front of teardown_order when the runiac config sets it, so it is destroyed
+before the steps it may depend on.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		level := -1
+		if cmd.Flags().Changed("level") {
+			if newStepLevel < 0 {
+				logrus.Fatalf("invalid --level %d, must be between 0 and %d", newStepLevel, maxProgressionLevel)
+			}
+
+			level = newStepLevel
+		}
+
+		stepDir, err := createStep(appFS, newStepTrack, level, args[0], getScaffoldRunner())
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		logrus.Infof("Created step %s", stepDir)
+
+		updateScaffoldedTeardownOrder(fmt.Sprintf("%s/%s", newStepTrack, args[0]))
+	},
+}
+
+// getScaffoldRunner returns the runner of the starter files, --runner or the runner of the runiac config
+func getScaffoldRunner() string {
+	if scaffoldRunner != "" {
+		return scaffoldRunner
+	}
+
+	if runner := viper.GetString("runner"); runner != "" {
+		return runner
+	}
+
+	return "terraform"
+}
+
+// validateTrackName returns an error when the name is not a valid name of a track directory
+func validateTrackName(name string) error {
+	if name == defaultTrackName {
+		return fmt.Errorf("track name '%s' is reserved for the steps at the top-level of the project", name)
+	}
+
+	if name != tracks.PRE_TRACK_NAME && !scaffoldName.MatchString(name) {
+		return fmt.Errorf("invalid track name '%s', must start with a letter or digit and only contain letters, digits, '_' and '-'", name)
+	}
+
+	return nil
+}
+
+// validateStepName returns an error when the name is not a valid name of a step
+func validateStepName(name string) error {
+	if !scaffoldName.MatchString(name) {
+		return fmt.Errorf("invalid step name '%s', must start with a letter or digit and only contain letters, digits, '_' and '-'", name)
+	}
+
+	return nil
+}
+
+// createTrack creates the track's directory with its first step and returns the directory of the step
+func createTrack(fs afero.Fs, track string, step string, runner string) (string, error) {
+	if err := validateTrackName(track); err != nil {
+		return "", err
+	}
+
+	if err := validateStepName(step); err != nil {
+		return "", err
+	}
+
+	if exists, _ := afero.DirExists(fs, getTrackDir(track)); exists {
+		return "", fmt.Errorf("track '%s' already exists at %s, add steps with 'runiac new step --track %s'", track, getTrackDir(track), track)
+	}
+
+	return createStep(fs, track, 1, step, runner)
+}
+
+// createStep creates the step's directory in the track with the starter files of the runner and returns the
+// directory. The step is added at the level after the highest progression level of the track when level is negative.
+func createStep(fs afero.Fs, track string, level int, step string, runner string) (string, error) {
+	if track != defaultTrackName {
+		if err := validateTrackName(track); err != nil {
+			return "", err
+		}
+	}
+
+	if err := validateStepName(step); err != nil {
+		return "", err
+	}
+
+	dir := getTrackDir(track)
+	highest := 0
+
+	if exists, _ := afero.DirExists(fs, dir); exists {
+		existing, err := getGraphTrack(fs, track)
+		if err != nil {
+			return "", err
+		}
+
+		for _, s := range existing.Steps {
+			if s.Name == step {
+				return "", fmt.Errorf("step '%s' already exists in the %s track, step names must be unique within a track", step, track)
+			}
+
+			if s.Level > highest {
+				highest = s.Level
+			}
+		}
+	}
+
+	if level < 0 {
+		level = highest + 1
+	}
+
+	if level > maxProgressionLevel {
+		return "", fmt.Errorf("invalid progression level %d, must be between 0 and %d", level, maxProgressionLevel)
+	}
+
+	stepDir := filepath.Join(dir, fmt.Sprintf("%s%d_%s", stepDirPrefix, level, step))
+
+	if err := fs.MkdirAll(stepDir, 0755); err != nil {
+		return "", err
+	}
+
+	files := getStepStarterFiles(runner, track, step)
+	if len(files) == 0 {
+		logrus.Warnf("No starter files for the %s runner, add the step's files to %s", runner, stepDir)
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := afero.WriteFile(fs, filepath.Join(stepDir, name), []byte(files[name]), 0644); err != nil {
+			return "", err
+		}
+	}
+
+	return stepDir, nil
+}
+
+// getStepStarterFiles returns the starter files of a step of the runner by file name, nil for runners without
+func getStepStarterFiles(runner string, track string, step string) map[string]string {
+	statePath := "${var.runiac_step}.terraform.tfstate"
+	if track != defaultTrackName {
+		statePath = fmt.Sprintf("%s/${var.runiac_step}/terraform.tfstate", track)
+	}
+
+	switch runner {
+	case "terraform":
+		return map[string]string{
+			"main.tf":      starterTerraformMain,
+			"variables.tf": starterTerraformVariables,
+			"backend.tf":   strings.ReplaceAll(starterTerraformBackend, "${STATE_PATH}", statePath),
+		}
+	case "terragrunt":
+		return map[string]string{
+			"terragrunt.hcl": starterTerragrunt,
+			"main.tf":        starterTerraformMain,
+			"variables.tf":   starterTerraformVariables,
+		}
+	case "arm":
+		return map[string]string{
+			"main.json": starterArmTemplate,
+		}
+	case "pulumi":
+		return map[string]string{
+			"Pulumi.yaml": strings.ReplaceAll(starterPulumiProject, "${STEP_NAME}", step),
+		}
+	}
+
+	return nil
+}
+
+// updateScaffoldedTeardownOrder adds the step to teardown_order of the runiac config, logging failures since the
+// step was already created
+func updateScaffoldedTeardownOrder(stepID string) {
+	updated, err := addToTeardownOrder(appFS, viper.ConfigFileUsed(), stepID)
+	if err != nil {
+		logrus.WithError(err).Warnf("Unable to add %s to teardown_order, add it to the runiac config", stepID)
+		return
+	}
+
+	if updated {
+		logrus.Infof("Added %s to the front of teardown_order", stepID)
+	}
+
+	if whitelist := viper.GetStringSlice("step_whitelist"); len(whitelist) > 0 {
+		logrus.Warnf("The runiac config sets step_whitelist, add %s to deploy the step", stepID)
+	}
+}
+
+// addToTeardownOrder adds the step id to the front of teardown_order when the runiac config file sets it, as a list
+// or comma separated. Returns false when the config file does not exist or does not set teardown_order. A runiac.hcl
+// is not rewritten, an error is returned when it sets teardown_order.
+func addToTeardownOrder(fs afero.Fs, configFile string, stepID string) (bool, error) {
+	if configFile == "" {
+		return false, nil
+	}
+
+	b, err := afero.ReadFile(fs, configFile)
+	if err != nil {
+		return false, nil
+	}
+
+	if strings.EqualFold(filepath.Ext(configFile), ".hcl") {
+		settings, err := config.DecodeHCLConfig(b)
+		if err != nil {
+			return false, fmt.Errorf("unable to read %s: %w", configFile, err)
+		}
+
+		if _, ok := settings["teardown_order"]; ok {
+			return false, fmt.Errorf("teardown_order of %s is only updated in yaml", configFile)
+		}
+
+		return false, nil
+	}
+
+	doc := yaml.Node{}
+
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return false, fmt.Errorf("unable to read %s: %w", configFile, err)
+	}
+
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return false, nil
+	}
+
+	root := doc.Content[0]
+	var order *yaml.Node
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "teardown_order" {
+			order = root.Content[i+1]
+		}
+	}
+
+	switch {
+	case order == nil:
+		return false, nil
+	case order.Kind == yaml.SequenceNode:
+		order.Content = append([]*yaml.Node{{Kind: yaml.ScalarNode, Tag: "!!str", Value: stepID}}, order.Content...)
+	case order.Kind == yaml.ScalarNode && strings.TrimSpace(order.Value) == "":
+		order.Value = stepID
+	case order.Kind == yaml.ScalarNode:
+		order.Value = stepID + "," + order.Value
+	default:
+		return false, fmt.Errorf("teardown_order in %s must be a list of {trackName}/{stepName} step ids", configFile)
+	}
+
+	var out bytes.Buffer
+
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+
+	if err := enc.Encode(&doc); err != nil {
+		return false, err
+	}
+
+	if err := afero.WriteFile(fs, configFile, out.Bytes(), 0644); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+const starterTerraformMain = `# Generated by runiac CLI.
+# The resources of the step, runiac applies them to the primary region and the regional resources in the regional
+# directory to every regional region.
+`
+
+const starterTerraformVariables = `# Generated by runiac CLI.
+variable "runiac_account_id" {
+  type = string
+}
+
+variable "runiac_region" {
+  type = string
+}
+
+variable "runiac_environment" {
+  type = string
+}
+
+variable "runiac_namespace" {
+  type    = string
+  default = ""
+}
+
+variable "runiac_step" {
+  type = string
+}
+`
+
+const starterTerraformBackend = `# Generated by runiac CLI.
+terraform {
+  backend "local" {
+    path          = "${STATE_PATH}"
+    workspace_dir = "/runiac/tfstate"
+  }
+}
+`
+
+const starterTerragrunt = `# Generated by runiac CLI.
+# runiac sets its variables, e.g. runiac_region, as TF_VAR_ env variables read by the inputs and the terraform
+# module of the step.
+inputs = {}
+`
+
+const starterArmTemplate = `{
+  "$schema": "https://schema.management.azure.com/schemas/2019-04-01/deploymentTemplate.json#",
+  "contentVersion": "1.0.0.0",
+  "parameters": {},
+  "resources": []
+}
+`
+
+const starterPulumiProject = `# Generated by runiac CLI.
+name: ${STEP_NAME}
+runtime: yaml
+description: The resources of the step, runiac sets its variables as RUNIAC_VAR_ env variables
+resources: {}
+`
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/optum/runiac/pkg/tracks"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateStep_ShouldAddStepAfterHighestLevel(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	_ = fs.MkdirAll("tracks/network/step1_vnet", 0755)
+	_ = fs.MkdirAll("tracks/network/step2_peering", 0755)
+
+	dir, err := createStep(fs, "network", -1, "dns", "terraform")
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join("tracks", "network", "step3_dns"), dir)
+
+	backend, err := afero.ReadFile(fs, filepath.Join(dir, "backend.tf"))
+	require.NoError(t, err)
+	require.Contains(t, string(backend), `path          = "network/${var.runiac_step}/terraform.tfstate"`)
+
+	for _, name := range []string{"main.tf", "variables.tf"} {
+		exists, _ := afero.Exists(fs, filepath.Join(dir, name))
+		require.True(t, exists, name)
+	}
+
+	dir, err = createStep(fs, defaultTrackName, -1, "initial", "arm")
+	require.NoError(t, err)
+	require.Equal(t, "step1_initial", dir)
+
+	exists, _ := afero.Exists(fs, filepath.Join(dir, "main.json"))
+	require.True(t, exists)
+
+	dir, err = createStep(fs, "network", 2, "firewall", "pulumi")
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join("tracks", "network", "step2_firewall"), dir)
+
+	project, err := afero.ReadFile(fs, filepath.Join(dir, "Pulumi.yaml"))
+	require.NoError(t, err)
+	require.Contains(t, string(project), "name: firewall")
+}
+
+func TestCreateStep_ShouldValidateNames(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	_ = fs.MkdirAll("tracks/network/step1_vnet", 0755)
+	_ = fs.MkdirAll("tracks/full/step9_last", 0755)
+
+	tests := map[string]struct {
+		track string
+		level int
+		step  string
+	}{
+		"ShouldRejectExistingStep":     {"network", 3, "vnet"},
+		"ShouldRejectInvalidStepName":  {"network", -1, "my step"},
+		"ShouldRejectNestedStepName":   {"network", -1, "a/b"},
+		"ShouldRejectInvalidTrackName": {"_network", -1, "vnet"},
+		"ShouldRejectLevelAboveNine":   {"full", -1, "overflow"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := createStep(fs, tc.track, tc.level, tc.step, "terraform")
+			require.Error(t, err)
+		})
+	}
+
+	_, err := createStep(fs, tracks.PRE_TRACK_NAME, -1, "init", "terraform")
+	require.NoError(t, err)
+}
+
+func TestCreateTrack(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	dir, err := createTrack(fs, "app", "initial", "terragrunt")
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join("tracks", "app", "step1_initial"), dir)
+
+	exists, _ := afero.Exists(fs, filepath.Join(dir, "terragrunt.hcl"))
+	require.True(t, exists)
+
+	_, err = createTrack(fs, "app", "other", "terraform")
+	require.Error(t, err)
+
+	_, err = createTrack(fs, defaultTrackName, "initial", "terraform")
+	require.Error(t, err)
+
+	dir, err = createTrack(fs, "custom", "initial", "acme")
+	require.NoError(t, err)
+
+	exists, _ = afero.DirExists(fs, dir)
+	require.True(t, exists)
+}
+
+func TestAddToTeardownOrder(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	updated, err := addToTeardownOrder(fs, "runiac.yml", "app/initial")
+	require.NoError(t, err)
+	require.False(t, updated)
+
+	_ = afero.WriteFile(fs, "runiac.yml", []byte("project: stub\n"), 0644)
+
+	updated, err = addToTeardownOrder(fs, "runiac.yml", "app/initial")
+	require.NoError(t, err)
+	require.False(t, updated)
+
+	_ = afero.WriteFile(fs, "runiac.yml", []byte("project: stub\n# destroyed in this order\nteardown_order:\n  - network/vnet\n"), 0644)
+
+	updated, err = addToTeardownOrder(fs, "runiac.yml", "network/dns")
+	require.NoError(t, err)
+	require.True(t, updated)
+
+	b, _ := afero.ReadFile(fs, "runiac.yml")
+	require.Equal(t, "project: stub\n# destroyed in this order\nteardown_order:\n  - network/dns\n  - network/vnet\n", string(b))
+
+	_ = afero.WriteFile(fs, "runiac.yml", []byte("teardown_order: network/vnet\n"), 0644)
+
+	_, err = addToTeardownOrder(fs, "runiac.yml", "network/dns")
+	require.NoError(t, err)
+
+	b, _ = afero.ReadFile(fs, "runiac.yml")
+	require.Equal(t, "teardown_order: network/dns,network/vnet\n", string(b))
+}
@@ -0,0 +1,340 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// scanSeverities are the severities of scan findings from lowest to highest
+var scanSeverities = []string{"low", "medium", "high", "critical"}
+
+// scanConfigKeys are the keys of the scan section of the runiac config
+var scanConfigKeys = []string{"enabled", "tool", "fail_on"}
+
+// ScanOnly scans the source of the steps without executing them
+var ScanOnly bool
+
+var scanFormat string
+
+func init() {
+	scanCmd.Flags().AddFlagSet(deployCmd.Flags())
+	scanCmd.Flags().StringVar(&scanFormat, "output", "text", "The scan summary format, text or json. With json the container output is written to stderr")
+
+	rootCmd.AddCommand(scanCmd)
+}
+
+var scanCmd = &cobra.Command{
+	Use:   "scan",
+	Short: "Scan the source of configurations",
+	Long: `Scans the source of every step with tfsec or checkov inside the container without executing the steps,
+accepting the flags of deploy. The findings are summarized per step and severity, and the scan fails when a
+step has findings at or above scan.fail_on. Configure the scan in the runiac config, enabled also scans each
+step before it is deployed. The deploy container includes tfsec, checkov must be installed by the project's
+dockerfile:
+
+  scan:
+    enabled: true
+    tool: tfsec    # or checkov
+    fail_on: high  # low, medium, high or critical, findings are only reported when not set`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if scanFormat != "text" && scanFormat != "json" {
+			logrus.Fatalf("invalid --output '%s', must be text or json", scanFormat)
+		}
+
+		if scanFormat == "json" {
+			runOutput = os.Stderr
+		}
+
+		failed := runDeploy(cmd, actionScan)
+
+		// nothing was scanned when only printing settings
+		if Why != "" || Test || ShowResolvedVars || PrintContext {
+			return nil
+		}
+
+		summary, err := readScanSummary(appFS, writtenReports)
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		if scanFormat == "json" {
+			err = printScanSummaryJSON(os.Stdout, summary)
+		} else {
+			err = printScanSummary(os.Stdout, summary)
+		}
+
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		return getRunError(cmd, failed)
+	},
+}
+
+// configureScan makes the run only scan the source of the steps
+func configureScan() error {
+	if Detach {
+		return fmt.Errorf("--detach can not be used with scan, the findings are summarized once the container exits")
+	}
+
+	if SelfDestroy {
+		return fmt.Errorf("--self-destroy can not be used with scan, scan does not deploy")
+	}
+
+	DryRun = true
+	ScanOnly = true
+
+	return nil
+}
+
+// validateScanConfig returns an error when the scanner or the fail_on severity of the scan section is unknown
+func validateScanConfig(tool string, failOn string) error {
+	if tool != "" && tool != "tfsec" && tool != "checkov" {
+		return fmt.Errorf("invalid scan.tool '%s', must be tfsec or checkov", tool)
+	}
+
+	if failOn != "" && getScanSeverityRank(failOn) == 0 {
+		return fmt.Errorf("invalid scan.fail_on '%s', must be one of %s", failOn, strings.Join(scanSeverities, ", "))
+	}
+
+	return nil
+}
+
+// validateScan validates the scan section of the runiac config when the run scans, requiring the scanner on the path
+// in --native mode
+func validateScan() error {
+	if !ScanOnly && !viper.GetBool("scan.enabled") {
+		return nil
+	}
+
+	tool := viper.GetString("scan.tool")
+
+	if err := validateScanConfig(tool, viper.GetString("scan.fail_on")); err != nil {
+		return err
+	}
+
+	if tool == "" {
+		tool = "tfsec"
+	}
+
+	if Native {
+		if _, err := lookPath(tool); err != nil {
+			return fmt.Errorf("scanning requires '%s' on the path in --native mode", tool)
+		}
+	}
+
+	return nil
+}
+
+// getScanSeverityRank returns the rank of the severity in scanSeverities, 0 when it is unknown
+func getScanSeverityRank(severity string) int {
+	for i, s := range scanSeverities {
+		if strings.ToLower(severity) == s {
+			return i + 1
+		}
+	}
+
+	return 0
+}
+
+// reportScanFindings is the subset of a ring's deployment report used to summarize its scan findings
+type reportScanFindings struct {
+	DeploymentRing string `yaml:"deployment_ring"`
+	Steps          []struct {
+		Track            string        `yaml:"track"`
+		Step             string        `yaml:"step"`
+		Action           string        `yaml:"action"`
+		RegionDeployType string        `yaml:"region_deploy_type"`
+		Region           string        `yaml:"region"`
+		Status           string        `yaml:"status"`
+		Error            string        `yaml:"error"`
+		Findings         []scanFinding `yaml:"findings"`
+	} `yaml:"steps"`
+}
+
+// scanFinding is a failed check of a scanner
+type scanFinding struct {
+	RuleID    string `json:"rule_id" yaml:"rule_id"`
+	Severity  string `json:"severity" yaml:"severity"`
+	Message   string `json:"message" yaml:"message"`
+	File      string `json:"file,omitempty" yaml:"file"`
+	StartLine int    `json:"start_line,omitempty" yaml:"start_line"`
+}
+
+// stepScanSummary counts the scan findings of a step's source per severity
+type stepScanSummary struct {
+	StepID           string        `json:"step_id"`
+	RegionDeployType string        `json:"region_deploy_type"`
+	DeploymentRing   string        `json:"deployment_ring"`
+	Blocked          bool          `json:"blocked"` // The step failed, its findings are at or above scan.fail_on
+	Critical         int           `json:"critical"`
+	High             int           `json:"high"`
+	Medium           int           `json:"medium"`
+	Low              int           `json:"low"`
+	Findings         []scanFinding `json:"findings"`
+}
+
+// scanSummary is the consolidated scan of a run
+type scanSummary struct {
+	Blocked  int               `json:"blocked"`
+	Critical int               `json:"critical"`
+	High     int               `json:"high"`
+	Medium   int               `json:"medium"`
+	Low      int               `json:"low"`
+	Steps    []stepScanSummary `json:"steps"`
+}
+
+// count adds the finding to the step, counting it by its severity
+func (s *stepScanSummary) count(f scanFinding) {
+	switch strings.ToLower(f.Severity) {
+	case "critical":
+		s.Critical++
+	case "high":
+		s.High++
+	case "medium":
+		s.Medium++
+	default:
+		s.Low++
+	}
+
+	s.Findings = append(s.Findings, f)
+}
+
+// readScanSummary summarizes the scan findings of the deployment reports at paths, ordered by ring and step. The
+// regional executions of a step scan the same source, so only the first region of a step is summarized.
+func readScanSummary(fs afero.Fs, paths []string) (scanSummary, error) {
+	summary := scanSummary{Steps: []stepScanSummary{}}
+	seen := map[string]bool{}
+
+	for _, path := range paths {
+		b, err := afero.ReadFile(fs, path)
+		if err != nil {
+			return summary, err
+		}
+
+		report := reportScanFindings{}
+
+		if err = yaml.Unmarshal(b, &report); err != nil {
+			return summary, fmt.Errorf("invalid deployment report %s: %w", path, err)
+		}
+
+		for _, s := range report.Steps {
+			key := strings.Join([]string{report.DeploymentRing, s.Track, s.Step, s.RegionDeployType}, "/")
+
+			if s.Action == "destroy" || seen[key] {
+				continue
+			}
+
+			seen[key] = true
+
+			step := stepScanSummary{
+				StepID:           fmt.Sprintf("%s/%s", s.Track, s.Step),
+				RegionDeployType: s.RegionDeployType,
+				DeploymentRing:   report.DeploymentRing,
+				Blocked:          strings.EqualFold(s.Status, "fail") || s.Error != "",
+				Findings:         []scanFinding{},
+			}
+
+			// findings without a severity are the validation and policy findings
+			for _, f := range s.Findings {
+				if f.Severity != "" {
+					step.count(f)
+				}
+			}
+
+			if step.Blocked {
+				summary.Blocked++
+			}
+
+			summary.Critical += step.Critical
+			summary.High += step.High
+			summary.Medium += step.Medium
+			summary.Low += step.Low
+			summary.Steps = append(summary.Steps, step)
+		}
+	}
+
+	sort.SliceStable(summary.Steps, func(i, j int) bool {
+		a, b := summary.Steps[i], summary.Steps[j]
+
+		if a.DeploymentRing != b.DeploymentRing {
+			return a.DeploymentRing < b.DeploymentRing
+		}
+
+		if a.StepID != b.StepID {
+			return a.StepID < b.StepID
+		}
+
+		return a.RegionDeployType < b.RegionDeployType
+	})
+
+	return summary, nil
+}
+
+// printScanSummary writes the findings per step and severity followed by each finding, highest severity first
+func printScanSummary(w io.Writer, summary scanSummary) error {
+	if len(summary.Steps) == 0 {
+		_, err := fmt.Fprintln(w, "No steps were scanned")
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "RING\tSTEP\tSOURCE\tCRITICAL\tHIGH\tMEDIUM\tLOW\tSTATUS")
+
+	for _, s := range summary.Steps {
+		status := "passed"
+		if s.Blocked {
+			status = "blocked"
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%d\t%d\t%s\n", s.DeploymentRing, s.StepID, s.RegionDeployType, s.Critical, s.High, s.Medium, s.Low, status)
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, s := range summary.Steps {
+		findings := append([]scanFinding{}, s.Findings...)
+
+		sort.SliceStable(findings, func(i, j int) bool {
+			return getScanSeverityRank(findings[i].Severity) > getScanSeverityRank(findings[j].Severity)
+		})
+
+		for _, f := range findings {
+			location := f.File
+			if f.StartLine > 0 {
+				location = fmt.Sprintf("%s:%d", f.File, f.StartLine)
+			}
+
+			fmt.Fprintf(w, "\n[%s] %s %s %s\n  %s", f.Severity, s.StepID, f.RuleID, location, f.Message)
+		}
+	}
+
+	total := summary.Critical + summary.High + summary.Medium + summary.Low
+
+	_, err := fmt.Fprintf(w, "\n\nScan: %d finding(s), %d critical, %d high, %d medium and %d low across %d step(s), %d blocked.\n",
+		total, summary.Critical, summary.High, summary.Medium, summary.Low, len(summary.Steps), summary.Blocked)
+
+	return err
+}
+
+// printScanSummaryJSON writes the summary as indented json
+func printScanSummaryJSON(w io.Writer, summary scanSummary) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(summary)
+}
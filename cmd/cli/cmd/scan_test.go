@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+const stubScanReport = `{
+  "deployment_ring": "dev",
+  "steps": [
+    {"track": "storage", "step": "bucket", "action": "deploy", "region_deploy_type": "primary", "region": "eastus", "status": "FAIL",
+     "error": "scan produced 1 finding(s) of high severity or higher",
+     "findings": [
+       {"rule_id": "tfsec/aws-s3-enable-bucket-logging", "level": "warning", "severity": "medium", "message": "Bucket does not have logging enabled.", "file": "tracks/storage/step1_bucket/main.tf", "start_line": 1},
+       {"rule_id": "tfsec/aws-s3-no-public-access", "level": "error", "severity": "high", "message": "Bucket allows public access.", "file": "tracks/storage/step1_bucket/main.tf", "start_line": 3},
+       {"rule_id": "terraform/validate", "level": "warning", "message": "Deprecated attribute"}
+     ]},
+    {"track": "network", "step": "vnet", "action": "deploy", "region_deploy_type": "regional", "region": "eastus", "status": "SUCCESS",
+     "findings": [{"rule_id": "checkov/CKV_AZURE_12", "level": "note", "severity": "low", "message": "vnet: flow logs are not enabled", "file": "tracks/network/step1_vnet/regional/main.tf"}]},
+    {"track": "network", "step": "vnet", "action": "deploy", "region_deploy_type": "regional", "region": "westus", "status": "SUCCESS",
+     "findings": [{"rule_id": "checkov/CKV_AZURE_12", "level": "note", "severity": "low", "message": "vnet: flow logs are not enabled", "file": "tracks/network/step1_vnet/regional/main.tf"}]}
+  ]
+}`
+
+func TestReadScanSummary_ShouldCountFindingsPerStep(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	_ = afero.WriteFile(fs, ".runiac/reports/dev.json", []byte(stubScanReport), 0644)
+
+	summary, err := readScanSummary(fs, []string{".runiac/reports/dev.json"})
+	require.NoError(t, err)
+
+	require.Len(t, summary.Steps, 2, "the regional executions of a step scan the same source")
+	require.Equal(t, "network/vnet", summary.Steps[0].StepID)
+	require.Equal(t, 1, summary.Steps[0].Low)
+	require.False(t, summary.Steps[0].Blocked)
+
+	bucket := summary.Steps[1]
+	require.True(t, bucket.Blocked)
+	require.Equal(t, 1, bucket.High)
+	require.Equal(t, 1, bucket.Medium)
+	require.Len(t, bucket.Findings, 2, "the validation findings are not scan findings")
+
+	require.Equal(t, 1, summary.Blocked)
+	require.Equal(t, 1, summary.High)
+	require.Equal(t, 1, summary.Medium)
+	require.Equal(t, 1, summary.Low)
+
+	var b bytes.Buffer
+	require.NoError(t, printScanSummary(&b, summary))
+	require.Contains(t, b.String(), "dev   storage/bucket  primary   0         1     1       0    blocked")
+	require.Contains(t, b.String(), "[high] storage/bucket tfsec/aws-s3-no-public-access tracks/storage/step1_bucket/main.tf:3\n  Bucket allows public access.\n[medium]")
+	require.Contains(t, b.String(), "Scan: 3 finding(s), 0 critical, 1 high, 1 medium and 1 low across 2 step(s), 1 blocked.")
+
+	b.Reset()
+	require.NoError(t, printScanSummaryJSON(&b, summary))
+	require.Contains(t, b.String(), `"rule_id": "tfsec/aws-s3-no-public-access"`)
+}
+
+func TestConfigureScan_ShouldOnlyScanSteps(t *testing.T) {
+	defer func() { DryRun, ScanOnly, Detach, SelfDestroy = false, false, false, false }()
+
+	require.NoError(t, configureScan())
+	require.True(t, DryRun)
+	require.True(t, ScanOnly)
+
+	Detach = true
+	require.Error(t, configureScan(), "the findings can not be summarized for a detached container")
+
+	Detach, SelfDestroy = false, true
+	require.Error(t, configureScan())
+}
+
+func TestValidateScan_ShouldRequireScannerInNativeMode(t *testing.T) {
+	defer func(f func(string) (string, error)) { lookPath = f }(lookPath)
+	defer func() { ScanOnly, Native = false, false }()
+	defer viper.Reset()
+
+	require.NoError(t, validateScan(), "nothing is validated when not scanning")
+
+	viper.Set("scan.enabled", true)
+	viper.Set("scan.fail_on", "severe")
+	require.EqualError(t, validateScan(), "invalid scan.fail_on 'severe', must be one of low, medium, high, critical")
+
+	viper.Set("scan.fail_on", "HIGH")
+	require.NoError(t, validateScan())
+
+	Native = true
+	lookPath = func(file string) (string, error) { return "", errors.New("not found") }
+	require.EqualError(t, validateScan(), "scanning requires 'tfsec' on the path in --native mode")
+
+	viper.Set("scan.tool", "checkov")
+	require.EqualError(t, validateScan(), "scanning requires 'checkov' on the path in --native mode")
+}
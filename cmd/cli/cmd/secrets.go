@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// secretsConfig is the secrets section of the runiac config, the env variables runiac resolves from vault and sets
+// in the container
+type secretsConfig struct {
+	Vault vaultConfig `mapstructure:"vault"`
+	Env   []secretEnv `mapstructure:"env"`
+}
+
+// vaultConfig is the vault server secrets are read from, the token is read from VAULT_TOKEN or ~/.vault-token
+type vaultConfig struct {
+	Address   string `mapstructure:"address"`   // Defaults to VAULT_ADDR
+	Namespace string `mapstructure:"namespace"` // The vault enterprise namespace, defaults to VAULT_NAMESPACE
+}
+
+// secretEnv maps the key of a vault secret to an env variable
+type secretEnv struct {
+	Name string `mapstructure:"name"` // The env variable set in the container, e.g. TF_VAR_db_password
+	Path string `mapstructure:"path"` // The api path of the secret, e.g. secret/data/app for the kv v2 secret app
+	Key  string `mapstructure:"key"`  // The key of the secret's data
+}
+
+// vaultClient reads secrets from vault
+var vaultClient = &http.Client{Timeout: 10 * time.Second}
+
+// secretValues are the resolved secret values, masked wherever runiac prints env variables or arguments
+var secretValues = map[string]bool{}
+
+// resolvedSecretEnv are the KEY=VALUE env variables resolved from the secrets section, set in the container before
+// --env so explicit variables take precedence
+var resolvedSecretEnv []string
+
+// getSecretsConfig returns the secrets section of the runiac config file
+func getSecretsConfig() (secretsConfig, error) {
+	secrets := secretsConfig{}
+
+	err := viper.UnmarshalKey("secrets", &secrets)
+	if err != nil {
+		return secrets, fmt.Errorf("invalid secrets configuration: %w", err)
+	}
+
+	for _, s := range secrets.Env {
+		if !validEnvName.MatchString(s.Name) || s.Path == "" || s.Key == "" {
+			return secrets, fmt.Errorf("invalid secrets configuration, every env variable requires a valid name, a path and a key: %+v", s)
+		}
+	}
+
+	if secrets.Vault.Address == "" {
+		secrets.Vault.Address = os.Getenv("VAULT_ADDR")
+	}
+
+	if secrets.Vault.Namespace == "" {
+		secrets.Vault.Namespace = os.Getenv("VAULT_NAMESPACE")
+	}
+
+	return secrets, nil
+}
+
+// resolveSecrets reads every secret of the secrets section from vault and returns the KEY=VALUE env variables,
+// registering the values for masking. Each path is read once.
+func resolveSecrets(client *http.Client, secrets secretsConfig) ([]string, error) {
+	if len(secrets.Env) == 0 {
+		return nil, nil
+	}
+
+	if secrets.Vault.Address == "" {
+		return nil, fmt.Errorf("the secrets section requires vault.address or VAULT_ADDR to be set")
+	}
+
+	token, err := getVaultToken()
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[string]map[string]interface{}{}
+	env := []string{}
+
+	for _, s := range secrets.Env {
+		if _, ok := data[s.Path]; !ok {
+			data[s.Path], err = readVaultSecret(client, secrets.Vault, token, s.Path)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		value, ok := data[s.Path][s.Key]
+		if !ok {
+			return nil, fmt.Errorf("the vault secret %s has no key '%s' for %s", s.Path, s.Key, s.Name)
+		}
+
+		v := formatOutputValue(value)
+		if v != "" {
+			secretValues[v] = true
+		}
+
+		env = append(env, fmt.Sprintf("%s=%s", s.Name, v))
+	}
+
+	return env, nil
+}
+
+// getVaultToken returns VAULT_TOKEN or the token the vault cli stored in ~/.vault-token
+func getVaultToken() (string, error) {
+	if token := os.Getenv("VAULT_TOKEN"); token != "" {
+		return token, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err == nil {
+		b, err := ioutil.ReadFile(filepath.Join(home, ".vault-token"))
+		if err == nil && strings.TrimSpace(string(b)) != "" {
+			return strings.TrimSpace(string(b)), nil
+		}
+	}
+
+	return "", fmt.Errorf("the secrets section requires a vault token, set VAULT_TOKEN or log in with 'vault login'")
+}
+
+// readVaultSecret returns the data of the secret at the api path, unwrapping the data of kv v2 secrets
+func readVaultSecret(client *http.Client, vault vaultConfig, token string, path string) (map[string]interface{}, error) {
+	url := fmt.Sprintf("%s/v1/%s", strings.TrimSuffix(vault.Address, "/"), strings.TrimPrefix(path, "/"))
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("X-Vault-Token", token)
+	req.Header.Set("Accept", "application/json")
+
+	if vault.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", vault.Namespace)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the vault secret %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("unable to read the vault secret %s, vault returned %s", path, resp.Status)
+	}
+
+	secret := struct {
+		Data map[string]interface{} `json:"data"`
+	}{}
+
+	err = json.NewDecoder(resp.Body).Decode(&secret)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the vault secret %s: %w", path, err)
+	}
+
+	// kv v2 nests the secret's data next to its metadata
+	if nested, ok := secret.Data["data"].(map[string]interface{}); ok {
+		if _, ok := secret.Data["metadata"]; ok {
+			return nested, nil
+		}
+	}
+
+	return secret.Data, nil
+}
+
+// maskArgs masks the resolved secret values in the arguments of a command before it is logged
+func maskArgs(args []string) []string {
+	masked := make([]string, len(args))
+
+	for i, a := range args {
+		masked[i] = maskEnv(a)
+	}
+
+	return masked
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func stubVaultServer(t *testing.T) (*httptest.Server, map[string]int) {
+	t.Helper()
+
+	reads := map[string]int{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "stub-token" || r.Header.Get("X-Vault-Namespace") != "team" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		reads[r.URL.Path]++
+
+		switch r.URL.Path {
+		case "/v1/secret/data/app":
+			_, _ = w.Write([]byte(`{"data":{"data":{"password":"hunter2","user":"admin"},"metadata":{"version":3}}}`))
+		case "/v1/kv/app":
+			_, _ = w.Write([]byte(`{"data":{"token":"s3cr3t"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	t.Cleanup(server.Close)
+
+	return server, reads
+}
+
+func TestResolveSecrets_ShouldReadKVSecrets(t *testing.T) {
+	_ = os.Setenv("VAULT_TOKEN", "stub-token")
+	defer os.Unsetenv("VAULT_TOKEN")
+	defer func() { secretValues = map[string]bool{} }()
+
+	server, reads := stubVaultServer(t)
+
+	env, err := resolveSecrets(server.Client(), secretsConfig{
+		Vault: vaultConfig{Address: server.URL + "/", Namespace: "team"},
+		Env: []secretEnv{
+			{Name: "TF_VAR_db_password", Path: "secret/data/app", Key: "password"},
+			{Name: "TF_VAR_db_user", Path: "secret/data/app", Key: "user"},
+			{Name: "ACME_TOKEN", Path: "/kv/app", Key: "token"},
+		},
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, []string{"TF_VAR_db_password=hunter2", "TF_VAR_db_user=admin", "ACME_TOKEN=s3cr3t"}, env)
+	require.Equal(t, 1, reads["/v1/secret/data/app"], "each path should be read once")
+
+	require.Equal(t, "TF_VAR_db_password="+maskedValue, maskEnv("TF_VAR_db_password=hunter2"))
+	require.Equal(t, "ACME_TOKEN="+maskedValue, maskEnv("ACME_TOKEN=s3cr3t"))
+	require.NotContains(t, strings.Join(maskArgs([]string{"docker", "run", "-e", "ACME_TOKEN=s3cr3t"}), " "), "s3cr3t")
+	require.NotContains(t, strings.Join(maskArgs([]string{"docker", "build", "--build-arg", "NPM_TOKEN=s3cr3t", "."}), " "), "s3cr3t", "build args are masked")
+}
+
+func TestResolveSecrets_ShouldFailOnMissingSecrets(t *testing.T) {
+	_ = os.Setenv("VAULT_TOKEN", "stub-token")
+	defer os.Unsetenv("VAULT_TOKEN")
+
+	server, _ := stubVaultServer(t)
+	vault := vaultConfig{Address: server.URL, Namespace: "team"}
+
+	_, err := resolveSecrets(server.Client(), secretsConfig{
+		Vault: vault,
+		Env:   []secretEnv{{Name: "TF_VAR_db_password", Path: "secret/data/app", Key: "missing"}},
+	})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "'missing'")
+
+	_, err = resolveSecrets(server.Client(), secretsConfig{
+		Vault: vault,
+		Env:   []secretEnv{{Name: "TF_VAR_db_password", Path: "secret/data/other", Key: "password"}},
+	})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "404")
+
+	_, err = resolveSecrets(server.Client(), secretsConfig{
+		Env: []secretEnv{{Name: "TF_VAR_db_password", Path: "secret/data/app", Key: "password"}},
+	})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "VAULT_ADDR")
+}
+
+func TestGetRunConfigArguments_ShouldSetSecretsBeforeExplicitEnv(t *testing.T) {
+	resolvedSecretEnv = []string{"TF_VAR_db_password=hunter2", "TF_VAR_region=secret"}
+	ExtraEnv = []string{"TF_VAR_region=explicit"}
+	defer func() { resolvedSecretEnv, ExtraEnv = nil, []string{} }()
+
+	args := strings.Join(getRunConfigArguments(""), " ")
+
+	require.Contains(t, args, "-e TF_VAR_db_password=hunter2")
+	require.Less(t, strings.Index(args, "TF_VAR_region=secret"), strings.Index(args, "TF_VAR_region=explicit"))
+}
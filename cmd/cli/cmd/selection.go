@@ -0,0 +1,257 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+// stepRegexPrefix marks a --steps selector as a regular expression matched against the {trackName}/{stepName} ids
+const stepRegexPrefix = "re:"
+
+// isStepPattern returns true when the --steps selector is a regular expression or a glob, e.g. core/* or */dns
+func isStepPattern(selector string) bool {
+	return strings.HasPrefix(selector, stepRegexPrefix) || strings.ContainsAny(selector, "*?[")
+}
+
+// hasStepPatterns returns true when one of the --steps selectors is a pattern
+func hasStepPatterns(selectors []string) bool {
+	for _, s := range selectors {
+		if isStepPattern(strings.TrimSpace(s)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// expandStepSelectors replaces the patterns of the --steps selectors with the ids of the steps of the graph they
+// match, in the order of the graph. A glob's * does not match the / between the track and the step, a regular
+// expression is matched against the whole id only when anchored. An error when a pattern is invalid or matches no
+// step, other selectors are kept as is.
+func expandStepSelectors(graph projectGraph, selectors []string) ([]string, error) {
+	ids := []string{}
+	for _, t := range graph.Tracks {
+		for _, s := range t.Steps {
+			ids = append(ids, s.ID)
+		}
+	}
+
+	expanded := []string{}
+	seen := map[string]bool{}
+
+	add := func(id string) {
+		if !seen[id] {
+			seen[id] = true
+			expanded = append(expanded, id)
+		}
+	}
+
+	for _, selector := range selectors {
+		selector = strings.TrimSpace(selector)
+
+		if !isStepPattern(selector) {
+			add(selector)
+			continue
+		}
+
+		match, err := getStepMatcher(selector)
+		if err != nil {
+			return nil, err
+		}
+
+		matched := false
+
+		for _, id := range ids {
+			if match(id) {
+				matched = true
+				add(id)
+			}
+		}
+
+		if !matched {
+			return nil, fmt.Errorf("--steps '%s' does not match any step of the project", selector)
+		}
+	}
+
+	return expanded, nil
+}
+
+// getStepMatcher returns the matcher of the regular expression or glob of the selector
+func getStepMatcher(selector string) (func(id string) bool, error) {
+	if strings.HasPrefix(selector, stepRegexPrefix) {
+		re, err := regexp.Compile(strings.TrimPrefix(selector, stepRegexPrefix))
+		if err != nil {
+			return nil, fmt.Errorf("invalid --steps regular expression '%s': %w", selector, err)
+		}
+
+		return re.MatchString, nil
+	}
+
+	if _, err := path.Match(selector, ""); err != nil {
+		return nil, fmt.Errorf("invalid --steps glob '%s': %w", selector, err)
+	}
+
+	return func(id string) bool {
+		matched, _ := path.Match(selector, id)
+		return matched
+	}, nil
+}
+
+// getNormalizedStepIDs returns the --steps selectors with the steps of the default track, which may be selected by
+// their name alone, as {trackName}/{stepName} ids. Patterns are kept as is.
+func getNormalizedStepIDs(selectors []string) []string {
+	ids := []string{}
+
+	for _, selector := range selectors {
+		selector = strings.TrimSpace(selector)
+
+		if !isStepPattern(selector) && !strings.Contains(selector, "/") {
+			selector = fmt.Sprintf("%s/%s", defaultTrackName, selector)
+		}
+
+		ids = append(ids, selector)
+	}
+
+	return ids
+}
+
+// isStepSelectionSet returns true when steps are selected by tags or excluded, requiring the whitelist to be resolved
+// from the project's steps
+func isStepSelectionSet(tags []string, skipTags []string, skipSteps []string) bool {
+	return len(tags) > 0 || len(skipTags) > 0 || len(skipSteps) > 0
+}
+
+// getSelectedStepWhitelist returns the steps of the whitelist, every step of the graph when empty, tagged with one of
+// the tags when set, without the steps tagged with one of the skipped tags or listed in the skipped steps. Tags are
+// case insensitive, the steps of the default track are selected as {trackName}/{stepName}. An error when a skipped step is not a step of the project.
+func getSelectedStepWhitelist(graph projectGraph, whitelist []string, tags []string, skipTags []string, skipSteps []string) ([]string, error) {
+	steps := map[string]graphStep{}
+	ids := []string{}
+
+	for _, t := range graph.Tracks {
+		for _, s := range t.Steps {
+			steps[s.ID] = s
+			ids = append(ids, s.ID)
+		}
+	}
+
+	skipped := map[string]bool{}
+
+	for _, id := range getNormalizedStepIDs(skipSteps) {
+		if _, ok := steps[id]; !ok {
+			return nil, fmt.Errorf("invalid --skip-steps '%s', not a step of the project. Steps are specified as {trackName}/{stepName}", id)
+		}
+
+		skipped[id] = true
+	}
+
+	if len(whitelist) > 0 {
+		ids = getNormalizedStepIDs(whitelist)
+	}
+
+	selected := []string{}
+
+	for _, id := range ids {
+		s := steps[id]
+
+		if skipped[id] || (len(tags) > 0 && !isStepTagged(s, tags)) || isStepTagged(s, skipTags) {
+			continue
+		}
+
+		selected = append(selected, id)
+	}
+
+	return selected, nil
+}
+
+// isStepTagged returns true when the step has one of the tags
+func isStepTagged(s graphStep, tags []string) bool {
+	for _, tag := range tags {
+		for _, stepTag := range s.Tags {
+			if strings.EqualFold(strings.TrimSpace(tag), stepTag) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// resolveStepWhitelist returns the --steps whitelist with the steps of the --tracks, expanded and filtered by the
+// --tags, --skip-tags, --skip-steps and --since selectors, every step when empty. unselected describes why no step is
+// selected, empty when steps are selected.
+func resolveStepWhitelist(fs afero.Fs) (whitelist []string, unselected string, err error) {
+	whitelist = StepWhitelist
+
+	if len(TrackWhitelist) > 0 {
+		trackSteps, err := getTrackStepIDs(fs, TrackWhitelist)
+		if err != nil {
+			return nil, "", err
+		}
+
+		whitelist = append(whitelist, trackSteps...)
+	}
+
+	if hasStepPatterns(whitelist) {
+		graph, err := getProjectGraph(fs)
+		if err != nil {
+			return nil, "", err
+		}
+
+		whitelist, err = expandStepSelectors(graph, whitelist)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	if isStepSelectionSet(StepTags, SkipTags, SkipSteps) {
+		graph, err := getProjectGraph(fs)
+		if err != nil {
+			return nil, "", err
+		}
+
+		whitelist, err = getSelectedStepWhitelist(graph, whitelist, StepTags, SkipTags, SkipSteps)
+		if err != nil {
+			return nil, "", err
+		}
+
+		if len(whitelist) == 0 {
+			return nil, "No steps are selected by --tags, --skip-tags and --skip-steps, nothing to run", nil
+		}
+	}
+
+	if SinceRef != "" {
+		if BuildContext != "" {
+			return nil, "", fmt.Errorf("--since can not be used with --build-context, the changes are read from the git repository of the working directory")
+		}
+
+		// the project is the working directory
+		dir, err := os.Getwd()
+		if err != nil {
+			return nil, "", err
+		}
+
+		since, changed, err := getSinceStepWhitelist(fs, dir, SinceRef, whitelist)
+		if err != nil {
+			return nil, "", err
+		}
+
+		if !changed {
+			return nil, fmt.Sprintf("No steps changed since %s, nothing to run", SinceRef), nil
+		}
+
+		if len(since) > 0 {
+			logrus.Infof("Running the steps changed since %s and their dependents: %s", SinceRef, strings.Join(since, ", "))
+		}
+
+		whitelist = since
+	}
+
+	return whitelist, "", nil
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSelectedStepWhitelist_ShouldSelectStepsByTagsAndExclusions(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	_ = fs.MkdirAll("tracks/network/step1_vnet", 0755)
+	_ = fs.MkdirAll("tracks/network/step2_dns", 0755)
+	_ = fs.MkdirAll("tracks/app/step1_web", 0755)
+	_ = afero.WriteFile(fs, "tracks/network/step1_vnet/runiac.yml", []byte("tags: [networking]\n"), 0644)
+	_ = afero.WriteFile(fs, "tracks/network/step2_dns/runiac.yml", []byte("tags: [networking, dns]\n"), 0644)
+
+	graph, err := getProjectGraph(fs)
+	require.NoError(t, err)
+	require.Equal(t, []string{"networking", "dns"}, graph.Tracks[1].Steps[1].Tags)
+
+	whitelist, err := getSelectedStepWhitelist(graph, nil, []string{"Networking"}, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"network/vnet", "network/dns"}, whitelist, "tags are case insensitive")
+
+	whitelist, _ = getSelectedStepWhitelist(graph, nil, []string{"networking"}, []string{"dns"}, nil)
+	require.Equal(t, []string{"network/vnet"}, whitelist)
+
+	whitelist, _ = getSelectedStepWhitelist(graph, nil, nil, nil, []string{"network/vnet"})
+	require.Equal(t, []string{"app/web", "network/dns"}, whitelist, "every other step runs")
+
+	whitelist, _ = getSelectedStepWhitelist(graph, []string{"app/web", "network/dns"}, nil, []string{"dns"}, nil)
+	require.Equal(t, []string{"app/web"}, whitelist, "the whitelist is restricted")
+
+	whitelist, _ = getSelectedStepWhitelist(graph, nil, []string{"database"}, nil, nil)
+	require.Empty(t, whitelist)
+
+	_, err = getSelectedStepWhitelist(graph, nil, nil, nil, []string{"network/missing"})
+	require.Error(t, err)
+}
+
+func TestExpandStepSelectors_ShouldMatchGlobsAndRegularExpressions(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	_ = fs.MkdirAll("tracks/core/step1_net", 0755)
+	_ = fs.MkdirAll("tracks/core/step2_dns", 0755)
+	_ = fs.MkdirAll("tracks/infra/step1_net", 0755)
+	_ = fs.MkdirAll("tracks/infra/step1_dns", 0755)
+	_ = fs.MkdirAll("tracks/infra/step2_db", 0755)
+
+	graph, err := getProjectGraph(fs)
+	require.NoError(t, err)
+
+	require.False(t, hasStepPatterns([]string{"core/net", "infra/db"}))
+	require.True(t, hasStepPatterns([]string{"core/net", "*/dns"}))
+
+	steps, err := expandStepSelectors(graph, []string{"core/*"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"core/net", "core/dns"}, steps)
+
+	steps, _ = expandStepSelectors(graph, []string{"*/dns", "core/dns", "infra/db"})
+	require.Equal(t, []string{"core/dns", "infra/dns", "infra/db"}, steps, "a step selected twice is listed once")
+
+	steps, _ = expandStepSelectors(graph, []string{"re:^infra/(net|dns)$"})
+	require.Equal(t, []string{"infra/dns", "infra/net"}, steps)
+
+	_, err = expandStepSelectors(graph, []string{"app/*"})
+	require.Error(t, err, "a pattern matching no step is an error")
+
+	_, err = expandStepSelectors(graph, []string{"re:^infra/(net"})
+	require.Error(t, err)
+
+	_, err = expandStepSelectors(graph, []string{"core/[net"})
+	require.Error(t, err)
+}
+
+func TestResolveStepWhitelist_ShouldApplyTheSelectorsOfTheRun(t *testing.T) {
+	defer func(steps []string, tracks []string, tags []string, skipSteps []string) {
+		StepWhitelist, TrackWhitelist, StepTags, SkipSteps = steps, tracks, tags, skipSteps
+	}(StepWhitelist, TrackWhitelist, StepTags, SkipSteps)
+
+	fs := afero.NewMemMapFs()
+	_ = fs.MkdirAll("tracks/network/step1_vnet", 0755)
+	_ = fs.MkdirAll("tracks/network/step2_dns", 0755)
+	_ = fs.MkdirAll("tracks/app/step1_web", 0755)
+	_ = afero.WriteFile(fs, "tracks/network/step2_dns/runiac.yml", []byte("tags: [dns]\n"), 0644)
+
+	StepWhitelist, TrackWhitelist, StepTags, SkipSteps = []string{"app/*"}, []string{"network"}, nil, []string{"network/vnet"}
+
+	whitelist, unselected, err := resolveStepWhitelist(fs)
+	require.NoError(t, err)
+	require.Empty(t, unselected)
+	require.Equal(t, []string{"app/web", "network/dns"}, whitelist)
+
+	StepWhitelist, TrackWhitelist, StepTags, SkipSteps = nil, nil, []string{"database"}, nil
+
+	whitelist, unselected, err = resolveStepWhitelist(fs)
+	require.NoError(t, err)
+	require.Empty(t, whitelist)
+	require.Equal(t, "No steps are selected by --tags, --skip-tags and --skip-steps, nothing to run", unselected)
+}
+
+func TestGetSelectedStepWhitelist_ShouldSelectStepsOfTheDefaultTrackByName(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	_ = fs.MkdirAll("step1_dns", 0755)
+	_ = fs.MkdirAll("step2_web", 0755)
+	_ = afero.WriteFile(fs, "step1_dns/runiac.yml", []byte("tags: [networking]\n"), 0644)
+
+	graph, err := getProjectGraph(fs)
+	require.NoError(t, err)
+
+	whitelist, err := getSelectedStepWhitelist(graph, []string{"dns", "web"}, []string{"networking"}, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"default/dns"}, whitelist, "a step of the default track may be selected by its name")
+
+	whitelist, err = getSelectedStepWhitelist(graph, nil, nil, nil, []string{"dns"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"default/web"}, whitelist)
+}
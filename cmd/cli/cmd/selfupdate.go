@@ -0,0 +1,498 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+const (
+	releaseRepository = "optum/runiac"
+	releaseChecksums  = "checksums.txt" // The sha256 checksums of the release archives, published by goreleaser
+	releaseMaxSize    = 200 << 20       // The maximum size of a downloaded release archive
+	imageVersionLabel = "org.opencontainers.image.version"
+)
+
+// releaseClient queries and downloads the runiac releases
+var releaseClient = &http.Client{Timeout: 5 * time.Minute}
+
+// releaseAPIURL is the GitHub api the releases are queried from, a variable so tests use a test server
+var releaseAPIURL = githubDefaultAPIURL
+
+// semanticVersionPattern matches a v1.2.3 or 1.2.3-rc.1 version, optionally followed by the suffix of an image tag
+var semanticVersionPattern = regexp.MustCompile(`^v?(\d+)\.(\d+)\.(\d+)(?:-([0-9A-Za-z.]+))?`)
+
+var (
+	updateVersion string
+	updateForce   bool
+)
+
+// getExecutablePath returns the path of the running runiac binary, a variable so tests do not replace the test binary
+var getExecutablePath = func() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.EvalSymlinks(exe)
+}
+
+// getContainerVersion returns the runiac version of the deploy container from its tag, e.g. v1.2.0-alpine-full, or
+// the org.opencontainers.image.version label of the local image, empty when unknown. A variable so tests do not need
+// a container engine.
+var getContainerVersion = func(image string) string {
+	if v := getImageTagVersion(image); v != "" {
+		return v
+	}
+
+	out, err := exec.Command(ContainerEngine, "image", "inspect", "-f", fmt.Sprintf("{{ index .Config.Labels %q }}", imageVersionLabel), image).Output()
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(out))
+}
+
+func init() {
+	selfUpdateCmd.Flags().StringVar(&updateVersion, "version", "", "The release to install, e.g. v1.2.0, defaults to the latest release")
+	selfUpdateCmd.Flags().BoolVar(&updateForce, "force", false, "Install the release even when it is not newer than this runiac")
+
+	rootCmd.AddCommand(selfUpdateCmd)
+}
+
+var selfUpdateCmd = &cobra.Command{
+	Use:   "self-update",
+	Short: "Update runiac to the latest release",
+	Long: fmt.Sprintf(`Replaces the runiac binary with the latest GitHub release of %s, or the release of --version. The
+release archive of this platform is verified against the sha256 checksums of the release before the binary is
+replaced. GITHUB_TOKEN, or GH_TOKEN, is used when set to avoid the rate limit of anonymous requests.`, releaseRepository),
+	Run: func(cmd *cobra.Command, args []string) {
+		release, err := getRelease(releaseClient, releaseAPIURL, updateVersion)
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		if !updateForce && compareVersions(Version, release.TagName) >= 0 {
+			logrus.Infof("runiac %s is up to date, the latest release is %s", Version, release.TagName)
+			return
+		}
+
+		path, err := getExecutablePath()
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		logrus.Infof("Updating runiac %s to %s...", Version, release.TagName)
+
+		binary, err := downloadReleaseBinary(releaseClient, release, runtime.GOOS, runtime.GOARCH)
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		err = replaceExecutable(appFS, path, binary, runtime.GOOS)
+		if err != nil {
+			logrus.WithError(err).Fatalf("Failed to replace %s, it may need to be updated with elevated permissions or the package manager it was installed with, e.g. brew upgrade runiac", path)
+		}
+
+		logrus.Infof("Updated runiac to %s: %s", release.TagName, release.HTMLURL)
+	},
+}
+
+// githubRelease is a release of the GitHub releases api
+type githubRelease struct {
+	TagName string         `json:"tag_name"`
+	HTMLURL string         `json:"html_url"`
+	Assets  []releaseAsset `json:"assets"`
+}
+
+// releaseAsset is a file attached to a GitHub release
+type releaseAsset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// semanticVersion is a parsed major.minor.patch version
+type semanticVersion struct {
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease string
+}
+
+// getRelease returns the latest release of runiac, or the release of the version when set
+func getRelease(client *http.Client, apiURL string, version string) (githubRelease, error) {
+	release := githubRelease{}
+
+	url := fmt.Sprintf("%s/repos/%s/releases/latest", strings.TrimSuffix(apiURL, "/"), releaseRepository)
+
+	if version != "" {
+		url = fmt.Sprintf("%s/repos/%s/releases/tags/v%s", strings.TrimSuffix(apiURL, "/"), releaseRepository, strings.TrimPrefix(version, "v"))
+	}
+
+	b, err := getReleaseFile(client, url, "application/vnd.github.v3+json", 1<<20)
+	if err != nil {
+		return release, fmt.Errorf("unable to query the release of runiac: %w", err)
+	}
+
+	if err = json.Unmarshal(b, &release); err != nil {
+		return release, fmt.Errorf("invalid release response of %s: %w", url, err)
+	}
+
+	if _, ok := parseVersion(release.TagName); !ok {
+		return release, fmt.Errorf("release %s of %s is not a semantic version", release.TagName, releaseRepository)
+	}
+
+	return release, nil
+}
+
+// downloadReleaseBinary downloads the release archive of the platform, verifies it against the release's checksums
+// and returns the runiac binary it contains
+func downloadReleaseBinary(client *http.Client, release githubRelease, goos string, goarch string) ([]byte, error) {
+	name := getReleaseArchiveName(release.TagName, goos, goarch)
+
+	archive, ok := release.getAsset(name)
+	if !ok {
+		return nil, fmt.Errorf("release %s has no archive %s for %s/%s", release.TagName, name, goos, goarch)
+	}
+
+	checksums, ok := release.getAsset(releaseChecksums)
+	if !ok {
+		return nil, fmt.Errorf("release %s has no %s, the archive can not be verified", release.TagName, releaseChecksums)
+	}
+
+	b, err := getReleaseFile(client, checksums.URL, "", 1<<20)
+	if err != nil {
+		return nil, fmt.Errorf("unable to download %s: %w", releaseChecksums, err)
+	}
+
+	expected, ok := parseChecksums(b)[name]
+	if !ok {
+		return nil, fmt.Errorf("%s of release %s has no checksum of %s", releaseChecksums, release.TagName, name)
+	}
+
+	b, err = getReleaseFile(client, archive.URL, "", releaseMaxSize)
+	if err != nil {
+		return nil, fmt.Errorf("unable to download %s: %w", name, err)
+	}
+
+	sum := sha256.Sum256(b)
+	if actual := hex.EncodeToString(sum[:]); actual != expected {
+		return nil, fmt.Errorf("checksum mismatch of %s, expected %s but was %s", name, expected, actual)
+	}
+
+	binary := "runiac"
+	if goos == "windows" {
+		binary += ".exe"
+	}
+
+	return extractArchiveFile(b, binary)
+}
+
+// getReleaseFile downloads the url, authenticated with GITHUB_TOKEN or GH_TOKEN when set, an error when the response
+// is not successful or exceeds maxSize
+func getReleaseFile(client *http.Client, url string, accept string, maxSize int64) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+
+	if token := getFirstEnv("", "GITHUB_TOKEN", "GH_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "token "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s returned %d", url, resp.StatusCode)
+	}
+
+	b, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(b)) > maxSize {
+		return nil, fmt.Errorf("GET %s exceeds %d bytes", url, maxSize)
+	}
+
+	return b, nil
+}
+
+// getAsset returns the asset of the release with the name
+func (r githubRelease) getAsset(name string) (releaseAsset, bool) {
+	for _, a := range r.Assets {
+		if a.Name == name {
+			return a, true
+		}
+	}
+
+	return releaseAsset{}, false
+}
+
+// getReleaseArchiveName returns the name goreleaser gives the archive of the platform, e.g.
+// runiac_1.2.0_linux_x86_64.tar.gz
+func getReleaseArchiveName(tag string, goos string, goarch string) string {
+	arch := goarch
+
+	switch goarch {
+	case "amd64":
+		arch = "x86_64"
+	case "386":
+		arch = "i386"
+	}
+
+	return fmt.Sprintf("runiac_%s_%s_%s.tar.gz", strings.TrimPrefix(tag, "v"), goos, arch)
+}
+
+// parseChecksums returns the sha256 checksums of a checksums.txt by the name of the file
+func parseChecksums(b []byte) map[string]string {
+	checksums := map[string]string{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 {
+			checksums[strings.TrimPrefix(fields[1], "*")] = strings.ToLower(fields[0])
+		}
+	}
+
+	return checksums
+}
+
+// extractArchiveFile returns the file of the tar.gz archive with the name, in any directory of the archive
+func extractArchiveFile(archive []byte, name string) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("the release archive does not contain %s", name)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if header.Typeflag == tar.TypeReg && filepath.Base(header.Name) == name {
+			return ioutil.ReadAll(io.LimitReader(tr, releaseMaxSize))
+		}
+	}
+}
+
+// replaceExecutable replaces the binary at path, writing the new binary next to it then renaming it over the old one
+// so an interrupted update does not leave a partial binary. A running windows binary can not be replaced, it is
+// renamed to {path}.old first.
+func replaceExecutable(fs afero.Fs, path string, binary []byte, goos string) error {
+	info, err := fs.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".new"
+
+	if err = afero.WriteFile(fs, tmp, binary, info.Mode().Perm()|0111); err != nil {
+		return err
+	}
+
+	if goos == "windows" {
+		old := path + ".old"
+		_ = fs.Remove(old)
+
+		if err = fs.Rename(path, old); err != nil {
+			_ = fs.Remove(tmp)
+			return err
+		}
+	}
+
+	if err = fs.Rename(tmp, path); err != nil {
+		_ = fs.Remove(tmp)
+		return err
+	}
+
+	return nil
+}
+
+// parseVersion parses a semantic version, false when it is not one, e.g. the empty version of a development build
+func parseVersion(version string) (semanticVersion, bool) {
+	m := semanticVersionPattern.FindStringSubmatch(strings.TrimSpace(version))
+	if m == nil {
+		return semanticVersion{}, false
+	}
+
+	v := semanticVersion{Prerelease: m[4]}
+	v.Major, _ = strconv.Atoi(m[1])
+	v.Minor, _ = strconv.Atoi(m[2])
+	v.Patch, _ = strconv.Atoi(m[3])
+
+	return v, true
+}
+
+// compareVersions returns -1 when a is older than b, 0 when equal and 1 when newer. A version that is not semantic,
+// e.g. of a development build, is older than every release.
+func compareVersions(a string, b string) int {
+	va, okA := parseVersion(a)
+	vb, okB := parseVersion(b)
+
+	switch {
+	case !okA && !okB:
+		return 0
+	case !okA:
+		return -1
+	case !okB:
+		return 1
+	}
+
+	for _, d := range []int{va.Major - vb.Major, va.Minor - vb.Minor, va.Patch - vb.Patch} {
+		if d != 0 {
+			return sign(d)
+		}
+	}
+
+	// a prerelease precedes its release
+	switch {
+	case va.Prerelease == vb.Prerelease:
+		return 0
+	case va.Prerelease == "":
+		return 1
+	case vb.Prerelease == "":
+		return -1
+	}
+
+	return comparePrereleases(va.Prerelease, vb.Prerelease)
+}
+
+// comparePrereleases orders the prereleases of the same version by their dot separated identifiers, as semver
+// specifies: numeric identifiers compare as numbers and precede alphanumeric ones, which compare lexically, and a
+// prerelease with more identifiers is newer when the others are equal, e.g. rc.9 < rc.10 < rc.10.1
+func comparePrereleases(a string, b string) int {
+	ia, ib := strings.Split(a, "."), strings.Split(b, ".")
+
+	for i := 0; i < len(ia) && i < len(ib); i++ {
+		numA, numB := isNumericIdentifier(ia[i]), isNumericIdentifier(ib[i])
+
+		switch {
+		case numA && numB:
+			// numeric identifiers have no leading zeros, a longer one is the larger number
+			if len(ia[i]) != len(ib[i]) {
+				return sign(len(ia[i]) - len(ib[i]))
+			}
+		case numA:
+			return -1
+		case numB:
+			return 1
+		}
+
+		if c := strings.Compare(ia[i], ib[i]); c != 0 {
+			return c
+		}
+	}
+
+	return sign(len(ia) - len(ib))
+}
+
+// isNumericIdentifier returns true when the prerelease identifier only consists of digits
+func isNumericIdentifier(identifier string) bool {
+	if identifier == "" {
+		return false
+	}
+
+	for _, r := range identifier {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// sign returns -1, 0 or 1 for the sign of d
+func sign(d int) int {
+	switch {
+	case d < 0:
+		return -1
+	case d > 0:
+		return 1
+	}
+
+	return 0
+}
+
+// describeVersionCheck describes how the version compares to the latest release, true when it is outdated
+func describeVersionCheck(version string, latest githubRelease) (string, bool) {
+	if _, ok := parseVersion(version); !ok {
+		return fmt.Sprintf("runiac %s is a development build, the latest release is %s: %s", version, latest.TagName, latest.HTMLURL), false
+	}
+
+	if compareVersions(version, latest.TagName) < 0 {
+		return fmt.Sprintf("runiac %s is outdated, the latest release is %s: %s. Run 'runiac self-update' to update.", version, latest.TagName, latest.HTMLURL), true
+	}
+
+	return fmt.Sprintf("runiac %s is the latest release", version), false
+}
+
+// getImageTagVersion returns the version of the image's tag, e.g. v1.2.0 of runiac/deploy:v1.2.0-alpine-full, empty
+// when the tag is not versioned such as latest. The suffix of the tag is the image's flavor, not a prerelease.
+func getImageTagVersion(image string) string {
+	// the registry host may have a port, the tag follows the last path segment
+	name := image[strings.LastIndex(image, "/")+1:]
+	name = strings.SplitN(name, "@", 2)[0]
+
+	i := strings.LastIndex(name, ":")
+	if i < 0 {
+		return ""
+	}
+
+	v, ok := parseVersion(name[i+1:])
+	if !ok {
+		return ""
+	}
+
+	return fmt.Sprintf("v%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// warnOutdatedCLI warns when the runiac of the deploy container, from its image, is incompatible with the cli. A
+// pinned or validated container fails on the version reported by its contract instead, the image is not inspected
+// for a development build.
+func warnOutdatedCLI(image string) {
+	if _, ok := parseVersion(Version); !ok {
+		return
+	}
+
+	if mismatch := getContainerVersionMismatch(Version, getContainerVersion(image)); mismatch != "" {
+		logrus.Warnf("The CLI may not match the deploy container %s: %s", image, mismatch)
+	}
+}
@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+// getTestReleaseArchive returns a tar.gz archive containing the files
+func getTestReleaseArchive(t *testing.T, files map[string]string) []byte {
+	var b bytes.Buffer
+
+	gz := gzip.NewWriter(&b)
+	tw := tar.NewWriter(gz)
+
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+
+	return b.Bytes()
+}
+
+// getTestReleaseServer serves release v1.2.0 with its linux amd64 archive and the checksums
+func getTestReleaseServer(t *testing.T, archive []byte, checksum string) *httptest.Server {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+
+	release := githubRelease{
+		TagName: "v1.2.0",
+		HTMLURL: "https://github.com/optum/runiac/releases/tag/v1.2.0",
+		Assets: []releaseAsset{
+			{Name: "runiac_1.2.0_linux_x86_64.tar.gz", URL: server.URL + "/download/runiac_1.2.0_linux_x86_64.tar.gz"},
+			{Name: releaseChecksums, URL: server.URL + "/download/checksums.txt"},
+		},
+	}
+
+	mux.HandleFunc("/repos/optum/runiac/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(release)
+	})
+	mux.HandleFunc("/download/runiac_1.2.0_linux_x86_64.tar.gz", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(archive)
+	})
+	mux.HandleFunc("/download/checksums.txt", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s  runiac_1.2.0_linux_x86_64.tar.gz\n%s  runiac_1.2.0_darwin_arm64.tar.gz\n", checksum, checksum)
+	})
+
+	return server
+}
+
+func TestCompareVersions_ShouldOrderSemanticVersions(t *testing.T) {
+	tests := map[string]struct {
+		a        string
+		b        string
+		expected int
+	}{
+		"ShouldBeEqual":                     {a: "v1.2.0", b: "1.2.0", expected: 0},
+		"ShouldCompareMinor":                {a: "v1.10.0", b: "v1.9.3", expected: 1},
+		"ShouldCompareMajor":                {a: "v1.9.0", b: "v2.0.0", expected: -1},
+		"ShouldOrderPrereleaseBefore":       {a: "v1.2.0-rc.1", b: "v1.2.0", expected: -1},
+		"ShouldOrderDevelopmentBuildFirst":  {a: "", b: "v0.0.1", expected: -1},
+		"ShouldCompareNumericPrereleases":   {a: "v1.2.0-rc.9", b: "v1.2.0-rc.10", expected: -1},
+		"ShouldCompareLargerPrerelease":     {a: "v1.2.0-rc.10", b: "v1.2.0-rc.9", expected: 1},
+		"ShouldBeEqualPrereleases":          {a: "v1.2.0-rc.10", b: "v1.2.0-rc.10", expected: 0},
+		"ShouldOrderNumericBeforeAlpha":     {a: "v1.2.0-1", b: "v1.2.0-alpha", expected: -1},
+		"ShouldCompareAlphaPrereleases":     {a: "v1.2.0-alpha", b: "v1.2.0-beta", expected: -1},
+		"ShouldOrderShorterPrereleaseFirst": {a: "v1.2.0-rc.1", b: "v1.2.0-rc.1.1", expected: -1},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, test.expected, compareVersions(test.a, test.b))
+		})
+	}
+}
+
+func TestDescribeVersionCheck_ShouldReportOutdatedVersion(t *testing.T) {
+	latest := githubRelease{TagName: "v1.2.0", HTMLURL: "https://github.com/optum/runiac/releases/tag/v1.2.0"}
+
+	message, outdated := describeVersionCheck("v1.1.0", latest)
+	require.True(t, outdated)
+	require.Equal(t, "runiac v1.1.0 is outdated, the latest release is v1.2.0: https://github.com/optum/runiac/releases/tag/v1.2.0. Run 'runiac self-update' to update.", message)
+
+	message, outdated = describeVersionCheck("1.2.0", latest)
+	require.False(t, outdated)
+	require.Equal(t, "runiac 1.2.0 is the latest release", message)
+
+	_, outdated = describeVersionCheck("", latest)
+	require.False(t, outdated, "a development build is not outdated")
+}
+
+func TestGetImageTagVersion_ShouldReadTheVersionOfTheTag(t *testing.T) {
+	require.Equal(t, "v1.3.0", getImageTagVersion("registry.example.com:5000/runiac/deploy:v1.3.0-alpine-full"))
+	require.Equal(t, "v1.3.0", getImageTagVersion("runiac/deploy:1.3.0@sha256:abc"))
+	require.Equal(t, "", getImageTagVersion("docker.io/runiac/deploy:latest-alpine-full"))
+	require.Equal(t, "", getImageTagVersion("localhost:5000/deploy"))
+}
+
+func TestDownloadReleaseBinary_ShouldVerifyChecksum(t *testing.T) {
+	archive := getTestReleaseArchive(t, map[string]string{"README.md": "readme", "runiac": "new binary"})
+	sum := sha256.Sum256(archive)
+
+	server := getTestReleaseServer(t, archive, hex.EncodeToString(sum[:]))
+	defer server.Close()
+
+	release, err := getRelease(server.Client(), server.URL, "")
+	require.NoError(t, err)
+	require.Equal(t, "v1.2.0", release.TagName)
+
+	binary, err := downloadReleaseBinary(server.Client(), release, "linux", "amd64")
+	require.NoError(t, err)
+	require.Equal(t, "new binary", string(binary))
+
+	_, err = downloadReleaseBinary(server.Client(), release, "windows", "arm64")
+	require.EqualError(t, err, "release v1.2.0 has no archive runiac_1.2.0_windows_arm64.tar.gz for windows/arm64")
+
+	tampered := getTestReleaseServer(t, archive, "0000")
+	defer tampered.Close()
+
+	release, err = getRelease(tampered.Client(), tampered.URL, "")
+	require.NoError(t, err)
+
+	_, err = downloadReleaseBinary(tampered.Client(), release, "linux", "amd64")
+	require.EqualError(t, err, fmt.Sprintf("checksum mismatch of runiac_1.2.0_linux_x86_64.tar.gz, expected 0000 but was %s", hex.EncodeToString(sum[:])))
+
+	_, err = getRelease(server.Client(), server.URL, "v9.9.9")
+	require.Error(t, err, "an unknown release should fail")
+}
+
+func TestReplaceExecutable_ShouldReplaceBinary(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	_ = afero.WriteFile(fs, "/usr/local/bin/runiac", []byte("old binary"), 0755)
+
+	require.NoError(t, replaceExecutable(fs, "/usr/local/bin/runiac", []byte("new binary"), "linux"))
+
+	b, _ := afero.ReadFile(fs, "/usr/local/bin/runiac")
+	require.Equal(t, "new binary", string(b))
+
+	exists, _ := afero.Exists(fs, "/usr/local/bin/runiac.new")
+	require.False(t, exists)
+
+	_ = afero.WriteFile(fs, "/bin/runiac.exe", []byte("old binary"), 0755)
+
+	require.NoError(t, replaceExecutable(fs, "/bin/runiac.exe", []byte("new binary"), "windows"))
+
+	b, _ = afero.ReadFile(fs, "/bin/runiac.exe.old")
+	require.Equal(t, "old binary", string(b), "a running windows binary is moved aside")
+
+	require.Error(t, replaceExecutable(fs, "/missing/runiac", []byte("new binary"), "linux"))
+}
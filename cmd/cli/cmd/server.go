@@ -0,0 +1,544 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const (
+	serverPlanDir          = ".runiac/server/plans" // The --output-dir of the plans of a pull request, within its checkout
+	serverCommentMaxOutput = 30000                  // The trailing characters of the output of a run included in its comment
+	serverMaxWebhookSize   = 25 << 20               // The maximum size of a webhook, GitHub caps its payloads at 25 MB
+)
+
+var (
+	serverListen          string
+	serverWorkDir         string
+	serverApplyUsers      []string
+	serverRequireApproval bool
+	serverAllowForks      bool
+)
+
+// runServerCommand runs a command in dir with the additional env variables and returns its stdout and stderr, a
+// variable so tests do not need git or a container engine
+var runServerCommand = func(dir string, env []string, name string, args ...string) (string, string, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+
+	return stdout.String(), stderr.String(), err
+}
+
+func init() {
+	serverCmd.Flags().StringVar(&serverListen, "listen", ":8080", "The address the webhooks are received on, at /events")
+	serverCmd.Flags().StringVar(&serverWorkDir, "work-dir", ".runiac/server", "The directory of the checkouts of the pull requests, one per pull request")
+	serverCmd.Flags().StringSliceVar(&serverApplyUsers, "apply-users", []string{}, "The users permitted to apply a pull request, by default the users that can push to the repository")
+	serverCmd.Flags().BoolVar(&serverRequireApproval, "require-approval", false, "Only apply pull requests that were approved by a reviewer")
+	serverCmd.Flags().BoolVar(&serverAllowForks, "allow-forks", false, "Plan and apply the pull requests of forks, which run the code of the fork with the credentials of the server")
+
+	rootCmd.AddCommand(serverCmd)
+}
+
+var serverCmd = &cobra.Command{
+	Use:   "server [-- deploy flags]",
+	Short: "Plan and apply pull requests from GitHub and GitLab webhooks",
+	Long: `Receives the pull request webhooks of GitHub and the merge request webhooks of GitLab at /events. When a pull
+request is opened or pushed to, the tracks it changes are planned with runiac plan in a checkout of its head and
+the plan is commented on the pull request. A change outside of the tracks directory plans every track. Commenting
+'runiac apply' applies the commented plan of the head of the pull request with runiac deploy --artifacts-from,
+'runiac plan' plans it again. The flags after -- are passed to runiac plan and deploy, e.g. -e dev -d dev.
+
+The webhooks are verified with GITHUB_WEBHOOK_SECRET and GITLAB_WEBHOOK_SECRET, the webhooks of a provider
+without a secret are rejected. The repositories are fetched and the comments posted with GITHUB_TOKEN and
+GITLAB_TOKEN, GITHUB_API_URL and GITLAB_API_URL target GitHub Enterprise or a self-managed GitLab.
+
+A pull request is applied when commented by a user that can push to the repository, a GitHub user with the write
+permission or a GitLab developer of the project, or by one of --apply-users which replaces the permission check.
+--require-approval only applies approved pull requests. The pull requests of forks are ignored unless
+--allow-forks, a plan runs the code of the pull request with the credentials of the server.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		executable, err := os.Executable()
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		s := newWebhookServer(executable, serverWorkDir, args)
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/events", s.handleWebhook)
+		mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+
+		logrus.Infof("Listening for webhooks on %s/events", serverListen)
+
+		err = http.ListenAndServe(serverListen, mux)
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+	},
+}
+
+// plannedPullRequest is the last plan of a pull request, applied by a runiac apply comment
+type plannedPullRequest struct {
+	SHA    string
+	Tracks []string // Empty when every track was planned
+}
+
+// webhookServer plans and applies the pull requests of the webhooks it receives, one run at a time per pull request
+type webhookServer struct {
+	executable string   // The runiac binary running plan and deploy
+	workDir    string   // The directory of the checkouts
+	args       []string // The flags passed to runiac plan and deploy
+	client     *http.Client
+	providers  map[string]webhookProvider
+
+	applyUsers      []string // The users permitted to apply, empty permits the users that can push to the repository
+	requireApproval bool     // Whether a pull request must be approved to be applied
+	allowForks      bool     // Whether the pull requests of forks are planned and applied
+
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+	plans map[string]plannedPullRequest
+
+	wg sync.WaitGroup // Tracks the events in progress, so tests can wait for them
+}
+
+// newWebhookServer returns a server running the executable for the pull requests of the providers configured in the
+// environment
+func newWebhookServer(executable string, workDir string, args []string) *webhookServer {
+	return &webhookServer{
+		executable: executable,
+		workDir:    workDir,
+		args:       args,
+		client:     &http.Client{Timeout: 30 * time.Second},
+		providers: map[string]webhookProvider{
+			providerGitHub: getWebhookProvider("GITHUB", githubDefaultAPIURL),
+			providerGitLab: getWebhookProvider("GITLAB", gitlabDefaultAPIURL),
+		},
+		applyUsers:      serverApplyUsers,
+		requireApproval: serverRequireApproval,
+		allowForks:      serverAllowForks,
+		locks:           map[string]*sync.Mutex{},
+		plans:           map[string]plannedPullRequest{},
+	}
+}
+
+// getWebhookProvider reads the configuration of the provider from the {PROVIDER}_ variables
+func getWebhookProvider(prefix string, defaultAPIURL string) webhookProvider {
+	provider := webhookProvider{
+		Secret: os.Getenv(prefix + "_WEBHOOK_SECRET"),
+		Token:  os.Getenv(prefix + "_TOKEN"),
+		APIURL: strings.TrimSuffix(os.Getenv(prefix+"_API_URL"), "/"),
+	}
+
+	if provider.APIURL == "" {
+		provider.APIURL = defaultAPIURL
+	}
+
+	return provider
+}
+
+// handleWebhook verifies and parses the webhook, the event is processed once the webhook is acknowledged since a
+// plan outlasts the timeout of the providers' webhooks
+func (s *webhookServer) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "webhooks are posted", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, serverMaxWebhookSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var event pullRequestEvent
+	var ok bool
+
+	switch {
+	case r.Header.Get("X-GitHub-Event") != "":
+		if err = verifyGitHubWebhook(s.providers[providerGitHub].Secret, r.Header.Get("X-Hub-Signature-256"), body); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		event, ok, err = parseGitHubWebhook(r.Header.Get("X-GitHub-Event"), body)
+	case r.Header.Get("X-Gitlab-Event") != "":
+		if err = verifyGitLabWebhook(s.providers[providerGitLab].Secret, r.Header.Get("X-Gitlab-Token")); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		event, ok, err = parseGitLabWebhook(r.Header.Get("X-Gitlab-Event"), body)
+	default:
+		http.Error(w, "not a GitHub or GitLab webhook", http.StatusBadRequest)
+		return
+	}
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !ok {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+
+	s.wg.Add(1)
+
+	go func() {
+		defer s.wg.Done()
+		s.process(event)
+	}()
+}
+
+// lock returns the lock of the pull request, its events are processed one at a time
+func (s *webhookServer) lock(key string) *sync.Mutex {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.locks[key]; !ok {
+		s.locks[key] = &sync.Mutex{}
+	}
+
+	return s.locks[key]
+}
+
+// process plans or applies the pull request of the event and comments the result, failures are commented as well
+func (s *webhookServer) process(e pullRequestEvent) {
+	lock := s.lock(e.getKey())
+	lock.Lock()
+	defer lock.Unlock()
+
+	logger := logrus.WithField("pull_request", e.getKey())
+	logger.Infof("Processing %s", e.Action)
+
+	comment, err := s.run(e)
+	if err != nil {
+		logger.WithError(err).Errorf("Unable to %s", e.Action)
+		comment = fmt.Sprintf("#### runiac %s failed\n\n```\n%s\n```", e.Action, tailString(err.Error(), serverCommentMaxOutput))
+	}
+
+	if comment == "" {
+		return
+	}
+
+	if err = postPullRequestComment(s.client, s.providers[e.Provider], e, comment); err != nil {
+		logger.WithError(err).Error("Unable to comment on the pull request")
+	}
+}
+
+// run checks out the head of the pull request and plans or applies it, returning the comment of the result
+func (s *webhookServer) run(e pullRequestEvent) (string, error) {
+	provider := s.providers[e.Provider]
+
+	// the comments of GitHub do not include the base and head of their pull request
+	if e.BaseRef == "" && e.Provider == providerGitHub {
+		pull, err := getGitHubPull(s.client, provider, e)
+		if err != nil {
+			return "", fmt.Errorf("unable to get the base of the pull request: %w", err)
+		}
+
+		e.BaseRef, e.Fork = pull.Base.Ref, pull.isFork(e.Repository)
+	}
+
+	if e.Fork && !s.allowForks {
+		return fmt.Sprintf("#### runiac %s\n\nThe pull requests of forks are not planned or applied, they would run the code of the fork with the credentials of the server.", e.Action), nil
+	}
+
+	if e.Action == serverActionApply {
+		refusal, err := s.authorizeApply(e, provider)
+		if err != nil {
+			return "", fmt.Errorf("unable to verify %s is permitted to apply: %w", e.User, err)
+		}
+
+		if refusal != "" {
+			logrus.WithField("pull_request", e.getKey()).Warnf("Refused to apply for %s: %s", e.User, refusal)
+			return fmt.Sprintf("#### runiac apply\n\n%s", refusal), nil
+		}
+	}
+
+	dir, sha, changed, err := s.checkout(e, provider)
+	if err != nil {
+		return "", err
+	}
+
+	if e.Action == serverActionApply {
+		return s.apply(e, dir, sha)
+	}
+
+	tracks, affected := getAffectedTracks(changed)
+	if !affected {
+		logrus.WithField("pull_request", e.getKey()).Infof("No tracks changed in %s", sha)
+		return "", nil
+	}
+
+	args := append([]string{"plan", "--output-dir", serverPlanDir}, getServerTrackArgs(tracks)...)
+
+	if err = os.RemoveAll(filepath.Join(dir, serverPlanDir)); err != nil {
+		return "", err
+	}
+
+	stdout, stderr, err := runServerCommand(dir, nil, s.executable, append(args, s.args...)...)
+
+	if err == nil {
+		s.mu.Lock()
+		s.plans[e.getKey()] = plannedPullRequest{SHA: sha, Tracks: tracks}
+		s.mu.Unlock()
+	}
+
+	return renderServerComment(serverActionPlan, sha, tracks, stdout, stderr, err), nil
+}
+
+// authorizeApply returns why the commenter of the event may not apply its pull request, empty when permitted
+func (s *webhookServer) authorizeApply(e pullRequestEvent, provider webhookProvider) (string, error) {
+	if e.User == "" {
+		return "The commenter of the pull request is unknown, it is not applied.", nil
+	}
+
+	if len(s.applyUsers) > 0 {
+		permitted := false
+		for _, user := range s.applyUsers {
+			permitted = permitted || strings.EqualFold(strings.TrimSpace(user), e.User)
+		}
+
+		if !permitted {
+			return fmt.Sprintf("@%s is not permitted to apply, the pull request is applied by %s.", e.User, strings.Join(s.applyUsers, ", ")), nil
+		}
+	} else {
+		permitted, err := hasWritePermission(s.client, provider, e)
+		if err != nil {
+			return "", err
+		}
+
+		if !permitted {
+			return fmt.Sprintf("@%s is not permitted to apply, the pull request is applied by the users that can push to the repository.", e.User), nil
+		}
+	}
+
+	if s.requireApproval {
+		approved, err := isApproved(s.client, provider, e)
+		if err != nil {
+			return "", err
+		}
+
+		if !approved {
+			return "The pull request is applied once approved by a reviewer.", nil
+		}
+	}
+
+	return "", nil
+}
+
+// apply applies the plans of the head of the pull request, which must be the head that was planned
+func (s *webhookServer) apply(e pullRequestEvent, dir string, sha string) (string, error) {
+	s.mu.Lock()
+	planned, ok := s.plans[e.getKey()]
+	s.mu.Unlock()
+
+	if !ok || planned.SHA != sha {
+		return fmt.Sprintf("#### runiac apply of %s\n\nThe head of the pull request was not planned, comment `runiac plan` to plan it.", getShortSHA(sha)), nil
+	}
+
+	args := append([]string{"deploy", "--artifacts-from", serverPlanDir}, getServerTrackArgs(planned.Tracks)...)
+
+	stdout, stderr, err := runServerCommand(dir, nil, s.executable, append(args, s.args...)...)
+
+	// the plans were applied, or partially applied, and must be planned again
+	s.mu.Lock()
+	delete(s.plans, e.getKey())
+	s.mu.Unlock()
+
+	return renderServerComment(serverActionApply, sha, planned.Tracks, stdout, stderr, err), nil
+}
+
+// checkout fetches the head and base of the pull request into its checkout and checks out the head, returning the
+// checkout's directory, the head commit and the paths the pull request changes
+func (s *webhookServer) checkout(e pullRequestEvent, provider webhookProvider) (string, string, []string, error) {
+	dir := filepath.Join(s.workDir, e.Provider, filepath.FromSlash(e.Repository), strconv.Itoa(e.Number))
+	env := getGitAuthEnv(e.Provider, provider.Token)
+
+	git := func(args ...string) (string, error) {
+		stdout, stderr, err := runServerCommand(dir, env, "git", args...)
+		if err != nil {
+			return "", fmt.Errorf("git %s failed: %w %s", args[0], err, strings.TrimSpace(stderr))
+		}
+
+		return strings.TrimSpace(stdout), nil
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, ".git")); os.IsNotExist(err) {
+		if err = os.MkdirAll(dir, 0755); err != nil {
+			return "", "", nil, err
+		}
+
+		if _, err = git("init", "-q"); err != nil {
+			return "", "", nil, err
+		}
+
+		if _, err = git("remote", "add", "origin", e.CloneURL); err != nil {
+			return "", "", nil, err
+		}
+	}
+
+	_, err := git("fetch", "-q", "--force", "origin", fmt.Sprintf("+%s:refs/runiac/head", e.getHeadRef()), fmt.Sprintf("+refs/heads/%s:refs/runiac/base", e.BaseRef))
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	if _, err = git("checkout", "-q", "--force", "--detach", "refs/runiac/head"); err != nil {
+		return "", "", nil, err
+	}
+
+	sha, err := git("rev-parse", "HEAD")
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	diff, err := git("diff", "--name-only", "refs/runiac/base...refs/runiac/head")
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	changed := []string{}
+	for _, path := range strings.Split(diff, "\n") {
+		if path != "" {
+			changed = append(changed, path)
+		}
+	}
+
+	return dir, sha, changed, nil
+}
+
+// getGitAuthEnv returns the env variables authenticating git to the provider with the token. The token is passed as
+// an http header configured from the environment, so it is not part of the remote's url or the arguments of git.
+func getGitAuthEnv(provider string, token string) []string {
+	env := []string{"GIT_TERMINAL_PROMPT=0"}
+
+	if token == "" {
+		return env
+	}
+
+	user := "x-access-token"
+	if provider == providerGitLab {
+		user = "oauth2"
+	}
+
+	credentials := base64.StdEncoding.EncodeToString([]byte(user + ":" + token))
+
+	return append(env, "GIT_CONFIG_COUNT=1", "GIT_CONFIG_KEY_0=http.extraHeader", "GIT_CONFIG_VALUE_0=Authorization: Basic "+credentials)
+}
+
+// getAffectedTracks returns the tracks of the changed paths, every track when a path outside of the tracks
+// directory changes since it may be shared by every track, e.g. the runiac config or the Dockerfile. A step
+// directory at the top-level of the project changes the default track. False when nothing changed.
+func getAffectedTracks(changed []string) ([]string, bool) {
+	if len(changed) == 0 {
+		return nil, false
+	}
+
+	tracks := map[string]bool{}
+
+	for _, path := range changed {
+		parts := strings.Split(path, "/")
+
+		switch {
+		case len(parts) > 2 && parts[0] == tracksDir:
+			tracks[parts[1]] = true
+		case len(parts) > 1 && strings.HasPrefix(parts[0], stepDirPrefix):
+			tracks[defaultTrackName] = true
+		default:
+			return nil, true
+		}
+	}
+
+	affected := []string{}
+	for track := range tracks {
+		affected = append(affected, track)
+	}
+
+	sort.Strings(affected)
+
+	return affected, true
+}
+
+// getServerTrackArgs returns the --track argument of the tracks, none when every track is targeted
+func getServerTrackArgs(tracks []string) []string {
+	if len(tracks) == 0 {
+		return nil
+	}
+
+	return []string{"--track", strings.Join(tracks, ",")}
+}
+
+// renderServerComment returns the markdown of the comment of a plan or apply, its output and the run's log collapsed
+func renderServerComment(action serverAction, sha string, tracks []string, stdout string, stderr string, err error) string {
+	var b strings.Builder
+
+	result := "succeeded"
+	if err != nil {
+		result = fmt.Sprintf("failed: %s", err)
+	}
+
+	fmt.Fprintf(&b, "#### runiac %s of %s %s\n\n", action, getShortSHA(sha), result)
+
+	if len(tracks) == 0 {
+		b.WriteString("Tracks: all\n\n")
+	} else {
+		fmt.Fprintf(&b, "Tracks: %s\n\n", strings.Join(tracks, ", "))
+	}
+
+	if strings.TrimSpace(stdout) != "" {
+		fmt.Fprintf(&b, "```\n%s\n```\n\n", tailString(strings.TrimSpace(stdout), serverCommentMaxOutput))
+	}
+
+	if strings.TrimSpace(stderr) != "" {
+		fmt.Fprintf(&b, "<details><summary>Log</summary>\n\n```\n%s\n```\n\n</details>\n\n", tailString(strings.TrimSpace(stderr), serverCommentMaxOutput))
+	}
+
+	if action == serverActionPlan && err == nil {
+		b.WriteString("Comment `runiac apply` to apply this plan.\n")
+	}
+
+	return b.String()
+}
+
+// getShortSHA returns the abbreviated commit
+func getShortSHA(sha string) string {
+	if len(sha) > 8 {
+		return sha[:8]
+	}
+
+	return sha
+}
+
+// tailString returns the last n characters of s, marking that the start was cut
+func tailString(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+
+	return "...\n" + s[len(s)-n:]
+}
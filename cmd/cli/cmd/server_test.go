@@ -0,0 +1,280 @@
+package cmd
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func signGitHubWebhook(secret string, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestHandleWebhook_ShouldOnlyAcceptVerifiedWebhooks(t *testing.T) {
+	defer func(run func(string, []string, string, ...string) (string, string, error)) { runServerCommand = run }(runServerCommand)
+
+	runServerCommand = func(dir string, env []string, name string, args ...string) (string, string, error) {
+		return "", "", nil
+	}
+
+	s := newWebhookServer("runiac", t.TempDir(), nil)
+	s.providers[providerGitHub] = webhookProvider{Secret: "secret", APIURL: "http://127.0.0.1:0"}
+
+	post := func(headers map[string]string, body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(body))
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+
+		w := httptest.NewRecorder()
+		s.handleWebhook(w, req)
+		s.wg.Wait()
+
+		return w.Code
+	}
+
+	opened := `{"action": "opened", "number": 1, "pull_request": {"base": {"ref": "main"}}, "repository": {"full_name": "optum/runiac"}}`
+
+	require.Equal(t, http.StatusAccepted, post(map[string]string{"X-GitHub-Event": "pull_request", "X-Hub-Signature-256": signGitHubWebhook("secret", opened)}, opened))
+	require.Equal(t, http.StatusUnauthorized, post(map[string]string{"X-GitHub-Event": "pull_request", "X-Hub-Signature-256": signGitHubWebhook("other", opened)}, opened))
+	require.Equal(t, http.StatusUnauthorized, post(map[string]string{"X-Gitlab-Event": "Merge Request Hook", "X-Gitlab-Token": "secret"}, `{}`), "GitLab has no secret")
+	require.Equal(t, http.StatusBadRequest, post(map[string]string{}, opened))
+
+	closed := `{"action": "closed", "number": 1}`
+	require.Equal(t, http.StatusOK, post(map[string]string{"X-GitHub-Event": "pull_request", "X-Hub-Signature-256": signGitHubWebhook("secret", closed)}, closed), "a closed pull request is not planned")
+}
+
+func TestParseWebhooks_ShouldActOnPullRequestsAndCommentCommands(t *testing.T) {
+	e, ok, err := parseGitHubWebhook("issue_comment", []byte(`{"action": "created", "issue": {"number": 7, "pull_request": {}}, "comment": {"body": "runiac apply\nplease", "user": {"login": "octocat"}, "author_association": "MEMBER"}, "repository": {"full_name": "optum/runiac", "clone_url": "https://github.com/optum/runiac.git"}}`))
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, pullRequestEvent{Provider: providerGitHub, Action: serverActionApply, Repository: "optum/runiac", CloneURL: "https://github.com/optum/runiac.git", Number: 7, User: "octocat", AuthorAssociation: "MEMBER"}, e)
+	require.Equal(t, "refs/pull/7/head", e.getHeadRef())
+
+	_, ok, _ = parseGitHubWebhook("issue_comment", []byte(`{"action": "created", "issue": {"number": 7}, "comment": {"body": "runiac apply"}}`))
+	require.False(t, ok, "the comment of an issue is not a pull request")
+
+	_, ok, _ = parseGitHubWebhook("issue_comment", []byte(`{"action": "created", "issue": {"number": 7, "pull_request": {}}, "comment": {"body": "should we runiac apply?"}}`))
+	require.False(t, ok, "the comment is not a command")
+
+	e, ok, _ = parseGitHubWebhook("pull_request", []byte(`{"action": "opened", "number": 1, "pull_request": {"base": {"ref": "main"}, "head": {"repo": {"full_name": "fork/runiac"}}}, "repository": {"full_name": "optum/runiac"}}`))
+	require.True(t, ok)
+	require.True(t, e.Fork, "the head of the pull request is in a fork")
+
+	e, ok, err = parseGitLabWebhook("Merge Request Hook", []byte(`{"object_attributes": {"action": "update", "iid": 3, "target_branch": "main", "oldrev": "abc", "source_project_id": 12, "target_project_id": 12}, "project": {"id": 12, "path_with_namespace": "group/infra", "git_http_url": "https://gitlab.com/group/infra.git"}}`))
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, pullRequestEvent{Provider: providerGitLab, Action: serverActionPlan, Repository: "group/infra", ProjectID: 12, CloneURL: "https://gitlab.com/group/infra.git", Number: 3, BaseRef: "main"}, e)
+	require.Equal(t, "refs/merge-requests/3/head", e.getHeadRef())
+
+	_, ok, _ = parseGitLabWebhook("Merge Request Hook", []byte(`{"object_attributes": {"action": "update", "iid": 3}}`))
+	require.False(t, ok, "an update without commits is not planned")
+
+	e, ok, _ = parseGitLabWebhook("Note Hook", []byte(`{"object_attributes": {"noteable_type": "MergeRequest", "note": "Runiac Plan"}, "merge_request": {"iid": 3, "target_branch": "main", "source_project_id": 13, "target_project_id": 12}, "user": {"id": 5, "username": "tanuki"}}`))
+	require.True(t, ok)
+	require.Equal(t, serverActionPlan, e.Action)
+	require.Equal(t, "main", e.BaseRef)
+	require.True(t, e.Fork, "the source branch is in a fork of the project")
+	require.Equal(t, "tanuki", e.User)
+	require.Equal(t, 5, e.UserID)
+}
+
+func TestGetAffectedTracks_ShouldPlanEveryTrackForSharedChanges(t *testing.T) {
+	tracks, ok := getAffectedTracks([]string{"tracks/network/step1_vpc/main.tf", "tracks/app/step1_web/main.tf", "tracks/network/step2_dns/main.tf"})
+	require.True(t, ok)
+	require.Equal(t, []string{"app", "network"}, tracks)
+
+	tracks, ok = getAffectedTracks([]string{"step1_default/main.tf"})
+	require.True(t, ok)
+	require.Equal(t, []string{defaultTrackName}, tracks)
+
+	tracks, ok = getAffectedTracks([]string{"tracks/network/step1_vpc/main.tf", "runiac.yml"})
+	require.True(t, ok)
+	require.Nil(t, tracks, "a shared change plans every track")
+
+	_, ok = getAffectedTracks(nil)
+	require.False(t, ok)
+}
+
+func TestWebhookServer_ShouldApplyThePlannedHead(t *testing.T) {
+	defer func(run func(string, []string, string, ...string) (string, string, error)) { runServerCommand = run }(runServerCommand)
+
+	var mu sync.Mutex
+	sha := "0123456789abcdef"
+	runs := []string{}
+	comments := []string{}
+
+	runServerCommand = func(dir string, env []string, name string, args ...string) (string, string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if name != "git" {
+			runs = append(runs, strings.Join(args, " "))
+			return "Plan: 1 to add, 0 to change, 0 to destroy", "level=info", nil
+		}
+
+		require.Contains(t, strings.Join(env, " "), "GIT_CONFIG_VALUE_0=Authorization: Basic ", "the token should authenticate git")
+
+		switch args[0] {
+		case "rev-parse":
+			return sha + "\n", "", nil
+		case "diff":
+			return "tracks/network/step1_vpc/main.tf\n", "", nil
+		}
+
+		return "", "", nil
+	}
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/optum/runiac/pulls/1":
+			_, _ = w.Write([]byte(`{"base": {"ref": "main"}, "head": {"repo": {"full_name": "optum/runiac"}}}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/optum/runiac/collaborators/octocat/permission":
+			_, _ = w.Write([]byte(`{"permission": "write"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/repos/optum/runiac/issues/1/comments":
+			payload := map[string]string{}
+			_ = json.NewDecoder(r.Body).Decode(&payload)
+
+			comments = append(comments, payload["body"])
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer api.Close()
+
+	s := newWebhookServer("runiac", t.TempDir(), []string{"-e", "dev"})
+	s.client = api.Client()
+	s.providers[providerGitHub] = webhookProvider{Token: "ghs_token", APIURL: api.URL}
+
+	pr := pullRequestEvent{Provider: providerGitHub, Repository: "optum/runiac", CloneURL: "https://github.com/optum/runiac.git", Number: 1}
+
+	// the head was not planned yet
+	apply := pr
+	apply.Action, apply.User = serverActionApply, "octocat"
+	s.process(apply)
+
+	plan := pr
+	plan.Action, plan.BaseRef = serverActionPlan, "main"
+	s.process(plan)
+	s.process(apply)
+
+	require.Equal(t, []string{
+		"plan --output-dir .runiac/server/plans --track network -e dev",
+		"deploy --artifacts-from .runiac/server/plans --track network -e dev",
+	}, runs)
+
+	require.Len(t, comments, 3)
+	require.Contains(t, comments[0], "The head of the pull request was not planned")
+	require.Contains(t, comments[1], "#### runiac plan of 01234567 succeeded")
+	require.Contains(t, comments[1], "Tracks: network")
+	require.Contains(t, comments[1], "Comment `runiac apply` to apply this plan.")
+	require.Contains(t, comments[2], "#### runiac apply of 01234567 succeeded")
+
+	// the applied plans are planned again before the next apply
+	s.process(apply)
+	require.Len(t, runs, 2)
+	require.Contains(t, comments[3], "The head of the pull request was not planned")
+}
+
+func TestWebhookServer_ShouldOnlyApplyForPermittedUsers(t *testing.T) {
+	defer func(run func(string, []string, string, ...string) (string, string, error)) { runServerCommand = run }(runServerCommand)
+
+	runs := 0
+
+	runServerCommand = func(dir string, env []string, name string, args ...string) (string, string, error) {
+		if name != "git" {
+			runs++
+		}
+
+		return "", "", nil
+	}
+
+	var comments []string
+	reviews := `[]`
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/repos/optum/runiac/pulls/1":
+			_, _ = w.Write([]byte(`{"base": {"ref": "main"}, "head": {"repo": {"full_name": "optum/runiac"}}}`))
+		case r.URL.Path == "/repos/optum/runiac/pulls/2":
+			_, _ = w.Write([]byte(`{"base": {"ref": "main"}, "head": {"repo": {"full_name": "fork/runiac"}}}`))
+		case r.URL.Path == "/repos/optum/runiac/collaborators/writer/permission":
+			_, _ = w.Write([]byte(`{"permission": "write"}`))
+		case r.URL.Path == "/repos/optum/runiac/collaborators/reader/permission":
+			_, _ = w.Write([]byte(`{"permission": "read"}`))
+		case r.URL.Path == "/repos/optum/runiac/pulls/1/reviews":
+			_, _ = w.Write([]byte(reviews))
+		case r.URL.Path == "/projects/12/members/all/5":
+			_, _ = w.Write([]byte(`{"access_level": 20}`))
+		case r.Method == http.MethodPost:
+			payload := map[string]string{}
+			_ = json.NewDecoder(r.Body).Decode(&payload)
+
+			comments = append(comments, payload["body"])
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer api.Close()
+
+	s := newWebhookServer("runiac", t.TempDir(), nil)
+	s.client = api.Client()
+	s.providers[providerGitHub] = webhookProvider{APIURL: api.URL}
+	s.providers[providerGitLab] = webhookProvider{APIURL: api.URL}
+
+	apply := func(e pullRequestEvent) string {
+		e.Action = serverActionApply
+		s.process(e)
+
+		return comments[len(comments)-1]
+	}
+
+	github := pullRequestEvent{Provider: providerGitHub, Repository: "optum/runiac", Number: 1}
+
+	e := github
+	e.User, e.AuthorAssociation = "stranger", "NONE"
+	require.Contains(t, apply(e), "@stranger is not permitted to apply")
+
+	e.User, e.AuthorAssociation = "reader", "COLLABORATOR"
+	require.Contains(t, apply(e), "@reader is not permitted to apply")
+
+	e.User = "writer"
+	require.Contains(t, apply(e), "The head of the pull request was not planned", "a user that can push is permitted")
+
+	require.Contains(t, apply(pullRequestEvent{Provider: providerGitLab, Repository: "group/infra", ProjectID: 12, Number: 3, BaseRef: "main", User: "tanuki", UserID: 5}), "@tanuki is not permitted to apply", "a GitLab reporter can not push")
+	require.Contains(t, apply(pullRequestEvent{Provider: providerGitLab, Repository: "group/infra", ProjectID: 12, Number: 3, BaseRef: "main", User: "guest", UserID: 6}), "@guest is not permitted to apply", "a user that is not a member can not push")
+
+	e = github
+	e.Number, e.User = 2, "writer"
+	require.Contains(t, apply(e), "The pull requests of forks are not planned or applied")
+
+	s.applyUsers = []string{"reader"}
+	e = github
+	e.User = "writer"
+	require.Contains(t, apply(e), "@writer is not permitted to apply, the pull request is applied by reader")
+
+	e.User = "Reader"
+	require.Contains(t, apply(e), "The head of the pull request was not planned", "--apply-users replaces the permission check")
+
+	s.requireApproval = true
+	reviews = `[{"user": {"login": "a"}, "state": "APPROVED"}, {"user": {"login": "a"}, "state": "CHANGES_REQUESTED"}]`
+	require.Contains(t, apply(e), "The pull request is applied once approved", "the latest review of a reviewer counts")
+
+	reviews = `[{"user": {"login": "a"}, "state": "APPROVED"}, {"user": {"login": "a"}, "state": "COMMENTED"}]`
+	require.Contains(t, apply(e), "The head of the pull request was not planned")
+
+	require.Zero(t, runs, "nothing was planned or applied")
+}
@@ -0,0 +1,434 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	providerGitHub = "github"
+	providerGitLab = "gitlab"
+
+	gitlabDefaultAPIURL   = "https://gitlab.com/api/v4"
+	gitlabDeveloperAccess = 30 // The access level of a GitLab developer, the lowest that can push to a project
+)
+
+var errGitLabNotFound = errors.New("not found")
+
+// serverAction is what a pull request event asks the server to do
+type serverAction string
+
+const (
+	serverActionPlan  serverAction = "plan"  // Plan the affected tracks, on a new or updated pull request or a runiac plan comment
+	serverActionApply serverAction = "apply" // Apply the plans of the head of the pull request, on a runiac apply comment
+)
+
+// serverCommentCommands are the comments of a pull request the server acts on
+var serverCommentCommands = map[string]serverAction{
+	"runiac plan":  serverActionPlan,
+	"runiac apply": serverActionApply,
+}
+
+// webhookProvider holds the configuration of a git provider the server receives webhooks from, read from the
+// {PROVIDER}_WEBHOOK_SECRET, {PROVIDER}_TOKEN and {PROVIDER}_API_URL variables
+type webhookProvider struct {
+	Secret string // Verifies the webhooks were sent by the provider, webhooks of a provider without a secret are rejected
+	Token  string // Fetches the pull requests and posts the comments
+	APIURL string
+}
+
+// pullRequestEvent is a webhook of a GitHub pull request or a GitLab merge request the server acts on
+type pullRequestEvent struct {
+	Provider   string
+	Action     serverAction
+	Repository string // The owner/repo of GitHub or path with namespace of GitLab
+	ProjectID  int    // The id of the GitLab project
+	CloneURL   string
+	Number     int    // The number of the GitHub pull request or iid of the GitLab merge request
+	BaseRef    string // The branch the pull request merges into, empty when the webhook does not include it
+	Fork       bool   // Whether the head of the pull request is in a fork of the repository
+
+	// The commenter of a comment command, who must be permitted to apply
+	User              string // The login of GitHub or username of GitLab
+	UserID            int    // The id of the GitLab user
+	AuthorAssociation string // The association of the GitHub user with the repository, e.g. MEMBER or NONE
+}
+
+// getKey returns the key of the pull request of the event, unique across providers
+func (e pullRequestEvent) getKey() string {
+	return fmt.Sprintf("%s/%s/%d", e.Provider, e.Repository, e.Number)
+}
+
+// getHeadRef returns the ref of the head of the pull request, fetched from the repository
+func (e pullRequestEvent) getHeadRef() string {
+	if e.Provider == providerGitLab {
+		return fmt.Sprintf("refs/merge-requests/%d/head", e.Number)
+	}
+
+	return fmt.Sprintf("refs/pull/%d/head", e.Number)
+}
+
+// getCommentCommand returns the action of a comment, the comment must be a command on its first line
+func getCommentCommand(comment string) (serverAction, bool) {
+	line := strings.TrimSpace(strings.SplitN(strings.TrimSpace(comment), "\n", 2)[0])
+	action, ok := serverCommentCommands[strings.ToLower(line)]
+
+	return action, ok
+}
+
+// verifyGitHubWebhook ensures the X-Hub-Signature-256 of the webhook is the HMAC-SHA256 of its body with the secret
+func verifyGitHubWebhook(secret string, signature string, body []byte) error {
+	if secret == "" {
+		return fmt.Errorf("GITHUB_WEBHOOK_SECRET is not set, GitHub webhooks are rejected")
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return fmt.Errorf("invalid X-Hub-Signature-256 of the GitHub webhook")
+	}
+
+	return nil
+}
+
+// verifyGitLabWebhook ensures the X-Gitlab-Token of the webhook is the secret
+func verifyGitLabWebhook(secret string, token string) error {
+	if secret == "" {
+		return fmt.Errorf("GITLAB_WEBHOOK_SECRET is not set, GitLab webhooks are rejected")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(token)) != 1 {
+		return fmt.Errorf("invalid X-Gitlab-Token of the GitLab webhook")
+	}
+
+	return nil
+}
+
+// githubWebhook is the subset of the pull_request and issue_comment webhooks of GitHub the server acts on
+type githubWebhook struct {
+	Action      string     `json:"action"`
+	Number      int        `json:"number"`
+	PullRequest githubPull `json:"pull_request"`
+	Issue       struct {
+		Number      int              `json:"number"`
+		PullRequest *json.RawMessage `json:"pull_request"` // Set when the issue of the comment is a pull request
+	} `json:"issue"`
+	Comment struct {
+		Body string `json:"body"`
+		User struct {
+			Login string `json:"login"`
+		} `json:"user"`
+		AuthorAssociation string `json:"author_association"`
+	} `json:"comment"`
+	Repository struct {
+		FullName string `json:"full_name"`
+		CloneURL string `json:"clone_url"`
+	} `json:"repository"`
+}
+
+// githubPull is the subset of a GitHub pull request the server acts on
+type githubPull struct {
+	Base struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
+	Head struct {
+		Repo *struct {
+			FullName string `json:"full_name"`
+		} `json:"repo"` // Null when the fork of the pull request was deleted
+	} `json:"head"`
+}
+
+// isFork returns whether the head of the pull request is in a different repository than the repository
+func (p githubPull) isFork(repository string) bool {
+	return p.Head.Repo == nil || !strings.EqualFold(p.Head.Repo.FullName, repository)
+}
+
+// parseGitHubWebhook returns the pull request event of a GitHub webhook, false when the server does not act on it.
+// A pull request is planned when opened, reopened or pushed to, a comment acts when it is one of
+// serverCommentCommands.
+func parseGitHubWebhook(event string, body []byte) (pullRequestEvent, bool, error) {
+	hook := githubWebhook{}
+
+	if err := json.Unmarshal(body, &hook); err != nil {
+		return pullRequestEvent{}, false, fmt.Errorf("invalid GitHub %s webhook: %w", event, err)
+	}
+
+	e := pullRequestEvent{
+		Provider:   providerGitHub,
+		Repository: hook.Repository.FullName,
+		CloneURL:   hook.Repository.CloneURL,
+	}
+
+	switch event {
+	case "pull_request":
+		if hook.Action != "opened" && hook.Action != "reopened" && hook.Action != "synchronize" {
+			return e, false, nil
+		}
+
+		e.Action, e.Number, e.BaseRef = serverActionPlan, hook.Number, hook.PullRequest.Base.Ref
+		e.Fork = hook.PullRequest.isFork(hook.Repository.FullName)
+	case "issue_comment":
+		action, ok := getCommentCommand(hook.Comment.Body)
+		if hook.Action != "created" || hook.Issue.PullRequest == nil || !ok {
+			return e, false, nil
+		}
+
+		// the comment does not include the base and head of the pull request, they are fetched when processed
+		e.Action, e.Number = action, hook.Issue.Number
+		e.User, e.AuthorAssociation = hook.Comment.User.Login, hook.Comment.AuthorAssociation
+	default:
+		return e, false, nil
+	}
+
+	return e, true, nil
+}
+
+// gitlabWebhook is the subset of the merge request and note webhooks of GitLab the server acts on
+type gitlabWebhook struct {
+	ObjectAttributes struct {
+		Action       string `json:"action"`
+		IID          int    `json:"iid"`
+		TargetBranch string `json:"target_branch"`
+		OldRev       string `json:"oldrev"` // Set when an update pushed commits
+		NoteableType string `json:"noteable_type"`
+		Note         string `json:"note"`
+		gitlabMergeRequestProjects
+	} `json:"object_attributes"`
+	MergeRequest struct {
+		IID          int    `json:"iid"`
+		TargetBranch string `json:"target_branch"`
+		gitlabMergeRequestProjects
+	} `json:"merge_request"`
+	User struct {
+		ID       int    `json:"id"`
+		Username string `json:"username"`
+	} `json:"user"` // The author of the note
+	Project struct {
+		ID                int    `json:"id"`
+		PathWithNamespace string `json:"path_with_namespace"`
+		GitHTTPURL        string `json:"git_http_url"`
+	} `json:"project"`
+}
+
+// gitlabMergeRequestProjects are the projects of the source and target branches of a merge request
+type gitlabMergeRequestProjects struct {
+	SourceProjectID int `json:"source_project_id"`
+	TargetProjectID int `json:"target_project_id"`
+}
+
+// isFork returns whether the source branch of the merge request is in a fork of the project
+func (p gitlabMergeRequestProjects) isFork() bool {
+	return p.SourceProjectID != p.TargetProjectID
+}
+
+// parseGitLabWebhook returns the merge request event of a GitLab webhook, false when the server does not act on it.
+// A merge request is planned when opened, reopened or pushed to, a note acts when it is one of serverCommentCommands.
+func parseGitLabWebhook(event string, body []byte) (pullRequestEvent, bool, error) {
+	hook := gitlabWebhook{}
+
+	if err := json.Unmarshal(body, &hook); err != nil {
+		return pullRequestEvent{}, false, fmt.Errorf("invalid GitLab %s webhook: %w", event, err)
+	}
+
+	e := pullRequestEvent{
+		Provider:   providerGitLab,
+		Repository: hook.Project.PathWithNamespace,
+		ProjectID:  hook.Project.ID,
+		CloneURL:   hook.Project.GitHTTPURL,
+	}
+
+	attributes := hook.ObjectAttributes
+
+	switch event {
+	case "Merge Request Hook":
+		// an update without commits changes the title, labels or assignees of the merge request
+		if attributes.Action != "open" && attributes.Action != "reopen" && (attributes.Action != "update" || attributes.OldRev == "") {
+			return e, false, nil
+		}
+
+		e.Action, e.Number, e.BaseRef = serverActionPlan, attributes.IID, attributes.TargetBranch
+		e.Fork = attributes.isFork()
+	case "Note Hook":
+		action, ok := getCommentCommand(attributes.Note)
+		if attributes.NoteableType != "MergeRequest" || !ok {
+			return e, false, nil
+		}
+
+		e.Action, e.Number, e.BaseRef = action, hook.MergeRequest.IID, hook.MergeRequest.TargetBranch
+		e.Fork = hook.MergeRequest.isFork()
+		e.User, e.UserID = hook.User.Username, hook.User.ID
+	default:
+		return e, false, nil
+	}
+
+	return e, true, nil
+}
+
+// getGitHubPull returns the GitHub pull request of the event
+func getGitHubPull(client *http.Client, provider webhookProvider, e pullRequestEvent) (githubPull, error) {
+	pull := githubPull{}
+
+	pr := githubPullRequest{APIURL: provider.APIURL, Repository: e.Repository, Number: e.Number, Token: provider.Token}
+
+	err := sendGitHubRequest(client, pr, http.MethodGet, fmt.Sprintf("%s/repos/%s/pulls/%d", pr.APIURL, pr.Repository, pr.Number), nil, &pull)
+
+	return pull, err
+}
+
+// githubWriteAssociations are the associations of a GitHub user with a repository that may grant write access, the
+// permission of the user is fetched to confirm it
+var githubWriteAssociations = map[string]bool{
+	"OWNER":        true,
+	"MEMBER":       true,
+	"COLLABORATOR": true,
+}
+
+// hasWritePermission returns whether the commenter of the event can push to the repository: a GitHub user with the
+// write, maintain or admin permission or a GitLab member that is at least a developer of the project
+func hasWritePermission(client *http.Client, provider webhookProvider, e pullRequestEvent) (bool, error) {
+	if e.Provider == providerGitLab {
+		member := struct {
+			AccessLevel int `json:"access_level"`
+		}{}
+
+		err := sendGitLabRequest(client, provider, http.MethodGet, fmt.Sprintf("%s/projects/%d/members/all/%d", provider.APIURL, e.ProjectID, e.UserID), nil, &member)
+		if errors.Is(err, errGitLabNotFound) {
+			return false, nil
+		}
+
+		return member.AccessLevel >= gitlabDeveloperAccess, err
+	}
+
+	if e.AuthorAssociation != "" && !githubWriteAssociations[e.AuthorAssociation] {
+		return false, nil
+	}
+
+	permission := struct {
+		Permission string `json:"permission"`
+	}{}
+
+	pr := githubPullRequest{APIURL: provider.APIURL, Repository: e.Repository, Number: e.Number, Token: provider.Token}
+
+	err := sendGitHubRequest(client, pr, http.MethodGet, fmt.Sprintf("%s/repos/%s/collaborators/%s/permission", pr.APIURL, pr.Repository, e.User), nil, &permission)
+	if err != nil {
+		return false, err
+	}
+
+	switch permission.Permission {
+	case "admin", "maintain", "write":
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// isApproved returns whether the pull request of the event was approved: the latest review of a GitHub reviewer
+// approved it or a GitLab approver approved the merge request and its approval rules are met
+func isApproved(client *http.Client, provider webhookProvider, e pullRequestEvent) (bool, error) {
+	if e.Provider == providerGitLab {
+		approvals := struct {
+			Approved   bool              `json:"approved"`
+			ApprovedBy []json.RawMessage `json:"approved_by"`
+		}{}
+
+		err := sendGitLabRequest(client, provider, http.MethodGet, fmt.Sprintf("%s/projects/%d/merge_requests/%d/approvals", provider.APIURL, e.ProjectID, e.Number), nil, &approvals)
+
+		return approvals.Approved && len(approvals.ApprovedBy) > 0, err
+	}
+
+	reviews := []struct {
+		User struct {
+			Login string `json:"login"`
+		} `json:"user"`
+		State string `json:"state"`
+	}{}
+
+	pr := githubPullRequest{APIURL: provider.APIURL, Repository: e.Repository, Number: e.Number, Token: provider.Token}
+
+	err := sendGitHubRequest(client, pr, http.MethodGet, fmt.Sprintf("%s/repos/%s/pulls/%d/reviews?per_page=100", pr.APIURL, pr.Repository, pr.Number), nil, &reviews)
+	if err != nil {
+		return false, err
+	}
+
+	// the reviews are listed oldest first, a comment does not change the review of the reviewer
+	latest := map[string]string{}
+	for _, review := range reviews {
+		if review.State != "COMMENTED" {
+			latest[review.User.Login] = review.State
+		}
+	}
+
+	for _, state := range latest {
+		if state == "APPROVED" {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// postPullRequestComment comments the body on the GitHub pull request or GitLab merge request of the event
+func postPullRequestComment(client *http.Client, provider webhookProvider, e pullRequestEvent, body string) error {
+	if e.Provider == providerGitHub {
+		pr := githubPullRequest{APIURL: provider.APIURL, Repository: e.Repository, Number: e.Number, Token: provider.Token}
+
+		return sendGitHubRequest(client, pr, http.MethodPost, fmt.Sprintf("%s/repos/%s/issues/%d/comments", pr.APIURL, pr.Repository, pr.Number), map[string]string{"body": body}, nil)
+	}
+
+	return sendGitLabRequest(client, provider, http.MethodPost, fmt.Sprintf("%s/projects/%d/merge_requests/%d/notes", provider.APIURL, e.ProjectID, e.Number), map[string]string{"body": body}, nil)
+}
+
+// sendGitLabRequest sends the payload to the GitLab API and decodes its response into out, when set. A missing
+// resource returns errGitLabNotFound.
+func sendGitLabRequest(client *http.Client, provider webhookProvider, method string, url string, payload interface{}, out interface{}) error {
+	var body io.Reader
+
+	if payload != nil {
+		b, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("PRIVATE-TOKEN", provider.Token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%s %s: %w", method, url, errGitLabNotFound)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s %s returned %s", method, url, resp.Status)
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,241 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// settingSource identifies where the value of a setting was resolved from
+type settingSource string
+
+const (
+	sourceFlag       settingSource = "flag"
+	sourceEnv        settingSource = "env"
+	sourceConfigSet  settingSource = "config-set"
+	sourceProfile    settingSource = "profile"
+	sourceOverlay    settingSource = "environment"
+	sourceConfigFile settingSource = "config file"
+	sourceDefault    settingSource = "default"
+)
+
+// fileConfig holds only the values read from the runiac config file, unlike the global viper instance
+// which also reflects values set at runtime
+var fileConfig = viper.New()
+
+// settingConfigKeys maps flags to their config key when it is not the flag name with '-' replaced by '_'
+var settingConfigKeys = map[string]string{
+	"primary-regions": "primary_region",
+	"steps":           "step_whitelist",
+	"runner-arg":      "runner_args",
+}
+
+// legacyEnvSettings could be set by their unprefixed environment variable before settings were resolved
+// consistently, e.g. CONTAINER_ENGINE, these are still honored after RUNIAC_{SETTING}
+var legacyEnvSettings = map[string]bool{
+	"container":        true,
+	"container_engine": true,
+	"dockerfile":       true,
+}
+
+// unresolvedSettings are flags that only control the CLI invocation itself and are never read from configuration.
+// The confirmations of the protected rings, freeze windows and plan thresholds are among them, a committed value
+// would permanently confirm every run.
+var unresolvedSettings = map[string]bool{
+	"help":            true,
+	"test":            true,
+	"why":             true,
+	"profile":         true,
+	"confirm-destroy": true,
+	"break-glass":     true,
+	"confirm":         true,
+}
+
+// settingLayer is one source a setting can be resolved from
+type settingLayer struct {
+	Source settingSource
+	Name   string // The name of the setting in the source, e.g. --container, RUNIAC_CONTAINER, profiles.ci.container or environments.prod.container
+	Value  string
+	Set    bool
+}
+
+// getSettingConfigKey returns the config key of the flag
+func getSettingConfigKey(flag string) string {
+	if key, ok := settingConfigKeys[flag]; ok {
+		return key
+	}
+
+	return strings.ReplaceAll(flag, "-", "_")
+}
+
+// getSettingEnvName returns the environment variable name of the flag, e.g. RUNIAC_CONTAINER_ENGINE
+func getSettingEnvName(flag string) string {
+	return "RUNIAC_" + strings.ToUpper(getSettingConfigKey(flag))
+}
+
+// resolveProfile returns the configuration profile selected with --profile, RUNIAC_PROFILE or profile in the config file
+func resolveProfile(flags *pflag.FlagSet) string {
+	if f := flags.Lookup("profile"); f != nil && f.Changed {
+		return f.Value.String()
+	}
+
+	if profile, ok := os.LookupEnv("RUNIAC_PROFILE"); ok {
+		return profile
+	}
+
+	return fileConfig.GetString("profile")
+}
+
+// resolveSetting returns every layer of the setting in order of precedence, flag > env > config-set > profile >
+// environment > config file > default, along with the index of the layer the setting resolves to
+func resolveSetting(flags *pflag.FlagSet, flag string, profile string, environment string) ([]settingLayer, int, error) {
+	f := flags.Lookup(strings.ReplaceAll(flag, "_", "-"))
+	if f == nil {
+		return nil, 0, fmt.Errorf("unknown setting '%s'", flag)
+	}
+
+	key := getSettingConfigKey(f.Name)
+	envName := getSettingEnvName(f.Name)
+	env, envSet := os.LookupEnv(envName)
+
+	if !envSet && legacyEnvSettings[key] {
+		envName = strings.ToUpper(key)
+		env, envSet = os.LookupEnv(envName)
+	}
+
+	fileValue := fileConfig.Get(key)
+	runtimeValue := viper.Get(key)
+	runtimeSet := viper.IsSet(key) && getSettingValueString(runtimeValue) != getSettingValueString(fileValue)
+
+	// the global config reads unprefixed environment variables, those are not settings set at runtime
+	if v, ok := os.LookupEnv(strings.ToUpper(key)); ok && v == getSettingValueString(runtimeValue) {
+		runtimeSet = false
+	}
+
+	profileKey := fmt.Sprintf("profiles.%s.%s", profile, key)
+	environmentKey := getEnvironmentConfigKey(environment, key)
+
+	layers := []settingLayer{
+		{Source: sourceFlag, Name: "--" + f.Name, Value: getFlagValueString(f), Set: f.Changed},
+		{Source: sourceEnv, Name: envName, Value: env, Set: envSet},
+		{Source: sourceConfigSet, Name: key, Value: getSettingValueString(runtimeValue), Set: runtimeSet},
+		{Source: sourceProfile, Name: profileKey, Value: getSettingValueString(fileConfig.Get(profileKey)), Set: profile != "" && fileConfig.IsSet(profileKey)},
+		{Source: sourceOverlay, Name: environmentKey, Value: getSettingValueString(fileConfig.Get(environmentKey)), Set: environment != "" && fileConfig.IsSet(environmentKey)},
+		{Source: sourceConfigFile, Name: key, Value: getSettingValueString(fileValue), Set: fileConfig.IsSet(key)},
+		{Source: sourceDefault, Name: "--" + f.Name, Value: f.DefValue, Set: true},
+	}
+
+	for i, l := range layers {
+		if l.Set {
+			return layers, i, nil
+		}
+	}
+
+	return layers, len(layers) - 1, nil
+}
+
+// isSettingExplicit returns true when the setting resolves to a value other than its default
+func isSettingExplicit(flags *pflag.FlagSet, flag string) bool {
+	profile := resolveProfile(flags)
+	layers, i, err := resolveSetting(flags, flag, profile, resolveEnvironment(flags, profile))
+
+	return err == nil && layers[i].Source != sourceDefault
+}
+
+// applySettings resolves every flag of the command that was not set on the command line from the environment
+// and configuration
+func applySettings(flags *pflag.FlagSet) error {
+	profile := resolveProfile(flags)
+	environment := resolveEnvironment(flags, profile)
+
+	var err error
+
+	flags.VisitAll(func(f *pflag.Flag) {
+		if err != nil || f.Changed || unresolvedSettings[f.Name] {
+			return
+		}
+
+		layers, i, resolveErr := resolveSetting(flags, f.Name, profile, environment)
+		if resolveErr != nil {
+			err = resolveErr
+			return
+		}
+
+		if layers[i].Source == sourceDefault {
+			return
+		}
+
+		if setErr := f.Value.Set(layers[i].Value); setErr != nil {
+			err = fmt.Errorf("invalid value '%s' for %s from %s %s: %w", layers[i].Value, f.Name, layers[i].Source, layers[i].Name, setErr)
+		}
+	})
+
+	return err
+}
+
+// printSettingResolution prints the resolution chain of a single setting, marking the layer that wins
+func printSettingResolution(w io.Writer, flags *pflag.FlagSet, setting string) error {
+	profile := resolveProfile(flags)
+	layers, winner, err := resolveSetting(flags, setting, profile, resolveEnvironment(flags, profile))
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "%s resolves to '%s' from %s\n\n", setting, maskSettingValue(layers[winner]), layers[winner].Source)
+
+	for i, l := range layers {
+		marker := " "
+		if i == winner {
+			marker = "*"
+		}
+
+		value := "(not set)"
+		if l.Set {
+			value = fmt.Sprintf("'%s'", maskSettingValue(l))
+		}
+
+		fmt.Fprintf(w, "%s %d. %-12s %-40s %s\n", marker, i+1, l.Source, l.Name, value)
+	}
+
+	return nil
+}
+
+func maskSettingValue(l settingLayer) string {
+	if isSensitiveEnvKey(l.Name) {
+		return maskedValue
+	}
+
+	return l.Value
+}
+
+// getFlagValueString returns the flag's value in the same comma separated format accepted by Set
+func getFlagValueString(f *pflag.Flag) string {
+	if s, ok := f.Value.(pflag.SliceValue); ok {
+		return strings.Join(s.GetSlice(), ",")
+	}
+
+	return f.Value.String()
+}
+
+// getSettingValueString formats a configuration value, joining lists with commas
+func getSettingValueString(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case []interface{}:
+		parts := make([]string, 0, len(t))
+		for _, p := range t {
+			parts = append(parts, fmt.Sprintf("%v", p))
+		}
+
+		return strings.Join(parts, ",")
+	case []string:
+		return strings.Join(t, ",")
+	default:
+		return fmt.Sprintf("%v", t)
+	}
+}
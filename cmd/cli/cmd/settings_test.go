@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func newStubSettingsFlags() (*pflag.FlagSet, *string, *[]string) {
+	var engine string
+	var regions []string
+
+	flags := pflag.NewFlagSet("stub", pflag.ContinueOnError)
+	flags.StringVar(&engine, "stub-engine", "docker", "")
+	flags.StringArrayVar(&regions, "primary-regions", []string{}, "")
+	flags.String("profile", "", "")
+
+	return flags, &engine, &regions
+}
+
+func TestResolveSetting_ShouldFollowPrecedence(t *testing.T) {
+	defer func() { fileConfig = viper.New() }()
+	defer viper.Set("stub_engine", nil)
+
+	flags, engine, _ := newStubSettingsFlags()
+
+	tests := []struct {
+		setup    func()
+		expected settingSource
+		value    string
+	}{
+		{func() {}, sourceDefault, "docker"},
+		{func() { fileConfig.Set("stub_engine", "file") }, sourceConfigFile, "file"},
+		{func() { fileConfig.Set("environments.prod.stub_engine", "overlay") }, sourceOverlay, "overlay"},
+		{func() { fileConfig.Set("profiles.ci.stub_engine", "profile") }, sourceProfile, "profile"},
+		{func() { viper.Set("stub_engine", "runtime") }, sourceConfigSet, "runtime"},
+		{func() { _ = os.Setenv("RUNIAC_STUB_ENGINE", "env") }, sourceEnv, "env"},
+		{func() { _ = flags.Set("stub-engine", "flag") }, sourceFlag, "flag"},
+	}
+	defer os.Unsetenv("RUNIAC_STUB_ENGINE")
+
+	for _, tt := range tests {
+		tt.setup()
+
+		layers, i, err := resolveSetting(flags, "stub-engine", "ci", "Prod")
+		require.NoError(t, err)
+		require.Equal(t, tt.expected, layers[i].Source)
+		require.Equal(t, tt.value, layers[i].Value)
+	}
+
+	require.Equal(t, "flag", *engine)
+
+	_, _, err := resolveSetting(flags, "missing", "", "")
+	require.Error(t, err)
+}
+
+func TestApplySettings_ShouldSetUnchangedFlagsFromConfiguration(t *testing.T) {
+	defer func() { fileConfig = viper.New() }()
+
+	flags, engine, regions := newStubSettingsFlags()
+
+	fileConfig.Set("stub_engine", "podman")
+	fileConfig.Set("primary_region", "centralus")
+
+	require.NoError(t, applySettings(flags))
+	require.Equal(t, "podman", *engine)
+	require.Equal(t, []string{"centralus"}, *regions)
+	require.False(t, flags.Changed("stub-engine"), "configuration must not be reported as set on the command line")
+}
+
+func TestPrintSettingResolution_ShouldMarkWinningLayer(t *testing.T) {
+	defer func() { fileConfig = viper.New() }()
+
+	flags, _, _ := newStubSettingsFlags()
+	fileConfig.Set("stub_engine", "podman")
+
+	var b bytes.Buffer
+	require.NoError(t, printSettingResolution(&b, flags, "stub_engine"))
+
+	out := b.String()
+	require.Contains(t, out, "stub_engine resolves to 'podman' from config file")
+	require.Contains(t, out, "* 6. config file")
+	require.Contains(t, out, "(not set)")
+}
+
+func TestApplySettings_ShouldOnlyConfirmFromCommandLine(t *testing.T) {
+	defer func() { fileConfig = viper.New() }()
+
+	var confirmDestroy, breakGlass string
+	var confirm bool
+
+	flags := pflag.NewFlagSet("stub", pflag.ContinueOnError)
+	flags.StringVar(&confirmDestroy, "confirm-destroy", "", "")
+	flags.StringVar(&breakGlass, "break-glass", "", "")
+	flags.BoolVar(&confirm, "confirm", false, "")
+
+	fileConfig.Set("confirm_destroy", "prod")
+	fileConfig.Set("break_glass", "committed reason")
+
+	_ = os.Setenv("RUNIAC_CONFIRM", "true")
+	defer os.Unsetenv("RUNIAC_CONFIRM")
+
+	require.NoError(t, applySettings(flags))
+	require.Equal(t, "", confirmDestroy, "a committed confirmation must not confirm destroying a protected ring")
+	require.Equal(t, "", breakGlass, "a committed reason must not override a freeze window")
+	require.False(t, confirm)
+
+	require.NoError(t, flags.Parse([]string{"--confirm-destroy", "prod"}))
+	require.NoError(t, applySettings(flags))
+	require.Equal(t, "prod", confirmDestroy)
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const cosignBinary = "cosign"
+
+// getCosignVerifyArguments returns the cosign arguments verifying the image was signed with the key or, for keyless
+// signatures, by the certificate identity and issuer
+func getCosignVerifyArguments(image string, key string, identity string, issuer string) ([]string, error) {
+	if image == "" {
+		return nil, errors.New("no --container base image to verify the signature of")
+	}
+
+	args := []string{"verify"}
+
+	switch {
+	case key != "":
+		args = append(args, "--key", key)
+	case identity != "" && issuer != "":
+		args = append(args, "--certificate-identity", identity, "--certificate-oidc-issuer", issuer)
+	default:
+		return nil, errors.New("--verify-signature requires --signature-key, or --signature-identity and --signature-issuer for keyless signatures")
+	}
+
+	return append(args, image), nil
+}
+
+// verifyImageSignature runs cosign verify against the image, failing when the image is not signed as expected
+func verifyImageSignature(image string, key string, identity string, issuer string) error {
+	args, err := getCosignVerifyArguments(image, key, identity, issuer)
+	if err != nil {
+		return err
+	}
+
+	if _, err := lookPath(cosignBinary); err != nil {
+		return fmt.Errorf("--verify-signature requires '%s' on the path", cosignBinary)
+	}
+
+	cmd := exec.Command(cosignBinary, args...)
+	cmd.Env = os.Environ()
+
+	logrus.Info(strings.Join(cmd.Args, " "))
+
+	b, err := cmd.CombinedOutput()
+	if err != nil {
+		logrus.Error(string(b))
+		return fmt.Errorf("signature verification of %s failed: %w", image, err)
+	}
+
+	logrus.Infof("Verified the signature of %s", image)
+
+	return nil
+}
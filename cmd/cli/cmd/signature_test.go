@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetCosignVerifyArguments_ShouldVerifyWithKeyOrKeylessIdentity(t *testing.T) {
+	image := "docker.io/runiac/deploy:latest-alpine-full"
+
+	args, err := getCosignVerifyArguments(image, "cosign.pub", "", "")
+	require.NoError(t, err)
+	require.Equal(t, []string{"verify", "--key", "cosign.pub", image}, args)
+
+	args, err = getCosignVerifyArguments(image, "", "release@example.com", "https://token.actions.githubusercontent.com")
+	require.NoError(t, err)
+	require.Equal(t, []string{"verify", "--certificate-identity", "release@example.com", "--certificate-oidc-issuer", "https://token.actions.githubusercontent.com", image}, args)
+
+	_, err = getCosignVerifyArguments(image, "", "release@example.com", "")
+	require.Error(t, err, "keyless verification requires both an identity and issuer")
+
+	_, err = getCosignVerifyArguments("", "cosign.pub", "", "")
+	require.Error(t, err)
+}
@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+// getChangedFiles returns the paths, relative to the project directory, of the files of the project directory that
+// changed since the git ref, including uncommitted and untracked files. git runs in the project directory, which may
+// be a subdirectory of the repository, whatever the working directory. A variable so tests do not need git.
+var getChangedFiles = func(dir string, ref string) ([]string, error) {
+	changed := []string{}
+
+	for _, args := range [][]string{
+		{"rev-parse", "--is-inside-work-tree"},
+		{"diff", "--name-only", "--relative", ref, "--"},
+		{"ls-files", "--others", "--exclude-standard"},
+	} {
+		out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).Output()
+		if err != nil {
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) && args[0] == "rev-parse" {
+				return nil, fmt.Errorf("--since requires the project directory %s to be in a git work tree: %s", dir, strings.TrimSpace(string(exitErr.Stderr)))
+			}
+
+			if errors.As(err, &exitErr) {
+				return nil, fmt.Errorf("git %s failed: %s", strings.Join(args, " "), strings.TrimSpace(string(exitErr.Stderr)))
+			}
+
+			return nil, fmt.Errorf("--since requires git: %w", err)
+		}
+
+		// the work tree check only succeeds or fails
+		if args[0] == "rev-parse" {
+			continue
+		}
+
+		for _, path := range strings.Split(string(out), "\n") {
+			if path = strings.TrimSpace(path); path != "" {
+				changed = append(changed, path)
+			}
+		}
+	}
+
+	return changed, nil
+}
+
+// getSinceStepWhitelist restricts the whitelist to the steps with files changed since the git ref and the steps that
+// depend on them, directly or through other steps. The project of fs is in dir. Every step of the whitelist runs when
+// a file outside of the tracks and step directories changes, e.g. the runiac config or the Dockerfile. False when no
+// targeted step changed.
+func getSinceStepWhitelist(fs afero.Fs, dir string, ref string, whitelist []string) ([]string, bool, error) {
+	graph, err := getProjectGraph(fs)
+	if err != nil {
+		return nil, false, err
+	}
+
+	changed, err := getChangedFiles(dir, ref)
+	if err != nil {
+		return nil, false, err
+	}
+
+	stepIDs, all := getChangedStepIDs(graph, changed)
+	if all {
+		return whitelist, true, nil
+	}
+
+	stepIDs = getDependentStepIDs(graph, stepIDs)
+
+	if len(whitelist) > 0 {
+		targeted := []string{}
+		whitelist = getNormalizedStepIDs(whitelist)
+
+		for _, id := range stepIDs {
+			if isStringInSlice(id, whitelist) {
+				targeted = append(targeted, id)
+			}
+		}
+
+		stepIDs = targeted
+	}
+
+	return stepIDs, len(stepIDs) > 0, nil
+}
+
+// getChangedStepIDs returns the ids of the steps of the graph containing the changed paths. A file of a track's
+// directory outside of its steps, e.g. its track.tfvars or shared modules, changes every step of the track. True when a
+// path outside of the tracks and step directories changed, which changes every step. Paths of removed steps are
+// ignored.
+func getChangedStepIDs(graph projectGraph, changed []string) ([]string, bool) {
+	steps := map[string]map[string]bool{}
+	for _, t := range graph.Tracks {
+		steps[t.Name] = map[string]bool{}
+
+		for _, s := range t.Steps {
+			steps[t.Name][s.Name] = true
+		}
+	}
+
+	changedIDs := map[string]bool{}
+
+	addStep := func(track string, dir string) {
+		if !strings.HasPrefix(dir, stepDirPrefix) || len(dir) <= len(stepDirPrefix)+2 {
+			return
+		}
+
+		if name := dir[len(stepDirPrefix)+2:]; steps[track][name] {
+			changedIDs[fmt.Sprintf("%s/%s", track, name)] = true
+		}
+	}
+
+	for _, path := range changed {
+		parts := strings.Split(path, "/")
+
+		switch {
+		case len(parts) > 3 && parts[0] == tracksDir && strings.HasPrefix(parts[2], stepDirPrefix):
+			addStep(parts[1], parts[2])
+		case len(parts) > 2 && parts[0] == tracksDir:
+			for _, t := range graph.Tracks {
+				if t.Name != parts[1] {
+					continue
+				}
+
+				for _, s := range t.Steps {
+					changedIDs[s.ID] = true
+				}
+			}
+		case len(parts) > 1 && strings.HasPrefix(parts[0], stepDirPrefix):
+			addStep(defaultTrackName, parts[0])
+		default:
+			return nil, true
+		}
+	}
+
+	return getSortedSetKeys(changedIDs), false
+}
+
+// getDependentStepIDs returns the steps and every step that depends on them, directly or through other steps,
+// following the execution order dependencies and inputs of the graph
+func getDependentStepIDs(graph projectGraph, stepIDs []string) []string {
+	dependents := map[string][]string{}
+	for _, e := range graph.getEdges() {
+		dependents[e.From] = append(dependents[e.From], e.To)
+	}
+
+	visited := map[string]bool{}
+
+	var visit func(id string)
+	visit = func(id string) {
+		if visited[id] {
+			return
+		}
+
+		visited[id] = true
+
+		for _, d := range dependents[id] {
+			visit(d)
+		}
+	}
+
+	for _, id := range stepIDs {
+		visit(id)
+	}
+
+	return getSortedSetKeys(visited)
+}
+
+// getSortedSetKeys returns the keys of the set in order
+func getSortedSetKeys(set map[string]bool) []string {
+	keys := []string{}
+	for k := range set {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func getTestSinceFs() afero.Fs {
+	fs := afero.NewMemMapFs()
+	_ = fs.MkdirAll("step1_default", 0755)
+	_ = fs.MkdirAll("tracks/network/step1_vnet", 0755)
+	_ = fs.MkdirAll("tracks/network/step1_firewall", 0755)
+	_ = fs.MkdirAll("tracks/network/step2_peering", 0755)
+	_ = fs.MkdirAll("tracks/network/step2_dns", 0755)
+	_ = fs.MkdirAll("tracks/app/step1_web", 0755)
+	_ = afero.WriteFile(fs, "tracks/network/step2_dns/runiac.yml", []byte("depends_on: [firewall]\n"), 0644)
+
+	return fs
+}
+
+func TestGetSinceStepWhitelist_ShouldRunTheChangedStepsAndTheirDependents(t *testing.T) {
+	defer func(get func(string, string) ([]string, error)) { getChangedFiles = get }(getChangedFiles)
+
+	fs := getTestSinceFs()
+	changed := []string{}
+	getChangedFiles = func(dir string, ref string) ([]string, error) {
+		require.Equal(t, "project", dir)
+		require.Equal(t, "origin/main", ref)
+		return changed, nil
+	}
+
+	changed = []string{"tracks/network/step1_vnet/main.tf"}
+	whitelist, ok, err := getSinceStepWhitelist(fs, "project", "origin/main", nil)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, []string{"network/peering", "network/vnet"}, whitelist, "dns only depends on the firewall")
+
+	changed = []string{"tracks/network/step1_firewall/main.tf", "step1_default/main.tf"}
+	whitelist, _, _ = getSinceStepWhitelist(fs, "project", "origin/main", nil)
+	require.Equal(t, []string{"default/default", "network/dns", "network/firewall", "network/peering"}, whitelist)
+
+	// a file of the track outside of its steps changes every step of the track
+	changed = []string{"tracks/app/track.tfvars"}
+	whitelist, _, _ = getSinceStepWhitelist(fs, "project", "origin/main", nil)
+	require.Equal(t, []string{"app/web"}, whitelist)
+
+	// so does a file of a nested directory of the track outside of its steps, e.g. its shared modules
+	changed = []string{"tracks/app/modules/web/main.tf"}
+	whitelist, _, _ = getSinceStepWhitelist(fs, "project", "origin/main", nil)
+	require.Equal(t, []string{"app/web"}, whitelist)
+
+	// the whitelist restricts the changed steps
+	changed = []string{"tracks/network/step1_vnet/main.tf"}
+	whitelist, ok, _ = getSinceStepWhitelist(fs, "project", "origin/main", []string{"network/peering", "app/web"})
+	require.True(t, ok)
+	require.Equal(t, []string{"network/peering"}, whitelist)
+
+	_, ok, _ = getSinceStepWhitelist(fs, "project", "origin/main", []string{"app/web"})
+	require.False(t, ok, "no targeted step changed")
+
+	changed = []string{"step1_default/main.tf"}
+	whitelist, ok, _ = getSinceStepWhitelist(fs, "project", "origin/main", []string{"default"})
+	require.True(t, ok, "a step of the default track may be selected by its name")
+	require.Equal(t, []string{"default/default"}, whitelist)
+
+	// a shared change runs the whitelist
+	changed = []string{"tracks/network/step1_vnet/main.tf", "Dockerfile"}
+	whitelist, ok, _ = getSinceStepWhitelist(fs, "project", "origin/main", []string{"app/web"})
+	require.True(t, ok)
+	require.Equal(t, []string{"app/web"}, whitelist)
+
+	// a removed step has nothing to run
+	changed = []string{"tracks/network/step3_removed/main.tf"}
+	_, ok, _ = getSinceStepWhitelist(fs, "project", "origin/main", nil)
+	require.False(t, ok)
+}
+
+func TestGetChangedFiles_ShouldReadTheChangesOfTheProjectDirectory(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	repo, err := ioutil.TempDir("", "runiac-since")
+	require.NoError(t, err)
+	defer os.RemoveAll(repo)
+
+	project := filepath.Join(repo, "infra")
+	require.NoError(t, os.MkdirAll(filepath.Join(project, "step1_default"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(project, "step1_default", "main.tf"), []byte(""), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(repo, "README.md"), []byte(""), 0644))
+
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "-A"},
+		{"-c", "user.name=runiac", "-c", "user.email=runiac@example.com", "commit", "-q", "-m", "init"},
+	} {
+		require.NoError(t, exec.Command("git", append([]string{"-C", repo}, args...)...).Run())
+	}
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(project, "step1_default", "main.tf"), []byte("# changed"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(project, "step1_default", "outputs.tf"), []byte(""), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(repo, "README.md"), []byte("# changed"), 0644))
+
+	changed, err := getChangedFiles(project, "HEAD")
+	require.NoError(t, err)
+	require.Equal(t, []string{"step1_default/main.tf", "step1_default/outputs.tf"}, changed, "the paths should be relative to the project, outside of it are ignored")
+
+	notRepo, err := ioutil.TempDir("", "runiac-since")
+	require.NoError(t, err)
+	defer os.RemoveAll(notRepo)
+
+	_, err = getChangedFiles(notRepo, "HEAD")
+	require.Error(t, err, "a project outside of a git work tree should fail")
+}
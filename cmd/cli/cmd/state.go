@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+const (
+	localStateDir       = ".runiac/tfstate" // The local state directory mounted to /runiac/tfstate, shared by runs without a deployment ring
+	ringStateMarker     = ".ring"           // Marks a subdirectory of localStateDir as the state directory of a deployment ring
+	stateMigratedMarker = ".migrated"       // Records the ring the shared state of localStateDir was migrated to
+	containerTFState    = "/runiac/tfstate"
+)
+
+var validRingStateName = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// getRingStateDir returns the local state directory for the ring, relative to the project. Runs without a
+// deployment ring share localStateDir while each ring is isolated in localStateDir/{ring}.
+func getRingStateDir(ring string) (string, error) {
+	if ring == "" {
+		return localStateDir, nil
+	}
+
+	if !validRingStateName.MatchString(ring) || ring == "." || ring == ".." {
+		return "", fmt.Errorf("deployment ring '%s' can not be used as a local state directory name", ring)
+	}
+
+	return filepath.Join(localStateDir, strings.ToLower(ring)), nil
+}
+
+// isRingStateDir returns true when dir was created for a deployment ring's local state
+func isRingStateDir(fs afero.Fs, dir string) bool {
+	exists, _ := afero.Exists(fs, filepath.Join(dir, ringStateMarker))
+
+	return exists
+}
+
+// prepareRingStateDir creates the ring's local state directory. Local state created before state was isolated
+// by ring lives directly in localStateDir and belongs to one of the rings, it is copied into the directory of the
+// migrateTo ring the first time that ring is used so its deployments are not orphaned. The other rings start with
+// empty state, a new ring fails until the shared state was migrated so it is not deployed again from empty state by
+// the ring it belongs to.
+func prepareRingStateDir(fs afero.Fs, ring string, migrateTo string) (string, error) {
+	dir, err := getRingStateDir(ring)
+	if err != nil || ring == "" {
+		return dir, err
+	}
+
+	exists, err := afero.DirExists(fs, dir)
+	if err != nil {
+		return "", err
+	}
+
+	if exists {
+		if !isRingStateDir(fs, dir) {
+			return "", fmt.Errorf("%s exists but is not the local state directory of deployment ring '%s', move it before deploying ring '%s'", dir, ring, ring)
+		}
+
+		return dir, nil
+	}
+
+	flat, err := getFlatStateEntries(fs)
+	if err != nil {
+		return "", err
+	}
+
+	migrated, _ := afero.Exists(fs, filepath.Join(localStateDir, stateMigratedMarker))
+	migrate := len(flat) > 0 && strings.EqualFold(migrateTo, ring)
+
+	if len(flat) > 0 && !migrated && migrateTo == "" {
+		return "", fmt.Errorf("the shared local state in %s (%s) was created before local state was isolated by deployment ring, "+
+			"deploy the ring it belongs to once with --migrate-state-to {ring} to copy it into that ring's state, the other rings start with empty state", localStateDir, strings.Join(flat, ", "))
+	}
+
+	if migrate && migrated && !strings.EqualFold(readStateMigratedRing(fs), ring) {
+		return "", fmt.Errorf("the shared local state in %s was already migrated to deployment ring '%s'", localStateDir, readStateMigratedRing(fs))
+	}
+
+	err = fs.MkdirAll(dir, 0755)
+	if err != nil {
+		return "", err
+	}
+
+	err = afero.WriteFile(fs, filepath.Join(dir, ringStateMarker), []byte(ring), 0644)
+	if err != nil {
+		return "", err
+	}
+
+	if !migrate {
+		return dir, nil
+	}
+
+	for _, name := range flat {
+		err = copyPath(fs, filepath.Join(localStateDir, name), filepath.Join(dir, name))
+		if err != nil {
+			return "", err
+		}
+	}
+
+	err = afero.WriteFile(fs, filepath.Join(localStateDir, stateMigratedMarker), []byte(ring), 0644)
+	if err != nil {
+		return "", err
+	}
+
+	logrus.Warnf("Local state is now isolated per deployment ring. Copied the existing shared state in %s (%s) to %s for ring '%s', the other rings start with empty state. "+
+		"Verify the ring's state and remove the shared copies from %s unless they are the state of runs without a deployment ring.",
+		localStateDir, strings.Join(flat, ", "), dir, ring, localStateDir)
+
+	return dir, nil
+}
+
+// getFlatStateEntries returns the entries of localStateDir that are not ring state directories, the state of runs
+// without a deployment ring and of the rings before state was isolated
+func getFlatStateEntries(fs afero.Fs) ([]string, error) {
+	items, err := afero.ReadDir(fs, localStateDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []string{}
+
+	for _, item := range items {
+		if item.Name() == stateMigratedMarker || (item.IsDir() && isRingStateDir(fs, filepath.Join(localStateDir, item.Name()))) {
+			continue
+		}
+
+		entries = append(entries, item.Name())
+	}
+
+	return entries, nil
+}
+
+// readStateMigratedRing returns the ring the shared local state was migrated to
+func readStateMigratedRing(fs afero.Fs) string {
+	b, _ := afero.ReadFile(fs, filepath.Join(localStateDir, stateMigratedMarker))
+
+	return strings.TrimSpace(string(b))
+}
+
+// copyPath recursively copies the file or directory src to dst
+func copyPath(fs afero.Fs, src string, dst string) error {
+	return afero.Walk(fs, src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(dst, rel)
+
+		if info.IsDir() {
+			return fs.MkdirAll(target, info.Mode())
+		}
+
+		b, err := afero.ReadFile(fs, path)
+		if err != nil {
+			return err
+		}
+
+		return afero.WriteFile(fs, target, b, info.Mode())
+	})
+}
+
+// getLocalStateSteps returns the ids of the steps whose terraform or terragrunt configuration keeps its state in the
+// container's local state directory, e.g. the workspace_dir of a local backend, which is only persisted when the
+// local state directory is mounted into the container
+func getLocalStateSteps(fs afero.Fs) ([]string, error) {
+	graph, err := getProjectGraph(fs)
+	if err != nil {
+		return nil, err
+	}
+
+	steps := []string{}
+
+	for _, t := range graph.Tracks {
+		for _, s := range t.Steps {
+			dir := filepath.Join(getTrackDir(t.Name), fmt.Sprintf("%s%d_%s", stepDirPrefix, s.Level, s.Name))
+
+			local := false
+
+			err = afero.Walk(fs, dir, func(path string, info os.FileInfo, err error) error {
+				if err != nil || local || info.IsDir() {
+					return err
+				}
+
+				if ext := filepath.Ext(path); ext != ".tf" && ext != ".hcl" && !strings.HasSuffix(path, ".tf.json") {
+					return nil
+				}
+
+				b, err := afero.ReadFile(fs, path)
+				local = err == nil && strings.Contains(string(b), containerTFState)
+
+				return err
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			if local {
+				steps = append(steps, s.ID)
+			}
+		}
+	}
+
+	return steps, nil
+}
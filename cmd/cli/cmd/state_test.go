@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetRingStateDir_ShouldIsolateRings(t *testing.T) {
+	tests := []struct {
+		ring      string
+		expected  string
+		expectErr bool
+	}{
+		{"", localStateDir, false},
+		{"dev", filepath.Join(localStateDir, "dev"), false},
+		{"Prod", filepath.Join(localStateDir, "prod"), false},
+		{"../prod", "", true},
+		{"..", "", true},
+	}
+
+	for _, tt := range tests {
+		dir, err := getRingStateDir(tt.ring)
+
+		if tt.expectErr {
+			require.Error(t, err, "getRingStateDir(\"%s\") should fail", tt.ring)
+		} else {
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, dir)
+		}
+	}
+}
+
+func TestPrepareRingStateDir_ShouldMigrateFlatStateIntoTheChosenRing(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	_ = afero.WriteFile(fs, filepath.Join(localStateDir, "terraform.tfstate"), []byte("flat"), 0644)
+	_ = afero.WriteFile(fs, filepath.Join(localStateDir, "terraform.tfstate.d", "primary-centralus", "terraform.tfstate"), []byte("workspace"), 0644)
+
+	_, err := prepareRingStateDir(fs, "prod", "")
+	require.Error(t, err, "a new ring should not start empty before the shared state was migrated")
+	require.Contains(t, err.Error(), "--migrate-state-to")
+
+	exists, _ := afero.Exists(fs, filepath.Join(localStateDir, "prod"))
+	require.False(t, exists)
+
+	devDir, err := prepareRingStateDir(fs, "dev", "dev")
+	require.NoError(t, err)
+
+	b, _ := afero.ReadFile(fs, filepath.Join(devDir, "terraform.tfstate"))
+	require.Equal(t, "flat", string(b))
+
+	b, _ = afero.ReadFile(fs, filepath.Join(devDir, "terraform.tfstate.d", "primary-centralus", "terraform.tfstate"))
+	require.Equal(t, "workspace", string(b))
+
+	// changes to the ring state must not be overwritten by migrating again
+	_ = afero.WriteFile(fs, filepath.Join(devDir, "terraform.tfstate"), []byte("dev"), 0644)
+
+	_, err = prepareRingStateDir(fs, "dev", "dev")
+	require.NoError(t, err)
+
+	b, _ = afero.ReadFile(fs, filepath.Join(devDir, "terraform.tfstate"))
+	require.Equal(t, "dev", string(b))
+
+	// the other rings start with empty state once the shared state was migrated
+	prodDir, err := prepareRingStateDir(fs, "prod", "")
+	require.NoError(t, err)
+
+	items, _ := afero.ReadDir(fs, prodDir)
+	require.Len(t, items, 1, "only the ring state marker")
+
+	_, err = prepareRingStateDir(fs, "test", "test")
+	require.EqualError(t, err, "the shared local state in .runiac/tfstate was already migrated to deployment ring 'dev'")
+
+	dir, err := prepareRingStateDir(fs, "", "")
+	require.NoError(t, err)
+	require.Equal(t, localStateDir, dir)
+}
+
+func TestPrepareRingStateDir_ShouldFailWhenDirectoryIsNotRingState(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	_ = afero.WriteFile(fs, filepath.Join(localStateDir, "prod", "terraform.tfstate"), []byte("flat"), 0644)
+
+	_, err := prepareRingStateDir(fs, "prod", "prod")
+	require.Error(t, err)
+}
@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// containerStepLogDir is where the step log directory of a run is mounted inside the container, the container
+// persists the output of each step to {trackName}/{stepName}.log
+const containerStepLogDir = "/runiac/logs"
+
+// getRunLogDir returns the host directory of the step logs of the run, .runiac/logs/{run id}
+func getRunLogDir(runID string) string {
+	return filepath.Join(containerLogDir, runID)
+}
+
+// getStepLogArguments returns the container run arguments persisting the output of each step of the run to its
+// step log directory. The container masks the values of the run's sensitive and secret env variables in the logs.
+func getStepLogArguments(runID string, runArgs []string) ([]string, error) {
+	path, err := filepath.Abs(getRunLogDir(runID))
+	if err != nil {
+		return nil, err
+	}
+
+	if err = os.MkdirAll(path, 0755); err != nil {
+		return nil, err
+	}
+
+	args := getVolumeArguments(path, containerStepLogDir, false)
+	args = appendE(args, "STEP_LOG_DIR", containerStepLogDir)
+
+	return appendEIfSet(args, "STEP_LOG_MASK_ENV", strings.Join(getMaskedEnvNames(getEnvFromArgs(runArgs)), ",")), nil
+}
+
+// getMaskedEnvNames returns the names of the KEY=VALUE env variables that are sensitive or hold a resolved secret
+func getMaskedEnvNames(env []string) []string {
+	names := []string{}
+
+	for _, e := range env {
+		parts := strings.SplitN(e, "=", 2)
+
+		if len(parts) == 2 && parts[1] != "" && (isSensitiveEnvKey(parts[0]) || secretValues[parts[1]]) {
+			names = append(names, parts[0])
+		}
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// findRunLogDir returns the step log directory of the run, matching the full or a shortened run id. Without a run
+// id, the step log directory of the most recent run is returned.
+func findRunLogDir(fs afero.Fs, runID string) (string, error) {
+	entries, err := afero.ReadDir(fs, containerLogDir)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+
+	runs := []os.FileInfo{}
+
+	for _, e := range entries {
+		if e.IsDir() && strings.HasPrefix(e.Name(), runID) {
+			runs = append(runs, e)
+		}
+	}
+
+	switch {
+	case len(runs) == 0 && runID == "":
+		return "", fmt.Errorf("no step logs in %s, the output of the steps is persisted by runiac deploy", containerLogDir)
+	case len(runs) == 0:
+		return "", fmt.Errorf("no step logs of run '%s' in %s, the run ids are the correlation ids of runiac history", runID, containerLogDir)
+	case len(runs) > 1 && runID != "":
+		return "", fmt.Errorf("run id '%s' is ambiguous, it matches %d runs in %s", runID, len(runs), containerLogDir)
+	}
+
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].ModTime().After(runs[j].ModTime()) })
+
+	return getRunLogDir(runs[0].Name()), nil
+}
+
+// isRunLogDir returns true when the id matches the step log directory of a run
+func isRunLogDir(fs afero.Fs, id string) bool {
+	_, err := findRunLogDir(fs, id)
+
+	return err == nil
+}
+
+// getStepLogs returns the step logs of the run directory by {trackName}/{stepName} id, only the log of the step
+// when set
+func getStepLogs(fs afero.Fs, runDir string, step string) (map[string]string, error) {
+	logs := map[string]string{}
+
+	if step != "" {
+		path := filepath.Join(runDir, filepath.FromSlash(step)+".log")
+
+		if exists, _ := afero.Exists(fs, path); exists {
+			logs[step] = path
+		}
+
+		return logs, nil
+	}
+
+	matches, err := afero.Glob(fs, filepath.Join(runDir, "*", "*.log"))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range matches {
+		rel, _ := filepath.Rel(runDir, path)
+		logs[strings.TrimSuffix(filepath.ToSlash(rel), ".log")] = path
+	}
+
+	return logs, nil
+}
+
+// readStepLog returns the output of the step log written after offset
+func readStepLog(fs afero.Fs, path string, offset int64) ([]byte, error) {
+	f, err := fs.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if _, err = f.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadAll(f)
+}
+
+// followStepLogs writes the step logs of the run, only the log of step when set and otherwise every step log with
+// each line prefixed by its step. New output is followed until done returns true and the logs are drained.
+func followStepLogs(fs afero.Fs, runDir string, step string, w io.Writer, done func() bool) error {
+	if step != "" && len(strings.Split(step, "/")) != 2 {
+		return fmt.Errorf("invalid step '%s', expected a {trackName}/{stepName} step id", step)
+	}
+
+	offsets := map[string]int64{}
+	finished := false
+
+	for {
+		logs, err := getStepLogs(fs, runDir, step)
+		if err != nil {
+			return err
+		}
+
+		ids := make([]string, 0, len(logs))
+		for id := range logs {
+			ids = append(ids, id)
+		}
+
+		sort.Strings(ids)
+
+		for _, id := range ids {
+			b, err := readStepLog(fs, logs[id], offsets[id])
+			if err != nil {
+				return err
+			}
+
+			// only complete lines are written, the rest is written once its line completed or the run finished
+			if !finished {
+				b = b[:bytes.LastIndexByte(b, '\n')+1]
+			}
+
+			offsets[id] += int64(len(b))
+
+			if step != "" {
+				_, err = w.Write(b)
+			} else {
+				err = writePrefixedLines(w, fmt.Sprintf("[%s] ", id), b)
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+
+		if finished {
+			if step != "" && len(logs) == 0 {
+				return fmt.Errorf("no log of step %s in %s, it was not executed", step, runDir)
+			}
+
+			return nil
+		}
+
+		// drain once more after done so output written while the run completed is not lost
+		finished = done()
+
+		if !finished {
+			time.Sleep(logFollowInterval)
+		}
+	}
+}
+
+// writePrefixedLines writes every line of b with the prefix
+func writePrefixedLines(w io.Writer, prefix string, b []byte) error {
+	for _, line := range strings.SplitAfter(string(b), "\n") {
+		if line == "" {
+			continue
+		}
+
+		if _, err := io.WriteString(w, prefix+line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindRunLogDir_ShouldMatchShortenedOrMostRecentRun(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	_, err := findRunLogDir(fs, "")
+	require.Error(t, err, "should fail without step logs")
+
+	require.NoError(t, fs.MkdirAll(getRunLogDir("abc123"), 0755))
+	require.NoError(t, fs.MkdirAll(getRunLogDir("abd456"), 0755))
+	require.NoError(t, afero.WriteFile(fs, getContainerLog("abe789"), []byte("container output\n"), 0644))
+	require.NoError(t, fs.Chtimes(getRunLogDir("abc123"), time.Now(), time.Now().Add(-time.Hour)))
+	require.NoError(t, fs.Chtimes(getRunLogDir("abd456"), time.Now(), time.Now()))
+
+	dir, err := findRunLogDir(fs, "abc")
+	require.NoError(t, err)
+	require.Equal(t, getRunLogDir("abc123"), dir)
+
+	dir, err = findRunLogDir(fs, "")
+	require.NoError(t, err)
+	require.Equal(t, getRunLogDir("abd456"), dir, "should default to the most recent run")
+
+	_, err = findRunLogDir(fs, "ab")
+	require.EqualError(t, err, "run id 'ab' is ambiguous, it matches 2 runs in .runiac/logs")
+
+	require.False(t, isRunLogDir(fs, "abe789"), "container logs are not runs")
+}
+
+func TestFollowStepLogs_ShouldWriteStepLogsOfRun(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	runDir := getRunLogDir("abc123")
+
+	require.NoError(t, afero.WriteFile(fs, filepath.Join(runDir, "network", "vpc.log"), []byte("plan\napply\n"), 0644))
+	require.NoError(t, afero.WriteFile(fs, filepath.Join(runDir, "app", "api.log"), []byte("deploy\npartial"), 0644))
+
+	var out bytes.Buffer
+	require.NoError(t, followStepLogs(fs, runDir, "", &out, func() bool { return true }))
+	require.Equal(t, "[app/api] deploy\n[network/vpc] plan\n[network/vpc] apply\n[app/api] partial", out.String(), "incomplete lines should be written last")
+
+	out.Reset()
+	require.NoError(t, followStepLogs(fs, runDir, "network/vpc", &out, func() bool { return true }))
+	require.Equal(t, "plan\napply\n", out.String())
+
+	require.EqualError(t, followStepLogs(fs, runDir, "network/dns", &out, func() bool { return true }),
+		"no log of step network/dns in .runiac/logs/abc123, it was not executed")
+	require.Error(t, followStepLogs(fs, runDir, "dns", &out, func() bool { return true }))
+}
+
+func TestFollowStepLogs_ShouldFollowRunInProgress(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	runDir := getRunLogDir("abc123")
+	path := filepath.Join(runDir, "network", "vpc.log")
+
+	polls := 0
+	var out bytes.Buffer
+
+	err := followStepLogs(fs, runDir, "network/vpc", &out, func() bool {
+		polls++
+
+		// the step starts once the run is followed, its output is written across polls
+		f, err := fs.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		require.NoError(t, err)
+		defer f.Close()
+
+		_, err = f.WriteString([]string{"pla", "n\napply\n"}[polls-1])
+		require.NoError(t, err)
+
+		return polls == 2
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, "plan\napply\n", out.String())
+}
+
+func TestGetMaskedEnvNames_ShouldReturnSensitiveAndSecretVariables(t *testing.T) {
+	secretValues["vault-value"] = true
+	defer delete(secretValues, "vault-value")
+
+	names := getMaskedEnvNames([]string{"TF_VAR_db_password=vault-value", "ARM_CLIENT_SECRET=abcd", "RUNIAC_ENVIRONMENT=prod", "GITHUB_TOKEN="})
+
+	require.Equal(t, []string{"ARM_CLIENT_SECRET", "TF_VAR_db_password"}, names)
+}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+const (
+	defaultTrackName = "default" // The name of the track formed by step directories at the top-level of the project
+	tracksDir        = "tracks"  // The directory containing the named tracks of a project
+	stepDirPrefix    = "step"    // Step directories follow the convention step{progressionLevel}_{stepName}
+)
+
+// getTrackDir returns the directory containing the steps of the named track
+func getTrackDir(track string) string {
+	if track == defaultTrackName {
+		return "."
+	}
+
+	return filepath.Join(tracksDir, track)
+}
+
+// getTrackStepIDs returns the ids of every step in the named tracks, in the {trackName}/{stepName} format used by --steps
+func getTrackStepIDs(fs afero.Fs, tracks []string) ([]string, error) {
+	stepIDs := []string{}
+
+	for _, track := range tracks {
+		track = strings.TrimSpace(track)
+		dir := getTrackDir(track)
+
+		items, err := afero.ReadDir(fs, dir)
+		if err != nil || track == "" {
+			return nil, fmt.Errorf("track '%s' does not exist, expected a directory at %s", track, dir)
+		}
+
+		trackStepIDs := []string{}
+
+		for _, item := range items {
+			// step folder convention is step{progressionLevel}_{stepName}
+			if item.IsDir() && strings.HasPrefix(item.Name(), stepDirPrefix) && len(item.Name()) > len(stepDirPrefix)+2 {
+				trackStepIDs = append(trackStepIDs, fmt.Sprintf("%s/%s", track, item.Name()[len(stepDirPrefix)+2:]))
+			}
+		}
+
+		if len(trackStepIDs) == 0 {
+			return nil, fmt.Errorf("track '%s' does not contain any steps", track)
+		}
+
+		sort.Strings(trackStepIDs)
+		stepIDs = append(stepIDs, trackStepIDs...)
+	}
+
+	return stepIDs, nil
+}
+
+// validateTeardownOrder checks every step of the teardown order exists in the project, is listed once and that the
+// order includes every step targeted by the whitelist, every step of the project when the whitelist is empty
+func validateTeardownOrder(fs afero.Fs, order []string, whitelist []string) error {
+	graph, err := getProjectGraph(fs)
+	if err != nil {
+		return err
+	}
+
+	projectSteps := map[string]bool{}
+	for _, t := range graph.Tracks {
+		for _, s := range t.Steps {
+			projectSteps[s.ID] = true
+		}
+	}
+
+	ordered := map[string]bool{}
+
+	for _, id := range order {
+		id = strings.TrimSpace(id)
+
+		if !projectSteps[id] {
+			return fmt.Errorf("--teardown-order step '%s' does not exist, expected a {trackName}/{stepName} step id", id)
+		}
+
+		if ordered[id] {
+			return fmt.Errorf("--teardown-order step '%s' is listed more than once", id)
+		}
+
+		ordered[id] = true
+	}
+
+	targeted := whitelist
+	if len(targeted) == 0 {
+		for id := range projectSteps {
+			targeted = append(targeted, id)
+		}
+	}
+
+	missing := []string{}
+
+	for _, id := range targeted {
+		// whitelisted steps that do not exist are not deployed, so there is nothing to destroy
+		if projectSteps[id] && !ordered[id] {
+			missing = append(missing, id)
+		}
+	}
+
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("--teardown-order does not include the targeted step(s) %s", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
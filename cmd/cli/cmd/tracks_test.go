@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetTrackStepIDs_ShouldExpandAllStepsInTrack(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	_ = fs.MkdirAll("tracks/network/step1_vnet", 0755)
+	_ = fs.MkdirAll("tracks/network/step2_peering", 0755)
+	_ = fs.MkdirAll("tracks/network/modules", 0755)
+	_ = fs.MkdirAll("tracks/empty/modules", 0755)
+	_ = fs.MkdirAll("step1_hello", 0755)
+
+	stepIDs, err := getTrackStepIDs(fs, []string{"network", "default"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"network/peering", "network/vnet", "default/hello"}, stepIDs)
+
+	_, err = getTrackStepIDs(fs, []string{"missing"})
+	require.Error(t, err, "a track that does not exist should fail")
+
+	_, err = getTrackStepIDs(fs, []string{"empty"})
+	require.Error(t, err, "a track without steps should fail")
+}
+
+func TestValidateTeardownOrder_ShouldRequireExistingAndTargetedSteps(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	_ = fs.MkdirAll("tracks/network/step1_vnet", 0755)
+	_ = fs.MkdirAll("tracks/app/step1_db", 0755)
+	_ = fs.MkdirAll("step1_hello", 0755)
+
+	tests := []struct {
+		name      string
+		order     []string
+		whitelist []string
+		err       bool
+	}{
+		{"track directory instead of step id", []string{"tracks/app/db", "network/vnet", "default/hello"}, nil, true},
+		{"every step in order", []string{"app/db", "network/vnet", "default/hello"}, nil, false},
+		{"missing untargeted step", []string{"app/db", "network/vnet"}, []string{"app/db", "network/vnet", "missing/step"}, false},
+		{"missing targeted step", []string{"app/db"}, []string{"app/db", "network/vnet"}, true},
+		{"all steps when not whitelisted", []string{"app/db", "network/vnet"}, nil, true},
+		{"duplicate step", []string{"app/db", "app/db"}, []string{"app/db"}, true},
+		{"step does not exist", []string{"app/db", "app/web"}, []string{"app/db"}, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateTeardownOrder(fs, tc.order, tc.whitelist)
+			if tc.err {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,389 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// configOnlyKeys are the keys of the runiac config that are not settings of a flag
+var configOnlyKeys = []string{
+	"project", "primary_region", "step_whitelist", "runner_args", "rings", "profiles", "profile", "env_passthrough",
+	"env_passthrough_only", "mask_patterns", "secrets", "freeze_windows", "freeze_notify_url", "require_approval_rings",
+	"protected_rings", "namespace_claim_ttl", "namespace_records", "account_id", "core_accounts", "backend", "max_retries",
+	"max_test_retries", "skip_regional", "plan_summary_require_confirm",
+	"scan", "hooks", "notifications", "metrics", "tracing_endpoint", "regional_rollout", "variables", environmentsConfigKey,
+}
+
+// ringConfigKeys are the keys of the rings.{ring} sections of the runiac config
+var ringConfigKeys = []string{"account", "features", "primary_regions", "regional_regions", "variables", "environments", "require_approval"}
+
+// runnerStepFiles are the files a step directory requires for each runner, a step requires one of them
+var runnerStepFiles = map[string][]string{
+	"terraform":  {"*.tf", "*.tf.json"},
+	"terragrunt": {"terragrunt.hcl"},
+	"arm":        {"main.json"},
+	"pulumi":     {"Pulumi.yaml", "Pulumi.yml"},
+}
+
+var validateStrict bool
+
+func init() {
+	validateCmd.Flags().BoolVar(&validateStrict, "strict", false, "Fail on warnings as well as errors")
+
+	rootCmd.AddCommand(validateCmd)
+}
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate the runiac config and the project's steps",
+	Long: `Statically validates runiac.yml or runiac.hcl and the project's step directories without building or running
+the deploy container: unknown config keys, the runner, dockerfiles, the step whitelist and teardown order, step
+naming collisions, depends_on, inputs and the files each step requires for the runner. Exits non-zero when an error
+is found, so pull requests can be gated on it.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if exists, _ := afero.Exists(appFS, fileConfig.ConfigFileUsed()); !exists {
+			logrus.Fatalf("no runiac config found at %s", fileConfig.ConfigFileUsed())
+		}
+
+		issues := validateProject(appFS, fileConfig, deployCmd.Flags())
+
+		errors := printValidationIssues(os.Stdout, issues, validateStrict)
+
+		if errors > 0 {
+			logrus.Fatalf("%s has %d error(s)", fileConfig.ConfigFileUsed(), errors)
+		}
+
+		logrus.Infof("%s is valid", fileConfig.ConfigFileUsed())
+	},
+}
+
+// validationIssue is a problem found validating the project, warnings do not fail the validation unless --strict
+type validationIssue struct {
+	Warning bool
+	Message string
+}
+
+// printValidationIssues prints the issues and returns the number of issues failing the validation
+func printValidationIssues(w io.Writer, issues []validationIssue, strict bool) int {
+	errors := 0
+
+	for _, issue := range issues {
+		severity := "error"
+
+		if issue.Warning && !strict {
+			severity = "warning"
+		} else {
+			errors++
+		}
+
+		fmt.Fprintf(w, "%s: %s\n", severity, issue.Message)
+	}
+
+	return errors
+}
+
+// validateProject returns the issues of the runiac config read into config and the project's step directories. The
+// flags are the settings that can be configured in the runiac config.
+func validateProject(fs afero.Fs, config *viper.Viper, flags *pflag.FlagSet) []validationIssue {
+	issues := []validationIssue{}
+
+	errorf := func(format string, a ...interface{}) {
+		issues = append(issues, validationIssue{Message: fmt.Sprintf(format, a...)})
+	}
+
+	warnf := func(format string, a ...interface{}) {
+		issues = append(issues, validationIssue{Warning: true, Message: fmt.Sprintf(format, a...)})
+	}
+
+	settings := getSettingConfigKeys(flags)
+
+	known := map[string]bool{}
+	for key := range settings {
+		known[key] = true
+	}
+
+	for _, key := range configOnlyKeys {
+		known[key] = true
+	}
+
+	for _, key := range getSortedKeys(config.AllSettings()) {
+		if !known[key] {
+			errorf("unknown key '%s' in the runiac config", key)
+		}
+	}
+
+	for _, ring := range getSortedKeys(config.GetStringMap("rings")) {
+		for _, key := range getSortedKeys(config.GetStringMap("rings." + ring)) {
+			if !isRingConfigKey(key) {
+				errorf("unknown key 'rings.%s.%s', must be one of %s", ring, key, strings.Join(ringConfigKeys, ", "))
+			}
+		}
+	}
+
+	for _, key := range getSortedKeys(config.GetStringMap("scan")) {
+		if !isScanConfigKey(key) {
+			errorf("unknown key 'scan.%s', must be one of %s", key, strings.Join(scanConfigKeys, ", "))
+		}
+	}
+
+	if err := validateScanConfig(config.GetString("scan.tool"), config.GetString("scan.fail_on")); err != nil {
+		errorf("%s", err)
+	}
+
+	for _, key := range getSortedKeys(config.GetStringMap("metrics")) {
+		if !isStringInSlice(key, metricsConfigKeys) {
+			errorf("unknown key 'metrics.%s', must be one of %s", key, strings.Join(metricsConfigKeys, ", "))
+		}
+	}
+
+	if _, err := getMetricsConfig(config); err != nil {
+		errorf("%s", err)
+	}
+
+	items := []map[string]interface{}{}
+
+	if err := config.UnmarshalKey("notifications", &items); err == nil {
+		for i, n := range items {
+			for _, key := range getSortedKeys(n) {
+				if !isStringInSlice(key, notificationKeys) {
+					errorf("unknown key 'notifications[%d].%s', must be one of %s", i, key, strings.Join(notificationKeys, ", "))
+				}
+			}
+		}
+	}
+
+	if _, err := getNotifications(config); err != nil {
+		errorf("%s", err)
+	}
+
+	waves := []map[string]interface{}{}
+
+	if err := config.UnmarshalKey("regional_rollout", &waves); err == nil {
+		for i, w := range waves {
+			for _, key := range getSortedKeys(w) {
+				if !isStringInSlice(key, rolloutWaveKeys) {
+					errorf("unknown key 'regional_rollout[%d].%s', must be one of %s", i, key, strings.Join(rolloutWaveKeys, ", "))
+				}
+			}
+		}
+	}
+
+	if rollout, err := getRegionalRollout(config); err != nil {
+		errorf("%s", err)
+	} else {
+		regionalRegions := []string{}
+		for _, ring := range getSortedKeys(config.GetStringMap("rings")) {
+			regionalRegions = append(regionalRegions, config.GetStringSlice("rings."+ring+".regional_regions")...)
+		}
+
+		for _, wave := range rollout {
+			for _, region := range wave.Regions {
+				if !isStringInSlice(region, regionalRegions) {
+					warnf("regional_rollout region %s is not a regional region of any ring", region)
+				}
+			}
+		}
+	}
+
+	for _, profile := range getSortedKeys(config.GetStringMap("profiles")) {
+		for _, key := range getSortedKeys(config.GetStringMap("profiles." + profile)) {
+			if !settings[key] {
+				errorf("unknown setting 'profiles.%s.%s'", profile, key)
+			}
+		}
+	}
+
+	for _, environment := range getSortedKeys(config.GetStringMap(environmentsConfigKey)) {
+		for _, key := range getSortedKeys(config.GetStringMap(environmentsConfigKey + "." + environment)) {
+			if unoverlaidConfigKeys[key] {
+				errorf("'%s.%s.%s' can not be set by an environment overlay", environmentsConfigKey, environment, key)
+			} else if !known[key] {
+				errorf("unknown key '%s.%s.%s'", environmentsConfigKey, environment, key)
+			}
+		}
+
+		for _, ring := range getSortedKeys(config.GetStringMap(environmentsConfigKey + "." + environment + ".rings")) {
+			for _, key := range getSortedKeys(config.GetStringMap(environmentsConfigKey + "." + environment + ".rings." + ring)) {
+				if !isRingConfigKey(key) {
+					errorf("unknown key '%s.%s.rings.%s.%s', must be one of %s", environmentsConfigKey, environment, ring, key, strings.Join(ringConfigKeys, ", "))
+				}
+			}
+		}
+	}
+
+	runner := flags.Lookup("runner").DefValue
+	if config.IsSet("runner") {
+		runner = config.GetString("runner")
+	}
+
+	if err := validateRunner(runner, config.GetStringSlice("runner_args")); err != nil {
+		errorf("%s", err)
+	}
+
+	if config.IsSet("dockerfile") {
+		if exists, _ := afero.Exists(fs, config.GetString("dockerfile")); !exists {
+			errorf("dockerfile %s does not exist", config.GetString("dockerfile"))
+		}
+	}
+
+	graph, err := getProjectGraph(fs)
+	if err != nil {
+		errorf("%s", err)
+		return issues
+	}
+
+	stepIDs := map[string]bool{}
+
+	for _, t := range graph.Tracks {
+		dirs := map[string][]string{}
+		stepNames := []string{}
+
+		for _, dir := range getStepDirs(fs, t.Name) {
+			name := dir[len(stepDirPrefix)+2:]
+
+			if len(dirs[name]) == 0 {
+				stepNames = append(stepNames, name)
+			}
+
+			dirs[name] = append(dirs[name], dir)
+
+			if _, err := strconv.Atoi(string(dir[len(stepDirPrefix)])); err != nil || dir[len(stepDirPrefix)+1] != '_' {
+				errorf("step directory %s must be named step{progressionLevel}_{stepName}", filepath.Join(getTrackDir(t.Name), dir))
+			} else if err := validateStepName(name); err != nil {
+				warnf("step %s/%s: %s", t.Name, name, err)
+			}
+		}
+
+		for _, name := range stepNames {
+			if len(dirs[name]) > 1 {
+				errorf("step name '%s' is used by more than one step of the %s track: %s", name, t.Name, strings.Join(dirs[name], ", "))
+			}
+		}
+
+		names := map[string]bool{}
+		for _, s := range t.Steps {
+			names[s.Name] = true
+			stepIDs[s.ID] = true
+		}
+
+		for _, s := range t.Steps {
+			for _, d := range s.DependsOn {
+				if !names[d] {
+					errorf("step %s depends on %s, which is not a step of the %s track", s.ID, d, t.Name)
+				}
+			}
+
+			for _, from := range getSortedStringKeys(s.Inputs) {
+				if !names[from] {
+					errorf("step %s reads the inputs %s from %s, which is not a step of the %s track", s.ID, s.Inputs[from], from, t.Name)
+				} else if from == s.Name {
+					errorf("step %s reads its own outputs as the inputs %s", s.ID, s.Inputs[from])
+				}
+			}
+
+			stepDir := filepath.Join(getTrackDir(t.Name), fmt.Sprintf("%s%d_%s", stepDirPrefix, s.Level, s.Name))
+
+			if patterns, ok := runnerStepFiles[runner]; ok && !hasStepFiles(fs, stepDir, patterns) && !hasStepFiles(fs, filepath.Join(stepDir, "regional"), patterns) {
+				errorf("step %s has none of the files the %s runner requires, %s", s.ID, runner, strings.Join(patterns, " or "))
+			}
+		}
+	}
+
+	for _, id := range config.GetStringSlice("step_whitelist") {
+		if id = strings.TrimSpace(id); !stepIDs[id] {
+			errorf("step_whitelist step '%s' does not exist, expected a {trackName}/{stepName} step id", id)
+		}
+	}
+
+	if order := config.GetStringSlice("teardown_order"); len(order) > 0 {
+		if err := validateTeardownOrder(fs, order, config.GetStringSlice("step_whitelist")); err != nil {
+			errorf("%s", strings.Replace(err.Error(), "--teardown-order", "teardown_order", 1))
+		}
+	}
+
+	return issues
+}
+
+// isRingConfigKey returns true when the key is a key of the rings.{ring} sections
+func isRingConfigKey(key string) bool {
+	for _, k := range ringConfigKeys {
+		if k == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isScanConfigKey returns true when the key is a key of the scan section
+func isScanConfigKey(key string) bool {
+	for _, k := range scanConfigKeys {
+		if k == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getStepDirs returns the names of the step directories of the track, including misnamed ones
+func getStepDirs(fs afero.Fs, track string) []string {
+	dirs := []string{}
+
+	items, _ := afero.ReadDir(fs, getTrackDir(track))
+	for _, item := range items {
+		if item.IsDir() && strings.HasPrefix(item.Name(), stepDirPrefix) && len(item.Name()) > len(stepDirPrefix)+2 {
+			dirs = append(dirs, item.Name())
+		}
+	}
+
+	return dirs
+}
+
+// hasStepFiles returns true when the directory contains a file matching one of the patterns
+func hasStepFiles(fs afero.Fs, dir string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matches, _ := afero.Glob(fs, filepath.Join(dir, pattern)); len(matches) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getSortedKeys returns the keys of the config section in alphabetical order
+func getSortedKeys(section map[string]interface{}) []string {
+	keys := []string{}
+
+	for k := range section {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// getSortedStringKeys returns the keys of the map in alphabetical order
+func getSortedStringKeys(m map[string]string) []string {
+	keys := []string{}
+
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func getValidateFixture(t *testing.T, config string) (afero.Fs, *viper.Viper) {
+	fs := afero.NewMemMapFs()
+
+	for path, content := range map[string]string{
+		"runiac.yml":                         config,
+		"tracks/network/step1_vnet/main.tf":  "",
+		"tracks/network/step2_dns/main.tf":   "",
+		"tracks/app/step1_web/regional/a.tf": "",
+	} {
+		require.NoError(t, afero.WriteFile(fs, path, []byte(content), 0644))
+	}
+
+	v := viper.New()
+	v.SetFs(fs)
+	v.SetConfigFile("runiac.yml")
+	require.NoError(t, v.ReadInConfig())
+
+	return fs, v
+}
+
+func getIssueMessages(issues []validationIssue) string {
+	messages := []string{}
+	for _, issue := range issues {
+		messages = append(messages, issue.Message)
+	}
+
+	return strings.Join(messages, "\n")
+}
+
+func TestValidateProject_ShouldAcceptValidProject(t *testing.T) {
+	fs, config := getValidateFixture(t, `
+project: stub
+runner: terraform
+primary_region: centralus
+max_retries: 2
+step_whitelist:
+  - network/vnet
+rings:
+  prod:
+    account: stub
+    regional_regions: [eastus, westus, centralus]
+    environments: [prod]
+    require_approval: true
+    variables:
+      sku: Standard
+regional_rollout:
+  - regions: [eastus]
+  - regions: [westus, centralus]
+    parallel: 1
+profiles:
+  ci:
+    log_level: debug
+variables:
+  owner: platform
+environments:
+  prod:
+    container: stub/runiac:prod
+    primary_region: eastus
+    rings:
+      prod:
+        account: stub-prod
+    variables:
+      owner: operations
+scan:
+  enabled: true
+  tool: checkov
+  fail_on: high
+notifications:
+  - type: slack
+    url: ${SLACK_WEBHOOK_URL}
+    events: [step_failed, deploy_completed]
+metrics:
+  pushgateway_url: ${PUSHGATEWAY_URL}
+  job: deploys
+`)
+
+	issues := validateProject(fs, config, deployCmd.Flags())
+	require.Empty(t, issues, getIssueMessages(issues))
+}
+
+func TestValidateProject_ShouldAcceptHCLConfig(t *testing.T) {
+	fs, _ := getValidateFixture(t, "")
+
+	require.NoError(t, afero.WriteFile(fs, "runiac.hcl", []byte(`
+project        = "stub"
+runner         = "terraform"
+primary_region = "centralus"
+
+rings "prod" {
+  account          = "stub"
+  regional_regions = ["eastus"]
+}
+
+notifications {
+  type   = "slack"
+  url    = "https://hooks.slack.com/stub"
+  events = ["step_failed"]
+}
+
+environments "prod" {
+  container = "stub/runiac:prod"
+}
+`), 0644))
+
+	v := viper.New()
+	require.NoError(t, config.ReadConfigFile(fs, v, "runiac.hcl"))
+
+	issues := validateProject(fs, v, deployCmd.Flags())
+	require.Empty(t, issues, getIssueMessages(issues))
+
+	require.NoError(t, afero.WriteFile(fs, "runiac.hcl", []byte(`rings "prod" { acount = "stub" }`), 0644))
+
+	v = viper.New()
+	require.NoError(t, config.ReadConfigFile(fs, v, "runiac.hcl"))
+	require.Contains(t, getIssueMessages(validateProject(fs, v, deployCmd.Flags())), "unknown key 'rings.prod.acount'")
+}
+
+func TestValidateProject_ShouldReportErrors(t *testing.T) {
+	fs, config := getValidateFixture(t, `
+project: stub
+runner: Terraform Runner
+dockerfile: .runiac/Dockerfile.custom
+primary_regoin: centralus
+step_whitelist:
+  - network/missing
+rings:
+  prod:
+    acount: stub
+profiles:
+  ci:
+    unknown_setting: true
+environments:
+  prod:
+    profile: ci
+    contianer: stub
+    rings:
+      prod:
+        acount: stub
+scan:
+  tool: trivy
+  fail: high
+notifications:
+  - type: discord
+    url: https://discord.example.com/webhook
+    channel: deploys
+metrics:
+  pushgateway: http://pushgateway:9091
+regional_rollout:
+  - region: [eastus]
+`)
+
+	_ = fs.MkdirAll("tracks/network/step3_vnet", 0755)
+	_ = fs.MkdirAll("tracks/app/stepX_bad", 0755)
+	_ = afero.WriteFile(fs, "tracks/app/step2_api/runiac.yml", []byte("depends_on: [missing]\n"), 0644)
+
+	messages := getIssueMessages(validateProject(fs, config, deployCmd.Flags()))
+
+	for _, expected := range []string{
+		"unknown key 'primary_regoin'",
+		"unknown key 'rings.prod.acount'",
+		"unknown setting 'profiles.ci.unknown_setting'",
+		"'environments.prod.profile' can not be set by an environment overlay",
+		"unknown key 'environments.prod.contianer'",
+		"unknown key 'environments.prod.rings.prod.acount'",
+		"invalid runner 'Terraform Runner'",
+		"dockerfile .runiac/Dockerfile.custom does not exist",
+		"step name 'vnet' is used by more than one step of the network track",
+		"step directory tracks/app/stepX_bad must be named",
+		"step app/api depends on missing",
+		"step_whitelist step 'network/missing' does not exist",
+		"unknown key 'scan.fail', must be one of enabled, tool, fail_on",
+		"invalid scan.tool 'trivy', must be tfsec or checkov",
+		"unknown key 'notifications[0].channel', must be one of type, url, events",
+		"invalid notifications[0].type 'discord', must be one of slack, teams, webhook",
+		"unknown key 'metrics.pushgateway', must be one of pushgateway_url, job",
+		"unknown key 'regional_rollout[0].region', must be one of regions, parallel",
+		"regional_rollout[0].regions is required",
+	} {
+		require.Contains(t, messages, expected)
+	}
+}
+
+func TestValidateProject_ShouldRequireRunnerFiles(t *testing.T) {
+	fs, config := getValidateFixture(t, "runner: arm\n")
+
+	messages := getIssueMessages(validateProject(fs, config, deployCmd.Flags()))
+
+	require.Contains(t, messages, "step network/vnet has none of the files the arm runner requires, main.json")
+	require.Contains(t, messages, "step app/web has none of the files the arm runner requires")
+
+	_ = afero.WriteFile(fs, "tracks/network/step1_vnet/main.json", []byte("{}"), 0644)
+
+	messages = getIssueMessages(validateProject(fs, config, deployCmd.Flags()))
+	require.NotContains(t, messages, "step network/vnet has none")
+}
+
+func TestPrintValidationIssues(t *testing.T) {
+	issues := []validationIssue{{Message: "broken"}, {Warning: true, Message: "suspicious"}}
+
+	var b bytes.Buffer
+	require.Equal(t, 1, printValidationIssues(&b, issues, false))
+	require.Equal(t, "error: broken\nwarning: suspicious\n", b.String())
+
+	require.Equal(t, 2, printValidationIssues(&bytes.Buffer{}, issues, true))
+}
@@ -3,12 +3,17 @@ package cmd
 import (
 	"fmt"
 
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
 var Version, Commit, Date string
 
+var versionCheck bool
+
 func init() {
+	versionCmd.Flags().BoolVar(&versionCheck, "check", false, "Check whether a newer release of runiac is available on GitHub")
+
 	rootCmd.AddCommand(versionCmd)
 }
 
@@ -18,5 +23,17 @@ var versionCmd = &cobra.Command{
 	Long:  `All software has versions. This is runiac's`,
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Printf("runiac %s. Commit %s.  Built on %s.\n", Version, Commit, Date)
+
+		if !versionCheck {
+			return
+		}
+
+		latest, err := getRelease(releaseClient, releaseAPIURL, "")
+		if err != nil {
+			logrus.WithError(err).Fatal(err)
+		}
+
+		message, _ := describeVersionCheck(Version, latest)
+		fmt.Println(message)
 	},
 }
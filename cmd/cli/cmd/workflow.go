@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// workflowClouds are the clouds gen github-actions writes the OIDC credential steps for
+var workflowClouds = []string{"aws", "azure", "gcp"}
+
+var (
+	workflowOptions pipelineOptions
+	workflowCloud   string
+)
+
+func init() {
+	addPipelineFlags(genGithubActionsCmd, &workflowOptions, ".github/workflows/runiac.yml")
+	genGithubActionsCmd.Flags().StringVar(&workflowCloud, "cloud", "", fmt.Sprintf("The cloud to authenticate to with OIDC (%s), commented hints for every cloud are written when not set. Defaults to azure for the arm runner", strings.Join(workflowClouds, ", ")))
+
+	genCmd.AddCommand(genGithubActionsCmd)
+}
+
+var genGithubActionsCmd = &cobra.Command{
+	Use:   "github-actions",
+	Short: "Generate a GitHub Actions workflow",
+	Long: `Writes a GitHub Actions workflow from the runiac config, planning every deployment ring on pull requests and
+commenting the plan, and deploying the rings in order on push. Each ring is a matrix job with its regions, deployed
+to the GitHub environment and runiac --environment named after the ring.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runPipelineGenerator(workflowOptions, func(graph projectGraph, targets []pipelineTarget, branch string) (string, error) {
+			return generateWorkflow(graph, targets, workflowCloud, branch)
+		})
+	},
+}
+
+// generateWorkflow returns a workflow planning and deploying the targets of the project
+func generateWorkflow(graph projectGraph, targets []pipelineTarget, cloud string, branch string) (string, error) {
+	if cloud == "" && viper.GetString("runner") == "arm" {
+		cloud = "azure"
+	}
+
+	cloud = strings.ToLower(cloud)
+	valid := cloud == ""
+
+	for _, c := range workflowClouds {
+		valid = valid || c == cloud
+	}
+
+	if !valid {
+		return "", fmt.Errorf("invalid --cloud '%s', must be one of %s", cloud, strings.Join(workflowClouds, ", "))
+	}
+
+	tracks := []string{}
+	for _, t := range graph.Tracks {
+		tracks = append(tracks, t.Name)
+	}
+
+	return renderPipeline("workflow", generatedWorkflowTemplate, struct {
+		Header  string
+		Project string
+		Version string
+		Branch  string
+		Cloud   string
+		Targets []pipelineTarget
+		Tracks  []string
+	}{getGeneratedPipelineHeader("github-actions"), viper.GetString("project"), strings.TrimPrefix(Version, "v"), branch, cloud, targets, tracks})
+}
+
+const generatedWorkflowTemplate = `[[ .Header ]]
+name: runiac[[ if .Project ]] [[ .Project ]][[ end ]]
+
+on:
+  pull_request:
+    branches:
+      - [[ .Branch ]]
+  push:
+    branches:
+      - [[ .Branch ]]
+  workflow_dispatch:
+[[- if .Tracks ]]
+    inputs:
+      track:
+        description: Only deploy the steps of this track
+        type: choice
+        default: all
+        options:
+          - all
+[[- range .Tracks ]]
+          - [[ . ]]
+[[- end ]]
+[[- end ]]
+
+permissions:
+  contents: read
+  id-token: write # authenticate to the cloud with OIDC instead of long lived credentials
+  pull-requests: write # comment the plan on pull requests
+
+env:
+  RUNIAC_VERSION: "[[ .Version ]]" # the latest release when empty
+
+jobs:
+  plan:
+    if: github.event_name == 'pull_request'
+    runs-on: ubuntu-latest
+    strategy:
+      fail-fast: false
+      matrix:
+        include:
+[[- template "targets" . ]]
+    steps:
+      - uses: actions/checkout@v3
+[[- template "setup" . ]]
+      - name: Plan
+        env:
+          GITHUB_TOKEN: ${{ github.token }} # updates the plan comment of the ring on the pull request
+[[- template "regionEnv" ]]
+        run: |
+[[- template "regionArgs" ]]
+
+          runiac plan -d "${{ matrix.ring }}" -e "${{ matrix.environment }}" --github-comment "${args[@]}"
+
+  deploy:
+    if: github.event_name != 'pull_request'
+    runs-on: ubuntu-latest
+    environment: ${{ matrix.environment }}
+    strategy:
+      max-parallel: 1 # deploy the rings in order, a failing ring stops the later rings
+      matrix:
+        include:
+[[- template "targets" . ]]
+    steps:
+      - uses: actions/checkout@v3
+[[- template "setup" . ]]
+      - name: Deploy
+        env:
+          TRACK: ${{ github.event.inputs.track }}
+[[- template "regionEnv" ]]
+        run: |
+[[- template "regionArgs" ]]
+          if [ -n "$TRACK" ] && [ "$TRACK" != "all" ]; then args+=(--track "$TRACK"); fi
+
+          runiac deploy -d "${{ matrix.ring }}" -e "${{ matrix.environment }}" "${args[@]}"
+      - name: Upload the deployment report
+        if: always()
+        uses: actions/upload-artifact@v3
+        with:
+          name: runiac-report-${{ matrix.ring }}
+          path: .runiac/reports/
+[[- define "targets" ]]
+[[- range .Targets ]]
+          - ring: [[ .Ring ]]
+            environment: [[ .Environment ]]
+            primary_region: "[[ .PrimaryRegion ]]"
+            regional_regions: "[[ .RegionalRegions ]]"
+[[- end ]]
+[[- end ]]
+[[- define "regionEnv" ]]
+          PRIMARY_REGION: ${{ matrix.primary_region }}
+          REGIONAL_REGIONS: ${{ matrix.regional_regions }}
+[[- end ]]
+[[- define "regionArgs" ]]
+          args=()
+          if [ -n "$PRIMARY_REGION" ]; then args+=(--primary-regions "$PRIMARY_REGION"); fi
+          for region in ${REGIONAL_REGIONS//,/ }; do args+=(--regional-regions "$region"); done
+[[- end ]]
+[[- define "setup" ]]
+      - name: Install runiac
+        env:
+          GH_TOKEN: ${{ github.token }}
+        run: |
+          gh release download ${RUNIAC_VERSION:+"v$RUNIAC_VERSION"} --repo optum/runiac --pattern 'runiac_*_linux_x86_64.tar.gz' --output runiac.tar.gz
+          sudo tar -xzf runiac.tar.gz -C /usr/local/bin runiac && rm runiac.tar.gz
+[[- if eq .Cloud "aws" ]]
+      - name: Authenticate to aws
+        uses: aws-actions/configure-aws-credentials@v2
+        with:
+          role-to-assume: ${{ vars.AWS_ROLE_ARN }} # trusting token.actions.githubusercontent.com for this repository
+          aws-region: ${{ matrix.primary_region }}
+[[- else if eq .Cloud "azure" ]]
+      - name: Authenticate to azure
+        # the azurerm provider exchanges the github token for the federated credential of the app registration
+        run: |
+          echo "ARM_USE_OIDC=true" >> "$GITHUB_ENV"
+          echo "ARM_CLIENT_ID=${{ vars.AZURE_CLIENT_ID }}" >> "$GITHUB_ENV"
+          echo "ARM_TENANT_ID=${{ vars.AZURE_TENANT_ID }}" >> "$GITHUB_ENV"
+          echo "ARM_SUBSCRIPTION_ID=${{ vars.AZURE_SUBSCRIPTION_ID }}" >> "$GITHUB_ENV"
+          echo "ARM_OIDC_REQUEST_URL=$ACTIONS_ID_TOKEN_REQUEST_URL" >> "$GITHUB_ENV"
+          echo "ARM_OIDC_REQUEST_TOKEN=$ACTIONS_ID_TOKEN_REQUEST_TOKEN" >> "$GITHUB_ENV"
+[[- else if eq .Cloud "gcp" ]]
+      - name: Authenticate to gcp
+        # add GOOGLE_ to env_passthrough in the runiac config to forward the credentials into the container
+        uses: google-github-actions/auth@v1
+        with:
+          workload_identity_provider: ${{ vars.GCP_WORKLOAD_IDENTITY_PROVIDER }}
+          service_account: ${{ vars.GCP_SERVICE_ACCOUNT }}
+[[- else ]]
+      # Authenticate to the cloud with OIDC, see runiac gen github-actions --cloud:
+      #   aws:   aws-actions/configure-aws-credentials with role-to-assume
+      #   azure: set ARM_USE_OIDC, ARM_CLIENT_ID, ARM_TENANT_ID and ARM_SUBSCRIPTION_ID for the azurerm provider
+      #   gcp:   google-github-actions/auth with workload_identity_provider, and GOOGLE_ in env_passthrough
+[[- end ]]
+[[- end ]]
+`
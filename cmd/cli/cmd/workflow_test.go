@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestGenerateWorkflow_ShouldPlanAndDeployEveryRing(t *testing.T) {
+	defer viper.Reset()
+
+	viper.Set("project", "stub")
+	viper.Set("runner", "terraform")
+	viper.Set("primary_region", "us-east-1")
+	viper.Set("regional_regions", "us-east-1,us-west-2")
+	viper.Set("rings.prod.regional_regions", []string{"us-east-1", "eu-west-1"})
+
+	graph := projectGraph{Tracks: []graphTrack{{Name: "network"}, {Name: "app"}}}
+
+	targets, err := getPipelineTargets([]string{"dev", "prod"})
+	require.NoError(t, err)
+
+	workflow, err := generateWorkflow(graph, targets, "aws", "main")
+	require.NoError(t, err)
+	require.Contains(t, workflow, getGeneratedPipelineHeader("github-actions"))
+
+	parsed := struct {
+		Jobs map[string]struct {
+			Strategy struct {
+				Matrix struct {
+					Include []map[string]string `yaml:"include"`
+				} `yaml:"matrix"`
+			} `yaml:"strategy"`
+			Steps []struct {
+				Name string `yaml:"name"`
+				Uses string `yaml:"uses"`
+			} `yaml:"steps"`
+		} `yaml:"jobs"`
+		On struct {
+			WorkflowDispatch struct {
+				Inputs struct {
+					Track struct {
+						Options []string `yaml:"options"`
+					} `yaml:"track"`
+				} `yaml:"inputs"`
+			} `yaml:"workflow_dispatch"`
+		} `yaml:"on"`
+	}{}
+
+	require.NoError(t, yaml.Unmarshal([]byte(workflow), &parsed), workflow)
+
+	expected := []map[string]string{
+		{"ring": "dev", "environment": "dev", "primary_region": "us-east-1", "regional_regions": "us-east-1,us-west-2"},
+		{"ring": "prod", "environment": "prod", "primary_region": "us-east-1", "regional_regions": "us-east-1,eu-west-1"},
+	}
+
+	for _, job := range []string{"plan", "deploy"} {
+		require.Contains(t, parsed.Jobs, job)
+		require.Equal(t, expected, parsed.Jobs[job].Strategy.Matrix.Include)
+
+		uses := []string{}
+		for _, s := range parsed.Jobs[job].Steps {
+			uses = append(uses, s.Uses)
+		}
+
+		require.Contains(t, uses, "aws-actions/configure-aws-credentials@v2")
+	}
+
+	require.Equal(t, []string{"all", "network", "app"}, parsed.On.WorkflowDispatch.Inputs.Track.Options)
+	require.Contains(t, workflow, "--github-comment")
+}
+
+func TestGenerateWorkflow_ShouldValidateOptions(t *testing.T) {
+	defer viper.Reset()
+
+	viper.Set("runner", "arm")
+
+	targets := []pipelineTarget{{Ring: "dev", ID: "dev", Environment: "dev"}}
+
+	workflow, err := generateWorkflow(projectGraph{}, targets, "", "main")
+	require.NoError(t, err)
+	require.Contains(t, workflow, "ARM_USE_OIDC=true", "the arm runner should default to azure")
+	require.NotContains(t, workflow, "inputs:", "no track input without tracks")
+
+	_, err = generateWorkflow(projectGraph{}, targets, "oracle", "main")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "--cloud")
+}
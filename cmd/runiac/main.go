@@ -1,29 +1,47 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/optum/runiac/pkg/config"
 	"github.com/optum/runiac/pkg/logging"
+	"github.com/optum/runiac/pkg/tracing"
 	"github.com/optum/runiac/pkg/tracks"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/afero"
 
 	pluginsarm "github.com/optum/runiac/plugins/arm"
+	pluginsexternal "github.com/optum/runiac/plugins/external"
+	pluginspulumi "github.com/optum/runiac/plugins/pulumi"
 	pluginsterraform "github.com/optum/runiac/plugins/terraform"
+	pluginsterragrunt "github.com/optum/runiac/plugins/terragrunt"
 )
 
 var fs afero.Fs
 var tracker tracks.Tracker
 var deployment config.Deployment
 var log *logrus.Entry
+var tracer *tracing.Tracer
+var deploySpan *tracing.Span
 
 func main() {
+	// lets the runiac CLI detect version skew with this container without requiring a deployment config
+	if len(os.Args) > 1 && os.Args[1] == "contract" {
+		j, _ := json.Marshal(config.GetContract())
+		fmt.Println(string(j))
+		return
+	}
+
 	initFunc()
 
 	log.Debugf("Beginning Account Deployment: %s", deployment.Config.AccountID)
@@ -34,11 +52,51 @@ func main() {
 
 	log.Debug("Completed executing tracks...")
 
+	if deployment.Config.StepLogDir != "" {
+		if err := afero.WriteFile(fs, filepath.Join(deployment.Config.StepLogDir, tracks.StepLogsCompleted), []byte{}, 0644); err != nil {
+			log.WithError(err).Warn("Failed to mark the step logs complete")
+		}
+	}
+
+	if deployment.Config.ManifestPath != "" {
+		err := writeManifest(fs, deployment.Config.ManifestPath, buildManifest(deployment.Config, output))
+		if err != nil {
+			log.WithError(err).Error("Failed to write resource manifest")
+		} else {
+			log.Infof("Wrote resource manifest to %s", deployment.Config.ManifestPath)
+		}
+	}
+
+	if deployment.Config.SarifPath != "" {
+		err := writeSarifLog(fs, deployment.Config.SarifPath, buildSarifLog(deployment.Config, output))
+		if err != nil {
+			log.WithError(err).Error("Failed to write SARIF report")
+		} else {
+			log.Infof("Wrote SARIF report to %s", deployment.Config.SarifPath)
+		}
+	}
+
+	if deployment.Config.OutputsPath != "" {
+		existing, err := readOutputs(fs, deployment.Config.OutputsPath)
+		if err == nil {
+			err = writeOutputs(fs, deployment.Config.OutputsPath, updateOutputs(deployment.Config, existing, output))
+		}
+
+		if err != nil {
+			log.WithError(err).Error("Failed to record step outputs")
+		} else {
+			log.Infof("Recorded step outputs to %s", deployment.Config.OutputsPath)
+		}
+	}
+
 	trackCount := len(output.Tracks)
 	failedSteps := []string{}
 	skippedSteps := []string{}
 	skippedTracks := []string{}
 	failedDestroySteps := []string{}
+	failedHooks := []string{}
+	failedTestSteps := []string{}
+	rolledBackSteps := []string{}
 	stepCount := 0
 	executedStepCount := 0
 	failedTestCount := 0
@@ -48,6 +106,10 @@ func main() {
 			skippedTracks = append(skippedTracks, t.Name)
 		}
 
+		if t.Output.HookErr != nil {
+			failedHooks = append(failedHooks, fmt.Sprintf("%v: %v", t.Name, t.Output.HookErr))
+		}
+
 		for _, tExecution := range t.Output.Executions {
 			executedStepCount += tExecution.Output.ExecutedCount
 			stepCount += tExecution.Output.ExecutedCount + tExecution.Output.SkippedCount
@@ -60,6 +122,14 @@ func main() {
 				case config.Skipped:
 					skippedSteps = append(skippedSteps, fmt.Sprintf("%v/%v/%v/%v", t.Name, s.Name, tExecution.RegionDeployType, tExecution.Region))
 				}
+
+				if s.TestOutput.Err != nil {
+					failedTestSteps = append(failedTestSteps, fmt.Sprintf("%v/%v/%v/%v", t.Name, s.Name, tExecution.RegionDeployType, tExecution.Region))
+				}
+
+				if s.TestOutput.RolledBack {
+					rolledBackSteps = append(rolledBackSteps, fmt.Sprintf("%v/%v/%v/%v", t.Name, s.Name, tExecution.RegionDeployType, tExecution.Region))
+				}
 			}
 
 		}
@@ -72,6 +142,14 @@ func main() {
 	}
 
 	failedStepCount := len(failedSteps)
+	sort.Strings(skippedTracks)
+	sort.Strings(failedHooks)
+	sort.Strings(failedTestSteps)
+	sort.Strings(rolledBackSteps)
+
+	if output.HookErr != nil {
+		failedHooks = append(failedHooks, output.HookErr.Error())
+	}
 
 	resultMessage := fmt.Sprintf("Executed %v/%v steps successfully with %v test failure(s) across %v track(s).",
 		executedStepCount-failedStepCount, stepCount, failedTestCount, trackCount-len(skippedTracks))
@@ -93,6 +171,74 @@ func main() {
 		result = "fail"
 	}
 
+	if len(failedTestSteps) > 0 {
+		resultMessage += fmt.Sprintf("  Failed tests: %v.", strings.Join(failedTestSteps, ", "))
+	}
+
+	// a rolled back step is no longer deployed
+	if len(rolledBackSteps) > 0 {
+		resultMessage += fmt.Sprintf("  Rolled back: %v.", strings.Join(rolledBackSteps, ", "))
+		result = "fail"
+	}
+
+	if output.RolledBack {
+		resultMessage += "  Rolled back the step executions of the failed deploy."
+	}
+
+	if len(failedHooks) > 0 {
+		resultMessage += fmt.Sprintf("  Failed hooks: %v.", strings.Join(failedHooks, ", "))
+		result = "fail"
+	}
+
+	if output.TeardownErr != nil {
+		resultMessage += fmt.Sprintf("  Destroy skipped: %v.", output.TeardownErr)
+		result = "fail"
+	}
+
+	if output.Err != nil {
+		resultMessage += fmt.Sprintf("  Not executed: %v.", output.Err)
+		result = "fail"
+	}
+
+	if output.ApprovalErr != nil {
+		resultMessage += fmt.Sprintf("  Not approved: %v, skipped tracks: %v.", output.ApprovalErr, strings.Join(skippedTracks, ", "))
+		result = "fail"
+	}
+
+	if tracer != nil {
+		var err error
+		if result != "success" {
+			err = errors.New(resultMessage)
+		}
+
+		deploySpan.Finish(err)
+
+		// the spans that ended are exported while the deploy runs, the remaining spans before exiting
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+
+		if err = tracer.Shutdown(ctx); err != nil {
+			log.WithError(err).Warn("Failed to export the deploy's traces")
+		} else {
+			log.Infof("Exported the deploy's traces to %s", tracer.Endpoint)
+		}
+
+		cancel()
+	}
+
+	if deployment.Config.ReportPath != "" {
+		exitCode := 0
+		if result != "success" {
+			exitCode = 1
+		}
+
+		err := writeReport(fs, deployment.Config.ReportPath, buildReport(deployment.Config, output, result, resultMessage, exitCode))
+		if err != nil {
+			log.WithError(err).Error("Failed to write deployment report")
+		} else {
+			log.Infof("Wrote deployment report to %s", deployment.Config.ReportPath)
+		}
+	}
+
 	slog := log.WithFields(logrus.Fields{
 		"type":          "summary",
 		"skipped":       strings.Join(skippedSteps, ","),
@@ -110,24 +256,22 @@ func main() {
 }
 
 func initFunc() {
-	// Log as JSON instead of the default ASCII formatter.
+	// Log as JSON instead of the default ASCII formatter, LOG_FORMAT is read before the configuration so
+	// configuration errors are formatted as well
 	logger := logrus.New()
-	if os.Getenv("LOG_FORMAT") == "JSON" {
-		logger.SetFormatter(&logrus.JSONFormatter{
-			TimestampFormat: time.RFC3339Nano,
-		})
-	} else {
-		logger.SetFormatter(&logging.RuniacFormatter{
-			DisableColors: os.Getenv("LOG_DISABLE_COLORS") == "true",
-		})
+	disableColors := os.Getenv("LOG_DISABLE_COLORS") == "true"
+
+	formatter, err := logging.NewFormatter(os.Getenv("LOG_FORMAT"), disableColors)
+	if err != nil {
+		formatter, _ = logging.NewFormatter("text", disableColors)
 	}
+
+	logger.SetFormatter(formatter)
 	logger.SetReportCaller(true)
 	log = logrus.NewEntry(logger)
 
 	deployment = config.Deployment{}
 
-	var err error
-
 	fs = afero.NewOsFs()
 
 	deployment.Config, err = config.GetConfig()
@@ -136,6 +280,15 @@ func initFunc() {
 		log.WithError(err).Fatal(err.Error())
 	}
 
+	if deployment.Config.LogFormat != "" {
+		formatter, err = logging.NewFormatter(deployment.Config.LogFormat, disableColors)
+		if err != nil {
+			log.WithError(err).Fatal(err.Error())
+		}
+
+		logger.SetFormatter(formatter)
+	}
+
 	// Only log the warning severity or above.
 	lvl, err := logrus.ParseLevel(deployment.Config.LogLevel)
 
@@ -175,14 +328,46 @@ func initFunc() {
 
 	log.Infof("Parsed configuration: %s", string(j))
 
+	// persist the resolved configuration alongside the run artifacts for later pipeline stages
+	if deployment.Config.OutputDir != "" {
+		_ = fs.MkdirAll(deployment.Config.OutputDir, 0755)
+
+		err = afero.WriteFile(fs, filepath.Join(deployment.Config.OutputDir, "config.json"), j, 0644)
+		if err != nil {
+			log.WithError(err).Warn("Failed to write resolved configuration to output directory")
+		}
+	}
+
 	log = log.WithFields(logrus.Fields{
 		"uniqueExternalExecutionID": deployment.Config.UniqueExternalExecutionID,
 	})
 
+	if endpoint := tracing.GetEndpoint(deployment.Config.TracingEndpoint); endpoint != "" {
+		var err error
+
+		if tracer, err = tracing.NewTracer(endpoint); err != nil {
+			log.WithError(err).Warn("Failed to trace the deploy")
+		} else {
+			log.Infof("Tracing the deploy to %s", endpoint)
+		}
+	}
+
+	// the deploy continues the trace of the CI job when it propagates one
+	deploySpan = tracer.StartRoot("deploy", os.Getenv("TRACEPARENT"),
+		"runiac.project", deployment.Config.Project,
+		"runiac.deployment_ring", deployment.Config.DeploymentRing,
+		"runiac.environment", deployment.Config.Environment,
+		"runiac.namespace", deployment.Config.Namespace,
+		"runiac.version", deployment.Config.Version,
+		"runiac.account_id", deployment.Config.AccountID,
+		"runiac.dry_run", strconv.FormatBool(deployment.Config.DryRun),
+	)
+
 	// init tracker last to ensure log configuration is set correctly
 	tracker = tracks.DirectoryBasedTracker{
-		Log: log,
-		Fs:  fs,
+		Log:  log,
+		Fs:   fs,
+		Span: deploySpan,
 	}
 
 	// initialize the runner plugin
@@ -193,15 +378,64 @@ func initFunc() {
 	}
 
 	plugin.Initialize(log)
+
+	if deployment.Config.PrePullProviders {
+		prePullProviders(plugin, deployment.Config)
+	}
+}
+
+// prePullProviders downloads the providers of every targeted step up front so network bound work is
+// observable separately from the deployment itself
+func prePullProviders(plugin config.RunnerPlugin, cfg config.Config) {
+	puller, ok := plugin.(config.ProviderPuller)
+	if !ok {
+		log.Warnf("The %s runner does not support pre-pulling providers", cfg.Runner)
+		return
+	}
+
+	dirs := []string{}
+
+	for _, t := range tracker.GatherTracks(cfg) {
+		for _, stepsAtLevel := range t.OrderedSteps {
+			for _, s := range stepsAtLevel {
+				dirs = append(dirs, s.Dir)
+
+				if s.RegionalResourcesExist {
+					dirs = append(dirs, filepath.Join(s.Dir, "regional"))
+				}
+			}
+		}
+	}
+
+	start := time.Now()
+
+	err := puller.PullProviders(log, dirs)
+
+	plog := log.WithField("duration", time.Since(start).String())
+
+	if err != nil {
+		plog.WithError(err).Warn("Pre-pulling providers did not complete, steps will retry during init")
+		return
+	}
+
+	plog.Infof("Pre-pulled providers for %d step directories", len(dirs))
 }
 
-func getRunnerPlugin(config config.Config) (config.RunnerPlugin, error) {
-	switch config.Runner {
+func getRunnerPlugin(cfg config.Config) (config.RunnerPlugin, error) {
+	switch cfg.Runner {
 	case "arm":
 		return pluginsarm.ArmPlugin{}, nil
 	case "terraform":
 		return pluginsterraform.TerraformPlugin{}, nil
+	case "pulumi":
+		return pluginspulumi.PulumiPlugin{}, nil
+	case "terragrunt":
+		return pluginsterragrunt.TerragruntPlugin{}, nil
 	default:
+		if _, err := exec.LookPath(config.ExternalRunnerBinary(cfg.Runner)); err == nil {
+			return pluginsexternal.ExternalPlugin{Binary: config.ExternalRunnerBinary(cfg.Runner)}, nil
+		}
+
 		return nil, errors.New("Invalid runner")
 	}
 }
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/pkg/tracks"
+	"github.com/spf13/afero"
+)
+
+// ResourceManifest describes the resources managed by a deployment, for use by asset inventories
+type ResourceManifest struct {
+	Project        string             `json:"project"`
+	Version        string             `json:"version"`
+	AccountID      string             `json:"account_id"`
+	DeploymentRing string             `json:"deployment_ring"`
+	Environment    string             `json:"environment"`
+	Namespace      string             `json:"namespace"`
+	CreatedAt      time.Time          `json:"created_at"`
+	Resources      []ManifestResource `json:"resources"`
+}
+
+// ManifestResource is a resource managed by a step execution
+type ManifestResource struct {
+	config.ManagedResource
+	Track            string `json:"track"`
+	Step             string `json:"step"`
+	RegionDeployType string `json:"region_deploy_type"`
+	Region           string `json:"region"`
+	DeploymentRing   string `json:"deployment_ring"`
+	AccountID        string `json:"account_id"`
+}
+
+// buildManifest collects the resources managed by every step executed in the stage
+func buildManifest(cfg config.Config, output tracks.Stage) ResourceManifest {
+	manifest := ResourceManifest{
+		Project:        cfg.Project,
+		Version:        cfg.Version,
+		AccountID:      cfg.AccountID,
+		DeploymentRing: cfg.DeploymentRing,
+		Environment:    cfg.Environment,
+		Namespace:      cfg.Namespace,
+		CreatedAt:      time.Now().UTC(),
+		Resources:      []ManifestResource{},
+	}
+
+	for _, t := range output.Tracks {
+		for _, tExecution := range t.Output.Executions {
+			for _, s := range tExecution.Output.Steps {
+				for _, r := range s.Output.ManagedResources {
+					manifest.Resources = append(manifest.Resources, ManifestResource{
+						ManagedResource:  r,
+						Track:            t.Name,
+						Step:             s.Name,
+						RegionDeployType: tExecution.RegionDeployType.String(),
+						Region:           tExecution.Region,
+						DeploymentRing:   cfg.DeploymentRing,
+						AccountID:        cfg.AccountID,
+					})
+				}
+			}
+		}
+	}
+
+	sort.Slice(manifest.Resources, func(i, j int) bool {
+		a, b := manifest.Resources[i], manifest.Resources[j]
+
+		if a.Track != b.Track {
+			return a.Track < b.Track
+		}
+
+		if a.Step != b.Step {
+			return a.Step < b.Step
+		}
+
+		if a.RegionDeployType+a.Region != b.RegionDeployType+b.Region {
+			return a.RegionDeployType+a.Region < b.RegionDeployType+b.Region
+		}
+
+		return a.Address < b.Address
+	})
+
+	return manifest
+}
+
+// writeManifest writes the manifest as json to path
+func writeManifest(fs afero.Fs, path string, manifest ResourceManifest) error {
+	b, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = fs.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	return afero.WriteFile(fs, path, b, 0644)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/pkg/tracks"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildManifest_ShouldIncludeResourcesOfEveryStepExecution(t *testing.T) {
+	cfg := config.Config{
+		AccountID:      "123",
+		DeploymentRing: "prod",
+		Environment:    "prod",
+	}
+
+	output := tracks.Stage{
+		Tracks: map[string]tracks.Track{
+			"network": {
+				Name: "network",
+				Output: tracks.Output{
+					Executions: []tracks.RegionExecution{
+						{
+							Region:           "eastus",
+							RegionDeployType: config.RegionalRegionDeployType,
+							Output: tracks.ExecutionOutput{
+								Steps: map[string]config.Step{
+									"vnet": {Name: "vnet", Output: config.StepOutput{ManagedResources: []config.ManagedResource{{Address: "b.vnet", ID: "2"}}}},
+								},
+							},
+						},
+						{
+							Region:           "centralus",
+							RegionDeployType: config.PrimaryRegionDeployType,
+							Output: tracks.ExecutionOutput{
+								Steps: map[string]config.Step{
+									"vnet": {Name: "vnet", Output: config.StepOutput{ManagedResources: []config.ManagedResource{{Address: "a.vnet", ID: "1"}}}},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	manifest := buildManifest(cfg, output)
+
+	require.Len(t, manifest.Resources, 2)
+	require.Equal(t, "a.vnet", manifest.Resources[0].Address)
+	require.Equal(t, "centralus", manifest.Resources[0].Region)
+	require.Equal(t, "prod", manifest.Resources[0].DeploymentRing)
+	require.Equal(t, "123", manifest.Resources[1].AccountID)
+
+	memFs := afero.NewMemMapFs()
+	require.NoError(t, writeManifest(memFs, "/runiac/manifest/manifest.json", manifest))
+
+	b, err := afero.ReadFile(memFs, "/runiac/manifest/manifest.json")
+	require.NoError(t, err)
+
+	written := ResourceManifest{}
+	require.NoError(t, json.Unmarshal(b, &written))
+	require.Len(t, written.Resources, 2)
+}
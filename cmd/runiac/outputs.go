@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/pkg/tracks"
+	"github.com/spf13/afero"
+)
+
+// StepOutputs are the outputs of a step execution recorded by the last deploy, for runiac output
+type StepOutputs struct {
+	Track            string                 `json:"track"`
+	Step             string                 `json:"step"`
+	RegionDeployType string                 `json:"region_deploy_type"`
+	Region           string                 `json:"region"`
+	AccountID        string                 `json:"account_id"`
+	Environment      string                 `json:"environment"`
+	Namespace        string                 `json:"namespace"`
+	Version          string                 `json:"version"`
+	UpdatedAt        time.Time              `json:"updated_at"`
+	Outputs          map[string]interface{} `json:"outputs"`
+	Sensitive        []string               `json:"sensitive,omitempty"` // The outputs marked sensitive, masked by runiac output
+}
+
+// key identifies the step execution, a later deploy of the same execution replaces its outputs
+func (o StepOutputs) key() string {
+	return filepath.Join(o.AccountID, o.Environment, o.Namespace, o.Track, o.Step, o.RegionDeployType, o.Region)
+}
+
+// readOutputs returns the step outputs recorded in path, none when it does not exist
+func readOutputs(fs afero.Fs, path string) ([]StepOutputs, error) {
+	outputs := []StepOutputs{}
+
+	b, err := afero.ReadFile(fs, path)
+	if os.IsNotExist(err) {
+		return outputs, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(b, &outputs)
+
+	return outputs, err
+}
+
+// updateOutputs records the outputs of the steps deployed in the stage and removes the outputs of destroyed steps.
+// Dry runs and destroys only planning the steps do not change the recorded outputs.
+func updateOutputs(cfg config.Config, existing []StepOutputs, output tracks.Stage) []StepOutputs {
+	byKey := map[string]StepOutputs{}
+	for _, o := range existing {
+		byKey[o.key()] = o
+	}
+
+	if cfg.DryRun {
+		return existing
+	}
+
+	now := time.Now().UTC()
+
+	for _, t := range output.Tracks {
+		for _, tExecution := range t.Output.Executions {
+			for _, s := range tExecution.Output.Steps {
+				if cfg.Destroy || s.Output.Status != config.Success {
+					continue
+				}
+
+				o := newStepOutputs(cfg, t.Name, s.Name, tExecution)
+				o.UpdatedAt = now
+				o.Outputs = s.Output.OutputVariables
+				o.Sensitive = s.Output.SensitiveOutputs
+
+				if o.Outputs == nil {
+					o.Outputs = map[string]interface{}{}
+				}
+
+				byKey[o.key()] = o
+			}
+		}
+
+		for _, tExecution := range t.DestroyOutput.Executions {
+			for _, s := range tExecution.Output.Steps {
+				if s.Output.Status == config.Success {
+					delete(byKey, newStepOutputs(cfg, t.Name, s.Name, tExecution).key())
+				}
+			}
+		}
+	}
+
+	updated := []StepOutputs{}
+	for _, o := range byKey {
+		updated = append(updated, o)
+	}
+
+	sort.Slice(updated, func(i, j int) bool { return updated[i].key() < updated[j].key() })
+
+	return updated
+}
+
+func newStepOutputs(cfg config.Config, track string, step string, tExecution tracks.RegionExecution) StepOutputs {
+	return StepOutputs{
+		Track:            track,
+		Step:             step,
+		RegionDeployType: tExecution.RegionDeployType.String(),
+		Region:           tExecution.Region,
+		AccountID:        cfg.AccountID,
+		Environment:      cfg.Environment,
+		Namespace:        cfg.Namespace,
+		Version:          cfg.Version,
+	}
+}
+
+// writeOutputs writes the step outputs as json to path
+func writeOutputs(fs afero.Fs, path string, outputs []StepOutputs) error {
+	b, err := json.MarshalIndent(outputs, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = fs.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	return afero.WriteFile(fs, path, b, 0600)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/pkg/tracks"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateOutputs_ShouldRecordDeployedAndRemoveDestroyedSteps(t *testing.T) {
+	cfg := config.Config{AccountID: "123", Environment: "dev", Version: "v1.0.0"}
+
+	executions := func(steps map[string]config.Step) []tracks.RegionExecution {
+		return []tracks.RegionExecution{{
+			Region:           "centralus",
+			RegionDeployType: config.PrimaryRegionDeployType,
+			Output:           tracks.ExecutionOutput{Steps: steps},
+		}}
+	}
+
+	deployed := tracks.Stage{
+		Tracks: map[string]tracks.Track{
+			"network": {
+				Name: "network",
+				Output: tracks.Output{Executions: executions(map[string]config.Step{
+					"vnet":   {Name: "vnet", Output: config.StepOutput{Status: config.Success, OutputVariables: map[string]interface{}{"vnet_id": "stub-vnet", "vnet_key": "stub-key"}, SensitiveOutputs: []string{"vnet_key"}}},
+					"subnet": {Name: "subnet", Output: config.StepOutput{Status: config.Fail, OutputVariables: map[string]interface{}{"subnet_id": "stub-subnet"}}},
+				})},
+			},
+		},
+	}
+
+	memFs := afero.NewMemMapFs()
+	path := "/runiac/tfstate/outputs.json"
+
+	existing, err := readOutputs(memFs, path)
+	require.NoError(t, err)
+	require.Empty(t, existing)
+
+	require.NoError(t, writeOutputs(memFs, path, updateOutputs(cfg, existing, deployed)))
+
+	recorded, err := readOutputs(memFs, path)
+	require.NoError(t, err)
+	require.Len(t, recorded, 1, "failed steps are not recorded")
+	require.Equal(t, "vnet", recorded[0].Step)
+	require.Equal(t, "stub-vnet", recorded[0].Outputs["vnet_id"])
+	require.Equal(t, []string{"vnet_key"}, recorded[0].Sensitive, "the sensitive outputs are recorded for runiac output to mask")
+	require.Equal(t, "v1.0.0", recorded[0].Version)
+
+	dryRun := cfg
+	dryRun.DryRun = true
+	require.Equal(t, recorded, updateOutputs(dryRun, recorded, tracks.Stage{}))
+
+	destroyed := tracks.Stage{
+		Tracks: map[string]tracks.Track{
+			"network": {
+				Name:          "network",
+				Output:        tracks.Output{Executions: executions(map[string]config.Step{"vnet": {Name: "vnet", Output: config.StepOutput{Status: config.Success}}})},
+				DestroyOutput: tracks.Output{Executions: executions(map[string]config.Step{"vnet": {Name: "vnet", Output: config.StepOutput{Status: config.Success}}})},
+			},
+		},
+	}
+
+	destroy := cfg
+	destroy.Destroy = true
+	require.Empty(t, updateOutputs(destroy, recorded, destroyed))
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/pkg/tracks"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+// DeploymentReport summarizes the result of a deployment for ci pipelines
+type DeploymentReport struct {
+	Project        string       `json:"project" yaml:"project"`
+	Version        string       `json:"version" yaml:"version"`
+	AccountID      string       `json:"account_id" yaml:"account_id"`
+	DeploymentRing string       `json:"deployment_ring" yaml:"deployment_ring"`
+	Environment    string       `json:"environment" yaml:"environment"`
+	Namespace      string       `json:"namespace" yaml:"namespace"`
+	DryRun         bool         `json:"dry_run" yaml:"dry_run"`
+	CreatedAt      time.Time    `json:"created_at" yaml:"created_at"`
+	Result         string       `json:"result" yaml:"result"`
+	ExitCode       int          `json:"exit_code" yaml:"exit_code"`
+	Message        string       `json:"message" yaml:"message"`
+	RolledBack     bool         `json:"rolled_back,omitempty" yaml:"rolled_back,omitempty"` // The deploy failed and the step executions it applied were destroyed
+	Steps          []ReportStep `json:"steps" yaml:"steps"`
+}
+
+// ReportStep is the result of a step execution in a region
+type ReportStep struct {
+	Track            string                   `json:"track" yaml:"track"`
+	Step             string                   `json:"step" yaml:"step"`
+	Action           string                   `json:"action" yaml:"action"` // deploy or destroy
+	RegionDeployType string                   `json:"region_deploy_type" yaml:"region_deploy_type"`
+	Region           string                   `json:"region" yaml:"region"`
+	Status           string                   `json:"status" yaml:"status"`
+	DurationSeconds  float64                  `json:"duration_seconds" yaml:"duration_seconds"`
+	ResourceChanges  config.ResourceChanges   `json:"resource_changes" yaml:"resource_changes"`
+	CostEstimate     *config.CostEstimate     `json:"cost_estimate,omitempty" yaml:"cost_estimate,omitempty"`
+	Findings         []config.Finding         `json:"findings,omitempty" yaml:"findings,omitempty"`
+	DriftedResources []config.DriftedResource `json:"drifted_resources,omitempty" yaml:"drifted_resources,omitempty"`
+	Error            string                   `json:"error,omitempty" yaml:"error,omitempty"`
+	Tests            string                   `json:"tests,omitempty" yaml:"tests,omitempty"` // passed or failed, empty when the step has no tests or they did not run
+	Assertions       []config.AssertionResult `json:"assertions,omitempty" yaml:"assertions,omitempty"`
+	RolledBack       bool                     `json:"rolled_back,omitempty" yaml:"rolled_back,omitempty"`
+	TestError        string                   `json:"test_error,omitempty" yaml:"test_error,omitempty"`
+}
+
+// buildReport summarizes every step executed in the stage along with the result of the deployment
+func buildReport(cfg config.Config, output tracks.Stage, result string, message string, exitCode int) DeploymentReport {
+	report := DeploymentReport{
+		Project:        cfg.Project,
+		Version:        cfg.Version,
+		AccountID:      cfg.AccountID,
+		DeploymentRing: cfg.DeploymentRing,
+		Environment:    cfg.Environment,
+		Namespace:      cfg.Namespace,
+		DryRun:         cfg.DryRun,
+		CreatedAt:      time.Now().UTC(),
+		Result:         result,
+		ExitCode:       exitCode,
+		Message:        message,
+		RolledBack:     output.RolledBack,
+		Steps:          []ReportStep{},
+	}
+
+	add := func(trackName string, action string, executions []tracks.RegionExecution) {
+		for _, tExecution := range executions {
+			for _, s := range tExecution.Output.Steps {
+				step := ReportStep{
+					Track:            trackName,
+					Step:             s.Name,
+					Action:           action,
+					RegionDeployType: tExecution.RegionDeployType.String(),
+					Region:           tExecution.Region,
+					Status:           s.Output.Status.String(),
+					DurationSeconds:  s.Output.Duration.Seconds(),
+					ResourceChanges:  s.Output.ResourceChanges,
+					CostEstimate:     s.Output.CostEstimate,
+					Findings:         s.Output.Findings,
+					DriftedResources: s.Output.DriftedResources,
+				}
+
+				if s.Output.Err != nil {
+					step.Error = s.Output.Err.Error()
+				}
+
+				if s.TestOutput.StepName != "" {
+					step.Tests = "passed"
+					step.Assertions = s.TestOutput.Assertions
+					step.RolledBack = s.TestOutput.RolledBack
+				}
+
+				if s.TestOutput.Err != nil {
+					step.Tests = "failed"
+					step.TestError = s.TestOutput.Err.Error()
+				}
+
+				report.Steps = append(report.Steps, step)
+			}
+		}
+	}
+
+	for _, t := range output.Tracks {
+		add(t.Name, "deploy", t.Output.Executions)
+		add(t.Name, "destroy", t.DestroyOutput.Executions)
+	}
+
+	sort.Slice(report.Steps, func(i, j int) bool {
+		a, b := report.Steps[i], report.Steps[j]
+
+		return fmt.Sprintf("%s/%s/%s/%s/%s", a.Action, a.Track, a.Step, a.RegionDeployType, a.Region) <
+			fmt.Sprintf("%s/%s/%s/%s/%s", b.Action, b.Track, b.Step, b.RegionDeployType, b.Region)
+	})
+
+	return report
+}
+
+// writeReport writes the report to path, as yaml when the extension is .yml or .yaml and json otherwise
+func writeReport(fs afero.Fs, path string, report DeploymentReport) error {
+	var b []byte
+	var err error
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yml", ".yaml":
+		b, err = yaml.Marshal(report)
+	default:
+		b, err = json.MarshalIndent(report, "", "  ")
+	}
+
+	if err != nil {
+		return err
+	}
+
+	err = fs.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	return afero.WriteFile(fs, path, b, 0644)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/pkg/tracks"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestBuildReport_ShouldSummarizeEveryStepExecution(t *testing.T) {
+	output := tracks.Stage{
+		Tracks: map[string]tracks.Track{
+			"network": {
+				Name: "network",
+				Output: tracks.Output{
+					Executions: []tracks.RegionExecution{
+						{
+							Region:           "centralus",
+							RegionDeployType: config.RegionalRegionDeployType,
+							Output: tracks.ExecutionOutput{
+								Steps: map[string]config.Step{
+									"vnet": {Name: "vnet", Output: config.StepOutput{
+										Status:          config.Success,
+										Duration:        90 * time.Second,
+										ResourceChanges: config.ResourceChanges{Add: 2, Change: 1},
+										CostEstimate:    &config.CostEstimate{Currency: "USD", MonthlyCost: 30, MonthlyCostDelta: 30},
+									}, TestOutput: config.StepTestOutput{
+										StepName:   "vnet",
+										Err:        errors.New("1 of 1 assertion(s) failed: gateway"),
+										Assertions: []config.AssertionResult{{Name: "gateway", Message: "connection refused"}},
+										RolledBack: true,
+									}},
+									"dns": {Name: "dns", Output: config.StepOutput{Status: config.Fail, Err: errors.New("apply failed")}},
+								},
+							},
+						},
+					},
+				},
+				DestroyOutput: tracks.Output{
+					Executions: []tracks.RegionExecution{
+						{
+							Region:           "centralus",
+							RegionDeployType: config.PrimaryRegionDeployType,
+							Output: tracks.ExecutionOutput{
+								Steps: map[string]config.Step{
+									"vnet": {Name: "vnet", Output: config.StepOutput{Status: config.Na}},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	report := buildReport(config.Config{Project: "stub", DeploymentRing: "dev"}, output, "fail", "Executed 1/2 steps", 1)
+
+	require.Equal(t, "fail", report.Result)
+	require.Equal(t, 1, report.ExitCode)
+	require.Equal(t, []ReportStep{
+		{Track: "network", Step: "dns", Action: "deploy", RegionDeployType: "regional", Region: "centralus", Status: "FAIL", Error: "apply failed"},
+		{Track: "network", Step: "vnet", Action: "deploy", RegionDeployType: "regional", Region: "centralus", Status: "SUCCESS", DurationSeconds: 90, ResourceChanges: config.ResourceChanges{Add: 2, Change: 1}, CostEstimate: &config.CostEstimate{Currency: "USD", MonthlyCost: 30, MonthlyCostDelta: 30},
+			Tests: "failed", Assertions: []config.AssertionResult{{Name: "gateway", Message: "connection refused"}}, RolledBack: true, TestError: "1 of 1 assertion(s) failed: gateway"},
+		{Track: "network", Step: "vnet", Action: "destroy", RegionDeployType: "primary", Region: "centralus", Status: "NA"},
+	}, report.Steps)
+}
+
+func TestWriteReport_ShouldWriteJSONOrYAMLByExtension(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	report := DeploymentReport{Project: "stub", Result: "success", Steps: []ReportStep{{Track: "network", Step: "vnet", ResourceChanges: config.ResourceChanges{Destroy: 1}}}}
+
+	require.NoError(t, writeReport(fs, "/reports/report.json", report))
+	require.NoError(t, writeReport(fs, "/reports/report.yaml", report))
+
+	b, err := afero.ReadFile(fs, "/reports/report.json")
+	require.NoError(t, err)
+
+	fromJSON := DeploymentReport{}
+	require.NoError(t, json.Unmarshal(b, &fromJSON))
+	require.Equal(t, report, fromJSON)
+
+	b, err = afero.ReadFile(fs, "/reports/report.yaml")
+	require.NoError(t, err)
+	require.Contains(t, string(b), "resource_changes:")
+
+	fromYAML := DeploymentReport{}
+	require.NoError(t, yaml.Unmarshal(b, &fromYAML))
+	require.Equal(t, report, fromYAML)
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/pkg/tracks"
+	"github.com/spf13/afero"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// sarifLog is the minimal subset of the SARIF 2.1.0 format needed to report step findings
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID     string                 `json:"ruleId"`
+	Level      string                 `json:"level"`
+	Message    sarifMessage           `json:"message"`
+	Locations  []sarifLocation        `json:"locations,omitempty"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine,omitempty"`
+	StartColumn int `json:"startColumn,omitempty"`
+	EndLine     int `json:"endLine,omitempty"`
+	EndColumn   int `json:"endColumn,omitempty"`
+}
+
+// buildSarifLog converts the findings of every step executed in the stage into a SARIF log
+func buildSarifLog(cfg config.Config, output tracks.Stage) sarifLog {
+	type keyedResult struct {
+		key    string
+		result sarifResult
+	}
+
+	keyed := []keyedResult{}
+	ruleIDs := map[string]bool{}
+
+	for _, t := range output.Tracks {
+		for _, tExecution := range t.Output.Executions {
+			for _, s := range tExecution.Output.Steps {
+				for _, f := range s.Output.Findings {
+					ruleIDs[f.RuleID] = true
+					keyed = append(keyed, keyedResult{
+						key:    fmt.Sprintf("%s/%s/%s/%s", t.Name, s.Name, tExecution.RegionDeployType, tExecution.Region),
+						result: newSarifResult(f, t.Name, s.Name, tExecution),
+					})
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(keyed, func(i, j int) bool { return keyed[i].key < keyed[j].key })
+
+	results := []sarifResult{}
+	for _, k := range keyed {
+		results = append(results, k.result)
+	}
+
+	rules := []sarifRule{}
+	for id := range ruleIDs {
+		rules = append(rules, sarifRule{ID: id})
+	}
+
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+
+	return sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs: []sarifRun{
+			{
+				Tool: sarifTool{
+					Driver: sarifDriver{
+						Name:           "runiac",
+						Version:        cfg.Version,
+						InformationURI: "https://runiac.io",
+						Rules:          rules,
+					},
+				},
+				Results: results,
+			},
+		},
+	}
+}
+
+func newSarifResult(f config.Finding, trackName string, stepName string, execution tracks.RegionExecution) sarifResult {
+	result := sarifResult{
+		RuleID:  f.RuleID,
+		Level:   string(f.Level),
+		Message: sarifMessage{Text: f.Message},
+		Properties: map[string]interface{}{
+			"step_id":            trackName + "/" + stepName,
+			"region_deploy_type": execution.RegionDeployType.String(),
+			"region":             execution.Region,
+		},
+	}
+
+	if f.File != "" {
+		location := sarifLocation{
+			PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: f.File},
+			},
+		}
+
+		if f.StartLine > 0 {
+			location.PhysicalLocation.Region = &sarifRegion{
+				StartLine:   f.StartLine,
+				StartColumn: f.StartColumn,
+				EndLine:     f.EndLine,
+				EndColumn:   f.EndColumn,
+			}
+		}
+
+		result.Locations = []sarifLocation{location}
+	}
+
+	return result
+}
+
+// writeSarifLog writes the SARIF log as json to path
+func writeSarifLog(fs afero.Fs, path string, log sarifLog) error {
+	b, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = fs.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	return afero.WriteFile(fs, path, b, 0644)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/pkg/tracks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildSarifLog_ShouldReportFindingsWithLocations(t *testing.T) {
+	output := tracks.Stage{
+		Tracks: map[string]tracks.Track{
+			"network": {
+				Name: "network",
+				Output: tracks.Output{
+					Executions: []tracks.RegionExecution{
+						{
+							Region:           "centralus",
+							RegionDeployType: config.PrimaryRegionDeployType,
+							Output: tracks.ExecutionOutput{
+								Steps: map[string]config.Step{
+									"vnet": {Name: "vnet", Output: config.StepOutput{Findings: []config.Finding{
+										{RuleID: "terraform/validate", Level: config.FindingError, Message: "Unsupported argument", File: "tracks/network/step1_vnet/main.tf", StartLine: 3},
+										{RuleID: "terraform/validate", Level: config.FindingWarning, Message: "Deprecated"},
+									}}},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	log := buildSarifLog(config.Config{Version: "v1.0.0"}, output)
+
+	require.Equal(t, "2.1.0", log.Version)
+	require.Len(t, log.Runs, 1)
+	require.Len(t, log.Runs[0].Tool.Driver.Rules, 1)
+
+	results := log.Runs[0].Results
+	require.Len(t, results, 2)
+	require.Equal(t, "error", results[0].Level)
+	require.Equal(t, "tracks/network/step1_vnet/main.tf", results[0].Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	require.Equal(t, 3, results[0].Locations[0].PhysicalLocation.Region.StartLine)
+	require.Empty(t, results[1].Locations, "findings without a file should not have a location")
+	require.Equal(t, "network/vnet", results[1].Properties["step_id"])
+}
@@ -7,18 +7,27 @@ require (
 	github.com/briandowns/spinner v1.11.1
 	github.com/go-errors/errors v1.0.1
 	github.com/go-playground/validator/v10 v10.1.0
-	github.com/golang/mock v1.3.1
+	github.com/golang/mock v1.4.4
 	github.com/gookit/color v1.4.2
 	github.com/gruntwork-io/gruntwork-cli v0.4.2
 	github.com/gruntwork-io/terratest v0.17.5
 	github.com/hashicorp/go-getter v1.5.2
+	github.com/hashicorp/hcl v1.0.0
 	github.com/otiai10/copy v1.4.2
 	github.com/sirupsen/logrus v1.4.2
 	github.com/spf13/afero v1.2.2
 	github.com/spf13/cobra v1.1.1
+	github.com/spf13/pflag v1.0.5
 	github.com/spf13/viper v1.7.0
-	github.com/stretchr/testify v1.7.0
+	github.com/stretchr/testify v1.7.1
 	github.com/urfave/cli v1.22.1 // indirect
+	go.opentelemetry.io/otel v1.7.0
+	go.opentelemetry.io/otel/exporters/otlp/otlptrace v1.7.0
+	go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc v1.7.0
+	go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp v1.7.0
+	go.opentelemetry.io/otel/sdk v1.7.0
+	go.opentelemetry.io/otel/trace v1.7.0
+	golang.org/x/crypto v0.0.0-20200622213623-75b288015ac9
 	golang.org/x/sys v0.0.0-20210514084401-e8d321eab015 // indirect
-	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b // indirect
+	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b
 )
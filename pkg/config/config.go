@@ -4,8 +4,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
 	"github.com/spf13/viper"
 	"net/http"
+	"os/exec"
+	"strings"
 	"time"
 
 	"github.com/go-playground/validator/v10"
@@ -13,6 +16,11 @@ import (
 
 var httpClient = &http.Client{Timeout: 10 * time.Second}
 
+const (
+	LocalDeploymentRing       = "local" // The deployment ring of the runiac CLI's --local
+	PullRequestDeploymentRing = "pr"    // The deployment ring of the runiac CLI's --pull-request
+)
+
 // use a single instance of Validate, it caches struct info
 var validate = validator.New()
 
@@ -22,28 +30,175 @@ type Config struct {
 	AccountID       string   `mapstructure:"account_id"`       // The cloud account id to deploy to (AWS Account, Azure Subscription or GCP Project)
 	TargetAccountID string   `mapstructure:"account_id"`       // The target account being deployed to using the delivery framework (use ACCOUNT_ID env for compatibility)
 	RegionalRegions []string `mapstructure:"regional_regions"` // runiac will apply regional step deployments across these regions
+	PrimaryRegions  []string `mapstructure:"primary_regions"`  // runiac will apply primary step deployments across these regions, defaults to the primary region
 	PrimaryRegion   string   `mapstructure:"primary_region" required:"true"`
 	DryRun          bool     `mapstructure:"dry_run"` // DryRun will only execute up to Terraform plan, describing what will happen if deployed
-	Runner          string   `mapstructure:runner`    // Delivery framework to invoke for executing steps
+	Runner          string   `mapstructure:"runner"`  // Delivery framework to invoke for executing steps
 
 	UniqueExternalExecutionID string
 	DeploymentRing            string `mapstructure:"deployment_ring"`
 	SelfDestroy               bool   `mapstructure:"self_destroy"` // Destroy will automatically execute Terraform Destroy after running deployments & tests
+	Destroy                   bool   `mapstructure:"destroy"`      // Destroy previously deployed resources without deploying, the steps are only planned to read the output variables the destroy depends on
 	RegionGroup               string
 	StepWhitelist             []string        `mapstructure:"step_whitelist"` // Target_Steps is a comma separated list of step ids to reflect the whitelisted steps to be executed, e.g. core#logging#final_destination_bucket, core#logging#bridge_azu
+	TeardownOrder             []string        `mapstructure:"teardown_order"` // The {trackName}/{stepName} ids of the steps in the order SelfDestroy destroys them, instead of the reverse progression order of each track
 	TargetAll                 bool            // This is a global whitelist and overrules targeted tracks and targeted steps, primarily for dev and testing
 	Version                   string          `mapstructure:"version"` // Version override
 	MaxRetries                int             `mapstructure:"max_retries"`
 	MaxTestRetries            int             `mapstructure:"max_test_retries"`
 	LogLevel                  string          `mapstructure:"log_level"`
+	LogFormat                 string          `mapstructure:"log_format"` // json for structured logs with track, step and region fields, otherwise text
 	CoreAccounts              CoreAccountsMap `mapstructure:"core_accounts"`
 	RegionGroups              RegionGroupsMap `mapstructure:"region_grouprs"`
+	OutputDir                 string          `mapstructure:"output_dir"`                   // Directory to write run artifacts (e.g. terraform plans) to for use by later pipeline stages
+	ArtifactsFrom             string          `mapstructure:"artifacts_from"`               // Directory containing the output_dir artifacts of a previous run to reuse in this run
+	PlanSummaryThreshold      int             `mapstructure:"plan_summary_threshold"`       // Warn when a step's plan changes more resources than this, disabled when 0
+	PlanSummaryRequireConfirm bool            `mapstructure:"plan_summary_require_confirm"` // Require confirmation to apply plans exceeding the plan summary threshold
+	Confirmed                 bool            `mapstructure:"confirmed"`                    // The user has confirmed applying changes that require confirmation
+	PrePullProviders          bool            `mapstructure:"pre_pull_providers"`           // Download the runner's providers for every step before executing any step
+	ManifestPath              string          `mapstructure:"manifest_path"`                // File to write a manifest of the resources managed by each step to
+	SarifPath                 string          `mapstructure:"sarif_path"`                   // File to write a SARIF report of the validation findings of each step to
+	RunnerArgs                []string        `mapstructure:"-"`                            // Additional arguments for the runner's commands, decoded from runner_args
+	SkipRegional              bool            `mapstructure:"skip_regional"`                // Skip the regional deployments of every track, e.g. when no region's inputs changed
+	RegionalRollout           []RolloutWave   `mapstructure:"regional_rollout"`             // The waves the regional regions are deployed in, e.g. a canary region first, from runiac.yml. Every regional region at once when empty
+	SimulateIAM               bool            `mapstructure:"simulate_iam"`                 // Simulate the aws IAM permissions each step's plan requires before applying
+	CostEstimate              bool            `mapstructure:"cost_estimate"`                // Estimate the monthly cost change of each step's plan with infracost
+	PolicyDir                 string          `mapstructure:"policy_dir"`                   // Directory of the rego policies each step's plan is evaluated against with conftest before applying, disabled when empty
+	PolicySoftFail            bool            `mapstructure:"policy_soft_fail"`             // Only warn when a plan violates a policy instead of failing the step
+	MaxParallel               int             `mapstructure:"max_parallel"`                 // The maximum number of steps of a track executing concurrently in each region, unlimited when 0
+	AbortAfterFailures        int             `mapstructure:"abort_after_failures"`         // Skip the remaining regions of a track once this many of its regions failed, never skipped when 0
+	ParallelPrimaryRegions    bool            `mapstructure:"parallel_primary_regions"`     // Apply the primary step deployments across the primary regions concurrently instead of one region at a time
+	StepTimeout               time.Duration   `mapstructure:"step_timeout"`                 // How long a step may execute in a region before it is killed and fails, unlimited when 0. Overrides timeout in the step's runiac.yml when set from RUNIAC_STEP_TIMEOUT
+	StepRetries               int             `mapstructure:"step_retries"`                 // How many times a failed step is retried, the runner's max_retries are disabled when the step is retried. Overrides retries in the step's runiac.yml when set from RUNIAC_STEP_RETRIES
+	StepRetryBackoff          time.Duration   `mapstructure:"step_retry_backoff"`           // How long to wait before the first retry of a step, doubled for every further retry. Overrides retry_backoff in the step's runiac.yml when set from RUNIAC_STEP_RETRY_BACKOFF
+	OutputsPath               string          `mapstructure:"outputs_path"`                 // File recording the outputs of the deployed steps for runiac output
+	ReportPath                string          `mapstructure:"report_path"`                  // File to write a summary report of the deployment to, yaml when the extension is .yml or .yaml
+	CheckpointPath            string          `mapstructure:"checkpoint_path"`              // File recording the step executions the deploy completed, so a failed deploy can be resumed
+	Resume                    bool            `mapstructure:"resume"`                       // Do not execute the steps the checkpoint of the previous deploy of the same version, environment and namespace records as completed
+	FromStep                  string          `mapstructure:"from_step"`                    // The {trackName}/{stepName} step its track starts at, the steps of the track at a lower progression level are not executed
+	Backend                   Backend         `mapstructure:"backend"`                      // The state backend the terraform runner configures for steps without a declared backend, from runiac.yml
+	Scan                      Scan            `mapstructure:"scan"`                         // The static scan of each step's source before it is deployed, from runiac.yml
+	ScanOnly                  bool            `mapstructure:"scan_only"`                    // Only scan the source of the steps without executing them, implies scan.enabled
+	Drift                     bool            `mapstructure:"drift"`                        // Plan refresh-only to detect the resources that drifted from the state, implies dry_run
+	TracingEndpoint           string          `mapstructure:"tracing_endpoint"`             // The OTLP endpoint the deploy's spans are exported to, otherwise OTEL_EXPORTER_OTLP_ENDPOINT. OTLP/gRPC when OTEL_EXPORTER_OTLP_PROTOCOL is grpc
+	PauseBetweenTracks        bool            `mapstructure:"pause_between_tracks"`         // Deploy the tracks one at a time, waiting for an approval after each track completed
+	Interactive               bool            `mapstructure:"interactive"`                  // Approvals are confirmed on stdin instead of requested from the approval_url
+	ApprovalURL               string          `mapstructure:"approval_url"`                 // The endpoint the approvals of tracks are requested from and polled until approved, denied or approval_timeout
+	ApprovalTimeout           time.Duration   `mapstructure:"approval_timeout"`             // How long an approval of a track is waited for, an hour when 0
+	StepLogDir                string          `mapstructure:"step_log_dir"`                 // Directory the output of each step is persisted to, {trackName}/{stepName}.log, disabled when empty
+	StepLogMaskEnv            []string        `mapstructure:"step_log_mask_env"`            // The env variables whose values are masked in the step logs, the sensitive and secret variables of the run
+	MaskPatterns              []string        `mapstructure:"mask_patterns"`                // Regular expressions matching values masked in the step logs, from runiac.yml
+	Hooks                     Hooks           `mapstructure:"hooks"`                        // The shell commands run before and after the deploy, each track and each step, from runiac.yml
+	TestRollback              bool            `mapstructure:"test_rollback"`                // Destroy a step's execution when its tests fail. Overridden by test_rollback in the step's runiac.yml
+	RollbackOnFailure         bool            `mapstructure:"rollback_on_failure"`          // Destroy the step executions a failed deploy applied, in reverse order, e.g. for ephemeral namespaces
 	// Set at task definition creation
 	Namespace   string `mapstructure:"namespace"`                   // The namespace to use in the Terraform run.
 	Environment string `mapstructure:"environment" required:"true"` // The name of the environment (e.g. pr, nonprod, prod)
 	Project     string `mapstructure:"project" required:"true"`
 }
 
+// Backend is the backend section of runiac.yml
+type Backend struct {
+	GCS GCSBackend `mapstructure:"gcs"`
+}
+
+// GCSBackend configures steps to store their state in a GCS bucket, keyed by project, environment, track, step and
+// namespace unless a prefix is set. The prefix may reference the ${var.runiac_*} variables of backend.tf files.
+type GCSBackend struct {
+	Bucket string `mapstructure:"bucket"`
+	Prefix string `mapstructure:"prefix"`
+}
+
+// Hooks is the hooks section of runiac.yml, the shell commands run before and after the deploy, each track and each
+// step. The pre and post step and track hooks can be added by the runiac.yml of a track, the step hooks by the
+// runiac.yml of a step:
+//
+//	hooks:
+//	  post_step:
+//	    - ./scripts/smoke-test.sh
+//	  post_deploy:
+//	    - curl -fsS -X POST "$CACHE_PURGE_URL"
+//
+// The commands run with sh in the directory of the step, track or project, with the RUNIAC_* variables of the
+// execution. A failing pre hook fails what it runs before without executing it, a post hook only runs once the step,
+// track or deploy succeeded and fails it when it fails. Hooks do not run when destroying.
+type Hooks struct {
+	PreDeploy  []string `mapstructure:"pre_deploy"`
+	PostDeploy []string `mapstructure:"post_deploy"`
+	PreTrack   []string `mapstructure:"pre_track"`
+	PostTrack  []string `mapstructure:"post_track"`
+	PreStep    []string `mapstructure:"pre_step"`
+	PostStep   []string `mapstructure:"post_step"` // The step's output variables are available as RUNIAC_OUTPUT_{NAME}
+}
+
+// ScanSeverities are the severities of scan findings from lowest to highest
+var ScanSeverities = []string{"low", "medium", "high", "critical"}
+
+// Scan is the scan section of runiac.yml
+type Scan struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Tool    string `mapstructure:"tool"`    // tfsec or checkov, tfsec when empty
+	FailOn  string `mapstructure:"fail_on"` // Do not deploy steps with findings of this severity or higher, the findings are only reported when empty
+}
+
+// Validate returns an error when the scanner or the fail_on severity is unknown
+func (s Scan) Validate() error {
+	if s.Tool != "" && s.Tool != "tfsec" && s.Tool != "checkov" {
+		return fmt.Errorf("invalid scan.tool '%s', must be tfsec or checkov", s.Tool)
+	}
+
+	if s.FailOn == "" {
+		return nil
+	}
+
+	for _, severity := range ScanSeverities {
+		if strings.ToLower(s.FailOn) == severity {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("invalid scan.fail_on '%s', must be one of %s", s.FailOn, strings.Join(ScanSeverities, ", "))
+}
+
+// RolloutWave is a wave of the regional_rollout section of runiac.yml. The regions of a wave are deployed once every
+// earlier wave completed without failures.
+type RolloutWave struct {
+	Regions  []string `mapstructure:"regions"`
+	Parallel int      `mapstructure:"parallel"` // The regions of the wave deployed concurrently, every region of the wave when 0
+}
+
+// IsNamespacedRing returns true for the namespace of a --local or --pull-request run, whose resources are created by
+// the runs of the namespace only
+func IsNamespacedRing(ring string, namespace string) bool {
+	return namespace != "" && (strings.EqualFold(ring, LocalDeploymentRing) || strings.EqualFold(ring, PullRequestDeploymentRing))
+}
+
+// ValidateRegionalRollout returns an error when a wave has no regions, a negative parallel or a region of an earlier
+// wave
+func ValidateRegionalRollout(waves []RolloutWave) error {
+	seen := map[string]bool{}
+
+	for i, wave := range waves {
+		if len(wave.Regions) == 0 {
+			return fmt.Errorf("regional_rollout[%d].regions is required", i)
+		}
+
+		if wave.Parallel < 0 {
+			return fmt.Errorf("invalid regional_rollout[%d].parallel %d, must be 0 or more", i, wave.Parallel)
+		}
+
+		for _, region := range wave.Regions {
+			if seen[region] {
+				return fmt.Errorf("region %s is part of more than one wave of the regional_rollout", region)
+			}
+
+			seen[region] = true
+		}
+	}
+
+	return nil
+}
+
 type RegionGroupsMap map[string]map[string][]string
 
 func (ipd *RegionGroupsMap) Decode(value string) error {
@@ -102,42 +257,28 @@ type Account struct {
 
 // GetConfig retrieves a deployment config
 func GetConfig() (Config, error) {
-	viper.SetConfigName("runiac") // name of config file (without extension)
-
-	viper.AddConfigPath(".")
 	viper.SetEnvPrefix("runiac")
 	viper.AutomaticEnv()
 
 	// https://github.com/spf13/viper/issues/188#issuecomment-255519149
-	_ = viper.BindEnv("environment")
-	_ = viper.BindEnv("namespace")
-	_ = viper.BindEnv("project")
-	_ = viper.BindEnv("log_level")
-	_ = viper.BindEnv("dry_run")
-	_ = viper.BindEnv("self_destroy")
-	_ = viper.BindEnv("deployment_ring")
-	_ = viper.BindEnv("primary_region")
-	_ = viper.BindEnv("regional_regions")
-	_ = viper.BindEnv("max_retries")
-	_ = viper.BindEnv("max_test_retries")
-	_ = viper.BindEnv("account_id")
-	_ = viper.BindEnv("runner")
-	_ = viper.BindEnv("step_whitelist")
-
-	if err := viper.ReadInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
-			// Config file not found; ignore error if desired
-		} else {
+	for _, key := range contractVariables {
+		_ = viper.BindEnv(key)
+	}
+
+	// the config file is optional, runiac.yml, runiac.yaml or runiac.hcl
+	if path := FindConfigFile(afero.NewOsFs(), "."); path != "" {
+		if err := ReadConfigFile(afero.NewOsFs(), viper.GetViper(), path); err != nil {
 			return Config{}, err
 		}
 	}
 
 	conf := &Config{
-		MaxTestRetries: 2,
-		MaxRetries:     3,
-		LogLevel:       logrus.InfoLevel.String(),
-		Project:        "runiac",
-		TargetAll:      true,
+		MaxTestRetries:   2,
+		MaxRetries:       3,
+		StepRetryBackoff: 30 * time.Second,
+		LogLevel:         logrus.InfoLevel.String(),
+		Project:          "runiac",
+		TargetAll:        true,
 	}
 	err := viper.Unmarshal(conf)
 
@@ -146,6 +287,20 @@ func GetConfig() (Config, error) {
 		return Config{}, err
 	}
 
+	conf.RunnerArgs, err = getRunnerArgs(viper.Get("runner_args"))
+	if err != nil {
+		return *conf, err
+	}
+
+	// the primary region is the first of the primary regions
+	if conf.PrimaryRegion == "" && len(conf.PrimaryRegions) > 0 {
+		conf.PrimaryRegion = conf.PrimaryRegions[0]
+	}
+
+	if len(conf.PrimaryRegions) == 0 && conf.PrimaryRegion != "" {
+		conf.PrimaryRegions = []string{conf.PrimaryRegion}
+	}
+
 	validate.RegisterStructValidation(InputValidation, conf)
 
 	err = validate.Struct(conf)
@@ -154,6 +309,36 @@ func GetConfig() (Config, error) {
 		return *conf, err
 	}
 
+	// the steps are not executed when only scanning their source and drift is never applied
+	if conf.ScanOnly || conf.Drift {
+		conf.DryRun = true
+	}
+
+	if (conf.Resume || conf.FromStep != "") && (conf.DryRun || conf.Destroy) {
+		return *conf, fmt.Errorf("resume and from_step can not be used with dry_run or destroy, they only apply to deploys")
+	}
+
+	if err := conf.Scan.Validate(); err != nil {
+		return *conf, err
+	}
+
+	if err := ValidateRegionalRollout(conf.RegionalRollout); err != nil {
+		return *conf, err
+	}
+
+	if conf.Destroy && conf.SelfDestroy {
+		return *conf, fmt.Errorf("destroy and self_destroy can not be used together, destroy does not deploy")
+	}
+
+	if conf.RollbackOnFailure && (conf.Destroy || conf.SelfDestroy || conf.Resume || conf.FromStep != "") {
+		return *conf, fmt.Errorf("rollback_on_failure can not be used with destroy, self_destroy, resume or from_step, it only destroys the step executions of the deploy")
+	}
+
+	// the rollback destroys every step the deploy applied, including the resources that existed before the deploy
+	if conf.RollbackOnFailure && !IsNamespacedRing(conf.DeploymentRing, conf.Namespace) {
+		return *conf, fmt.Errorf("rollback_on_failure can only be used with the namespaced %s and %s deployment rings, it destroys every step the failed deploy applied", LocalDeploymentRing, PullRequestDeploymentRing)
+	}
+
 	// if step whitelist is set, respect it
 	if conf.TargetAll && len(conf.StepWhitelist) > 0 {
 		conf.TargetAll = false
@@ -173,7 +358,51 @@ func InputValidation(sl validator.StructLevel) {
 		sl.ReportError(input.Namespace, "primary_region", "primaryRegion", "required-primary-region", "")
 	}
 
-	if input.Runner != "terraform" && input.Runner != "arm" {
+	if !IsBuiltinRunner(input.Runner) && !isExternalRunnerInstalled(input.Runner) {
 		sl.ReportError(input.Runner, "runner", "runner", "invalid-runner", "")
 	}
 }
+
+var lookPath = exec.LookPath
+
+// isExternalRunnerInstalled returns true when the executable of the external runner plugin is on the path
+func isExternalRunnerInstalled(runner string) bool {
+	if runner == "" {
+		return false
+	}
+
+	_, err := lookPath(ExternalRunnerBinary(runner))
+
+	return err == nil
+}
+
+// getRunnerArgs decodes runner_args, a list in the config file or a json array of strings in the environment
+// so individual arguments may contain commas
+func getRunnerArgs(value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		if v == "" {
+			return nil, nil
+		}
+
+		args := []string{}
+		if err := json.Unmarshal([]byte(v), &args); err != nil {
+			return nil, fmt.Errorf("runner_args must be a json array of strings: %w", err)
+		}
+
+		return args, nil
+	case []interface{}:
+		args := []string{}
+		for _, arg := range v {
+			args = append(args, fmt.Sprintf("%v", arg))
+		}
+
+		return args, nil
+	case []string:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("runner_args must be a list of strings")
+	}
+}
@@ -1,8 +1,10 @@
 package config
 
 import (
+	"errors"
 	"github.com/stretchr/testify/require"
 	"os"
+	"os/exec"
 	"testing"
 )
 
@@ -37,3 +39,116 @@ func TestGetConfig_EnvironmentVariablesShouldMatch(t *testing.T) {
 	require.NotEmpty(t, conf.StepWhitelist)
 	require.Equal(t, "default/default", conf.StepWhitelist[0])
 }
+
+func TestGetConfig_ShouldDefaultPrimaryRegionToFirstPrimaryRegion(t *testing.T) {
+	_ = os.Setenv("RUNIAC_PRIMARY_REGIONS", "eastus,westus")
+	_ = os.Setenv("RUNIAC_RUNNER", "terraform")
+	defer os.Unsetenv("RUNIAC_PRIMARY_REGIONS")
+
+	conf, err := GetConfig()
+
+	require.NoError(t, err)
+	require.Equal(t, "eastus", conf.PrimaryRegion)
+	require.Equal(t, []string{"eastus", "westus"}, conf.PrimaryRegions)
+
+	_ = os.Unsetenv("RUNIAC_PRIMARY_REGIONS")
+	_ = os.Setenv("RUNIAC_PRIMARY_REGION", "centralus")
+	defer os.Unsetenv("RUNIAC_PRIMARY_REGION")
+
+	conf, err = GetConfig()
+
+	require.NoError(t, err)
+	require.Equal(t, []string{"centralus"}, conf.PrimaryRegions, "the primary regions default to the primary region")
+}
+
+func TestGetRunnerArgs_ShouldDecodeJsonAndConfigFileLists(t *testing.T) {
+	t.Parallel()
+
+	args, err := getRunnerArgs(`["--parallel", "4", "--target=a,b"]`)
+	require.NoError(t, err)
+	require.Equal(t, []string{"--parallel", "4", "--target=a,b"}, args)
+
+	args, err = getRunnerArgs([]interface{}{"--refresh"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"--refresh"}, args)
+
+	args, err = getRunnerArgs(nil)
+	require.NoError(t, err)
+	require.Empty(t, args)
+
+	_, err = getRunnerArgs("--refresh")
+	require.Error(t, err, "environment runner args must be a json array")
+}
+
+func TestGetContract_ShouldRequireContractVariables(t *testing.T) {
+	contract := GetContract()
+
+	require.Equal(t, ContractVersion, contract.Version)
+	require.Equal(t, RuniacVersion, contract.RuniacVersion)
+	require.Contains(t, contract.Variables, "RUNIAC_PRIMARY_REGION")
+	require.Contains(t, contract.Variables, "RUNIAC_SIMULATE_IAM")
+
+	for _, required := range contract.Required {
+		require.Contains(t, contract.Variables, required, "required variable %s should be part of the contract", required)
+	}
+}
+
+func TestIsExternalRunnerInstalled_ShouldLookUpRunnerPlugin(t *testing.T) {
+	defer func() { lookPath = exec.LookPath }()
+
+	lookPath = func(file string) (string, error) {
+		if file == "runiac-runner-ansible" {
+			return "/usr/local/bin/" + file, nil
+		}
+		return "", errors.New("not found")
+	}
+
+	require.True(t, isExternalRunnerInstalled("ansible"))
+	require.False(t, isExternalRunnerInstalled("chef"))
+	require.False(t, isExternalRunnerInstalled(""))
+}
+
+func TestResourceChanges_Count(t *testing.T) {
+	changes := ResourceChanges{}
+
+	for _, actions := range [][]string{{"create"}, {"update"}, {"delete"}, {"delete", "create"}, {"no-op"}, {"read"}} {
+		changes.Count(actions)
+	}
+
+	require.Equal(t, ResourceChanges{Add: 2, Change: 1, Destroy: 2}, changes)
+}
+
+func TestValidateRegionalRollout_ShouldRejectInvalidWaves(t *testing.T) {
+	require.NoError(t, ValidateRegionalRollout(nil))
+	require.NoError(t, ValidateRegionalRollout([]RolloutWave{{Regions: []string{"us-east-1"}}, {Regions: []string{"us-east-2", "us-west-2"}, Parallel: 1}}))
+
+	require.EqualError(t, ValidateRegionalRollout([]RolloutWave{{Regions: []string{}}}), "regional_rollout[0].regions is required")
+	require.EqualError(t, ValidateRegionalRollout([]RolloutWave{{Regions: []string{"us-east-1"}, Parallel: -1}}), "invalid regional_rollout[0].parallel -1, must be 0 or more")
+	require.EqualError(t, ValidateRegionalRollout([]RolloutWave{{Regions: []string{"us-east-1"}}, {Regions: []string{"us-east-1"}}}), "region us-east-1 is part of more than one wave of the regional_rollout")
+}
+
+func TestScan_ValidateShouldRejectUnknownToolsAndSeverities(t *testing.T) {
+	require.NoError(t, Scan{}.Validate())
+	require.NoError(t, Scan{Enabled: true, Tool: "checkov", FailOn: "HIGH"}.Validate())
+	require.EqualError(t, Scan{Tool: "trivy"}.Validate(), "invalid scan.tool 'trivy', must be tfsec or checkov")
+	require.EqualError(t, Scan{FailOn: "severe"}.Validate(), "invalid scan.fail_on 'severe', must be one of low, medium, high, critical")
+}
+
+func TestGetConfig_ShouldOnlyRollBackNamespacedRings(t *testing.T) {
+	_ = os.Setenv("RUNIAC_PRIMARY_REGION", "centralus")
+	_ = os.Setenv("RUNIAC_ROLLBACK_ON_FAILURE", "true")
+	_ = os.Setenv("RUNIAC_DEPLOYMENT_RING", "prod")
+	defer os.Unsetenv("RUNIAC_ROLLBACK_ON_FAILURE")
+	defer os.Unsetenv("RUNIAC_DEPLOYMENT_RING")
+
+	_, err := GetConfig()
+	require.EqualError(t, err, "rollback_on_failure can only be used with the namespaced local and pr deployment rings, it destroys every step the failed deploy applied")
+
+	_ = os.Setenv("RUNIAC_DEPLOYMENT_RING", "pr")
+	_ = os.Setenv("RUNIAC_NAMESPACE", "42")
+	defer os.Unsetenv("RUNIAC_NAMESPACE")
+
+	conf, err := GetConfig()
+	require.NoError(t, err)
+	require.True(t, conf.RollbackOnFailure)
+}
@@ -0,0 +1,143 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/hcl"
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+)
+
+// ConfigFileExts are the extensions of the runiac config files in order of precedence, runiac.yml, runiac.yaml or
+// runiac.hcl
+var ConfigFileExts = []string{"yml", "yaml", "hcl"}
+
+// hclListKeys are the keys of the runiac config whose hcl blocks are a list, e.g. one notifications block for each
+// notification. The blocks of every other key are merged into a map, e.g. rings "prod" { ... } into rings.prod.
+var hclListKeys = map[string]bool{
+	"notifications":    true,
+	"regional_rollout": true,
+	"freeze_windows":   true,
+	"secrets.env":      true,
+}
+
+// FindConfigFile returns the path of the runiac config file in the directory, empty when there is none
+func FindConfigFile(fs afero.Fs, dir string) string {
+	for _, ext := range ConfigFileExts {
+		path := filepath.Join(dir, "runiac."+ext)
+
+		if info, err := fs.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+
+	return ""
+}
+
+// ReadConfigFile reads the runiac config file into v. yaml is read by viper, hcl is decoded with its blocks
+// normalized to the structure of the yaml config so both formats have the same keys.
+func ReadConfigFile(fs afero.Fs, v *viper.Viper, path string) error {
+	v.SetFs(fs)
+	v.SetConfigFile(path)
+
+	if !strings.EqualFold(filepath.Ext(path), ".hcl") {
+		return v.ReadInConfig()
+	}
+
+	b, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return err
+	}
+
+	settings, err := DecodeHCLConfig(b)
+	if err != nil {
+		return fmt.Errorf("unable to read %s: %w", path, err)
+	}
+
+	return v.MergeConfigMap(settings)
+}
+
+// DecodeHCLConfig decodes a runiac config in hcl. Blocks decode to lists of maps in hcl, these are merged into a
+// single map unless the key is one of hclListKeys.
+func DecodeHCLConfig(b []byte) (map[string]interface{}, error) {
+	obj, err := hcl.Parse(string(b))
+	if err != nil {
+		return nil, err
+	}
+
+	settings := map[string]interface{}{}
+
+	if err = hcl.DecodeObject(&settings, obj); err != nil {
+		return nil, err
+	}
+
+	return normalizeHCLMap(settings, ""), nil
+}
+
+// normalizeHCLMap normalizes the values of the hcl map at the key path
+func normalizeHCLMap(m map[string]interface{}, path string) map[string]interface{} {
+	normalized := map[string]interface{}{}
+
+	for k, v := range m {
+		key := strings.ToLower(k)
+		if path != "" {
+			key = path + "." + key
+		}
+
+		normalized[strings.ToLower(k)] = normalizeHCLValue(v, key)
+	}
+
+	return normalized
+}
+
+// normalizeHCLValue merges the blocks of the key path into a map, or normalizes each block of a list key
+func normalizeHCLValue(value interface{}, path string) interface{} {
+	blocks, ok := value.([]map[string]interface{})
+	if !ok {
+		return value
+	}
+
+	if isHCLListKey(path) {
+		list := []interface{}{}
+		for _, block := range blocks {
+			list = append(list, normalizeHCLMap(block, path))
+		}
+
+		return list
+	}
+
+	merged := map[string]interface{}{}
+
+	for _, block := range blocks {
+		mergeHCLMaps(merged, normalizeHCLMap(block, path))
+	}
+
+	return merged
+}
+
+// mergeHCLMaps merges src into dst, the maps of a key repeated by several blocks are merged key by key
+func mergeHCLMaps(dst map[string]interface{}, src map[string]interface{}) {
+	for k, v := range src {
+		existing, isMap := dst[k].(map[string]interface{})
+		if next, nextIsMap := v.(map[string]interface{}); isMap && nextIsMap {
+			mergeHCLMaps(existing, next)
+			continue
+		}
+
+		dst[k] = v
+	}
+}
+
+// isHCLListKey returns true when the key path is one of hclListKeys, also within the overlay of an environment or a
+// profile, e.g. environments.prod.notifications
+func isHCLListKey(path string) bool {
+	parts := strings.Split(path, ".")
+
+	if len(parts) > 2 && (parts[0] == "environments" || parts[0] == "profiles") {
+		parts = parts[2:]
+	}
+
+	return hclListKeys[strings.Join(parts, ".")]
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+var hclExample = []byte(`
+project        = "stub"
+primary_region = "centralus"
+step_whitelist = ["network/vnet"]
+
+rings "dev" {
+  account          = "111"
+  regional_regions = ["eastus"]
+}
+
+rings "prod" {
+  account = "222"
+
+  variables {
+    sku = "Premium"
+  }
+}
+
+backend {
+  type   = "gcs"
+  bucket = "stub-state"
+}
+
+environments "prod" {
+  container = "stub/runiac:prod"
+
+  notifications {
+    type = "slack"
+  }
+}
+
+notifications {
+  type   = "slack"
+  events = ["step_failed"]
+}
+
+notifications {
+  type = "teams"
+}
+`)
+
+func TestReadConfigFile_ShouldNormalizeHCLBlocks(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	_ = afero.WriteFile(fs, "runiac.hcl", hclExample, 0644)
+
+	path := FindConfigFile(fs, ".")
+	require.Equal(t, "runiac.hcl", path)
+
+	v := viper.New()
+	require.NoError(t, ReadConfigFile(fs, v, path))
+
+	require.Equal(t, "stub", v.GetString("project"))
+	require.Equal(t, []string{"network/vnet"}, v.GetStringSlice("step_whitelist"))
+	require.Equal(t, "111", v.GetString("rings.dev.account"))
+	require.Equal(t, []string{"eastus"}, v.GetStringSlice("rings.dev.regional_regions"))
+	require.Equal(t, "Premium", v.GetString("rings.prod.variables.sku"))
+	require.Equal(t, "stub-state", v.GetString("backend.bucket"))
+	require.Equal(t, "stub/runiac:prod", v.GetString("environments.prod.container"))
+
+	notifications := []map[string]interface{}{}
+	require.NoError(t, v.UnmarshalKey("notifications", &notifications))
+	require.Len(t, notifications, 2, "every notifications block should be a notification")
+	require.Equal(t, "teams", notifications[1]["type"])
+
+	overlay := []map[string]interface{}{}
+	require.NoError(t, v.UnmarshalKey("environments.prod.notifications", &overlay))
+	require.Len(t, overlay, 1, "the notifications of an environment overlay should be a list")
+
+	_ = afero.WriteFile(fs, "runiac.yml", []byte("project: yaml\n"), 0644)
+	require.Equal(t, "runiac.yml", FindConfigFile(fs, "."), "runiac.yml should take precedence")
+
+	_ = afero.WriteFile(fs, "step1_invalid/runiac.hcl", []byte(`rings "prod" {`), 0644)
+	require.Error(t, ReadConfigFile(fs, viper.New(), FindConfigFile(fs, "step1_invalid")))
+	require.Empty(t, FindConfigFile(fs, "step2_missing"))
+}
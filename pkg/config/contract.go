@@ -0,0 +1,107 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ContractVersion is incremented whenever the meaning of an existing RUNIAC_* variable changes or a new variable
+// becomes required, so the runiac CLI can detect version skew with the deploy container
+const ContractVersion = 1
+
+// RuniacVersion is the runiac release the container was built from, set with
+// -ldflags "-X github.com/optum/runiac/pkg/config.RuniacVersion=v1.2.0". Empty for a development build.
+var RuniacVersion string
+
+// contractVariables are the config keys the container reads from RUNIAC_* environment variables
+var contractVariables = []string{
+	"environment",
+	"namespace",
+	"project",
+	"version",
+	"log_level",
+	"dry_run",
+	"self_destroy",
+	"destroy",
+	"deployment_ring",
+	"primary_region",
+	"primary_regions",
+	"parallel_primary_regions",
+	"regional_regions",
+	"max_retries",
+	"max_test_retries",
+	"account_id",
+	"runner",
+	"step_whitelist",
+	"teardown_order",
+	"output_dir",
+	"artifacts_from",
+	"plan_summary_threshold",
+	"plan_summary_require_confirm",
+	"confirmed",
+	"pre_pull_providers",
+	"manifest_path",
+	"sarif_path",
+	"runner_args",
+	"skip_regional",
+	"simulate_iam",
+	"cost_estimate",
+	"policy_dir",
+	"policy_soft_fail",
+	"max_parallel",
+	"abort_after_failures",
+	"step_timeout",
+	"step_retries",
+	"step_retry_backoff",
+	"outputs_path",
+	"report_path",
+	"checkpoint_path",
+	"resume",
+	"from_step",
+	"scan_only",
+	"drift",
+	"tracing_endpoint",
+	"pause_between_tracks",
+	"interactive",
+	"approval_url",
+	"approval_timeout",
+	"step_log_dir",
+	"step_log_mask_env",
+	"log_format",
+	"test_rollback",
+	"rollback_on_failure",
+}
+
+// requiredContractVariables must be set by the environment or the runiac config file
+var requiredContractVariables = []string{
+	"environment",
+	"primary_region",
+}
+
+// Contract describes the environment variables the container understands, printed by runiac contract
+type Contract struct {
+	Version       int      `json:"version"`
+	RuniacVersion string   `json:"runiac_version,omitempty"` // The runiac release of the container, the CLI verifies it is compatible
+	Variables     []string `json:"variables"`
+	Required      []string `json:"required"`
+}
+
+// GetContract returns the container's environment variable contract
+func GetContract() Contract {
+	return Contract{
+		Version:       ContractVersion,
+		RuniacVersion: RuniacVersion,
+		Variables:     getContractEnvNames(contractVariables),
+		Required:      getContractEnvNames(requiredContractVariables),
+	}
+}
+
+func getContractEnvNames(keys []string) []string {
+	names := []string{}
+
+	for _, key := range keys {
+		names = append(names, fmt.Sprintf("RUNIAC_%s", strings.ToUpper(key)))
+	}
+
+	return names
+}
@@ -8,3 +8,42 @@ type RunnerPlugin interface {
 	// Any user-facing output should be sent to the provided`logger` instance.
 	Initialize(logger *logrus.Entry)
 }
+
+// Interface ProviderPuller is optionally implemented by runner plugins able to download
+// the providers of the given step directories ahead of executing them.
+type ProviderPuller interface {
+	PullProviders(logger *logrus.Entry, dirs []string) error
+}
+
+// Interface Runner describes the deployment lifecycle of a step, implemented by runner plugins. Deployments use
+// Init, Plan, Apply and Outputs, destroys Init, Plan and Destroy.
+type Runner interface {
+	Init(exec StepExecution) error
+	Plan(exec StepExecution, destroy bool) error
+	Apply(exec StepExecution) error
+	Destroy(exec StepExecution) error
+	Outputs(exec StepExecution) (map[string]interface{}, error)
+}
+
+// ExternalRunnerPrefix prefixes the executables of external runner plugins, e.g. runiac-runner-ansible for the
+// ansible runner
+const ExternalRunnerPrefix = "runiac-runner-"
+
+// BuiltinRunners are the runners compiled into runiac, every other runner is an external runner plugin
+var BuiltinRunners = []string{"terraform", "arm", "pulumi", "terragrunt"}
+
+// ExternalRunnerBinary returns the executable of the external runner plugin
+func ExternalRunnerBinary(runner string) string {
+	return ExternalRunnerPrefix + runner
+}
+
+// IsBuiltinRunner returns true when the runner is compiled into runiac
+func IsBuiltinRunner(runner string) bool {
+	for _, r := range BuiltinRunners {
+		if runner == r {
+			return true
+		}
+	}
+
+	return false
+}
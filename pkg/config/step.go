@@ -1,6 +1,11 @@
 package config
 
 import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/optum/runiac/pkg/tracing"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/afero"
 )
@@ -16,6 +21,7 @@ type StepExecution struct {
 	RegionGroup                string
 	PrimaryRegion              string
 	Dir                        string
+	TrackDir                   string // The directory of the step's track, the project directory for the default track
 	Environment                string `json:"environment"`
 	AppVersion                 string `json:"app_version"`
 	AccountID                  string `json:"account_id"`
@@ -32,6 +38,22 @@ type StepExecution struct {
 	TrackName                  string
 	DryRun                     bool
 	SelfDestroy                bool
+	OutputDir                  string                       // Directory to write step artifacts to, empty when disabled
+	ArtifactsFrom              string                       // Directory to read step artifacts of a previous run from, empty when disabled
+	PlanSummaryThreshold       int                          // Warn when the plan changes more resources than this, disabled when 0
+	PlanSummaryRequireConfirm  bool                         // Do not apply plans exceeding PlanSummaryThreshold unless Confirmed
+	Confirmed                  bool                         // The user confirmed applying changes that require confirmation
+	ExportManifest             bool                         // Collect the resources managed by the step into StepOutput.ManagedResources
+	CollectFindings            bool                         // Validate the step and collect the results into StepOutput.Findings
+	RunnerArgs                 []string                     // Additional arguments for the runner's commands
+	SimulateIAM                bool                         // Simulate the aws IAM permissions the plan requires and collect missing ones into StepOutput.Findings
+	EstimateCost               bool                         // Estimate the monthly cost change of the plan with infracost into StepOutput.CostEstimate
+	PolicyDir                  string                       // Evaluate the plan against the rego policies in this directory before applying, collecting violations into StepOutput.Findings
+	PolicySoftFail             bool                         // Apply plans violating a policy, only warning about the violations
+	DetectDrift                bool                         // Plan refresh-only, collecting the resources that drifted from the state into StepOutput.DriftedResources
+	Backend                    Backend                      // The state backend to configure when the step does not declare one
+	Context                    context.Context              // Done when the step times out, the runners kill their commands once it is done. Nil when the step has no timeout
+	Span                       *tracing.Span                `json:"-"` // The step's span, runners trace their phases as its children. Nil when tracing is not configured
 	DefaultStepOutputVariables map[string]map[string]string // Previous step output variables are available in this map. K=StepName,V=map[VarName:VarVal]
 	OptionalStepParams         map[string]string
 	RequiredStepParams         map[string]interface{}
@@ -43,7 +65,14 @@ type Step struct {
 	Name                   string
 	TrackName              string
 	Dir                    string
-	ProgressionLevel       int // 1, 2, 3...
+	ProgressionLevel       int           // 1, 2, 3...
+	DependsOn              []string      // The names of the steps of the track this step depends on, from depends_on in the step's runiac.yml. When nil, the step depends on the steps of the previous progression level
+	Inputs                 []StepInput   // The input variables the step reads from the outputs of the steps it depends on, from inputs in the step's runiac.yml
+	Timeout                time.Duration // How long the step may execute in a region before it fails, unlimited when 0. RUNIAC_STEP_TIMEOUT overrides timeout in the step's runiac.yml, otherwise step_timeout
+	Retries                int           // How many times the step is retried when it fails. RUNIAC_STEP_RETRIES overrides retries in the step's runiac.yml, otherwise step_retries
+	RetryBackoff           time.Duration // How long to wait before the first retry, doubled for every further retry. RUNIAC_STEP_RETRY_BACKOFF overrides retry_backoff in the step's runiac.yml, otherwise step_retry_backoff
+	Hooks                  Hooks         // The pre_step and post_step hooks of the project, the step's track and the step
+	TestRollback           bool          // Destroy the step's execution when its tests fail. From test_rollback in the step's runiac.yml, otherwise test_rollback
 	RegionalResourcesExist bool
 	TestsExist             bool
 	RegionalTestsExist     bool // TODO: remove the need for these TestsExists and evaulate in real time during evaluation vs gather?
@@ -52,14 +81,31 @@ type Step struct {
 	Output                 StepOutput
 	TestOutput             StepTestOutput
 	Runner                 Stepper
+	Span                   *tracing.Span `json:"-"` // The region span the step's execution is traced under, nil when tracing is not configured
 	//runiacConfig       runiacConfig
 }
 
+// StepInput is an input variable of a step set to an output variable of another step of the track
+type StepInput struct {
+	Name   string // The name of the input variable, e.g. TF_VAR_{name} for terraform
+	Step   string // The name of the step whose output is read
+	Output string // The name of the output variable
+}
+
 // StepTestOutput represents the output of a step's test
 type StepTestOutput struct {
 	StepName     string
 	StreamOutput string
 	Err          error
+	Assertions   []AssertionResult // The results of the assertions of the step's tests/assertions.yml
+	RolledBack   bool              // The step's execution was destroyed because its tests failed
+}
+
+// AssertionResult is the result of an assertion of a step's tests/assertions.yml
+type AssertionResult struct {
+	Name    string `json:"name" yaml:"name"`
+	Passed  bool   `json:"passed" yaml:"passed"`
+	Message string `json:"message,omitempty" yaml:"message,omitempty"` // Why the assertion failed
 }
 
 // StepOutput represents the output of a step
@@ -71,6 +117,83 @@ type StepOutput struct {
 	StreamOutput     string
 	Err              error
 	OutputVariables  map[string]interface{}
+	SensitiveOutputs []string // The names of the output variables the runner marked sensitive
+	ManagedResources []ManagedResource
+	Findings         []Finding
+	ResourceChanges  ResourceChanges   // The resources changed by the step's plan
+	CostEstimate     *CostEstimate     // The monthly cost change of the step's plan, nil when it was not estimated
+	DriftedResources []DriftedResource // The resources changed outside of the runner since the step was last deployed
+	Duration         time.Duration     // How long the step executed
+}
+
+// ResourceChanges counts the resources changed by a plan, a replaced resource is both added and destroyed
+type ResourceChanges struct {
+	Add     int `json:"add"`
+	Change  int `json:"change"`
+	Destroy int `json:"destroy"`
+}
+
+// Count counts a resource change of a terraform show -json plan by its actions, no-op and read do not change
+// infrastructure
+func (c *ResourceChanges) Count(actions []string) {
+	switch strings.Join(actions, ",") {
+	case "create":
+		c.Add++
+	case "update":
+		c.Change++
+	case "delete":
+		c.Destroy++
+	case "delete,create", "create,delete":
+		c.Add++
+		c.Destroy++
+	}
+}
+
+// DriftedResource is a resource whose remote object was changed outside of the runner, the action is update when
+// the object was modified and delete when it no longer exists
+type DriftedResource struct {
+	Address string `json:"address" yaml:"address"`
+	Type    string `json:"type" yaml:"type"`
+	Action  string `json:"action" yaml:"action"`
+}
+
+// CostEstimate is the estimated monthly cost of a step's resources before and after applying its plan
+type CostEstimate struct {
+	Currency         string  `json:"currency" yaml:"currency"`
+	PastMonthlyCost  float64 `json:"past_monthly_cost" yaml:"past_monthly_cost"`
+	MonthlyCost      float64 `json:"monthly_cost" yaml:"monthly_cost"`
+	MonthlyCostDelta float64 `json:"monthly_cost_delta" yaml:"monthly_cost_delta"`
+}
+
+// FindingLevel is the severity of a validation or policy finding, named after the SARIF result levels
+type FindingLevel string
+
+const (
+	FindingError   FindingLevel = "error"
+	FindingWarning FindingLevel = "warning"
+	FindingNote    FindingLevel = "note"
+)
+
+// Finding represents a validation or policy finding for a step, with its location when known
+type Finding struct {
+	RuleID      string       `json:"rule_id" yaml:"rule_id"`
+	Level       FindingLevel `json:"level" yaml:"level"`
+	Severity    string       `json:"severity,omitempty" yaml:"severity,omitempty"` // The severity of a scan finding, one of ScanSeverities
+	Message     string       `json:"message" yaml:"message"`
+	File        string       `json:"file,omitempty" yaml:"file,omitempty"` // Relative to the project root
+	StartLine   int          `json:"start_line,omitempty" yaml:"start_line,omitempty"`
+	StartColumn int          `json:"start_column,omitempty" yaml:"start_column,omitempty"`
+	EndLine     int          `json:"end_line,omitempty" yaml:"end_line,omitempty"`
+	EndColumn   int          `json:"end_column,omitempty" yaml:"end_column,omitempty"`
+}
+
+// ManagedResource represents a resource managed by a step as recorded in the runner's state
+type ManagedResource struct {
+	Address  string `json:"address"`
+	Type     string `json:"type"`
+	Name     string `json:"name"`
+	ID       string `json:"id"`
+	Provider string `json:"provider"`
 }
 
 // TFProviderType represents a Terraform provider type
@@ -111,5 +234,5 @@ const (
 )
 
 func (d DeployResult) String() string {
-	return [...]string{"FAIL", "SUCCESS", "UNSTABLE", "SKIPPED"}[d]
+	return [...]string{"FAIL", "SUCCESS", "UNSTABLE", "SKIPPED", "NA"}[d]
 }
@@ -6,6 +6,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"runtime"
 	"strings"
+	"time"
 )
 
 const (
@@ -16,6 +17,23 @@ const (
 	green  = 32
 )
 
+// NewFormatter returns the logrus formatter of the log format, json for structured logs with the track, step and
+// region as fields or text (the default) for the runiac formatter
+func NewFormatter(format string, disableColors bool) (logrus.Formatter, error) {
+	switch strings.ToLower(format) {
+	case "json":
+		return &logrus.JSONFormatter{
+			TimestampFormat: time.RFC3339Nano,
+		}, nil
+	case "", "text":
+		return &RuniacFormatter{
+			DisableColors: disableColors,
+		}, nil
+	default:
+		return nil, fmt.Errorf("invalid log format '%s', must be json or text", format)
+	}
+}
+
 // Formatter that is called on by logrus.
 type RuniacFormatter struct {
 	// DisableTimestamp allows disabling automatic timestamps in output
@@ -2,6 +2,7 @@ package shell
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"github.com/sirupsen/logrus"
@@ -22,7 +23,17 @@ type Command struct {
 	OutputMaxLineSize int               // The max line size of stdout and stderr (in bytes)
 	Logger            *logrus.Entry
 	NonInteractive    bool
-	SensitiveArgs     bool // If true, will not log the arguments to the command
+	SensitiveArgs     bool            // If true, will not log the arguments to the command
+	Context           context.Context // If set, kills the command when the context is done, e.g. when the step times out
+}
+
+// newCmd returns the Cmd of the command, killed when the command's context is done
+func newCmd(command Command) *exec.Cmd {
+	if command.Context != nil {
+		return exec.CommandContext(command.Context, command.Command, command.Args...)
+	}
+
+	return exec.Command(command.Command, command.Args...)
 }
 
 // RunCommand runs a shell command and redirects its stdout and stderr to the stdout of the atomic script itself.
@@ -57,7 +68,7 @@ func RunCommandAndGetStdOut(command Command) (string, error) {
 // be printed to the stdout and stderr of this Go program to make debugging easier.
 func runCommandAndStoreOutput(command Command, storedStdout *[]string, storedStderr *[]string) error {
 
-	cmd := exec.Command(command.Command, command.Args...)
+	cmd := newCmd(command)
 	cmd.Dir = command.WorkingDir
 	cmd.Stdin = os.Stdin
 	cmd.Env = formatEnvVars(command)
@@ -20,7 +20,7 @@ func RunShellCommand(command Command) error {
 		command.Logger.Infof("Running command: %s %s", command.Command, strings.Join(command.Args, " "))
 	}
 
-	cmd := exec.Command(command.Command, command.Args...)
+	cmd := newCmd(command)
 
 	// TODO: consider logging this via options.Logger
 	cmd.Stdin = os.Stdin
@@ -47,7 +47,7 @@ func RunShellCommandAndGetOutput(command Command) (string, error) {
 		command.Logger.Infof("Running command: %s %s", command.Command, strings.Join(command.Args, " "))
 	}
 
-	cmd := exec.Command(command.Command, command.Args...)
+	cmd := newCmd(command)
 
 	cmd.Stdin = os.Stdin
 	cmd.Dir = command.WorkingDir
@@ -63,6 +63,32 @@ func RunShellCommandAndGetOutput(command Command) (string, error) {
 	return string(out), errors.WithStackTrace(err)
 }
 
+// Run the specified shell command with the specified arguments. Return solely its stdout as a string, stderr is
+// connected to the stderr of the currently running app. Use this for machine readable output, e.g. json.
+func RunShellCommandAndGetStdOut(command Command) (string, error) {
+	if command.SensitiveArgs {
+		command.Logger.Infof("Running command: %s (args redacted)", command.Command)
+	} else {
+		command.Logger.Infof("Running command: %s %s", command.Command, strings.Join(command.Args, " "))
+	}
+
+	cmd := newCmd(command)
+
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+	cmd.Dir = command.WorkingDir
+
+	if len(command.Env) > 0 {
+		cmd.Env = os.Environ()
+		for k, v := range command.Env {
+			cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
+		}
+	}
+
+	out, err := cmd.Output()
+	return string(out), errors.WithStackTrace(err)
+}
+
 func KeysStringString(m map[string]string) string {
 	keys := make([]string, 0, len(m))
 	for k := range m {
@@ -80,7 +106,7 @@ func RunShellCommandAndGetAndStreamOutput(command Command) (string, error) {
 		command.Logger.Infof("Running command: %s %s.\nEnvVars: %s", command.Command, strings.Join(command.Args, " "), KeysStringString(command.Env))
 	}
 
-	cmd := exec.Command(command.Command, command.Args...)
+	cmd := newCmd(command)
 
 	if len(command.Env) > 0 {
 		cmd.Env = os.Environ()
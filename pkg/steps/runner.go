@@ -0,0 +1,83 @@
+package steps
+
+import (
+	"github.com/optum/runiac/pkg/config"
+)
+
+// RunnerStepper executes steps with the lifecycle of a config.Runner, e.g. an external runner plugin
+type RunnerStepper struct {
+	Runner config.Runner
+}
+
+func (stepper RunnerStepper) PreExecute(exec config.StepExecution) (config.StepExecution, error) {
+	return exec, nil
+}
+
+// ExecuteStepDestroy destroys a step
+func (stepper RunnerStepper) ExecuteStepDestroy(exec config.StepExecution) (output config.StepOutput) {
+	output = newRunnerStepOutput(exec)
+
+	if output.Err = stepper.Runner.Init(exec); output.Err != nil {
+		exec.Logger.WithError(output.Err).Error("Error during runner init")
+		return
+	}
+
+	if output.Err = stepper.Runner.Plan(exec, true); output.Err != nil {
+		exec.Logger.WithError(output.Err).Error("Error running runner plan")
+		return
+	}
+
+	if exec.DryRun {
+		exec.Logger.Info("---------- Skipping destroy, this is a dry run ---------- ")
+	} else if output.Err = stepper.Runner.Destroy(exec); output.Err != nil {
+		exec.Logger.WithError(output.Err).Error("Error running runner destroy")
+		return
+	}
+
+	output.Status = config.Success
+	return
+}
+
+// ExecuteStep deploys a step
+func (stepper RunnerStepper) ExecuteStep(exec config.StepExecution) (output config.StepOutput) {
+	output = newRunnerStepOutput(exec)
+
+	if output.Err = stepper.Runner.Init(exec); output.Err != nil {
+		exec.Logger.WithError(output.Err).Error("Error during runner init")
+		return
+	}
+
+	if output.Err = stepper.Runner.Plan(exec, false); output.Err != nil {
+		exec.Logger.WithError(output.Err).Error("Error running runner plan")
+		return
+	}
+
+	if exec.DryRun {
+		exec.Logger.Info("---------- Skipping apply, this is a dry run ---------- ")
+	} else if output.Err = stepper.Runner.Apply(exec); output.Err != nil {
+		exec.Logger.WithError(output.Err).Error("Error running runner apply")
+		return
+	}
+
+	if output.OutputVariables, output.Err = stepper.Runner.Outputs(exec); output.Err != nil {
+		exec.Logger.WithError(output.Err).Error("Error reading runner outputs")
+		return
+	}
+
+	output.Status = config.Success
+	return
+}
+
+// ExecuteStepTests executes the tests for a step, runners do not support tests yet
+func (stepper RunnerStepper) ExecuteStepTests(exec config.StepExecution) (output config.StepTestOutput) {
+	return
+}
+
+func newRunnerStepOutput(exec config.StepExecution) config.StepOutput {
+	return config.StepOutput{
+		RegionDeployType: exec.RegionDeployType,
+		Region:           exec.Region,
+		StepName:         exec.StepName,
+		Status:           config.Fail, // assume failure
+	}
+}
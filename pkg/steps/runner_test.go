@@ -0,0 +1,102 @@
+package steps_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/pkg/steps"
+	"github.com/stretchr/testify/require"
+)
+
+type stubRunner struct {
+	actions  []string
+	applyErr error
+}
+
+func (r *stubRunner) Init(exec config.StepExecution) error {
+	r.actions = append(r.actions, "init")
+	return nil
+}
+
+func (r *stubRunner) Plan(exec config.StepExecution, destroy bool) error {
+	if destroy {
+		r.actions = append(r.actions, "plan-destroy")
+	} else {
+		r.actions = append(r.actions, "plan")
+	}
+	return nil
+}
+
+func (r *stubRunner) Apply(exec config.StepExecution) error {
+	r.actions = append(r.actions, "apply")
+	return r.applyErr
+}
+
+func (r *stubRunner) Destroy(exec config.StepExecution) error {
+	r.actions = append(r.actions, "destroy")
+	return nil
+}
+
+func (r *stubRunner) Outputs(exec config.StepExecution) (map[string]interface{}, error) {
+	r.actions = append(r.actions, "outputs")
+	return map[string]interface{}{"id": "stub"}, nil
+}
+
+func TestRunnerStepper_ShouldExecuteRunnerLifecycle(t *testing.T) {
+	tests := []struct {
+		name            string
+		destroy         bool
+		dryRun          bool
+		applyErr        error
+		expectedActions []string
+		expectedStatus  config.DeployResult
+	}{
+		{
+			name:            "ShouldDeploy",
+			expectedActions: []string{"init", "plan", "apply", "outputs"},
+			expectedStatus:  config.Success,
+		},
+		{
+			name:            "ShouldSkipApplyWhenDryRun",
+			dryRun:          true,
+			expectedActions: []string{"init", "plan", "outputs"},
+			expectedStatus:  config.Success,
+		},
+		{
+			name:            "ShouldFailWhenApplyFails",
+			applyErr:        errors.New("stub"),
+			expectedActions: []string{"init", "plan", "apply"},
+			expectedStatus:  config.Fail,
+		},
+		{
+			name:            "ShouldDestroy",
+			destroy:         true,
+			expectedActions: []string{"init", "plan-destroy", "destroy"},
+			expectedStatus:  config.Success,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			runner := &stubRunner{applyErr: tc.applyErr}
+			stepper := steps.RunnerStepper{Runner: runner}
+			exec := config.StepExecution{StepName: "stub", Logger: logger, DryRun: tc.dryRun}
+
+			var output config.StepOutput
+			if tc.destroy {
+				output = stepper.ExecuteStepDestroy(exec)
+			} else {
+				output = stepper.ExecuteStep(exec)
+			}
+
+			require.Equal(t, tc.expectedActions, runner.actions)
+			require.Equal(t, tc.expectedStatus, output.Status)
+			require.Equal(t, "stub", output.StepName)
+
+			if tc.expectedStatus == config.Success && !tc.destroy {
+				require.Equal(t, "stub", output.OutputVariables["id"])
+			}
+		})
+	}
+}
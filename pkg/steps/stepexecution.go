@@ -13,6 +13,26 @@ import (
 	"strings"
 )
 
+// getPrimaryRegion returns the primary region of an execution, the executing region of a primary execution and
+// otherwise the first primary region whose outputs the regional executions receive
+func getPrimaryRegion(cfg config.Config, regionDeployType config.RegionDeployType, region string) string {
+	if regionDeployType == config.PrimaryRegionDeployType {
+		return region
+	}
+
+	return cfg.PrimaryRegion
+}
+
+// getRunnerMaxRetries returns how many times the runner retries its commands, max_retries unless the step itself is
+// retried, so a step is not attempted (retries+1)×max_retries times
+func getRunnerMaxRetries(s config.Step) int {
+	if s.Retries > 0 {
+		return 0
+	}
+
+	return s.DeployConfig.MaxRetries
+}
+
 func NewExecution(s config.Step, logger *logrus.Entry, fs afero.Fs, regionDeployType config.RegionDeployType, region string, defaultStepOutputVariables map[string]map[string]string) config.StepExecution {
 	return config.StepExecution{
 		RegionDeployType:           regionDeployType,
@@ -20,6 +40,7 @@ func NewExecution(s config.Step, logger *logrus.Entry, fs afero.Fs, regionDeploy
 		Fs:                         fs,
 		TargetAccountID:            s.DeployConfig.TargetAccountID,
 		RegionGroup:                s.DeployConfig.RegionGroup,
+		PrimaryRegion:              getPrimaryRegion(s.DeployConfig, regionDeployType, region),
 		DefaultStepOutputVariables: defaultStepOutputVariables,
 		Environment:                s.DeployConfig.Environment,
 		AppVersion:                 s.DeployConfig.Version,
@@ -29,9 +50,10 @@ func NewExecution(s config.Step, logger *logrus.Entry, fs afero.Fs, regionDeploy
 		StepID:                     s.ID,
 		Namespace:                  s.DeployConfig.Namespace,
 		Dir:                        s.Dir,
+		TrackDir:                   filepath.Dir(s.Dir),
 		DeploymentRing:             s.DeployConfig.DeploymentRing,
 		DryRun:                     s.DeployConfig.DryRun,
-		MaxRetries:                 s.DeployConfig.MaxRetries,
+		MaxRetries:                 getRunnerMaxRetries(s),
 		MaxTestRetries:             s.DeployConfig.MaxTestRetries,
 		Project:                    s.DeployConfig.Project,
 		TrackName:                  s.TrackName,
@@ -39,6 +61,20 @@ func NewExecution(s config.Step, logger *logrus.Entry, fs afero.Fs, regionDeploy
 		UniqueExternalExecutionID:  s.DeployConfig.UniqueExternalExecutionID,
 		RegionGroups:               s.DeployConfig.RegionGroups,
 		SelfDestroy:                s.DeployConfig.SelfDestroy,
+		OutputDir:                  s.DeployConfig.OutputDir,
+		ArtifactsFrom:              s.DeployConfig.ArtifactsFrom,
+		PlanSummaryThreshold:       s.DeployConfig.PlanSummaryThreshold,
+		PlanSummaryRequireConfirm:  s.DeployConfig.PlanSummaryRequireConfirm,
+		Confirmed:                  s.DeployConfig.Confirmed,
+		ExportManifest:             s.DeployConfig.ManifestPath != "",
+		CollectFindings:            s.DeployConfig.SarifPath != "",
+		RunnerArgs:                 s.DeployConfig.RunnerArgs,
+		SimulateIAM:                s.DeployConfig.SimulateIAM,
+		EstimateCost:               s.DeployConfig.CostEstimate,
+		PolicyDir:                  s.DeployConfig.PolicyDir,
+		PolicySoftFail:             s.DeployConfig.PolicySoftFail,
+		DetectDrift:                s.DeployConfig.Drift,
+		Backend:                    s.DeployConfig.Backend,
 		Logger: logger.WithFields(logrus.Fields{
 			"step":            s.Name,
 			"stepProgression": s.ProgressionLevel,
@@ -143,6 +179,13 @@ func InitExecution(s config.Step, logger *logrus.Entry, fs afero.Fs,
 	// Add previous step outputs from the track into stepParams
 	stepParams := AppendToStepParams(params, exec.DefaultStepOutputVariables)
 
+	// the declared inputs take precedence over the {stepName}-{output} variables
+	stepParams, err := AppendStepInputs(stepParams, s.Inputs, exec.DefaultStepOutputVariables, exec.RegionDeployType)
+	if err != nil {
+		exec.Logger.WithError(err).Error(err)
+		return exec, err
+	}
+
 	// if step has an output, add here (primarily for tests)
 	// TODO: find a better way to handle this that doesn't rely on re-calling this method for tests
 	if s.Output.OutputVariables != nil {
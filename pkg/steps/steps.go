@@ -5,7 +5,11 @@ package steps
 import (
 	"fmt"
 	pluginsarm "github.com/optum/runiac/plugins/arm"
+	pluginsexternal "github.com/optum/runiac/plugins/external"
+	pluginspulumi "github.com/optum/runiac/plugins/pulumi"
 	pluginsterraform "github.com/optum/runiac/plugins/terraform"
+	pluginsterragrunt "github.com/optum/runiac/plugins/terragrunt"
+	"os/exec"
 	"strings"
 
 	"github.com/optum/runiac/pkg/config"
@@ -17,7 +21,15 @@ func DetermineRunner(s config.Step) config.Stepper {
 		return pluginsarm.ArmStepper{}
 	case "terraform":
 		return pluginsterraform.TerraformStepper{}
+	case "pulumi":
+		return pluginspulumi.PulumiStepper{}
+	case "terragrunt":
+		return pluginsterragrunt.TerragruntStepper{}
 	default:
+		if _, err := exec.LookPath(config.ExternalRunnerBinary(s.DeployConfig.Runner)); err == nil {
+			return RunnerStepper{Runner: pluginsexternal.ExternalRunner{Binary: config.ExternalRunnerBinary(s.DeployConfig.Runner)}}
+		}
+
 		return nil
 	}
 }
@@ -34,6 +46,32 @@ func AppendToStepParams(stepParams map[string]string, incomingOutputVars map[str
 	return stepParams
 }
 
+// AppendStepInputs adds the input variables the step reads from the outputs of other steps to stepParams. A regional
+// execution reads the output of the referenced step's regional execution in the same region when it has one,
+// otherwise its primary output.
+func AppendStepInputs(stepParams map[string]string, inputs []config.StepInput, outputVars map[string]map[string]string, regionDeployType config.RegionDeployType) (map[string]string, error) {
+	for _, input := range inputs {
+		outputs := outputVars[input.Step]
+
+		if regionDeployType == config.RegionalRegionDeployType {
+			if regional, ok := outputVars[fmt.Sprintf("%s-%s", input.Step, config.RegionalRegionDeployType.String())]; ok {
+				if _, ok := regional[input.Output]; ok {
+					outputs = regional
+				}
+			}
+		}
+
+		value, ok := outputs[input.Output]
+		if !ok {
+			return stepParams, fmt.Errorf("input %s reads the output %s of step %s, which it did not output", input.Name, input.Output, input.Step)
+		}
+
+		stepParams[input.Name] = value
+	}
+
+	return stepParams, nil
+}
+
 func KeysStringMap(m map[string]map[string]string) string {
 	keys := make([]string, 0, len(m))
 	for k := range m {
@@ -54,3 +54,26 @@ func TestAddTrackOutputToParams(t *testing.T) {
 	require.Equal(t, "v2", mockParams["cool_step1-k2"], "stepParams should be set with the correct key and value")
 	require.Equal(t, "v3", mockParams["cool_step2-k3"], "stepParams should be set with the correct key and value")
 }
+
+func TestAppendStepInputs_ShouldPreferTheRegionalOutput(t *testing.T) {
+	outputVars := map[string]map[string]string{
+		"network":          {"vnet_id": "vnet-1", "subnet_id": "subnet-1"},
+		"network-regional": {"subnet_id": "subnet-2"},
+	}
+
+	inputs := []config.StepInput{
+		{Name: "network_vnet_id", Step: "network", Output: "vnet_id"},
+		{Name: "subnet", Step: "network", Output: "subnet_id"},
+	}
+
+	params, err := steps.AppendStepInputs(map[string]string{}, inputs, outputVars, config.PrimaryRegionDeployType)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"network_vnet_id": "vnet-1", "subnet": "subnet-1"}, params)
+
+	params, err = steps.AppendStepInputs(map[string]string{}, inputs, outputVars, config.RegionalRegionDeployType)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"network_vnet_id": "vnet-1", "subnet": "subnet-2"}, params, "a regional execution should read the regional output when there is one")
+
+	_, err = steps.AppendStepInputs(map[string]string{}, []config.StepInput{{Name: "dns_zone", Step: "dns", Output: "zone"}}, outputVars, config.PrimaryRegionDeployType)
+	require.EqualError(t, err, "input dns_zone reads the output zone of step dns, which it did not output")
+}
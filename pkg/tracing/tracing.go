@@ -0,0 +1,254 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracesPath is appended to an OTLP/HTTP endpoint to export traces
+const tracesPath = "/v1/traces"
+
+// protocolGRPC is the OTEL_EXPORTER_OTLP_PROTOCOL exporting to an OTLP/gRPC endpoint instead of OTLP/HTTP
+const protocolGRPC = "grpc"
+
+// instrumentationName names the tracer of runiac's spans
+const instrumentationName = "github.com/optum/runiac"
+
+// propagator reads and writes the W3C traceparent, which continues a trace across processes
+var propagator = propagation.TraceContext{}
+
+// Tracer exports the spans of a deployment to an OTLP endpoint with the OpenTelemetry SDK. The spans are exported by
+// a batch span processor as they end, so the spans of a deploy that crashes are exported up to its crash.
+type Tracer struct {
+	Endpoint    string // The OTLP traces endpoint, e.g. http://localhost:4318/v1/traces
+	ServiceName string
+
+	provider *sdktrace.TracerProvider
+	tracer   trace.Tracer
+}
+
+// Span is a timed operation of a trace. The methods of a nil span do nothing, so code can be traced whether or not
+// tracing is configured.
+type Span struct {
+	TraceID      string
+	SpanID       string
+	ParentSpanID string
+	Name         string
+
+	ctx    context.Context
+	span   trace.Span
+	tracer *Tracer
+	ended  sync.Once
+}
+
+// GetEndpoint returns the OTLP/HTTP traces endpoint, the configured endpoint or otherwise the endpoint of the
+// OpenTelemetry environment variables. The traces path is appended to endpoints that are not traces endpoints. Empty
+// when tracing is not configured.
+func GetEndpoint(configured string) string {
+	if configured == "" {
+		if traces := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"); traces != "" {
+			return traces
+		}
+
+		configured = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	}
+
+	if configured == "" || strings.HasSuffix(configured, tracesPath) {
+		return configured
+	}
+
+	return strings.TrimSuffix(configured, "/") + tracesPath
+}
+
+// getProtocol returns the OTLP protocol of the OpenTelemetry environment variables, grpc or otherwise http/protobuf
+func getProtocol() string {
+	if protocol := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL"); protocol != "" {
+		return protocol
+	}
+
+	return os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL")
+}
+
+// newExporter returns the OTLP exporter of the endpoint, OTLP/gRPC when the protocol is grpc and otherwise
+// OTLP/HTTP. The exporters read the OTEL_EXPORTER_OTLP_HEADERS and the other OpenTelemetry environment variables.
+func newExporter(endpoint string, protocol string) (*otlptrace.Exporter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid tracing endpoint '%s', expected a url such as http://localhost:4318", endpoint)
+	}
+
+	if protocol == protocolGRPC {
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(u.Host)}
+
+		if u.Scheme == "http" {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+
+		return otlptracegrpc.New(context.Background(), opts...)
+	}
+
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(u.Host), otlptracehttp.WithURLPath(u.Path)}
+
+	if u.Scheme == "http" {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+
+	return otlptracehttp.New(context.Background(), opts...)
+}
+
+// NewTracer returns a tracer exporting to the endpoint, configured with the OpenTelemetry environment variables,
+// e.g. OTEL_EXPORTER_OTLP_HEADERS, OTEL_EXPORTER_OTLP_PROTOCOL and OTEL_SERVICE_NAME
+func NewTracer(endpoint string) (*Tracer, error) {
+	exporter, err := newExporter(endpoint, getProtocol())
+	if err != nil {
+		return nil, err
+	}
+
+	t := NewTracerWithExporter(exporter)
+	t.Endpoint = endpoint
+
+	return t, nil
+}
+
+// NewTracerWithExporter returns a tracer batching its spans to the exporter
+func NewTracerWithExporter(exporter sdktrace.SpanExporter) *Tracer {
+	serviceName := os.Getenv("OTEL_SERVICE_NAME")
+	if serviceName == "" {
+		serviceName = "runiac"
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+	)
+
+	return &Tracer{
+		ServiceName: serviceName,
+		provider:    provider,
+		tracer:      provider.Tracer(instrumentationName),
+	}
+}
+
+// Flush exports the spans that ended without waiting for their batch
+func (t *Tracer) Flush(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+
+	return t.provider.ForceFlush(ctx)
+}
+
+// Shutdown exports the spans that ended and were not exported yet and stops the tracer
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+
+	return t.provider.Shutdown(ctx)
+}
+
+// StartRoot starts the root span of the tracer. When traceparent is a W3C traceparent, e.g. TRACEPARENT set by the
+// CI system, the span continues its trace. The attributes are key and value pairs.
+func (t *Tracer) StartRoot(name string, traceparent string, attributes ...string) *Span {
+	if t == nil {
+		return nil
+	}
+
+	carrier := propagation.HeaderCarrier(http.Header{})
+	carrier.Set("traceparent", strings.TrimSpace(traceparent))
+
+	ctx := propagator.Extract(context.Background(), carrier)
+
+	s := &Span{tracer: t}
+
+	if parent := trace.SpanContextFromContext(ctx); parent.IsValid() {
+		s.ParentSpanID = parent.SpanID().String()
+	}
+
+	return s.start(ctx, name, attributes)
+}
+
+// StartChild starts a span of the same trace as a child of the span
+func (s *Span) StartChild(name string, attributes ...string) *Span {
+	if s == nil {
+		return nil
+	}
+
+	child := &Span{
+		ParentSpanID: s.SpanID,
+		tracer:       s.tracer,
+	}
+
+	return child.start(s.ctx, name, attributes)
+}
+
+// start starts the span under the span of the context
+func (s *Span) start(ctx context.Context, name string, attributes []string) *Span {
+	kvs := []attribute.KeyValue{}
+
+	for i := 0; i+1 < len(attributes); i += 2 {
+		kvs = append(kvs, attribute.String(attributes[i], attributes[i+1]))
+	}
+
+	s.Name = name
+	s.ctx, s.span = s.tracer.tracer.Start(ctx, name, trace.WithAttributes(kvs...))
+
+	sc := s.span.SpanContext()
+	s.TraceID, s.SpanID = sc.TraceID().String(), sc.SpanID().String()
+
+	return s
+}
+
+// SetAttribute sets an attribute of the span
+func (s *Span) SetAttribute(key string, value string) {
+	if s == nil {
+		return
+	}
+
+	s.span.SetAttributes(attribute.String(key, value))
+}
+
+// Finish ends the span, an error marks the operation failed. Only the first call ends the span.
+func (s *Span) Finish(err error) {
+	if s == nil {
+		return
+	}
+
+	s.ended.Do(func() {
+		if err != nil {
+			s.span.RecordError(err)
+			s.span.SetStatus(codes.Error, err.Error())
+		} else {
+			s.span.SetStatus(codes.Ok, "")
+		}
+
+		s.span.End()
+	})
+}
+
+// Traceparent returns the W3C traceparent of the span for child processes to continue the trace
+func (s *Span) Traceparent() string {
+	if s == nil {
+		return ""
+	}
+
+	carrier := propagation.HeaderCarrier(http.Header{})
+	propagator.Inject(s.ctx, carrier)
+
+	return carrier.Get("traceparent")
+}
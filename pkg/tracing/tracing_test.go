@@ -0,0 +1,110 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestGetEndpoint_ShouldPreferConfiguredEndpoint(t *testing.T) {
+	defer os.Unsetenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	defer os.Unsetenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+
+	require.Empty(t, GetEndpoint(""), "tracing is not configured")
+
+	os.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://collector:4318/")
+	require.Equal(t, "http://collector:4318/v1/traces", GetEndpoint(""))
+
+	os.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "http://traces:4318/v1/traces")
+	require.Equal(t, "http://traces:4318/v1/traces", GetEndpoint(""))
+
+	require.Equal(t, "https://otlp.example.com/v1/traces", GetEndpoint("https://otlp.example.com"))
+}
+
+func TestNewTracer_ShouldRejectInvalidEndpoint(t *testing.T) {
+	_, err := NewTracer("collector:4318")
+	require.Error(t, err)
+}
+
+func TestStartRoot_ShouldContinueTraceparent(t *testing.T) {
+	tracer := NewTracerWithExporter(tracetest.NewInMemoryExporter())
+
+	root := tracer.StartRoot("deploy", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", root.TraceID)
+	require.Equal(t, "00f067aa0ba902b7", root.ParentSpanID)
+	require.Regexp(t, "^00-4bf92f3577b34da6a3ce929d0e0e4736-[0-9a-f]{16}-01$", root.Traceparent())
+
+	root = tracer.StartRoot("deploy", "invalid")
+	require.Len(t, root.TraceID, 32)
+	require.Empty(t, root.ParentSpanID, "an invalid traceparent starts a new trace")
+}
+
+func TestSpan_ShouldDoNothingWithoutTracer(t *testing.T) {
+	var tracer *Tracer
+
+	span := tracer.StartRoot("deploy", "")
+	require.Nil(t, span)
+
+	child := span.StartChild("track network")
+	child.SetAttribute("runiac.track", "network")
+	child.Finish(nil)
+	require.Nil(t, child)
+	require.Empty(t, child.Traceparent())
+	require.NoError(t, tracer.Shutdown(context.Background()))
+}
+
+func TestShutdown_ShouldExportEndedSpans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tracer := NewTracerWithExporter(exporter)
+
+	root := tracer.StartRoot("deploy", "", "runiac.deployment_ring", "prod")
+	step := root.StartChild("step network/vpc")
+	step.Finish(errors.New("Error running terraform apply"))
+	step.Finish(nil)
+	root.Finish(nil)
+
+	require.NoError(t, tracer.Flush(context.Background()))
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2, "a span is only ended once")
+
+	require.Equal(t, "step network/vpc", spans[0].Name)
+	require.Equal(t, root.SpanID, spans[0].Parent.SpanID().String())
+	require.Equal(t, codes.Error, spans[0].Status.Code)
+	require.Equal(t, "Error running terraform apply", spans[0].Status.Description)
+
+	require.Equal(t, "deploy", spans[1].Name)
+	require.Equal(t, codes.Ok, spans[1].Status.Code)
+	require.Equal(t, "runiac.deployment_ring", string(spans[1].Attributes[0].Key))
+	require.Equal(t, "prod", spans[1].Attributes[0].Value.AsString())
+}
+
+func TestNewTracer_ShouldExportToOTLPHTTPEndpoint(t *testing.T) {
+	requests := make(chan *http.Request, 10)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- r
+	}))
+	defer server.Close()
+
+	require.NoError(t, os.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "x-api-key=secret"))
+	defer os.Unsetenv("OTEL_EXPORTER_OTLP_HEADERS")
+
+	tracer, err := NewTracer(server.URL + tracesPath)
+	require.NoError(t, err)
+
+	tracer.StartRoot("deploy", "").Finish(nil)
+	require.NoError(t, tracer.Shutdown(context.Background()))
+
+	r := <-requests
+	require.Equal(t, tracesPath, r.URL.Path)
+	require.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+	require.Equal(t, "secret", r.Header.Get("x-api-key"))
+}
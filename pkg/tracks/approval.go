@@ -0,0 +1,280 @@
+package tracks
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// The gates a deploy waits for an approval at
+const (
+	ApprovalGatePlanned   = "planned"   // The track was planned and waits to be deployed
+	ApprovalGateCompleted = "completed" // The track completed and the remaining tracks wait to be deployed
+)
+
+const (
+	approvalStatusApproved = "approved"
+	approvalStatusDenied   = "denied"
+	approvalStatusPending  = "pending"
+)
+
+// trackApprovalManual is the approval of a track's runiac.yml that requires its plan to be approved before it is
+// deployed
+const trackApprovalManual = "manual"
+
+// defaultApprovalTimeout is how long an approval is waited for unless approval_timeout is set
+const defaultApprovalTimeout = time.Hour
+
+// ApproveTrackFunc waits for the approval to continue the deploy at the gate of the track, only an approval returns
+// without error
+type ApproveTrackFunc func(logger *logrus.Entry, cfg config.Config, track string, gate string) error
+
+var ApproveTrack ApproveTrackFunc = WaitForTrackApproval
+
+// approvalPollInterval is how often the approval endpoint is polled for a decision, stubbed by the tests
+var approvalPollInterval = 15 * time.Second
+
+// approvalInput is read for the interactive approvals, stubbed by the tests
+var approvalInput = bufio.NewReader(os.Stdin)
+
+// approvalMu serializes the approvals of tracks deploying concurrently so only one prompt is shown at a time
+var approvalMu sync.Mutex
+
+// trackApprovalRequest is posted to the approval endpoint to request approval for continuing the deploy at a gate
+type trackApprovalRequest struct {
+	CorrelationID  string `json:"correlation_id"`
+	Gate           string `json:"gate"`
+	Track          string `json:"track"`
+	Project        string `json:"project"`
+	DeploymentRing string `json:"deployment_ring"`
+	Environment    string `json:"environment"`
+	Namespace      string `json:"namespace"`
+	Version        string `json:"version"`
+}
+
+// trackApprovalResponse is returned by the approval endpoint for both the request and every poll
+type trackApprovalResponse struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// readTrackApproval reads whether the plan of the track must be approved before it is deployed from approval in the
+// track's runiac.yml
+func readTrackApproval(tConfig *viper.Viper, trackName string) (bool, error) {
+	if tConfig == nil || !tConfig.IsSet("approval") {
+		return false, nil
+	}
+
+	switch approval := strings.ToLower(tConfig.GetString("approval")); approval {
+	case trackApprovalManual:
+		return true, nil
+	case "", "auto":
+		return false, nil
+	default:
+		return false, fmt.Errorf("invalid approval '%s' of track %s, must be manual or auto", approval, trackName)
+	}
+}
+
+// isApprovalGated returns true when the deploy waits for approvals. Dry runs and destroys never deploy and self
+// destroying deploys are of ephemeral environments, so they are not gated.
+func isApprovalGated(cfg config.Config) bool {
+	return !cfg.DryRun && !cfg.Destroy && !cfg.SelfDestroy
+}
+
+// approvedTrackOutput is the output of a track's deploy, Err is set when the track was not approved
+type approvedTrackOutput struct {
+	Output Output
+	Err    error
+}
+
+// deployApprovedTrack deploys the track. A track requiring approval is planned first and only deployed once its plan
+// was approved, otherwise the output of its plan is returned. A failed plan is not approved, its failed steps fail
+// the deploy.
+func deployApprovedTrack(execution Execution, cfg config.Config, t Track) approvedTrackOutput {
+	out := make(chan Output)
+
+	if t.ApprovalRequired && isApprovalGated(cfg) {
+		execution.Logger.Infof("Planning track %s, its plan must be approved before it is deployed", t.Name)
+
+		go DeployTrack(execution, cfg, getPlanOnlyTrack(t), out)
+		plan := <-out
+
+		if err := getTrackErr(plan); err != nil {
+			execution.Logger.WithError(err).Errorf("The plan of track %s failed, it is not deployed", t.Name)
+			return approvedTrackOutput{Output: plan}
+		}
+
+		if err := ApproveTrack(execution.Logger, cfg, t.Name, ApprovalGatePlanned); err != nil {
+			return approvedTrackOutput{Output: plan, Err: err}
+		}
+	}
+
+	go DeployTrack(execution, cfg, t, out)
+
+	return approvedTrackOutput{Output: <-out}
+}
+
+// joinApprovalErr adds err to the approval errors of the deploy
+func joinApprovalErr(approvalErr error, err error) error {
+	if approvalErr == nil {
+		return err
+	}
+
+	return fmt.Errorf("%v; %w", approvalErr, err)
+}
+
+// WaitForTrackApproval waits for the approval to continue the deploy at the gate of the track. Interactive deploys
+// prompt for a confirmation, otherwise the approval is requested from approval_url.
+func WaitForTrackApproval(logger *logrus.Entry, cfg config.Config, track string, gate string) error {
+	approvalMu.Lock()
+	defer approvalMu.Unlock()
+
+	if cfg.Interactive {
+		return promptForApproval(logger, track, gate)
+	}
+
+	if cfg.ApprovalURL == "" {
+		return fmt.Errorf("track %s requires an approval to continue, but the deploy is neither interactive nor configured with an approval_url", track)
+	}
+
+	correlationID, err := newCorrelationID()
+	if err != nil {
+		return err
+	}
+
+	timeout := cfg.ApprovalTimeout
+	if timeout <= 0 {
+		timeout = defaultApprovalTimeout
+	}
+
+	return waitForApproval(logger, &http.Client{Timeout: 30 * time.Second}, cfg.ApprovalURL, trackApprovalRequest{
+		CorrelationID:  correlationID,
+		Gate:           gate,
+		Track:          track,
+		Project:        cfg.Project,
+		DeploymentRing: cfg.DeploymentRing,
+		Environment:    cfg.Environment,
+		Namespace:      cfg.Namespace,
+		Version:        cfg.Version,
+	}, timeout)
+}
+
+// getApprovalQuestion describes what continuing the deploy at the gate of the track does
+func getApprovalQuestion(track string, gate string) string {
+	if gate == ApprovalGatePlanned {
+		return fmt.Sprintf("Track %s was planned. Deploy it?", track)
+	}
+
+	return fmt.Sprintf("Track %s completed. Continue deploying the remaining tracks?", track)
+}
+
+// promptForApproval asks for confirmation on stdin, only yes approves
+func promptForApproval(logger *logrus.Entry, track string, gate string) error {
+	fmt.Printf("%s [y/N]: ", getApprovalQuestion(track, gate))
+
+	answer, err := approvalInput.ReadString('\n')
+	if err != nil && answer == "" {
+		return fmt.Errorf("unable to read the approval of track %s: %w", track, err)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		logger.Infof("Track %s was approved", track)
+		return nil
+	default:
+		return fmt.Errorf("track %s was not approved", track)
+	}
+}
+
+// newCorrelationID returns a random identifier correlating an approval request with this deploy
+func newCorrelationID() (string, error) {
+	b := make([]byte, 16)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// waitForApproval posts the approval request to url, then polls {url}/{correlation_id} until the request is
+// approved, denied or the timeout elapses. Only an approval returns without error.
+func waitForApproval(logger *logrus.Entry, client *http.Client, url string, req trackApprovalRequest, timeout time.Duration) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	resp, err := doApprovalRequest(client, http.MethodPost, url, body)
+	if err != nil {
+		return fmt.Errorf("requesting approval of track %s failed: %w", req.Track, err)
+	}
+
+	pollURL := fmt.Sprintf("%s/%s", strings.TrimSuffix(url, "/"), req.CorrelationID)
+	deadline := time.Now().Add(timeout)
+
+	for {
+		switch strings.ToLower(resp.Status) {
+		case approvalStatusApproved:
+			logger.Infof("Track %s was approved (correlation id %s) %s", req.Track, req.CorrelationID, resp.Message)
+			return nil
+		case approvalStatusDenied:
+			return fmt.Errorf("track %s was denied (correlation id %s): %s", req.Track, req.CorrelationID, resp.Message)
+		case approvalStatusPending, "":
+		default:
+			return fmt.Errorf("approval endpoint returned unknown status '%s'", resp.Status)
+		}
+
+		if time.Now().Add(approvalPollInterval).After(deadline) {
+			return fmt.Errorf("timed out after %s waiting for approval of track %s (correlation id %s)", timeout, req.Track, req.CorrelationID)
+		}
+
+		logger.Infof("Waiting for approval of track %s (correlation id %s)...", req.Track, req.CorrelationID)
+		time.Sleep(approvalPollInterval)
+
+		resp, err = doApprovalRequest(client, http.MethodGet, pollURL, nil)
+		if err != nil {
+			// transient failures should not lose an approval that may still be granted
+			logger.WithError(err).Warn("Polling approval endpoint failed")
+			resp = trackApprovalResponse{Status: approvalStatusPending}
+		}
+	}
+}
+
+func doApprovalRequest(client *http.Client, method string, url string, body []byte) (trackApprovalResponse, error) {
+	approval := trackApprovalResponse{}
+
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return approval, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return approval, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return approval, fmt.Errorf("approval endpoint %s returned %s", url, resp.Status)
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&approval)
+
+	return approval, err
+}
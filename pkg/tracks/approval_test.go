@@ -0,0 +1,95 @@
+package tracks
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadTrackApproval_ShouldRequireApprovalOfManualTracks(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	for dir, approval := range map[string]string{"tracks/manual": "manual", "tracks/auto": "auto", "tracks/invalid": "later"} {
+		require.NoError(t, afero.WriteFile(fs, dir+"/runiac.yml", []byte("approval: "+approval+"\n"), 0644))
+	}
+
+	for dir, expected := range map[string]bool{"tracks/manual": true, "tracks/auto": false, "tracks/none": false} {
+		tConfig, err := readStepConfig(fs, dir)
+		require.NoError(t, err)
+
+		required, err := readTrackApproval(tConfig, dir)
+		require.NoError(t, err)
+		require.Equal(t, expected, required, dir)
+	}
+
+	tConfig, err := readStepConfig(fs, "tracks/invalid")
+	require.NoError(t, err)
+
+	_, err = readTrackApproval(tConfig, "invalid")
+	require.EqualError(t, err, "invalid approval 'later' of track invalid, must be manual or auto")
+}
+
+func TestWaitForTrackApproval_ShouldPromptForInteractiveApproval(t *testing.T) {
+	logger := logrus.NewEntry(logrus.New())
+	defer func(input *bufio.Reader) { approvalInput = input }(approvalInput)
+
+	approvalInput = bufio.NewReader(strings.NewReader("yes\nn\n"))
+
+	require.NoError(t, WaitForTrackApproval(logger, config.Config{Interactive: true}, "network", ApprovalGatePlanned))
+	require.EqualError(t, WaitForTrackApproval(logger, config.Config{Interactive: true}, "network", ApprovalGateCompleted), "track network was not approved")
+	require.Error(t, WaitForTrackApproval(logger, config.Config{Interactive: true}, "network", ApprovalGateCompleted), "stdin is closed")
+
+	require.EqualError(t, WaitForTrackApproval(logger, config.Config{}, "network", ApprovalGatePlanned),
+		"track network requires an approval to continue, but the deploy is neither interactive nor configured with an approval_url")
+}
+
+func TestWaitForTrackApproval_ShouldPollApprovalURL(t *testing.T) {
+	logger := logrus.NewEntry(logrus.New())
+	defer func(interval time.Duration) { approvalPollInterval = interval }(approvalPollInterval)
+
+	approvalPollInterval = time.Millisecond
+
+	for final, expectedErr := range map[string]string{approvalStatusApproved: "", approvalStatusDenied: "track network was denied"} {
+		var polls int32
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			status := approvalStatusPending
+
+			if r.Method == http.MethodPost {
+				req := trackApprovalRequest{}
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+				assert.Equal(t, "network", req.Track)
+				assert.Equal(t, ApprovalGatePlanned, req.Gate)
+				assert.Equal(t, "prod", req.DeploymentRing)
+			} else if atomic.AddInt32(&polls, 1) > 1 {
+				status = final
+			}
+
+			_, _ = fmt.Fprintf(w, `{"status": "%s"}`, status)
+		}))
+
+		err := WaitForTrackApproval(logger, config.Config{ApprovalURL: server.URL, DeploymentRing: "prod"}, "network", ApprovalGatePlanned)
+		server.Close()
+
+		if expectedErr == "" {
+			require.NoError(t, err)
+		} else {
+			require.Error(t, err)
+			require.Contains(t, err.Error(), expectedErr)
+		}
+
+		require.Equal(t, int32(2), atomic.LoadInt32(&polls), "should poll until %s", final)
+	}
+}
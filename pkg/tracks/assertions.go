@@ -0,0 +1,321 @@
+package tracks
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	assertionsFile             = "tests/assertions.yml"
+	assertionDefaultTimeout    = 10 * time.Second
+	assertionDefaultRetryDelay = 10 * time.Second
+	assertionMaxBodySize       = 1 << 20 // The maximum size of a response body read for body_contains
+)
+
+// assertionVariablePattern matches the ${name} references of an assertion to the step's variables and outputs
+var assertionVariablePattern = regexp.MustCompile(`\$\{([A-Za-z0-9_-]+)\}`)
+
+// assertionHTTPClient sends the requests of the http assertions, assertionDial opens the connections of the tcp
+// assertions and assertionSleep waits between the attempts. Stubbed by the tests.
+var (
+	assertionHTTPClient = &http.Client{}
+	assertionDial       = net.DialTimeout
+	assertionSleep      = time.Sleep
+)
+
+// stepAssertions is the tests/assertions.yml of a step, the assertions are evaluated once the step applied
+//
+//	retries: 3            # attempts after the first failed attempt, e.g. while dns propagates
+//	retry_delay: 10s
+//	assertions:
+//	  - name: api is healthy
+//	    http:
+//	      url: https://${dns_name}/health
+//	      status: 200
+//	      body_contains: ok
+//	  - name: database accepts connections
+//	    tcp:
+//	      address: ${db_endpoint}:5432
+//
+// ${name} references a variable of the step, e.g. its own output variables or a runiac_* variable. The outputs of the
+// other steps are referenced as ${stepName-output}.
+type stepAssertions struct {
+	Retries    int           `yaml:"retries"`
+	RetryDelay time.Duration `yaml:"retry_delay"`
+	Assertions []assertion   `yaml:"assertions"`
+}
+
+// assertion is an http or tcp assertion of tests/assertions.yml
+type assertion struct {
+	Name string         `yaml:"name"`
+	HTTP *httpAssertion `yaml:"http"`
+	TCP  *tcpAssertion  `yaml:"tcp"`
+}
+
+// httpAssertion asserts the response to a request, the status defaults to 200 and the method to GET
+type httpAssertion struct {
+	URL          string            `yaml:"url"`
+	Method       string            `yaml:"method"`
+	Headers      map[string]string `yaml:"headers"`
+	Status       int               `yaml:"status"`
+	BodyContains string            `yaml:"body_contains"`
+	Timeout      time.Duration     `yaml:"timeout"`
+}
+
+// tcpAssertion asserts a connection to the host:port address can be opened
+type tcpAssertion struct {
+	Address string        `yaml:"address"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// readStepAssertions reads the tests/assertions.yml of the directory, nil when it has none
+func readStepAssertions(fs afero.Fs, dir string) (*stepAssertions, error) {
+	path := filepath.Join(dir, assertionsFile)
+
+	if !fileExists(fs, path) {
+		return nil, nil
+	}
+
+	b, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &stepAssertions{}
+
+	if err := yaml.Unmarshal(b, a); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", path, err)
+	}
+
+	for i, assertion := range a.Assertions {
+		if (assertion.HTTP == nil) == (assertion.TCP == nil) {
+			return nil, fmt.Errorf("invalid %s: assertions[%d] must be one of http or tcp", path, i)
+		}
+	}
+
+	if a.Retries < 0 || a.RetryDelay < 0 {
+		return nil, fmt.Errorf("invalid %s: retries and retry_delay can not be negative", path)
+	}
+
+	return a, nil
+}
+
+// evaluateStepAssertions evaluates the assertions with the step's variables, retrying the failed assertions up to
+// the retries. An error when an assertion failed every attempt.
+func evaluateStepAssertions(logger *logrus.Entry, a *stepAssertions, vars map[string]string) ([]config.AssertionResult, error) {
+	results := make([]config.AssertionResult, len(a.Assertions))
+
+	delay := a.RetryDelay
+	if delay == 0 {
+		delay = assertionDefaultRetryDelay
+	}
+
+	for attempt := 0; attempt <= a.Retries; attempt++ {
+		if attempt > 0 {
+			logger.Warnf("Retrying the failed assertions in %s, attempt %d of %d", delay, attempt+1, a.Retries+1)
+			assertionSleep(delay)
+		}
+
+		failed := 0
+
+		for i, assertion := range a.Assertions {
+			if results[i].Passed {
+				continue
+			}
+
+			results[i] = evaluateAssertion(assertion, vars)
+
+			if !results[i].Passed {
+				failed++
+			}
+		}
+
+		if failed == 0 {
+			break
+		}
+	}
+
+	failedNames := []string{}
+
+	for _, r := range results {
+		if r.Passed {
+			logger.Infof("Assertion '%s' passed", r.Name)
+		} else {
+			logger.Errorf("Assertion '%s' failed: %s", r.Name, r.Message)
+			failedNames = append(failedNames, r.Name)
+		}
+	}
+
+	if len(failedNames) > 0 {
+		return results, fmt.Errorf("%d of %d assertion(s) failed: %s", len(failedNames), len(results), strings.Join(failedNames, ", "))
+	}
+
+	return results, nil
+}
+
+// evaluateAssertion evaluates the http or tcp assertion once
+func evaluateAssertion(a assertion, vars map[string]string) config.AssertionResult {
+	result := config.AssertionResult{Name: a.Name}
+
+	var err error
+	if a.HTTP != nil {
+		err = evaluateHTTPAssertion(*a.HTTP, vars)
+	} else {
+		err = evaluateTCPAssertion(*a.TCP, vars)
+	}
+
+	if result.Name == "" {
+		result.Name = describeAssertion(a)
+	}
+
+	if err != nil {
+		result.Message = err.Error()
+	} else {
+		result.Passed = true
+	}
+
+	return result
+}
+
+// describeAssertion names an assertion without a name after what it asserts
+func describeAssertion(a assertion) string {
+	if a.HTTP != nil {
+		method := a.HTTP.Method
+		if method == "" {
+			method = http.MethodGet
+		}
+
+		return fmt.Sprintf("%s %s", strings.ToUpper(method), a.HTTP.URL)
+	}
+
+	return fmt.Sprintf("tcp %s", a.TCP.Address)
+}
+
+// evaluateHTTPAssertion sends the request, an error when it fails or the response does not match
+func evaluateHTTPAssertion(a httpAssertion, vars map[string]string) error {
+	url, err := expandAssertionVariables(a.URL, vars)
+	if err != nil {
+		return err
+	}
+
+	method := strings.ToUpper(a.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return err
+	}
+
+	for k, v := range a.Headers {
+		if v, err = expandAssertionVariables(v, vars); err != nil {
+			return err
+		}
+
+		req.Header.Set(k, v)
+	}
+
+	timeout := a.Timeout
+	if timeout == 0 {
+		timeout = assertionDefaultTimeout
+	}
+
+	client := *assertionHTTPClient
+	client.Timeout = timeout
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	status := a.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	if resp.StatusCode != status {
+		return fmt.Errorf("%s %s returned %d, expected %d", method, url, resp.StatusCode, status)
+	}
+
+	if a.BodyContains == "" {
+		return nil
+	}
+
+	contains, err := expandAssertionVariables(a.BodyContains, vars)
+	if err != nil {
+		return err
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, assertionMaxBodySize))
+	if err != nil {
+		return err
+	}
+
+	if !strings.Contains(string(body), contains) {
+		return fmt.Errorf("the response of %s %s does not contain '%s'", method, url, contains)
+	}
+
+	return nil
+}
+
+// evaluateTCPAssertion opens a connection to the address, an error when it can not be opened
+func evaluateTCPAssertion(a tcpAssertion, vars map[string]string) error {
+	address, err := expandAssertionVariables(a.Address, vars)
+	if err != nil {
+		return err
+	}
+
+	timeout := a.Timeout
+	if timeout == 0 {
+		timeout = assertionDefaultTimeout
+	}
+
+	conn, err := assertionDial("tcp", address, timeout)
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
+
+// expandAssertionVariables replaces the ${name} references of s with the step's variables, an error when a
+// referenced variable is not set
+func expandAssertionVariables(s string, vars map[string]string) (string, error) {
+	missing := []string{}
+
+	expanded := assertionVariablePattern.ReplaceAllStringFunc(s, func(ref string) string {
+		name := ref[2 : len(ref)-1]
+
+		v, ok := vars[name]
+		if !ok {
+			missing = append(missing, name)
+		}
+
+		return v
+	})
+
+	if len(missing) > 0 {
+		return "", fmt.Errorf("unknown variable(s) %s, not a variable or output of the step", strings.Join(missing, ", "))
+	}
+
+	return expanded, nil
+}
+
+// hasStepTests returns true when the directory has a tests/tests.test binary or tests/assertions.yml
+func hasStepTests(fs afero.Fs, dir string) bool {
+	return fileExists(fs, filepath.Join(dir, "tests/tests.test")) || fileExists(fs, filepath.Join(dir, assertionsFile))
+}
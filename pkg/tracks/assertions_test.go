@@ -0,0 +1,300 @@
+package tracks
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/pkg/steps"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+const stubAssertions = `
+retries: 2
+retry_delay: 1s
+assertions:
+  - name: api is healthy
+    http:
+      url: ${url}/health
+      status: 200
+      body_contains: ok
+  - tcp:
+      address: ${db_endpoint}:5432
+`
+
+// rollbackStepper counts the destroys of the step
+type rollbackStepper struct {
+	destroys *int
+}
+
+func (s rollbackStepper) PreExecute(exec config.StepExecution) (config.StepExecution, error) {
+	return exec, nil
+}
+
+func (s rollbackStepper) ExecuteStep(exec config.StepExecution) config.StepOutput {
+	return config.StepOutput{Status: config.Success}
+}
+
+func (s rollbackStepper) ExecuteStepTests(exec config.StepExecution) config.StepTestOutput {
+	return config.StepTestOutput{}
+}
+
+func (s rollbackStepper) ExecuteStepDestroy(exec config.StepExecution) config.StepOutput {
+	*s.destroys++
+	return config.StepOutput{Status: config.Success}
+}
+
+func TestReadStepAssertions_ShouldReadAssertionsYml(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	a, err := readStepAssertions(fs, "tracks/core/step1_api")
+
+	require.NoError(t, err)
+	require.Nil(t, a, "a step without tests/assertions.yml has no assertions")
+
+	_ = afero.WriteFile(fs, "tracks/core/step1_api/tests/assertions.yml", []byte(stubAssertions), 0644)
+
+	a, err = readStepAssertions(fs, "tracks/core/step1_api")
+
+	require.NoError(t, err)
+	require.Equal(t, 2, a.Retries)
+	require.Equal(t, time.Second, a.RetryDelay)
+	require.Len(t, a.Assertions, 2)
+	require.Equal(t, "${url}/health", a.Assertions[0].HTTP.URL)
+	require.Equal(t, "${db_endpoint}:5432", a.Assertions[1].TCP.Address)
+
+	_ = afero.WriteFile(fs, "tracks/core/step1_api/tests/assertions.yml", []byte("assertions:\n  - name: nothing\n"), 0644)
+
+	_, err = readStepAssertions(fs, "tracks/core/step1_api")
+
+	require.EqualError(t, err, "invalid tracks/core/step1_api/tests/assertions.yml: assertions[0] must be one of http or tcp")
+}
+
+func TestEvaluateStepAssertions_ShouldRetryFailedAssertions(t *testing.T) {
+	defer func(f func(time.Duration)) { assertionSleep = f }(assertionSleep)
+	defer func(f func(string, string, time.Duration) (net.Conn, error)) { assertionDial = f }(assertionDial)
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		// the api is ready on the second attempt
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"status": "ok"}`))
+	}))
+	defer server.Close()
+
+	sleeps := []time.Duration{}
+	assertionSleep = func(d time.Duration) { sleeps = append(sleeps, d) }
+
+	dialed := []string{}
+	assertionDial = func(network string, address string, timeout time.Duration) (net.Conn, error) {
+		dialed = append(dialed, address)
+
+		client, conn := net.Pipe()
+		_ = client.Close()
+
+		return conn, nil
+	}
+
+	fs := afero.NewMemMapFs()
+	_ = afero.WriteFile(fs, "step1_api/tests/assertions.yml", []byte(stubAssertions), 0644)
+	a, _ := readStepAssertions(fs, "step1_api")
+
+	results, err := evaluateStepAssertions(checkpointLogger, a, map[string]string{"url": server.URL, "db_endpoint": "db.internal"})
+
+	require.NoError(t, err)
+	require.Equal(t, []config.AssertionResult{
+		{Name: "api is healthy", Passed: true},
+		{Name: "tcp ${db_endpoint}:5432", Passed: true},
+	}, results)
+	require.Equal(t, 2, requests)
+	require.Equal(t, []string{"db.internal:5432"}, dialed, "passed assertions are not evaluated again")
+	require.Equal(t, []time.Duration{time.Second}, sleeps)
+}
+
+func TestEvaluateStepAssertions_ShouldFailAfterRetries(t *testing.T) {
+	defer func(f func(time.Duration)) { assertionSleep = f }(assertionSleep)
+	defer func(f func(string, string, time.Duration) (net.Conn, error)) { assertionDial = f }(assertionDial)
+
+	assertionSleep = func(d time.Duration) {}
+	assertionDial = func(network string, address string, timeout time.Duration) (net.Conn, error) {
+		return nil, errors.New("connection refused")
+	}
+
+	a := &stepAssertions{Retries: 1, Assertions: []assertion{
+		{Name: "database accepts connections", TCP: &tcpAssertion{Address: "${db_endpoint}:5432"}},
+		{Name: "cache accepts connections", TCP: &tcpAssertion{Address: "${cache_endpoint}:6379"}},
+	}}
+
+	results, err := evaluateStepAssertions(checkpointLogger, a, map[string]string{"db_endpoint": "db.internal"})
+
+	require.EqualError(t, err, "2 of 2 assertion(s) failed: database accepts connections, cache accepts connections")
+	require.Equal(t, "connection refused", results[0].Message)
+	require.Equal(t, "unknown variable(s) cache_endpoint, not a variable or output of the step", results[1].Message)
+}
+
+func TestEvaluateHTTPAssertion_ShouldMatchResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("created"))
+	}))
+	defer server.Close()
+
+	vars := map[string]string{"url": server.URL, "token": "token"}
+
+	require.NoError(t, evaluateHTTPAssertion(httpAssertion{URL: "${url}", Method: "post", Headers: map[string]string{"Authorization": "Bearer ${token}"}, Status: 201, BodyContains: "created"}, vars))
+
+	err := evaluateHTTPAssertion(httpAssertion{URL: "${url}"}, vars)
+	require.Error(t, err)
+	require.True(t, strings.HasSuffix(err.Error(), "returned 401, expected 200"), err.Error())
+
+	err = evaluateHTTPAssertion(httpAssertion{URL: "${url}", Method: "POST", Headers: map[string]string{"Authorization": "Bearer ${token}"}, Status: 201, BodyContains: "deleted"}, vars)
+	require.Error(t, err)
+	require.True(t, strings.HasSuffix(err.Error(), "does not contain 'deleted'"), err.Error())
+}
+
+func TestExecuteStepTest_ShouldRollBackStepWhenTestsFail(t *testing.T) {
+	defer func(f func(string, string, time.Duration) (net.Conn, error)) { assertionDial = f }(assertionDial)
+	assertionDial = func(network string, address string, timeout time.Duration) (net.Conn, error) {
+		return nil, errors.New("connection refused")
+	}
+
+	tests := map[string]struct {
+		rollback           bool
+		expectedDestroys   int
+		expectedRolledBack bool
+	}{
+		"ShouldRollBackWhenEnabled":       {rollback: true, expectedDestroys: 1, expectedRolledBack: true},
+		"ShouldNotRollBackWhenNotEnabled": {rollback: false, expectedDestroys: 0, expectedRolledBack: false},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			_ = afero.WriteFile(fs, "tracks/core/step1_db/tests/assertions.yml", []byte("assertions:\n  - tcp:\n      address: ${db_endpoint}:5432\n"), 0644)
+
+			destroys := 0
+			s := config.Step{
+				ID:           "core/db",
+				Name:         "db",
+				TrackName:    "core",
+				Dir:          "tracks/core/step1_db",
+				TestRollback: test.rollback,
+				Runner:       rollbackStepper{destroys: &destroys},
+				Output:       config.StepOutput{Status: config.Success, OutputVariables: map[string]interface{}{"db_endpoint": "db.internal"}},
+			}
+
+			in := make(chan config.Step, 1)
+			out := make(chan config.StepTestOutput, 1)
+			in <- s
+
+			executeStepTest(checkpointLogger, fs, "us-east-1", config.PrimaryRegionDeployType, map[string]map[string]string{}, in, out)
+			tested := <-out
+
+			require.Equal(t, "db", tested.StepName)
+			require.EqualError(t, tested.Err, "1 of 1 assertion(s) failed: tcp ${db_endpoint}:5432")
+			require.Equal(t, test.expectedDestroys, destroys)
+			require.Equal(t, test.expectedRolledBack, tested.RolledBack)
+		})
+	}
+}
+
+func TestRunStepTests_ShouldReferenceTheStepsOwnOutputsByName(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vpc") != "vpc-0123" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	fs := afero.NewMemMapFs()
+	_ = afero.WriteFile(fs, "tracks/core/step2_api/tests/assertions.yml", []byte("assertions:\n  - http:\n      url: ${url}/health\n      headers:\n        X-Vpc: ${network-vpc_id}\n      body_contains: ok\n"), 0644)
+
+	// the outputs of the other steps are passed to the execution as {stepName}-{output}
+	network := AppendTrackOutput(map[string]map[string]string{}, config.StepOutput{StepName: "network", OutputVariables: map[string]interface{}{"vpc_id": "vpc-0123"}})
+
+	s := config.Step{
+		Name:   "api",
+		Dir:    "tracks/core/step2_api",
+		Runner: rollbackStepper{destroys: new(int)},
+		Output: config.StepOutput{StepName: "api", Status: config.Success, OutputVariables: map[string]interface{}{"url": server.URL}},
+	}
+
+	output := runStepTests(checkpointLogger, fs, s, config.StepExecution{Dir: s.Dir, OptionalStepParams: steps.AppendToStepParams(map[string]string{}, network)})
+
+	require.NoError(t, output.Err)
+	require.Equal(t, []config.AssertionResult{{Name: "GET ${url}/health", Passed: true}}, output.Assertions)
+}
+
+func TestExecuteDeployTrackRegion_ShouldNotExecuteTheDependentsOfARolledBackStep(t *testing.T) {
+	defer func(f func(string, string, time.Duration) (net.Conn, error)) { assertionDial = f }(assertionDial)
+	defer func(f func(string, config.RegionDeployType, *logrus.Entry, afero.Fs, map[string]map[string]string, int, config.Step, chan<- config.Step, bool)) {
+		ExecuteStep = f
+	}(ExecuteStep)
+
+	assertionDial = func(network string, address string, timeout time.Duration) (net.Conn, error) {
+		return nil, errors.New("connection refused")
+	}
+
+	var mu sync.Mutex
+	executed := []string{}
+
+	ExecuteStep = func(region string, regionDeployType config.RegionDeployType, logger *logrus.Entry, fs afero.Fs, vars map[string]map[string]string, progression int, s config.Step, out chan<- config.Step, destroy bool) {
+		mu.Lock()
+		executed = append(executed, s.Name)
+		mu.Unlock()
+
+		s.Output = config.StepOutput{StepName: s.Name, Status: config.Success, OutputVariables: map[string]interface{}{"db_endpoint": "db.internal"}}
+		out <- s
+	}
+
+	fs := afero.NewMemMapFs()
+	_ = afero.WriteFile(fs, "tracks/core/step1_db/tests/assertions.yml", []byte("assertions:\n  - tcp:\n      address: ${db_endpoint}:5432\n"), 0644)
+
+	destroys := 0
+
+	in := make(chan RegionExecution, 1)
+	out := make(chan RegionExecution, 1)
+
+	in <- RegionExecution{
+		TrackName: "core",
+		TrackOrderedSteps: map[int][]config.Step{
+			1: {{Name: "db", TrackName: "core", ProgressionLevel: 1, Dir: "tracks/core/step1_db", TestsExist: true, TestRollback: true, Runner: rollbackStepper{destroys: &destroys}}},
+			2: {{Name: "app", TrackName: "core", ProgressionLevel: 2, Dir: "tracks/core/step2_app", Runner: rollbackStepper{destroys: &destroys}}},
+		},
+		Logger:           checkpointLogger,
+		Fs:               fs,
+		Region:           "us-east-1",
+		RegionDeployType: config.PrimaryRegionDeployType,
+	}
+
+	ExecuteDeployTrackRegion(in, out)
+	execution := <-out
+
+	require.Equal(t, []string{"db"}, executed, "the dependents of the rolled back step should not execute")
+	require.Equal(t, 1, destroys)
+	require.True(t, execution.Output.Steps["db"].TestOutput.RolledBack)
+	require.Equal(t, config.Skipped, execution.Output.Steps["app"].Output.Status)
+	require.Equal(t, 1, execution.Output.FailedTestCount)
+}
@@ -0,0 +1,236 @@
+package tracks
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+// Checkpoint records the step executions a deploy completed, so a failed deploy of the same version, environment
+// and namespace can be resumed
+type Checkpoint struct {
+	AccountID      string                    `json:"account_id"`
+	Environment    string                    `json:"environment"`
+	Namespace      string                    `json:"namespace"`
+	Version        string                    `json:"version"`
+	DeploymentRing string                    `json:"deployment_ring"`
+	Steps          map[string]CheckpointStep `json:"steps"` // The completed step executions by {trackName}/{stepName}/{regionDeployType}/{region}
+}
+
+// CheckpointStep is a completed step execution with the output variables later steps read
+type CheckpointStep struct {
+	CompletedAt     time.Time              `json:"completed_at"`
+	OutputVariables map[string]interface{} `json:"output_variables"`
+}
+
+// checkpoints records the completed step executions of the running deploy, nil when the deploy is not checkpointed
+var checkpoints *checkpointer
+
+// checkpointer records the step executions of the deploy to the checkpoint file as they complete and resolves
+// the step executions that are not executed again
+type checkpointer struct {
+	mu         sync.Mutex
+	fs         afero.Fs
+	path       string
+	current    Checkpoint
+	previous   map[string]CheckpointStep // The step executions the resumed deploy completed
+	fromTrack  string                    // The track of from_step, its steps of a lower progression level are not executed
+	fromLevel  int
+	fromStepID string
+}
+
+// getCheckpointKey returns the key of the step execution in the checkpoint
+func getCheckpointKey(trackName string, stepName string, regionDeployType config.RegionDeployType, region string) string {
+	return strings.Join([]string{trackName, stepName, regionDeployType.String(), region}, "/")
+}
+
+// newCheckpointer returns the checkpointer of the deploy, nil when it is a dry run, a destroy or no checkpoint_path
+// is configured. When resuming, the step executions the checkpoint of the same version, environment and namespace
+// records are not executed again. With from_step, the steps of its track at a lower progression level are not
+// executed either.
+func newCheckpointer(fs afero.Fs, logger *logrus.Entry, cfg config.Config, tracks []Track) (*checkpointer, error) {
+	if cfg.CheckpointPath == "" || cfg.DryRun || cfg.Destroy {
+		if cfg.Resume || cfg.FromStep != "" {
+			return nil, fmt.Errorf("resume and from_step require a checkpoint_path and can not be used with dry_run or destroy")
+		}
+
+		return nil, nil
+	}
+
+	c := &checkpointer{
+		fs:   fs,
+		path: cfg.CheckpointPath,
+		current: Checkpoint{
+			AccountID:      cfg.AccountID,
+			Environment:    cfg.Environment,
+			Namespace:      cfg.Namespace,
+			Version:        cfg.Version,
+			DeploymentRing: cfg.DeploymentRing,
+			Steps:          map[string]CheckpointStep{},
+		},
+		previous: map[string]CheckpointStep{},
+	}
+
+	if cfg.Resume {
+		previous, err := readCheckpoint(fs, cfg.CheckpointPath)
+
+		switch {
+		case err != nil:
+			return nil, err
+		case previous == nil:
+			logger.Warnf("No checkpoint of a previous deploy at %s, executing every step", cfg.CheckpointPath)
+		case previous.AccountID != cfg.AccountID || previous.Environment != cfg.Environment || previous.Namespace != cfg.Namespace || previous.Version != cfg.Version:
+			logger.Warnf("The checkpoint at %s was recorded by a deploy of version '%s' to environment '%s' and namespace '%s' of account '%s', executing every step",
+				cfg.CheckpointPath, previous.Version, previous.Environment, previous.Namespace, previous.AccountID)
+		default:
+			logger.Infof("Resuming the deploy, %d step executions completed in the previous deploy", len(previous.Steps))
+
+			for key, s := range previous.Steps {
+				c.previous[key] = s
+				c.current.Steps[key] = s
+			}
+		}
+	}
+
+	if cfg.FromStep != "" {
+		found := false
+
+		for _, t := range tracks {
+			for _, progression := range t.OrderedSteps {
+				for _, s := range progression {
+					if s.ID == strings.TrimSpace(cfg.FromStep) {
+						c.fromTrack, c.fromLevel, c.fromStepID, found = t.Name, s.ProgressionLevel, s.ID, true
+					}
+				}
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("from_step '%s' is not a targeted step, expected a {trackName}/{stepName} step id", cfg.FromStep)
+		}
+
+		logger.Infof("Starting the %s track at step %s", c.fromTrack, c.fromStepID)
+	}
+
+	// the checkpoint of a deploy that is not resumed starts empty
+	if err := c.write(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// readCheckpoint returns the checkpoint recorded at path, nil when there is none
+func readCheckpoint(fs afero.Fs, path string) (*Checkpoint, error) {
+	b, err := afero.ReadFile(fs, path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	c := &Checkpoint{}
+
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("unable to read the checkpoint at %s: %w", path, err)
+	}
+
+	return c, nil
+}
+
+// getSkippedOutput returns the output of the step execution when it is not executed again, the step completed in
+// the resumed deploy or precedes from_step
+func (c *checkpointer) getSkippedOutput(logger *logrus.Entry, s config.Step, regionDeployType config.RegionDeployType, region string) (config.StepOutput, bool) {
+	if c == nil {
+		return config.StepOutput{}, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	output := config.StepOutput{
+		Status:           config.Success,
+		RegionDeployType: regionDeployType,
+		Region:           region,
+		StepName:         s.Name,
+	}
+
+	if previous, ok := c.previous[getCheckpointKey(s.TrackName, s.Name, regionDeployType, region)]; ok {
+		logger.Info("Not executing the step, it completed in the previous deploy")
+
+		output.OutputVariables = previous.OutputVariables
+
+		return output, true
+	}
+
+	if s.TrackName == c.fromTrack && s.ProgressionLevel < c.fromLevel {
+		logger.Warnf("Not executing the step, it precedes %s. Its output variables are not available to the later steps of the track", c.fromStepID)
+
+		return output, true
+	}
+
+	return config.StepOutput{}, false
+}
+
+// record records the step execution when it completed successfully, a step that was only planned, e.g. before its
+// track was approved, is not recorded
+func (c *checkpointer) record(logger *logrus.Entry, s config.Step) {
+	if c == nil || s.Output.Err != nil || s.Output.Status != config.Success || s.DeployConfig.DryRun {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.current.Steps[getCheckpointKey(s.TrackName, s.Name, s.Output.RegionDeployType, s.Output.Region)] = CheckpointStep{
+		CompletedAt:     time.Now().UTC(),
+		OutputVariables: s.Output.OutputVariables,
+	}
+
+	if err := c.write(); err != nil {
+		logger.WithError(err).Warnf("Failed to record the step to the checkpoint at %s, a resumed deploy will execute it again", c.path)
+	}
+}
+
+// forget removes the step execution from the checkpoint, e.g. once it was rolled back
+func (c *checkpointer) forget(logger *logrus.Entry, s config.Step, regionDeployType config.RegionDeployType, region string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := getCheckpointKey(s.TrackName, s.Name, regionDeployType, region)
+	if _, ok := c.current.Steps[key]; !ok {
+		return
+	}
+
+	delete(c.current.Steps, key)
+
+	if err := c.write(); err != nil {
+		logger.WithError(err).Warnf("Failed to remove the step from the checkpoint at %s, a resumed deploy will not execute it again", c.path)
+	}
+}
+
+// write writes the checkpoint as json to the checkpoint file
+func (c *checkpointer) write() error {
+	b, err := json.MarshalIndent(c.current, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := c.fs.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+
+	return afero.WriteFile(c.fs, c.path, b, 0600)
+}
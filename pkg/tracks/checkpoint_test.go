@@ -0,0 +1,111 @@
+package tracks
+
+import (
+	"testing"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+var checkpointLogger = logrus.NewEntry(logrus.New())
+
+func getCheckpointTracks() []Track {
+	return []Track{
+		{
+			Name: "app",
+			OrderedSteps: map[int][]config.Step{
+				1: {{ID: "app/network", Name: "network", TrackName: "app", ProgressionLevel: 1}},
+				2: {{ID: "app/db", Name: "db", TrackName: "app", ProgressionLevel: 2}},
+				3: {{ID: "app/api", Name: "api", TrackName: "app", ProgressionLevel: 3}},
+			},
+		},
+	}
+}
+
+func TestCheckpointer_ShouldResumeCompletedStepExecutions(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	cfg := config.Config{CheckpointPath: "/runiac/tfstate/checkpoint.json", Environment: "dev", Version: "1.0.0"}
+	network := getCheckpointTracks()[0].OrderedSteps[1][0]
+	db := getCheckpointTracks()[0].OrderedSteps[2][0]
+
+	c, err := newCheckpointer(fs, checkpointLogger, cfg, getCheckpointTracks())
+	require.NoError(t, err)
+
+	network.Output = config.StepOutput{Status: config.Success, RegionDeployType: config.PrimaryRegionDeployType, Region: "us-east-1", OutputVariables: map[string]interface{}{"vpc_id": "vpc-123"}}
+	c.record(checkpointLogger, network)
+
+	db.Output = config.StepOutput{Status: config.Fail, RegionDeployType: config.PrimaryRegionDeployType, Region: "us-east-1"}
+	c.record(checkpointLogger, db)
+
+	// the resumed deploy of the same version
+	cfg.Resume = true
+
+	resumed, err := newCheckpointer(fs, checkpointLogger, cfg, getCheckpointTracks())
+	require.NoError(t, err)
+
+	output, ok := resumed.getSkippedOutput(checkpointLogger, network, config.PrimaryRegionDeployType, "us-east-1")
+	require.True(t, ok, "the completed step is not executed again")
+	require.Equal(t, config.Success, output.Status)
+	require.Equal(t, "network", output.StepName)
+	require.Equal(t, map[string]interface{}{"vpc_id": "vpc-123"}, output.OutputVariables)
+
+	_, ok = resumed.getSkippedOutput(checkpointLogger, network, config.RegionalRegionDeployType, "us-west-2")
+	require.False(t, ok, "the step is executed in the regions it did not complete in")
+
+	_, ok = resumed.getSkippedOutput(checkpointLogger, db, config.PrimaryRegionDeployType, "us-east-1")
+	require.False(t, ok, "the failed step is executed again")
+
+	// a deploy of another version executes every step
+	cfg.Version = "1.1.0"
+
+	other, err := newCheckpointer(fs, checkpointLogger, cfg, getCheckpointTracks())
+	require.NoError(t, err)
+
+	_, ok = other.getSkippedOutput(checkpointLogger, network, config.PrimaryRegionDeployType, "us-east-1")
+	require.False(t, ok)
+
+	recorded, err := readCheckpoint(fs, cfg.CheckpointPath)
+	require.NoError(t, err)
+	require.Equal(t, "1.1.0", recorded.Version)
+	require.Empty(t, recorded.Steps, "the checkpoint of a deploy that is not resumed starts empty")
+}
+
+func TestCheckpointer_ShouldStartTrackFromStep(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	cfg := config.Config{CheckpointPath: "/runiac/tfstate/checkpoint.json", FromStep: "app/db"}
+	steps := getCheckpointTracks()[0].OrderedSteps
+
+	c, err := newCheckpointer(fs, checkpointLogger, cfg, getCheckpointTracks())
+	require.NoError(t, err)
+
+	output, ok := c.getSkippedOutput(checkpointLogger, steps[1][0], config.PrimaryRegionDeployType, "us-east-1")
+	require.True(t, ok, "the steps preceding from_step are not executed")
+	require.Equal(t, config.Success, output.Status)
+
+	_, ok = c.getSkippedOutput(checkpointLogger, steps[2][0], config.PrimaryRegionDeployType, "us-east-1")
+	require.False(t, ok)
+
+	_, ok = c.getSkippedOutput(checkpointLogger, steps[3][0], config.PrimaryRegionDeployType, "us-east-1")
+	require.False(t, ok)
+
+	cfg.FromStep = "app/missing"
+
+	_, err = newCheckpointer(fs, checkpointLogger, cfg, getCheckpointTracks())
+	require.EqualError(t, err, "from_step 'app/missing' is not a targeted step, expected a {trackName}/{stepName} step id")
+}
+
+func TestCheckpointer_ShouldNotCheckpointDryRuns(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	c, err := newCheckpointer(fs, checkpointLogger, config.Config{CheckpointPath: "/runiac/tfstate/checkpoint.json", DryRun: true}, getCheckpointTracks())
+	require.NoError(t, err)
+	require.Nil(t, c)
+
+	_, ok := c.getSkippedOutput(checkpointLogger, config.Step{Name: "network"}, config.PrimaryRegionDeployType, "us-east-1")
+	require.False(t, ok, "a nil checkpointer executes every step")
+
+	_, err = newCheckpointer(fs, checkpointLogger, config.Config{Resume: true}, getCheckpointTracks())
+	require.Error(t, err, "resuming requires a checkpoint_path")
+}
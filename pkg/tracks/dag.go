@@ -0,0 +1,307 @@
+package tracks
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+)
+
+// readStepConfig reads the optional runiac.yml, runiac.yaml or runiac.hcl in the step directory, nil when the step
+// has none
+func readStepConfig(fs afero.Fs, dir string) (*viper.Viper, error) {
+	path := config.FindConfigFile(fs, dir)
+
+	// the step configuration file is optional
+	if path == "" {
+		return nil, nil
+	}
+
+	sConfig := viper.New()
+
+	if err := config.ReadConfigFile(fs, sConfig, path); err != nil {
+		return nil, fmt.Errorf("unable to read the configuration of step %s: %w", dir, err)
+	}
+
+	return sConfig, nil
+}
+
+// readStepDependsOn reads the depends_on list of the step configuration, the names of the steps of the same track the
+// step depends on. Steps may be referenced by name or {trackName}/{stepName}.
+func readStepDependsOn(sConfig *viper.Viper, trackName string, dir string) ([]string, error) {
+	if sConfig == nil || !sConfig.IsSet("depends_on") {
+		return nil, nil
+	}
+
+	dependsOn := []string{}
+
+	for _, name := range sConfig.GetStringSlice("depends_on") {
+		name = strings.TrimPrefix(strings.TrimSpace(name), trackName+"/")
+
+		if strings.Contains(name, "/") {
+			return nil, fmt.Errorf("step %s depends on %s, steps can only depend on steps of the %s track", dir, name, trackName)
+		}
+
+		dependsOn = append(dependsOn, name)
+	}
+
+	return dependsOn, nil
+}
+
+// validateStepDependencies returns an error when a step of the track depends on a step that does not exist in the
+// track's directory or the dependencies form a cycle. Dependencies on steps that are not targeted are ignored.
+func validateStepDependencies(t Track, stepNames []string) error {
+	names := map[string]bool{}
+	for _, name := range stepNames {
+		names[name] = true
+	}
+
+	for _, progression := range t.OrderedSteps {
+		for _, s := range progression {
+			for _, d := range s.DependsOn {
+				if !names[d] {
+					return fmt.Errorf("step %s depends on %s, which is not a step of the %s track", s.ID, d, t.Name)
+				}
+
+				if d == s.Name {
+					return fmt.Errorf("step %s depends on itself", s.ID)
+				}
+			}
+		}
+	}
+
+	dependencies := getStepDependencies(t.OrderedSteps)
+	visited := map[string]int{} // 1 while visiting the step's dependencies, 2 once they are acyclic
+
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch visited[name] {
+		case 1:
+			return fmt.Errorf("the depends_on of the %s track form a cycle: %s", t.Name, strings.Join(append(path, name), " -> "))
+		case 2:
+			return nil
+		}
+
+		visited[name] = 1
+
+		for _, d := range dependencies[name] {
+			if err := visit(d, append(path, name)); err != nil {
+				return err
+			}
+		}
+
+		visited[name] = 2
+
+		return nil
+	}
+
+	for _, s := range getGraphOrder(t.OrderedSteps) {
+		if err := visit(s.Name, []string{}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getStepDependencies returns the names of the steps each step of the track depends on, by step name. Steps declaring
+// depends_on depend on those steps, every other step depends on all steps of the previous progression level.
+func getStepDependencies(orderedSteps map[int][]config.Step) map[string][]string {
+	dependencies := map[string][]string{}
+	targeted := map[string]bool{}
+
+	for _, s := range getGraphOrder(orderedSteps) {
+		targeted[s.Name] = true
+	}
+
+	previous := []string{}
+
+	for _, level := range getProgressionLevels(orderedSteps) {
+		current := []string{}
+
+		for _, s := range orderedSteps[level] {
+			current = append(current, s.Name)
+
+			if s.DependsOn == nil {
+				dependencies[s.Name] = previous
+				continue
+			}
+
+			dependencies[s.Name] = []string{}
+
+			for _, d := range s.DependsOn {
+				if targeted[d] {
+					dependencies[s.Name] = append(dependencies[s.Name], d)
+				}
+			}
+		}
+
+		sort.Strings(current)
+		previous = current
+	}
+
+	return dependencies
+}
+
+// getProgressionLevels returns the progression levels of the track that have steps, in ascending order
+func getProgressionLevels(orderedSteps map[int][]config.Step) []int {
+	levels := []int{}
+
+	for level, steps := range orderedSteps {
+		if len(steps) > 0 {
+			levels = append(levels, level)
+		}
+	}
+
+	sort.Ints(levels)
+
+	return levels
+}
+
+// getGraphOrder returns the steps of the track ordered by progression level then name
+func getGraphOrder(orderedSteps map[int][]config.Step) []config.Step {
+	ordered := []config.Step{}
+
+	for _, level := range getProgressionLevels(orderedSteps) {
+		steps := append([]config.Step{}, orderedSteps[level]...)
+		sort.Slice(steps, func(i, j int) bool { return steps[i].Name < steps[j].Name })
+
+		ordered = append(ordered, steps...)
+	}
+
+	return ordered
+}
+
+// stepLaunchFunc is called once the steps s waits on completed. It returns the func executing s, which runs
+// concurrently with the other executing steps, and returns s with its output.
+type stepLaunchFunc func(s config.Step, completed []config.Step) func() config.Step
+
+// executeStepGraph executes the steps of the track, launching each step as soon as the steps it depends on
+// completed, so independent steps execute concurrently. When reverse, e.g. for destroys, a step waits on the steps
+// that depend on it instead. At most maxParallel steps execute at the same time, unlimited when 0. complete is called
+// with every step in the order the steps complete.
+func executeStepGraph(orderedSteps map[int][]config.Step, reverse bool, maxParallel int, launch stepLaunchFunc, complete func(s config.Step)) {
+	dependencies := getStepDependencies(orderedSteps)
+	waitsOn := map[string][]string{}
+	unblocks := map[string][]string{}
+
+	for name, deps := range dependencies {
+		for _, d := range deps {
+			if reverse {
+				waitsOn[d] = append(waitsOn[d], name)
+				unblocks[name] = append(unblocks[name], d)
+			} else {
+				waitsOn[name] = append(waitsOn[name], d)
+				unblocks[d] = append(unblocks[d], name)
+			}
+		}
+	}
+
+	ordered := getGraphOrder(orderedSteps)
+	steps := map[string]config.Step{}
+	pending := map[string]int{}
+
+	for _, s := range ordered {
+		steps[s.Name] = s
+		pending[s.Name] = len(waitsOn[s.Name])
+	}
+
+	var sem chan struct{}
+	if maxParallel > 0 {
+		sem = make(chan struct{}, maxParallel)
+	}
+
+	results := make(chan config.Step)
+	completed := map[string]config.Step{}
+
+	start := func(s config.Step) {
+		waited := []config.Step{}
+		for _, name := range waitsOn[s.Name] {
+			waited = append(waited, completed[name])
+		}
+
+		execute := launch(s, waited)
+
+		go func() {
+			if sem == nil {
+				results <- execute()
+				return
+			}
+
+			sem <- struct{}{}
+			s := execute()
+			<-sem
+
+			results <- s
+		}()
+	}
+
+	if reverse {
+		for i, j := 0, len(ordered)-1; i < j; i, j = i+1, j-1 {
+			ordered[i], ordered[j] = ordered[j], ordered[i]
+		}
+	}
+
+	for _, s := range ordered {
+		if pending[s.Name] == 0 {
+			start(s)
+		}
+	}
+
+	for range ordered {
+		s := <-results
+		completed[s.Name] = s
+
+		complete(s)
+
+		next := unblocks[s.Name]
+		sort.Strings(next)
+
+		for _, name := range next {
+			pending[name]--
+
+			if pending[name] == 0 {
+				start(steps[name])
+			}
+		}
+	}
+}
+
+// isStepBlocking returns true when a step waiting on s must be skipped, s failed, was skipped itself or its failed
+// tests roll it back
+func isStepBlocking(s config.Step) bool {
+	return s.Output.Err != nil || s.Output.Status == config.Fail || s.Output.Status == config.Skipped || (s.TestRollback && s.TestOutput.Err != nil)
+}
+
+// copyStepOutputVariables returns a copy of the output variables, so executing steps do not read the map while it is
+// updated with the output of completed steps
+func copyStepOutputVariables(vars map[string]map[string]string) map[string]map[string]string {
+	copied := make(map[string]map[string]string, len(vars))
+
+	for step, outputs := range vars {
+		copied[step] = make(map[string]string, len(outputs))
+
+		for k, v := range outputs {
+			copied[step][k] = v
+		}
+	}
+
+	return copied
+}
+
+// getStepDirNames returns the names of every step directory of the track, targeted or not
+func getStepDirNames(fs afero.Fs, dir string) []string {
+	names := []string{}
+
+	items, _ := afero.ReadDir(fs, dir)
+	for _, item := range items {
+		if strings.HasPrefix(item.Name(), "step") && len(item.Name()) > len("step")+2 {
+			names = append(names, item.Name()[len("step")+2:])
+		}
+	}
+
+	return names
+}
@@ -0,0 +1,153 @@
+package tracks
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/pkg/shell"
+	"github.com/optum/runiac/plugins/terraform/pkg/terraform"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+const (
+	hookPreDeploy  = "pre_deploy"
+	hookPostDeploy = "post_deploy"
+	hookPreTrack   = "pre_track"
+	hookPostTrack  = "post_track"
+	hookPreStep    = "pre_step"
+	hookPostStep   = "post_step"
+)
+
+// trackHookKeys are the hooks a track's runiac.yml may configure, stepHookKeys the hooks of a step's runiac.yml
+var (
+	trackHookKeys = []string{hookPreTrack, hookPostTrack, hookPreStep, hookPostStep}
+	stepHookKeys  = []string{hookPreStep, hookPostStep}
+)
+
+// hookEnvNamePattern matches the characters of an output variable's name that are not valid in an environment
+// variable's name
+var hookEnvNamePattern = regexp.MustCompile(`[^A-Z0-9_]`)
+
+// runHookCommand runs the hook's shell command in the directory with the additional environment variables, stubbed
+// by the tests
+var runHookCommand = func(logger *logrus.Entry, dir string, command string, env map[string]string) error {
+	return shell.RunCommand(shell.Command{
+		Command:        "sh",
+		Args:           []string{"-c", command},
+		WorkingDir:     dir,
+		Env:            env,
+		NonInteractive: true,
+		Logger:         logger,
+	})
+}
+
+// readHooks reads the hooks section of the track or step configuration, an error when it configures a hook that is
+// not one of the allowed hooks
+func readHooks(conf *viper.Viper, allowed []string, owner string) (config.Hooks, error) {
+	hooks := config.Hooks{}
+
+	if conf == nil || !conf.IsSet("hooks") {
+		return hooks, nil
+	}
+
+	for key := range conf.GetStringMap("hooks") {
+		if !contains(allowed, key) {
+			return hooks, fmt.Errorf("invalid hook '%s' of %s, must be one of %s", key, owner, strings.Join(allowed, ", "))
+		}
+	}
+
+	if err := conf.UnmarshalKey("hooks", &hooks); err != nil {
+		return hooks, fmt.Errorf("invalid hooks of %s: %w", owner, err)
+	}
+
+	return hooks, nil
+}
+
+// appendHooks returns the track and step hooks followed by the track and step hooks of more, the deploy hooks are
+// only configured by the project
+func appendHooks(hooks config.Hooks, more config.Hooks) config.Hooks {
+	join := func(a []string, b []string) []string {
+		return append(append([]string{}, a...), b...)
+	}
+
+	return config.Hooks{
+		PreTrack:  join(hooks.PreTrack, more.PreTrack),
+		PostTrack: join(hooks.PostTrack, more.PostTrack),
+		PreStep:   join(hooks.PreStep, more.PreStep),
+		PostStep:  join(hooks.PostStep, more.PostStep),
+	}
+}
+
+// runHooks runs the commands of the hook one after another, stopping at the first command that fails
+func runHooks(logger *logrus.Entry, hook string, commands []string, dir string, env map[string]string) error {
+	if len(commands) == 0 {
+		return nil
+	}
+
+	logger = logger.WithField("hook", hook)
+
+	for i, command := range commands {
+		logger.Infof("Running %s hook %d of %d", hook, i+1, len(commands))
+
+		if err := runHookCommand(logger, dir, command, env); err != nil {
+			return fmt.Errorf("%s hook '%s' failed: %w", hook, command, err)
+		}
+	}
+
+	return nil
+}
+
+// getHookEnv returns the RUNIAC_* variables of the deploy the hooks run with
+func getHookEnv(cfg config.Config, hook string, dryRun bool) map[string]string {
+	return map[string]string{
+		"RUNIAC_HOOK":            hook,
+		"RUNIAC_PROJECT":         cfg.Project,
+		"RUNIAC_ENVIRONMENT":     cfg.Environment,
+		"RUNIAC_NAMESPACE":       cfg.Namespace,
+		"RUNIAC_DEPLOYMENT_RING": cfg.DeploymentRing,
+		"RUNIAC_VERSION":         cfg.Version,
+		"RUNIAC_ACCOUNT_ID":      cfg.AccountID,
+		"RUNIAC_PRIMARY_REGION":  cfg.PrimaryRegion,
+		"RUNIAC_DRY_RUN":         strconv.FormatBool(dryRun),
+	}
+}
+
+// getTrackHookEnv returns the variables of the track's hooks
+func getTrackHookEnv(cfg config.Config, t Track, hook string) map[string]string {
+	env := getHookEnv(cfg, hook, cfg.DryRun || t.PlanOnly)
+	env["RUNIAC_TRACK"] = t.Name
+
+	return env
+}
+
+// getStepHookEnv returns the variables of the hooks of the step's execution, the step's output variables are set as
+// RUNIAC_OUTPUT_{NAME} once it executed
+func getStepHookEnv(s config.Step, exec config.StepExecution, hook string, outputs map[string]interface{}) map[string]string {
+	env := getHookEnv(s.DeployConfig, hook, exec.DryRun)
+	env["RUNIAC_TRACK"] = s.TrackName
+	env["RUNIAC_STEP"] = s.Name
+	env["RUNIAC_STEP_ID"] = s.ID
+	env["RUNIAC_REGION"] = exec.Region
+	env["RUNIAC_REGION_DEPLOY_TYPE"] = exec.RegionDeployType.String()
+
+	for name, value := range outputs {
+		env["RUNIAC_OUTPUT_"+hookEnvNamePattern.ReplaceAllString(strings.ToUpper(name), "_")] = terraform.OutputToString(value)
+	}
+
+	return env
+}
+
+// isStageSuccessful returns true when no track of the stage was skipped or failed
+func isStageSuccessful(stage Stage) bool {
+	for _, t := range stage.Tracks {
+		if t.Skipped || getTrackErr(t.Output) != nil {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,171 @@
+package tracks
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+// hookCall is a hook command run by the stubbed runHookCommand
+type hookCall struct {
+	Dir     string
+	Command string
+	Env     map[string]string
+}
+
+// stubHookCommands records the hook commands, failing the commands of failing
+func stubHookCommands(t *testing.T, failing ...string) *[]hookCall {
+	calls := &[]hookCall{}
+
+	f := runHookCommand
+	t.Cleanup(func() { runHookCommand = f })
+
+	runHookCommand = func(logger *logrus.Entry, dir string, command string, env map[string]string) error {
+		*calls = append(*calls, hookCall{Dir: dir, Command: command, Env: env})
+
+		if contains(failing, command) {
+			return errors.New("exit status 1")
+		}
+
+		return nil
+	}
+
+	return calls
+}
+
+// hookStepper counts the executions of the step, which outputs a dns name
+type hookStepper struct {
+	executions *int
+}
+
+func (s hookStepper) PreExecute(exec config.StepExecution) (config.StepExecution, error) {
+	return exec, nil
+}
+
+func (s hookStepper) ExecuteStep(exec config.StepExecution) config.StepOutput {
+	*s.executions++
+	return config.StepOutput{Status: config.Success, OutputVariables: map[string]interface{}{"dns-name": "api.example.com"}}
+}
+
+func (s hookStepper) ExecuteStepTests(exec config.StepExecution) config.StepTestOutput {
+	return config.StepTestOutput{}
+}
+
+func (s hookStepper) ExecuteStepDestroy(exec config.StepExecution) config.StepOutput {
+	return s.ExecuteStep(exec)
+}
+
+func TestReadHooks_ShouldOnlyReadAllowedHooks(t *testing.T) {
+	v := viper.New()
+	v.Set("hooks", map[string]interface{}{"pre_step": "./check.sh", "post_step": []string{"./smoke.sh", "./purge.sh"}})
+
+	hooks, err := readHooks(v, stepHookKeys, "step core/dns")
+
+	require.NoError(t, err)
+	require.Equal(t, []string{"./check.sh"}, hooks.PreStep, "a single command is a list of one command")
+	require.Equal(t, []string{"./smoke.sh", "./purge.sh"}, hooks.PostStep)
+
+	v.Set("hooks", map[string]interface{}{"post_deploy": "./notify.sh"})
+
+	_, err = readHooks(v, stepHookKeys, "step core/dns")
+
+	require.EqualError(t, err, "invalid hook 'post_deploy' of step core/dns, must be one of pre_step, post_step")
+
+	hooks, err = readHooks(nil, stepHookKeys, "step core/dns")
+
+	require.NoError(t, err, "steps without a runiac.yml have no hooks")
+	require.Empty(t, hooks.PreStep)
+}
+
+func TestAppendHooks_ShouldRunProjectHooksFirst(t *testing.T) {
+	hooks := appendHooks(config.Hooks{PreDeploy: []string{"deploy"}, PreStep: []string{"project"}}, config.Hooks{PreStep: []string{"track"}, PostTrack: []string{"track"}})
+
+	require.Equal(t, []string{"project", "track"}, hooks.PreStep)
+	require.Equal(t, []string{"track"}, hooks.PostTrack)
+	require.Empty(t, hooks.PreDeploy, "the deploy hooks are not hooks of a track")
+}
+
+func TestExecuteStepImpl_ShouldRunStepHooks(t *testing.T) {
+	tests := map[string]struct {
+		failing            []string
+		destroy            bool
+		expectedCommands   []string
+		expectedExecutions int
+		expectedStatus     config.DeployResult
+	}{
+		"ShouldRunPreAndPostHooks":         {expectedCommands: []string{"./check.sh", "./smoke.sh"}, expectedExecutions: 1, expectedStatus: config.Success},
+		"ShouldNotExecuteWhenPreHookFails": {failing: []string{"./check.sh"}, expectedCommands: []string{"./check.sh"}, expectedExecutions: 0, expectedStatus: config.Fail},
+		"ShouldFailWhenPostHookFails":      {failing: []string{"./smoke.sh"}, expectedCommands: []string{"./check.sh", "./smoke.sh"}, expectedExecutions: 1, expectedStatus: config.Fail},
+		"ShouldNotRunHooksWhenDestroying":  {destroy: true, expectedCommands: []string{}, expectedExecutions: 1, expectedStatus: config.Success},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			calls := stubHookCommands(t, test.failing...)
+			executions := 0
+			out := make(chan config.Step, 1)
+
+			s := config.Step{
+				ID:           "core/dns",
+				Name:         "dns",
+				TrackName:    "core",
+				Dir:          "tracks/core/step1_dns",
+				DeployConfig: config.Config{Project: "shop", Environment: "prod"},
+				Hooks:        config.Hooks{PreStep: []string{"./check.sh"}, PostStep: []string{"./smoke.sh"}},
+				Runner:       hookStepper{executions: &executions},
+			}
+
+			ExecuteStepImpl("us-east-1", config.PrimaryRegionDeployType, checkpointLogger, afero.NewMemMapFs(), map[string]map[string]string{}, 1, s, out, test.destroy)
+			executed := <-out
+
+			commands := []string{}
+			for _, c := range *calls {
+				commands = append(commands, c.Command)
+				require.Equal(t, s.Dir, c.Dir, "the step hooks run in the step's directory")
+			}
+
+			require.Equal(t, test.expectedCommands, commands)
+			require.Equal(t, test.expectedExecutions, executions)
+			require.Equal(t, test.expectedStatus, executed.Output.Status)
+
+			if len(*calls) == 2 {
+				require.Equal(t, "api.example.com", (*calls)[1].Env["RUNIAC_OUTPUT_DNS_NAME"], "the post_step hooks receive the step's outputs")
+			}
+		})
+	}
+}
+
+func TestGetStepHookEnv_ShouldSetContextAndOutputs(t *testing.T) {
+	s := config.Step{ID: "core/dns", Name: "dns", TrackName: "core", DeployConfig: config.Config{Project: "shop", Environment: "prod", DeploymentRing: "prd"}}
+	exec := config.StepExecution{Region: "us-east-2", RegionDeployType: config.RegionalRegionDeployType, DryRun: true}
+
+	env := getStepHookEnv(s, exec, hookPostStep, map[string]interface{}{"dns-name": "api.example.com", "port": 443})
+
+	require.Equal(t, "post_step", env["RUNIAC_HOOK"])
+	require.Equal(t, "shop", env["RUNIAC_PROJECT"])
+	require.Equal(t, "prod", env["RUNIAC_ENVIRONMENT"])
+	require.Equal(t, "prd", env["RUNIAC_DEPLOYMENT_RING"])
+	require.Equal(t, "core", env["RUNIAC_TRACK"])
+	require.Equal(t, "dns", env["RUNIAC_STEP"])
+	require.Equal(t, "core/dns", env["RUNIAC_STEP_ID"])
+	require.Equal(t, "us-east-2", env["RUNIAC_REGION"])
+	require.Equal(t, "regional", env["RUNIAC_REGION_DEPLOY_TYPE"])
+	require.Equal(t, "true", env["RUNIAC_DRY_RUN"])
+	require.Equal(t, "api.example.com", env["RUNIAC_OUTPUT_DNS_NAME"])
+	require.Equal(t, "443", env["RUNIAC_OUTPUT_PORT"])
+}
+
+func TestIsStageSuccessful_ShouldFailOnSkippedOrFailedTracks(t *testing.T) {
+	succeeded := Track{Name: "core"}
+	failed := Track{Name: "app", Output: Output{HookErr: errors.New("post_track hook './smoke.sh' failed")}}
+	skipped := Track{Name: "app", Skipped: true}
+
+	require.True(t, isStageSuccessful(Stage{Tracks: map[string]Track{"core": succeeded}}))
+	require.False(t, isStageSuccessful(Stage{Tracks: map[string]Track{"core": succeeded, "app": failed}}))
+	require.False(t, isStageSuccessful(Stage{Tracks: map[string]Track{"core": succeeded, "app": skipped}}))
+}
@@ -0,0 +1,122 @@
+package tracks
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/spf13/viper"
+)
+
+// inputNamePattern matches the names of input variables, the identifiers terraform accepts
+var inputNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// readStepInputs reads the inputs of the step configuration, the output variables of other steps of the track the
+// step reads as input variables. inputs is either a map of input variable names to {stepName}.{output} references or
+// a list of references, each read as the {stepName}_{output} input variable. Steps may be referenced by name or
+// {trackName}/{stepName}. The step configuration's keys are case insensitive, so input variable names are lower case.
+func readStepInputs(sConfig *viper.Viper, trackName string, dir string) ([]config.StepInput, error) {
+	if sConfig == nil || !sConfig.IsSet("inputs") {
+		return nil, nil
+	}
+
+	references := map[string]string{}
+
+	switch inputs := sConfig.Get("inputs").(type) {
+	case map[string]interface{}:
+		for name, reference := range inputs {
+			references[name] = fmt.Sprintf("%v", reference)
+		}
+	case []interface{}:
+		for _, reference := range inputs {
+			r := strings.TrimPrefix(strings.TrimSpace(fmt.Sprintf("%v", reference)), trackName+"/")
+			references[strings.Replace(r, ".", "_", 1)] = r
+		}
+	default:
+		return nil, fmt.Errorf("invalid inputs of step %s, must be a map of input variables to {stepName}.{output} or a list of {stepName}.{output}", dir)
+	}
+
+	inputs := []config.StepInput{}
+
+	for name, reference := range references {
+		reference = strings.TrimPrefix(strings.TrimSpace(reference), trackName+"/")
+
+		parts := strings.SplitN(reference, ".", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid input %s of step %s, '%s' must reference an output as {stepName}.{output}", name, dir, reference)
+		}
+
+		if strings.Contains(parts[0], "/") {
+			return nil, fmt.Errorf("input %s of step %s reads %s, steps can only read the outputs of steps of the %s track", name, dir, reference, trackName)
+		}
+
+		if !inputNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid input %s of step %s, the name may only contain letters, digits, '_' and '-'", name, dir)
+		}
+
+		inputs = append(inputs, config.StepInput{Name: name, Step: parts[0], Output: parts[1]})
+	}
+
+	sort.Slice(inputs, func(i, j int) bool { return inputs[i].Name < inputs[j].Name })
+
+	return inputs, nil
+}
+
+// validateStepInputs returns an error when a step of the track reads the output of a step that does not exist in
+// the track's directory or that the step does not depend on, directly or through the steps it depends on, since the
+// output would not be available when the step executes. The outputs of steps that are not targeted may be recorded
+// by a previous run, e.g. with --resume, and are only checked once the step executes.
+func validateStepInputs(t Track, stepNames []string) error {
+	names := map[string]bool{}
+	for _, name := range stepNames {
+		names[name] = true
+	}
+
+	targeted := map[string]bool{}
+	for _, s := range getGraphOrder(t.OrderedSteps) {
+		targeted[s.Name] = true
+	}
+
+	dependencies := getStepDependencies(t.OrderedSteps)
+
+	for _, s := range getGraphOrder(t.OrderedSteps) {
+		ancestors := getStepAncestors(dependencies, s.Name)
+
+		for _, input := range s.Inputs {
+			if !names[input.Step] {
+				return fmt.Errorf("input %s of step %s reads %s.%s, %s is not a step of the %s track", input.Name, s.ID, input.Step, input.Output, input.Step, t.Name)
+			}
+
+			if input.Step == s.Name {
+				return fmt.Errorf("input %s of step %s reads its own output %s", input.Name, s.ID, input.Output)
+			}
+
+			if targeted[input.Step] && !ancestors[input.Step] {
+				return fmt.Errorf("input %s of step %s reads %s.%s, but %s does not depend on %s. Add %s to its depends_on or move it to an earlier progression level", input.Name, s.ID, input.Step, input.Output, s.Name, input.Step, input.Step)
+			}
+		}
+	}
+
+	return nil
+}
+
+// getStepAncestors returns the names of the steps the step depends on, directly or through the steps it depends on
+func getStepAncestors(dependencies map[string][]string, name string) map[string]bool {
+	ancestors := map[string]bool{}
+
+	var visit func(name string)
+	visit = func(name string) {
+		for _, d := range dependencies[name] {
+			if !ancestors[d] {
+				ancestors[d] = true
+				visit(d)
+			}
+		}
+	}
+
+	visit(name)
+
+	return ancestors
+}
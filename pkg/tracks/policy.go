@@ -0,0 +1,112 @@
+package tracks
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// stepRetrySleep waits between the attempts of a step, stubbed by the tests
+var stepRetrySleep = time.Sleep
+
+// isStepPolicyEnvSet returns true when the RUNIAC_* env variable of the config key is set, e.g. by --step-retries
+func isStepPolicyEnvSet(key string) bool {
+	_, ok := os.LookupEnv("RUNIAC_" + strings.ToUpper(key))
+
+	return ok
+}
+
+// setStepRetryPolicy sets the timeout, retries and retry backoff of the step from RUNIAC_STEP_TIMEOUT,
+// RUNIAC_STEP_RETRIES and RUNIAC_STEP_RETRY_BACKOFF when set, otherwise from the step configuration, otherwise from
+// step_timeout, step_retries and step_retry_backoff of the config
+func setStepRetryPolicy(s *config.Step, sConfig *viper.Viper, cfg config.Config) error {
+	s.Timeout, s.Retries, s.RetryBackoff = cfg.StepTimeout, cfg.StepRetries, cfg.StepRetryBackoff
+
+	if sConfig != nil {
+		var err error
+
+		if sConfig.IsSet("timeout") && !isStepPolicyEnvSet("step_timeout") {
+			if s.Timeout, err = time.ParseDuration(sConfig.GetString("timeout")); err != nil {
+				return fmt.Errorf("invalid timeout of step %s, expected a duration such as 30m: %w", s.ID, err)
+			}
+		}
+
+		if sConfig.IsSet("retries") && !isStepPolicyEnvSet("step_retries") {
+			if s.Retries, err = strconv.Atoi(strings.TrimSpace(sConfig.GetString("retries"))); err != nil {
+				return fmt.Errorf("invalid retries of step %s, expected a number: %w", s.ID, err)
+			}
+		}
+
+		if sConfig.IsSet("retry_backoff") && !isStepPolicyEnvSet("step_retry_backoff") {
+			if s.RetryBackoff, err = time.ParseDuration(sConfig.GetString("retry_backoff")); err != nil {
+				return fmt.Errorf("invalid retry_backoff of step %s, expected a duration such as 30s: %w", s.ID, err)
+			}
+		}
+	}
+
+	if s.Timeout < 0 || s.Retries < 0 || s.RetryBackoff < 0 {
+		return fmt.Errorf("the timeout, retries and retry_backoff of step %s can not be negative", s.ID)
+	}
+
+	return nil
+}
+
+// executeStepWithRetries executes the step, retrying it up to s.Retries times while it fails. The wait between
+// attempts starts at s.RetryBackoff and doubles for every further attempt.
+func executeStepWithRetries(logger *logrus.Entry, s config.Step, exec config.StepExecution, execute func(exec config.StepExecution) config.StepOutput) config.StepOutput {
+	backoff := s.RetryBackoff
+
+	for attempt := 1; ; attempt++ {
+		output := executeStepAttempt(s, exec, execute)
+
+		if output.Err == nil && output.Status != config.Fail {
+			if attempt > 1 {
+				logger.WithField("attempt", attempt).Infof("Step succeeded on attempt %d of %d", attempt, s.Retries+1)
+			}
+
+			return output
+		}
+
+		if attempt > s.Retries {
+			return output
+		}
+
+		logger.WithError(output.Err).WithFields(logrus.Fields{
+			"attempt": attempt,
+			"retries": s.Retries,
+			"backoff": backoff.String(),
+		}).Warnf("Retrying step after attempt %d of %d failed, waiting %s", attempt, s.Retries+1, backoff)
+
+		stepRetrySleep(backoff)
+		backoff *= 2
+	}
+}
+
+// executeStepAttempt executes the step once. When the step has a timeout, the runners kill their commands once it is
+// exceeded and the attempt fails.
+func executeStepAttempt(s config.Step, exec config.StepExecution, execute func(exec config.StepExecution) config.StepOutput) config.StepOutput {
+	if s.Timeout <= 0 {
+		return execute(exec)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.Timeout)
+	defer cancel()
+
+	exec.Context = ctx
+
+	output := execute(exec)
+
+	if ctx.Err() == context.DeadlineExceeded {
+		output.Status = config.Fail
+		output.Err = fmt.Errorf("step %s timed out after %s", s.ID, s.Timeout)
+	}
+
+	return output
+}
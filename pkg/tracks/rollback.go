@@ -0,0 +1,78 @@
+package tracks
+
+import (
+	"fmt"
+
+	"github.com/optum/runiac/pkg/config"
+)
+
+// rollbackOnFailure destroys the step executions the deploy applied when rollback_on_failure is set and the deploy
+// failed, restoring an ephemeral namespace to its state before the deploy. Updated steps are destroyed as well, so the
+// config only permits it for the namespaced rings, see config.IsNamespacedRing. The tracks are destroyed in parallel and the
+// pretrack last, the steps of each track in reverse progression order.
+func (tracker DirectoryBasedTracker) rollbackOnFailure(cfg config.Config, output *Stage, parallelTracks []Track, preTrack *Track) {
+	if !cfg.RollbackOnFailure || cfg.DryRun || !config.IsNamespacedRing(cfg.DeploymentRing, cfg.Namespace) || (output.HookErr == nil && isStageSuccessful(*output)) {
+		return
+	}
+
+	applied := 0
+	for _, t := range output.Tracks {
+		applied += len(getRollbackSteps(t))
+	}
+
+	if applied == 0 {
+		tracker.Log.Info("Skipping rollback, the failed deploy did not apply a step")
+		return
+	}
+
+	tracker.Log.Warn("Rolling back the step executions of the failed deploy...")
+
+	tracker.destroyTracks(cfg, *output, parallelTracks, preTrack, true)
+
+	for _, t := range output.Tracks {
+		if err := getTrackErr(t.DestroyOutput); err != nil {
+			tracker.Log.WithError(err).Errorf("Failed to roll back track %s", t.Name)
+			return
+		}
+	}
+
+	output.RolledBack = true
+
+	tracker.Log.Info("Rolled back the failed deploy")
+}
+
+// getRollbackSteps returns the names of the steps the deploy applied or attempted to apply in each region of the
+// track, keyed by {regionDeployType}-{region}. Planned, skipped and rolled back steps are not included.
+func getRollbackSteps(t Track) map[string]map[string]bool {
+	rollbackSteps := map[string]map[string]bool{}
+
+	for _, exec := range t.Output.Executions {
+		for _, s := range exec.Output.Steps {
+			if s.DeployConfig.DryRun || s.TestOutput.RolledBack || (s.Output.Status != config.Success && s.Output.Status != config.Fail) {
+				continue
+			}
+
+			key := fmt.Sprintf("%s-%s", exec.RegionDeployType, exec.Region)
+			if rollbackSteps[key] == nil {
+				rollbackSteps[key] = map[string]bool{}
+			}
+
+			rollbackSteps[key][s.Name] = true
+		}
+	}
+
+	return rollbackSteps
+}
+
+// getDestroySteps returns the steps of the region destroyed when rolling back, nil when every step is destroyed
+func (execution Execution) getDestroySteps(regionDeployType config.RegionDeployType, region string) map[string]bool {
+	if execution.RollbackSteps == nil {
+		return nil
+	}
+
+	if steps, ok := execution.RollbackSteps[fmt.Sprintf("%s-%s", regionDeployType, region)]; ok {
+		return steps
+	}
+
+	return map[string]bool{}
+}
@@ -0,0 +1,73 @@
+package tracks
+
+import (
+	"testing"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetRollbackSteps_ShouldOnlyIncludeAppliedSteps(t *testing.T) {
+	track := Track{Output: Output{Executions: []RegionExecution{
+		{
+			Region:           "us-east-1",
+			RegionDeployType: config.PrimaryRegionDeployType,
+			Output: ExecutionOutput{Steps: map[string]config.Step{
+				"network": {Name: "network", Output: config.StepOutput{Status: config.Success}},
+				"db":      {Name: "db", Output: config.StepOutput{Status: config.Fail}},
+				"api":     {Name: "api", Output: config.StepOutput{Status: config.Skipped}},
+				"cache":   {Name: "cache", Output: config.StepOutput{Status: config.Success}, TestOutput: config.StepTestOutput{RolledBack: true}},
+			}},
+		},
+		{
+			Region:           "us-east-2",
+			RegionDeployType: config.RegionalRegionDeployType,
+			Output: ExecutionOutput{Steps: map[string]config.Step{
+				"network": {Name: "network", Output: config.StepOutput{Status: config.Na}},
+				"planned": {Name: "planned", DeployConfig: config.Config{DryRun: true}, Output: config.StepOutput{Status: config.Success}},
+			}},
+		},
+	}}}
+
+	require.Equal(t, map[string]map[string]bool{
+		"primary-us-east-1": {"network": true, "db": true},
+	}, getRollbackSteps(track))
+}
+
+func TestExecuteDestroyTrackRegion_ShouldOnlyDestroyRollbackSteps(t *testing.T) {
+	destroys := 0
+	stepper := rollbackStepper{destroys: &destroys}
+
+	in := make(chan RegionExecution, 1)
+	out := make(chan RegionExecution, 1)
+
+	in <- RegionExecution{
+		TrackName: "core",
+		TrackOrderedSteps: map[int][]config.Step{
+			1: {{ID: "core/network", Name: "network", TrackName: "core", ProgressionLevel: 1, Runner: stepper}},
+			2: {{ID: "core/db", Name: "db", TrackName: "core", ProgressionLevel: 2, Runner: stepper}},
+		},
+		Logger:           checkpointLogger,
+		Fs:               afero.NewMemMapFs(),
+		Region:           "us-east-1",
+		RegionDeployType: config.PrimaryRegionDeployType,
+		DestroySteps:     map[string]bool{"network": true},
+	}
+
+	ExecuteDestroyTrackRegion(in, out)
+	destroyed := <-out
+
+	require.Equal(t, 1, destroys)
+	require.Equal(t, config.Success, destroyed.Output.Steps["network"].Output.Status)
+	require.Equal(t, config.Na, destroyed.Output.Steps["db"].Output.Status, "the step the deploy did not apply is not destroyed")
+}
+
+func TestGetDestroySteps_ShouldDestroyNothingInRegionsWithoutAppliedSteps(t *testing.T) {
+	require.Nil(t, Execution{}.getDestroySteps(config.PrimaryRegionDeployType, "us-east-1"), "every step is destroyed when not rolling back")
+
+	execution := Execution{RollbackSteps: map[string]map[string]bool{"primary-us-east-1": {"network": true}}}
+
+	require.Equal(t, map[string]bool{"network": true}, execution.getDestroySteps(config.PrimaryRegionDeployType, "us-east-1"))
+	require.Equal(t, map[string]bool{}, execution.getDestroySteps(config.RegionalRegionDeployType, "us-east-2"))
+}
@@ -0,0 +1,46 @@
+package tracks
+
+import (
+	"github.com/optum/runiac/pkg/config"
+)
+
+// getRegionalRolloutWaves returns the waves the target regions are deployed in. The waves of the regional rollout
+// only contain the target regions, the target regions of no wave are deployed at once in a final wave.
+func getRegionalRolloutWaves(rollout []config.RolloutWave, targetRegions []string) []config.RolloutWave {
+	targeted := map[string]bool{}
+	for _, region := range targetRegions {
+		targeted[region] = true
+	}
+
+	waves := []config.RolloutWave{}
+	rolledOut := map[string]bool{}
+
+	for _, wave := range rollout {
+		regions := []string{}
+
+		for _, region := range wave.Regions {
+			if targeted[region] && !rolledOut[region] {
+				regions = append(regions, region)
+				rolledOut[region] = true
+			}
+		}
+
+		if len(regions) > 0 {
+			waves = append(waves, config.RolloutWave{Regions: regions, Parallel: wave.Parallel})
+		}
+	}
+
+	remaining := []string{}
+
+	for _, region := range targetRegions {
+		if !rolledOut[region] {
+			remaining = append(remaining, region)
+		}
+	}
+
+	if len(remaining) > 0 {
+		waves = append(waves, config.RolloutWave{Regions: remaining})
+	}
+
+	return waves
+}
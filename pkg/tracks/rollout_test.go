@@ -0,0 +1,28 @@
+package tracks
+
+import (
+	"testing"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetRegionalRolloutWaves_ShouldOnlyRolloutTargetRegions(t *testing.T) {
+	rollout := []config.RolloutWave{
+		{Regions: []string{"us-east-1"}},
+		{Regions: []string{"us-east-2", "us-west-1", "us-west-2"}, Parallel: 2},
+		{Regions: []string{"eu-west-1"}},
+	}
+
+	require.Equal(t, []config.RolloutWave{
+		{Regions: []string{"us-east-1"}},
+		{Regions: []string{"us-east-2", "us-west-2"}, Parallel: 2},
+		{Regions: []string{"ca-central-1"}},
+	}, getRegionalRolloutWaves(rollout, []string{"us-east-1", "us-west-2", "ca-central-1", "us-east-2"}), "the target regions of no wave are rolled out last")
+
+	require.Equal(t, []config.RolloutWave{
+		{Regions: []string{"us-east-1", "us-east-2"}},
+	}, getRegionalRolloutWaves(nil, []string{"us-east-1", "us-east-2"}), "every target region is deployed at once without a rollout")
+
+	require.Empty(t, getRegionalRolloutWaves(rollout, []string{}))
+}
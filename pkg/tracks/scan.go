@@ -0,0 +1,228 @@
+package tracks
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/pkg/shell"
+	"github.com/sirupsen/logrus"
+)
+
+// runScanCommand runs the scanner in the step directory, returning its stdout
+var runScanCommand = func(exec config.StepExecution, command string, args ...string) (string, error) {
+	return shell.RunCommandAndGetStdOut(shell.Command{
+		Command:        command,
+		Args:           args,
+		WorkingDir:     exec.Dir,
+		NonInteractive: true,
+		Logger:         exec.Logger,
+		Context:        exec.Context,
+	})
+}
+
+// tfsecOutput is the tfsec --format json output
+type tfsecOutput struct {
+	Results []struct {
+		RuleID      string `json:"rule_id"`
+		LongID      string `json:"long_id"`
+		Description string `json:"description"`
+		Severity    string `json:"severity"`
+		Location    struct {
+			Filename  string `json:"filename"`
+			StartLine int    `json:"start_line"`
+			EndLine   int    `json:"end_line"`
+		} `json:"location"`
+	} `json:"results"`
+}
+
+// checkovOutput is the checkov --output json report of a framework, checkov writes a list of reports when the
+// directory contains the source of several frameworks
+type checkovOutput struct {
+	CheckType string `json:"check_type"`
+	Results   struct {
+		FailedChecks []struct {
+			CheckID       string `json:"check_id"`
+			CheckName     string `json:"check_name"`
+			Resource      string `json:"resource"`
+			FilePath      string `json:"file_path"`
+			FileLineRange []int  `json:"file_line_range"`
+			Severity      string `json:"severity"`
+		} `json:"failed_checks"`
+	} `json:"results"`
+}
+
+// scanStep scans the source of the step execution with the configured scanner, returning the findings and an error
+// when the step must not be deployed, because a finding is at or above scan.fail_on or the source could not be
+// scanned while scan.fail_on is set
+func scanStep(logger *logrus.Entry, exec config.StepExecution, scan config.Scan) ([]config.Finding, error) {
+	tool := scan.Tool
+	if tool == "" {
+		tool = "tfsec"
+	}
+
+	logger = logger.WithField("scan", tool)
+	exec.Logger = logger
+
+	var findings []config.Finding
+	var err error
+
+	switch tool {
+	case "checkov":
+		resp, runErr := runScanCommand(exec, "checkov", "--directory", exec.Dir, "--output", "json", "--quiet", "--soft-fail")
+		findings, err = getCheckovFindings(exec.Dir, resp)
+
+		if err != nil && runErr != nil {
+			err = runErr
+		}
+	default:
+		resp, runErr := runScanCommand(exec, "tfsec", exec.Dir, "--format", "json", "--no-colour", "--soft-fail")
+		findings, err = getTfsecFindings(resp)
+
+		if err != nil && runErr != nil {
+			err = runErr
+		}
+	}
+
+	if err != nil {
+		if scan.FailOn == "" {
+			logger.WithError(err).Warn("Unable to scan the step")
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("unable to scan the step with %s: %w", tool, err)
+	}
+
+	blocking := 0
+
+	for _, f := range findings {
+		if scan.FailOn != "" && getScanSeverityRank(f.Severity) >= getScanSeverityRank(scan.FailOn) {
+			blocking++
+		}
+	}
+
+	logger.Infof("Scan produced %d finding(s)", len(findings))
+
+	if blocking > 0 {
+		return findings, fmt.Errorf("scan produced %d finding(s) of %s severity or higher", blocking, strings.ToLower(scan.FailOn))
+	}
+
+	return findings, nil
+}
+
+// getScanSeverityRank returns the rank of the severity in config.ScanSeverities, 0 when it is unknown
+func getScanSeverityRank(severity string) int {
+	for i, s := range config.ScanSeverities {
+		if strings.ToLower(severity) == s {
+			return i + 1
+		}
+	}
+
+	return 0
+}
+
+// getScanSeverity maps the severity of a scanner to one of config.ScanSeverities. Checks without a severity, such as
+// checkov's without a Bridgecrew API key, are medium.
+func getScanSeverity(severity string) string {
+	switch strings.ToLower(severity) {
+	case "critical":
+		return "critical"
+	case "high", "error":
+		return "high"
+	case "low", "info":
+		return "low"
+	default:
+		return "medium"
+	}
+}
+
+// newScanFinding returns the finding of a failed check, its level follows the severity
+func newScanFinding(ruleID string, severity string, message string, file string, startLine int, endLine int) config.Finding {
+	f := config.Finding{
+		RuleID:    ruleID,
+		Level:     config.FindingWarning,
+		Severity:  getScanSeverity(severity),
+		Message:   message,
+		File:      filepath.ToSlash(file),
+		StartLine: startLine,
+		EndLine:   endLine,
+	}
+
+	switch f.Severity {
+	case "critical", "high":
+		f.Level = config.FindingError
+	case "low":
+		f.Level = config.FindingNote
+	}
+
+	return f
+}
+
+// getTfsecFindings parses the tfsec --format json output into findings, the files are relative to the project root
+func getTfsecFindings(tfsecJSON string) ([]config.Finding, error) {
+	output := tfsecOutput{}
+
+	if err := json.Unmarshal([]byte(tfsecJSON), &output); err != nil {
+		return nil, err
+	}
+
+	findings := []config.Finding{}
+
+	for _, r := range output.Results {
+		file := r.Location.Filename
+
+		if filepath.IsAbs(file) {
+			if wd, err := os.Getwd(); err == nil {
+				if rel, err := filepath.Rel(wd, file); err == nil && !strings.HasPrefix(rel, "..") {
+					file = rel
+				}
+			}
+		}
+
+		ruleID := r.RuleID
+		if r.LongID != "" {
+			ruleID = r.LongID
+		}
+
+		findings = append(findings, newScanFinding("tfsec/"+ruleID, r.Severity, r.Description, file, r.Location.StartLine, r.Location.EndLine))
+	}
+
+	return findings, nil
+}
+
+// getCheckovFindings parses the checkov --output json output of the step directory dir into findings
+func getCheckovFindings(dir string, checkovJSON string) ([]config.Finding, error) {
+	reports := []checkovOutput{}
+
+	if strings.HasPrefix(strings.TrimSpace(checkovJSON), "{") {
+		report := checkovOutput{}
+
+		if err := json.Unmarshal([]byte(checkovJSON), &report); err != nil {
+			return nil, err
+		}
+
+		reports = append(reports, report)
+	} else if err := json.Unmarshal([]byte(checkovJSON), &reports); err != nil {
+		return nil, err
+	}
+
+	findings := []config.Finding{}
+
+	for _, report := range reports {
+		for _, c := range report.Results.FailedChecks {
+			startLine, endLine := 0, 0
+			if len(c.FileLineRange) == 2 {
+				startLine, endLine = c.FileLineRange[0], c.FileLineRange[1]
+			}
+
+			message := fmt.Sprintf("%s: %s", c.Resource, c.CheckName)
+
+			findings = append(findings, newScanFinding("checkov/"+c.CheckID, c.Severity, message, filepath.Join(dir, c.FilePath), startLine, endLine))
+		}
+	}
+
+	return findings, nil
+}
@@ -0,0 +1,154 @@
+package tracks
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+const stubTfsecOutput = `{"results": [
+	{"rule_id": "AWS002", "long_id": "aws-s3-enable-bucket-logging", "description": "Resource 'aws_s3_bucket.logs' does not have logging enabled.", "severity": "MEDIUM",
+	 "location": {"filename": "tracks/storage/step1_bucket/main.tf", "start_line": 1, "end_line": 5}},
+	{"rule_id": "AWS001", "description": "Resource 'aws_s3_bucket.logs' has an ACL which allows public access.", "severity": "HIGH",
+	 "location": {"filename": "tracks/storage/step1_bucket/main.tf", "start_line": 3, "end_line": 3}}
+]}`
+
+const stubCheckovOutput = `[
+	{"check_type": "terraform", "results": {"failed_checks": [
+		{"check_id": "CKV_AWS_20", "check_name": "S3 Bucket has an ACL defined which allows public READ access.", "resource": "aws_s3_bucket.logs",
+		 "file_path": "/main.tf", "file_line_range": [1, 5], "severity": null}
+	]}},
+	{"check_type": "secrets", "results": {"failed_checks": [
+		{"check_id": "CKV_SECRET_2", "check_name": "AWS Access Key", "resource": "key", "file_path": "/vars.tf", "file_line_range": [2, 2], "severity": "CRITICAL"}
+	]}}
+]`
+
+// scanStepper counts the executions of the step
+type scanStepper struct {
+	executions *int
+}
+
+func (s scanStepper) PreExecute(exec config.StepExecution) (config.StepExecution, error) {
+	return exec, nil
+}
+
+func (s scanStepper) ExecuteStep(exec config.StepExecution) config.StepOutput {
+	*s.executions++
+	return config.StepOutput{Status: config.Success}
+}
+
+func (s scanStepper) ExecuteStepTests(exec config.StepExecution) config.StepTestOutput {
+	return config.StepTestOutput{}
+}
+
+func (s scanStepper) ExecuteStepDestroy(exec config.StepExecution) config.StepOutput {
+	return s.ExecuteStep(exec)
+}
+
+func TestGetTfsecFindings_ShouldMapSeverities(t *testing.T) {
+	findings, err := getTfsecFindings(stubTfsecOutput)
+	require.NoError(t, err)
+
+	require.Equal(t, []config.Finding{
+		{RuleID: "tfsec/aws-s3-enable-bucket-logging", Level: config.FindingWarning, Severity: "medium", Message: "Resource 'aws_s3_bucket.logs' does not have logging enabled.", File: "tracks/storage/step1_bucket/main.tf", StartLine: 1, EndLine: 5},
+		{RuleID: "tfsec/AWS001", Level: config.FindingError, Severity: "high", Message: "Resource 'aws_s3_bucket.logs' has an ACL which allows public access.", File: "tracks/storage/step1_bucket/main.tf", StartLine: 3, EndLine: 3},
+	}, findings)
+
+	findings, err = getTfsecFindings(`{"results": null}`)
+	require.NoError(t, err)
+	require.Empty(t, findings)
+}
+
+func TestGetCheckovFindings_ShouldReadEveryFrameworkReport(t *testing.T) {
+	findings, err := getCheckovFindings("tracks/storage/step1_bucket", stubCheckovOutput)
+	require.NoError(t, err)
+
+	require.Len(t, findings, 2)
+	require.Equal(t, config.Finding{RuleID: "checkov/CKV_AWS_20", Level: config.FindingWarning, Severity: "medium", Message: "aws_s3_bucket.logs: S3 Bucket has an ACL defined which allows public READ access.", File: "tracks/storage/step1_bucket/main.tf", StartLine: 1, EndLine: 5}, findings[0], "checks without a severity are medium")
+	require.Equal(t, "critical", findings[1].Severity)
+
+	findings, err = getCheckovFindings("tracks/storage/step1_bucket", `{"check_type": "terraform", "results": {"failed_checks": []}}`)
+	require.NoError(t, err)
+	require.Empty(t, findings)
+}
+
+func TestScanStep_ShouldBlockFindingsAtFailOnSeverity(t *testing.T) {
+	commands := [][]string{}
+
+	defer func(f func(config.StepExecution, string, ...string) (string, error)) { runScanCommand = f }(runScanCommand)
+	runScanCommand = func(exec config.StepExecution, command string, args ...string) (string, error) {
+		commands = append(commands, append([]string{command}, args...))
+		return stubTfsecOutput, nil
+	}
+
+	exec := config.StepExecution{Dir: "tracks/storage/step1_bucket", Logger: checkpointLogger}
+
+	findings, err := scanStep(checkpointLogger, exec, config.Scan{Enabled: true})
+	require.NoError(t, err, "findings are only reported without fail_on")
+	require.Len(t, findings, 2)
+	require.Equal(t, []string{"tfsec", "tracks/storage/step1_bucket", "--format", "json", "--no-colour", "--soft-fail"}, commands[0])
+
+	findings, err = scanStep(checkpointLogger, exec, config.Scan{Enabled: true, FailOn: "HIGH"})
+	require.EqualError(t, err, "scan produced 1 finding(s) of high severity or higher")
+	require.Len(t, findings, 2)
+
+	_, err = scanStep(checkpointLogger, exec, config.Scan{Enabled: true, FailOn: "critical"})
+	require.NoError(t, err)
+
+	runScanCommand = func(exec config.StepExecution, command string, args ...string) (string, error) {
+		return "", errors.New("executable file not found in $PATH")
+	}
+
+	_, err = scanStep(checkpointLogger, exec, config.Scan{Enabled: true, Tool: "checkov"})
+	require.NoError(t, err, "failing to scan is only logged without fail_on")
+
+	_, err = scanStep(checkpointLogger, exec, config.Scan{Enabled: true, Tool: "checkov", FailOn: "high"})
+	require.EqualError(t, err, "unable to scan the step with checkov: executable file not found in $PATH")
+}
+
+func TestExecuteStepImpl_ShouldNotExecuteStepsBlockedByScan(t *testing.T) {
+	defer func(f func(config.StepExecution, string, ...string) (string, error)) { runScanCommand = f }(runScanCommand)
+	runScanCommand = func(exec config.StepExecution, command string, args ...string) (string, error) {
+		return stubTfsecOutput, nil
+	}
+
+	tests := map[string]struct {
+		deployConfig       config.Config
+		expectedExecutions int
+		expectedStatus     config.DeployResult
+	}{
+		"ShouldExecuteStepWithFindings":  {deployConfig: config.Config{Scan: config.Scan{Enabled: true}}, expectedExecutions: 1, expectedStatus: config.Success},
+		"ShouldNotExecuteBlockedStep":    {deployConfig: config.Config{Scan: config.Scan{Enabled: true, FailOn: "high"}}, expectedExecutions: 0, expectedStatus: config.Fail},
+		"ShouldOnlyScanWhenScanOnly":     {deployConfig: config.Config{ScanOnly: true}, expectedExecutions: 0, expectedStatus: config.Success},
+		"ShouldNotScanWhenScanDisabled":  {deployConfig: config.Config{}, expectedExecutions: 1, expectedStatus: config.Success},
+		"ShouldFailScanOnlyBlockedSteps": {deployConfig: config.Config{ScanOnly: true, Scan: config.Scan{FailOn: "medium"}}, expectedExecutions: 0, expectedStatus: config.Fail},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			executions := 0
+			out := make(chan config.Step, 1)
+
+			s := config.Step{
+				ID:           "storage/bucket",
+				Name:         "bucket",
+				TrackName:    "storage",
+				DeployConfig: test.deployConfig,
+				Runner:       scanStepper{executions: &executions},
+			}
+
+			ExecuteStepImpl("us-east-1", config.PrimaryRegionDeployType, checkpointLogger, afero.NewMemMapFs(), map[string]map[string]string{}, 1, s, out, false)
+			executed := <-out
+
+			require.Equal(t, test.expectedExecutions, executions)
+			require.Equal(t, test.expectedStatus, executed.Output.Status)
+
+			if test.deployConfig.Scan.Enabled || test.deployConfig.ScanOnly {
+				require.Len(t, executed.Output.Findings, 2, "the scan findings are part of the step's output")
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package tracks
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+// StepLogsCompleted marks the step logs of a deploy as complete once its tracks executed, so the logs of a deploy
+// in progress can be followed until it completes
+const StepLogsCompleted = ".done"
+
+// stepLogFormatter formats the entries of the step logs, with every field as the entries of a step's region
+// executions and retries are appended to the same log
+var stepLogFormatter = &logrus.TextFormatter{DisableColors: true, FullTimestamp: true}
+
+// stepLogMaskedValue replaces the masked values in the step logs
+const stepLogMaskedValue = "***"
+
+// minStepLogMaskedValueLength is the length below which values are not masked, matching the masking of the CLI
+const minStepLogMaskedValueLength = 4
+
+// stepLogHook appends the entries of a step to its log, with the values of the step_log_mask_env variables and the
+// matches of the mask_patterns masked
+type stepLogHook struct {
+	mu       sync.Mutex
+	file     afero.File
+	values   []string
+	patterns []*regexp.Regexp
+}
+
+func (h *stepLogHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *stepLogHook) Fire(entry *logrus.Entry) error {
+	b, err := stepLogFormatter.Format(entry)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	_, err = h.file.Write([]byte(h.mask(string(b))))
+
+	return err
+}
+
+// mask returns s with the masked values and the matches of the patterns replaced
+func (h *stepLogHook) mask(s string) string {
+	for _, v := range h.values {
+		s = strings.ReplaceAll(s, v, stepLogMaskedValue)
+	}
+
+	for _, re := range h.patterns {
+		s = re.ReplaceAllString(s, stepLogMaskedValue)
+	}
+
+	return s
+}
+
+// newStepLogHook returns the hook appending to the step log f, masking the values of the env variables named by
+// step_log_mask_env and the matches of the mask_patterns. A value is also masked as the formatter quotes it.
+func newStepLogHook(logger *logrus.Entry, f afero.File, cfg config.Config) *stepLogHook {
+	unique := map[string]bool{}
+
+	for _, name := range cfg.StepLogMaskEnv {
+		if v := os.Getenv(name); len(v) >= minStepLogMaskedValueLength {
+			unique[v] = true
+
+			if quoted := strconv.Quote(v); quoted[1:len(quoted)-1] != v {
+				unique[quoted[1:len(quoted)-1]] = true
+			}
+		}
+	}
+
+	h := &stepLogHook{file: f}
+
+	for v := range unique {
+		h.values = append(h.values, v)
+	}
+
+	// longest first so a value containing another value is masked entirely
+	sort.Slice(h.values, func(i, j int) bool {
+		if len(h.values[i]) != len(h.values[j]) {
+			return len(h.values[i]) > len(h.values[j])
+		}
+		return h.values[i] < h.values[j]
+	})
+
+	for _, p := range cfg.MaskPatterns {
+		if strings.TrimSpace(p) == "" {
+			continue
+		}
+
+		re, err := regexp.Compile(p)
+		if err != nil {
+			logger.WithError(err).Warnf("Ignoring the invalid mask pattern '%s' in the step logs", p)
+			continue
+		}
+
+		h.patterns = append(h.patterns, re)
+	}
+
+	return h
+}
+
+// getStepLog returns the path of the step's log in step_log_dir, {trackName}/{stepName}.log
+func getStepLog(dir string, s config.Step) string {
+	return filepath.Join(dir, s.TrackName, s.Name+".log")
+}
+
+// openStepLog returns a logger that also appends the entries of the step, including the output of the runner's
+// commands, to the step's log when step_log_dir is set. Sensitive values are masked as they are in the terminal.
+// The returned func closes the log.
+func openStepLog(logger *logrus.Entry, fs afero.Fs, s config.Step) (*logrus.Entry, func()) {
+	if s.DeployConfig.StepLogDir == "" {
+		return logger, func() {}
+	}
+
+	path := getStepLog(s.DeployConfig.StepLogDir, s)
+
+	err := fs.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		logger.WithError(err).Warnf("Unable to persist the output of step %s to %s", s.ID, path)
+		return logger, func() {}
+	}
+
+	// the log may hold output the masking misses, only readable by the user that ran the step
+	f, err := fs.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		logger.WithError(err).Warnf("Unable to persist the output of step %s to %s", s.ID, path)
+		return logger, func() {}
+	}
+
+	// a copy of the logger, so only the entries of this step execution are appended to its log
+	stepLogger := logrus.New()
+	stepLogger.Out = logger.Logger.Out
+	stepLogger.Formatter = logger.Logger.Formatter
+	stepLogger.ReportCaller = logger.Logger.ReportCaller
+	stepLogger.ExitFunc = logger.Logger.ExitFunc
+	stepLogger.SetLevel(logger.Logger.GetLevel())
+
+	for level, hooks := range logger.Logger.Hooks {
+		stepLogger.Hooks[level] = append([]logrus.Hook{}, hooks...)
+	}
+
+	stepLogger.AddHook(newStepLogHook(logger, f, s.DeployConfig))
+
+	return stepLogger.WithFields(logger.Data), func() { _ = f.Close() }
+}
@@ -0,0 +1,76 @@
+package tracks
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenStepLog_ShouldAppendEntriesOfStepToItsLog(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	var out bytes.Buffer
+	logs := logrus.New()
+	logs.Out = &out
+	logger := logs.WithField("region", "us-east-1")
+
+	s := config.Step{Name: "vpc", TrackName: "network", ID: "network/vpc", DeployConfig: config.Config{StepLogDir: "/runiac/logs"}}
+
+	for _, message := range []string{"terraform plan", "terraform apply"} {
+		stepLogger, closeStepLog := openStepLog(logger, fs, s)
+		stepLogger.Info(message)
+		closeStepLog()
+	}
+
+	logger.Info("not of the step")
+
+	b, err := afero.ReadFile(fs, "/runiac/logs/network/vpc.log")
+	require.NoError(t, err)
+	require.Contains(t, string(b), `msg="terraform plan" region=us-east-1`)
+	require.Contains(t, string(b), `msg="terraform apply" region=us-east-1`)
+	require.NotContains(t, string(b), "not of the step")
+
+	require.Contains(t, out.String(), "terraform apply", "the entries of the step should still be logged")
+
+	s.DeployConfig.StepLogDir = ""
+	stepLogger, _ := openStepLog(logger, fs, s)
+	require.Equal(t, logger, stepLogger, "without step_log_dir the logger should not be copied")
+}
+
+func TestOpenStepLog_ShouldMaskSensitiveValues(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	require.NoError(t, os.Setenv("TF_VAR_db_password", `s3cr"et-value`))
+	require.NoError(t, os.Setenv("ARM_CLIENT_SECRET", "abc"))
+	defer os.Unsetenv("TF_VAR_db_password")
+	defer os.Unsetenv("ARM_CLIENT_SECRET")
+
+	logs := logrus.New()
+	logs.Out = &bytes.Buffer{}
+
+	s := config.Step{Name: "db", TrackName: "data", ID: "data/db", DeployConfig: config.Config{
+		StepLogDir:     "/runiac/logs",
+		StepLogMaskEnv: []string{"TF_VAR_db_password", "ARM_CLIENT_SECRET"},
+		MaskPatterns:   []string{`ghp_[A-Za-z0-9]+`, "("},
+	}}
+
+	stepLogger, closeStepLog := openStepLog(logs.WithField("region", "us-east-1"), fs, s)
+	stepLogger.Info(`password = "s3cr"et-value", token ghp_abc123, key abc`)
+	closeStepLog()
+
+	b, err := afero.ReadFile(fs, "/runiac/logs/data/db.log")
+	require.NoError(t, err)
+	require.NotContains(t, string(b), "et-value")
+	require.NotContains(t, string(b), "ghp_abc123")
+	require.Contains(t, string(b), "token ***")
+	require.Contains(t, string(b), "key abc", "values shorter than the minimum should not be masked")
+
+	info, err := fs.Stat("/runiac/logs/data/db.log")
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
@@ -0,0 +1,224 @@
+package tracks
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+// teardownStep is a step of the teardown order along with its track
+type teardownStep struct {
+	Track Track
+	Step  config.Step
+}
+
+// teardownResult is the destroy output of a step in one of its regions
+type teardownResult struct {
+	Execution *RegionExecution
+	Step      config.Step
+}
+
+// getTeardownSteps returns the executed steps in the teardown order. Steps of the order that were not executed,
+// e.g. not targeted by the step whitelist, are ignored. Every executed step must be part of the order.
+func getTeardownSteps(order []string, tracks map[string]Track) ([]teardownStep, error) {
+	executed := map[string]teardownStep{}
+
+	for _, t := range tracks {
+		for _, progression := range t.OrderedSteps {
+			for _, s := range progression {
+				executed[s.ID] = teardownStep{Track: t, Step: s}
+			}
+		}
+	}
+
+	teardown := []teardownStep{}
+	ordered := map[string]bool{}
+
+	for _, id := range order {
+		id = strings.TrimSpace(id)
+
+		if ordered[id] {
+			return nil, fmt.Errorf("step %s is listed more than once in the teardown order", id)
+		}
+
+		ordered[id] = true
+
+		if s, ok := executed[id]; ok {
+			teardown = append(teardown, s)
+		}
+	}
+
+	missing := []string{}
+
+	for id := range executed {
+		if !ordered[id] {
+			missing = append(missing, id)
+		}
+	}
+
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("the teardown order does not include step(s) %s", strings.Join(missing, ", "))
+	}
+
+	return teardown, nil
+}
+
+// ExecuteTeardownOrder destroys the executed steps one at a time in the configured teardown order instead of the
+// reverse progression order of each track. Each step is destroyed across the regional regions before the primary
+// region, and the remaining steps are skipped after a failure.
+func ExecuteTeardownOrder(logger *logrus.Entry, fs afero.Fs, cfg config.Config, output Stage) error {
+	teardown, err := getTeardownSteps(cfg.TeardownOrder, output.Tracks)
+	if err != nil {
+		return err
+	}
+
+	executions := map[string]map[string]*RegionExecution{}
+	failed := false
+
+	for _, ts := range teardown {
+		t := output.Tracks[ts.Track.Name]
+
+		if executions[t.Name] == nil {
+			executions[t.Name] = map[string]*RegionExecution{}
+		}
+
+		stepLogger := logger.WithFields(logrus.Fields{
+			"track":  t.Name,
+			"action": "destroy",
+		})
+
+		targets := []RegionExecution{}
+
+		if ts.Step.RegionalResourcesExist {
+			for _, region := range cfg.RegionalRegions {
+				targets = append(targets, RegionExecution{Region: region, RegionDeployType: config.RegionalRegionDeployType})
+			}
+		}
+
+		groups := [][]RegionExecution{targets}
+		primaries := []RegionExecution{}
+
+		for _, region := range getPrimaryRegions(cfg) {
+			primary := RegionExecution{Region: region, RegionDeployType: config.PrimaryRegionDeployType}
+
+			if cfg.ParallelPrimaryRegions {
+				primaries = append(primaries, primary)
+			} else {
+				groups = append(groups, []RegionExecution{primary})
+			}
+		}
+
+		if len(primaries) > 0 {
+			groups = append(groups, primaries)
+		}
+
+		// the regional regions are destroyed in parallel, followed by the primary regions
+		for _, group := range groups {
+			resultChan := make(chan teardownResult)
+
+			for _, target := range group {
+				exec := getTeardownExecution(executions[t.Name], t, output.Tracks[PRE_TRACK_NAME], target, stepLogger, fs)
+
+				go func(exec *RegionExecution, s config.Step, skip bool) {
+					if skip {
+						stepLogger.WithField("step", s.Name).Warn("Skipping step due to earlier step failures in the teardown order")
+
+						s.Output.Status = config.Skipped
+						resultChan <- teardownResult{Execution: exec, Step: s}
+						return
+					}
+
+					sChan := make(chan config.Step, 1)
+
+					ExecuteStep(exec.Region, exec.RegionDeployType, stepLogger.WithFields(logrus.Fields{
+						"region":           exec.Region,
+						"regionDeployType": exec.RegionDeployType.String(),
+					}), fs, exec.Output.StepOutputVariables, s.ProgressionLevel, s, sChan, true)
+
+					resultChan <- teardownResult{Execution: exec, Step: <-sChan}
+				}(exec, ts.Step, failed)
+			}
+
+			for range group {
+				result := <-resultChan
+				exec, s := result.Execution, result.Step
+
+				if s.Output.Status == config.Skipped {
+					exec.Output.SkippedCount++
+				} else {
+					exec.Output.ExecutedCount++
+				}
+
+				exec.Output.Steps[s.Name] = s
+
+				if s.Output.Err != nil {
+					exec.Output.FailureCount++
+					exec.Output.FailedSteps = append(exec.Output.FailedSteps, s)
+					failed = true
+				}
+			}
+		}
+	}
+
+	for name, regionExecutions := range executions {
+		t := output.Tracks[name]
+		t.DestroyOutput = Output{Name: name, Executions: []RegionExecution{}}
+
+		for _, exec := range regionExecutions {
+			t.DestroyOutput.Executions = append(t.DestroyOutput.Executions, *exec)
+		}
+
+		output.Tracks[name] = t
+	}
+
+	return nil
+}
+
+// getTeardownExecution returns the region execution of the track accumulating the destroyed steps, created with
+// the track's and the pretrack's output variables of that region on first use
+func getTeardownExecution(executions map[string]*RegionExecution, t Track, preTrack Track, target RegionExecution, logger *logrus.Entry, fs afero.Fs) *RegionExecution {
+	key := fmt.Sprintf("%s-%s", target.RegionDeployType, target.Region)
+
+	if exec, ok := executions[key]; ok {
+		return exec
+	}
+
+	vars := map[string]map[string]string{}
+
+	for _, e := range t.Output.Executions {
+		if e.RegionDeployType == target.RegionDeployType && e.Region == target.Region && e.Output.StepOutputVariables != nil {
+			vars = e.Output.StepOutputVariables
+		}
+	}
+
+	if !t.IsPreTrack && preTrack.Name != "" {
+		vars = AppendPreTrackOutputsToDefaultStepOutputVariables(vars, &preTrack.Output, target.RegionDeployType, target.Region)
+	}
+
+	exec := &RegionExecution{
+		TrackName:                  t.Name,
+		TrackDir:                   t.Dir,
+		TrackStepProgressionsCount: t.StepProgressionsCount,
+		TrackOrderedSteps:          t.OrderedSteps,
+		Logger:                     logger,
+		Fs:                         fs,
+		Region:                     target.Region,
+		RegionDeployType:           target.RegionDeployType,
+		DefaultStepOutputVariables: vars,
+		Output: ExecutionOutput{
+			Name:                t.Name,
+			Dir:                 t.Dir,
+			Steps:               map[string]config.Step{},
+			StepOutputVariables: vars,
+		},
+	}
+
+	executions[key] = exec
+
+	return exec
+}
@@ -7,10 +7,12 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/optum/runiac/pkg/cloudaccountdeployment"
 	"github.com/optum/runiac/pkg/config"
 	"github.com/optum/runiac/pkg/steps"
+	"github.com/optum/runiac/pkg/tracing"
 	"github.com/optum/runiac/plugins/terraform/pkg/terraform"
 	"github.com/otiai10/copy"
 	"github.com/sirupsen/logrus"
@@ -22,6 +24,13 @@ const (
 	DEFAULT_TRACK_NAME = "default"   // The name of the default top-level track
 )
 
+// The messages logged when a step starts and completes executing in a region, the CLI's progress view tracks the
+// status of the steps from them
+const (
+	StepStartedMessage   = "Executing step"
+	StepCompletedMessage = "Completed step"
+)
+
 // ExecuteTrackFunc facilitates track executions across multiple regions and RegionDeployTypes (e.g. Primary us-east-1 and regional us-*)
 type ExecuteTrackFunc func(execution Execution, cfg config.Config, t Track, out chan<- Output)
 
@@ -47,8 +56,9 @@ type Tracker interface {
 
 // DirectoryBasedTracker implements the Tracker interface
 type DirectoryBasedTracker struct {
-	Log *logrus.Entry
-	Fs  afero.Fs
+	Log  *logrus.Entry
+	Fs   afero.Fs
+	Span *tracing.Span // The deploy span the tracks are traced under, nil when tracing is not configured
 }
 
 // Track represents a delivery framework track (unit of functionality)
@@ -63,13 +73,17 @@ type Track struct {
 	OrderedSteps                map[int][]config.Step
 	Output                      Output
 	DestroyOutput               Output
-	IsPreTrack                  bool // If true, this is a PreTrack, meaning it should be run before all other tracks
-	IsDefaultTrack              bool // If true, this track represents steps contained in a standalone, top-level track
-	Skipped                     bool // Indicates that the track was skipped. This will be for non-pretrack tracks if the pretrack fails
+	IsPreTrack                  bool         // If true, this is a PreTrack, meaning it should be run before all other tracks
+	IsDefaultTrack              bool         // If true, this track represents steps contained in a standalone, top-level track
+	Skipped                     bool         // Indicates that the track was skipped. This will be for non-pretrack tracks if the pretrack fails
+	ApprovalRequired            bool         // If true, the track is planned and only deployed once its plan was approved, approval: manual in the track's runiac.yml
+	PlanOnly                    bool         // If true, the steps of the track are only planned, see getPlanOnlyTrack
+	Hooks                       config.Hooks // The track and step hooks of the project and the track's runiac.yml
 }
 
 type Output struct {
 	Name                       string
+	HookErr                    error // Set when a pre_track or post_track hook failed
 	PrimaryStepOutputVariables map[string]map[string]string
 	Executions                 []RegionExecution
 }
@@ -80,13 +94,14 @@ type Execution struct {
 	Output                              ExecutionOutput
 	DefaultExecutionStepOutputVariables map[string]map[string]map[string]string
 	PreTrackOutput                      *Output
+	Span                                *tracing.Span              // The deploy span the track is traced under
+	RollbackSteps                       map[string]map[string]bool // The steps destroyed when rolling back a failed deploy, keyed by {regionDeployType}-{region}. Every step is destroyed when nil
 }
 
 type RegionExecution struct {
 	TrackName                  string
 	TrackDir                   string
 	TrackStepProgressionsCount int
-	TrackStepsWithTestsCount   int
 	TrackOrderedSteps          map[int][]config.Step
 	Logger                     *logrus.Entry
 	Fs                         afero.Fs
@@ -94,7 +109,11 @@ type RegionExecution struct {
 	Region                     string
 	RegionDeployType           config.RegionDeployType
 	PrimaryOutput              ExecutionOutput // This value is only set when regiondeploytype == regional
+	MaxParallel                int             // The maximum number of steps executing concurrently, unlimited when 0
 	DefaultStepOutputVariables map[string]map[string]string
+	Span                       *tracing.Span   // The track span the region is traced under
+	RolloutHalted              bool            // An earlier wave of the regional rollout failed or the abort_after_failures regions failed, the steps of the region are skipped
+	DestroySteps               map[string]bool // The names of the steps destroyed when rolling back, every step of the track is destroyed when nil
 }
 
 // TrackOutput represents the output from a track execution
@@ -112,7 +131,12 @@ type ExecutionOutput struct {
 
 // Stage represents the outputs of tracks
 type Stage struct {
-	Tracks map[string]Track
+	Tracks      map[string]Track
+	TeardownErr error // Set when the configured teardown order can not be applied, nothing is destroyed
+	Err         error // Set when the deploy can not be checkpointed or resumed, no track is executed
+	ApprovalErr error // Set when an approval of the deploy was not granted, the tracks waiting for it are skipped
+	HookErr     error // Set when a pre_deploy or post_deploy hook failed, no track is executed when a pre_deploy hook failed
+	RolledBack  bool  // Set when the deploy failed and the steps it applied were destroyed, see rollback_on_failure
 }
 
 // GatherTracks gets all tracks that should be executed based
@@ -200,6 +224,28 @@ func (tracker DirectoryBasedTracker) readTrack(cfg config.Config, name string, d
 		}
 	}
 
+	t.Hooks = appendHooks(cfg.Hooks, config.Hooks{})
+
+	// the runiac.yml of the default track's directory is the project's configuration
+	if !t.IsDefaultTrack {
+		tConfig, err := readStepConfig(tracker.Fs, t.Dir)
+		if err == nil {
+			t.ApprovalRequired, err = readTrackApproval(tConfig, t.Name)
+		}
+
+		if err == nil {
+			var hooks config.Hooks
+			if hooks, err = readHooks(tConfig, trackHookKeys, "track "+t.Name); err == nil {
+				t.Hooks = appendHooks(t.Hooks, hooks)
+			}
+		}
+
+		if err != nil {
+			tracker.Log.WithError(err).Error("Failed to read track configuration")
+			return t, false, err
+		}
+	}
+
 	// TODO(step:config)
 	//tConfig := viper.New()
 	//tConfig.SetConfigName("runiac")         // name of cfg file (without extension)
@@ -269,12 +315,42 @@ func (tracker DirectoryBasedTracker) readTrack(cfg config.Config, name string, d
 					ID:               stepID,
 				}
 
-				step.TestsExist = fileExists(tracker.Fs, filepath.Join(step.Dir, "tests/tests.test"))
+				sConfig, err := readStepConfig(tracker.Fs, step.Dir)
+				if err == nil {
+					step.DependsOn, err = readStepDependsOn(sConfig, t.Name, step.Dir)
+				}
+
+				if err == nil {
+					step.Inputs, err = readStepInputs(sConfig, t.Name, step.Dir)
+				}
+
+				if err == nil {
+					err = setStepRetryPolicy(&step, sConfig, cfg)
+				}
+
+				step.TestRollback = cfg.TestRollback
+				if sConfig != nil && sConfig.IsSet("test_rollback") {
+					step.TestRollback = sConfig.GetBool("test_rollback")
+				}
+
+				if err == nil {
+					var hooks config.Hooks
+					if hooks, err = readHooks(sConfig, stepHookKeys, "step "+stepID); err == nil {
+						step.Hooks = appendHooks(config.Hooks{PreStep: t.Hooks.PreStep, PostStep: t.Hooks.PostStep}, hooks)
+					}
+				}
+
+				if err != nil {
+					tracker.Log.WithError(err).Error("Failed to read step configuration")
+					return t, false, err
+				}
+
+				step.TestsExist = hasStepTests(tracker.Fs, step.Dir)
 				step.RegionalResourcesExist = exists(tracker.Fs, filepath.Join(step.Dir, "regional"))
 				step.Runner = steps.DetermineRunner(step)
 
 				if step.RegionalResourcesExist {
-					step.RegionalTestsExist = fileExists(tracker.Fs, filepath.Join(step.Dir, "regional", "tests/tests.test"))
+					step.RegionalTestsExist = hasStepTests(tracker.Fs, filepath.Join(step.Dir, "regional"))
 				}
 
 				tracker.Log.Infof("Adding Step %s. Tests Exist: %v. Regional Resources Exist: %v. Regional Tests Exist: %v.", stepID, step.TestsExist, step.RegionalResourcesExist, step.RegionalTestsExist)
@@ -297,6 +373,16 @@ func (tracker DirectoryBasedTracker) readTrack(cfg config.Config, name string, d
 			}
 		}
 
+		if err := validateStepDependencies(t, getStepDirNames(tracker.Fs, t.Dir)); err != nil {
+			tracker.Log.WithError(err).Error("Failed to resolve step dependencies")
+			return t, false, err
+		}
+
+		if err := validateStepInputs(t, getStepDirNames(tracker.Fs, t.Dir)); err != nil {
+			tracker.Log.WithError(err).Error("Failed to resolve step inputs")
+			return t, false, err
+		}
+
 		t.StepProgressionsCount = highestProgressionLevel
 	}
 
@@ -327,15 +413,50 @@ func (tracker DirectoryBasedTracker) ExecuteTracks(cfg config.Config) (output St
 	var tracks = tracker.GatherTracks(cfg) // **All** tracks
 	var parallelTracks []Track             // Tracks that should be executed in parallel
 
+	var err error
+
+	checkpoints, err = newCheckpointer(tracker.Fs, tracker.Log, cfg, tracks)
+	if err != nil {
+		tracker.Log.WithError(err).Error("Failed to checkpoint the deploy, no track is executed")
+		output.Err = err
+		return
+	}
+
+	// hooks do not run when destroying
+	runDeployHooks := !cfg.Destroy
+
+	if runDeployHooks {
+		if output.HookErr = runHooks(tracker.Log, hookPreDeploy, cfg.Hooks.PreDeploy, ".", getHookEnv(cfg, hookPreDeploy, cfg.DryRun)); output.HookErr != nil {
+			tracker.Log.WithError(output.HookErr).Error("A pre_deploy hook failed, no track is executed")
+			return
+		}
+	}
+
 	// Pre track
 	var preTrackExists bool
 	var preTrack Track
+	var preTrackRef *Track // The pretrack when it exists
+
+	// destroying without deploying only plans the steps, reading the output variables of the deployed resources
+	// the destroy depends on
+	deployTrack := func(t Track) Track {
+		if cfg.Destroy {
+			return getPlanOnlyTrack(t)
+		}
+
+		return t
+	}
+
+	if cfg.Destroy {
+		tracker.Log.Info("Planning the steps to read their output variables before destroying...")
+	}
 
 	for _, t := range tracks {
 		output.Tracks[t.Name] = t
 		if t.IsPreTrack {
 			preTrackExists = true
 			preTrack = t
+			preTrackRef = &preTrack
 		} else {
 			parallelTracks = append(parallelTracks, t)
 		}
@@ -351,8 +472,9 @@ func (tracker DirectoryBasedTracker) ExecuteTracks(cfg config.Config) (output St
 			Fs:                                  tracker.Fs,
 			Output:                              ExecutionOutput{},
 			DefaultExecutionStepOutputVariables: map[string]map[string]map[string]string{},
+			Span:                                tracker.Span,
 		}
-		go DeployTrack(preTrackExecution, cfg, preTrack, preTrackChan)
+		go DeployTrack(preTrackExecution, cfg, deployTrack(preTrack), preTrackChan)
 		// Wait for the track to contain an item,
 		// indicating the track has completed.
 		preTrackOutput := <-preTrackChan
@@ -362,6 +484,19 @@ func (tracker DirectoryBasedTracker) ExecuteTracks(cfg config.Config) (output St
 		// If any of the pretrack's executions has a step failure,
 		// the pretrack is considered failed
 		// so we cannot continue with the other tracks
+		if preTrackOutput.HookErr != nil {
+			tracker.Log.Error("Pre-track hook failed, subsequent tracks will not be executed")
+			// Mark all other tracks as skipped
+			for _, track := range output.Tracks {
+				if track.Name != PRE_TRACK_NAME {
+					track.Skipped = true
+					output.Tracks[track.Name] = track
+				}
+			}
+			tracker.rollbackOnFailure(cfg, &output, parallelTracks, preTrackRef)
+			return
+		}
+
 		for _, exec := range preTrackOutput.Executions {
 			for _, step := range exec.Output.Steps {
 				if step.Output.Status == config.Fail {
@@ -373,121 +508,227 @@ func (tracker DirectoryBasedTracker) ExecuteTracks(cfg config.Config) (output St
 							output.Tracks[track.Name] = track
 						}
 					}
+					tracker.rollbackOnFailure(cfg, &output, parallelTracks, preTrackRef)
 					return
 				}
 			}
 		}
 	}
 
-	// Execute non pre/post tracks in parallel
-	numParallelTracks := len(parallelTracks)
-	parallelTrackChan := make(chan Output)
-
-	// execute all tracks concurrently
-	// within ExecuteDeployTrack, track result will be added to trackChan feeding next loop
-	for _, t := range parallelTracks {
+	newExecution := func() Execution {
 		execution := Execution{
 			Logger:                              tracker.Log,
 			Fs:                                  tracker.Fs,
 			Output:                              ExecutionOutput{},
 			DefaultExecutionStepOutputVariables: map[string]map[string]map[string]string{},
+			Span:                                tracker.Span,
 		}
 		// If there is a pretrack, add its outputs
 		// to the execution so they are available.
 		if preTrackExists {
 			execution.PreTrackOutput = &preTrack.Output
 		}
-		go DeployTrack(execution, cfg, t, parallelTrackChan)
+
+		return execution
 	}
 
-	// wait for all executions to finish (this loop matches above range)
-	for tExecution := 0; tExecution < numParallelTracks; tExecution++ {
-		// waiting to append <-trackChan Track N times will inherently wait for all above executions to finish
-		tOutput := <-parallelTrackChan
-		if t, ok := output.Tracks[tOutput.Name]; ok {
+	// records the output of the track, a track that was not approved is skipped
+	recordTrack := func(result approvedTrackOutput) {
+		if t, ok := output.Tracks[result.Output.Name]; ok {
 			// TODO: is it better to have a pointer for map value?
-			t.Output = tOutput
-			output.Tracks[tOutput.Name] = t
+			t.Output = result.Output
+			t.Skipped = result.Err != nil
+			output.Tracks[result.Output.Name] = t
 		}
-	}
 
-	// If SelfDestroy or Destroy is set (e.g. during PRs), destroy any resources created by the tracks
-	if cfg.SelfDestroy && !cfg.DryRun {
-		tracker.Log.Info("Executing destroy...")
-		trackDestroyChan := make(chan Output)
+		if result.Err != nil {
+			tracker.Log.WithError(result.Err).Errorf("Skipping track %s, it was not approved", result.Output.Name)
+			output.ApprovalErr = joinApprovalErr(output.ApprovalErr, result.Err)
+		}
+	}
 
-		for _, t := range parallelTracks {
-			executionStepOutputVariables := map[string]map[string]map[string]string{}
+	if cfg.PauseBetweenTracks && isApprovalGated(cfg) {
+		// Execute non pre/post tracks one at a time, each completed track waits for an approval to continue
+		for i, t := range parallelTracks {
+			recordTrack(deployApprovedTrack(newExecution(), cfg, t))
 
-			for _, exec := range output.Tracks[t.Name].Output.Executions {
-				executionStepOutputVariables[fmt.Sprintf("%s-%s", exec.RegionDeployType, exec.Region)] = exec.Output.StepOutputVariables
+			if output.ApprovalErr == nil && i < len(parallelTracks)-1 {
+				if err := ApproveTrack(tracker.Log, cfg, t.Name, ApprovalGateCompleted); err != nil {
+					output.ApprovalErr = err
+				}
 			}
 
-			if tracker.Log.Level == logrus.DebugLevel {
-				jsonBytes, _ := json.Marshal(executionStepOutputVariables)
+			if output.ApprovalErr != nil {
+				for _, remaining := range parallelTracks[i+1:] {
+					tracker.Log.Warnf("Skipping track %s, the deploy was not approved to continue", remaining.Name)
 
-				tracker.Log.Debugf("OUTPUT VARS: %s", string(jsonBytes))
-			}
+					skipped := output.Tracks[remaining.Name]
+					skipped.Skipped = true
+					output.Tracks[remaining.Name] = skipped
+				}
 
-			execution := Execution{
-				Logger:                              tracker.Log,
-				Fs:                                  tracker.Fs,
-				Output:                              ExecutionOutput{},
-				DefaultExecutionStepOutputVariables: executionStepOutputVariables,
+				break
 			}
-			// If there is a pretrack, add its outputs
-			// to the execution so they are available.
-			if preTrackExists {
-				execution.PreTrackOutput = &preTrack.Output
-			}
-			go DestroyTrack(execution, cfg, t, trackDestroyChan)
+		}
+	} else {
+		// Execute non pre/post tracks in parallel
+		numParallelTracks := len(parallelTracks)
+		parallelTrackChan := make(chan approvedTrackOutput)
+
+		// execute all tracks concurrently
+		// within ExecuteDeployTrack, track result will be added to trackChan feeding next loop
+		for _, t := range parallelTracks {
+			go func(t Track) {
+				parallelTrackChan <- deployApprovedTrack(newExecution(), cfg, deployTrack(t))
+			}(t)
 		}
 
 		// wait for all executions to finish (this loop matches above range)
-		for range parallelTracks {
-			// waiting to append <-trackDestroyChan Track N times will inherently wait for all above executions to finish
-			tDestroyOutout := <-trackDestroyChan
-
-			if t, ok := output.Tracks[tDestroyOutout.Name]; ok {
-				// TODO: is it better to have a pointer for map value?
-				t.DestroyOutput = tDestroyOutout
-				output.Tracks[tDestroyOutout.Name] = t
-			}
+		for tExecution := 0; tExecution < numParallelTracks; tExecution++ {
+			// waiting to append <-trackChan Track N times will inherently wait for all above executions to finish
+			recordTrack(<-parallelTrackChan)
 		}
+	}
 
-		// Destroy _pretrack if it exists
-		if preTrackExists {
-			tracker.Log.Debug("Pre-track destroying")
-			executionStepOutputVariables := map[string]map[string]map[string]string{}
+	// the post_deploy hooks run once every track deployed successfully, before self destroying
+	if runDeployHooks && output.ApprovalErr == nil && isStageSuccessful(output) {
+		if output.HookErr = runHooks(tracker.Log, hookPostDeploy, cfg.Hooks.PostDeploy, ".", getHookEnv(cfg, hookPostDeploy, cfg.DryRun)); output.HookErr != nil {
+			tracker.Log.WithError(output.HookErr).Error("A post_deploy hook failed")
+		}
+	}
 
-			for _, exec := range output.Tracks[preTrack.Name].Output.Executions {
-				executionStepOutputVariables[fmt.Sprintf("%s-%s", exec.RegionDeployType, exec.Region)] = exec.Output.StepOutputVariables
-			}
+	// If SelfDestroy or Destroy is set (e.g. during PRs), destroy any resources created by the tracks. A dry run of
+	// Destroy plans the destroy.
+	destroy := (cfg.SelfDestroy && !cfg.DryRun) || cfg.Destroy
 
-			destroyPreTrackChan := make(chan Output)
-			preTrackDestroyExecution := Execution{
-				Logger:                              tracker.Log,
-				Fs:                                  tracker.Fs,
-				Output:                              ExecutionOutput{},
-				DefaultExecutionStepOutputVariables: executionStepOutputVariables,
-				PreTrackOutput:                      &preTrack.Output,
-			}
-			go DestroyTrack(preTrackDestroyExecution, cfg, preTrack, destroyPreTrackChan)
-			// Wait for the track to contain an item,
-			// indicating the track has been destroyed.
-			preTrackDestroyOutput := <-destroyPreTrackChan
-			preTrack.DestroyOutput = preTrackDestroyOutput
-			tracker.Log.Debug("Pre-track destroy finished")
-			if t, ok := output.Tracks[preTrackDestroyOutput.Name]; ok {
-				t.DestroyOutput = preTrackDestroyOutput
-				output.Tracks[preTrackDestroyOutput.Name] = t
-			}
+	if destroy && len(cfg.TeardownOrder) > 0 {
+		tracker.Log.Infof("Executing destroy in teardown order %s...", strings.Join(cfg.TeardownOrder, ", "))
+
+		output.TeardownErr = ExecuteTeardownOrder(tracker.Log, tracker.Fs, cfg, output)
+		if output.TeardownErr != nil {
+			tracker.Log.WithError(output.TeardownErr).Error("Skipping destroy, the teardown order is invalid")
 		}
+	} else if destroy {
+		tracker.Log.Info("Executing destroy...")
+		tracker.destroyTracks(cfg, output, parallelTracks, preTrackRef, false)
 	}
 
+	tracker.rollbackOnFailure(cfg, &output, parallelTracks, preTrackRef)
+
 	return
 }
 
+// destroyTracks destroys the tracks in parallel, then the pretrack when it is set. When rolling back, only the step
+// executions the deploy applied are destroyed, see getRollbackSteps.
+func (tracker DirectoryBasedTracker) destroyTracks(cfg config.Config, output Stage, parallelTracks []Track, preTrack *Track, rollback bool) {
+	trackDestroyChan := make(chan Output)
+	destroying := 0
+
+	for _, t := range parallelTracks {
+		rollbackSteps := map[string]map[string]bool(nil)
+
+		// when rolling back, the tracks the deploy did not apply a step of are not destroyed
+		if rollback {
+			if rollbackSteps = getRollbackSteps(output.Tracks[t.Name]); len(rollbackSteps) == 0 {
+				continue
+			}
+		}
+
+		executionStepOutputVariables := map[string]map[string]map[string]string{}
+
+		for _, exec := range output.Tracks[t.Name].Output.Executions {
+			executionStepOutputVariables[fmt.Sprintf("%s-%s", exec.RegionDeployType, exec.Region)] = exec.Output.StepOutputVariables
+		}
+
+		if tracker.Log.Level == logrus.DebugLevel {
+			jsonBytes, _ := json.Marshal(executionStepOutputVariables)
+
+			tracker.Log.Debugf("OUTPUT VARS: %s", string(jsonBytes))
+		}
+
+		execution := Execution{
+			Logger:                              tracker.Log,
+			Fs:                                  tracker.Fs,
+			Output:                              ExecutionOutput{},
+			DefaultExecutionStepOutputVariables: executionStepOutputVariables,
+			Span:                                tracker.Span,
+			RollbackSteps:                       rollbackSteps,
+		}
+		// If there is a pretrack, add its outputs
+		// to the execution so they are available.
+		if preTrack != nil {
+			execution.PreTrackOutput = &preTrack.Output
+		}
+		go DestroyTrack(execution, cfg, t, trackDestroyChan)
+		destroying++
+	}
+
+	// wait for all executions to finish (this loop matches above range)
+	for i := 0; i < destroying; i++ {
+		// waiting to append <-trackDestroyChan Track N times will inherently wait for all above executions to finish
+		tDestroyOutout := <-trackDestroyChan
+
+		if t, ok := output.Tracks[tDestroyOutout.Name]; ok {
+			// TODO: is it better to have a pointer for map value?
+			t.DestroyOutput = tDestroyOutout
+			output.Tracks[tDestroyOutout.Name] = t
+		}
+	}
+
+	preTrackRollbackSteps := map[string]map[string]bool(nil)
+	if rollback && preTrack != nil {
+		preTrackRollbackSteps = getRollbackSteps(output.Tracks[preTrack.Name])
+	}
+
+	// Destroy _pretrack if it exists
+	if preTrack != nil && (!rollback || len(preTrackRollbackSteps) > 0) {
+		tracker.Log.Debug("Pre-track destroying")
+		executionStepOutputVariables := map[string]map[string]map[string]string{}
+
+		for _, exec := range output.Tracks[preTrack.Name].Output.Executions {
+			executionStepOutputVariables[fmt.Sprintf("%s-%s", exec.RegionDeployType, exec.Region)] = exec.Output.StepOutputVariables
+		}
+
+		destroyPreTrackChan := make(chan Output)
+		preTrackDestroyExecution := Execution{
+			Logger:                              tracker.Log,
+			Fs:                                  tracker.Fs,
+			Output:                              ExecutionOutput{},
+			DefaultExecutionStepOutputVariables: executionStepOutputVariables,
+			PreTrackOutput:                      &preTrack.Output,
+			Span:                                tracker.Span,
+			RollbackSteps:                       preTrackRollbackSteps,
+		}
+		go DestroyTrack(preTrackDestroyExecution, cfg, *preTrack, destroyPreTrackChan)
+		// Wait for the track to contain an item,
+		// indicating the track has been destroyed.
+		preTrackDestroyOutput := <-destroyPreTrackChan
+		preTrack.DestroyOutput = preTrackDestroyOutput
+		tracker.Log.Debug("Pre-track destroy finished")
+		if t, ok := output.Tracks[preTrackDestroyOutput.Name]; ok {
+			t.DestroyOutput = preTrackDestroyOutput
+			output.Tracks[preTrackDestroyOutput.Name] = t
+		}
+	}
+}
+
+// getPlanOnlyTrack returns a copy of the track whose steps are dry runs
+func getPlanOnlyTrack(t Track) Track {
+	orderedSteps := map[int][]config.Step{}
+
+	for progressionLevel, steps := range t.OrderedSteps {
+		for _, s := range steps {
+			s.DeployConfig.DryRun = true
+			orderedSteps[progressionLevel] = append(orderedSteps[progressionLevel], s)
+		}
+	}
+
+	t.OrderedSteps = orderedSteps
+	t.PlanOnly = true
+
+	return t
+}
+
 // Adds step outputs variables to the track output variables map
 // K = Step Name, V = map[StepOutputVarName: StepOutputVarValue]
 func AppendTrackOutput(trackOutputVariables map[string]map[string]string, output config.StepOutput) map[string]map[string]string {
@@ -532,6 +773,16 @@ func AppendPreTrackOutputsToDefaultStepOutputVariables(defaultStepOutputVariable
 	return defaultStepOutputVariables
 }
 
+// getPrimaryRegions returns the regions the primary steps are executed in, the primary region unless primary regions
+// are configured
+func getPrimaryRegions(cfg config.Config) []string {
+	if len(cfg.PrimaryRegions) == 0 {
+		return []string{cfg.PrimaryRegion}
+	}
+
+	return cfg.PrimaryRegions
+}
+
 // ExecuteDeployTrack is for executing a single track across regions
 func ExecuteDeployTrack(execution Execution, cfg config.Config, t Track, out chan<- Output) {
 	logger := execution.Logger.WithFields(logrus.Fields{
@@ -539,46 +790,107 @@ func ExecuteDeployTrack(execution Execution, cfg config.Config, t Track, out cha
 		"action": "deploy",
 	})
 
+	span := execution.Span.StartChild("track "+t.Name, "runiac.track", t.Name, "runiac.action", "deploy")
+
 	output := Output{
 		Name:                       t.Name,
 		Executions:                 []RegionExecution{},
 		PrimaryStepOutputVariables: map[string]map[string]string{},
 	}
 
-	primaryOutChan := make(chan RegionExecution, 1)
-	primaryInChan := make(chan RegionExecution, 1)
+	// hooks do not run when destroying, the post_track hooks run once every step of the track succeeded
+	runTrackHooks := !cfg.Destroy
+
+	complete := func() {
+		if runTrackHooks && getTrackErr(output) == nil {
+			output.HookErr = runHooks(logger, hookPostTrack, t.Hooks.PostTrack, t.Dir, getTrackHookEnv(cfg, t, hookPostTrack))
+		}
+
+		if output.HookErr != nil {
+			logger.WithError(output.HookErr).Error("A post_track hook failed")
+		}
+
+		span.Finish(getTrackErr(output))
+		out <- output
+	}
+
+	if runTrackHooks {
+		if output.HookErr = runHooks(logger, hookPreTrack, t.Hooks.PreTrack, t.Dir, getTrackHookEnv(cfg, t, hookPreTrack)); output.HookErr != nil {
+			logger.WithError(output.HookErr).Error("Skipping track, a pre_track hook failed")
+
+			span.Finish(getTrackErr(output))
+			out <- output
+			return
+		}
+	}
+
+	primaryRegions := getPrimaryRegions(cfg)
+	primaryOutChan := make(chan RegionExecution, len(primaryRegions))
+	primaryInChan := make(chan RegionExecution, len(primaryRegions))
+	primaryExecutions := map[string]RegionExecution{}
+	primaryFailures := 0
+
+	for _, region := range primaryRegions {
+		primaryRegionExecution := RegionExecution{
+			TrackName:                  t.Name,
+			TrackDir:                   t.Dir,
+			TrackStepProgressionsCount: t.StepProgressionsCount,
+			TrackOrderedSteps:          t.OrderedSteps,
+			MaxParallel:                cfg.MaxParallel,
+			Logger:                     logger,
+			Fs:                         execution.Fs,
+			Output:                     ExecutionOutput{},
+			Region:                     region,
+			RegionDeployType:           config.PrimaryRegionDeployType,
+			DefaultStepOutputVariables: map[string]map[string]string{},
+			Span:                       span,
+			RolloutHalted:              shouldAbortRegions(cfg, primaryFailures),
+		}
+
+		if val, ok := execution.DefaultExecutionStepOutputVariables[fmt.Sprintf("%s-%s", primaryRegionExecution.RegionDeployType, primaryRegionExecution.Region)]; ok {
+			primaryRegionExecution.DefaultStepOutputVariables = val
+		}
+
+		// Add step outputs for primary steps
+		// from the pretrack
+		if execution.PreTrackOutput != nil {
+			primaryRegionExecution.DefaultStepOutputVariables = AppendPreTrackOutputsToDefaultStepOutputVariables(primaryRegionExecution.DefaultStepOutputVariables, execution.PreTrackOutput, primaryRegionExecution.RegionDeployType, primaryRegionExecution.Region)
+		}
+
+		go DeployTrackRegion(primaryInChan, primaryOutChan)
+		primaryInChan <- primaryRegionExecution
 
-	region := cfg.PrimaryRegion // TODO(cfg:region): allow this to be overridden
+		// unless parallel, each primary region completes before the next one starts
+		if !cfg.ParallelPrimaryRegions {
+			primaryTrackExecution := <-primaryOutChan
+			primaryExecutions[primaryTrackExecution.Region] = primaryTrackExecution
 
-	primaryRegionExecution := RegionExecution{
-		TrackName:                  t.Name,
-		TrackDir:                   t.Dir,
-		TrackStepProgressionsCount: t.StepProgressionsCount,
-		TrackStepsWithTestsCount:   t.StepsWithTestsCount,
-		TrackOrderedSteps:          t.OrderedSteps,
-		Logger:                     logger,
-		Fs:                         execution.Fs,
-		Output:                     ExecutionOutput{},
-		Region:                     region,
-		RegionDeployType:           config.PrimaryRegionDeployType,
-		DefaultStepOutputVariables: map[string]map[string]string{},
+			if primaryTrackExecution.Output.FailureCount > 0 {
+				primaryFailures++
+			}
+		}
 	}
 
-	if val, ok := execution.DefaultExecutionStepOutputVariables[fmt.Sprintf("%s-%s", primaryRegionExecution.RegionDeployType, primaryRegionExecution.Region)]; ok {
-		primaryRegionExecution.DefaultStepOutputVariables = val
+	if cfg.ParallelPrimaryRegions {
+		for range primaryRegions {
+			primaryTrackExecution := <-primaryOutChan
+			primaryExecutions[primaryTrackExecution.Region] = primaryTrackExecution
+		}
 	}
 
-	// Add step outputs for primary steps
-	// from the pretrack
-	if execution.PreTrackOutput != nil {
-		primaryRegionExecution.DefaultStepOutputVariables = AppendPreTrackOutputsToDefaultStepOutputVariables(primaryRegionExecution.DefaultStepOutputVariables, execution.PreTrackOutput, primaryRegionExecution.RegionDeployType, primaryRegionExecution.Region)
+	for _, region := range primaryRegions {
+		output.Executions = append(output.Executions, primaryExecutions[region])
 	}
 
-	go DeployTrackRegion(primaryInChan, primaryOutChan)
-	primaryInChan <- primaryRegionExecution
+	// the regional regions receive the step outputs of the first primary region and are skipped when any primary
+	// region failed
+	primaryTrackExecution := output.Executions[0]
+	primaryOutput := primaryTrackExecution.Output
+
+	for _, e := range output.Executions[1:] {
+		primaryOutput.FailureCount += e.Output.FailureCount
+	}
 
-	primaryTrackExecution := <-primaryOutChan
-	output.Executions = append(output.Executions, primaryTrackExecution)
 	output.PrimaryStepOutputVariables = primaryTrackExecution.Output.StepOutputVariables
 
 	// end early if track has no regional step resources
@@ -590,7 +902,19 @@ func ExecuteDeployTrack(execution Execution, cfg config.Config, t Track, out cha
 			logger.WithError(err).Error(err)
 		}
 
-		out <- output
+		complete()
+		return
+	}
+
+	if cfg.SkipRegional {
+		logger.Info("Skipping regional deployments, completing track.")
+		_, err := cloudaccountdeployment.FlushTrack(logger, t.Name)
+
+		if err != nil {
+			logger.WithError(err).Error(err)
+		}
+
+		complete()
 		return
 	}
 
@@ -599,13 +923,9 @@ func ExecuteDeployTrack(execution Execution, cfg config.Config, t Track, out cha
 	regionOutChan := make(chan RegionExecution, targetRegionsCount)
 	regionInChan := make(chan RegionExecution, targetRegionsCount)
 
-	logger.Infof("Primary region successfully completed, executing regional deployments in %v.", targetRegions)
+	logger.Infof("Primary regions %v completed, executing regional deployments in %v.", primaryRegions, targetRegions)
 
-	for i := 0; i < targetRegionsCount; i++ {
-		go DeployTrackRegion(regionInChan, regionOutChan)
-	}
-
-	for _, reg := range targetRegions {
+	newRegionalExecution := func(reg string) RegionExecution {
 		outputVars := map[string]map[string]string{}
 
 		// Like slices, maps hold references to an underlying data structure. If you pass a map to a function that changes the contents of the map, the changes will be visible in the caller.
@@ -619,15 +939,16 @@ func ExecuteDeployTrack(execution Execution, cfg config.Config, t Track, out cha
 			TrackName:                  t.Name,
 			TrackDir:                   t.Dir,
 			TrackStepProgressionsCount: t.StepProgressionsCount,
-			TrackStepsWithTestsCount:   t.StepsWithRegionalTestsCount,
 			TrackOrderedSteps:          t.OrderedSteps,
+			MaxParallel:                cfg.MaxParallel,
 			Logger:                     logger,
 			Fs:                         execution.Fs,
 			Output:                     ExecutionOutput{},
 			Region:                     reg,
 			RegionDeployType:           config.RegionalRegionDeployType,
 			DefaultStepOutputVariables: outputVars,
-			PrimaryOutput:              primaryTrackExecution.Output,
+			PrimaryOutput:              primaryOutput,
+			Span:                       span,
 		}
 
 		// Add step outputs for regional steps
@@ -636,12 +957,58 @@ func ExecuteDeployTrack(execution Execution, cfg config.Config, t Track, out cha
 			regionalRegionExecution.DefaultStepOutputVariables = AppendPreTrackOutputsToDefaultStepOutputVariables(regionalRegionExecution.DefaultStepOutputVariables, execution.PreTrackOutput, regionalRegionExecution.RegionDeployType, regionalRegionExecution.Region)
 		}
 
-		regionInChan <- regionalRegionExecution
+		return regionalRegionExecution
 	}
 
-	for i := 0; i < targetRegionsCount; i++ {
-		regionTrackOutput := <-regionOutChan
-		output.Executions = append(output.Executions, regionTrackOutput)
+	// the regions of a wave are deployed once every earlier wave completed, a failure halts the later waves
+	halted := false
+	failures := 0
+	waves := getRegionalRolloutWaves(cfg.RegionalRollout, targetRegions)
+
+	for i, wave := range waves {
+		parallel := wave.Parallel
+		if parallel <= 0 || parallel > len(wave.Regions) {
+			parallel = len(wave.Regions)
+		}
+
+		if len(waves) > 1 {
+			logger.Infof("Executing wave %d of %d of the regional rollout in %v.", i+1, len(waves), wave.Regions)
+		}
+
+		inflight := 0
+		failed := false
+
+		receive := func() {
+			regionTrackOutput := <-regionOutChan
+			output.Executions = append(output.Executions, regionTrackOutput)
+			if regionTrackOutput.Output.FailureCount > 0 {
+				failed = true
+				failures++
+			}
+			inflight--
+		}
+
+		for _, reg := range wave.Regions {
+			if inflight == parallel {
+				receive()
+			}
+
+			regionalRegionExecution := newRegionalExecution(reg)
+			regionalRegionExecution.RolloutHalted = halted || shouldAbortRegions(cfg, failures)
+
+			go DeployTrackRegion(regionInChan, regionOutChan)
+			regionInChan <- regionalRegionExecution
+			inflight++
+		}
+
+		for inflight > 0 {
+			receive()
+		}
+
+		if failed && !halted && i < len(waves)-1 {
+			logger.Warnf("Wave %d of the regional rollout failed, halting the regional rollout.", i+1)
+			halted = true
+		}
 	}
 
 	stepExecutions, err := cloudaccountdeployment.FlushTrack(logger, t.Name)
@@ -656,7 +1023,13 @@ func ExecuteDeployTrack(execution Execution, cfg config.Config, t Track, out cha
 		logger.Debug(string(json))
 	}
 
-	out <- output
+	complete()
+}
+
+// shouldAbortRegions returns true when the remaining regions of the track should be skipped, once the
+// abort_after_failures regions failed
+func shouldAbortRegions(cfg config.Config, failures int) bool {
+	return cfg.AbortAfterFailures > 0 && failures >= cfg.AbortAfterFailures
 }
 
 // ExecuteDestroyTrack is a helper function for destroying a track
@@ -666,6 +1039,8 @@ func ExecuteDestroyTrack(execution Execution, cfg config.Config, t Track, out ch
 		"action": "destroy",
 	})
 
+	span := execution.Span.StartChild("track "+t.Name, "runiac.track", t.Name, "runiac.action", "destroy")
+
 	output := Output{
 		Name:       t.Name,
 		Executions: []RegionExecution{},
@@ -691,12 +1066,15 @@ func ExecuteDestroyTrack(execution Execution, cfg config.Config, t Track, out ch
 				TrackDir:                   t.Dir,
 				TrackStepProgressionsCount: t.StepProgressionsCount,
 				TrackOrderedSteps:          t.OrderedSteps,
+				MaxParallel:                cfg.MaxParallel,
 				Logger:                     trackLogger,
 				Fs:                         execution.Fs,
 				Output:                     ExecutionOutput{},
 				Region:                     reg,
 				RegionDeployType:           config.RegionalRegionDeployType,
 				DefaultStepOutputVariables: execution.DefaultExecutionStepOutputVariables[fmt.Sprintf("%s-%s", config.RegionalRegionDeployType, reg)],
+				Span:                       span,
+				DestroySteps:               execution.getDestroySteps(config.RegionalRegionDeployType, reg),
 			}
 
 			// Add step outputs for regional steps
@@ -715,36 +1093,55 @@ func ExecuteDestroyTrack(execution Execution, cfg config.Config, t Track, out ch
 	}
 
 	// clean up primary
-	primaryOutChan := make(chan RegionExecution, 1)
-	primaryInChan := make(chan RegionExecution, 1)
+	primaryRegions := getPrimaryRegions(cfg)
+	primaryOutChan := make(chan RegionExecution, len(primaryRegions))
+	primaryInChan := make(chan RegionExecution, len(primaryRegions))
+	primaryExecutions := map[string]RegionExecution{}
+
+	for _, region := range primaryRegions {
+		primaryExecution := RegionExecution{
+			TrackName:                  t.Name,
+			TrackDir:                   t.Dir,
+			TrackStepProgressionsCount: t.StepProgressionsCount,
+			TrackOrderedSteps:          t.OrderedSteps,
+			MaxParallel:                cfg.MaxParallel,
+			Logger:                     trackLogger,
+			Fs:                         execution.Fs,
+			Output:                     ExecutionOutput{},
+			Region:                     region,
+			RegionDeployType:           config.PrimaryRegionDeployType,
+			DefaultStepOutputVariables: execution.DefaultExecutionStepOutputVariables[fmt.Sprintf("%s-%s", config.PrimaryRegionDeployType, region)],
+			Span:                       span,
+			DestroySteps:               execution.getDestroySteps(config.PrimaryRegionDeployType, region),
+		}
+
+		// Add step outputs for primary steps
+		// from the pretrack
+		if execution.PreTrackOutput != nil {
+			primaryExecution.DefaultStepOutputVariables = AppendPreTrackOutputsToDefaultStepOutputVariables(primaryExecution.DefaultStepOutputVariables, execution.PreTrackOutput, primaryExecution.RegionDeployType, primaryExecution.Region)
+		}
 
-	region := cfg.PrimaryRegion // TODO(cfg:region): allow this to be overridden
+		go DestroyTrackRegion(primaryInChan, primaryOutChan)
+		primaryInChan <- primaryExecution
 
-	primaryExecution := RegionExecution{
-		TrackName:                  t.Name,
-		TrackDir:                   t.Dir,
-		TrackStepProgressionsCount: t.StepProgressionsCount,
-		TrackOrderedSteps:          t.OrderedSteps,
-		Logger:                     trackLogger,
-		Fs:                         execution.Fs,
-		Output:                     ExecutionOutput{},
-		Region:                     region,
-		RegionDeployType:           config.PrimaryRegionDeployType,
-		DefaultStepOutputVariables: execution.DefaultExecutionStepOutputVariables[fmt.Sprintf("%s-%s", config.PrimaryRegionDeployType, region)],
+		if !cfg.ParallelPrimaryRegions {
+			primaryTrackOutput := <-primaryOutChan
+			primaryExecutions[primaryTrackOutput.Region] = primaryTrackOutput
+		}
 	}
 
-	// Add step outputs for primary steps
-	// from the pretrack
-	if execution.PreTrackOutput != nil {
-		primaryExecution.DefaultStepOutputVariables = AppendPreTrackOutputsToDefaultStepOutputVariables(primaryExecution.DefaultStepOutputVariables, execution.PreTrackOutput, primaryExecution.RegionDeployType, primaryExecution.Region)
+	if cfg.ParallelPrimaryRegions {
+		for range primaryRegions {
+			primaryTrackOutput := <-primaryOutChan
+			primaryExecutions[primaryTrackOutput.Region] = primaryTrackOutput
+		}
 	}
 
-	go DestroyTrackRegion(primaryInChan, primaryOutChan)
-	primaryInChan <- primaryExecution
-
-	primaryTrackOutput := <-primaryOutChan
-	output.Executions = append(output.Executions, primaryTrackOutput)
+	for _, region := range primaryRegions {
+		output.Executions = append(output.Executions, primaryExecutions[region])
+	}
 
+	span.Finish(getTrackErr(output))
 	out <- output
 }
 
@@ -755,6 +1152,8 @@ func ExecuteDeployTrackRegion(in <-chan RegionExecution, out chan<- RegionExecut
 		"regionDeployType": execution.RegionDeployType.String(),
 	})
 
+	span := execution.Span.StartChild(fmt.Sprintf("region %s/%s", execution.RegionDeployType, execution.Region), "runiac.region", execution.Region, "runiac.region_deploy_type", execution.RegionDeployType.String())
+
 	execution.Output = ExecutionOutput{
 		Name:                execution.TrackName,
 		Dir:                 execution.TrackDir,
@@ -769,79 +1168,102 @@ func ExecuteDeployTrackRegion(in <-chan RegionExecution, out chan<- RegionExecut
 	// define test channel outside of stepProgression loop to allow tests to run in background while steps proceed through progressions
 	testOutChan := make(chan config.StepTestOutput)
 	testInChan := make(chan config.Step)
+	backgroundTests := 0
 
-	// Create testing goroutines.
-	for testExecution := 0; testExecution < execution.TrackStepsWithTestsCount; testExecution++ {
-		go executeStepTest(logger, execution.Fs, execution.Region, execution.RegionDeployType, execution.Output.StepOutputVariables, testInChan, testOutChan)
-	}
+	// steps execute once the steps they depend on completed, by default the steps of the previous progression level
+	executeStepGraph(execution.TrackOrderedSteps, false, execution.MaxParallel, func(s config.Step, completed []config.Step) func() config.Step {
+		slogger := logger.WithFields(logrus.Fields{
+			"step": s.Name,
+		})
+
+		blocked := false
+		for _, c := range completed {
+			blocked = blocked || isStepBlocking(c)
+		}
+
+		// regional resources do not exist
+		if execution.RegionDeployType == config.RegionalRegionDeployType && !s.RegionalResourcesExist {
+			return func() config.Step {
+				s.Output.Status = config.Na
+				return s
+			}
+			// if any failures of the steps it depends on, skip
+		} else if blocked {
+			return func() config.Step {
+				slogger.Warn("Skipping step due to earlier step failures in this region")
+
+				s.Output.Status = config.Skipped
+				return s
+			}
+		} else if execution.PrimaryOutput.FailureCount > 0 {
+			return func() config.Step {
+				slogger.Warn("Skipping step due to failures in primary region deployment")
+
+				s.Output.Status = config.Skipped
+				return s
+			}
+		} else if execution.RolloutHalted {
+			return func() config.Step {
+				slogger.Warn("Skipping step due to failures in earlier regions of the rollout")
 
-	for progressionLevel := 1; progressionLevel <= execution.TrackStepProgressionsCount; progressionLevel++ {
-		sChan := make(chan config.Step)
-		for _, s := range execution.TrackOrderedSteps[progressionLevel] {
-
-			// regional resources do not exist
-			if execution.RegionDeployType == config.RegionalRegionDeployType && !s.RegionalResourcesExist {
-				go func(s config.Step) {
-					s.Output.Status = config.Na
-					sChan <- s
-				}(s)
-				// if any previous failures, skip
-			} else if progressionLevel > 1 && execution.Output.FailureCount > 0 {
-				go func(s config.Step, logger *logrus.Entry) {
-					slogger := logger.WithFields(logrus.Fields{
-						"step": s.Name,
-					})
-
-					slogger.Warn("Skipping step due to earlier step failures in this region")
-
-					s.Output.Status = config.Skipped
-					sChan <- s
-				}(s, logger)
-			} else if execution.PrimaryOutput.FailureCount > 0 {
-				go func(s config.Step, logger *logrus.Entry) {
-					slogger := logger.WithFields(logrus.Fields{
-						"step": s.Name,
-					})
-
-					slogger.Warn("Skipping step due to failures in primary region deployment")
-
-					s.Output.Status = config.Skipped
-					sChan <- s
-				}(s, logger)
-			} else {
-				go ExecuteStep(execution.Region, execution.RegionDeployType, logger, execution.Fs, execution.Output.StepOutputVariables, progressionLevel, s, sChan, false)
+				s.Output.Status = config.Skipped
+				return s
 			}
 		}
 
-		N := len(execution.TrackOrderedSteps[progressionLevel])
-		for i := 0; i < N; i++ {
+		vars := copyStepOutputVariables(execution.Output.StepOutputVariables)
+
+		s.Span = span
+
+		return func() config.Step {
+			sChan := make(chan config.Step, 1)
+			ExecuteStep(execution.Region, execution.RegionDeployType, logger, execution.Fs, vars, s.ProgressionLevel, s, sChan, false)
+
 			s := <-sChan
-			if s.Output.Status == config.Skipped {
-				execution.Output.SkippedCount++
-			} else {
-				execution.Output.ExecutedCount++
-			}
-			execution.Output.Steps[s.Name] = s
-			execution.Output.StepOutputVariables = AppendTrackOutput(execution.Output.StepOutputVariables, s.Output)
 
-			if s.Output.Err != nil || s.Output.Status == config.Fail {
-				execution.Output.FailureCount++
-				execution.Output.FailedSteps = append(execution.Output.FailedSteps, s)
+			// the steps depending on a step its tests roll back must not execute, its tests run before them
+			if s.TestRollback && hasRegionTests(s, execution.RegionDeployType) {
+				s.TestOutput = testStep(logger, execution.Fs, execution.Region, execution.RegionDeployType, vars, s)
 			}
 
-			// trigger tests if exist, this number needs to match testing goroutines triggered above
-			// further filtering happens after trigger
-			if execution.RegionDeployType == config.RegionalRegionDeployType && s.RegionalTestsExist {
-				logger.Debug("Triggering tests")
-				testInChan <- s
-			} else if execution.RegionDeployType == config.PrimaryRegionDeployType && s.TestsExist {
-				logger.Debug("Triggering tests")
-				testInChan <- s
+			return s
+		}
+	}, func(s config.Step) {
+		if s.Output.Status == config.Skipped {
+			execution.Output.SkippedCount++
+		} else {
+			execution.Output.ExecutedCount++
+		}
+		execution.Output.Steps[s.Name] = s
+		execution.Output.StepOutputVariables = AppendTrackOutput(execution.Output.StepOutputVariables, s.Output)
+
+		if s.Output.Err != nil || s.Output.Status == config.Fail {
+			execution.Output.FailureCount++
+			execution.Output.FailedSteps = append(execution.Output.FailedSteps, s)
+		}
+
+		// trigger tests if exist, further filtering happens after trigger. The tests of a step with test_rollback
+		// already ran.
+		if !hasRegionTests(s, execution.RegionDeployType) {
+			return
+		}
+
+		if s.TestRollback {
+			if s.TestOutput.Err != nil {
+				execution.Output.FailedTestCount++
 			}
+
+			return
 		}
-	}
 
-	for testExecution := 0; testExecution < execution.TrackStepsWithTestsCount; testExecution++ {
+		logger.Debug("Triggering tests")
+		backgroundTests++
+
+		go executeStepTest(logger, execution.Fs, execution.Region, execution.RegionDeployType, execution.Output.StepOutputVariables, testInChan, testOutChan)
+		testInChan <- s
+	})
+
+	for testExecution := 0; testExecution < backgroundTests; testExecution++ {
 		s := <-testOutChan
 
 		// add test output to trackOut
@@ -863,6 +1285,7 @@ func ExecuteDeployTrackRegion(in <-chan RegionExecution, out chan<- RegionExecut
 		}
 	}
 
+	span.Finish(getRegionErr(execution.Output))
 	out <- execution
 }
 
@@ -874,6 +1297,8 @@ func ExecuteDestroyTrackRegion(in <-chan RegionExecution, out chan<- RegionExecu
 		"regionDeployType": execution.RegionDeployType.String(),
 	})
 
+	span := execution.Span.StartChild(fmt.Sprintf("region %s/%s", execution.RegionDeployType, execution.Region), "runiac.region", execution.Region, "runiac.region_deploy_type", execution.RegionDeployType.String())
+
 	execution.Output = ExecutionOutput{
 		Name:                execution.TrackName,
 		Dir:                 execution.TrackDir,
@@ -881,36 +1306,55 @@ func ExecuteDestroyTrackRegion(in <-chan RegionExecution, out chan<- RegionExecu
 		StepOutputVariables: execution.DefaultStepOutputVariables,
 	}
 
-	for i := execution.TrackStepProgressionsCount; i >= 1; i-- {
-		sChan := make(chan config.Step)
-		for progressionLevel, s := range execution.TrackOrderedSteps[i] {
-			// if any previous failures, skip
-			if (progressionLevel > 1 && execution.Output.FailureCount > 0) || (execution.RegionDeployType == config.RegionalRegionDeployType && !s.RegionalResourcesExist) {
-				go func(s config.Step) {
-					s.Output.Status = config.Skipped
-					sChan <- s
-				}(s)
-			} else {
-				go ExecuteStep(execution.Region, execution.RegionDeployType, logger, execution.Fs, execution.Output.StepOutputVariables, i, s, sChan, true)
+	// steps are destroyed once the steps depending on them were destroyed, in reverse progression order by default
+	executeStepGraph(execution.TrackOrderedSteps, true, execution.MaxParallel, func(s config.Step, completed []config.Step) func() config.Step {
+		// when rolling back, the steps the deploy did not apply are not destroyed
+		if execution.DestroySteps != nil && !execution.DestroySteps[s.Name] {
+			return func() config.Step {
+				s.Output.Status = config.Na
+				return s
 			}
 		}
-		N := len(execution.TrackOrderedSteps[i])
-		for i := 0; i < N; i++ {
-			s := <-sChan
-			if s.Output.Status == config.Skipped {
-				execution.Output.SkippedCount++
-			} else {
-				execution.Output.ExecutedCount++
-			}
-			execution.Output.Steps[s.Name] = s
 
-			if s.Output.Err != nil {
-				execution.Output.FailureCount++
-				execution.Output.FailedSteps = append(execution.Output.FailedSteps, s)
+		failed := false
+		for _, c := range completed {
+			failed = failed || c.Output.Err != nil
+		}
+
+		// if any failures of the steps depending on it, skip
+		if failed || (execution.RegionDeployType == config.RegionalRegionDeployType && !s.RegionalResourcesExist) {
+			return func() config.Step {
+				s.Output.Status = config.Skipped
+				return s
 			}
 		}
-	}
 
+		s.Span = span
+
+		return func() config.Step {
+			sChan := make(chan config.Step, 1)
+			ExecuteStep(execution.Region, execution.RegionDeployType, logger, execution.Fs, execution.Output.StepOutputVariables, s.ProgressionLevel, s, sChan, true)
+
+			return <-sChan
+		}
+	}, func(s config.Step) {
+		if s.Output.Status == config.Skipped {
+			execution.Output.SkippedCount++
+		} else {
+			execution.Output.ExecutedCount++
+		}
+		execution.Output.Steps[s.Name] = s
+
+		if s.Output.Err != nil {
+			execution.Output.FailureCount++
+			execution.Output.FailedSteps = append(execution.Output.FailedSteps, s)
+		} else if execution.DestroySteps != nil && s.Output.Status == config.Success {
+			// a rolled back step is executed again by a resumed deploy
+			checkpoints.forget(logger, s, execution.RegionDeployType, execution.Region)
+		}
+	})
+
+	span.Finish(getRegionErr(execution.Output))
 	out <- execution
 	return
 }
@@ -919,6 +1363,37 @@ func ExecuteStepImpl(region string, regionDeployType config.RegionDeployType,
 	logger *logrus.Entry, fs afero.Fs, defaultStepOutputVariables map[string]map[string]string, stepProgression int,
 	s config.Step, out chan<- config.Step, destroy bool) {
 
+	start := time.Now()
+
+	logger, closeStepLog := openStepLog(logger, fs, s)
+	defer closeStepLog()
+
+	stepLogger := logger.WithFields(logrus.Fields{
+		"step":            s.Name,
+		"stepProgression": s.ProgressionLevel,
+	})
+
+	stepLogger.Info(StepStartedMessage)
+
+	action := "deploy"
+	if destroy {
+		action = "destroy"
+	}
+
+	span := s.Span.StartChild("step "+s.ID, "runiac.step", s.ID, "runiac.action", action)
+
+	if !destroy {
+		if output, ok := checkpoints.getSkippedOutput(stepLogger, s, regionDeployType, region); ok {
+			s.Output = output
+
+			span.Finish(getStepErr(s.Output))
+			logStepCompleted(stepLogger, s.Output)
+
+			out <- s
+			return
+		}
+	}
+
 	exec, err := steps.InitExecution(s, logger, fs, regionDeployType, region, defaultStepOutputVariables)
 
 	// if error initializing, short circuit
@@ -931,29 +1406,173 @@ func ExecuteStepImpl(region string, regionDeployType config.RegionDeployType,
 			StreamOutput:     "",
 			Err:              err,
 			OutputVariables:  nil,
+			Duration:         time.Since(start),
 		}
+
+		span.Finish(getStepErr(s.Output))
+		logStepCompleted(stepLogger, s.Output)
+
 		out <- s
 		return
 	}
 
-	var output config.StepOutput
+	var scanFindings []config.Finding
+
+	if !destroy && (s.DeployConfig.Scan.Enabled || s.DeployConfig.ScanOnly) {
+		scanFindings, err = scanStep(stepLogger, exec, s.DeployConfig.Scan)
+
+		// the step is not executed when only scanning or its findings block deploying it
+		if err != nil || s.DeployConfig.ScanOnly {
+			s.Output = config.StepOutput{
+				Status:           config.Success,
+				RegionDeployType: regionDeployType,
+				Region:           region,
+				StepName:         s.Name,
+				Err:              err,
+				Findings:         scanFindings,
+				Duration:         time.Since(start),
+			}
+
+			if err != nil {
+				s.Output.Status = config.Fail
+			}
+
+			span.Finish(getStepErr(s.Output))
+			logStepCompleted(stepLogger, s.Output)
+
+			out <- s
+			return
+		}
+	}
+
+	// hooks do not run when destroying
+	runStepHooks := !destroy && !s.DeployConfig.Destroy
+
+	if runStepHooks {
+		if err = runHooks(stepLogger, hookPreStep, s.Hooks.PreStep, s.Dir, getStepHookEnv(s, exec, hookPreStep, nil)); err != nil {
+			s.Output = config.StepOutput{
+				Status:           config.Fail,
+				RegionDeployType: regionDeployType,
+				Region:           region,
+				StepName:         s.Name,
+				Err:              err,
+				Findings:         scanFindings,
+				Duration:         time.Since(start),
+			}
+
+			stepLogger.WithError(err).Error("Skipping step, a pre_step hook failed")
+
+			span.Finish(getStepErr(s.Output))
+			logStepCompleted(stepLogger, s.Output)
+
+			out <- s
+			return
+		}
+	}
 
+	exec.Span = span
 	exec2, _ := s.Runner.PreExecute(exec)
 
-	if destroy {
-		output = steps.ExecuteStepDestroy(s.Runner, exec2)
-	} else {
-		output = steps.ExecuteStep(s.Runner, exec2)
+	output := executeStepWithRetries(stepLogger, s, exec2, func(exec config.StepExecution) config.StepOutput {
+		if destroy {
+			return steps.ExecuteStepDestroy(s.Runner, exec)
+		}
+
+		return steps.ExecuteStep(s.Runner, exec)
+	})
+
+	// the post_step hooks run once the step succeeded, e.g. smoke testing what it applied
+	if runStepHooks && output.Err == nil && output.Status == config.Success {
+		if err = runHooks(stepLogger, hookPostStep, s.Hooks.PostStep, s.Dir, getStepHookEnv(s, exec2, hookPostStep, output.OutputVariables)); err != nil {
+			stepLogger.WithError(err).Error("A post_step hook failed")
+
+			output.Status = config.Fail
+			output.Err = err
+		}
 	}
 
+	output.Duration = time.Since(start)
+	output.Findings = append(scanFindings, output.Findings...)
 	s.Output = output
 
+	if !destroy {
+		checkpoints.record(stepLogger, s)
+	}
+
+	span.Finish(getStepErr(s.Output))
+	logStepCompleted(stepLogger, s.Output)
+
 	out <- s
 	return
 }
 
+// getStepErr returns the error of the step's output, an error as well when the step failed without one
+func getStepErr(output config.StepOutput) error {
+	if output.Err == nil && output.Status == config.Fail {
+		return fmt.Errorf("step %s failed", output.StepName)
+	}
+
+	return output.Err
+}
+
+// getRegionErr returns an error when a step of the region execution failed
+func getRegionErr(output ExecutionOutput) error {
+	if output.FailureCount > 0 {
+		return fmt.Errorf("%d step(s) failed", output.FailureCount)
+	}
+
+	return nil
+}
+
+// getTrackErr returns an error when a step of the track failed in a region or a hook of the track failed
+func getTrackErr(output Output) error {
+	if output.HookErr != nil {
+		return output.HookErr
+	}
+
+	failures := 0
+	for _, e := range output.Executions {
+		failures += e.Output.FailureCount
+	}
+
+	if failures > 0 {
+		return fmt.Errorf("%d step execution(s) failed", failures)
+	}
+
+	return nil
+}
+
+// logStepCompleted logs the status and duration of the step's execution
+func logStepCompleted(logger *logrus.Entry, output config.StepOutput) {
+	status := output.Status
+	if output.Err != nil {
+		status = config.Fail
+	}
+
+	logger.WithFields(logrus.Fields{
+		"status":   status.String(),
+		"duration": output.Duration.String(),
+	}).Info(StepCompletedMessage)
+}
+
 func executeStepTest(incomingLogger *logrus.Entry, fs afero.Fs, region string, regionDeployType config.RegionDeployType, defaultStepOutputVariables map[string]map[string]string, in <-chan config.Step, out chan<- config.StepTestOutput) {
 	s := <-in
+
+	out <- testStep(incomingLogger, fs, region, regionDeployType, defaultStepOutputVariables, s)
+}
+
+// hasRegionTests returns true when the step has tests for the executions of the region deploy type
+func hasRegionTests(s config.Step, regionDeployType config.RegionDeployType) bool {
+	if regionDeployType == config.RegionalRegionDeployType {
+		return s.RegionalTestsExist
+	}
+
+	return s.TestsExist
+}
+
+// testStep runs the tests of the step's execution in the region, rolling the step back when its tests fail and
+// test_rollback is set
+func testStep(incomingLogger *logrus.Entry, fs afero.Fs, region string, regionDeployType config.RegionDeployType, defaultStepOutputVariables map[string]map[string]string, s config.Step) config.StepTestOutput {
 	tOutput := config.StepTestOutput{}
 
 	logger := incomingLogger.WithFields(logrus.Fields{
@@ -977,25 +1596,81 @@ func executeStepTest(incomingLogger *logrus.Entry, fs afero.Fs, region string, r
 
 		// if err initializing, short circuit
 		if err != nil {
-			tOutput = config.StepTestOutput{
+			return config.StepTestOutput{
 				StepName:     s.Name,
 				StreamOutput: "",
 				Err:          err,
 			}
-
-			out <- tOutput
-			return
 		}
 
-		tOutput = s.Runner.ExecuteStepTests(exec)
+		tOutput = runStepTests(logger, fs, s, exec)
 
 		if tOutput.Err != nil {
 			logger.WithError(tOutput.Err).Error("Error executing tests for step")
+
+			if s.TestRollback {
+				if err = rollbackStep(logger, s, exec); err != nil {
+					logger.WithError(err).Error("Failed to roll back the step")
+					tOutput.Err = fmt.Errorf("%v; rollback failed: %w", tOutput.Err, err)
+				} else {
+					tOutput.RolledBack = true
+				}
+			}
 		}
 	}
 
-	out <- tOutput
-	return
+	return tOutput
+}
+
+// runStepTests runs the step's tests/tests.test binary with the step's runner and evaluates its tests/assertions.yml.
+// The assertions reference the step's own outputs by name, the outputs of the other steps are {stepName}-{output}.
+func runStepTests(logger *logrus.Entry, fs afero.Fs, s config.Step, exec config.StepExecution) config.StepTestOutput {
+	output := config.StepTestOutput{}
+
+	if fileExists(fs, filepath.Join(exec.Dir, "tests/tests.test")) {
+		output = s.Runner.ExecuteStepTests(exec)
+	}
+
+	output.StepName = s.Name
+
+	a, err := readStepAssertions(fs, exec.Dir)
+	if err == nil && a != nil {
+		vars := map[string]string{}
+
+		for k, v := range exec.OptionalStepParams {
+			vars[k] = v
+		}
+
+		for k, v := range s.Output.OutputVariables {
+			vars[k] = terraform.OutputToString(v)
+		}
+
+		output.Assertions, err = evaluateStepAssertions(logger, a, vars)
+	}
+
+	if err != nil && output.Err != nil {
+		output.Err = fmt.Errorf("%v; %w", output.Err, err)
+	} else if err != nil {
+		output.Err = err
+	}
+
+	return output
+}
+
+// rollbackStep destroys the step's execution once its tests failed, so the resumed deploy executes it again. The
+// steps depending on it were not executed, its tests run before them.
+func rollbackStep(logger *logrus.Entry, s config.Step, exec config.StepExecution) error {
+	logger.Warn("Rolling back the step, its tests failed")
+
+	if err := getStepErr(steps.ExecuteStepDestroy(s.Runner, exec)); err != nil {
+		return err
+	}
+
+	checkpoints.forget(logger, s, exec.RegionDeployType, exec.Region)
+
+	logger.Info("Rolled back the step")
+
+	return nil
 }
 
 func contains(s []string, e string) bool {
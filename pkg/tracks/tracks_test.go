@@ -1,18 +1,24 @@
 package tracks_test
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"github.com/golang/mock/gomock"
 	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/pkg/tracing"
 	"github.com/optum/runiac/pkg/tracks"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 )
 
 var fs afero.Fs
@@ -518,6 +524,192 @@ func TestExecuteDeployTrack_ShouldExecuteCorrectStepsAndRegions(t *testing.T) {
 	}
 }
 
+func TestExecuteDeployTrack_ShouldExecuteEveryPrimaryRegion(t *testing.T) {
+	defer func(f func(<-chan tracks.RegionExecution, chan<- tracks.RegionExecution)) {
+		tracks.DeployTrackRegion = f
+	}(tracks.DeployTrackRegion)
+
+	for name, parallel := range map[string]bool{"Sequential": false, "Parallel": true} {
+		t.Run(name, func(t *testing.T) {
+			var mu sync.Mutex
+			inflight, maxInflight := 0, 0
+			regional := []tracks.RegionExecution{}
+
+			tracks.DeployTrackRegion = func(in <-chan tracks.RegionExecution, out chan<- tracks.RegionExecution) {
+				regionExecution := <-in
+
+				mu.Lock()
+				if regionExecution.RegionDeployType == config.RegionalRegionDeployType {
+					regional = append(regional, regionExecution)
+				} else {
+					inflight++
+					if inflight > maxInflight {
+						maxInflight = inflight
+					}
+				}
+				mu.Unlock()
+
+				time.Sleep(20 * time.Millisecond)
+
+				regionExecution.Output = tracks.ExecutionOutput{
+					StepOutputVariables: map[string]map[string]string{"net/vpc": {"region": regionExecution.Region}},
+				}
+
+				if regionExecution.Region == "us-west-2" {
+					regionExecution.Output.FailureCount = 1
+				}
+
+				mu.Lock()
+				if regionExecution.RegionDeployType == config.PrimaryRegionDeployType {
+					inflight--
+				}
+				mu.Unlock()
+
+				out <- regionExecution
+			}
+
+			trackChan := make(chan tracks.Output, 1)
+
+			tracks.ExecuteDeployTrack(tracks.Execution{
+				Logger: logger,
+				Fs:     fs,
+			}, config.Config{
+				PrimaryRegion:          "us-east-1",
+				PrimaryRegions:         []string{"us-east-1", "us-west-2"},
+				ParallelPrimaryRegions: parallel,
+				RegionalRegions:        []string{"us-east-2"},
+			}, tracks.Track{
+				Name:               "net",
+				RegionalDeployment: true,
+			}, trackChan)
+
+			output := <-trackChan
+
+			require.Len(t, output.Executions, 3)
+			require.Equal(t, "us-east-1", output.Executions[0].Region, "the executions are ordered by the primary regions")
+			require.Equal(t, "us-west-2", output.Executions[1].Region)
+			require.Equal(t, config.PrimaryRegionDeployType, output.Executions[1].RegionDeployType)
+			require.Equal(t, "us-east-1", output.PrimaryStepOutputVariables["net/vpc"]["region"], "the primary outputs are the first primary region's")
+
+			require.Len(t, regional, 1)
+			require.Equal(t, "us-east-1", regional[0].DefaultStepOutputVariables["net/vpc"]["region"])
+			require.Equal(t, 1, regional[0].PrimaryOutput.FailureCount, "the regional regions are skipped when any primary region failed")
+
+			if parallel {
+				require.Equal(t, 2, maxInflight, "the primary regions should execute concurrently")
+			} else {
+				require.Equal(t, 1, maxInflight, "each primary region should complete before the next starts")
+			}
+		})
+	}
+}
+
+func TestExecuteDeployTrack_ShouldHaltRegionalRolloutAfterFailedWave(t *testing.T) {
+	defer func(f func(<-chan tracks.RegionExecution, chan<- tracks.RegionExecution)) {
+		tracks.DeployTrackRegion = f
+	}(tracks.DeployTrackRegion)
+
+	var mu sync.Mutex
+	started := []string{}
+	halted := map[string]bool{}
+
+	tracks.DeployTrackRegion = func(in <-chan tracks.RegionExecution, out chan<- tracks.RegionExecution) {
+		regionExecution := <-in
+
+		mu.Lock()
+		if regionExecution.RegionDeployType == config.RegionalRegionDeployType {
+			started = append(started, regionExecution.Region)
+			halted[regionExecution.Region] = regionExecution.RolloutHalted
+		}
+		mu.Unlock()
+
+		regionExecution.Output = tracks.ExecutionOutput{}
+
+		if regionExecution.Region == "us-east-2" {
+			regionExecution.Output.FailureCount = 1
+		}
+
+		out <- regionExecution
+	}
+
+	trackChan := make(chan tracks.Output, 1)
+
+	tracks.ExecuteDeployTrack(tracks.Execution{
+		Logger: logger,
+		Fs:     fs,
+	}, config.Config{
+		PrimaryRegion:   "us-east-1",
+		RegionalRegions: []string{"us-east-1", "us-east-2", "us-west-1", "us-west-2"},
+		RegionalRollout: []config.RolloutWave{
+			{Regions: []string{"us-east-1"}},
+			{Regions: []string{"us-east-2"}},
+			{Regions: []string{"us-west-1", "us-west-2"}, Parallel: 1},
+		},
+	}, tracks.Track{
+		Name:               "net",
+		RegionalDeployment: true,
+	}, trackChan)
+
+	output := <-trackChan
+
+	require.Len(t, output.Executions, 5, "the regions of halted waves are still reported")
+	require.Equal(t, []string{"us-east-1", "us-east-2", "us-west-1", "us-west-2"}, started, "the waves should be deployed in order")
+	require.False(t, halted["us-east-1"])
+	require.False(t, halted["us-east-2"])
+	require.True(t, halted["us-west-1"], "a failed wave should halt the later waves")
+	require.True(t, halted["us-west-2"])
+}
+
+func TestExecuteDeployTrack_ShouldSkipRemainingRegionsAfterFailures(t *testing.T) {
+	defer func(f func(<-chan tracks.RegionExecution, chan<- tracks.RegionExecution)) {
+		tracks.DeployTrackRegion = f
+	}(tracks.DeployTrackRegion)
+
+	var mu sync.Mutex
+	halted := map[string]bool{}
+
+	tracks.DeployTrackRegion = func(in <-chan tracks.RegionExecution, out chan<- tracks.RegionExecution) {
+		regionExecution := <-in
+
+		mu.Lock()
+		if regionExecution.RegionDeployType == config.RegionalRegionDeployType {
+			halted[regionExecution.Region] = regionExecution.RolloutHalted
+		}
+		mu.Unlock()
+
+		regionExecution.Output = tracks.ExecutionOutput{}
+
+		if regionExecution.RegionDeployType == config.RegionalRegionDeployType && !regionExecution.RolloutHalted {
+			regionExecution.Output.FailureCount = 1
+		}
+
+		out <- regionExecution
+	}
+
+	trackChan := make(chan tracks.Output, 1)
+
+	tracks.ExecuteDeployTrack(tracks.Execution{
+		Logger: logger,
+		Fs:     fs,
+	}, config.Config{
+		PrimaryRegion:      "us-east-1",
+		RegionalRegions:    []string{"us-east-1", "us-east-2", "us-west-1", "us-west-2"},
+		RegionalRollout:    []config.RolloutWave{{Regions: []string{"us-east-1", "us-east-2", "us-west-1", "us-west-2"}, Parallel: 1}},
+		AbortAfterFailures: 2,
+	}, tracks.Track{
+		Name:               "net",
+		RegionalDeployment: true,
+	}, trackChan)
+
+	output := <-trackChan
+
+	require.Len(t, output.Executions, 5, "the skipped regions are still reported")
+	require.False(t, halted["us-east-1"])
+	require.False(t, halted["us-east-2"])
+	require.True(t, halted["us-west-1"], "the remaining regions should be skipped once the threshold failed")
+	require.True(t, halted["us-west-2"])
+}
+
 func TestAddToTrackOutput(t *testing.T) {
 	stepOutputVariables := make(map[string]interface{})
 	stepOutputVariables["resource_name"] = "my-cool-resource"
@@ -735,7 +927,6 @@ func TestExecuteDeployTrackRegion_ShouldPassRegionalVariables(t *testing.T) {
 		TrackName:                  "",
 		TrackDir:                   "",
 		TrackStepProgressionsCount: 2,
-		TrackStepsWithTestsCount:   0,
 		TrackOrderedSteps: map[int][]config.Step{
 			1: {
 				{
@@ -892,6 +1083,39 @@ func TestExecuteDeployTrackRegion_ShouldSkipWhenPrimaryFails(t *testing.T) {
 	require.Len(t, executeStepSpy, 0, "Should not execute regional steps when primary region fails")
 }
 
+func TestExecuteDeployTrackRegion_ShouldSkipWhenRolloutHalted(t *testing.T) {
+	outChan := make(chan tracks.RegionExecution, 1)
+	inChan := make(chan tracks.RegionExecution, 1)
+
+	executeStepSpy := map[string]config.Step{}
+
+	tracks.ExecuteStep = func(region string, regionDeployType config.RegionDeployType, entry *logrus.Entry, fs afero.Fs, defaultStepOutputVariables map[string]map[string]string, stepProgression int,
+		s config.Step, out chan<- config.Step, destroy bool) {
+		executeStepSpy[s.Name] = s
+
+		s.Output = config.StepOutput{Status: config.Success}
+		out <- s
+	}
+
+	go tracks.ExecuteDeployTrackRegion(inChan, outChan)
+	inChan <- tracks.RegionExecution{
+		Logger:                     logger,
+		Fs:                         fs,
+		Region:                     "us-west-2",
+		RegionDeployType:           config.RegionalRegionDeployType,
+		TrackStepProgressionsCount: 1,
+		TrackOrderedSteps: map[int][]config.Step{
+			1: {{Name: "step_p1", RegionalResourcesExist: true}},
+		},
+		RolloutHalted: true,
+	}
+	regionalExecution := <-outChan
+
+	require.Len(t, executeStepSpy, 0, "Should not execute regional steps when an earlier wave of the rollout failed")
+	require.Equal(t, config.Skipped, regionalExecution.Output.Steps["step_p1"].Output.Status)
+	require.Equal(t, 1, regionalExecution.Output.SkippedCount)
+}
+
 func TestExecuteDeployTrackRegion_ShouldNaWhenRegionalResourcesDoNotExist(t *testing.T) {
 	primaryOutChan := make(chan tracks.RegionExecution, 1)
 	primaryInChan := make(chan tracks.RegionExecution, 1)
@@ -922,3 +1146,688 @@ func TestExecuteDeployTrackRegion_ShouldNaWhenRegionalResourcesDoNotExist(t *tes
 	require.NotNil(t, primaryTrackExecution)
 	require.Equal(t, config.Na, primaryTrackExecution.Output.Steps["step_p1"].Output.Status)
 }
+
+func TestExecuteTeardownOrder_ShouldDestroyStepsInOrder(t *testing.T) {
+	var mu sync.Mutex
+	destroyed := []string{}
+
+	tracks.ExecuteStep = func(region string, regionDeployType config.RegionDeployType, entry *logrus.Entry, fs afero.Fs, defaultStepOutputVariables map[string]map[string]string, stepProgression int,
+		s config.Step, out chan<- config.Step, destroy bool) {
+		mu.Lock()
+		destroyed = append(destroyed, fmt.Sprintf("%s/%s", s.ID, region))
+		mu.Unlock()
+
+		s.Output = config.StepOutput{Status: config.Success, Region: region, RegionDeployType: regionDeployType}
+		if s.ID == "network/vnet" && destroy {
+			s.Output.Status = config.Fail
+			s.Output.Err = fmt.Errorf("stub failure")
+		}
+		out <- s
+	}
+	defer func() { tracks.ExecuteStep = tracks.ExecuteStepImpl }()
+
+	stage := tracks.Stage{Tracks: map[string]tracks.Track{
+		"network": {Name: "network", OrderedSteps: map[int][]config.Step{
+			1: {{ID: "network/vnet", Name: "vnet"}},
+		}},
+		"app": {Name: "app", OrderedSteps: map[int][]config.Step{
+			1: {{ID: "app/db", Name: "db"}},
+			2: {{ID: "app/web", Name: "web", RegionalResourcesExist: true}},
+		}},
+	}}
+
+	cfg := config.Config{
+		PrimaryRegion:   "eastus",
+		RegionalRegions: []string{"westus"},
+		TeardownOrder:   []string{"app/web", "network/vnet", "app/db", "other/untargeted"},
+	}
+
+	err := tracks.ExecuteTeardownOrder(logger, fs, cfg, stage)
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"app/web/westus", "app/web/eastus", "network/vnet/eastus"}, destroyed, "should destroy regional before primary in the teardown order, stopping after a failure")
+
+	failed := []string{}
+	skipped := 0
+	for _, exec := range stage.Tracks["network"].DestroyOutput.Executions {
+		for _, s := range exec.Output.FailedSteps {
+			failed = append(failed, s.ID)
+		}
+	}
+	for _, exec := range stage.Tracks["app"].DestroyOutput.Executions {
+		skipped += exec.Output.SkippedCount
+	}
+
+	require.Equal(t, []string{"network/vnet"}, failed)
+	require.Equal(t, 1, skipped, "should skip app/db after the failure")
+}
+
+func TestExecuteTeardownOrder_ShouldFailWhenStepsAreMissing(t *testing.T) {
+	stage := tracks.Stage{Tracks: map[string]tracks.Track{
+		"app": {Name: "app", OrderedSteps: map[int][]config.Step{
+			1: {{ID: "app/db", Name: "db"}},
+			2: {{ID: "app/web", Name: "web"}},
+		}},
+	}}
+
+	err := tracks.ExecuteTeardownOrder(logger, fs, config.Config{TeardownOrder: []string{"app/web"}}, stage)
+	require.EqualError(t, err, "the teardown order does not include step(s) app/db")
+
+	err = tracks.ExecuteTeardownOrder(logger, fs, config.Config{TeardownOrder: []string{"app/web", "app/db", "app/web"}}, stage)
+	require.Error(t, err, "should fail when a step is listed more than once")
+
+	require.Empty(t, stage.Tracks["app"].DestroyOutput.Executions, "should not destroy with an invalid teardown order")
+}
+
+func TestExecuteTracks_ShouldOnlyPlanStepsBeforeDestroying(t *testing.T) {
+	deployDryRuns := map[string]bool{}
+	destroyDryRuns := map[string]bool{}
+	var mu sync.Mutex
+
+	tracks.DeployTrack = func(execution tracks.Execution, cfg config.Config, t tracks.Track, out chan<- tracks.Output) {
+		mu.Lock()
+		for _, steps := range t.OrderedSteps {
+			for _, s := range steps {
+				deployDryRuns[s.ID] = s.DeployConfig.DryRun
+			}
+		}
+		mu.Unlock()
+
+		out <- tracks.Output{Name: t.Name}
+	}
+
+	tracks.DestroyTrack = func(execution tracks.Execution, cfg config.Config, t tracks.Track, out chan<- tracks.Output) {
+		mu.Lock()
+		for _, steps := range t.OrderedSteps {
+			for _, s := range steps {
+				destroyDryRuns[s.ID] = s.DeployConfig.DryRun
+			}
+		}
+		mu.Unlock()
+
+		out <- tracks.Output{Name: t.Name}
+	}
+
+	defer func() {
+		tracks.DeployTrack = tracks.ExecuteDeployTrack
+		tracks.DestroyTrack = tracks.ExecuteDestroyTrack
+	}()
+
+	sut.ExecuteTracks(config.Config{
+		TargetAll: true,
+		Destroy:   true,
+	})
+
+	require.NotEmpty(t, deployDryRuns)
+	for id, dryRun := range deployDryRuns {
+		require.True(t, dryRun, "step %s should only be planned", id)
+	}
+
+	require.Len(t, destroyDryRuns, len(deployDryRuns), "every planned step should be destroyed")
+	for id, dryRun := range destroyDryRuns {
+		require.False(t, dryRun, "step %s should be destroyed", id)
+	}
+}
+
+func TestExecuteTracks_ShouldOnlyDeployTrackRequiringApprovalOnceItsPlanWasApproved(t *testing.T) {
+	require.NoError(t, afero.WriteFile(fs, "tracks/track-b/runiac.yml", []byte("approval: manual\n"), 0644))
+	defer fs.Remove("tracks/track-b/runiac.yml")
+
+	var mu sync.Mutex
+	deploys := []string{}
+
+	tracks.DeployTrack = func(execution tracks.Execution, cfg config.Config, t tracks.Track, out chan<- tracks.Output) {
+		dryRun := true
+		for _, steps := range t.OrderedSteps {
+			for _, s := range steps {
+				dryRun = dryRun && s.DeployConfig.DryRun
+			}
+		}
+
+		mu.Lock()
+		deploys = append(deploys, fmt.Sprintf("%s:%v", t.Name, dryRun))
+		mu.Unlock()
+
+		out <- tracks.Output{Name: t.Name}
+	}
+
+	approvals := []string{}
+	tracks.ApproveTrack = func(logger *logrus.Entry, cfg config.Config, track string, gate string) error {
+		mu.Lock()
+		approvals = append(approvals, fmt.Sprintf("%s:%s", track, gate))
+		mu.Unlock()
+
+		return nil
+	}
+
+	defer func() {
+		tracks.DeployTrack = tracks.ExecuteDeployTrack
+		tracks.ApproveTrack = tracks.WaitForTrackApproval
+	}()
+
+	stage := sut.ExecuteTracks(config.Config{TargetAll: true})
+
+	require.NoError(t, stage.ApprovalErr)
+	require.Equal(t, []string{"track-b:planned"}, approvals)
+	require.ElementsMatch(t, []string{"_pretrack:false", "track-a:false", "track-b:true", "track-b:false"}, deploys)
+	require.False(t, stage.Tracks["track-b"].Skipped)
+
+	// the plan of a track that is not approved is kept for the report, the track is skipped
+	approvals = []string{}
+	tracks.ApproveTrack = func(logger *logrus.Entry, cfg config.Config, track string, gate string) error {
+		return fmt.Errorf("track %s was denied", track)
+	}
+
+	deploys = []string{}
+	stage = sut.ExecuteTracks(config.Config{TargetAll: true})
+
+	require.EqualError(t, stage.ApprovalErr, "track track-b was denied")
+	require.ElementsMatch(t, []string{"_pretrack:false", "track-a:false", "track-b:true"}, deploys)
+	require.True(t, stage.Tracks["track-b"].Skipped)
+	require.False(t, stage.Tracks["track-a"].Skipped)
+
+	// dry runs are never gated
+	deploys = []string{}
+	stage = sut.ExecuteTracks(config.Config{TargetAll: true, DryRun: true})
+
+	require.NoError(t, stage.ApprovalErr)
+	require.ElementsMatch(t, []string{"_pretrack:true", "track-a:true", "track-b:true"}, deploys, "every track should only be planned once")
+}
+
+func TestExecuteTracks_ShouldPauseBetweenTracks(t *testing.T) {
+	deploys := []string{}
+
+	tracks.DeployTrack = func(execution tracks.Execution, cfg config.Config, t tracks.Track, out chan<- tracks.Output) {
+		deploys = append(deploys, t.Name)
+		out <- tracks.Output{Name: t.Name}
+	}
+
+	approvals := []string{}
+	tracks.ApproveTrack = func(logger *logrus.Entry, cfg config.Config, track string, gate string) error {
+		approvals = append(approvals, fmt.Sprintf("%s:%s", track, gate))
+		return nil
+	}
+
+	defer func() {
+		tracks.DeployTrack = tracks.ExecuteDeployTrack
+		tracks.ApproveTrack = tracks.WaitForTrackApproval
+	}()
+
+	stage := sut.ExecuteTracks(config.Config{TargetAll: true, PauseBetweenTracks: true})
+
+	require.NoError(t, stage.ApprovalErr)
+	require.Equal(t, []string{"_pretrack", "track-a", "track-b"}, deploys, "the tracks should deploy one at a time")
+	require.Equal(t, []string{"track-a:completed"}, approvals, "the last track should not wait for an approval")
+
+	// the tracks after a track the deploy was not approved to continue after are skipped
+	deploys = []string{}
+	tracks.ApproveTrack = func(logger *logrus.Entry, cfg config.Config, track string, gate string) error {
+		return fmt.Errorf("track %s was not approved", track)
+	}
+
+	stage = sut.ExecuteTracks(config.Config{TargetAll: true, PauseBetweenTracks: true})
+
+	require.EqualError(t, stage.ApprovalErr, "track track-a was not approved")
+	require.Equal(t, []string{"_pretrack", "track-a"}, deploys)
+	require.False(t, stage.Tracks["track-a"].Skipped)
+	require.True(t, stage.Tracks["track-b"].Skipped)
+}
+
+func TestExecuteDeployTrackRegion_ShouldStartStepsOnceTheirDependenciesComplete(t *testing.T) {
+	defer func() { tracks.ExecuteStep = tracks.ExecuteStepImpl }()
+
+	release := make(chan struct{})
+
+	tracks.ExecuteStep = func(region string, regionDeployType config.RegionDeployType, entry *logrus.Entry, fs afero.Fs, defaultStepOutputVariables map[string]map[string]string, stepProgression int,
+		s config.Step, out chan<- config.Step, destroy bool) {
+		s.Output = config.StepOutput{Status: config.Success}
+
+		switch s.Name {
+		case "slow":
+			// only completes once app started, which does not depend on it
+			select {
+			case <-release:
+			case <-time.After(5 * time.Second):
+				s.Output.Status = config.Fail
+			}
+		case "app":
+			close(release)
+		}
+
+		out <- s
+	}
+
+	inChan := make(chan tracks.RegionExecution, 1)
+	outChan := make(chan tracks.RegionExecution, 1)
+
+	go tracks.ExecuteDeployTrackRegion(inChan, outChan)
+	inChan <- tracks.RegionExecution{
+		Logger:                     logger,
+		Fs:                         fs,
+		TrackStepProgressionsCount: 2,
+		TrackOrderedSteps: map[int][]config.Step{
+			1: {{Name: "slow", ProgressionLevel: 1}, {Name: "network", ProgressionLevel: 1}},
+			2: {{Name: "app", ProgressionLevel: 2, DependsOn: []string{"network"}}},
+		},
+		RegionDeployType: config.PrimaryRegionDeployType,
+	}
+	execution := <-outChan
+
+	require.Equal(t, 3, execution.Output.ExecutedCount)
+	require.Equal(t, 0, execution.Output.FailureCount, "app should execute while slow is executing")
+}
+
+func TestExecuteDeployTrackRegion_ShouldOnlySkipDependentsOfFailedSteps(t *testing.T) {
+	defer func() { tracks.ExecuteStep = tracks.ExecuteStepImpl }()
+
+	var mu sync.Mutex
+	executed := map[string]bool{}
+
+	tracks.ExecuteStep = func(region string, regionDeployType config.RegionDeployType, entry *logrus.Entry, fs afero.Fs, defaultStepOutputVariables map[string]map[string]string, stepProgression int,
+		s config.Step, out chan<- config.Step, destroy bool) {
+		mu.Lock()
+		executed[s.Name] = true
+		mu.Unlock()
+
+		s.Output = config.StepOutput{Status: config.Success}
+		if s.Name == "a" {
+			s.Output.Status = config.Fail
+		}
+
+		out <- s
+	}
+
+	inChan := make(chan tracks.RegionExecution, 1)
+	outChan := make(chan tracks.RegionExecution, 1)
+
+	go tracks.ExecuteDeployTrackRegion(inChan, outChan)
+	inChan <- tracks.RegionExecution{
+		Logger:                     logger,
+		Fs:                         fs,
+		TrackStepProgressionsCount: 3,
+		TrackOrderedSteps: map[int][]config.Step{
+			1: {{Name: "a", ProgressionLevel: 1}, {Name: "b", ProgressionLevel: 1}},
+			2: {{Name: "c", ProgressionLevel: 2, DependsOn: []string{"b"}}, {Name: "d", ProgressionLevel: 2}},
+			3: {{Name: "e", ProgressionLevel: 3, DependsOn: []string{"c"}}},
+		},
+		RegionDeployType: config.PrimaryRegionDeployType,
+	}
+	execution := <-outChan
+
+	require.Equal(t, map[string]bool{"a": true, "b": true, "c": true, "e": true}, executed)
+	require.Equal(t, config.Skipped, execution.Output.Steps["d"].Output.Status, "d implicitly depends on the failed a")
+	require.Equal(t, 1, execution.Output.SkippedCount)
+}
+
+func TestExecuteDeployTrackRegion_ShouldLimitParallelSteps(t *testing.T) {
+	defer func() { tracks.ExecuteStep = tracks.ExecuteStepImpl }()
+
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+
+	tracks.ExecuteStep = func(region string, regionDeployType config.RegionDeployType, entry *logrus.Entry, fs afero.Fs, defaultStepOutputVariables map[string]map[string]string, stepProgression int,
+		s config.Step, out chan<- config.Step, destroy bool) {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+
+		s.Output = config.StepOutput{Status: config.Success}
+		out <- s
+	}
+
+	inChan := make(chan tracks.RegionExecution, 1)
+	outChan := make(chan tracks.RegionExecution, 1)
+
+	go tracks.ExecuteDeployTrackRegion(inChan, outChan)
+	inChan <- tracks.RegionExecution{
+		Logger:                     logger,
+		Fs:                         fs,
+		TrackStepProgressionsCount: 1,
+		TrackOrderedSteps: map[int][]config.Step{
+			1: {{Name: "a", ProgressionLevel: 1}, {Name: "b", ProgressionLevel: 1}, {Name: "c", ProgressionLevel: 1}, {Name: "d", ProgressionLevel: 1}},
+		},
+		RegionDeployType: config.PrimaryRegionDeployType,
+		MaxParallel:      2,
+	}
+	execution := <-outChan
+
+	require.Equal(t, 4, execution.Output.ExecutedCount)
+	require.Equal(t, 2, maxRunning)
+}
+
+func TestExecuteDestroyTrackRegion_ShouldDestroyDependentsFirst(t *testing.T) {
+	defer func() { tracks.ExecuteStep = tracks.ExecuteStepImpl }()
+
+	var mu sync.Mutex
+	destroyed := []string{}
+
+	tracks.ExecuteStep = func(region string, regionDeployType config.RegionDeployType, entry *logrus.Entry, fs afero.Fs, defaultStepOutputVariables map[string]map[string]string, stepProgression int,
+		s config.Step, out chan<- config.Step, destroy bool) {
+		mu.Lock()
+		destroyed = append(destroyed, s.Name)
+		mu.Unlock()
+
+		s.Output = config.StepOutput{Status: config.Success}
+		out <- s
+	}
+
+	inChan := make(chan tracks.RegionExecution, 1)
+	outChan := make(chan tracks.RegionExecution, 1)
+
+	go tracks.ExecuteDestroyTrackRegion(inChan, outChan)
+	inChan <- tracks.RegionExecution{
+		Logger:                     logger,
+		Fs:                         fs,
+		TrackStepProgressionsCount: 3,
+		TrackOrderedSteps: map[int][]config.Step{
+			1: {{Name: "network", ProgressionLevel: 1}},
+			2: {{Name: "db", ProgressionLevel: 2, DependsOn: []string{"network"}}},
+			3: {{Name: "app", ProgressionLevel: 3}},
+		},
+		RegionDeployType: config.PrimaryRegionDeployType,
+	}
+	execution := <-outChan
+
+	require.Equal(t, 3, execution.Output.ExecutedCount)
+	require.Equal(t, []string{"app", "db", "network"}, destroyed)
+}
+
+func TestGatherTracks_ShouldReadStepDependencies(t *testing.T) {
+	stepFs := afero.NewMemMapFs()
+	_ = stepFs.MkdirAll("tracks/app/step1_network", 0755)
+	_ = stepFs.MkdirAll("tracks/app/step1_dns", 0755)
+	_ = afero.WriteFile(stepFs, "tracks/app/step1_dns/runiac.yml", []byte("enabled: true\n"), 0644)
+	_ = stepFs.MkdirAll("tracks/app/step2_cluster", 0755)
+	_ = afero.WriteFile(stepFs, "tracks/app/step2_cluster/runiac.yml", []byte("depends_on:\n  - app/network\n"), 0644)
+	_ = stepFs.MkdirAll("tracks/app/step3_ingress", 0755)
+	_ = afero.WriteFile(stepFs, "tracks/app/step3_ingress/runiac.hcl", []byte(`depends_on = ["dns"]`), 0644)
+	_ = stepFs.MkdirAll("tracks/cycle/step1_a", 0755)
+	_ = stepFs.MkdirAll("tracks/cycle/step2_b", 0755)
+	_ = afero.WriteFile(stepFs, "tracks/cycle/step1_a/runiac.yml", []byte("depends_on: [b]\n"), 0644)
+	_ = afero.WriteFile(stepFs, "tracks/cycle/step2_b/runiac.yml", []byte("depends_on: [a]\n"), 0644)
+	_ = stepFs.MkdirAll("tracks/unknown/step1_a", 0755)
+	_ = afero.WriteFile(stepFs, "tracks/unknown/step1_a/runiac.yml", []byte("depends_on: [missing]\n"), 0644)
+
+	gathered := tracks.DirectoryBasedTracker{Fs: stepFs, Log: logger}.GatherTracks(config.Config{TargetAll: true})
+
+	require.Len(t, gathered, 1, "tracks with cyclic or unknown dependencies are not executed")
+	require.Equal(t, "app", gathered[0].Name)
+
+	for _, s := range gathered[0].OrderedSteps[2] {
+		require.Equal(t, []string{"network"}, s.DependsOn)
+	}
+
+	for _, s := range gathered[0].OrderedSteps[1] {
+		require.Nil(t, s.DependsOn)
+	}
+
+	require.Equal(t, []string{"dns"}, gathered[0].OrderedSteps[3][0].DependsOn, "the step's runiac.hcl should be read")
+}
+
+func TestGatherTracks_ShouldReadStepInputs(t *testing.T) {
+	stepFs := afero.NewMemMapFs()
+	_ = stepFs.MkdirAll("tracks/app/step1_network", 0755)
+	_ = stepFs.MkdirAll("tracks/app/step2_cluster", 0755)
+	_ = afero.WriteFile(stepFs, "tracks/app/step2_cluster/runiac.yml", []byte("inputs:\n  subnet_id: app/network.subnet_id\n"), 0644)
+	_ = stepFs.MkdirAll("tracks/app/step3_dns", 0755)
+	_ = afero.WriteFile(stepFs, "tracks/app/step3_dns/runiac.yml", []byte("inputs: [network.vnet_id, cluster.fqdn]\n"), 0644)
+	_ = stepFs.MkdirAll("tracks/sibling/step1_a", 0755)
+	_ = stepFs.MkdirAll("tracks/sibling/step1_b", 0755)
+	_ = afero.WriteFile(stepFs, "tracks/sibling/step1_b/runiac.yml", []byte("inputs: [a.id]\n"), 0644)
+	_ = stepFs.MkdirAll("tracks/unknown/step1_a", 0755)
+	_ = afero.WriteFile(stepFs, "tracks/unknown/step1_a/runiac.yml", []byte("inputs: [missing.id]\n"), 0644)
+	_ = stepFs.MkdirAll("tracks/invalid/step1_a", 0755)
+	_ = afero.WriteFile(stepFs, "tracks/invalid/step1_a/runiac.yml", []byte("inputs: [id]\n"), 0644)
+
+	gathered := tracks.DirectoryBasedTracker{Fs: stepFs, Log: logger}.GatherTracks(config.Config{TargetAll: true})
+
+	require.Len(t, gathered, 1, "tracks reading outputs of steps they do not depend on, unknown steps or invalid references are not executed")
+	require.Equal(t, "app", gathered[0].Name)
+
+	require.Nil(t, gathered[0].OrderedSteps[1][0].Inputs)
+	require.Equal(t, []config.StepInput{{Name: "subnet_id", Step: "network", Output: "subnet_id"}}, gathered[0].OrderedSteps[2][0].Inputs)
+	require.Equal(t, []config.StepInput{
+		{Name: "cluster_fqdn", Step: "cluster", Output: "fqdn"},
+		{Name: "network_vnet_id", Step: "network", Output: "vnet_id"},
+	}, gathered[0].OrderedSteps[3][0].Inputs, "a step should read the outputs of the steps it depends on through other steps")
+}
+
+func TestGatherTracks_ShouldReadStepRetryPolicy(t *testing.T) {
+	stepFs := afero.NewMemMapFs()
+	_ = stepFs.MkdirAll("tracks/app/step1_network", 0755)
+	_ = stepFs.MkdirAll("tracks/app/step2_cluster", 0755)
+	_ = afero.WriteFile(stepFs, "tracks/app/step2_cluster/runiac.yml", []byte("timeout: 45m\nretries: 3\nretry_backoff: 1m\n"), 0644)
+	_ = stepFs.MkdirAll("tracks/invalid/step1_a", 0755)
+	_ = afero.WriteFile(stepFs, "tracks/invalid/step1_a/runiac.yml", []byte("timeout: soon\n"), 0644)
+
+	gathered := tracks.DirectoryBasedTracker{Fs: stepFs, Log: logger}.GatherTracks(config.Config{
+		TargetAll:        true,
+		StepTimeout:      time.Hour,
+		StepRetries:      1,
+		StepRetryBackoff: 30 * time.Second,
+	})
+
+	require.Len(t, gathered, 1, "tracks with an invalid step timeout are not executed")
+
+	network := gathered[0].OrderedSteps[1][0]
+	require.Equal(t, time.Hour, network.Timeout)
+	require.Equal(t, 1, network.Retries)
+	require.Equal(t, 30*time.Second, network.RetryBackoff)
+
+	cluster := gathered[0].OrderedSteps[2][0]
+	require.Equal(t, 45*time.Minute, cluster.Timeout)
+	require.Equal(t, 3, cluster.Retries)
+	require.Equal(t, time.Minute, cluster.RetryBackoff)
+}
+
+func TestGatherTracks_ShouldPreferTheStepRetryPolicyOfTheEnv(t *testing.T) {
+	stepFs := afero.NewMemMapFs()
+	_ = stepFs.MkdirAll("tracks/app/step1_cluster", 0755)
+	_ = afero.WriteFile(stepFs, "tracks/app/step1_cluster/runiac.yml", []byte("timeout: 45m\nretries: 3\nretry_backoff: 1m\n"), 0644)
+
+	require.NoError(t, os.Setenv("RUNIAC_STEP_RETRIES", "5"))
+	defer os.Unsetenv("RUNIAC_STEP_RETRIES")
+
+	gathered := tracks.DirectoryBasedTracker{Fs: stepFs, Log: logger}.GatherTracks(config.Config{
+		TargetAll:        true,
+		StepRetries:      5,
+		StepRetryBackoff: 30 * time.Second,
+	})
+
+	cluster := gathered[0].OrderedSteps[1][0]
+	require.Equal(t, 5, cluster.Retries, "RUNIAC_STEP_RETRIES should override the step's retries")
+	require.Equal(t, 45*time.Minute, cluster.Timeout)
+	require.Equal(t, time.Minute, cluster.RetryBackoff)
+}
+
+// stubStepper fails the first failures executions of the step, an execution blocks until its context is done when
+// block is set
+type stubStepper struct {
+	failures   int
+	block      bool
+	executions *int
+}
+
+func (s stubStepper) PreExecute(exec config.StepExecution) (config.StepExecution, error) {
+	return exec, nil
+}
+
+func (s stubStepper) ExecuteStep(exec config.StepExecution) config.StepOutput {
+	*s.executions++
+
+	if s.block {
+		<-exec.Context.Done()
+		return config.StepOutput{Status: config.Success}
+	}
+
+	if *s.executions <= s.failures {
+		return config.StepOutput{Status: config.Fail, Err: fmt.Errorf("throttled")}
+	}
+
+	return config.StepOutput{Status: config.Success}
+}
+
+func (s stubStepper) ExecuteStepTests(exec config.StepExecution) config.StepTestOutput {
+	return config.StepTestOutput{}
+}
+
+func (s stubStepper) ExecuteStepDestroy(exec config.StepExecution) config.StepOutput {
+	return s.ExecuteStep(exec)
+}
+
+func TestExecuteStepImpl_ShouldRetryFailedStep(t *testing.T) {
+	var test = map[string]struct {
+		failures           int
+		retries            int
+		expectedExecutions int
+		expectedStatus     config.DeployResult
+	}{
+		"ShouldSucceedAfterRetries":       {failures: 2, retries: 2, expectedExecutions: 3, expectedStatus: config.Success},
+		"ShouldFailOnceRetriesExhausted":  {failures: 3, retries: 2, expectedExecutions: 3, expectedStatus: config.Fail},
+		"ShouldNotRetryWithoutRetries":    {failures: 1, retries: 0, expectedExecutions: 1, expectedStatus: config.Fail},
+		"ShouldNotRetrySuccessfulAttempt": {failures: 0, retries: 2, expectedExecutions: 1, expectedStatus: config.Success},
+	}
+
+	for name, test := range test {
+		t.Run(name, func(t *testing.T) {
+			executions := 0
+			out := make(chan config.Step, 1)
+
+			s := config.Step{
+				ID:        "app/network",
+				Name:      "network",
+				TrackName: "app",
+				Retries:   test.retries,
+				Runner:    stubStepper{failures: test.failures, executions: &executions},
+			}
+
+			tracks.ExecuteStepImpl("us-east-1", config.PrimaryRegionDeployType, logger, fs, map[string]map[string]string{}, 1, s, out, false)
+			executed := <-out
+
+			require.Equal(t, test.expectedExecutions, executions)
+			require.Equal(t, test.expectedStatus, executed.Output.Status)
+		})
+	}
+}
+
+func TestExecuteStepImpl_ShouldFailStepExceedingTimeout(t *testing.T) {
+	executions := 0
+	out := make(chan config.Step, 1)
+
+	s := config.Step{
+		ID:        "app/network",
+		Name:      "network",
+		TrackName: "app",
+		Timeout:   10 * time.Millisecond,
+		Retries:   1,
+		Runner:    stubStepper{block: true, executions: &executions},
+	}
+
+	tracks.ExecuteStepImpl("us-east-1", config.PrimaryRegionDeployType, logger, fs, map[string]map[string]string{}, 1, s, out, true)
+	executed := <-out
+
+	require.Equal(t, 2, executions, "a timed out attempt is retried")
+	require.Equal(t, config.Fail, executed.Output.Status)
+	require.EqualError(t, executed.Output.Err, "step app/network timed out after 10ms")
+}
+
+func TestExecuteStepImpl_ShouldTraceStepUnderRegionSpan(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tracer := tracing.NewTracerWithExporter(exporter)
+	region := tracer.StartRoot("region primary/us-east-1", "")
+
+	executions := 0
+	out := make(chan config.Step, 1)
+
+	s := config.Step{
+		ID:        "app/network",
+		Name:      "network",
+		TrackName: "app",
+		Runner:    stubStepper{failures: 1, executions: &executions},
+		Span:      region,
+	}
+
+	tracks.ExecuteStepImpl("us-east-1", config.PrimaryRegionDeployType, logger, fs, map[string]map[string]string{}, 1, s, out, false)
+	<-out
+
+	require.NoError(t, tracer.Flush(context.Background()))
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	require.Equal(t, "step app/network", spans[0].Name)
+	require.Equal(t, region.SpanID, spans[0].Parent.SpanID().String())
+	require.Equal(t, codes.Error, spans[0].Status.Code)
+	require.Equal(t, "throttled", spans[0].Status.Description)
+}
+
+func TestExecuteTracks_ShouldRollBackAppliedStepsWhenDeployFails(t *testing.T) {
+	var mu sync.Mutex
+	rollbackSteps := map[string]map[string]map[string]bool{}
+
+	applied := func(name string, status config.DeployResult) tracks.Output {
+		failures := 0
+		if status == config.Fail {
+			failures = 1
+		}
+
+		return tracks.Output{Name: name, Executions: []tracks.RegionExecution{{
+			Region:           "us-east-1",
+			RegionDeployType: config.PrimaryRegionDeployType,
+			Output: tracks.ExecutionOutput{FailureCount: failures, Steps: map[string]config.Step{
+				"step1": {Name: "step1", Output: config.StepOutput{Status: status}},
+				"step2": {Name: "step2", Output: config.StepOutput{Status: config.Skipped}},
+			}},
+		}}}
+	}
+
+	tracks.DeployTrack = func(execution tracks.Execution, cfg config.Config, t tracks.Track, out chan<- tracks.Output) {
+		switch t.Name {
+		case "track-a":
+			out <- applied(t.Name, config.Fail)
+		case "track-b":
+			out <- tracks.Output{Name: t.Name}
+		default:
+			out <- applied(t.Name, config.Success)
+		}
+	}
+
+	tracks.DestroyTrack = func(execution tracks.Execution, cfg config.Config, t tracks.Track, out chan<- tracks.Output) {
+		mu.Lock()
+		rollbackSteps[t.Name] = execution.RollbackSteps
+		mu.Unlock()
+
+		out <- tracks.Output{Name: t.Name}
+	}
+
+	defer func() {
+		tracks.DeployTrack = tracks.ExecuteDeployTrack
+		tracks.DestroyTrack = tracks.ExecuteDestroyTrack
+	}()
+
+	stage := sut.ExecuteTracks(config.Config{TargetAll: true})
+
+	require.False(t, stage.RolledBack, "should not roll back when rollback_on_failure is not set")
+	require.Empty(t, rollbackSteps)
+
+	stage = sut.ExecuteTracks(config.Config{TargetAll: true, RollbackOnFailure: true, DeploymentRing: "prod", Namespace: "42"})
+
+	require.False(t, stage.RolledBack, "should only roll back namespaced rings")
+	require.Empty(t, rollbackSteps)
+
+	stage = sut.ExecuteTracks(config.Config{TargetAll: true, RollbackOnFailure: true, DeploymentRing: config.PullRequestDeploymentRing, Namespace: "42"})
+
+	require.True(t, stage.RolledBack)
+	require.Equal(t, map[string]map[string]map[string]bool{
+		"_pretrack": {"primary-us-east-1": {"step1": true}},
+		"track-a":   {"primary-us-east-1": {"step1": true}},
+	}, rollbackSteps, "should only destroy the steps the deploy applied, the track without applied steps is not destroyed")
+}
@@ -14,6 +14,7 @@ func RunAzureCLICommand(streamOutput bool, options *Options, additionalArgs ...s
 		NonInteractive:    true,
 		SensitiveArgs:     false,
 		Logger:            options.Logger,
+		Context:           options.Context,
 	}
 
 	if streamOutput {
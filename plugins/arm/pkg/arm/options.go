@@ -1,6 +1,8 @@
 package arm
 
 import (
+	"context"
+
 	"github.com/sirupsen/logrus"
 )
 
@@ -10,4 +12,5 @@ type Options struct {
 	EnvVars           map[string]string
 	OutputMaxLineSize int
 	Logger            *logrus.Entry
+	Context           context.Context // If set, kills the running command when the context is done, e.g. when the step times out
 }
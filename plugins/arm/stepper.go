@@ -124,6 +124,7 @@ func getCommonOptions(exec config.StepExecution) (options *arm.Options, err erro
 		AzureCLIDir:    exec.Dir,
 		EnvVars:        map[string]string{},
 		Logger:         exec.Logger,
+		Context:        exec.Context,
 	}
 
 	return
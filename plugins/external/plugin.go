@@ -0,0 +1,30 @@
+package plugins_external
+
+import (
+	"os/exec"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ExternalPlugin is a runner plugin executable, runiac-runner-{name} on the path
+type ExternalPlugin struct {
+	Binary string
+}
+
+func (info ExternalPlugin) Initialize(logger *logrus.Entry) {
+	logger.Infof("Initializing runiac external runner plugin %s", info.Binary)
+
+	path, err := exec.LookPath(info.Binary)
+	if err != nil {
+		logger.WithError(err).Errorf("Unable to find the runner plugin %s on the path", info.Binary)
+		return
+	}
+
+	logger.Info("Binary: ", path)
+
+	// the version action is optional for runner plugins
+	out, err := exec.Command(info.Binary, "version").Output()
+	if err == nil {
+		logger.Info("Version: ", string(out))
+	}
+}
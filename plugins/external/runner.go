@@ -0,0 +1,166 @@
+package plugins_external
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/sirupsen/logrus"
+)
+
+// ExternalRunner implements config.Runner by executing a runner plugin. Each lifecycle action runs
+// `{binary} {action}` in the step directory with the request as json on stdin: init, plan, apply, destroy and
+// outputs, which writes the step's outputs as a json object to stdout. Any other output is logged.
+type ExternalRunner struct {
+	Binary string
+}
+
+// execCommand is shadowed by the step executions named exec
+var execCommand = exec.CommandContext
+
+// runnerRequest is the json the runner plugin reads from stdin
+type runnerRequest struct {
+	Action           string            `json:"action"`
+	Dir              string            `json:"dir"`
+	Project          string            `json:"project"`
+	Track            string            `json:"track"`
+	Step             string            `json:"step"`
+	DeploymentRing   string            `json:"deployment_ring"`
+	Environment      string            `json:"environment"`
+	Namespace        string            `json:"namespace"`
+	AppVersion       string            `json:"app_version"`
+	AccountID        string            `json:"account_id"`
+	Region           string            `json:"region"`
+	RegionDeployType string            `json:"region_deploy_type"`
+	PrimaryRegion    string            `json:"primary_region"`
+	DryRun           bool              `json:"dry_run"`
+	Destroy          bool              `json:"destroy"`
+	Params           map[string]string `json:"params"` // step parameters, including previous step outputs as {stepName}-{outputName}
+	RunnerArgs       []string          `json:"runner_args"`
+}
+
+func (r ExternalRunner) Init(exec config.StepExecution) error {
+	_, err := r.run(exec, "init", false)
+	return err
+}
+
+func (r ExternalRunner) Plan(exec config.StepExecution, destroy bool) error {
+	_, err := r.run(exec, "plan", destroy)
+	return err
+}
+
+func (r ExternalRunner) Apply(exec config.StepExecution) error {
+	_, err := r.run(exec, "apply", false)
+	return err
+}
+
+func (r ExternalRunner) Destroy(exec config.StepExecution) error {
+	_, err := r.run(exec, "destroy", true)
+	return err
+}
+
+func (r ExternalRunner) Outputs(exec config.StepExecution) (map[string]interface{}, error) {
+	out, err := r.run(exec, "outputs", false)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := map[string]interface{}{}
+
+	if strings.TrimSpace(out) == "" {
+		return outputs, nil
+	}
+
+	if err := json.Unmarshal([]byte(out), &outputs); err != nil {
+		return nil, fmt.Errorf("runner plugin %s wrote invalid outputs, expected a json object: %w", r.Binary, err)
+	}
+
+	return outputs, nil
+}
+
+// run executes the action, returning stdout for outputs and logging it otherwise. The action is traced under the
+// step's span, the plugin continues the trace from TRACEPARENT.
+func (r ExternalRunner) run(exec config.StepExecution, action string, destroy bool) (out string, err error) {
+	input, err := json.Marshal(newRunnerRequest(exec, action, destroy))
+	if err != nil {
+		return "", err
+	}
+
+	span := exec.Span.StartChild("runner "+action, "runiac.runner", r.Binary)
+	defer func() { span.Finish(err) }()
+
+	logger := exec.Logger.WithField("runner", action)
+	logger.Infof("Running command: %s %s", r.Binary, action)
+
+	var stdout bytes.Buffer
+
+	stderr := logger.WriterLevel(logrus.ErrorLevel)
+	defer stderr.Close()
+
+	// the step's context kills the runner plugin when the step times out
+	ctx := exec.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	cmd := execCommand(ctx, r.Binary, action)
+	cmd.Dir = exec.Dir
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stderr = stderr
+
+	if traceparent := span.Traceparent(); traceparent != "" {
+		cmd.Env = append(os.Environ(), "TRACEPARENT="+traceparent)
+	}
+
+	if action == "outputs" {
+		cmd.Stdout = &stdout
+	} else {
+		info := logger.WriterLevel(logrus.InfoLevel)
+		defer info.Close()
+
+		cmd.Stdout = info
+	}
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("runner plugin %s %s failed: %w", r.Binary, action, err)
+	}
+
+	return stdout.String(), nil
+}
+
+func newRunnerRequest(exec config.StepExecution, action string, destroy bool) runnerRequest {
+	params := exec.OptionalStepParams
+	if params == nil {
+		params = map[string]string{}
+	}
+
+	runnerArgs := exec.RunnerArgs
+	if runnerArgs == nil {
+		runnerArgs = []string{}
+	}
+
+	return runnerRequest{
+		Action:           action,
+		Dir:              exec.Dir,
+		Project:          exec.Project,
+		Track:            exec.TrackName,
+		Step:             exec.StepName,
+		DeploymentRing:   exec.DeploymentRing,
+		Environment:      exec.Environment,
+		Namespace:        exec.Namespace,
+		AppVersion:       exec.AppVersion,
+		AccountID:        exec.AccountID,
+		Region:           exec.Region,
+		RegionDeployType: exec.RegionDeployType.String(),
+		PrimaryRegion:    exec.PrimaryRegion,
+		DryRun:           exec.DryRun,
+		Destroy:          destroy,
+		Params:           params,
+		RunnerArgs:       runnerArgs,
+	}
+}
@@ -0,0 +1,91 @@
+package plugins_external
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/pkg/tracing"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// stubPlugin records the request of each action and writes its outputs
+const stubPlugin = `#!/bin/sh
+cat > "request_$1.json"
+echo "$TRACEPARENT" > "traceparent_$1"
+if [ "$1" = "outputs" ]; then
+  echo '{"bucket_name": "stub-bucket"}'
+elif [ "$1" = "apply" ]; then
+  echo "applied"
+  exit 3
+fi
+`
+
+func TestExternalRunner_ShouldExecuteLifecycleActionsWithRequest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "runiac-runner")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	binary := filepath.Join(dir, "runiac-runner-stub")
+	require.NoError(t, ioutil.WriteFile(binary, []byte(stubPlugin), 0755))
+
+	runner := ExternalRunner{Binary: binary}
+	exec := config.StepExecution{
+		Dir:                dir,
+		StepName:           "network",
+		Region:             "us-east-1",
+		Logger:             logrus.NewEntry(logrus.New()),
+		OptionalStepParams: map[string]string{"step1-vpc_id": "vpc-stub"},
+	}
+
+	require.NoError(t, runner.Init(exec))
+	require.NoError(t, runner.Plan(exec, true))
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "request_plan.json"))
+	require.NoError(t, err)
+
+	request := runnerRequest{}
+	require.NoError(t, json.Unmarshal(b, &request))
+	require.Equal(t, "plan", request.Action)
+	require.Equal(t, "network", request.Step)
+	require.Equal(t, "us-east-1", request.Region)
+	require.True(t, request.Destroy)
+	require.Equal(t, "vpc-stub", request.Params["step1-vpc_id"])
+
+	outputs, err := runner.Outputs(exec)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"bucket_name": "stub-bucket"}, outputs)
+
+	err = runner.Apply(exec)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "runiac-runner-stub apply failed")
+}
+
+func TestExternalRunner_ShouldPropagateTheTraceOfTheStep(t *testing.T) {
+	dir, err := ioutil.TempDir("", "runiac-runner")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	binary := filepath.Join(dir, "runiac-runner-stub")
+	require.NoError(t, ioutil.WriteFile(binary, []byte(stubPlugin), 0755))
+
+	step := tracing.NewTracerWithExporter(tracetest.NewInMemoryExporter()).StartRoot("step app/network", "")
+
+	runner := ExternalRunner{Binary: binary}
+	exec := config.StepExecution{
+		Dir:    dir,
+		Logger: logrus.NewEntry(logrus.New()),
+		Span:   step,
+	}
+
+	require.NoError(t, runner.Init(exec))
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "traceparent_init"))
+	require.NoError(t, err)
+	require.Regexp(t, "^00-"+step.TraceID+"-[0-9a-f]{16}-01\n$", string(b), "the plugin should continue the step's trace")
+}
@@ -0,0 +1,40 @@
+package pulumi
+
+import (
+	"github.com/optum/runiac/pkg/shell"
+)
+
+// RunPulumiCommandAndGetStdOut runs pulumi and returns solely its stdout, for pulumi's json output
+func RunPulumiCommandAndGetStdOut(options *Options, additionalArgs ...string) (string, error) {
+	return shell.RunShellCommandAndGetStdOut(shell.Command{
+		Command:           options.PulumiBinary,
+		Args:              append(additionalArgs, "--non-interactive"),
+		WorkingDir:        options.PulumiDir,
+		Env:               options.EnvVars,
+		OutputMaxLineSize: options.OutputMaxLineSize,
+		NonInteractive:    true,
+		SensitiveArgs:     false,
+		Logger:            options.Logger,
+		Context:           options.Context,
+	})
+}
+
+func RunPulumiCommand(streamOutput bool, options *Options, additionalArgs ...string) (string, error) {
+	cmd := shell.Command{
+		Command:           options.PulumiBinary,
+		Args:              append(additionalArgs, "--non-interactive"),
+		WorkingDir:        options.PulumiDir,
+		Env:               options.EnvVars,
+		OutputMaxLineSize: options.OutputMaxLineSize,
+		NonInteractive:    true,
+		SensitiveArgs:     false,
+		Logger:            options.Logger,
+		Context:           options.Context,
+	}
+
+	if streamOutput {
+		return shell.RunShellCommandAndGetAndStreamOutput(cmd)
+	}
+
+	return shell.RunShellCommandAndGetOutput(cmd)
+}
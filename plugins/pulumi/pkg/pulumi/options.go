@@ -0,0 +1,18 @@
+package pulumi
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+type Options struct {
+	PulumiBinary      string
+	PulumiDir         string
+	Stack             string
+	EnvVars           map[string]string
+	AdditionalArgs    []string // Appended to preview, up and destroy
+	OutputMaxLineSize int
+	Logger            *logrus.Entry
+	Context           context.Context // If set, kills the running command when the context is done, e.g. when the step times out
+}
@@ -0,0 +1,36 @@
+package pulumi
+
+type Pulumier interface {
+	Destroy(options *Options) (out string, err error)
+	Preview(options *Options) (out string, err error)
+	StackSelect(options *Options) (out string, err error)
+	StackOutput(options *Options) (outputs map[string]interface{}, err error)
+	Up(options *Options) (out string, err error)
+	Version(options *Options) (out string, err error)
+}
+
+type PulumiCLI struct{}
+
+func (p PulumiCLI) Destroy(options *Options) (out string, err error) {
+	return Destroy(options)
+}
+
+func (p PulumiCLI) Preview(options *Options) (out string, err error) {
+	return Preview(options)
+}
+
+func (p PulumiCLI) StackSelect(options *Options) (out string, err error) {
+	return StackSelect(options)
+}
+
+func (p PulumiCLI) StackOutput(options *Options) (outputs map[string]interface{}, err error) {
+	return StackOutput(options)
+}
+
+func (p PulumiCLI) Up(options *Options) (out string, err error) {
+	return Up(options)
+}
+
+func (p PulumiCLI) Version(options *Options) (out string, err error) {
+	return Version(options)
+}
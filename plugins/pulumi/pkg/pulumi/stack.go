@@ -0,0 +1,39 @@
+package pulumi
+
+import "encoding/json"
+
+// StackSelect selects the options' stack, creating it when it does not exist
+func StackSelect(options *Options) (out string, err error) {
+	args := []string{
+		"stack",
+		"select",
+		options.Stack,
+		"--create",
+	}
+
+	return RunPulumiCommand(false, options, args...)
+}
+
+// StackOutput returns the outputs of the options' stack, including secrets so downstream steps may consume them
+func StackOutput(options *Options) (map[string]interface{}, error) {
+	args := []string{
+		"stack",
+		"output",
+		"--json",
+		"--show-secrets",
+		"--stack",
+		options.Stack,
+	}
+
+	out, err := RunPulumiCommandAndGetStdOut(options, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(out), &outputs); err != nil {
+		return nil, err
+	}
+
+	return outputs, nil
+}
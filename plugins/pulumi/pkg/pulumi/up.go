@@ -0,0 +1,33 @@
+package pulumi
+
+func Preview(options *Options) (out string, err error) {
+	args := []string{
+		"preview",
+		"--stack",
+		options.Stack,
+	}
+
+	return RunPulumiCommand(true, options, append(args, options.AdditionalArgs...)...)
+}
+
+func Up(options *Options) (out string, err error) {
+	args := []string{
+		"up",
+		"--yes",
+		"--stack",
+		options.Stack,
+	}
+
+	return RunPulumiCommand(true, options, append(args, options.AdditionalArgs...)...)
+}
+
+func Destroy(options *Options) (out string, err error) {
+	args := []string{
+		"destroy",
+		"--yes",
+		"--stack",
+		options.Stack,
+	}
+
+	return RunPulumiCommand(true, options, append(args, options.AdditionalArgs...)...)
+}
@@ -0,0 +1,9 @@
+package pulumi
+
+func Version(options *Options) (out string, err error) {
+	args := []string{
+		"version",
+	}
+
+	return RunPulumiCommand(false, options, args...)
+}
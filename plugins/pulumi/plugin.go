@@ -0,0 +1,34 @@
+package plugins_pulumi
+
+import (
+	"os"
+
+	"github.com/optum/runiac/plugins/pulumi/pkg/pulumi"
+	"github.com/sirupsen/logrus"
+)
+
+type PulumiPlugin struct{}
+
+func (info PulumiPlugin) Initialize(logger *logrus.Entry) {
+	logger.Info("Initializing runiac Pulumi plugin")
+	logger.Warn("The Pulumi runner is currently experimental and is subject to change in future runiac releases")
+
+	if os.Getenv("PULUMI_ACCESS_TOKEN") == "" && os.Getenv("PULUMI_BACKEND_URL") == "" {
+		logger.Warn("Neither PULUMI_ACCESS_TOKEN nor PULUMI_BACKEND_URL is set, pulumi will not be able to log in to a state backend")
+	}
+
+	// display pulumi binary information
+	options := &pulumi.Options{
+		PulumiBinary: "pulumi",
+		PulumiDir:    ".",
+		EnvVars:      map[string]string{},
+		Logger:       logger.WithField("PulumiPlugin", "info"),
+	}
+
+	out, err := pulumiCLI.Version(options)
+	if err != nil {
+		logger.Warn("Unable to print pulumi CLI version")
+	} else {
+		logger.Info("Binary: ", out)
+	}
+}
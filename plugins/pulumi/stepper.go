@@ -0,0 +1,139 @@
+package plugins_pulumi
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/plugins/pulumi/pkg/pulumi"
+)
+
+type PulumiStepper struct{}
+
+var pulumiCLI pulumi.Pulumier = pulumi.PulumiCLI{}
+
+func (stepper PulumiStepper) PreExecute(exec config.StepExecution) (config.StepExecution, error) {
+	return exec, nil
+}
+
+// ExecuteStepDestroy destroys a step
+func (stepper PulumiStepper) ExecuteStepDestroy(exec config.StepExecution) (output config.StepOutput) {
+	output.RegionDeployType = exec.RegionDeployType
+	output.Region = exec.Region
+	output.StepName = exec.StepName
+	output.Status = config.Fail
+
+	options := getCommonOptions(exec)
+
+	_, output.Err = pulumiCLI.StackSelect(options)
+	if output.Err != nil {
+		options.Logger.WithError(output.Err).Error("Failed to select stack")
+		return
+	}
+
+	_, output.Err = pulumiCLI.Destroy(options)
+	if output.Err != nil {
+		options.Logger.WithError(output.Err).Error("Failed to destroy stack")
+		return
+	}
+
+	output.Status = config.Success
+	return
+}
+
+// ExecuteStep deploys a step
+func (stepper PulumiStepper) ExecuteStep(exec config.StepExecution) (output config.StepOutput) {
+	output.RegionDeployType = exec.RegionDeployType
+	output.Region = exec.Region
+	output.StepName = exec.StepName
+	output.Status = config.Fail
+
+	options := getCommonOptions(exec)
+
+	_, output.Err = pulumiCLI.StackSelect(options)
+	if output.Err != nil {
+		options.Logger.WithError(output.Err).Error("Failed to select stack")
+		return
+	}
+
+	if exec.DryRun {
+		_, output.Err = pulumiCLI.Preview(options)
+		if output.Err != nil {
+			options.Logger.WithError(output.Err).Error("Failed to preview stack")
+			return
+		}
+
+		options.Logger.Info("---------- Skipping up, this is a dry run ---------- ")
+	} else {
+		_, output.Err = pulumiCLI.Up(options)
+		if output.Err != nil {
+			options.Logger.WithError(output.Err).Error("Failed to update stack")
+			return
+		}
+	}
+
+	// stack outputs are exposed to downstream steps like terraform outputs
+	output.OutputVariables, output.Err = pulumiCLI.StackOutput(options)
+	if output.Err != nil {
+		options.Logger.WithError(output.Err).Error("Failed to read stack outputs")
+		return
+	}
+
+	output.Status = config.Success
+	return
+}
+
+// ExecuteStepTests executes the tests for a step
+func (stepper PulumiStepper) ExecuteStepTests(exec config.StepExecution) (output config.StepTestOutput) {
+	// TODO
+	return
+}
+
+func getCommonOptions(exec config.StepExecution) *pulumi.Options {
+	return &pulumi.Options{
+		PulumiBinary:   "pulumi",
+		PulumiDir:      exec.Dir,
+		Stack:          getStackName(exec),
+		EnvVars:        GetPulumiEnvVars(exec),
+		AdditionalArgs: exec.RunnerArgs,
+		Logger:         exec.Logger,
+		Context:        exec.Context,
+	}
+}
+
+// GetPulumiEnvVars returns the step parameters, including the outputs of previous steps as {stepName}-{outputName},
+// and the runiac variables as RUNIAC_VAR_{name} env variables for the pulumi program with dashes replaced by
+// underscores, e.g. RUNIAC_VAR_step1_bucket_name or RUNIAC_VAR_runiac_region
+func GetPulumiEnvVars(exec config.StepExecution) map[string]string {
+	vars := map[string]string{
+		"runiac_account_id":  exec.AccountID,
+		"runiac_region":      exec.Region,
+		"runiac_app_version": exec.AppVersion,
+		"runiac_namespace":   exec.Namespace,
+		"runiac_environment": exec.Environment,
+	}
+
+	for k, v := range exec.OptionalStepParams {
+		vars[k] = v
+	}
+
+	env := map[string]string{}
+	for k, v := range vars {
+		env[fmt.Sprintf("RUNIAC_VAR_%s", strings.ReplaceAll(k, "-", "_"))] = v
+	}
+
+	return env
+}
+
+// getStackName returns the pulumi stack for the execution, isolated by namespace, environment and region
+func getStackName(exec config.StepExecution) string {
+	parts := []string{}
+
+	for _, part := range []string{exec.Namespace, exec.Environment, exec.Region} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+
+	return strings.ToLower(fmt.Sprintf("runiac-%s", strings.Join(parts, "-")))
+}
@@ -0,0 +1,63 @@
+package plugins_pulumi
+
+import (
+	"testing"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/plugins/pulumi/pkg/pulumi"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetStackName_ShouldIsolateByNamespaceEnvironmentAndRegion(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "runiac-pr-12-nonprod-centralus", getStackName(config.StepExecution{Namespace: "PR-12", Environment: "nonprod", Region: "centralus"}))
+	require.Equal(t, "runiac-prod-eastus2", getStackName(config.StepExecution{Environment: "prod", Region: "eastus2"}))
+}
+
+type stubPulumier struct {
+	pulumi.PulumiCLI
+	options []*pulumi.Options
+	outputs map[string]interface{}
+}
+
+func (s *stubPulumier) StackSelect(options *pulumi.Options) (string, error) {
+	s.options = append(s.options, options)
+	return "", nil
+}
+
+func (s *stubPulumier) Preview(options *pulumi.Options) (string, error) {
+	return "", nil
+}
+
+func (s *stubPulumier) Up(options *pulumi.Options) (string, error) {
+	return "", nil
+}
+
+func (s *stubPulumier) StackOutput(options *pulumi.Options) (map[string]interface{}, error) {
+	return s.outputs, nil
+}
+
+func TestExecuteStep_ShouldExposeStackOutputsAndStepParams(t *testing.T) {
+	stub := &stubPulumier{outputs: map[string]interface{}{"bucket_name": "stub-bucket"}}
+	pulumiCLI = stub
+	defer func() { pulumiCLI = pulumi.PulumiCLI{} }()
+
+	output := PulumiStepper{}.ExecuteStep(config.StepExecution{
+		StepName:           "stub",
+		Environment:        "dev",
+		Region:             "centralus",
+		Logger:             logrus.NewEntry(logrus.New()),
+		OptionalStepParams: map[string]string{"step1-bucket_name": "upstream-bucket"},
+	})
+
+	require.NoError(t, output.Err)
+	require.Equal(t, config.Success, output.Status)
+	require.Equal(t, "stub-bucket", output.OutputVariables["bucket_name"])
+
+	require.Len(t, stub.options, 1)
+	require.Equal(t, "runiac-dev-centralus", stub.options[0].Stack)
+	require.Equal(t, "upstream-bucket", stub.options[0].EnvVars["RUNIAC_VAR_step1_bucket_name"])
+	require.Equal(t, "centralus", stub.options[0].EnvVars["RUNIAC_VAR_runiac_region"])
+}
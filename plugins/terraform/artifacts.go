@@ -0,0 +1,157 @@
+package plugins_terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/spf13/afero"
+)
+
+const (
+	artifactMetadataFile = "artifact.json" // Describes the step execution the artifacts were produced for
+	artifactPlanFile     = "tfplan"        // The binary terraform plan
+	artifactPlanJSONFile = "tfplan.json"   // The terraform show -json representation of the plan
+	artifactPlanTextFile = "tfplan.txt"    // The human-readable terraform show representation of the plan, for review
+)
+
+// StepArtifact describes the step execution a set of saved artifacts was produced for
+type StepArtifact struct {
+	StepID           string    `json:"step_id"`
+	Track            string    `json:"track"`
+	Step             string    `json:"step"`
+	RegionDeployType string    `json:"region_deploy_type"`
+	Region           string    `json:"region"`
+	DeploymentRing   string    `json:"deployment_ring"`
+	Environment      string    `json:"environment"`
+	Namespace        string    `json:"namespace"`
+	Version          string    `json:"version"`
+	Destroy          bool      `json:"destroy"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// getStepArtifactDir returns the directory for a step execution's artifacts beneath root
+func getStepArtifactDir(root string, exec config.StepExecution) string {
+	return filepath.Join(root, exec.TrackName, exec.StepName, fmt.Sprintf("%s-%s", exec.RegionDeployType, exec.Region))
+}
+
+func newStepArtifact(exec config.StepExecution, destroy bool) StepArtifact {
+	return StepArtifact{
+		StepID:           exec.StepID,
+		Track:            exec.TrackName,
+		Step:             exec.StepName,
+		RegionDeployType: exec.RegionDeployType.String(),
+		Region:           exec.Region,
+		DeploymentRing:   exec.DeploymentRing,
+		Environment:      exec.Environment,
+		Namespace:        exec.Namespace,
+		Version:          exec.AppVersion,
+		Destroy:          destroy,
+		CreatedAt:        time.Now().UTC(),
+	}
+}
+
+// saveStepArtifacts copies the plan and its json and human-readable representations to the output directory along
+// with metadata describing the execution so a later stage can verify it is applying a compatible plan. The
+// human-readable plan is skipped when planText is empty.
+func saveStepArtifacts(exec config.StepExecution, tfplan string, planJSON string, planText string, destroy bool) error {
+	dir := getStepArtifactDir(exec.OutputDir, exec)
+
+	err := exec.Fs.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	b, err := afero.ReadFile(exec.Fs, filepath.Join(exec.Dir, tfplan))
+	if err != nil {
+		return err
+	}
+
+	err = afero.WriteFile(exec.Fs, filepath.Join(dir, artifactPlanFile), b, 0644)
+	if err != nil {
+		return err
+	}
+
+	err = afero.WriteFile(exec.Fs, filepath.Join(dir, artifactPlanJSONFile), []byte(planJSON), 0644)
+	if err != nil {
+		return err
+	}
+
+	if planText != "" {
+		err = afero.WriteFile(exec.Fs, filepath.Join(dir, artifactPlanTextFile), []byte(planText), 0644)
+		if err != nil {
+			return err
+		}
+	}
+
+	metadata, err := json.MarshalIndent(newStepArtifact(exec, destroy), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	exec.Logger.Infof("Saved plan artifacts to %s", dir)
+
+	return afero.WriteFile(exec.Fs, filepath.Join(dir, artifactMetadataFile), metadata, 0644)
+}
+
+// restoreStepArtifacts validates the artifacts of a previous run are compatible with this execution
+// and copies the saved plan into the execution directory as tfplan
+func restoreStepArtifacts(exec config.StepExecution, tfplan string, destroy bool) error {
+	dir := getStepArtifactDir(exec.ArtifactsFrom, exec)
+
+	b, err := afero.ReadFile(exec.Fs, filepath.Join(dir, artifactMetadataFile))
+	if err != nil {
+		return fmt.Errorf("no artifacts found for step %s in %s: %w", exec.StepID, dir, err)
+	}
+
+	saved := StepArtifact{}
+	err = json.Unmarshal(b, &saved)
+	if err != nil {
+		return err
+	}
+
+	err = checkStepArtifactCompatibility(saved, newStepArtifact(exec, destroy))
+	if err != nil {
+		return err
+	}
+
+	if saved.Version != exec.AppVersion {
+		exec.Logger.Warnf("Artifacts were produced for version %s, current version is %s", saved.Version, exec.AppVersion)
+	}
+
+	plan, err := afero.ReadFile(exec.Fs, filepath.Join(dir, artifactPlanFile))
+	if err != nil {
+		return err
+	}
+
+	exec.Logger.Infof("Using plan artifacts from %s", dir)
+
+	return afero.WriteFile(exec.Fs, filepath.Join(exec.Dir, tfplan), plan, 0644)
+}
+
+// checkStepArtifactCompatibility returns an error when the saved artifact was produced for a different execution
+func checkStepArtifactCompatibility(saved StepArtifact, current StepArtifact) error {
+	fields := []struct {
+		name    string
+		saved   string
+		current string
+	}{
+		{"step", saved.StepID, current.StepID},
+		{"region deploy type", saved.RegionDeployType, current.RegionDeployType},
+		{"region", saved.Region, current.Region},
+		{"deployment ring", saved.DeploymentRing, current.DeploymentRing},
+		{"environment", saved.Environment, current.Environment},
+		{"namespace", saved.Namespace, current.Namespace},
+		{"destroy", fmt.Sprintf("%v", saved.Destroy), fmt.Sprintf("%v", current.Destroy)},
+	}
+
+	for _, f := range fields {
+		if f.saved != f.current {
+			return fmt.Errorf("artifacts for step %s are incompatible, %s was '%s' but is '%s'", current.StepID, f.name, f.saved, f.current)
+		}
+	}
+
+	return nil
+}
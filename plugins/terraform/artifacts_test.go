@@ -0,0 +1,59 @@
+package plugins_terraform
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStepArtifacts_ShouldRestoreSavedPlanForCompatibleExecution(t *testing.T) {
+	t.Parallel()
+	fs := afero.NewMemMapFs()
+
+	exec := config.StepExecution{
+		Fs:               fs,
+		Logger:           logger,
+		Dir:              "step1_stub",
+		StepID:           "track/stub",
+		StepName:         "stub",
+		TrackName:        "track",
+		Region:           "centralus",
+		RegionDeployType: config.PrimaryRegionDeployType,
+		DeploymentRing:   "prod",
+		Environment:      "prod",
+		OutputDir:        "output",
+	}
+
+	_ = afero.WriteFile(fs, filepath.Join(exec.Dir, "tfplan"), []byte("stub plan"), 0644)
+
+	err := saveStepArtifacts(exec, "tfplan", `{"format_version":"0.1"}`, "No changes.", false)
+	require.NoError(t, err)
+
+	exists, _ := afero.Exists(fs, filepath.Join("output", "track", "stub", "primary-centralus", "tfplan.json"))
+	require.True(t, exists, "plan json should be saved to the output directory")
+
+	text, _ := afero.ReadFile(fs, filepath.Join("output", "track", "stub", "primary-centralus", "tfplan.txt"))
+	require.Equal(t, "No changes.", string(text), "the human-readable plan should be saved for review")
+
+	applyExec := exec
+	applyExec.OutputDir = ""
+	applyExec.ArtifactsFrom = "output"
+	applyExec.Dir = "apply"
+
+	err = restoreStepArtifacts(applyExec, "restored", false)
+	require.NoError(t, err)
+
+	b, _ := afero.ReadFile(fs, filepath.Join("apply", "restored"))
+	require.Equal(t, "stub plan", string(b))
+
+	// a different ring must not apply the saved plan
+	ringExec := applyExec
+	ringExec.DeploymentRing = "dev"
+	require.Error(t, restoreStepArtifacts(ringExec, "restored", false))
+
+	// a destroy must not apply a deploy plan
+	require.Error(t, restoreStepArtifacts(applyExec, "restored", true))
+}
@@ -0,0 +1,107 @@
+package plugins_terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/pkg/shell"
+	"github.com/optum/runiac/plugins/terraform/pkg/terraform"
+	"github.com/spf13/afero"
+)
+
+// costEstimatePlanFile is the terraform show -json plan infracost estimates, written to the step directory
+const costEstimatePlanFile = "tfplan.infracost.json"
+
+// runInfracostCommand runs the infracost cli, returning its stdout
+var runInfracostCommand = func(options *terraform.Options, args ...string) (string, error) {
+	return shell.RunCommandAndGetStdOut(shell.Command{
+		Command:        "infracost",
+		Args:           args,
+		WorkingDir:     options.TerraformDir,
+		Env:            options.EnvVars,
+		NonInteractive: true,
+		Logger:         options.Logger,
+		Context:        options.Context,
+	})
+}
+
+// infracostBreakdown is the subset of infracost breakdown --format json used to estimate a plan's cost, the costs
+// are decimal strings and null when infracost can not price the resources
+type infracostBreakdown struct {
+	Currency             string  `json:"currency"`
+	TotalMonthlyCost     *string `json:"totalMonthlyCost"`
+	PastTotalMonthlyCost *string `json:"pastTotalMonthlyCost"`
+	DiffTotalMonthlyCost *string `json:"diffTotalMonthlyCost"`
+}
+
+// estimateCost estimates the monthly cost of the step's resources before and after applying the plan with infracost.
+// Estimation is best effort, failing to estimate is logged and yields no estimate.
+func estimateCost(exec config.StepExecution, planJSON string, tfOptions *terraform.Options) *config.CostEstimate {
+	logger := tfOptions.Logger.WithField("infracost", "breakdown")
+	path := filepath.Join(exec.Dir, costEstimatePlanFile)
+
+	if err := afero.WriteFile(exec.Fs, path, []byte(planJSON), 0644); err != nil {
+		logger.WithError(err).Warn("Unable to write the plan for infracost, skipping cost estimation")
+		return nil
+	}
+
+	defer func() { _ = exec.Fs.Remove(path) }()
+
+	options := *tfOptions
+	options.Logger = logger
+
+	resp, err := runInfracostCommand(&options, "breakdown", "--path", path, "--format", "json")
+	if err != nil {
+		logger.WithError(err).Warn("Unable to estimate the cost of the plan, infracost and INFRACOST_API_KEY are required in the container")
+		return nil
+	}
+
+	estimate, err := getCostEstimate(resp)
+	if err != nil {
+		logger.WithError(err).Warn("Unable to parse the infracost breakdown")
+		return nil
+	}
+
+	logger.Infof("Estimated monthly cost %.2f %s, %+.2f %s", estimate.MonthlyCost, estimate.Currency, estimate.MonthlyCostDelta, estimate.Currency)
+
+	return estimate
+}
+
+// getCostEstimate parses the infracost breakdown --format json output of a plan into its cost estimate
+func getCostEstimate(breakdownJSON string) (*config.CostEstimate, error) {
+	breakdown := infracostBreakdown{}
+
+	if err := json.Unmarshal([]byte(breakdownJSON), &breakdown); err != nil {
+		return nil, err
+	}
+
+	estimate := &config.CostEstimate{Currency: breakdown.Currency}
+
+	costs := []struct {
+		name  string
+		value *string
+		cost  *float64
+	}{
+		{"totalMonthlyCost", breakdown.TotalMonthlyCost, &estimate.MonthlyCost},
+		{"pastTotalMonthlyCost", breakdown.PastTotalMonthlyCost, &estimate.PastMonthlyCost},
+		{"diffTotalMonthlyCost", breakdown.DiffTotalMonthlyCost, &estimate.MonthlyCostDelta},
+	}
+
+	for _, c := range costs {
+		if c.value == nil {
+			continue
+		}
+
+		v, err := strconv.ParseFloat(*c.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s '%s': %w", c.name, *c.value, err)
+		}
+
+		*c.cost = v
+	}
+
+	return estimate, nil
+}
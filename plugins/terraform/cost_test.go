@@ -0,0 +1,60 @@
+package plugins_terraform
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/plugins/terraform/pkg/terraform"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetCostEstimate_ShouldParseInfracostBreakdown(t *testing.T) {
+	t.Parallel()
+
+	estimate, err := getCostEstimate(`{"version": "0.2", "currency": "USD", "totalMonthlyCost": "142.5", "pastTotalMonthlyCost": "100", "diffTotalMonthlyCost": "42.5", "projects": []}`)
+	require.NoError(t, err)
+	require.Equal(t, &config.CostEstimate{Currency: "USD", PastMonthlyCost: 100, MonthlyCost: 142.5, MonthlyCostDelta: 42.5}, estimate)
+
+	estimate, err = getCostEstimate(`{"currency": "EUR", "totalMonthlyCost": null, "pastTotalMonthlyCost": null, "diffTotalMonthlyCost": null}`)
+	require.NoError(t, err)
+	require.Equal(t, &config.CostEstimate{Currency: "EUR"}, estimate, "resources infracost can not price cost nothing")
+
+	_, err = getCostEstimate(`{"currency": "USD", "totalMonthlyCost": "n/a"}`)
+	require.Error(t, err)
+}
+
+func TestEstimateCost_ShouldRunInfracostAgainstThePlan(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	exec := config.StepExecution{Fs: fs, Dir: "/steps/network", Logger: logger}
+
+	var path, planJSON string
+
+	defer func(f func(*terraform.Options, ...string) (string, error)) { runInfracostCommand = f }(runInfracostCommand)
+	runInfracostCommand = func(options *terraform.Options, args ...string) (string, error) {
+		path = args[2]
+		b, _ := afero.ReadFile(fs, path)
+		planJSON = string(b)
+
+		require.Equal(t, []string{"breakdown", "--path", path, "--format", "json"}, args)
+
+		return `{"currency": "USD", "totalMonthlyCost": "10", "pastTotalMonthlyCost": "15", "diffTotalMonthlyCost": "-5"}`, nil
+	}
+
+	estimate := estimateCost(exec, `{"resource_changes": []}`, &terraform.Options{Logger: logger})
+
+	require.Equal(t, filepath.Join("/steps/network", costEstimatePlanFile), path)
+	require.Equal(t, `{"resource_changes": []}`, planJSON)
+	require.Equal(t, -5.0, estimate.MonthlyCostDelta)
+
+	exists, _ := afero.Exists(fs, path)
+	require.False(t, exists, "the plan written for infracost is removed")
+
+	runInfracostCommand = func(options *terraform.Options, args ...string) (string, error) {
+		return "", errors.New("infracost: command not found")
+	}
+
+	require.Nil(t, estimateCost(exec, `{}`, &terraform.Options{Logger: logger}), "failing to estimate does not fail the step")
+}
@@ -0,0 +1,42 @@
+package plugins_terraform
+
+import (
+	"github.com/optum/runiac/pkg/config"
+)
+
+// getDriftedResources returns the resources of a refresh-only plan whose remote objects changed outside of terraform.
+// Data sources are read on every refresh and are not drift.
+func getDriftedResources(p plan) []config.DriftedResource {
+	drifted := []config.DriftedResource{}
+
+	for _, c := range p.ResourceDrift {
+		if c.Mode == "data" {
+			continue
+		}
+
+		action := "update"
+
+		for _, a := range c.Change.Actions {
+			switch a {
+			case "delete":
+				action = "delete"
+			case "no-op", "read":
+				if len(c.Change.Actions) == 1 {
+					action = ""
+				}
+			}
+		}
+
+		if action == "" {
+			continue
+		}
+
+		drifted = append(drifted, config.DriftedResource{
+			Address: c.Address,
+			Type:    c.Type,
+			Action:  action,
+		})
+	}
+
+	return drifted
+}
@@ -0,0 +1,30 @@
+package plugins_terraform
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+const stubRefreshOnlyPlan = `{"format_version": "0.2", "resource_drift": [
+	{"address": "aws_s3_bucket.logs", "mode": "managed", "type": "aws_s3_bucket", "name": "logs", "change": {"actions": ["update"]}},
+	{"address": "aws_iam_role.deployer", "mode": "managed", "type": "aws_iam_role", "name": "deployer", "change": {"actions": ["delete"]}},
+	{"address": "aws_sqs_queue.jobs", "mode": "managed", "type": "aws_sqs_queue", "name": "jobs", "change": {"actions": ["no-op"]}},
+	{"address": "data.aws_caller_identity.current", "mode": "data", "type": "aws_caller_identity", "name": "current", "change": {"actions": ["read"]}}
+]}`
+
+func TestGetDriftedResources_ShouldOnlyIncludeChangedManagedResources(t *testing.T) {
+	t.Parallel()
+
+	p := plan{}
+	require.NoError(t, json.Unmarshal([]byte(stubRefreshOnlyPlan), &p))
+
+	require.Equal(t, []config.DriftedResource{
+		{Address: "aws_s3_bucket.logs", Type: "aws_s3_bucket", Action: "update"},
+		{Address: "aws_iam_role.deployer", Type: "aws_iam_role", Action: "delete"},
+	}, getDriftedResources(p))
+
+	require.Empty(t, getDriftedResources(plan{}), "a plan without drift has no drifted resources")
+}
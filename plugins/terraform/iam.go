@@ -0,0 +1,200 @@
+package plugins_terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/pkg/shell"
+	"github.com/optum/runiac/plugins/terraform/pkg/terraform"
+	"github.com/sirupsen/logrus"
+)
+
+const iamSimulationRuleID = "aws/iam-simulation"
+
+// iamResourceActions are the IAM actions of aws resources whose name does not follow aws_{service}_{resource}, or
+// whose actions are not named {Verb}{Resource}, indexed by resource type and then plan action
+var iamResourceActions = map[string]map[string]string{
+	"aws_instance":         {"create": "ec2:RunInstances", "update": "ec2:ModifyInstanceAttribute", "delete": "ec2:TerminateInstances"},
+	"aws_vpc":              {"create": "ec2:CreateVpc", "update": "ec2:ModifyVpcAttribute", "delete": "ec2:DeleteVpc"},
+	"aws_subnet":           {"create": "ec2:CreateSubnet", "update": "ec2:ModifySubnetAttribute", "delete": "ec2:DeleteSubnet"},
+	"aws_security_group":   {"create": "ec2:CreateSecurityGroup", "update": "ec2:AuthorizeSecurityGroupIngress", "delete": "ec2:DeleteSecurityGroup"},
+	"aws_route_table":      {"create": "ec2:CreateRouteTable", "update": "ec2:CreateRoute", "delete": "ec2:DeleteRouteTable"},
+	"aws_internet_gateway": {"create": "ec2:CreateInternetGateway", "update": "ec2:AttachInternetGateway", "delete": "ec2:DeleteInternetGateway"},
+	"aws_nat_gateway":      {"create": "ec2:CreateNatGateway", "delete": "ec2:DeleteNatGateway"},
+	"aws_eip":              {"create": "ec2:AllocateAddress", "delete": "ec2:ReleaseAddress"},
+	"aws_s3_bucket":        {"create": "s3:CreateBucket", "update": "s3:PutBucketTagging", "delete": "s3:DeleteBucket"},
+	"aws_s3_bucket_policy": {"create": "s3:PutBucketPolicy", "update": "s3:PutBucketPolicy", "delete": "s3:DeleteBucketPolicy"},
+	"aws_lambda_function":  {"create": "lambda:CreateFunction", "update": "lambda:UpdateFunctionConfiguration", "delete": "lambda:DeleteFunction"},
+	"aws_sqs_queue":        {"create": "sqs:CreateQueue", "update": "sqs:SetQueueAttributes", "delete": "sqs:DeleteQueue"},
+	"aws_sns_topic":        {"create": "sns:CreateTopic", "update": "sns:SetTopicAttributes", "delete": "sns:DeleteTopic"},
+	"aws_kms_key":          {"create": "kms:CreateKey", "update": "kms:UpdateKeyDescription", "delete": "kms:ScheduleKeyDeletion"},
+	"aws_cloudwatch_log_group": {
+		"create": "logs:CreateLogGroup", "update": "logs:PutRetentionPolicy", "delete": "logs:DeleteLogGroup",
+	},
+}
+
+// iamVerbs are the IAM action verbs of plan actions
+var iamVerbs = map[string]string{
+	"create": "Create",
+	"update": "Update",
+	"delete": "Delete",
+}
+
+// runAWSCommand runs the aws cli, returning its stdout
+var runAWSCommand = func(options *terraform.Options, args ...string) (string, error) {
+	return shell.RunCommandAndGetStdOut(shell.Command{
+		Command:        "aws",
+		Args:           append(args, "--output", "json"),
+		WorkingDir:     options.TerraformDir,
+		Env:            options.EnvVars,
+		NonInteractive: true,
+		Logger:         options.Logger,
+		Context:        options.Context,
+	})
+}
+
+// getIAMAction heuristically returns the IAM action required to perform the plan action on an aws resource type,
+// e.g. aws_dynamodb_table create is dynamodb:CreateTable
+func getIAMAction(resourceType string, action string) string {
+	if actions, ok := iamResourceActions[resourceType]; ok {
+		return actions[action]
+	}
+
+	verb, ok := iamVerbs[action]
+	if !ok {
+		return ""
+	}
+
+	parts := strings.Split(strings.TrimPrefix(resourceType, "aws_"), "_")
+	if !strings.HasPrefix(resourceType, "aws_") || len(parts) < 2 {
+		return ""
+	}
+
+	resource := ""
+	for _, part := range parts[1:] {
+		resource += strings.Title(part)
+	}
+
+	return fmt.Sprintf("%s:%s%s", parts[0], verb, resource)
+}
+
+// getPlanIAMActions returns the IAM actions the plan's changes to aws resources likely require, along with the
+// addresses of the resources requiring each action
+func getPlanIAMActions(p plan) map[string][]string {
+	actions := map[string][]string{}
+
+	for _, c := range p.ResourceChanges {
+		if c.Mode == "data" || !strings.HasPrefix(c.Type, "aws_") {
+			continue
+		}
+
+		for _, a := range c.Change.Actions {
+			if iamAction := getIAMAction(c.Type, a); iamAction != "" {
+				actions[iamAction] = append(actions[iamAction], c.Address)
+			}
+		}
+	}
+
+	return actions
+}
+
+// getPolicySourceArn returns the IAM principal arn of the caller, mapping an assumed role session to its role
+func getPolicySourceArn(callerArn string) string {
+	// arn:aws:sts::123456789012:assumed-role/{role}/{session}
+	parts := strings.SplitN(callerArn, ":", 6)
+	if len(parts) != 6 || parts[2] != "sts" || !strings.HasPrefix(parts[5], "assumed-role/") {
+		return callerArn
+	}
+
+	role := strings.Split(strings.TrimPrefix(parts[5], "assumed-role/"), "/")[0]
+
+	return fmt.Sprintf("arn:%s:iam::%s:role/%s", parts[1], parts[4], role)
+}
+
+type callerIdentity struct {
+	Arn string `json:"Arn"`
+}
+
+type policySimulation struct {
+	EvaluationResults []struct {
+		EvalActionName string `json:"EvalActionName"`
+		EvalDecision   string `json:"EvalDecision"`
+	} `json:"EvaluationResults"`
+}
+
+// simulateIAM simulates the IAM actions the plan requires against the caller's policies and returns a finding for
+// each action that is not allowed. Simulation is best effort, failing to simulate is logged and yields no findings.
+func simulateIAM(p plan, tfOptions *terraform.Options) []config.Finding {
+	logger := tfOptions.Logger.WithField("iam", "simulate")
+	actions := getPlanIAMActions(p)
+
+	if len(actions) == 0 {
+		logger.Info("Plan requires no aws IAM actions to simulate")
+		return nil
+	}
+
+	options := *tfOptions
+	options.Logger = logger
+
+	resp, err := runAWSCommand(&options, "sts", "get-caller-identity")
+	if err != nil {
+		logger.WithError(err).Warn("Unable to determine the aws caller identity, skipping IAM simulation")
+		return nil
+	}
+
+	caller := callerIdentity{}
+	if err := json.Unmarshal([]byte(resp), &caller); err != nil {
+		logger.WithError(err).Warn("Unable to parse the aws caller identity, skipping IAM simulation")
+		return nil
+	}
+
+	names := []string{}
+	for name := range actions {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	args := append([]string{"iam", "simulate-principal-policy", "--policy-source-arn", getPolicySourceArn(caller.Arn), "--action-names"}, names...)
+
+	resp, err = runAWSCommand(&options, args...)
+	if err != nil {
+		logger.WithError(err).Warn("Unable to simulate IAM permissions, the caller may lack iam:SimulatePrincipalPolicy")
+		return nil
+	}
+
+	simulation := policySimulation{}
+	if err := json.Unmarshal([]byte(resp), &simulation); err != nil {
+		logger.WithError(err).Warn("Unable to parse the IAM simulation results")
+		return nil
+	}
+
+	return getIAMSimulationFindings(simulation, actions, logger)
+}
+
+// getIAMSimulationFindings returns a finding for each simulated action that was not allowed
+func getIAMSimulationFindings(simulation policySimulation, actions map[string][]string, logger *logrus.Entry) []config.Finding {
+	findings := []config.Finding{}
+
+	for _, r := range simulation.EvaluationResults {
+		if r.EvalDecision == "allowed" {
+			continue
+		}
+
+		msg := fmt.Sprintf("%s is %s for the deploying credentials, required by %s", r.EvalActionName, r.EvalDecision, strings.Join(actions[r.EvalActionName], ", "))
+		logger.Warn(msg)
+
+		findings = append(findings, config.Finding{
+			RuleID:  iamSimulationRuleID,
+			Level:   config.FindingWarning,
+			Message: msg,
+		})
+	}
+
+	logger.Infof("Simulated %d IAM action(s), %d may be missing", len(simulation.EvaluationResults), len(findings))
+
+	return findings
+}
@@ -0,0 +1,75 @@
+package plugins_terraform
+
+import (
+	"testing"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/plugins/terraform/pkg/terraform"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetIAMAction_ShouldMapResourceTypesToActions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		resourceType string
+		action       string
+		expected     string
+	}{
+		{"aws_dynamodb_table", "create", "dynamodb:CreateTable"},
+		{"aws_iam_role_policy", "delete", "iam:DeleteRolePolicy"},
+		{"aws_instance", "create", "ec2:RunInstances"},
+		{"aws_s3_bucket", "update", "s3:PutBucketTagging"},
+		{"aws_dynamodb_table", "no-op", ""},
+		{"azurerm_resource_group", "create", ""},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, getIAMAction(tt.resourceType, tt.action), "getIAMAction(\"%s\", \"%s\")", tt.resourceType, tt.action)
+	}
+}
+
+func TestGetPolicySourceArn_ShouldMapAssumedRolesToTheirRole(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "arn:aws:iam::123456789012:role/deployer", getPolicySourceArn("arn:aws:sts::123456789012:assumed-role/deployer/runiac"))
+	require.Equal(t, "arn:aws:iam::123456789012:user/ci", getPolicySourceArn("arn:aws:iam::123456789012:user/ci"))
+}
+
+func TestSimulateIAM_ShouldReportActionsThatAreNotAllowed(t *testing.T) {
+	p := plan{
+		ResourceChanges: []resourceChange{
+			{Address: "aws_sqs_queue.events", Type: "aws_sqs_queue", Change: change{Actions: []string{"delete", "create"}}},
+			{Address: "aws_dynamodb_table.locks", Type: "aws_dynamodb_table", Change: change{Actions: []string{"create"}}},
+			{Address: "data.aws_caller_identity.current", Mode: "data", Type: "aws_caller_identity", Change: change{Actions: []string{"read"}}},
+		},
+	}
+
+	commands := [][]string{}
+
+	defer func(f func(*terraform.Options, ...string) (string, error)) { runAWSCommand = f }(runAWSCommand)
+	runAWSCommand = func(options *terraform.Options, args ...string) (string, error) {
+		commands = append(commands, args)
+
+		if args[0] == "sts" {
+			return `{"Arn": "arn:aws:sts::123456789012:assumed-role/deployer/runiac"}`, nil
+		}
+
+		return `{"EvaluationResults": [
+			{"EvalActionName": "dynamodb:CreateTable", "EvalDecision": "allowed"},
+			{"EvalActionName": "sqs:CreateQueue", "EvalDecision": "allowed"},
+			{"EvalActionName": "sqs:DeleteQueue", "EvalDecision": "implicitDeny"}
+		]}`, nil
+	}
+
+	findings := simulateIAM(p, &terraform.Options{Logger: logger})
+
+	require.Len(t, commands, 2)
+	require.Equal(t, []string{"iam", "simulate-principal-policy", "--policy-source-arn", "arn:aws:iam::123456789012:role/deployer", "--action-names", "dynamodb:CreateTable", "sqs:CreateQueue", "sqs:DeleteQueue"}, commands[1])
+
+	require.Len(t, findings, 1)
+	require.Equal(t, iamSimulationRuleID, findings[0].RuleID)
+	require.Equal(t, config.FindingWarning, findings[0].Level)
+	require.Contains(t, findings[0].Message, "sqs:DeleteQueue")
+	require.Contains(t, findings[0].Message, "aws_sqs_queue.events")
+}
@@ -42,6 +42,7 @@ func RunTerraformCommand(streamOutput bool, additionalOptions *Options, addition
 		NonInteractive:    true,
 		SensitiveArgs:     false,
 		Logger:            options.Logger,
+		Context:           options.Context,
 	}
 
 	options.Logger.Debugf("Executing Command with following Env Vars set: %s", KeysStringString(cmd.Env))
@@ -74,6 +75,7 @@ func GetExitCodeForTerraformCommand(additionalOptions *Options, additionalArgs .
 		Logger:            options.Logger,
 		NonInteractive:    true,
 		SensitiveArgs:     true,
+		Context:           options.Context,
 	}
 
 	_, err := shell.RunShellCommandAndGetOutput(cmd)
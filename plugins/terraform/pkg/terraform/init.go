@@ -27,3 +27,21 @@ func Init(options *Options) (out string, err error) {
 	return
 
 }
+
+// InitProviders calls terraform init without configuring the backend to only download the providers and modules
+// required and return stdout/stderr.
+func InitProviders(options *Options) (out string, err error) {
+	args := []string{"init", "-backend=false", "-input=false"}
+
+	retryErr := retry.DoWithRetry("terraform init providers", 3, 10*time.Second, options.Logger, func(attempt int) error {
+		out, err = RunTerraformCommand(true, options, args...)
+
+		return err
+	})
+
+	if retryErr != nil {
+		options.Logger.WithError(retryErr).Error("Error attempting retryable action")
+	}
+
+	return
+}
@@ -3,6 +3,7 @@ package terraform
 // This code follows: https://github.com/gruntwork-io/terratest/blob/master/modules/terraform/options.go
 
 import (
+	"context"
 	"github.com/sirupsen/logrus"
 	"time"
 )
@@ -25,4 +26,6 @@ type Options struct {
 	OutputMaxLineSize        int                    // The max size of one line in stdout and stderr (in bytes)
 	Logger                   *logrus.Entry
 	PluginCacheDir           string
+	Context                  context.Context // If set, kills the running command when the context is done, e.g. when the step times out
+	RefreshOnly              bool            // Whether plan only updates the state to match the remote objects, detecting drift without planning changes
 }
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 )
 
@@ -14,9 +15,46 @@ func OutputAll(options *Options) (map[string]interface{}, error) {
 	return OutputForKeysE(options, nil)
 }
 
+// OutputAllWithSensitive calls terraform and returns all the outputs as a map and the sorted names of the outputs
+// marked sensitive
+func OutputAllWithSensitive(options *Options) (map[string]interface{}, []string, error) {
+	outputMap, err := getOutputMap(options)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	outputs, err := getOutputValues(outputMap, nil)
+
+	return outputs, getSensitiveOutputs(outputMap), err
+}
+
+// getSensitiveOutputs returns the sorted names of the outputs marked sensitive
+func getSensitiveOutputs(outputMap map[string]map[string]interface{}) []string {
+	sensitive := []string{}
+	for key, output := range outputMap {
+		if s, ok := output["sensitive"].(bool); ok && s {
+			sensitive = append(sensitive, key)
+		}
+	}
+
+	sort.Strings(sensitive)
+
+	return sensitive
+}
+
 // OutputForKeysE calls terraform output for the given key list and returns values as a map.
 // The returned values are of type interface{} and need to be type casted as necessary. Refer to output_test.go
 func OutputForKeysE(options *Options, keys []string) (map[string]interface{}, error) {
+	outputMap, err := getOutputMap(options)
+	if err != nil {
+		return nil, err
+	}
+
+	return getOutputValues(outputMap, keys)
+}
+
+// getOutputMap calls terraform output and returns every output by its name
+func getOutputMap(options *Options) (map[string]map[string]interface{}, error) {
 	out, err := RunTerraformCommand(false, options, "output", "-no-color", "-json")
 	if err != nil {
 		return nil, err
@@ -27,6 +65,12 @@ func OutputForKeysE(options *Options, keys []string) (map[string]interface{}, er
 		return nil, err
 	}
 
+	return outputMap, nil
+}
+
+// getOutputValues returns the values of the outputs of the keys, every output when keys is nil
+func getOutputValues(outputMap map[string]map[string]interface{}, keys []string) (map[string]interface{}, error) {
+
 	if keys == nil {
 		outputKeys := make([]string, 0, len(outputMap))
 		for k := range outputMap {
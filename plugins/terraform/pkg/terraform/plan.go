@@ -10,5 +10,9 @@ func Plan(options *Options, tfplan string, destroy bool) (string, error) {
 		args = append(args, "-destroy")
 	}
 
+	if options.RefreshOnly {
+		args = append(args, "-refresh-only")
+	}
+
 	return RunTerraformCommand(true, options, FormatArgs(options, args...)...)
 }
@@ -6,3 +6,17 @@ func Show(options *Options, tfplan string) (string, error) {
 
 	return RunTerraformCommand(false, options, FormatArgs(options, args...)...)
 }
+
+// ShowPlan runs terraform show for the plan and returns its human-readable output, without colors, and any error
+func ShowPlan(options *Options, tfplan string) (string, error) {
+	args := []string{"show", "-no-color", tfplan}
+
+	return RunTerraformCommand(false, options, FormatArgs(options, args...)...)
+}
+
+// ShowState runs terraform show for the current state and returns the output and any error
+func ShowState(options *Options) (string, error) {
+	args := []string{"show", "-json"}
+
+	return RunTerraformCommand(false, options, FormatArgs(options, args...)...)
+}
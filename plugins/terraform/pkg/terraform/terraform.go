@@ -17,12 +17,17 @@ const DefaultErrorExitCode = 1
 type Terraformer interface {
 	Version(options *Options) (out string, err error)
 	Show(options *Options, tfplan string) (string, error)
+	ShowPlan(options *Options, tfplan string) (string, error)
+	ShowState(options *Options) (string, error)
 	Plan(options *Options, tfplan string, destroy bool) (string, error)
 	OutputAll(options *Options) (map[string]interface{}, error)
+	OutputAllWithSensitive(options *Options) (map[string]interface{}, []string, error)
 	OutputForKeysE(options *Options, keys []string) (map[string]interface{}, error)
 	OutputToString(value interface{}) string
 	Init(options *Options) (out string, err error)
+	InitProviders(options *Options) (out string, err error)
 	Apply(options *Options, tfplan string) (string, error)
+	Validate(options *Options) (string, error)
 	WorkspaceSelect(options *Options, workspace string) (string, error)
 }
 
@@ -36,6 +41,14 @@ func (t Terraform) Show(options *Options, tfplan string) (string, error) {
 	return Show(options, tfplan)
 }
 
+func (t Terraform) ShowPlan(options *Options, tfplan string) (string, error) {
+	return ShowPlan(options, tfplan)
+}
+
+func (t Terraform) ShowState(options *Options) (string, error) {
+	return ShowState(options)
+}
+
 func (t Terraform) Plan(options *Options, tfplan string, destroy bool) (string, error) {
 	return Plan(options, tfplan, destroy)
 }
@@ -44,6 +57,10 @@ func (t Terraform) OutputAll(options *Options) (map[string]interface{}, error) {
 	return OutputAll(options)
 }
 
+func (t Terraform) OutputAllWithSensitive(options *Options) (map[string]interface{}, []string, error) {
+	return OutputAllWithSensitive(options)
+}
+
 func (t Terraform) OutputForKeysE(options *Options, keys []string) (map[string]interface{}, error) {
 	return OutputForKeysE(options, keys)
 }
@@ -52,10 +69,18 @@ func (t Terraform) Init(options *Options) (out string, err error) {
 	return Init(options)
 }
 
+func (t Terraform) InitProviders(options *Options) (out string, err error) {
+	return InitProviders(options)
+}
+
 func (t Terraform) Apply(options *Options, tfplan string) (string, error) {
 	return Apply(options, tfplan)
 }
 
+func (t Terraform) Validate(options *Options) (string, error) {
+	return Validate(options)
+}
+
 func (t Terraform) OutputToString(value interface{}) string {
 	return OutputToString(value)
 }
@@ -95,3 +95,14 @@ func TestOutputToString(t *testing.T) {
 		assert.Equal(t, tc.ExpectedString, result)
 	}
 }
+
+func TestGetSensitiveOutputs(t *testing.T) {
+	outputMap := map[string]map[string]interface{}{
+		"password": {"sensitive": true, "value": "stub"},
+		"host":     {"sensitive": false, "value": "stub.example.com"},
+		"key":      {"sensitive": true, "value": "stub"},
+		"port":     {"value": 5432},
+	}
+
+	assert.Equal(t, []string{"key", "password"}, getSensitiveOutputs(outputMap))
+}
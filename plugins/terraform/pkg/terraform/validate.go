@@ -0,0 +1,7 @@
+package terraform
+
+// Validate runs terraform validate and returns the json output and any error.
+// Validate does not accept variables, so options.Vars and options.VarFiles are ignored.
+func Validate(options *Options) (string, error) {
+	return RunTerraformCommand(false, options, "validate", "-json")
+}
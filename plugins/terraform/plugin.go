@@ -1,6 +1,9 @@
 package plugins_terraform
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/optum/runiac/plugins/terraform/pkg/terraform"
 	"github.com/sirupsen/logrus"
 )
@@ -31,3 +34,39 @@ func (info TerraformPlugin) Initialize(logger *logrus.Entry) {
 		tfOptions.Logger.Info("Binary: ", resp)
 	}
 }
+
+// PullProviders runs terraform init without a backend in each step directory so provider and module
+// downloads happen, and are timed, separately from the deployment
+func (info TerraformPlugin) PullProviders(logger *logrus.Entry, dirs []string) error {
+	failed := 0
+
+	for _, dir := range dirs {
+		start := time.Now()
+
+		tfOptions := &terraform.Options{
+			TerraformDir: dir,
+			EnvVars: map[string]string{
+				"CHECKPOINT_DISABLE": "true",
+			},
+			Logger:             logger.WithField("terraform", "init-providers").WithField("dir", dir),
+			NoColor:            true,
+			MaxRetries:         1,
+			TimeBetweenRetries: 0,
+		}
+
+		_, err := terraformer.InitProviders(tfOptions)
+		if err != nil {
+			tfOptions.Logger.WithError(err).Error("Error pulling providers")
+			failed++
+			continue
+		}
+
+		tfOptions.Logger.WithField("duration", time.Since(start).String()).Info("Pulled providers")
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to pull providers for %d of %d step directories", failed, len(dirs))
+	}
+
+	return nil
+}
@@ -0,0 +1,133 @@
+package plugins_terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/pkg/shell"
+	"github.com/optum/runiac/plugins/terraform/pkg/terraform"
+	"github.com/spf13/afero"
+)
+
+// policyPlanFile is the terraform show -json plan conftest evaluates, written to the step directory
+const policyPlanFile = "tfplan.policy.json"
+
+// runConftestCommand runs the conftest cli, returning its stdout
+var runConftestCommand = func(options *terraform.Options, args ...string) (string, error) {
+	return shell.RunCommandAndGetStdOut(shell.Command{
+		Command:        "conftest",
+		Args:           args,
+		WorkingDir:     options.TerraformDir,
+		Env:            options.EnvVars,
+		NonInteractive: true,
+		Logger:         options.Logger,
+		Context:        options.Context,
+	})
+}
+
+// conftestResult is the conftest test --output json result of evaluating a file against the policies of a namespace
+type conftestResult struct {
+	Filename  string              `json:"filename"`
+	Namespace string              `json:"namespace"`
+	Successes int                 `json:"successes"`
+	Failures  []conftestViolation `json:"failures"`
+	Warnings  []conftestViolation `json:"warnings"`
+}
+
+// conftestViolation is a deny or warn rule of a policy that matched the evaluated file
+type conftestViolation struct {
+	Msg string `json:"msg"`
+}
+
+// checkPolicies evaluates the plan against the rego policies of the step execution, returning the violations as
+// findings and an error when the plan must not be applied, because it violates a deny rule or the policies could
+// not be evaluated. With PolicySoftFail the plan is applied regardless and the violations are only logged.
+func checkPolicies(exec config.StepExecution, planJSON string, tfOptions *terraform.Options) ([]config.Finding, error) {
+	logger := tfOptions.Logger.WithField("conftest", "test")
+
+	options := *tfOptions
+	options.Logger = logger
+
+	findings, err := evaluatePolicies(exec, planJSON, &options)
+	if err != nil {
+		if exec.PolicySoftFail {
+			logger.WithError(err).Warn("Unable to evaluate the policies, applying the plan with policy soft-fail")
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	violations := 0
+
+	for _, f := range findings {
+		if f.Level != config.FindingError {
+			logger.WithField("rule", f.RuleID).Warn(f.Message)
+			continue
+		}
+
+		violations++
+
+		if exec.PolicySoftFail {
+			logger.WithField("rule", f.RuleID).Warn(f.Message)
+		} else {
+			logger.WithField("rule", f.RuleID).Error(f.Message)
+		}
+	}
+
+	logger.Infof("Plan violates %d policy rule(s) in %s", violations, exec.PolicyDir)
+
+	if violations == 0 || exec.PolicySoftFail {
+		return findings, nil
+	}
+
+	return findings, fmt.Errorf("plan violates %d policy rule(s) in %s", violations, exec.PolicyDir)
+}
+
+// evaluatePolicies runs conftest against the plan, returning a finding for each failed deny and matched warn rule
+func evaluatePolicies(exec config.StepExecution, planJSON string, tfOptions *terraform.Options) ([]config.Finding, error) {
+	path := filepath.Join(exec.Dir, policyPlanFile)
+
+	if err := afero.WriteFile(exec.Fs, path, []byte(planJSON), 0644); err != nil {
+		return nil, fmt.Errorf("unable to write the plan for conftest: %w", err)
+	}
+
+	defer func() { _ = exec.Fs.Remove(path) }()
+
+	// conftest exits non-zero when a deny rule fails, still producing the json output
+	resp, err := runConftestCommand(tfOptions, "test", "--policy", exec.PolicyDir, "--all-namespaces", "--no-color", "--output", "json", path)
+
+	results := []conftestResult{}
+
+	if parseErr := json.Unmarshal([]byte(resp), &results); parseErr != nil {
+		if err != nil {
+			return nil, fmt.Errorf("unable to evaluate the policies in %s: %w", exec.PolicyDir, err)
+		}
+
+		return nil, fmt.Errorf("unable to parse the conftest results: %w", parseErr)
+	}
+
+	return getPolicyFindings(results), nil
+}
+
+// getPolicyFindings returns an error finding for each failed deny rule and a warning finding for each matched warn
+// rule, identified by the policy's namespace
+func getPolicyFindings(results []conftestResult) []config.Finding {
+	findings := []config.Finding{}
+
+	for _, r := range results {
+		ruleID := fmt.Sprintf("policy/%s", r.Namespace)
+
+		for _, v := range r.Failures {
+			findings = append(findings, config.Finding{RuleID: ruleID, Level: config.FindingError, Message: v.Msg})
+		}
+
+		for _, v := range r.Warnings {
+			findings = append(findings, config.Finding{RuleID: ruleID, Level: config.FindingWarning, Message: v.Msg})
+		}
+	}
+
+	return findings
+}
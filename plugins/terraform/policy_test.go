@@ -0,0 +1,76 @@
+package plugins_terraform
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/plugins/terraform/pkg/terraform"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+const stubConftestResults = `[
+	{"filename": "/steps/storage/tfplan.policy.json", "namespace": "storage", "successes": 2,
+	 "failures": [{"msg": "aws_s3_bucket.logs must not be public"}],
+	 "warnings": [{"msg": "aws_s3_bucket.logs should enable versioning"}]},
+	{"filename": "/steps/storage/tfplan.policy.json", "namespace": "main", "successes": 1}
+]`
+
+func TestCheckPolicies_ShouldFailPlansViolatingPolicies(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	exec := config.StepExecution{Fs: fs, Dir: "/steps/storage", PolicyDir: "/runiac/policies", Logger: logger}
+	commands := [][]string{}
+
+	defer func(f func(*terraform.Options, ...string) (string, error)) { runConftestCommand = f }(runConftestCommand)
+	runConftestCommand = func(options *terraform.Options, args ...string) (string, error) {
+		commands = append(commands, args)
+
+		return stubConftestResults, errors.New("exit status 1")
+	}
+
+	findings, err := checkPolicies(exec, `{"resource_changes": []}`, &terraform.Options{Logger: logger})
+	require.EqualError(t, err, "plan violates 1 policy rule(s) in /runiac/policies")
+	require.Equal(t, []config.Finding{
+		{RuleID: "policy/storage", Level: config.FindingError, Message: "aws_s3_bucket.logs must not be public"},
+		{RuleID: "policy/storage", Level: config.FindingWarning, Message: "aws_s3_bucket.logs should enable versioning"},
+	}, findings)
+	require.Equal(t, []string{"test", "--policy", "/runiac/policies", "--all-namespaces", "--no-color", "--output", "json", "/steps/storage/tfplan.policy.json"}, commands[0])
+
+	exists, _ := afero.Exists(fs, "/steps/storage/tfplan.policy.json")
+	require.False(t, exists, "the plan written for conftest is removed")
+
+	exec.PolicySoftFail = true
+
+	findings, err = checkPolicies(exec, `{"resource_changes": []}`, &terraform.Options{Logger: logger})
+	require.NoError(t, err, "soft-fail only warns about violations")
+	require.Len(t, findings, 2)
+}
+
+func TestCheckPolicies_ShouldFailWhenPoliciesCanNotBeEvaluated(t *testing.T) {
+	exec := config.StepExecution{Fs: afero.NewMemMapFs(), Dir: "/steps/storage", PolicyDir: "/runiac/policies", Logger: logger}
+
+	defer func(f func(*terraform.Options, ...string) (string, error)) { runConftestCommand = f }(runConftestCommand)
+	runConftestCommand = func(options *terraform.Options, args ...string) (string, error) {
+		return "", errors.New("conftest: command not found")
+	}
+
+	_, err := checkPolicies(exec, `{}`, &terraform.Options{Logger: logger})
+	require.EqualError(t, err, "unable to evaluate the policies in /runiac/policies: conftest: command not found")
+
+	exec.PolicySoftFail = true
+
+	findings, err := checkPolicies(exec, `{}`, &terraform.Options{Logger: logger})
+	require.NoError(t, err)
+	require.Empty(t, findings)
+
+	runConftestCommand = func(options *terraform.Options, args ...string) (string, error) {
+		return `[{"namespace": "main", "successes": 3}]`, nil
+	}
+
+	exec.PolicySoftFail = false
+
+	findings, err = checkPolicies(exec, `{}`, &terraform.Options{Logger: logger})
+	require.NoError(t, err)
+	require.Empty(t, findings)
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -14,6 +15,7 @@ import (
 	"github.com/optum/runiac/pkg/shell"
 	"github.com/optum/runiac/plugins/terraform/pkg/terraform"
 	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
 )
 
 type TerraformStepper struct{}
@@ -97,6 +99,95 @@ func GetTerraformCLIVars(exec config.StepExecution) map[string]interface{} {
 	return vars
 }
 
+// trackVarFile is the name of the var files in a track directory passed to every step of the track, e.g. track.tfvars
+// and track.prod.tfvars for the prod environment
+const trackVarFile = "track"
+
+// getTrackVarFiles returns the var files of the execution's track, track.tfvars, track.tfvars.json and the
+// environment's track.{environment}.tfvars and track.{environment}.tfvars.json, relative to the execution directory.
+// The environment's var files are passed after the track's so their values take precedence.
+func getTrackVarFiles(exec config.StepExecution) []string {
+	files := []string{}
+
+	if exec.TrackDir == "" || exec.Fs == nil {
+		return files
+	}
+
+	names := []string{trackVarFile}
+	if exec.Environment != "" {
+		names = append(names, fmt.Sprintf("%s.%s", trackVarFile, strings.ToLower(exec.Environment)))
+	}
+
+	for _, name := range names {
+		for _, ext := range []string{".tfvars", ".tfvars.json"} {
+			file := filepath.Join(exec.TrackDir, name+ext)
+
+			if exists, _ := afero.Exists(exec.Fs, file); !exists {
+				continue
+			}
+
+			if rel, err := filepath.Rel(exec.Dir, file); err == nil {
+				file = rel
+			}
+
+			exec.Logger.Debugf("Adding the %s var file of track %s", file, exec.TrackName)
+			files = append(files, file)
+		}
+	}
+
+	return files
+}
+
+// ringVarFilesDir is the directory of a step containing the var files of each deployment ring, e.g. rings/prod.tfvars
+const ringVarFilesDir = "rings"
+
+// getRingVarFiles returns the var files of the execution's deployment ring in the step directory, rings/{ring}.tfvars
+// and rings/{ring}.tfvars.json, relative to the step directory. A regional execution reads them from the step's
+// regional directory.
+func getRingVarFiles(exec config.StepExecution) []string {
+	files := []string{}
+
+	if exec.DeploymentRing == "" || exec.Fs == nil {
+		return files
+	}
+
+	for _, ext := range []string{".tfvars", ".tfvars.json"} {
+		file := filepath.Join(ringVarFilesDir, strings.ToLower(exec.DeploymentRing)+ext)
+
+		if exists, _ := afero.Exists(exec.Fs, filepath.Join(exec.Dir, file)); exists {
+			exec.Logger.Debugf("Adding the %s var file of deployment ring %s", file, exec.DeploymentRing)
+			files = append(files, file)
+		}
+	}
+
+	return files
+}
+
+// regionVarFilesDir is the directory of a step's regional directory containing the var files of each region, e.g.
+// regional/regions/eastus.tfvars
+const regionVarFilesDir = "regions"
+
+// getRegionVarFiles returns the var files of a regional execution's region in its regional directory,
+// regions/{region}.tfvars and regions/{region}.tfvars.json, relative to the execution directory
+func getRegionVarFiles(exec config.StepExecution) []string {
+	files := []string{}
+
+	if exec.RegionDeployType != config.RegionalRegionDeployType || exec.Region == "" || exec.Fs == nil {
+		return files
+	}
+
+	for _, ext := range []string{".tfvars", ".tfvars.json"} {
+		file := filepath.Join(regionVarFilesDir, strings.ToLower(exec.Region)+ext)
+
+		if exists, _ := afero.Exists(exec.Fs, filepath.Join(exec.Dir, file)); exists {
+			exec.Logger.Debugf("Adding the %s var file of region %s", file, exec.Region)
+			files = append(files, file)
+		}
+	}
+
+	return files
+}
+
 func GetTerraformEnvVars(exec config.StepExecution) map[string]string {
 	output := exec.OptionalStepParams
 
@@ -195,9 +286,18 @@ var executeTerraformInDir = func(exec config.StepExecution, destroy bool) (outpu
 		return
 	}
 
+	output.Err = EnsureGCSBackend(exec)
+
+	if output.Err != nil {
+		tfOptions.Logger.WithError(output.Err).Error("unable to configure the gcs backend")
+		return
+	}
+
 	tfOptions.BackendConfig = GetBackendConfig(exec, ParseTFBackend).Config
 	tfOptions.Logger = tfOptions.Logger.WithField("terraform", "init")
+	span := exec.Span.StartChild("terraform init")
 	resp, output.Err = terraformer.Init(tfOptions)
+	span.Finish(output.Err)
 
 	if output.Err != nil {
 		tfOptions.Logger.WithError(output.Err).Error("Error during terraform init")
@@ -211,20 +311,37 @@ var executeTerraformInDir = func(exec config.StepExecution, destroy bool) (outpu
 	if exec.Namespace != "" {
 		workspace = fmt.Sprintf("%s-%s", exec.Namespace, workspace)
 	}
+	span = exec.Span.StartChild("terraform workspace", "terraform.workspace", workspace)
 	resp, output.Err = terraformer.WorkspaceSelect(tfOptions, workspace)
+	span.Finish(output.Err)
 
 	if output.Err != nil {
 		tfOptions.Logger.WithError(output.Err).Error("Error during terraform init")
 		return
 	}
 
+	if exec.CollectFindings {
+		output.Findings = validateStep(exec, tfOptions)
+	}
+
+	validationFindings := output.Findings
+	tfplan := fmt.Sprintf("%s%s%stfplan", exec.StepName, exec.RegionDeployType, exec.Region)
+
+	// reuse the plan produced by a previous run instead of planning again
+	if exec.ArtifactsFrom != "" {
+		output.Err = restoreStepArtifacts(exec, tfplan, destroy)
+
+		if output.Err != nil {
+			tfOptions.Logger.WithError(output.Err).Error("Error restoring plan artifacts")
+			return
+		}
+	}
+
 	// terraform plan
 	_ = retry.DoWithRetry("terraform plan and apply", tfOptions.MaxRetries, 10*time.Second, tfOptions.Logger, func(attempt int) error {
 
 		retryLogger := tfOptions.Logger.WithField("retryCount", attempt)
 
-		tfplan := fmt.Sprintf("%s%s%stfplan", exec.StepName, exec.RegionDeployType, exec.Region)
-
 		// terraform plan
 		tfOptions, output.Err = getCommonTfOptions2(exec)
 
@@ -242,12 +359,18 @@ var executeTerraformInDir = func(exec config.StepExecution, destroy bool) (outpu
 		}
 
 		tfOptions.Vars = GetTerraformCLIVars(exec)
+		tfOptions.VarFiles = append(append(getTrackVarFiles(exec), getRingVarFiles(exec)...), getRegionVarFiles(exec)...)
+		tfOptions.RefreshOnly = exec.DetectDrift && !destroy
 
-		resp, output.Err = terraformer.Plan(tfOptions, tfplan, destroy)
+		if exec.ArtifactsFrom == "" {
+			span := exec.Span.StartChild("terraform plan", "runiac.attempt", strconv.Itoa(attempt))
+			resp, output.Err = terraformer.Plan(tfOptions, tfplan, destroy)
+			span.Finish(output.Err)
 
-		if output.Err != nil {
-			tfOptions.Logger.WithError(output.Err).Error("Error running terraform plan")
-			return output.Err
+			if output.Err != nil {
+				tfOptions.Logger.WithError(output.Err).Error("Error running terraform plan")
+				return output.Err
+			}
 		}
 
 		// validate terraform plan
@@ -259,13 +382,29 @@ var executeTerraformInDir = func(exec config.StepExecution, destroy bool) (outpu
 		}
 
 		baseOptions.Logger = retryLogger.WithField("terraform", "show")
+		span := exec.Span.StartChild("terraform show", "runiac.attempt", strconv.Itoa(attempt))
 		resp, output.Err = terraformer.Show(baseOptions, tfplan)
+		span.Finish(output.Err)
 
 		if output.Err != nil {
 			baseOptions.Logger.WithError(output.Err).Errorf("Error during terraform show:\n%s", resp)
 			return output.Err
 		}
 
+		if exec.OutputDir != "" {
+			planText, err := terraformer.ShowPlan(baseOptions, tfplan)
+			if err != nil {
+				baseOptions.Logger.WithError(err).Warn("Failed to render the plan for review")
+				planText = ""
+			}
+
+			err = saveStepArtifacts(exec, tfplan, resp, planText, destroy)
+
+			if err != nil {
+				baseOptions.Logger.WithError(err).Warn("Failed to save plan artifacts")
+			}
+		}
+
 		plan := plan{}
 		output.Err = json.Unmarshal([]byte(resp), &plan)
 
@@ -276,6 +415,8 @@ var executeTerraformInDir = func(exec config.StepExecution, destroy bool) (outpu
 		// aws_cloudtrail.central_logging_trail, aws_cloudtrail, central_logging_trail: [no-op]
 
 		resourceChangesByAction := map[string][]string{}
+		output.ResourceChanges = config.ResourceChanges{}
+
 		for _, c := range plan.ResourceChanges {
 			key := fmt.Sprintf("%s", c.Change.Actions)
 			if resourceChangesByAction[key] == nil {
@@ -283,12 +424,49 @@ var executeTerraformInDir = func(exec config.StepExecution, destroy bool) (outpu
 			}
 
 			resourceChangesByAction[key] = append(resourceChangesByAction[key], c.Address)
+			output.ResourceChanges.Count(c.Change.Actions)
 
 			tfOptions.Logger.Info(fmt.Sprintf("%s, %s, %s: %s", c.Address, c.Type, c.Name, c.Change.Actions))
 		}
+		output.Findings = validationFindings
+
+		if tfOptions.RefreshOnly {
+			output.DriftedResources = getDriftedResources(plan)
+			tfOptions.Logger.Infof("%d resource(s) drifted from the state", len(output.DriftedResources))
+		}
+
+		if exec.SimulateIAM {
+			output.Findings = append(output.Findings, simulateIAM(plan, baseOptions)...)
+		}
+
+		if exec.EstimateCost {
+			output.CostEstimate = estimateCost(exec, resp, baseOptions)
+		}
+
+		if exec.PolicyDir != "" {
+			policyFindings, err := checkPolicies(exec, resp, baseOptions)
+			output.Findings = append(output.Findings, policyFindings...)
+
+			if err != nil {
+				output.Err = err
+				tfOptions.Logger.WithError(output.Err).Error("---------- Skipping apply, plan violates policies ---------- ")
+
+				// do not retry, the plan will not change
+				return nil
+			}
+		}
+
 		applyChanges := true
 		//noChanges := len(resourceChangesByAction["[no-op]"]) == len(plan.ResourceChanges)
 
+		if exceedsPlanSummaryThreshold(exec, plan, tfOptions.Logger) && exec.PlanSummaryRequireConfirm && !exec.Confirmed && !exec.DryRun {
+			output.Err = fmt.Errorf("plan exceeds the plan summary threshold of %d changed resources, re-run with --confirm to apply", exec.PlanSummaryThreshold)
+			tfOptions.Logger.WithError(output.Err).Error("---------- Skipping apply, plan requires confirmation ---------- ")
+
+			// do not retry, the plan will not change without confirmation
+			return nil
+		}
+
 		// only run apply on when not dry run and changes exist
 		if exec.DryRun {
 			tfOptions.Logger.Info("---------- Skipping apply, this is a dry run ---------- ")
@@ -303,7 +481,9 @@ var executeTerraformInDir = func(exec config.StepExecution, destroy bool) (outpu
 		if applyChanges {
 			// terraform apply
 			baseOptions.Logger = retryLogger.WithField("terraform", "apply")
+			span := exec.Span.StartChild("terraform apply", "runiac.attempt", strconv.Itoa(attempt))
 			resp, output.Err = terraformer.Apply(baseOptions, tfplan)
+			span.Finish(output.Err)
 
 			if output.Err != nil {
 				baseOptions.Logger.WithError(output.Err).Error("Error running terraform apply")
@@ -321,12 +501,27 @@ var executeTerraformInDir = func(exec config.StepExecution, destroy bool) (outpu
 
 		baseOptions.Logger = retryLogger.WithField("terraform", "output")
 
-		output.OutputVariables, output.Err = terraformer.OutputAll(tfOptions)
+		span = exec.Span.StartChild("terraform output")
+		output.OutputVariables, output.SensitiveOutputs, output.Err = terraformer.OutputAllWithSensitive(tfOptions)
+		span.Finish(output.Err)
 
 		if output.Err != nil {
 			baseOptions.Logger.WithError(output.Err).Error("Error running terraform output")
 		}
 
+		if exec.ExportManifest {
+			baseOptions.Logger = retryLogger.WithField("terraform", "show")
+			resp, err = terraformer.ShowState(baseOptions)
+
+			if err == nil {
+				output.ManagedResources, err = getManagedResources(resp)
+			}
+
+			if err != nil {
+				baseOptions.Logger.WithError(err).Warn("Unable to collect managed resources for the manifest")
+			}
+		}
+
 		output.Status = config.Success
 
 		return nil
@@ -335,6 +530,80 @@ var executeTerraformInDir = func(exec config.StepExecution, destroy bool) (outpu
 	return
 }
 
+// countPlanChanges returns the number of resources the plan will change, along with how many of those will be destroyed
+func countPlanChanges(p plan) (changed int, destroyed int) {
+	for _, c := range p.ResourceChanges {
+		if len(c.Change.Actions) == 0 || contains(c.Change.Actions, "no-op") || contains(c.Change.Actions, "read") {
+			continue
+		}
+
+		changed++
+
+		if contains(c.Change.Actions, "delete") {
+			destroyed++
+		}
+	}
+
+	return
+}
+
+// exceedsPlanSummaryThreshold warns when the plan changes more resources than the configured plan summary threshold
+func exceedsPlanSummaryThreshold(exec config.StepExecution, p plan, logger *logrus.Entry) bool {
+	if exec.PlanSummaryThreshold <= 0 {
+		return false
+	}
+
+	changed, destroyed := countPlanChanges(p)
+
+	if changed <= exec.PlanSummaryThreshold {
+		return false
+	}
+
+	logger.Warnf("!!!!!!!!!! Plan changes %d resources (%d destroyed), exceeding the plan summary threshold of %d. Review carefully! !!!!!!!!!!", changed, destroyed, exec.PlanSummaryThreshold)
+
+	return true
+}
+
+// getManagedResources parses the terraform show -json output of the current state into the resources it manages
+func getManagedResources(stateJSON string) ([]config.ManagedResource, error) {
+	st := state{}
+
+	err := json.Unmarshal([]byte(stateJSON), &st)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := []config.ManagedResource{}
+	modules := []stateModule{st.Values.RootModule}
+
+	for len(modules) > 0 {
+		m := modules[0]
+		modules = append(modules[1:], m.ChildModules...)
+
+		for _, r := range m.Resources {
+			// data sources are read, not managed
+			if r.Mode == "data" {
+				continue
+			}
+
+			id := ""
+			if v, ok := r.Values["id"]; ok && v != nil {
+				id = fmt.Sprintf("%v", v)
+			}
+
+			resources = append(resources, config.ManagedResource{
+				Address:  r.Address,
+				Type:     r.Type,
+				Name:     r.Name,
+				ID:       id,
+				Provider: r.ProviderName,
+			})
+		}
+	}
+
+	return resources, nil
+}
+
 // GetBackendConfig parses a backend.tf file
 // TODO, replace this with a cleaner hcl2json2struct merge where backend.tf configurations take priority over defined defaults here
 func GetBackendConfig(exec config.StepExecution, backendParser TFBackendParser) TerraformBackend {
@@ -383,6 +652,17 @@ func GetBackendConfig(exec config.StepExecution, backendParser TFBackendParser)
 		exec.Logger.Debugf("Declared GCS prefix: %s", b["prefix"])
 	}
 
+	// the backend section of runiac.yml configures what the gcs backend does not declare
+	if declaredBackend.Type == GCSBackend && exec.Backend.GCS.Bucket != "" {
+		if declaredBackend.GCSBucket == "" {
+			b["bucket"] = exec.Backend.GCS.Bucket
+		}
+
+		if declaredBackend.GCSPrefix == "" {
+			b["prefix"] = getGCSPrefix(exec)
+		}
+	}
+
 	if declaredBackend.AZUResourceGroupName != "" {
 		b["resource_group_name"] = interpolateString(exec, declaredBackend.AZUResourceGroupName)
 	}
@@ -476,6 +756,7 @@ func getCommonTfOptions2(exec config.StepExecution) (tfOptions *terraform.Option
 		RetryableTerraformErrors: map[string]string{".*": "General Terraform error occurred."},
 		MaxRetries:               exec.MaxRetries,
 		TimeBetweenRetries:       5 * time.Second,
+		Context:                  exec.Context,
 	}
 
 	return
@@ -38,6 +38,63 @@ func TestGetTerraformEnvArgs_ShouldIncludeEnvironment(t *testing.T) {
 	require.Equal(t, "fun", vars["runiac_environment"])
 }
 
+func TestGetRingVarFiles_ShouldReturnVarFilesOfDeploymentRing(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	_ = afero.WriteFile(fs, "tracks/net/step1_vpc/rings/prod.tfvars", []byte(`cidr = "10.0.0.0/16"`), 0644)
+	_ = afero.WriteFile(fs, "tracks/net/step1_vpc/rings/prod.tfvars.json", []byte(`{"zones": 3}`), 0644)
+	_ = afero.WriteFile(fs, "tracks/net/step1_vpc/rings/dev.tfvars", []byte(`cidr = "10.1.0.0/16"`), 0644)
+
+	exec := config.StepExecution{Fs: fs, Dir: "tracks/net/step1_vpc", DeploymentRing: "Prod", Logger: logger}
+
+	require.Equal(t, []string{"rings/prod.tfvars", "rings/prod.tfvars.json"}, getRingVarFiles(exec))
+
+	exec.DeploymentRing = "stage"
+	require.Empty(t, getRingVarFiles(exec), "rings without var files should not add var files")
+
+	exec.DeploymentRing = ""
+	require.Empty(t, getRingVarFiles(exec))
+}
+
+func TestGetRegionVarFiles_ShouldReturnVarFilesOfRegionalRegion(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	_ = afero.WriteFile(fs, "tracks/net/step1_vpc/regional-eastus/regions/eastus.tfvars", []byte(`zones = 3`), 0644)
+	_ = afero.WriteFile(fs, "tracks/net/step1_vpc/regional-eastus/regions/westus.tfvars", []byte(`zones = 2`), 0644)
+
+	exec := config.StepExecution{Fs: fs, Dir: "tracks/net/step1_vpc/regional-eastus", Region: "EastUS", RegionDeployType: config.RegionalRegionDeployType, Logger: logger}
+
+	require.Equal(t, []string{"regions/eastus.tfvars"}, getRegionVarFiles(exec))
+
+	exec.RegionDeployType = config.PrimaryRegionDeployType
+	require.Empty(t, getRegionVarFiles(exec), "a primary execution should not read region var files")
+}
+
+func TestGetTrackVarFiles_ShouldReturnVarFilesOfTrackAndEnvironment(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	_ = afero.WriteFile(fs, "tracks/net/track.tfvars", []byte(`owner = "platform"`), 0644)
+	_ = afero.WriteFile(fs, "tracks/net/track.prod.tfvars.json", []byte(`{"zones": 3}`), 0644)
+	_ = afero.WriteFile(fs, "tracks/net/track.dev.tfvars", []byte(`zones = 1`), 0644)
+	_ = fs.MkdirAll("tracks/net/step1_vpc/regional-us-east-1", 0755)
+
+	exec := config.StepExecution{Fs: fs, Dir: "tracks/net/step1_vpc", TrackDir: "tracks/net", TrackName: "net", Environment: "Prod", Logger: logger}
+
+	require.Equal(t, []string{"../track.tfvars", "../track.prod.tfvars.json"}, getTrackVarFiles(exec))
+
+	exec.Dir = "tracks/net/step1_vpc/regional-us-east-1"
+	require.Equal(t, []string{"../../track.tfvars", "../../track.prod.tfvars.json"}, getTrackVarFiles(exec), "a regional execution should read the var files of the track")
+
+	exec.Environment = ""
+	require.Equal(t, []string{"../../track.tfvars"}, getTrackVarFiles(exec))
+
+	exec.TrackDir = "tracks/app"
+	require.Empty(t, getTrackVarFiles(exec), "tracks without var files should not add var files")
+}
+
 func TestGetBackendConfig_ShouldParseAssumeRoleCoreAccountIDMapCorrectly(t *testing.T) {
 	t.Parallel()
 	fs := afero.NewMemMapFs()
@@ -555,3 +612,151 @@ func TestStringToBackendType(t *testing.T) {
 		require.Equal(t, tc.errorExists, err != nil, "The error result should match the expected")
 	}
 }
+
+func TestCountPlanChanges_ShouldIgnoreNoOpAndReadChanges(t *testing.T) {
+	t.Parallel()
+
+	p := plan{
+		ResourceChanges: []resourceChange{
+			{Address: "a", Change: change{Actions: []string{"no-op"}}},
+			{Address: "b", Change: change{Actions: []string{"read"}}},
+			{Address: "c", Change: change{Actions: []string{"create"}}},
+			{Address: "d", Change: change{Actions: []string{"update"}}},
+			{Address: "e", Change: change{Actions: []string{"delete", "create"}}},
+			{Address: "f", Change: change{Actions: []string{"delete"}}},
+		},
+	}
+
+	changed, destroyed := countPlanChanges(p)
+
+	require.Equal(t, 4, changed)
+	require.Equal(t, 2, destroyed)
+}
+
+func TestExceedsPlanSummaryThreshold_ShouldOnlyExceedWhenEnabled(t *testing.T) {
+	t.Parallel()
+
+	p := plan{
+		ResourceChanges: []resourceChange{
+			{Address: "a", Change: change{Actions: []string{"create"}}},
+			{Address: "b", Change: change{Actions: []string{"delete"}}},
+		},
+	}
+
+	require.False(t, exceedsPlanSummaryThreshold(config.StepExecution{}, p, logger), "threshold is disabled by default")
+	require.False(t, exceedsPlanSummaryThreshold(config.StepExecution{PlanSummaryThreshold: 2}, p, logger))
+	require.True(t, exceedsPlanSummaryThreshold(config.StepExecution{PlanSummaryThreshold: 1}, p, logger))
+}
+
+func TestGetManagedResources_ShouldIncludeChildModulesAndSkipDataSources(t *testing.T) {
+	t.Parallel()
+
+	stateJSON := `{
+		"format_version": "0.1",
+		"values": {
+			"root_module": {
+				"resources": [
+					{"address": "azurerm_resource_group.rg", "mode": "managed", "type": "azurerm_resource_group", "name": "rg", "provider_name": "registry.terraform.io/hashicorp/azurerm", "values": {"id": "/subscriptions/1/resourceGroups/rg"}},
+					{"address": "data.azurerm_client_config.current", "mode": "data", "type": "azurerm_client_config", "name": "current", "values": {"id": "1"}}
+				],
+				"child_modules": [
+					{
+						"address": "module.network",
+						"resources": [
+							{"address": "module.network.azurerm_virtual_network.vnet", "mode": "managed", "type": "azurerm_virtual_network", "name": "vnet", "values": {"id": "vnet-id"}}
+						]
+					}
+				]
+			}
+		}
+	}`
+
+	resources, err := getManagedResources(stateJSON)
+
+	require.NoError(t, err)
+	require.Len(t, resources, 2)
+	require.Equal(t, "/subscriptions/1/resourceGroups/rg", resources[0].ID)
+	require.Equal(t, "module.network.azurerm_virtual_network.vnet", resources[1].Address)
+	require.Equal(t, "vnet-id", resources[1].ID)
+}
+
+func TestGetManagedResources_ShouldHandleEmptyState(t *testing.T) {
+	t.Parallel()
+
+	resources, err := getManagedResources(`{"format_version": "0.1"}`)
+
+	require.NoError(t, err)
+	require.Empty(t, resources)
+}
+
+func TestGetBackendConfig_ShouldConfigureGCSBackendFromRuniacConfig(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		backendTf      string
+		prefix         string
+		expectedBucket string
+		expectedPrefix string
+	}{
+		{
+			name:           "ShouldGenerateBackendWhenNotDeclared",
+			expectedBucket: "stub-state",
+			expectedPrefix: "runiac/dev/core/network/pr-12",
+		},
+		{
+			name:           "ShouldInterpolateConfiguredPrefix",
+			prefix:         "states/${var.runiac_environment}/${var.runiac_step}",
+			expectedBucket: "stub-state",
+			expectedPrefix: "states/dev/network",
+		},
+		{
+			name:           "ShouldPreferDeclaredBackend",
+			backendTf:      "terraform {\n  backend \"gcs\" {\n    bucket = \"declared-state\"\n  }\n}\n",
+			expectedBucket: "declared-state",
+			expectedPrefix: "runiac/dev/core/network/pr-12",
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			fs := afero.NewMemMapFs()
+			if tc.backendTf != "" {
+				_ = afero.WriteFile(fs, "step1_network/backend.tf", []byte(tc.backendTf), 0644)
+			}
+
+			exec := config.StepExecution{
+				Fs:          fs,
+				Logger:      logger,
+				Dir:         "step1_network",
+				Project:     "runiac",
+				Environment: "dev",
+				TrackName:   "core",
+				StepName:    "network",
+				Namespace:   "PR-12",
+				Backend:     config.Backend{GCS: config.GCSBackend{Bucket: "stub-state", Prefix: tc.prefix}},
+			}
+
+			require.NoError(t, EnsureGCSBackend(exec))
+
+			backend := GetBackendConfig(exec, ParseTFBackend)
+
+			require.Equal(t, GCSBackend, backend.Type)
+			require.Equal(t, tc.expectedBucket, backend.Config["bucket"])
+			require.Equal(t, tc.expectedPrefix, backend.Config["prefix"])
+		})
+	}
+}
+
+func TestEnsureGCSBackend_ShouldNotWriteBackendWhenNotConfigured(t *testing.T) {
+	t.Parallel()
+	fs := afero.NewMemMapFs()
+
+	require.NoError(t, EnsureGCSBackend(config.StepExecution{Fs: fs, Logger: logger, Dir: "step1_network"}))
+
+	exists, _ := afero.Exists(fs, "step1_network/backend.tf")
+	require.False(t, exists)
+}
@@ -165,6 +165,78 @@ func ParseTFBackend(fs afero.Fs, log *logrus.Entry, file string) (backend Terraf
 	return
 }
 
+// gcsBackendFile declares the gcs backend configured by the backend section of runiac.yml
+const gcsBackendFile = `terraform {
+  backend "gcs" {}
+}
+`
+
+// EnsureGCSBackend writes a backend.tf declaring the gcs backend into the step directory when the backend section of
+// runiac.yml configures a GCS bucket and the step does not declare a backend
+func EnsureGCSBackend(exec config.StepExecution) error {
+	if exec.Backend.GCS.Bucket == "" {
+		return nil
+	}
+
+	file := filepath.Join(exec.Dir, "backend.tf")
+
+	exists, err := afero.Exists(exec.Fs, file)
+	if err != nil || exists {
+		return err
+	}
+
+	exec.Logger.Infof("Configuring the gcs backend in bucket %s", exec.Backend.GCS.Bucket)
+
+	return afero.WriteFile(exec.Fs, file, []byte(gcsBackendFile), 0644)
+}
+
+// getGCSPrefix returns the prefix of the step's state in the GCS bucket of the backend section of runiac.yml,
+// {project}/{environment}/{track}/{step}/{namespace} unless a prefix is configured
+func getGCSPrefix(exec config.StepExecution) string {
+	if exec.Backend.GCS.Prefix != "" {
+		return interpolateString(exec, exec.Backend.GCS.Prefix)
+	}
+
+	parts := []string{}
+
+	for _, part := range []string{exec.Project, exec.Environment, exec.TrackName, exec.StepName, exec.Namespace} {
+		if part != "" {
+			parts = append(parts, strings.ToLower(part))
+		}
+	}
+
+	return strings.Join(parts, "/")
+}
+
+// state is the top-level representation of the json format of the current state
+type state struct {
+	FormatVersion    string      `json:"format_version,omitempty"`
+	TerraformVersion string      `json:"terraform_version,omitempty"`
+	Values           stateValues `json:"values,omitempty"`
+}
+
+// stateValues is the common representation of resolved values for the current state
+type stateValues struct {
+	RootModule stateModule `json:"root_module,omitempty"`
+}
+
+// stateModule is the representation of a module in the state, modules may contain child modules
+type stateModule struct {
+	Address      string          `json:"address,omitempty"`
+	Resources    []stateResource `json:"resources,omitempty"`
+	ChildModules []stateModule   `json:"child_modules,omitempty"`
+}
+
+// stateResource is the representation of a resource in the state
+type stateResource struct {
+	Address      string                 `json:"address,omitempty"`
+	Mode         string                 `json:"mode,omitempty"`
+	Type         string                 `json:"type,omitempty"`
+	Name         string                 `json:"name,omitempty"`
+	ProviderName string                 `json:"provider_name,omitempty"`
+	Values       map[string]interface{} `json:"values,omitempty"`
+}
+
 // Plan is the top-level representation of the json format of a plan. It includes
 // the complete config and current state.
 type plan struct {
@@ -174,10 +246,12 @@ type plan struct {
 	//PlannedValues    stateValues `json:"planned_values,omitempty"`
 	// ResourceChanges are sorted in a user-friendly order that is undefined at
 	// this time, but consistent.
-	ResourceChanges []resourceChange  `json:"resource_changes,omitempty"`
-	OutputChanges   map[string]change `json:"output_changes,omitempty"`
-	PriorState      json.RawMessage   `json:"prior_state,omitempty"`
-	Config          json.RawMessage   `json:"configuration,omitempty"`
+	ResourceChanges []resourceChange `json:"resource_changes,omitempty"`
+	// ResourceDrift are the changes made to the remote objects outside of terraform since the last apply
+	ResourceDrift []resourceChange  `json:"resource_drift,omitempty"`
+	OutputChanges map[string]change `json:"output_changes,omitempty"`
+	PriorState    json.RawMessage   `json:"prior_state,omitempty"`
+	Config        json.RawMessage   `json:"configuration,omitempty"`
 }
 
 // resourceChange is a description of an individual change action that Terraform
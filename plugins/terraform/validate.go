@@ -0,0 +1,107 @@
+package plugins_terraform
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/plugins/terraform/pkg/terraform"
+)
+
+const validateRuleID = "terraform/validate"
+
+// validateOutput is the json output of terraform validate
+type validateOutput struct {
+	Valid        bool                 `json:"valid"`
+	ErrorCount   int                  `json:"error_count"`
+	WarningCount int                  `json:"warning_count"`
+	Diagnostics  []validateDiagnostic `json:"diagnostics"`
+}
+
+// validateDiagnostic is an error or warning reported by terraform validate
+type validateDiagnostic struct {
+	Severity string                   `json:"severity"`
+	Summary  string                   `json:"summary"`
+	Detail   string                   `json:"detail"`
+	Range    *validateDiagnosticRange `json:"range,omitempty"`
+}
+
+type validateDiagnosticRange struct {
+	Filename string                `json:"filename"`
+	Start    validateDiagnosticPos `json:"start"`
+	End      validateDiagnosticPos `json:"end"`
+}
+
+type validateDiagnosticPos struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// validateStep runs terraform validate in an initialized step directory and returns its findings
+func validateStep(exec config.StepExecution, tfOptions *terraform.Options) []config.Finding {
+	logger := tfOptions.Logger.WithField("terraform", "validate")
+	options := *tfOptions
+	options.Logger = logger
+
+	// terraform validate exits non-zero when the configuration is invalid, still producing the json output
+	resp, err := terraformer.Validate(&options)
+
+	findings, parseErr := getValidationFindings(exec.Dir, resp)
+	if parseErr != nil {
+		logger.WithError(parseErr).WithField("validateError", err).Warn("Unable to collect validation findings")
+		return nil
+	}
+
+	logger.Infof("Validation produced %d finding(s)", len(findings))
+
+	return findings
+}
+
+// getValidationFindings parses the terraform validate -json output of the step directory dir into findings
+func getValidationFindings(dir string, validateJSON string) ([]config.Finding, error) {
+	// the output may be preceded by messages terraform writes to stderr
+	start := strings.Index(validateJSON, "{")
+	if start < 0 {
+		return nil, errors.New("terraform validate did not produce json output")
+	}
+
+	out := validateOutput{}
+
+	err := json.Unmarshal([]byte(validateJSON[start:]), &out)
+	if err != nil {
+		return nil, err
+	}
+
+	findings := []config.Finding{}
+
+	for _, d := range out.Diagnostics {
+		f := config.Finding{
+			RuleID:  validateRuleID,
+			Level:   config.FindingWarning,
+			Message: d.Summary,
+		}
+
+		if d.Severity == "error" {
+			f.Level = config.FindingError
+		}
+
+		if d.Detail != "" {
+			f.Message = fmt.Sprintf("%s: %s", d.Summary, d.Detail)
+		}
+
+		if d.Range != nil {
+			f.File = filepath.ToSlash(filepath.Join(dir, d.Range.Filename))
+			f.StartLine = d.Range.Start.Line
+			f.StartColumn = d.Range.Start.Column
+			f.EndLine = d.Range.End.Line
+			f.EndColumn = d.Range.End.Column
+		}
+
+		findings = append(findings, f)
+	}
+
+	return findings, nil
+}
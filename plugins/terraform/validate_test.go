@@ -0,0 +1,51 @@
+package plugins_terraform
+
+import (
+	"testing"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetValidationFindings_ShouldMapDiagnosticsWithLocations(t *testing.T) {
+	t.Parallel()
+
+	validateJSON := `Warning: some stderr output
+{
+  "valid": false,
+  "error_count": 1,
+  "warning_count": 1,
+  "diagnostics": [
+    {
+      "severity": "error",
+      "summary": "Unsupported argument",
+      "detail": "An argument named \"nme\" is not expected here.",
+      "range": {"filename": "main.tf", "start": {"line": 3, "column": 3}, "end": {"line": 3, "column": 6}}
+    },
+    {
+      "severity": "warning",
+      "summary": "Deprecated provider"
+    }
+  ]
+}`
+
+	findings, err := getValidationFindings("tracks/network/step1_vnet", validateJSON)
+
+	require.NoError(t, err)
+	require.Len(t, findings, 2)
+	require.Equal(t, config.FindingError, findings[0].Level)
+	require.Equal(t, "tracks/network/step1_vnet/main.tf", findings[0].File)
+	require.Equal(t, 3, findings[0].StartLine)
+	require.Equal(t, 6, findings[0].EndColumn)
+	require.Contains(t, findings[0].Message, "nme")
+	require.Equal(t, config.FindingWarning, findings[1].Level)
+	require.Empty(t, findings[1].File)
+}
+
+func TestGetValidationFindings_ShouldFailWithoutJSON(t *testing.T) {
+	t.Parallel()
+
+	_, err := getValidationFindings("step1_default", "Error: terraform not initialized")
+
+	require.Error(t, err)
+}
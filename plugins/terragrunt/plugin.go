@@ -0,0 +1,35 @@
+package plugins_terragrunt
+
+import (
+	"github.com/optum/runiac/plugins/terraform/pkg/terraform"
+	"github.com/sirupsen/logrus"
+)
+
+type TerragruntPlugin struct{}
+
+func (info TerragruntPlugin) Initialize(logger *logrus.Entry) {
+	logger.Info("Initializing runiac Terragrunt plugin")
+	logger.Warn("The Terragrunt runner is currently experimental and is subject to change in future runiac releases")
+
+	// display terragrunt and the wrapped terraform binary information
+	for _, binary := range []string{"terragrunt", "terraform"} {
+		tfOptions := &terraform.Options{
+			TerraformBinary: binary,
+			TerraformDir:    ".",
+			EnvVars: map[string]string{
+				"CHECKPOINT_DISABLE": "true",
+			},
+			Logger:             logger.WithField(binary, "version"),
+			NoColor:            true,
+			MaxRetries:         1,
+			TimeBetweenRetries: 0,
+		}
+
+		resp, err := terraformer.Version(tfOptions)
+		if err != nil {
+			tfOptions.Logger.WithError(err).Errorf("Error running %s version", binary)
+		} else {
+			tfOptions.Logger.Info("Binary: ", resp)
+		}
+	}
+}
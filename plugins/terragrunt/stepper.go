@@ -0,0 +1,262 @@
+package plugins_terragrunt
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/optum/runiac/pkg/retry"
+	"github.com/optum/runiac/pkg/shell"
+	pluginsterraform "github.com/optum/runiac/plugins/terraform"
+	"github.com/optum/runiac/plugins/terraform/pkg/terraform"
+	"github.com/spf13/afero"
+)
+
+type TerragruntStepper struct{}
+
+var terraformer terraform.Terraformer = terraform.Terraform{}
+
+// terragruntManagedBackend matches the terragrunt.hcl blocks configuring the backend, either directly or through the
+// included parent configuration of a monorepo
+var terragruntManagedBackend = regexp.MustCompile(`(?m)^\s*(remote_state|include)\b`)
+
+func (stepper TerragruntStepper) PreExecute(exec config.StepExecution) (config.StepExecution, error) {
+	return exec, nil
+}
+
+// ExecuteStepDestroy destroys a step
+func (stepper TerragruntStepper) ExecuteStepDestroy(exec config.StepExecution) config.StepOutput {
+	return executeTerragruntInDir(exec, true)
+}
+
+// ExecuteStep deploys a step
+func (stepper TerragruntStepper) ExecuteStep(exec config.StepExecution) config.StepOutput {
+	return executeTerragruntInDir(exec, false)
+}
+
+// ExecuteStepTests executes the tests for a step, the tests of terragrunt steps are built and run like terraform's
+func (stepper TerragruntStepper) ExecuteStepTests(exec config.StepExecution) config.StepTestOutput {
+	return pluginsterraform.TerraformStepper{}.ExecuteStepTests(exec)
+}
+
+// executeTerragruntInDir is a helper function for executing terragrunt in a specified directory
+var executeTerragruntInDir = func(exec config.StepExecution, destroy bool) (output config.StepOutput) {
+	output.RegionDeployType = exec.RegionDeployType
+	output.Region = exec.Region
+	output.StepName = exec.StepName
+	output.Status = config.Fail // assume failure
+
+	// terragrunt init
+	tgOptions := getCommonTgOptions(exec)
+
+	if !isBackendManagedByTerragrunt(exec.Fs, exec.Dir) {
+		output.Err = pluginsterraform.EnsureGCSBackend(exec)
+
+		if output.Err != nil {
+			exec.Logger.WithError(output.Err).Error("unable to configure the gcs backend")
+			return
+		}
+
+		tgOptions.BackendConfig = pluginsterraform.GetBackendConfig(exec, pluginsterraform.ParseTFBackend).Config
+	}
+
+	tgOptions.Logger = exec.Logger.WithField("terragrunt", "init")
+	_, output.Err = terraformer.Init(tgOptions)
+
+	if output.Err != nil {
+		tgOptions.Logger.WithError(output.Err).Error("Error during terragrunt init")
+		return
+	}
+
+	tgOptions = getCommonTgOptions(exec)
+	tgOptions.Logger = exec.Logger.WithField("terragrunt", "workspace")
+
+	_, output.Err = terraformer.WorkspaceSelect(tgOptions, getWorkspace(exec))
+
+	if output.Err != nil {
+		tgOptions.Logger.WithError(output.Err).Error("Error during terragrunt workspace select")
+		return
+	}
+
+	tfplan := fmt.Sprintf("%s%s%stfplan", exec.StepName, exec.RegionDeployType, exec.Region)
+
+	_ = retry.DoWithRetry("terragrunt plan and apply", exec.MaxRetries, 10*time.Second, exec.Logger, func(attempt int) error {
+		retryLogger := exec.Logger.WithField("retryCount", attempt)
+
+		// terragrunt plan
+		tgOptions := getCommonTgOptions(exec)
+		tgOptions.Logger = retryLogger.WithField("terragrunt", "plan")
+
+		_, output.Err = terraformer.Plan(tgOptions, tfplan, destroy)
+
+		if output.Err != nil {
+			tgOptions.Logger.WithError(output.Err).Error("Error running terragrunt plan")
+			return output.Err
+		}
+
+		tgOptions.Logger = retryLogger.WithField("terragrunt", "show")
+
+		var resp string
+		resp, output.Err = runTerragruntCommandAndGetStdOut(tgOptions, "show", "-json", tfplan)
+
+		if output.Err != nil {
+			tgOptions.Logger.WithError(output.Err).Errorf("Error during terragrunt show:\n%s", resp)
+			return output.Err
+		}
+
+		output.ResourceChanges, output.Err = logPlanChanges(tgOptions, resp)
+
+		if output.Err != nil {
+			tgOptions.Logger.WithError(output.Err).Error("Error unmarshalling terragrunt show")
+			return output.Err
+		}
+
+		// only run apply when not a dry run
+		if exec.DryRun {
+			tgOptions.Logger.Info("---------- Skipping apply, this is a dry run ---------- ")
+		} else {
+			tgOptions.Logger = retryLogger.WithField("terragrunt", "apply")
+			_, output.Err = terraformer.Apply(tgOptions, tfplan)
+
+			if output.Err != nil {
+				tgOptions.Logger.WithError(output.Err).Error("Error running terragrunt apply")
+				return output.Err
+			}
+		}
+
+		// parse terragrunt output
+		tgOptions.Logger = retryLogger.WithField("terragrunt", "output")
+		output.OutputVariables, output.Err = outputAll(tgOptions)
+
+		if output.Err != nil {
+			tgOptions.Logger.WithError(output.Err).Error("Error running terragrunt output")
+		}
+
+		output.Status = config.Success
+
+		return nil
+	})
+
+	return
+}
+
+// getCommonTgOptions returns the options of the terragrunt commands, the runiac variables are set as TF_VAR env
+// variables so terragrunt.hcl inputs and the wrapped terraform modules can read them alike
+func getCommonTgOptions(exec config.StepExecution) *terraform.Options {
+	tgOptions := &terraform.Options{
+		TerraformBinary: "terragrunt",
+		TerraformDir:    exec.Dir,
+		EnvVars: map[string]string{
+			"TERRAGRUNT_NON_INTERACTIVE": "true",
+			"TF_INPUT":                   "false",
+		},
+		Logger:             exec.Logger,
+		NoColor:            true,
+		MaxRetries:         exec.MaxRetries,
+		TimeBetweenRetries: 5 * time.Second,
+		Context:            exec.Context,
+	}
+
+	for k, v := range pluginsterraform.GetTerraformEnvVars(exec) {
+		tgOptions.EnvVars[fmt.Sprintf("TF_VAR_%s", k)] = v
+	}
+
+	for k, v := range pluginsterraform.GetTerraformCLIVars(exec) {
+		tgOptions.EnvVars[fmt.Sprintf("TF_VAR_%s", k)] = fmt.Sprintf("%v", v)
+	}
+
+	return tgOptions
+}
+
+// getWorkspace returns the terraform workspace isolating the state of each region, matching the terraform runner
+func getWorkspace(exec config.StepExecution) string {
+	workspace := fmt.Sprintf("%s-%s", exec.RegionDeployType.String(), exec.Region)
+
+	if exec.Namespace != "" {
+		workspace = fmt.Sprintf("%s-%s", exec.Namespace, workspace)
+	}
+
+	return workspace
+}
+
+// isBackendManagedByTerragrunt returns true when the step's terragrunt.hcl configures the backend with remote_state
+// or includes a parent configuration that may. runiac's backend conventions of backend.tf only apply otherwise.
+func isBackendManagedByTerragrunt(fs afero.Fs, dir string) bool {
+	b, err := afero.ReadFile(fs, filepath.Join(dir, "terragrunt.hcl"))
+	if err != nil {
+		return false
+	}
+
+	return terragruntManagedBackend.Match(b)
+}
+
+// runTerragruntCommandAndGetStdOut runs terragrunt and returns solely its stdout, terragrunt logs to stderr which
+// would otherwise corrupt json output
+func runTerragruntCommandAndGetStdOut(options *terraform.Options, args ...string) (string, error) {
+	options, args = terraform.GetCommonOptions(options, args...)
+
+	return shell.RunShellCommandAndGetStdOut(shell.Command{
+		Command:           options.TerraformBinary,
+		Args:              args,
+		WorkingDir:        options.TerraformDir,
+		Env:               options.EnvVars,
+		OutputMaxLineSize: options.OutputMaxLineSize,
+		NonInteractive:    true,
+		Logger:            options.Logger,
+		Context:           options.Context,
+	})
+}
+
+// outputAll calls terragrunt output and returns all the outputs as a map
+func outputAll(options *terraform.Options) (map[string]interface{}, error) {
+	out, err := runTerragruntCommandAndGetStdOut(options, "output", "-json")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseOutputs(out)
+}
+
+// parseOutputs parses the values of terraform output -json
+func parseOutputs(out string) (map[string]interface{}, error) {
+	outputMap := map[string]map[string]interface{}{}
+	if err := json.Unmarshal([]byte(out), &outputMap); err != nil {
+		return nil, err
+	}
+
+	outputs := map[string]interface{}{}
+	for k, v := range outputMap {
+		outputs[k] = v["value"]
+	}
+
+	return outputs, nil
+}
+
+type plan struct {
+	ResourceChanges []struct {
+		Address string `json:"address"`
+		Change  struct {
+			Actions []string `json:"actions"`
+		} `json:"change"`
+	} `json:"resource_changes"`
+}
+
+// logPlanChanges logs the resource changes of the terraform show -json plan and returns their counts
+func logPlanChanges(options *terraform.Options, planJSON string) (config.ResourceChanges, error) {
+	p := plan{}
+	changes := config.ResourceChanges{}
+
+	if err := json.Unmarshal([]byte(planJSON), &p); err != nil {
+		return changes, err
+	}
+
+	for _, c := range p.ResourceChanges {
+		options.Logger.Infof("%s: %s", c.Address, c.Change.Actions)
+		changes.Count(c.Change.Actions)
+	}
+
+	return changes, nil
+}
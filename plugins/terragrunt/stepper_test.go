@@ -0,0 +1,45 @@
+package plugins_terragrunt
+
+import (
+	"testing"
+
+	"github.com/optum/runiac/pkg/config"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsBackendManagedByTerragrunt(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	_ = afero.WriteFile(fs, "remote/terragrunt.hcl", []byte("remote_state {\n  backend = \"s3\"\n}\n"), 0644)
+	_ = afero.WriteFile(fs, "include/terragrunt.hcl", []byte("include {\n  path = find_in_parent_folders()\n}\n"), 0644)
+	_ = afero.WriteFile(fs, "inputs/terragrunt.hcl", []byte("inputs = {\n  remote_state_bucket = \"stub\"\n}\n"), 0644)
+
+	require.True(t, isBackendManagedByTerragrunt(fs, "remote"))
+	require.True(t, isBackendManagedByTerragrunt(fs, "include"))
+	require.False(t, isBackendManagedByTerragrunt(fs, "inputs"))
+	require.False(t, isBackendManagedByTerragrunt(fs, "missing"))
+}
+
+func TestParseOutputs_ShouldReturnOutputValues(t *testing.T) {
+	t.Parallel()
+
+	outputs, err := parseOutputs(`{"bucket":{"sensitive":false,"type":"string","value":"stub-bucket"},"ids":{"sensitive":false,"type":["list","string"],"value":["a","b"]}}`)
+
+	require.NoError(t, err)
+	require.Equal(t, "stub-bucket", outputs["bucket"])
+	require.Equal(t, []interface{}{"a", "b"}, outputs["ids"])
+}
+
+func TestGetCommonTgOptions_ShouldSetRuniacVariablesAsEnv(t *testing.T) {
+	t.Parallel()
+
+	options := getCommonTgOptions(config.StepExecution{Dir: "step1_stub", Region: "us-east-1", AccountID: "123456789012", Environment: "dev"})
+
+	require.Equal(t, "terragrunt", options.TerraformBinary)
+	require.Equal(t, "true", options.EnvVars["TERRAGRUNT_NON_INTERACTIVE"])
+	require.Equal(t, "us-east-1", options.EnvVars["TF_VAR_runiac_region"])
+	require.Equal(t, "123456789012", options.EnvVars["TF_VAR_runiac_account_id"])
+	require.Equal(t, "dev", options.EnvVars["TF_VAR_runiac_environment"])
+}
@@ -10,9 +10,15 @@ then
   push=true
 fi
 
+# if not in version not set, set to local default
+if [ -z "$VERSION"  ]
+then
+  VERSION=$(whoami)
+fi
+
 rm -rf ./reports;
 outputVolume=$(docker volume create);
-DOCKER_BUILDKIT=1 docker build -f "build/package/alpine-builder/Dockerfile" -t "runiac:alpine-builder" . || exit 1;
+DOCKER_BUILDKIT=1 docker build --build-arg RUNIAC_VERSION="$VERSION" -f "build/package/alpine-builder/Dockerfile" -t "runiac:alpine-builder" . || exit 1;
 CID=$(docker create -v "$outputVolume":/reports "runiac:alpine-builder");
 docker cp "$CID":/reports $(pwd);
 touch ./reports/*.xml;
@@ -30,17 +36,11 @@ for d in build/package/*/ ; do
   dir="${d%/*}"
   cleanDir=${dir##*/}
 
-  # if not in version not set, set to local default
-  if [ -z "$VERSION"  ]
-  then
-    VERSION=$(whoami)
-  fi
-
   image="runiac:$VERSION-$cleanDir"
 
   echo "building ${image}"
 
-  DOCKER_BUILDKIT=1 docker build -f "$d/Dockerfile" -t "$image" .
+  DOCKER_BUILDKIT=1 docker build --build-arg RUNIAC_VERSION="$VERSION" -f "$d/Dockerfile" -t "$image" .
 
   if [ "$push" == "true"  ]
   then